@@ -0,0 +1,3 @@
+-- 000002_create_skipped_transactions.down.sql
+DROP INDEX IF EXISTS idx_skipped_transactions_user_id;
+DROP TABLE skipped_transactions;
@@ -0,0 +1,14 @@
+-- 000002_create_skipped_transactions.up.sql
+CREATE TABLE IF NOT EXISTS skipped_transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    raw_text TEXT NOT NULL,
+    payload TEXT NOT NULL, -- Minimal file in the source format containing only this row, used for re-processing
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    UNIQUE(user_id, source, raw_text)
+);
+
+CREATE INDEX IF NOT EXISTS idx_skipped_transactions_user_id ON skipped_transactions(user_id);
@@ -0,0 +1,3 @@
+-- 000003_create_user_identities.down.sql
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP TABLE IF EXISTS user_identities;
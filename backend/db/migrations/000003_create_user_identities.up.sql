@@ -0,0 +1,19 @@
+-- 000003_create_user_identities.up.sql
+CREATE TABLE IF NOT EXISTS user_identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    provider TEXT NOT NULL, -- 'local' or 'google'
+    provider_user_id TEXT NOT NULL, -- Google account ID, or the email for local identities
+    email TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    UNIQUE(provider, provider_user_id),
+    UNIQUE(user_id, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Existing password accounts get their local identity. Google accounts are linked on their next login,
+-- since the Google account ID was not stored before.
+INSERT OR IGNORE INTO user_identities (user_id, provider, provider_user_id, email)
+SELECT id, 'local', email, email FROM users WHERE auth_provider = 'local' OR auth_provider IS NULL;
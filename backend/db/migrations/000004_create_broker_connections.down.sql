@@ -0,0 +1,2 @@
+-- 000004_create_broker_connections.down.sql
+DROP TABLE IF EXISTS broker_connections;
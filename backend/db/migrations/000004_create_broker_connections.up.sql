@@ -0,0 +1,15 @@
+-- 000004_create_broker_connections.up.sql
+CREATE TABLE IF NOT EXISTS broker_connections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    broker TEXT NOT NULL, -- e.g. 'ibkr'
+    encrypted_token TEXT NOT NULL, -- AES-GCM encrypted with CREDENTIALS_ENCRYPTION_KEY
+    query_id TEXT NOT NULL,
+    last_sync_at TIMESTAMP,
+    last_sync_status TEXT,
+    last_sync_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    UNIQUE(user_id, broker)
+);
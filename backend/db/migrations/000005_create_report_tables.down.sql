@@ -0,0 +1,6 @@
+-- 000005_create_report_tables.down.sql
+DROP INDEX IF EXISTS idx_report_stock_holdings_user_version;
+DROP TABLE IF EXISTS report_stock_holdings;
+DROP INDEX IF EXISTS idx_report_stock_sales_user_version;
+DROP TABLE IF EXISTS report_stock_sales;
+DROP TABLE IF EXISTS report_versions;
@@ -0,0 +1,58 @@
+-- 000005_create_report_tables.up.sql
+-- Materialized FIFO results. Rows are valid only for the data_version recorded in report_versions,
+-- a hash of the user's processed transactions; any change to them triggers a recomputation.
+CREATE TABLE IF NOT EXISTS report_versions (
+    user_id INTEGER PRIMARY KEY,
+    data_version TEXT NOT NULL,
+    holding_years TEXT NOT NULL DEFAULT '', -- Comma-separated years with a holdings snapshot, including empty ones
+    computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS report_stock_sales (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    data_version TEXT NOT NULL,
+    position INTEGER NOT NULL, -- Order of the sale in the FIFO output
+    sale_date TEXT NOT NULL,
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    isin TEXT,
+    quantity INTEGER NOT NULL,
+    sale_price REAL,
+    sale_amount REAL,
+    sale_currency TEXT,
+    sale_amount_eur REAL,
+    sale_exchange_rate REAL,
+    buy_price REAL,
+    buy_amount REAL,
+    buy_currency TEXT,
+    buy_amount_eur REAL,
+    buy_exchange_rate REAL,
+    commission REAL,
+    transaction_tax REAL,
+    delta REAL,
+    country_code TEXT,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_stock_sales_user_version ON report_stock_sales(user_id, data_version);
+
+CREATE TABLE IF NOT EXISTS report_stock_holdings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    data_version TEXT NOT NULL,
+    year TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    isin TEXT,
+    quantity INTEGER NOT NULL,
+    buy_price REAL,
+    buy_amount REAL,
+    buy_currency TEXT,
+    buy_amount_eur REAL,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_stock_holdings_user_version ON report_stock_holdings(user_id, data_version, year);
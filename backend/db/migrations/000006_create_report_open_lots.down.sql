@@ -0,0 +1,7 @@
+-- 000006_create_report_open_lots.down.sql
+DROP INDEX IF EXISTS idx_report_open_lots_user_version;
+DROP TABLE IF EXISTS report_open_lots;
+ALTER TABLE report_versions DROP COLUMN fifo_last_year;
+ALTER TABLE report_versions DROP COLUMN fifo_last_date;
+ALTER TABLE report_versions DROP COLUMN transaction_count;
+ALTER TABLE report_versions DROP COLUMN last_transaction_id;
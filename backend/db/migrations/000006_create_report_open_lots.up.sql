@@ -0,0 +1,28 @@
+-- 000006_create_report_open_lots.up.sql
+-- FIFO state saved with each materialized report so appended transactions can resume matching.
+ALTER TABLE report_versions ADD COLUMN last_transaction_id INTEGER NOT NULL DEFAULT 0; -- Highest processed_transactions.id included
+ALTER TABLE report_versions ADD COLUMN transaction_count INTEGER NOT NULL DEFAULT 0; -- Rows with id <= last_transaction_id at computation time
+ALTER TABLE report_versions ADD COLUMN fifo_last_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE report_versions ADD COLUMN fifo_last_year INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS report_open_lots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    data_version TEXT NOT NULL,
+    isin TEXT NOT NULL,
+    position INTEGER NOT NULL, -- FIFO order within the ISIN
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    quantity INTEGER NOT NULL,
+    original_quantity INTEGER NOT NULL,
+    price REAL,
+    amount REAL,
+    amount_eur REAL,
+    currency TEXT,
+    exchange_rate REAL,
+    commission REAL,
+    transaction_tax REAL,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_open_lots_user_version ON report_open_lots(user_id, data_version, isin);
@@ -0,0 +1,2 @@
+-- 000007_create_csv_mappings.down.sql
+DROP TABLE IF EXISTS csv_mappings;
@@ -0,0 +1,10 @@
+-- 000007_create_csv_mappings.up.sql
+CREATE TABLE IF NOT EXISTS csv_mappings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    mapping TEXT NOT NULL, -- JSON column mapping used by the generic CSV parser
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    UNIQUE(user_id)
+);
@@ -0,0 +1,5 @@
+-- 000008_create_transaction_tags.down.sql
+DROP INDEX IF EXISTS idx_transaction_notes_user_id;
+DROP TABLE IF EXISTS transaction_notes;
+DROP INDEX IF EXISTS idx_transaction_tags_user_tag;
+DROP TABLE IF EXISTS transaction_tags;
@@ -0,0 +1,24 @@
+-- 000008_create_transaction_tags.up.sql
+CREATE TABLE IF NOT EXISTS transaction_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    tag TEXT NOT NULL, -- e.g. 'PEA', 'speculative', 'gift'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES processed_transactions(id),
+    UNIQUE(transaction_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_tags_user_tag ON transaction_tags(user_id, tag);
+
+CREATE TABLE IF NOT EXISTS transaction_notes (
+    transaction_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    note TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES processed_transactions(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_notes_user_id ON transaction_notes(user_id);
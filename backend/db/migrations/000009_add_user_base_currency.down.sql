@@ -0,0 +1,2 @@
+-- 000009_add_user_base_currency.down.sql
+ALTER TABLE users DROP COLUMN base_currency;
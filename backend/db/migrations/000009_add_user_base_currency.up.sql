@@ -0,0 +1,3 @@
+-- 000009_add_user_base_currency.up.sql
+-- Currency that amount_eur and every report amount are expressed in. Existing users keep EUR.
+ALTER TABLE users ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'EUR';
@@ -0,0 +1,2 @@
+-- 000010_add_user_locale.down.sql
+ALTER TABLE users DROP COLUMN locale;
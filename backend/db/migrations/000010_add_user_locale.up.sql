@@ -0,0 +1,3 @@
+-- 000010_add_user_locale.up.sql
+-- Locale of API-generated text (country names, report messages, emails). Existing users keep Portuguese.
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'pt-PT';
@@ -0,0 +1,5 @@
+-- 000011_add_login_lockout.down.sql
+ALTER TABLE users DROP COLUMN account_unlock_token_expires_at;
+ALTER TABLE users DROP COLUMN account_unlock_token;
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_login_attempts;
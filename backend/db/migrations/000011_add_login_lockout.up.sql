@@ -0,0 +1,7 @@
+-- 000011_add_login_lockout.up.sql
+-- Per-account failed login tracking. After too many failures the account is locked until
+-- locked_until, with exponential backoff, and an unlock link is emailed to the owner.
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN account_unlock_token TEXT;
+ALTER TABLE users ADD COLUMN account_unlock_token_expires_at TIMESTAMP;
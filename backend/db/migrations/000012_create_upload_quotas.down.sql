@@ -0,0 +1,3 @@
+-- 000012_create_upload_quotas.down.sql
+DROP TABLE IF EXISTS upload_usage;
+ALTER TABLE users DROP COLUMN plan;
@@ -0,0 +1,12 @@
+-- 000012_create_upload_quotas.up.sql
+-- Subscription plan of each user; its limits are configured on the server.
+ALTER TABLE users ADD COLUMN plan TEXT NOT NULL DEFAULT 'free';
+
+-- Files uploaded per user and calendar month, for the monthly upload quota.
+CREATE TABLE IF NOT EXISTS upload_usage (
+    user_id INTEGER NOT NULL,
+    month TEXT NOT NULL, -- YYYY-MM
+    uploads INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, month),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
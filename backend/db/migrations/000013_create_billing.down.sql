@@ -0,0 +1,4 @@
+-- 000013_create_billing.down.sql
+DROP INDEX IF EXISTS idx_subscriptions_customer;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS plans;
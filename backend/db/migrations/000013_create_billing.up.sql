@@ -0,0 +1,29 @@
+-- 000013_create_billing.up.sql
+-- Billing catalog: which plans include premium features and the Stripe price that buys each paid plan.
+-- Upload and transaction limits stay in the server configuration.
+CREATE TABLE IF NOT EXISTS plans (
+    name TEXT PRIMARY KEY,
+    display_name TEXT NOT NULL,
+    premium BOOLEAN NOT NULL DEFAULT FALSE,
+    stripe_price_id TEXT UNIQUE
+);
+
+INSERT OR IGNORE INTO plans (name, display_name, premium) VALUES ('free', 'Free', FALSE);
+INSERT OR IGNORE INTO plans (name, display_name, premium) VALUES ('premium', 'Premium', TRUE);
+
+-- Latest known state of each user's subscription with the payment provider.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    provider TEXT NOT NULL DEFAULT 'stripe',
+    customer_id TEXT NOT NULL,
+    subscription_id TEXT,
+    plan TEXT,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    UNIQUE(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_customer ON subscriptions (provider, customer_id);
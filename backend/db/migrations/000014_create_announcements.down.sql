@@ -0,0 +1,3 @@
+-- 000014_create_announcements.down.sql
+DROP INDEX IF EXISTS idx_announcements_ends_at;
+DROP TABLE IF EXISTS announcements;
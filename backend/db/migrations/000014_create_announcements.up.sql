@@ -0,0 +1,12 @@
+-- 000014_create_announcements.up.sql
+-- Service-wide announcements set by an admin and shown to every user. At most one is active:
+-- setting a new one ends the previous one.
+CREATE TABLE IF NOT EXISTS announcements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message TEXT NOT NULL,
+    level TEXT NOT NULL DEFAULT 'info', -- info, warning or maintenance
+    ends_at TIMESTAMP, -- NULL until cleared, unless given an expiry
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_ends_at ON announcements (ends_at);
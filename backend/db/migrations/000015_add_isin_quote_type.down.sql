@@ -0,0 +1,2 @@
+-- 000015_add_isin_quote_type.down.sql
+ALTER TABLE isin_ticker_map DROP COLUMN quote_type;
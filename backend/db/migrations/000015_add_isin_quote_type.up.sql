@@ -0,0 +1,4 @@
+-- 000015_add_isin_quote_type.up.sql
+-- Instrument type reported by the Yahoo search (EQUITY, ETF, MUTUALFUND, ...), used to tell ETFs from
+-- stocks. NULL for mappings cached before it was recorded; they are looked up again.
+ALTER TABLE isin_ticker_map ADD COLUMN quote_type TEXT;
@@ -0,0 +1,3 @@
+-- 000016_add_fiscal_year_start.down.sql
+ALTER TABLE report_stock_sales DROP COLUMN tax_year;
+ALTER TABLE users DROP COLUMN fiscal_year_start;
@@ -0,0 +1,7 @@
+-- 000016_add_fiscal_year_start.up.sql
+-- First day of each user's tax year, as DD-MM (e.g. 06-04 for UK tax years). Reports bucket results
+-- by the tax year a date falls in, labelled with the calendar year it starts in.
+ALTER TABLE users ADD COLUMN fiscal_year_start TEXT NOT NULL DEFAULT '01-01';
+
+-- Tax year each materialized stock sale is reported in.
+ALTER TABLE report_stock_sales ADD COLUMN tax_year TEXT NOT NULL DEFAULT '';
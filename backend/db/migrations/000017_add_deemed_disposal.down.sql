@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN deemed_disposal_enabled;
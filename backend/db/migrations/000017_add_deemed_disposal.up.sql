@@ -0,0 +1,2 @@
+-- Whether the user is subject to the Irish 8-year deemed disposal rule on ETF holdings.
+ALTER TABLE users ADD COLUMN deemed_disposal_enabled INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- 000018_create_upload_batches.down.sql
+DROP TABLE IF EXISTS upload_batches;
@@ -0,0 +1,18 @@
+-- 000018_create_upload_batches.up.sql
+-- One row per uploaded file. A client may send an Idempotency-Key with the upload: a retry with the
+-- same key is answered from the stored batch instead of processing the file again.
+CREATE TABLE IF NOT EXISTS upload_batches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    idempotency_key TEXT, -- NULL when not sent, and once the key has expired
+    status TEXT NOT NULL DEFAULT 'processing', -- processing, completed or failed
+    rows_imported INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    UNIQUE (user_id, idempotency_key),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
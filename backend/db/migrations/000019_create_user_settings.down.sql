@@ -0,0 +1,13 @@
+-- 000019_create_user_settings.down.sql
+ALTER TABLE users ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'EUR';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'pt-PT';
+ALTER TABLE users ADD COLUMN fiscal_year_start TEXT NOT NULL DEFAULT '01-01';
+ALTER TABLE users ADD COLUMN deemed_disposal_enabled INTEGER NOT NULL DEFAULT 0;
+
+UPDATE users SET
+    base_currency = COALESCE((SELECT json_extract(settings, '$.base_currency') FROM user_settings WHERE user_id = users.id), 'EUR'),
+    locale = COALESCE((SELECT json_extract(settings, '$.locale') FROM user_settings WHERE user_id = users.id), 'pt-PT'),
+    fiscal_year_start = COALESCE((SELECT json_extract(settings, '$.fiscal_year_start') FROM user_settings WHERE user_id = users.id), '01-01'),
+    deemed_disposal_enabled = COALESCE((SELECT json_extract(settings, '$.deemed_disposal') FROM user_settings WHERE user_id = users.id), 0);
+
+DROP TABLE IF EXISTS user_settings;
@@ -0,0 +1,23 @@
+-- 000019_create_user_settings.up.sql
+-- Per-user preferences as a JSON object, validated by the application: base_currency, matching_method,
+-- locale, fiscal_year_start, default_account and deemed_disposal. Missing keys take their defaults.
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id INTEGER PRIMARY KEY,
+    settings TEXT NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+-- Move the preferences kept so far as columns of users.
+INSERT INTO user_settings (user_id, settings)
+SELECT id, json_object(
+    'base_currency', base_currency,
+    'locale', locale,
+    'fiscal_year_start', fiscal_year_start,
+    'deemed_disposal', json(CASE WHEN deemed_disposal_enabled THEN 'true' ELSE 'false' END))
+FROM users;
+
+ALTER TABLE users DROP COLUMN base_currency;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN fiscal_year_start;
+ALTER TABLE users DROP COLUMN deemed_disposal_enabled;
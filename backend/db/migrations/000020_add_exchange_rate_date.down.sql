@@ -0,0 +1,5 @@
+-- 000020_add_exchange_rate_date.down.sql
+ALTER TABLE report_open_lots DROP COLUMN exchange_rate_date;
+ALTER TABLE report_stock_sales DROP COLUMN buy_exchange_rate_date;
+ALTER TABLE report_stock_sales DROP COLUMN sale_exchange_rate_date;
+ALTER TABLE processed_transactions DROP COLUMN exchange_rate_date;
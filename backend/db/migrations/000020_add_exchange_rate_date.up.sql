@@ -0,0 +1,8 @@
+-- 000020_add_exchange_rate_date.up.sql
+-- Day of the ECB observation each exchange rate was taken from, as DD-MM-YYYY. It precedes the
+-- transaction date when that fell on a weekend or holiday, and is empty when unknown.
+ALTER TABLE processed_transactions ADD COLUMN exchange_rate_date TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE report_stock_sales ADD COLUMN sale_exchange_rate_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE report_stock_sales ADD COLUMN buy_exchange_rate_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE report_open_lots ADD COLUMN exchange_rate_date TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- 000021_create_unknown_descriptions.down.sql
+DROP INDEX IF EXISTS idx_unknown_descriptions_occurrences;
+DROP TABLE IF EXISTS unknown_descriptions;
@@ -0,0 +1,15 @@
+-- 000021_create_unknown_descriptions.up.sql
+-- Descriptions the parsers could not classify, reported by users who opted in with the
+-- share_unknown_descriptions setting. Rows are not linked to users: the description is identified by
+-- the hash of its normalized text, and only a truncated sample with the numbers masked is kept.
+CREATE TABLE IF NOT EXISTS unknown_descriptions (
+    source TEXT NOT NULL,
+    pattern_hash TEXT NOT NULL,
+    sample TEXT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 0,
+    first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, pattern_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_unknown_descriptions_occurrences ON unknown_descriptions(occurrences DESC);
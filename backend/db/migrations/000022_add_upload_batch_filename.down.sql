@@ -0,0 +1,3 @@
+-- 000022_add_upload_batch_filename.down.sql
+DROP INDEX IF EXISTS idx_upload_batches_user_created;
+ALTER TABLE upload_batches DROP COLUMN filename;
@@ -0,0 +1,6 @@
+-- 000022_add_upload_batch_filename.up.sql
+-- Name of the uploaded file, shown in the upload history. Empty for imports that are not file uploads,
+-- such as IBKR Flex syncs, and for batches recorded before it was stored.
+ALTER TABLE upload_batches ADD COLUMN filename TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_upload_batches_user_created ON upload_batches(user_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- 000023_add_broker_balance.down.sql
+ALTER TABLE processed_transactions DROP COLUMN broker_balance_currency;
+ALTER TABLE processed_transactions DROP COLUMN broker_balance;
@@ -0,0 +1,5 @@
+-- 000023_add_broker_balance.up.sql
+-- Cash balance the broker's statement reports after each row, such as DeGiro's Saldo column, to check
+-- the balance rebuilt from the transactions against. The currency is empty when the statement has none.
+ALTER TABLE processed_transactions ADD COLUMN broker_balance REAL NOT NULL DEFAULT 0;
+ALTER TABLE processed_transactions ADD COLUMN broker_balance_currency TEXT NOT NULL DEFAULT '';
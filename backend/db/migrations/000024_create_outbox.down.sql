@@ -0,0 +1,3 @@
+-- 000024_create_outbox.down.sql
+DROP INDEX IF EXISTS idx_outbox_status_next_attempt;
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,2 @@
+-- 000025_add_user_data_version.down.sql
+ALTER TABLE users DROP COLUMN data_version;
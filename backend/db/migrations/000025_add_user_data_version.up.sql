@@ -0,0 +1,4 @@
+-- 000025_add_user_data_version.up.sql
+-- Counter bumped whenever a user's transactions or settings change, from which report responses
+-- derive their ETag.
+ALTER TABLE users ADD COLUMN data_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- 000026_hash_refresh_tokens.down.sql
+-- Encrypted refresh tokens cannot be looked up without their hash: those sessions have to log in again.
+DROP INDEX IF EXISTS idx_sessions_refresh_token_hash;
+ALTER TABLE sessions DROP COLUMN refresh_token_hash;
@@ -0,0 +1,6 @@
+-- 000026_hash_refresh_tokens.up.sql
+-- Refresh tokens are stored encrypted, and looked up by the SHA-256 of the token instead. Sessions
+-- created before have no hash and a plain refresh token until the re-encryption run at startup.
+ALTER TABLE sessions ADD COLUMN refresh_token_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions(refresh_token_hash);
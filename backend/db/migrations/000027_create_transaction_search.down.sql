@@ -0,0 +1,5 @@
+-- 000027_create_transaction_search.down.sql
+DROP TRIGGER IF EXISTS processed_transactions_fts_update;
+DROP TRIGGER IF EXISTS processed_transactions_fts_delete;
+DROP TRIGGER IF EXISTS processed_transactions_fts_insert;
+DROP TABLE IF EXISTS processed_transactions_fts;
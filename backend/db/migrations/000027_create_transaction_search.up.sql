@@ -0,0 +1,28 @@
+-- 000027_create_transaction_search.up.sql
+-- Full-text index of the fields GET /search looks in. It holds no copy of the data, reading it from
+-- processed_transactions, and the triggers keep it in step with that table.
+CREATE VIRTUAL TABLE IF NOT EXISTS processed_transactions_fts USING fts5(
+    product_name, isin, description, order_id,
+    content = 'processed_transactions', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS processed_transactions_fts_insert AFTER INSERT ON processed_transactions BEGIN
+    INSERT INTO processed_transactions_fts (rowid, product_name, isin, description, order_id)
+    VALUES (new.id, new.product_name, new.isin, new.description, new.order_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS processed_transactions_fts_delete AFTER DELETE ON processed_transactions BEGIN
+    INSERT INTO processed_transactions_fts (processed_transactions_fts, rowid, product_name, isin, description, order_id)
+    VALUES ('delete', old.id, old.product_name, old.isin, old.description, old.order_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS processed_transactions_fts_update AFTER UPDATE OF product_name, isin, description, order_id ON processed_transactions BEGIN
+    INSERT INTO processed_transactions_fts (processed_transactions_fts, rowid, product_name, isin, description, order_id)
+    VALUES ('delete', old.id, old.product_name, old.isin, old.description, old.order_id);
+    INSERT INTO processed_transactions_fts (rowid, product_name, isin, description, order_id)
+    VALUES (new.id, new.product_name, new.isin, new.description, new.order_id);
+END;
+
+-- Index the transactions stored before
+INSERT INTO processed_transactions_fts (processed_transactions_fts) VALUES ('rebuild');
@@ -0,0 +1,18 @@
+-- 000028_create_alert_rules.up.sql
+-- Conditions users ask to be emailed about, checked by a background job. triggered_key identifies
+-- the occurrence last notified, so the same one is not emailed again; it is cleared once the
+-- condition no longer holds.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- below_cost_basis, monthly_dividends_above or unmatched_sell
+    isin TEXT NOT NULL DEFAULT '',
+    threshold REAL NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    triggered_key TEXT NOT NULL DEFAULT '',
+    last_triggered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules(user_id);
@@ -0,0 +1,4 @@
+-- 000031_create_share_links.down.sql
+DROP INDEX IF EXISTS idx_share_links_expires_at;
+DROP INDEX IF EXISTS idx_share_links_user_id;
+DROP TABLE IF EXISTS share_links;
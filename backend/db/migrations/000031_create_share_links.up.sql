@@ -0,0 +1,18 @@
+-- 000031_create_share_links.up.sql
+-- Expiring links users hand out for read-only access to some of their reports. Only the SHA-256 of
+-- the token is stored: the token itself is shown once, when the link is created.
+CREATE TABLE IF NOT EXISTS share_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    label TEXT NOT NULL DEFAULT '',
+    scopes TEXT NOT NULL, -- Comma-separated report scopes
+    year TEXT NOT NULL DEFAULT '', -- Only tax year the link opens, empty for every year
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user_id ON share_links(user_id);
+CREATE INDEX IF NOT EXISTS idx_share_links_expires_at ON share_links(expires_at);
@@ -0,0 +1,7 @@
+-- 000032_create_households.down.sql
+DROP INDEX IF EXISTS idx_household_invitations_invitee_email;
+DROP INDEX IF EXISTS idx_household_invitations_inviter_id;
+DROP TABLE IF EXISTS household_invitations;
+DROP INDEX IF EXISTS idx_household_members_household_id;
+DROP TABLE IF EXISTS household_members;
+DROP TABLE IF EXISTS households;
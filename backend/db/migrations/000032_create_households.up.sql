@@ -0,0 +1,30 @@
+-- 000032_create_households.up.sql
+-- Users who file jointly can group their accounts in a household to see combined reports. A user
+-- joins one only by accepting an invitation sent to their email; leaving dissolves the household
+-- once a single member would remain.
+CREATE TABLE IF NOT EXISTS households (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS household_members (
+    household_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL UNIQUE, -- A user belongs to at most one household
+    joined_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(household_id) REFERENCES households(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_household_members_household_id ON household_members(household_id);
+
+CREATE TABLE IF NOT EXISTS household_invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inviter_id INTEGER NOT NULL,
+    invitee_email TEXT NOT NULL, -- Lowercased
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(inviter_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_household_invitations_inviter_id ON household_invitations(inviter_id);
+CREATE INDEX IF NOT EXISTS idx_household_invitations_invitee_email ON household_invitations(invitee_email);
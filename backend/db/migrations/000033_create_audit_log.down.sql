@@ -0,0 +1,4 @@
+-- 000033_create_audit_log.down.sql
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_user_id;
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,14 @@
+-- 000034_split_degiro_order_commissions.down.sql
+-- Gives each DeGiro account statement fill the commission of its whole order again, scaling its share
+-- back up by the quantity of the order.
+UPDATE processed_transactions
+SET commission = commission * (
+    SELECT SUM(o.quantity) FROM processed_transactions o
+    WHERE o.user_id = processed_transactions.user_id AND o.order_id = processed_transactions.order_id
+      AND o.source = 'degiro' AND o.transaction_type IN ('STOCK', 'OPTION')
+) / quantity
+WHERE source = 'degiro' AND transaction_type IN ('STOCK', 'OPTION') AND order_id <> ''
+  AND commission > 0 AND quantity > 0 AND input_string NOT LIKE 'DeGiroTrade|%'
+  AND (SELECT COUNT(*) FROM processed_transactions o
+       WHERE o.user_id = processed_transactions.user_id AND o.order_id = processed_transactions.order_id
+         AND o.source = 'degiro' AND o.transaction_type IN ('STOCK', 'OPTION')) > 1;
@@ -0,0 +1,25 @@
+-- 000034_split_degiro_order_commissions.up.sql
+-- DeGiro account statement trades of an order executed in several partial fills each carried the
+-- whole commission of the order. Share it out among the fills by quantity, as the parser now does,
+-- counting the fills replaced by rows of the trades export, which carry their own costs.
+UPDATE users SET data_version = data_version + 1
+WHERE id IN (
+    SELECT t.user_id FROM processed_transactions t
+    WHERE t.source = 'degiro' AND t.transaction_type IN ('STOCK', 'OPTION') AND t.order_id <> ''
+      AND t.commission > 0 AND t.quantity > 0 AND t.input_string NOT LIKE 'DeGiroTrade|%'
+      AND (SELECT COUNT(*) FROM processed_transactions o
+           WHERE o.user_id = t.user_id AND o.order_id = t.order_id AND o.source = 'degiro'
+             AND o.transaction_type IN ('STOCK', 'OPTION')) > 1
+);
+
+UPDATE processed_transactions
+SET commission = commission * quantity / (
+    SELECT SUM(o.quantity) FROM processed_transactions o
+    WHERE o.user_id = processed_transactions.user_id AND o.order_id = processed_transactions.order_id
+      AND o.source = 'degiro' AND o.transaction_type IN ('STOCK', 'OPTION')
+)
+WHERE source = 'degiro' AND transaction_type IN ('STOCK', 'OPTION') AND order_id <> ''
+  AND commission > 0 AND quantity > 0 AND input_string NOT LIKE 'DeGiroTrade|%'
+  AND (SELECT COUNT(*) FROM processed_transactions o
+       WHERE o.user_id = processed_transactions.user_id AND o.order_id = processed_transactions.order_id
+         AND o.source = 'degiro' AND o.transaction_type IN ('STOCK', 'OPTION')) > 1;
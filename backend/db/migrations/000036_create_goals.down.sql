@@ -0,0 +1,3 @@
+-- 000036_create_goals.down.sql
+DROP INDEX IF EXISTS idx_goals_user_id;
+DROP TABLE IF EXISTS goals;
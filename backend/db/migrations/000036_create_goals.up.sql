@@ -0,0 +1,15 @@
+-- 000036_create_goals.up.sql
+-- Investment goals: a portfolio value to reach, by target_date when set, or an amount to contribute
+-- every month. Amounts are in the user's base currency.
+CREATE TABLE IF NOT EXISTS goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL, -- portfolio_value or monthly_contribution
+    target_amount REAL NOT NULL,
+    target_date TEXT NOT NULL DEFAULT '', -- YYYY-MM-DD, portfolio_value only
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);
@@ -0,0 +1,2 @@
+-- 000037_add_report_prefix_hash.down.sql
+ALTER TABLE report_versions DROP COLUMN prefix_hash;
@@ -0,0 +1,5 @@
+-- 000039_hash_account_unlock_tokens.up.sql
+-- Account unlock tokens are stored as their SHA-256 hash, like share link tokens. Plain tokens issued
+-- before cannot be hashed here and are dropped: those accounts unlock when their lock runs out.
+UPDATE users SET account_unlock_token = NULL, account_unlock_token_expires_at = NULL;
+ALTER TABLE users RENAME COLUMN account_unlock_token TO account_unlock_token_hash;
@@ -0,0 +1,24 @@
+-- 000025_initial_schema.down.sql
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS unknown_descriptions;
+DROP TABLE IF EXISTS user_settings;
+DROP TABLE IF EXISTS upload_batches;
+DROP TABLE IF EXISTS announcements;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS plans;
+DROP TABLE IF EXISTS upload_usage;
+DROP TABLE IF EXISTS transaction_notes;
+DROP TABLE IF EXISTS transaction_tags;
+DROP TABLE IF EXISTS csv_mappings;
+DROP TABLE IF EXISTS report_open_lots;
+DROP TABLE IF EXISTS report_stock_holdings;
+DROP TABLE IF EXISTS report_stock_sales;
+DROP TABLE IF EXISTS report_versions;
+DROP TABLE IF EXISTS broker_connections;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS skipped_transactions;
+DROP TABLE IF EXISTS daily_prices;
+DROP TABLE IF EXISTS isin_ticker_map;
+DROP TABLE IF EXISTS processed_transactions;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
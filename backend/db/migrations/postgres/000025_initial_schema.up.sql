@@ -0,0 +1,332 @@
+-- 000025_initial_schema.up.sql
+-- PostgreSQL schema, equivalent to the SQLite migrations up to 000025 that it starts from. Each later
+-- migration in db/migrations needs a counterpart here, with the same number.
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    auth_provider TEXT DEFAULT 'local',
+    is_email_verified BOOLEAN DEFAULT FALSE,
+    email_verification_token TEXT,
+    email_verification_token_expires_at TIMESTAMPTZ,
+    password_reset_token TEXT,
+    password_reset_token_expires_at TIMESTAMPTZ,
+    upload_count INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    account_unlock_token TEXT,
+    account_unlock_token_expires_at TIMESTAMPTZ,
+    plan TEXT NOT NULL DEFAULT 'free',
+    data_version BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    user_agent TEXT,
+    client_ip TEXT,
+    is_blocked BOOLEAN DEFAULT FALSE,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS processed_transactions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    date TEXT NOT NULL,
+    source TEXT NOT NULL,
+    product_name TEXT NOT NULL,
+    isin TEXT,
+    quantity BIGINT,
+    original_quantity BIGINT,
+    price DOUBLE PRECISION,
+    transaction_type TEXT,
+    transaction_subtype TEXT,
+    buy_sell TEXT,
+    description TEXT,
+    amount DOUBLE PRECISION,
+    currency TEXT,
+    commission DOUBLE PRECISION,
+    order_id TEXT,
+    exchange_rate DOUBLE PRECISION,
+    exchange_rate_date TEXT NOT NULL DEFAULT '',
+    amount_eur DOUBLE PRECISION,
+    country_code TEXT,
+    input_string TEXT,
+    hash_id TEXT,
+    broker_balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    broker_balance_currency TEXT NOT NULL DEFAULT '',
+    UNIQUE(user_id, hash_id)
+);
+
+CREATE TABLE IF NOT EXISTS isin_ticker_map (
+    isin TEXT PRIMARY KEY NOT NULL,
+    ticker_symbol TEXT NOT NULL,
+    exchange TEXT,
+    currency TEXT NOT NULL,
+    company_name TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_checked_at TIMESTAMPTZ,
+    quote_type TEXT
+);
+
+CREATE TABLE IF NOT EXISTS daily_prices (
+    ticker_symbol TEXT NOT NULL,
+    date TEXT NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    currency TEXT NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ticker_symbol, date)
+);
+
+CREATE TABLE IF NOT EXISTS skipped_transactions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    source TEXT NOT NULL,
+    raw_text TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, source, raw_text)
+);
+
+CREATE INDEX IF NOT EXISTS idx_skipped_transactions_user_id ON skipped_transactions(user_id);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    provider TEXT NOT NULL,
+    provider_user_id TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, provider_user_id),
+    UNIQUE(user_id, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS broker_connections (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    broker TEXT NOT NULL,
+    encrypted_token TEXT NOT NULL,
+    query_id TEXT NOT NULL,
+    last_sync_at TIMESTAMPTZ,
+    last_sync_status TEXT,
+    last_sync_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, broker)
+);
+
+CREATE TABLE IF NOT EXISTS report_versions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id),
+    data_version TEXT NOT NULL,
+    holding_years TEXT NOT NULL DEFAULT '',
+    computed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_transaction_id BIGINT NOT NULL DEFAULT 0,
+    transaction_count BIGINT NOT NULL DEFAULT 0,
+    fifo_last_date TEXT NOT NULL DEFAULT '',
+    fifo_last_year INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS report_stock_sales (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    data_version TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    sale_date TEXT NOT NULL,
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    isin TEXT,
+    quantity BIGINT NOT NULL,
+    sale_price DOUBLE PRECISION,
+    sale_amount DOUBLE PRECISION,
+    sale_currency TEXT,
+    sale_amount_eur DOUBLE PRECISION,
+    sale_exchange_rate DOUBLE PRECISION,
+    buy_price DOUBLE PRECISION,
+    buy_amount DOUBLE PRECISION,
+    buy_currency TEXT,
+    buy_amount_eur DOUBLE PRECISION,
+    buy_exchange_rate DOUBLE PRECISION,
+    commission DOUBLE PRECISION,
+    transaction_tax DOUBLE PRECISION,
+    delta DOUBLE PRECISION,
+    country_code TEXT,
+    tax_year TEXT NOT NULL DEFAULT '',
+    sale_exchange_rate_date TEXT NOT NULL DEFAULT '',
+    buy_exchange_rate_date TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_stock_sales_user_version ON report_stock_sales(user_id, data_version);
+
+CREATE TABLE IF NOT EXISTS report_stock_holdings (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    data_version TEXT NOT NULL,
+    year TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    isin TEXT,
+    quantity BIGINT NOT NULL,
+    buy_price DOUBLE PRECISION,
+    buy_amount DOUBLE PRECISION,
+    buy_currency TEXT,
+    buy_amount_eur DOUBLE PRECISION
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_stock_holdings_user_version ON report_stock_holdings(user_id, data_version, year);
+
+CREATE TABLE IF NOT EXISTS report_open_lots (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    data_version TEXT NOT NULL,
+    isin TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    buy_date TEXT NOT NULL,
+    product_name TEXT,
+    quantity BIGINT NOT NULL,
+    original_quantity BIGINT NOT NULL,
+    price DOUBLE PRECISION,
+    amount DOUBLE PRECISION,
+    amount_eur DOUBLE PRECISION,
+    currency TEXT,
+    exchange_rate DOUBLE PRECISION,
+    commission DOUBLE PRECISION,
+    transaction_tax DOUBLE PRECISION,
+    exchange_rate_date TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_open_lots_user_version ON report_open_lots(user_id, data_version, isin);
+
+CREATE TABLE IF NOT EXISTS csv_mappings (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    mapping TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id)
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tags (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    transaction_id BIGINT NOT NULL REFERENCES processed_transactions(id),
+    tag TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(transaction_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_tags_user_tag ON transaction_tags(user_id, tag);
+
+CREATE TABLE IF NOT EXISTS transaction_notes (
+    transaction_id BIGINT PRIMARY KEY REFERENCES processed_transactions(id),
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    note TEXT NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_notes_user_id ON transaction_notes(user_id);
+
+CREATE TABLE IF NOT EXISTS upload_usage (
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    month TEXT NOT NULL, -- YYYY-MM
+    uploads INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, month)
+);
+
+CREATE TABLE IF NOT EXISTS plans (
+    name TEXT PRIMARY KEY,
+    display_name TEXT NOT NULL,
+    premium BOOLEAN NOT NULL DEFAULT FALSE,
+    stripe_price_id TEXT UNIQUE
+);
+
+INSERT INTO plans (name, display_name, premium) VALUES ('free', 'Free', FALSE), ('premium', 'Premium', TRUE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    provider TEXT NOT NULL DEFAULT 'stripe',
+    customer_id TEXT NOT NULL,
+    subscription_id TEXT,
+    plan TEXT,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_customer ON subscriptions (provider, customer_id);
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    message TEXT NOT NULL,
+    level TEXT NOT NULL DEFAULT 'info',
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_ends_at ON announcements (ends_at);
+
+CREATE TABLE IF NOT EXISTS upload_batches (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    source TEXT NOT NULL,
+    idempotency_key TEXT,
+    status TEXT NOT NULL DEFAULT 'processing',
+    rows_imported INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    filename TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_batches_user_created ON upload_batches(user_id, created_at DESC);
+
+-- The settings stay a JSON document in text, as in SQLite; it is updated through jsonb.
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id),
+    settings TEXT NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS unknown_descriptions (
+    source TEXT NOT NULL,
+    pattern_hash TEXT NOT NULL,
+    sample TEXT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 0,
+    first_seen TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, pattern_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_unknown_descriptions_occurrences ON unknown_descriptions(occurrences DESC);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    kind TEXT NOT NULL,
+    to_email TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next_attempt ON outbox(status, next_attempt_at);
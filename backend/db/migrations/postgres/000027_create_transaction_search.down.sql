@@ -0,0 +1,2 @@
+-- 000027_create_transaction_search.down.sql
+DROP INDEX IF EXISTS idx_processed_transactions_search;
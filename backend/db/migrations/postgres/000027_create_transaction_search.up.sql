@@ -0,0 +1,6 @@
+-- 000027_create_transaction_search.up.sql
+-- Full-text index of the fields GET /search looks in. The expression must match the one of the
+-- search query in the model package for the index to be used.
+CREATE INDEX IF NOT EXISTS idx_processed_transactions_search ON processed_transactions USING GIN (
+    to_tsvector('simple', COALESCE(product_name, '') || ' ' || COALESCE(isin, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(order_id, ''))
+);
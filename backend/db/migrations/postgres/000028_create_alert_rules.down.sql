@@ -0,0 +1,3 @@
+-- 000028_create_alert_rules.down.sql
+DROP INDEX IF EXISTS idx_alert_rules_user_id;
+DROP TABLE IF EXISTS alert_rules;
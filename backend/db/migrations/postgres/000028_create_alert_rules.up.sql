@@ -0,0 +1,17 @@
+-- 000028_create_alert_rules.up.sql
+-- Conditions users ask to be emailed about, checked by a background job. triggered_key identifies
+-- the occurrence last notified, so the same one is not emailed again; it is cleared once the
+-- condition no longer holds.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    kind TEXT NOT NULL, -- below_cost_basis, monthly_dividends_above or unmatched_sell
+    isin TEXT NOT NULL DEFAULT '',
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    triggered_key TEXT NOT NULL DEFAULT '',
+    last_triggered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules(user_id);
@@ -0,0 +1,6 @@
+-- 000029_create_webhooks.down.sql
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_status_next_attempt;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhooks_user_id;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,3 @@
+-- 000030_add_calendar_feed.down.sql
+ALTER TABLE users DROP COLUMN calendar_feed_enabled;
+ALTER TABLE users DROP COLUMN calendar_token_version;
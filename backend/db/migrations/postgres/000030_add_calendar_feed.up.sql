@@ -0,0 +1,5 @@
+-- 000030_add_calendar_feed.up.sql
+-- The calendar feed is read with a token signed for the user and calendar_token_version. Issuing a new
+-- token or turning the feed off increments the version, which invalidates the tokens handed out before.
+ALTER TABLE users ADD COLUMN calendar_token_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN calendar_feed_enabled BOOLEAN NOT NULL DEFAULT FALSE;
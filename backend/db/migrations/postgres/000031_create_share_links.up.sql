@@ -0,0 +1,17 @@
+-- 000031_create_share_links.up.sql
+-- Expiring links users hand out for read-only access to some of their reports. Only the SHA-256 of
+-- the token is stored: the token itself is shown once, when the link is created.
+CREATE TABLE IF NOT EXISTS share_links (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    token_hash TEXT NOT NULL UNIQUE,
+    label TEXT NOT NULL DEFAULT '',
+    scopes TEXT NOT NULL, -- Comma-separated report scopes
+    year TEXT NOT NULL DEFAULT '', -- Only tax year the link opens, empty for every year
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user_id ON share_links(user_id);
+CREATE INDEX IF NOT EXISTS idx_share_links_expires_at ON share_links(expires_at);
@@ -0,0 +1,27 @@
+-- 000032_create_households.up.sql
+-- Users who file jointly can group their accounts in a household to see combined reports. A user
+-- joins one only by accepting an invitation sent to their email; leaving dissolves the household
+-- once a single member would remain.
+CREATE TABLE IF NOT EXISTS households (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS household_members (
+    household_id BIGINT NOT NULL REFERENCES households(id),
+    user_id BIGINT NOT NULL UNIQUE REFERENCES users(id), -- A user belongs to at most one household
+    joined_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_household_members_household_id ON household_members(household_id);
+
+CREATE TABLE IF NOT EXISTS household_invitations (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    inviter_id BIGINT NOT NULL REFERENCES users(id),
+    invitee_email TEXT NOT NULL, -- Lowercased
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_household_invitations_inviter_id ON household_invitations(inviter_id);
+CREATE INDEX IF NOT EXISTS idx_household_invitations_invitee_email ON household_invitations(invitee_email);
@@ -0,0 +1,15 @@
+-- 000033_create_audit_log.up.sql
+-- Security-relevant actions on an account, with the IP address and user agent they came from, for the
+-- user to review their account activity. Rows are kept for a year.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    action TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '', -- JSON object with the action's details, empty when it has none
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,3 @@
+-- 000035_add_transaction_executed_at.down.sql
+DROP INDEX IF EXISTS idx_processed_transactions_user_executed_at;
+ALTER TABLE processed_transactions DROP COLUMN executed_at;
@@ -0,0 +1,11 @@
+-- 000035_add_transaction_executed_at.up.sql
+-- When each transaction was executed, in UTC. date keeps the DD-MM-YYYY day the broker reports, which
+-- does not sort as text; transactions are ordered by executed_at instead. Stored transactions carry no
+-- time of day, so they are given midnight UTC of their date.
+ALTER TABLE processed_transactions ADD COLUMN executed_at TIMESTAMPTZ;
+
+UPDATE processed_transactions
+SET executed_at = to_date(date, 'DD-MM-YYYY')::timestamp AT TIME ZONE 'UTC'
+WHERE date ~ '^[0-9]{2}-[0-9]{2}-[0-9]{4}$';
+
+CREATE INDEX IF NOT EXISTS idx_processed_transactions_user_executed_at ON processed_transactions(user_id, executed_at);
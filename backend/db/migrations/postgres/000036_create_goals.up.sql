@@ -0,0 +1,14 @@
+-- 000036_create_goals.up.sql
+-- Investment goals: a portfolio value to reach, by target_date when set, or an amount to contribute
+-- every month. Amounts are in the user's base currency.
+CREATE TABLE IF NOT EXISTS goals (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL, -- portfolio_value or monthly_contribution
+    target_amount DOUBLE PRECISION NOT NULL,
+    target_date TEXT NOT NULL DEFAULT '', -- YYYY-MM-DD, portfolio_value only
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);
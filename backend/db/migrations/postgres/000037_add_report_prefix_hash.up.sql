@@ -0,0 +1,5 @@
+-- 000037_add_report_prefix_hash.up.sql
+-- Hash of the id and hash_id of the transactions a materialized report was computed from, so that a
+-- report is only resumed while none of them was replaced in place. Reports saved before have none and
+-- are recomputed in full.
+ALTER TABLE report_versions ADD COLUMN prefix_hash TEXT NOT NULL DEFAULT '';
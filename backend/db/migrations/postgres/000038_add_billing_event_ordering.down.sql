@@ -0,0 +1,3 @@
+-- 000038_add_billing_event_ordering.down.sql
+DROP TABLE IF EXISTS billing_events;
+ALTER TABLE subscriptions DROP COLUMN last_event_created;
@@ -0,0 +1,15 @@
+-- 000038_add_billing_event_ordering.up.sql
+-- Webhook events already applied, so a redelivered event is skipped, and the creation time (unix
+-- seconds) of the last event applied to each subscription, so an event delivered out of order does
+-- not undo a later one.
+CREATE TABLE IF NOT EXISTS billing_events (
+    provider TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    processed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_events_processed_at ON billing_events (processed_at);
+
+ALTER TABLE subscriptions ADD COLUMN last_event_created BIGINT NOT NULL DEFAULT 0;
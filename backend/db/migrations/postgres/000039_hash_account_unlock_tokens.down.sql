@@ -0,0 +1,4 @@
+-- 000039_hash_account_unlock_tokens.down.sql
+-- Hashed tokens cannot be turned back into the emailed ones: unlock links sent before are dropped.
+UPDATE users SET account_unlock_token_hash = NULL, account_unlock_token_expires_at = NULL;
+ALTER TABLE users RENAME COLUMN account_unlock_token_hash TO account_unlock_token;
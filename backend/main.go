@@ -12,14 +12,20 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/patrickmn/go-cache"
+	"github.com/username/taxfolio/backend/src/cache"
 	"github.com/username/taxfolio/backend/src/config"
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/handlers"
 	"github.com/username/taxfolio/backend/src/logger"
-	_ "github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/metrics"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/parsers/ibkr"
 	"github.com/username/taxfolio/backend/src/processors"
 	"github.com/username/taxfolio/backend/src/security"
+	"github.com/username/taxfolio/backend/src/security/password"
+	"github.com/username/taxfolio/backend/src/security/validation"
 	"github.com/username/taxfolio/backend/src/services"
 	"github.com/username/taxfolio/backend/src/utils"
 	"golang.org/x/time/rate"
@@ -30,7 +36,9 @@ import (
 // for security features (like Secure cookies) to work correctly behind a reverse proxy.
 func proxyHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("X-Forwarded-Proto") == "https" {
+		// With several proxies the header lists one scheme per hop; the first is the client's.
+		proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+		if strings.EqualFold(strings.TrimSpace(proto), "https") {
 			r.URL.Scheme = "https"
 			r.TLS = &tls.ConnectionState{}
 		}
@@ -43,7 +51,7 @@ var limiter = rate.NewLimiter(rate.Every(100*time.Millisecond), 30)
 func rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !limiter.Allow() {
-			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			utils.SendJSONError(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 			logger.L.Warn("Rate limit exceeded",
 				"method", r.Method,
 				"path", r.URL.Path,
@@ -54,15 +62,35 @@ func rateLimitMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// isOriginAllowed checks an Origin header against the configured origins.
+// An entry like "https://*.example.com" matches any subdomain of example.com over https,
+// but not example.com itself.
+func isOriginAllowed(origin string, allowedOrigins []string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == origin {
+			return true
+		}
+		scheme, host, found := strings.Cut(allowed, "://*.")
+		if !found {
+			continue
+		}
+		prefix := scheme + "://"
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, "."+host) &&
+			len(origin) > len(prefix)+len(host)+1 {
+			return true
+		}
+	}
+	return false
+}
+
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		allowedOrigins := map[string]bool{
-			"http://localhost:3000":      true,
-			"https://visorfinanceiro.pt": true,
-		}
 
-		if allowedOrigins[origin] {
+		if isOriginAllowed(origin, config.Cfg.AllowedOrigins) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
@@ -81,6 +109,31 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// backupConfig says where database backups go. The bucket is reached at the AWS S3 endpoint of its
+// region unless BACKUP_S3_ENDPOINT names another S3-compatible store.
+func backupConfig() services.BackupConfig {
+	backup := services.BackupConfig{Dir: config.Cfg.BackupDir, Keep: config.Cfg.BackupKeep}
+	if config.Cfg.BackupS3Bucket == "" {
+		return backup
+	}
+	endpoint := config.Cfg.BackupS3Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + config.Cfg.BackupS3Region + ".amazonaws.com"
+	}
+	backup.S3 = &services.S3Config{
+		Endpoint:        endpoint,
+		Region:          config.Cfg.BackupS3Region,
+		Bucket:          config.Cfg.BackupS3Bucket,
+		Prefix:          config.Cfg.BackupS3Prefix,
+		AccessKeyID:     config.Cfg.BackupS3AccessKeyID,
+		SecretAccessKey: config.Cfg.BackupS3SecretAccessKey,
+	}
+	if backup.S3.AccessKeyID == config.Cfg.AWSAccessKeyID {
+		backup.S3.SessionToken = config.Cfg.AWSSessionToken // Temporary AWS credentials come with one
+	}
+	return backup
+}
+
 func main() {
 	config.LoadConfig()
 	logger.InitLogger(config.Cfg.LogLevel)
@@ -95,69 +148,207 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := utils.SetRoundingMode(config.Cfg.RoundingMode); err != nil {
+		logger.L.Error("ROUNDING_MODE configuration invalid.", "error", err)
+		os.Exit(1)
+	}
+	if err := ibkr.SetTimeZone(config.Cfg.IBKRTimeZone); err != nil {
+		logger.L.Error("IBKR_TIMEZONE configuration invalid.", "error", err)
+		os.Exit(1)
+	}
+
 	logger.L.Info("Initializing data loaders...")
 	if err := utils.InitCountryData(config.Cfg.CountryDataPath); err != nil {
 		logger.L.Error("Failed to load country data", "error", err)
 	}
 
 	logger.L.Info("Initializing database...", "path", config.Cfg.DatabasePath)
-	database.InitDB(config.Cfg.DatabasePath)
-	database.RunMigrations(config.Cfg.DatabasePath)
+	database.InitDB(config.Cfg.DatabasePath, config.Cfg.DatabaseURL, database.Settings{
+		BusyTimeout:     config.Cfg.DBBusyTimeout,
+		MaxOpenConns:    config.Cfg.DBMaxOpenConns,
+		MaxIdleConns:    config.Cfg.DBMaxIdleConns,
+		ConnMaxLifetime: config.Cfg.DBConnMaxLifetime,
+		QueryTimeout:    config.Cfg.DBQueryTimeout,
+	})
+	database.RunMigrations(config.Cfg.DatabasePath, config.Cfg.DatabaseURL)
 	logger.L.Info("Database initialized successfully.")
 
-	logger.L.Info("Initializing report cache...")
-	reportCache := cache.New(services.DefaultCacheExpiration, services.CacheCleanupInterval)
+	maintenanceService := services.NewMaintenanceService(database.DB, backupConfig())
+	maintenanceService.StartScheduler(config.Cfg.MaintenanceInterval)
+	maintenanceService.StartBackupScheduler(config.Cfg.BackupInterval)
+
+	// Secrets stored with a previous key, or before they were encrypted, are encrypted with the current
+	// key before any request needs them.
+	keyring := security.NewKeyring(config.Cfg.CredentialsEncryptionKeyID, config.Cfg.CredentialsEncryptionKey, config.Cfg.CredentialsPreviousKeys)
+	encryptionService := services.NewEncryptionService(database.DB, keyring)
+	if _, err := encryptionService.ReencryptAll(); err != nil {
+		logger.L.Error("Failed to re-encrypt stored secrets", "error", err)
+	}
+
+	logger.L.Info("Initializing report cache...", "backend", config.Cfg.CacheBackend)
+	reportCache, err := cache.New(config.Cfg.CacheBackend, config.Cfg.RedisURL, services.DefaultCacheExpiration, services.CacheCleanupInterval)
+	if err != nil {
+		logger.L.Error("Report cache configuration invalid.", "error", err)
+		os.Exit(1)
+	}
 	logger.L.Info("Report cache initialized.")
 
 	logger.L.Info("Initializing services and handlers...")
 	handlers.InitializeGoogleOAuthConfig()
 	authService := security.NewAuthService(config.Cfg.JWTSecret)
-	emailService := services.NewEmailService()
-	userHandler := handlers.NewUserHandler(authService, emailService)
+	emailService := services.NewEmailService(database.DB)
+	emailService.StartOutboxWorker(config.Cfg.EmailOutboxInterval)
+	integrityService := services.NewIntegrityService(database.DB, emailService, config.Cfg.OperatorAlertEmail)
+	integrityService.StartScheduler(config.Cfg.IntegrityCheckInterval)
+	passwordPolicy := password.Policy{MinLength: config.Cfg.PasswordMinLength, MinStrength: config.Cfg.PasswordMinStrength}
+	if config.Cfg.PasswordBreachCheck {
+		passwordPolicy.Breaches = password.NewHIBPClient()
+	}
+	transactionRepository := model.NewTransactionRepository(database.DB)
+	userHandler := handlers.NewUserHandler(authService, emailService, transactionRepository, passwordPolicy, keyring)
 
 	// Instantiate the new price service
 	priceService := services.NewPriceService()
 
-	transactionProcessor := processors.NewTransactionProcessor()
+	transactionProcessor := processors.NewTransactionProcessor(config.Cfg.UploadWorkers)
 	dividendProcessor := processors.NewDividendProcessor()
 	stockProcessor := processors.NewStockProcessor()
 	optionProcessor := processors.NewOptionProcessor()
 	cashMovementProcessor := processors.NewCashMovementProcessor()
 	feeProcessor := processors.NewFeeProcessor()
+	deemedDisposalProcessor := processors.NewDeemedDisposalProcessor()
+	coveredCallProcessor := processors.NewCoveredCallProcessor()
+	bondProcessor := processors.NewBondProcessor()
+	cashBalanceProcessor := processors.NewCashBalanceProcessor()
+	contributionProcessor := processors.NewContributionProcessor()
 
+	quotaService := services.NewQuotaService(database.DB)
+	webhookService := services.NewWebhookService(database.DB, keyring, config.Cfg.WebhookAllowPrivateURLs)
+	webhookService.StartDeliveryWorker(config.Cfg.WebhookDeliveryInterval)
 	uploadService := services.NewUploadService(
+		transactionRepository,
 		transactionProcessor,
 		dividendProcessor,
 		stockProcessor,
 		optionProcessor,
 		cashMovementProcessor,
 		feeProcessor,
+		coveredCallProcessor,
 		reportCache,
+		emailService,
+		quotaService,
+		webhookService,
+		float64(config.Cfg.LargeCashMovementThreshold),
+		parsers.Limits{
+			MaxRows:      config.Cfg.MaxUploadRows,
+			MaxParseTime: config.Cfg.MaxParseTime,
+			MaxMemory:    config.Cfg.MaxParseMemory,
+		},
+		config.Cfg.UploadBatchSize,
+	)
+
+	billingService := services.NewBillingService(
+		database.DB,
+		quotaService,
+		config.Cfg.StripeSecretKey,
+		config.Cfg.StripeWebhookSecret,
+		config.Cfg.BillingSuccessURL,
+		config.Cfg.BillingCancelURL,
 	)
+	billingHandler := handlers.NewBillingHandler(billingService)
+	requirePremium := handlers.RequirePremium(billingService)
+	etag := handlers.DataETagMiddleware(uploadService)
 
-	uploadHandler := handlers.NewUploadHandler(uploadService)
+	// Uploaded files are scanned before parsing by the scanners UPLOAD_SCANNERS lists, in that order.
+	var uploadScanners validation.Scanners
+	for _, name := range config.Cfg.UploadScanners {
+		switch name {
+		case "heuristic":
+			uploadScanners = append(uploadScanners, validation.HeuristicScanner{})
+		case "clamav":
+			uploadScanners = append(uploadScanners, validation.NewClamAVScanner(config.Cfg.ClamAVAddress, config.Cfg.UploadScanTimeout))
+		default:
+			logger.L.Error("UPLOAD_SCANNERS configuration invalid. Use heuristic and/or clamav.", "scanner", name)
+			os.Exit(1)
+		}
+	}
+	var uploadScanner validation.FileScanner
+	if len(uploadScanners) > 0 {
+		uploadScanner = uploadScanners
+	}
+	uploadHandler := handlers.NewUploadHandler(uploadService, billingService, uploadScanner, config.Cfg.UploadScanFailOpen)
 	// Pass both services to the PortfolioHandler constructor
-	portfolioHandler := handlers.NewPortfolioHandler(uploadService, priceService)
-	dividendHandler := handlers.NewDividendHandler(uploadService)
-	txHandler := handlers.NewTransactionHandler(uploadService)
+	transactionTagService := services.NewTransactionTagService(database.DB)
+	portfolioHandler := handlers.NewPortfolioHandler(uploadService, priceService, transactionTagService)
+	reportService := services.NewReportService(database.DB, transactionRepository, uploadService)
+	householdService := services.NewHouseholdService(database.DB, reportService, uploadService)
+	householdHandler := handlers.NewHouseholdHandler(householdService)
+	dividendCalendarService := services.NewDividendCalendarService(uploadService)
+	dividendHandler := handlers.NewDividendHandler(uploadService, dividendCalendarService, transactionTagService, householdService)
+	txHandler := handlers.NewTransactionHandler(transactionRepository, uploadService, transactionTagService)
+	settingsService := services.NewSettingsService(database.DB, uploadService)
+	settingsHandler := handlers.NewSettingsHandler(uploadService, settingsService)
 	feeHandler := handlers.NewFeeHandler(uploadService)
+	v2Handler := handlers.NewV2Handler(transactionRepository, uploadService, transactionTagService)
+	performanceService := services.NewPerformanceService(transactionRepository, stockProcessor, cashMovementProcessor, priceService, config.Cfg.BenchmarkISIN)
+	performanceHandler := handlers.NewPerformanceHandler(performanceService)
+	dataQualityService := services.NewDataQualityService(transactionRepository, stockProcessor)
+	dataQualityHandler := handlers.NewDataQualityHandler(dataQualityService)
+	unrealizedGainsService := services.NewUnrealizedGainsService(uploadService, priceService)
+	unrealizedGainsHandler := handlers.NewUnrealizedGainsHandler(unrealizedGainsService)
+	deemedDisposalService := services.NewDeemedDisposalService(database.DB, transactionRepository, deemedDisposalProcessor, uploadService, priceService)
+	deemedDisposalHandler := handlers.NewDeemedDisposalHandler(deemedDisposalService)
+	taxReportService := services.NewTaxReportService(database.DB, uploadService)
+	taxReportHandler := handlers.NewTaxReportHandler(taxReportService)
+	reportHandler := handlers.NewReportHandler(reportService, householdService)
+	bondService := services.NewBondService(transactionRepository, bondProcessor)
+	bondHandler := handlers.NewBondHandler(bondService)
+	cashBalanceService := services.NewCashBalanceService(database.DB, transactionRepository, cashBalanceProcessor, cashMovementProcessor, contributionProcessor)
+	cashBalanceHandler := handlers.NewCashBalanceHandler(cashBalanceService)
+	recalculationService := services.NewRecalculationService(database.DB, transactionRepository, uploadService)
+	recalculationHandler := handlers.NewRecalculationHandler(recalculationService)
+	ibkrFlexService := services.NewIBKRFlexService(database.DB, uploadService, keyring)
+	ibkrFlexService.StartScheduler(config.Cfg.IBKRFlexSyncInterval)
+	ibkrFlexHandler := handlers.NewIBKRFlexHandler(ibkrFlexService)
+	alertService := services.NewAlertService(database.DB, transactionRepository, uploadService, unrealizedGainsService, dataQualityService, emailService)
+	alertService.StartScheduler(config.Cfg.AlertCheckInterval)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	goalService := services.NewGoalService(database.DB, unrealizedGainsService, cashBalanceService)
+	goalHandler := handlers.NewGoalHandler(goalService)
+	services.StartPriceRefresh(config.Cfg.PriceRefreshInterval, webhookService, unrealizedGainsService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	calendarService := services.NewCalendarService(database.DB, config.Cfg.JWTSecret, config.Cfg.APIBaseURL, dividendCalendarService, uploadService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	shareLinkService := services.NewShareLinkService(database.DB)
+	shareLinkHandler := handlers.NewShareLinkHandler(shareLinkService)
+	usageHandler := handlers.NewUsageHandler(quotaService)
+	statusService := services.NewStatusService(database.DB)
+	statusHandler := handlers.NewStatusHandler(statusService)
+	adminHandler := handlers.NewAdminHandler(maintenanceService, quotaService, billingService, statusService, uploadService, emailService, encryptionService)
 
 	logger.L.Info("Configuring routes...")
 	r := chi.NewRouter()
+	openAPIHandler := handlers.NewOpenAPIHandler(r, handlers.APIVersion1)
+	openAPIV2Handler := handlers.NewOpenAPIHandler(r, handlers.APIVersion2)
 
 	// Global middleware
+	r.Use(middleware.RequestID)
+	r.Use(handlers.RequestLoggerMiddleware)
 	r.Use(middleware.Recoverer)
+	r.Use(metrics.Middleware)
 	r.Use(proxyHeadersMiddleware)
 	r.Use(enableCORS)
 	r.Use(rateLimitMiddleware)
+	r.Use(handlers.CompressMiddleware(config.Cfg.CompressMinSize))
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"message": "VisorFinanceiro Backend is running"})
 	})
+	r.Handle("/metrics", metrics.Handler(config.Cfg.MetricsToken))
 
-	// API routes
-	r.Route("/api", func(r chi.Router) {
+	// API routes, version 1
+	handlers.MountAPIVersion(r, handlers.APIVersion1, func(r chi.Router) {
 		// Public auth routes
 		r.Group(func(r chi.Router) {
 			r.Get("/auth/csrf", handlers.GetCSRFToken)
@@ -166,37 +357,175 @@ func main() {
 			r.Get("/auth/google/callback", userHandler.HandleGoogleCallback)
 		})
 
+		// Service status and announcements, polled by the frontend
+		r.Get("/status", statusHandler.HandleGetStatus)
+
+		// Machine-readable description of these routes
+		r.Get("/openapi.json", openAPIHandler.HandleGetOpenAPISpec)
+
+		// Calendar feed, authenticated by the signed token in its URL
+		r.Get("/calendar.ics", calendarHandler.HandleGetCalendarFeed)
+
+		// Reports shared for reading, authenticated by the share link token in the path
+		r.Route("/shared/{token}", func(r chi.Router) {
+			r.Use(handlers.ShareLinkMiddleware(shareLinkService))
+			r.Use(handlers.LocaleMiddleware)
+
+			r.Get("/", shareLinkHandler.HandleGetSharedLink)
+			r.With(handlers.RequireShareScope(models.ShareScopeAnnualReport)).Get("/reports/annual", reportHandler.HandleGetAnnualReport)
+			r.With(handlers.RequireShareScope(models.ShareScopeTaxReport)).Get("/tax-report", taxReportHandler.HandleGetTaxReport)
+			r.With(handlers.RequireShareScope(models.ShareScopeDividends)).Get("/dividends/detail", dividendHandler.HandleGetDividendDetail)
+			r.With(handlers.RequireShareScope(models.ShareScopeHoldings)).Get("/holdings/stocks", portfolioHandler.HandleGetStockHoldings)
+		})
+
+		// Payment provider webhooks, authenticated by their signature
+		r.Post("/billing/webhook", billingHandler.HandleWebhook)
+
 		// Auth actions with CSRF protection
 		r.Group(func(r chi.Router) {
 			r.Use(handlers.CSRFMiddleware(config.Cfg.CSRFAuthKey))
 			r.Post("/auth/login", userHandler.LoginUserHandler)
 			r.Post("/auth/register", userHandler.RegisterUserHandler)
 			r.Post("/auth/refresh", userHandler.RefreshTokenHandler)
+			r.Post("/auth/google/exchange", userHandler.HandleGoogleExchange)
 			r.With(userHandler.AuthMiddleware).Post("/auth/logout", userHandler.LogoutUserHandler)
 			r.Post("/auth/request-password-reset", userHandler.RequestPasswordResetHandler)
 			r.Post("/auth/reset-password", userHandler.ResetPasswordHandler)
+			r.Post("/auth/unlock-account", userHandler.UnlockAccountHandler)
+		})
+
+		// Operator routes, authenticated with the admin bearer token instead of a user session
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.AdminTokenMiddleware(config.Cfg.AdminToken))
+			r.Post("/admin/maintenance/cleanup", adminHandler.HandleRunMaintenance)
+			r.Post("/admin/maintenance/backup", adminHandler.HandleRunBackup)
+			r.Post("/admin/encryption/reencrypt", adminHandler.HandleReencrypt)
+			r.Put("/admin/users/{id}/plan", adminHandler.HandleSetUserPlan)
+			r.Put("/admin/plans/{name}/price", adminHandler.HandleSetPlanPrice)
+			r.Put("/admin/announcement", adminHandler.HandleSetAnnouncement)
+			r.Delete("/admin/announcement", adminHandler.HandleClearAnnouncement)
+			r.Post("/admin/recalculate/{userID}", recalculationHandler.HandleRecalculateUser)
+			r.Get("/admin/unknown-descriptions", adminHandler.HandleGetUnknownDescriptions)
+			r.Get("/admin/outbox", adminHandler.HandleGetOutbox)
+			r.Post("/admin/outbox/{id}/retry", adminHandler.HandleRetryOutboxEmail)
 		})
 
 		// Protected API routes with CSRF and Auth
 		r.Group(func(r chi.Router) {
 			r.Use(handlers.CSRFMiddleware(config.Cfg.CSRFAuthKey))
 			r.Use(userHandler.AuthMiddleware)
+			r.Use(handlers.LocaleMiddleware)
 
 			r.Post("/upload", uploadHandler.HandleUpload)
-			r.Get("/realizedgains-data", uploadHandler.HandleGetRealizedGainsData)
+			r.Post("/upload/preview", uploadHandler.HandleUploadPreview)
+			r.Get("/upload/csv-mapping", uploadHandler.HandleGetCSVMapping)
+			r.Put("/upload/csv-mapping", uploadHandler.HandleSaveCSVMapping)
+			r.Get("/uploads/history", uploadHandler.HandleGetUploadHistory)
+			r.With(etag).Get("/realizedgains-data", uploadHandler.HandleGetRealizedGainsData)
 			r.Get("/transactions/processed", txHandler.HandleGetProcessedTransactions)
+			r.Get("/transactions/skipped", txHandler.HandleGetSkippedTransactions)
+			r.Post("/transactions/skipped/reprocess", txHandler.HandleReprocessSkippedTransactions)
+			r.Post("/transactions/skipped/{id}/manual", txHandler.HandleImportSkippedTransactionManually)
+			r.Get("/transactions/tags", txHandler.HandleGetTags)
+			r.Put("/transactions/{id}/tags", txHandler.HandleSetTransactionTags)
+			r.Put("/transactions/{id}/note", txHandler.HandleSetTransactionNote)
+			r.Get("/search", txHandler.HandleSearch)
 			r.Get("/holdings/current-value", portfolioHandler.HandleGetCurrentHoldingsValue)
-			r.Get("/holdings/stocks", portfolioHandler.HandleGetStockHoldings)
-			r.Get("/holdings/options", portfolioHandler.HandleGetOptionHoldings)
-			r.Get("/stock-sales", portfolioHandler.HandleGetStockSales)
-			r.Get("/option-sales", portfolioHandler.HandleGetOptionSales)
-			r.Get("/dividend-tax-summary", dividendHandler.HandleGetDividendTaxSummary)
-			r.Get("/dividend-transactions", dividendHandler.HandleGetDividendTransactions)
+			r.With(etag).Get("/holdings/stocks", portfolioHandler.HandleGetStockHoldings)
+			r.Get("/holdings/years", portfolioHandler.HandleGetHoldingYears)
+			r.With(etag).Get("/holdings/options", portfolioHandler.HandleGetOptionHoldings)
+			r.Post("/holdings/opening-lots", portfolioHandler.HandleAddOpeningLots)
+			r.Get("/holdings/cost-basis-adjustments", portfolioHandler.HandleGetCostBasisAdjustments)
+			r.Get("/positions/{isin}/timeline", portfolioHandler.HandleGetPositionTimeline)
+			r.With(etag).Get("/stock-sales", portfolioHandler.HandleGetStockSales)
+			r.With(etag).Get("/option-sales", portfolioHandler.HandleGetOptionSales)
+			r.With(etag).Get("/dividend-tax-summary", dividendHandler.HandleGetDividendTaxSummary)
+			r.With(etag).Get("/dividend-transactions", dividendHandler.HandleGetDividendTransactions)
+			r.Get("/dividends/calendar", dividendHandler.HandleGetDividendCalendar)
+			r.Get("/dividends/detail", dividendHandler.HandleGetDividendDetail)
 			r.Get("/fees", feeHandler.HandleGetFeeDetails)
+			r.With(requirePremium).Get("/performance", performanceHandler.HandleGetPerformance)
+			r.Get("/data-quality", dataQualityHandler.HandleGetDataQuality)
+			r.Get("/unrealized-gains", unrealizedGainsHandler.HandleGetUnrealizedGains)
+			r.Get("/deemed-disposals", deemedDisposalHandler.HandleGetDeemedDisposals)
+			r.Get("/tax-report", taxReportHandler.HandleGetTaxReport)
+			r.Get("/reports/annual", reportHandler.HandleGetAnnualReport)
+			r.Get("/reports/compare", reportHandler.HandleCompareYears)
+			r.Get("/bond-income", bondHandler.HandleGetBondIncome)
+			r.Get("/cash/balance", cashBalanceHandler.HandleGetCashBalance)
+			r.Get("/cash/contributions", cashBalanceHandler.HandleGetContributions)
+			r.With(requirePremium).Get("/brokers/ibkr/flex", ibkrFlexHandler.HandleGetFlexConnection)
+			r.With(requirePremium).Put("/brokers/ibkr/flex", ibkrFlexHandler.HandleSaveFlexConnection)
+			r.Delete("/brokers/ibkr/flex", ibkrFlexHandler.HandleDeleteFlexConnection)
+			r.With(requirePremium).Post("/brokers/ibkr/flex/sync", ibkrFlexHandler.HandleSyncFlexConnection)
+			r.Get("/alerts", alertHandler.HandleGetAlertRules)
+			r.Post("/alerts", alertHandler.HandleCreateAlertRule)
+			r.Put("/alerts/{id}", alertHandler.HandleUpdateAlertRule)
+			r.Delete("/alerts/{id}", alertHandler.HandleDeleteAlertRule)
+			r.Get("/goals", goalHandler.HandleGetGoals)
+			r.Post("/goals", goalHandler.HandleCreateGoal)
+			r.Put("/goals/{id}", goalHandler.HandleUpdateGoal)
+			r.Delete("/goals/{id}", goalHandler.HandleDeleteGoal)
+			r.Get("/dashboard", goalHandler.HandleGetDashboard)
+			r.Get("/webhooks", webhookHandler.HandleGetWebhooks)
+			r.Post("/webhooks", webhookHandler.HandleCreateWebhook)
+			r.Put("/webhooks/{id}", webhookHandler.HandleUpdateWebhook)
+			r.Delete("/webhooks/{id}", webhookHandler.HandleDeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", webhookHandler.HandleGetWebhookDeliveries)
+			r.Get("/share-links", shareLinkHandler.HandleGetShareLinks)
+			r.Post("/share-links", shareLinkHandler.HandleCreateShareLink)
+			r.Delete("/share-links/{id}", shareLinkHandler.HandleDeleteShareLink)
+			r.Get("/household", householdHandler.HandleGetHousehold)
+			r.Delete("/household", householdHandler.HandleLeaveHousehold)
+			r.Post("/household/invitations", householdHandler.HandleCreateHouseholdInvitation)
+			r.Post("/household/invitations/{id}/accept", householdHandler.HandleAcceptHouseholdInvitation)
+			r.Delete("/household/invitations/{id}", householdHandler.HandleDeleteHouseholdInvitation)
 			r.Delete("/transactions/all", txHandler.HandleDeleteAllProcessedTransactions)
 			r.Get("/user/has-data", userHandler.HandleCheckUserData)
+			r.Get("/user/usage", usageHandler.HandleGetUsage)
+			r.Post("/user/recalculate", recalculationHandler.HandleRecalculate)
+			r.Get("/billing/plans", billingHandler.HandleGetPlans)
+			r.Get("/billing/subscription", billingHandler.HandleGetSubscription)
+			r.Post("/billing/checkout", billingHandler.HandleCreateCheckout)
 			r.Post("/user/change-password", userHandler.ChangePasswordHandler)
 			r.Post("/user/delete-account", userHandler.DeleteAccountHandler)
+			r.Get("/user/settings", settingsHandler.HandleGetSettings)
+			r.Put("/user/settings", settingsHandler.HandleUpdateSettings)
+			r.Get("/user/base-currency", settingsHandler.HandleGetBaseCurrency)
+			r.Put("/user/base-currency", settingsHandler.HandleSetBaseCurrency)
+			r.Get("/user/fiscal-year", settingsHandler.HandleGetFiscalYear)
+			r.Put("/user/fiscal-year", settingsHandler.HandleSetFiscalYear)
+			r.Get("/user/deemed-disposal", deemedDisposalHandler.HandleGetSetting)
+			r.Put("/user/deemed-disposal", deemedDisposalHandler.HandleSetSetting)
+			r.Get("/user/locale", settingsHandler.HandleGetLocale)
+			r.Put("/user/locale", settingsHandler.HandleSetLocale)
+			r.Get("/user/identities", userHandler.HandleGetIdentities)
+			r.Post("/user/identities/google", userHandler.HandleStartGoogleLink)
+			r.Post("/user/identities/local", userHandler.HandleAddLocalIdentity)
+			r.Delete("/user/identities/{provider}", userHandler.HandleDeleteIdentity)
+			r.Get("/user/audit-log", userHandler.HandleGetAuditLog)
+			r.Get("/user/calendar-token", calendarHandler.HandleGetCalendarToken)
+			r.Post("/user/calendar-token", calendarHandler.HandleCreateCalendarToken)
+			r.Delete("/user/calendar-token", calendarHandler.HandleDeleteCalendarToken)
+		})
+	})
+
+	// API routes, version 2: typed reports with ISO dates and decimal amounts
+	handlers.MountAPIVersion(r, handlers.APIVersion2, func(r chi.Router) {
+		r.Get("/openapi.json", openAPIV2Handler.HandleGetOpenAPISpec)
+
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.CSRFMiddleware(config.Cfg.CSRFAuthKey))
+			r.Use(userHandler.AuthMiddleware)
+			r.Use(handlers.LocaleMiddleware)
+
+			r.Get("/transactions", v2Handler.HandleGetTransactions)
+			r.With(etag).Get("/dividends", v2Handler.HandleGetDividends)
+			r.With(etag).Get("/stock-sales", v2Handler.HandleGetStockSales)
+			r.With(etag).Get("/holdings/stocks", v2Handler.HandleGetStockHoldings)
+			r.With(etag).Get("/holdings/options", v2Handler.HandleGetOptionHoldings)
+			r.With(etag).Get("/option-sales", v2Handler.HandleGetOptionSales)
+			r.Get("/fees", v2Handler.HandleGetFees)
 		})
 	})
 
@@ -204,7 +533,9 @@ func main() {
 		if !strings.HasPrefix(r.URL.Path, "/api/") {
 			logger.L.Warn("Root level path not found", "method", r.Method, "path", r.URL.Path)
 			http.NotFound(w, r)
+			return
 		}
+		utils.SendJSONError(w, "Not found", http.StatusNotFound)
 	})
 
 	serverAddr := ":" + config.Cfg.Port
@@ -0,0 +1,278 @@
+// backend/src/apiv2/resources.go
+package apiv2
+
+import (
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// Transaction is a transaction as /api/v2 returns it.
+type Transaction struct {
+	ID               int64              `json:"id"`
+	Date             Date               `json:"date" doc:"Day of the transaction at the broker"`
+	ExecutedAt       *time.Time         `json:"executed_at,omitempty" doc:"When it was executed, in UTC; midnight of date when the broker reports no time"`
+	Source           string             `json:"source" doc:"Broker the transaction was imported from, e.g. degiro or ibkr"`
+	ProductName      string             `json:"product_name"`
+	ISIN             string             `json:"isin,omitempty"`
+	Type             TransactionType    `json:"type"`
+	Subtype          TransactionSubtype `json:"subtype,omitempty"`
+	Side             Side               `json:"side,omitempty"`
+	Quantity         int                `json:"quantity"`
+	Price            Decimal            `json:"price"`
+	Amount           Decimal            `json:"amount" doc:"Amount in currency; negative for money paid"`
+	Currency         string             `json:"currency" doc:"ISO 4217 code of the original currency"`
+	Commission       Decimal            `json:"commission"`
+	ExchangeRate     Decimal            `json:"exchange_rate" doc:"Units of currency per unit of the base currency"`
+	ExchangeRateDate Date               `json:"exchange_rate_date,omitempty" doc:"Day of the ECB rate used; omitted for rates the broker executed at"`
+	AmountBase       Decimal            `json:"amount_base" doc:"Amount in the user's base currency"`
+	OrderID          string             `json:"order_id,omitempty"`
+	Description      string             `json:"description"`
+	Country          *Country           `json:"country,omitempty"`
+	AssetClass       string             `json:"asset_class,omitempty" doc:"STOCK, ETF, FUND or OTHER, when known"`
+	Note             string             `json:"note,omitempty"`
+	Tags             []string           `json:"tags,omitempty"`
+}
+
+// TransactionOf converts a transaction whose country label is already rendered in the request locale.
+func TransactionOf(tx models.ProcessedTransaction) Transaction {
+	out := Transaction{
+		ID:               tx.ID,
+		Date:             DateOf(tx.Date),
+		Source:           tx.Source,
+		ProductName:      tx.ProductName,
+		ISIN:             tx.ISIN,
+		Type:             transactionTypeOf(tx.TransactionType),
+		Subtype:          transactionSubtypeOf(tx.TransactionSubType),
+		Side:             sideOf(tx.BuySell),
+		Quantity:         tx.Quantity,
+		Price:            DecimalOf(tx.Price),
+		Amount:           DecimalOf(tx.Amount),
+		Currency:         tx.Currency,
+		Commission:       DecimalOf(tx.Commission),
+		ExchangeRate:     decimalOfFloat(tx.ExchangeRate),
+		ExchangeRateDate: DateOf(tx.ExchangeRateDate),
+		AmountBase:       DecimalOf(tx.AmountEUR),
+		OrderID:          tx.OrderID,
+		Description:      tx.Description,
+		AssetClass:       tx.AssetClass,
+		Note:             tx.Note,
+		Tags:             tx.Tags,
+	}
+	if !tx.ExecutedAt.IsZero() {
+		executedAt := tx.ExecutedAt.UTC()
+		out.ExecutedAt = &executedAt
+	}
+	if tx.CountryCode != "" {
+		country := CountryOf(tx.CountryCode)
+		out.Country = &country
+	}
+	return out
+}
+
+// StockSale is the sale of shares matched to the lot they were bought in.
+type StockSale struct {
+	ISIN                 string   `json:"isin"`
+	ProductName          string   `json:"product_name"`
+	Quantity             int      `json:"quantity"`
+	BuyDate              Date     `json:"buy_date"`
+	BuyPrice             Decimal  `json:"buy_price"`
+	BuyAmount            Decimal  `json:"buy_amount" doc:"Cost in buy_currency"`
+	BuyCurrency          string   `json:"buy_currency"`
+	BuyExchangeRate      Decimal  `json:"buy_exchange_rate"`
+	BuyExchangeRateDate  Date     `json:"buy_exchange_rate_date,omitempty"`
+	BuyAmountBase        Decimal  `json:"buy_amount_base" doc:"Cost in the base currency"`
+	SaleDate             Date     `json:"sale_date"`
+	SalePrice            Decimal  `json:"sale_price"`
+	SaleAmount           Decimal  `json:"sale_amount" doc:"Proceeds in sale_currency"`
+	SaleCurrency         string   `json:"sale_currency"`
+	SaleExchangeRate     Decimal  `json:"sale_exchange_rate"`
+	SaleExchangeRateDate Date     `json:"sale_exchange_rate_date,omitempty"`
+	SaleAmountBase       Decimal  `json:"sale_amount_base" doc:"Proceeds in the base currency"`
+	Commission           Decimal  `json:"commission" doc:"Commissions of the buy and the sale, in the base currency"`
+	TransactionTax       Decimal  `json:"transaction_tax" doc:"Stamp duty and financial transaction tax of the buy and the sale, in the base currency"`
+	Delta                Decimal  `json:"delta" doc:"sale_amount_base less buy_amount_base"`
+	Gain                 Decimal  `json:"gain" doc:"delta less commission and transaction_tax"`
+	TaxableGain          Decimal  `json:"taxable_gain" doc:"gain times inclusion_rate"`
+	InclusionRate        Decimal  `json:"inclusion_rate" doc:"Share of the gain that is taxable, from 0 to 1"`
+	HoldingDays          int      `json:"holding_days"`
+	HeldOver24Months     bool     `json:"held_over_24_months" doc:"Whether the shares were held for more than 24 calendar months"`
+	HoldingRule          string   `json:"holding_rule,omitempty" doc:"Rule the holding period falls under, e.g. PT_SHORT_TERM"`
+	TaxYear              string   `json:"tax_year" doc:"Tax year the gain is realised in, under the user's fiscal year"`
+	Country              *Country `json:"country,omitempty"`
+	AssetClass           string   `json:"asset_class,omitempty" doc:"STOCK, ETF, FUND or OTHER, when known"`
+}
+
+// StockSaleOf converts a sale whose country label is already rendered in the request locale.
+func StockSaleOf(sale models.SaleDetail) StockSale {
+	out := StockSale{
+		ISIN:                 sale.ISIN,
+		ProductName:          sale.ProductName,
+		Quantity:             sale.Quantity,
+		BuyDate:              DateOf(sale.BuyDate),
+		BuyPrice:             DecimalOf(sale.BuyPrice),
+		BuyAmount:            DecimalOf(sale.BuyAmount),
+		BuyCurrency:          sale.BuyCurrency,
+		BuyExchangeRate:      decimalOfFloat(sale.BuyExchangeRate),
+		BuyExchangeRateDate:  DateOf(sale.BuyExchangeRateDate),
+		BuyAmountBase:        DecimalOf(sale.BuyAmountEUR),
+		SaleDate:             DateOf(sale.SaleDate),
+		SalePrice:            DecimalOf(sale.SalePrice),
+		SaleAmount:           DecimalOf(sale.SaleAmount),
+		SaleCurrency:         sale.SaleCurrency,
+		SaleExchangeRate:     decimalOfFloat(sale.SaleExchangeRate),
+		SaleExchangeRateDate: DateOf(sale.SaleExchangeRateDate),
+		SaleAmountBase:       DecimalOf(sale.SaleAmountEUR),
+		Commission:           DecimalOf(sale.Commission),
+		TransactionTax:       DecimalOf(sale.TransactionTax),
+		Delta:                DecimalOf(sale.Delta),
+		Gain:                 DecimalOf(sale.GainEUR),
+		TaxableGain:          DecimalOf(sale.TaxableGainEUR),
+		InclusionRate:        decimalOfFloat(sale.InclusionRate),
+		HoldingDays:          sale.HoldingDays,
+		HeldOver24Months:     sale.HeldOver24Months,
+		HoldingRule:          sale.HoldingRule,
+		TaxYear:              sale.TaxYear,
+		AssetClass:           sale.AssetClass,
+	}
+	if sale.CountryCode != "" {
+		country := CountryOf(sale.CountryCode)
+		out.Country = &country
+	}
+	return out
+}
+
+// Lot is a purchase of shares still held, in whole or in part.
+type Lot struct {
+	ISIN          string  `json:"isin"`
+	ProductName   string  `json:"product_name"`
+	Quantity      int     `json:"quantity" doc:"Shares of the purchase still held"`
+	BuyDate       Date    `json:"buy_date"`
+	BuyPrice      Decimal `json:"buy_price"`
+	BuyAmount     Decimal `json:"buy_amount" doc:"Cost of the shares held, in buy_currency"`
+	BuyCurrency   string  `json:"buy_currency"`
+	BuyAmountBase Decimal `json:"buy_amount_base" doc:"Cost of the shares held, in the base currency"`
+	AssetClass    string  `json:"asset_class,omitempty" doc:"STOCK, ETF, FUND or OTHER, when known"`
+	CoveredShares int     `json:"covered_shares,omitempty" doc:"Shares set aside for short calls written against the lot"`
+}
+
+// LotOf converts a purchase lot.
+func LotOf(lot models.PurchaseLot) Lot {
+	out := Lot{
+		ISIN:          lot.ISIN,
+		ProductName:   lot.ProductName,
+		Quantity:      lot.Quantity,
+		BuyDate:       DateOf(lot.BuyDate),
+		BuyPrice:      decimalOfFloat(lot.BuyPrice),
+		BuyAmount:     decimalOfFloat(lot.BuyAmount),
+		BuyCurrency:   lot.BuyCurrency,
+		BuyAmountBase: decimalOfFloat(lot.BuyAmountEUR),
+		AssetClass:    lot.AssetClass,
+	}
+	for _, call := range lot.CoveredCalls {
+		if call.OptionCloseDate == "" {
+			out.CoveredShares += call.Shares
+		}
+	}
+	return out
+}
+
+// OptionSale is an option position opened and closed, by a trade or by expiring.
+type OptionSale struct {
+	ProductName     string   `json:"product_name" doc:"Option as the broker names it, e.g. FLW P31.00 18MAR22"`
+	Quantity        int      `json:"quantity"`
+	OpenDate        Date     `json:"open_date"`
+	OpenPrice       Decimal  `json:"open_price"`
+	OpenAmount      Decimal  `json:"open_amount" doc:"Amount in open_currency"`
+	OpenCurrency    string   `json:"open_currency"`
+	OpenAmountBase  Decimal  `json:"open_amount_base" doc:"Amount in the base currency"`
+	CloseDate       Date     `json:"close_date"`
+	ClosePrice      Decimal  `json:"close_price"`
+	CloseAmount     Decimal  `json:"close_amount" doc:"Amount in close_currency"`
+	CloseCurrency   string   `json:"close_currency"`
+	CloseAmountBase Decimal  `json:"close_amount_base" doc:"Amount in the base currency"`
+	Commission      Decimal  `json:"commission" doc:"Commissions of the opening and closing trades"`
+	Delta           Decimal  `json:"delta" doc:"Gain or loss in the base currency"`
+	OpenOrderID     string   `json:"open_order_id,omitempty"`
+	CloseOrderID    string   `json:"close_order_id,omitempty" doc:"EXPIRED for positions that expired worthless"`
+	TaxYear         string   `json:"tax_year" doc:"Tax year of the close date, under the user's fiscal year"`
+	Country         *Country `json:"country,omitempty"`
+}
+
+// OptionSaleOf converts an option sale whose country label is already rendered in the request locale.
+func OptionSaleOf(sale models.OptionSaleDetail) OptionSale {
+	out := OptionSale{
+		ProductName:     sale.ProductName,
+		Quantity:        sale.Quantity,
+		OpenDate:        DateOf(sale.OpenDate),
+		OpenPrice:       decimalOfFloat(sale.OpenPrice),
+		OpenAmount:      decimalOfFloat(sale.OpenAmount),
+		OpenCurrency:    sale.OpenCurrency,
+		OpenAmountBase:  decimalOfFloat(sale.OpenAmountEUR),
+		CloseDate:       DateOf(sale.CloseDate),
+		ClosePrice:      decimalOfFloat(sale.ClosePrice),
+		CloseAmount:     decimalOfFloat(sale.CloseAmount),
+		CloseCurrency:   sale.CloseCurrency,
+		CloseAmountBase: decimalOfFloat(sale.CloseAmountEUR),
+		Commission:      decimalOfFloat(sale.Commission),
+		Delta:           decimalOfFloat(sale.Delta),
+		OpenOrderID:     sale.OpenOrderID,
+		CloseOrderID:    sale.CloseOrderID,
+		TaxYear:         sale.TaxYear,
+	}
+	if sale.CountryCode != "" {
+		country := CountryOf(sale.CountryCode)
+		out.Country = &country
+	}
+	return out
+}
+
+// OptionPosition is an option position still open.
+type OptionPosition struct {
+	ProductName    string  `json:"product_name"`
+	Quantity       int     `json:"quantity" doc:"Positive for long positions, negative for short ones"`
+	OpenDate       Date    `json:"open_date"`
+	OpenPrice      Decimal `json:"open_price"`
+	OpenAmount     Decimal `json:"open_amount" doc:"Amount in open_currency"`
+	OpenCurrency   string  `json:"open_currency"`
+	OpenAmountBase Decimal `json:"open_amount_base" doc:"Amount in the base currency"`
+}
+
+// OptionPositionOf converts an open option position.
+func OptionPositionOf(holding models.OptionHolding) OptionPosition {
+	return OptionPosition{
+		ProductName:    holding.ProductName,
+		Quantity:       holding.Quantity,
+		OpenDate:       DateOf(holding.OpenDate),
+		OpenPrice:      decimalOfFloat(holding.OpenPrice),
+		OpenAmount:     decimalOfFloat(holding.OpenAmount),
+		OpenCurrency:   holding.OpenCurrency,
+		OpenAmountBase: decimalOfFloat(holding.OpenAmountEUR),
+	}
+}
+
+// Holdings are the lots held at the end of a year.
+type Holdings struct {
+	Year string `json:"year"`
+	Lots []Lot  `json:"lots"`
+}
+
+// Fee is a fee, commission or transaction tax paid.
+type Fee struct {
+	Date        Date        `json:"date"`
+	Category    FeeCategory `json:"category"`
+	Description string      `json:"description"`
+	AmountBase  Decimal     `json:"amount_base" doc:"Amount in the base currency; negative for fees paid"`
+	Source      string      `json:"source"`
+}
+
+// FeeOf converts a fee.
+func FeeOf(fee models.FeeDetail) Fee {
+	return Fee{
+		Date:        DateOf(fee.Date),
+		Category:    feeCategoryOf(fee.Category),
+		Description: fee.Description,
+		AmountBase:  decimalOfFloat(fee.AmountEUR),
+		Source:      fee.Source,
+	}
+}
@@ -0,0 +1,149 @@
+// backend/src/apiv2/types.go
+package apiv2
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/openapi"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// Decimal is an exact decimal number encoded as a JSON string, such as "-12.5", so that amounts are
+// not rounded by clients decoding JSON numbers as binary floating point.
+type Decimal string
+
+// DecimalOf returns an amount as a Decimal, with the decimals it has.
+func DecimalOf(m models.Money) Decimal {
+	return Decimal(m.String())
+}
+
+// decimalOfFloat returns a figure the reports still keep as a float64, such as an exchange rate,
+// rounded to the millionths the amounts are kept in.
+func decimalOfFloat(f float64) Decimal {
+	return DecimalOf(models.NewMoney(f))
+}
+
+func (Decimal) OpenAPISchema() openapi.Schema {
+	return openapi.Schema{Type: "string", Format: "decimal", Description: `Exact decimal number, e.g. "-1234.5"`}
+}
+
+// Date is a calendar day in ISO 8601 form, YYYY-MM-DD.
+type Date string
+
+// DateOf converts a day stored as DD-MM-YYYY. It returns "" for an empty or malformed day, which
+// the fields holding it omit.
+func DateOf(day string) Date {
+	t, err := time.Parse(utils.DefaultDateFormat, day)
+	if err != nil {
+		return ""
+	}
+	return Date(t.Format(time.DateOnly))
+}
+
+func (Date) OpenAPISchema() openapi.Schema {
+	return openapi.Schema{Type: "string", Format: "date"}
+}
+
+// Country is the country of an instrument, derived from its ISIN.
+type Country struct {
+	Code string `json:"code,omitempty" doc:"ISO 3166-1 numeric code, as asked for in the tax forms; omitted when unknown"`
+	Name string `json:"name" doc:"Name in the request locale"`
+}
+
+// CountryOf splits a country label as the reports render it, "840 - United States of America".
+func CountryOf(label string) Country {
+	code, name, found := strings.Cut(label, " - ")
+	if !found {
+		return Country{Name: label}
+	}
+	return Country{Code: code, Name: name}
+}
+
+// enumSchema documents a string that takes one of values.
+func enumSchema(values []string, description string) openapi.Schema {
+	return openapi.Schema{Type: "string", Enum: values, Description: description}
+}
+
+// enumOf returns value when it is one of values, and "OTHER" otherwise, so that a response never
+// holds a value its schema does not list.
+func enumOf(value string, values []string) string {
+	value = strings.ToUpper(value)
+	if slices.Contains(values, value) {
+		return value
+	}
+	return "OTHER"
+}
+
+// TransactionType is the kind of a transaction.
+type TransactionType string
+
+// TransactionTypes lists the values of TransactionType. OTHER stands for instruments a broker
+// reports that are not handled, such as futures.
+var TransactionTypes = []string{
+	"STOCK", "OPTION", "BOND", "DIVIDEND", "SCRIP_DIVIDEND", "RETURN_OF_CAPITAL",
+	"INTEREST", "FEE", "TAX", "CASH", "OTHER",
+}
+
+func transactionTypeOf(value string) TransactionType {
+	return TransactionType(enumOf(value, TransactionTypes))
+}
+
+func (TransactionType) OpenAPISchema() openapi.Schema {
+	return enumSchema(TransactionTypes, "Kind of transaction")
+}
+
+// TransactionSubtype refines a TransactionType, e.g. an OPTION into a CALL or PUT.
+type TransactionSubtype string
+
+// TransactionSubtypes lists the values of TransactionSubtype.
+var TransactionSubtypes = []string{
+	"CALL", "PUT", "TAX", "DEPOSIT", "WITHDRAWAL", "FX", "STAMP_DUTY", "FTT", "TRADE_TAX",
+	"COUPON", "ACCRUED_INTEREST", "REDEMPTION", models.OpeningBalanceSubType,
+	models.MoneyMarketConversionSubType, models.MoneyMarketPriceChangeSubType, "OTHER",
+}
+
+func transactionSubtypeOf(value string) TransactionSubtype {
+	if value == "" {
+		return ""
+	}
+	return TransactionSubtype(enumOf(value, TransactionSubtypes))
+}
+
+func (TransactionSubtype) OpenAPISchema() openapi.Schema {
+	return enumSchema(TransactionSubtypes, "Refinement of the type: CALL or PUT for options, TAX for withholding tax, DEPOSIT, WITHDRAWAL or FX for cash")
+}
+
+// Side is the side of a trade.
+type Side string
+
+// Sides lists the values of Side.
+var Sides = []string{"BUY", "SELL"}
+
+func sideOf(value string) Side {
+	if value == "" {
+		return ""
+	}
+	return Side(enumOf(value, Sides))
+}
+
+func (Side) OpenAPISchema() openapi.Schema {
+	return enumSchema(Sides, "Side of a trade")
+}
+
+// FeeCategory is the kind of a fee.
+type FeeCategory string
+
+// FeeCategories lists the values of FeeCategory.
+var FeeCategories = []string{"BROKERAGE_FEE", "TRANSACTION_TAX", "TRADE_COMMISSION", "OTHER"}
+
+// feeCategoryOf converts the category of a fee, e.g. "Trade Commission" into TRADE_COMMISSION.
+func feeCategoryOf(category string) FeeCategory {
+	return FeeCategory(enumOf(strings.ReplaceAll(category, " ", "_"), FeeCategories))
+}
+
+func (FeeCategory) OpenAPISchema() openapi.Schema {
+	return enumSchema(FeeCategories, "Kind of fee")
+}
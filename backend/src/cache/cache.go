@@ -0,0 +1,84 @@
+// backend/src/cache/cache.go
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// NoExpiration keeps a value until it is deleted.
+const NoExpiration time.Duration = 0
+
+// Backends a Cache can be kept in.
+const (
+	BackendMemory = "memory" // In the process; each instance has its own
+	BackendRedis  = "redis"  // In a Redis server, shared by the instances using it
+)
+
+// Cache keeps the reports computed for users by key, so they are not computed again on every request.
+// A value is read back into a pointer to a variable of the type it was stored with.
+type Cache interface {
+	// Get reads the value stored under key into dest, a pointer, and reports whether there was one.
+	Get(key string, dest any) bool
+	// Set stores value under key for ttl, or until it is deleted with NoExpiration.
+	Set(key string, value any, ttl time.Duration)
+	// Delete removes the values stored under keys, for every instance sharing the cache.
+	Delete(keys ...string)
+}
+
+// New creates a cache kept in backend: BackendMemory, where values expire after defaultExpiration unless
+// set otherwise and are removed every cleanupInterval, or BackendRedis, in the server at redisURL.
+func New(backend, redisURL string, defaultExpiration, cleanupInterval time.Duration) (Cache, error) {
+	switch backend {
+	case "", BackendMemory:
+		return NewMemory(defaultExpiration, cleanupInterval), nil
+	case BackendRedis:
+		redis, err := NewRedis(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		return redis, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q: use %s or %s", backend, BackendMemory, BackendRedis)
+	}
+}
+
+// memoryCache keeps values in the process, as they are: callers must not modify what they get.
+type memoryCache struct {
+	items *gocache.Cache
+}
+
+// NewMemory creates a cache kept in the process.
+func NewMemory(defaultExpiration, cleanupInterval time.Duration) Cache {
+	return &memoryCache{items: gocache.New(defaultExpiration, cleanupInterval)}
+}
+
+func (c *memoryCache) Get(key string, dest any) bool {
+	value, found := c.items.Get(key)
+	if !found {
+		return false
+	}
+	target := reflect.ValueOf(dest).Elem()
+	stored := reflect.ValueOf(value)
+	if !stored.Type().AssignableTo(target.Type()) {
+		return false
+	}
+	target.Set(stored)
+	return true
+}
+
+func (c *memoryCache) Set(key string, value any, ttl time.Duration) {
+	if ttl == NoExpiration {
+		ttl = gocache.NoExpiration
+	}
+	c.items.Set(key, value, ttl)
+}
+
+func (c *memoryCache) Delete(keys ...string) {
+	for _, key := range keys {
+		c.items.Delete(key)
+	}
+}
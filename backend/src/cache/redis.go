@@ -0,0 +1,246 @@
+// backend/src/cache/redis.go
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+const (
+	redisKeyPrefix = "rumoclaro:"    // Keeps the keys apart from those of other applications on the server
+	redisTimeout   = 5 * time.Second // Longest a command may take, dialing included
+	redisMaxIdle   = 8               // Connections kept open between commands
+)
+
+// redisError is an error reply of the server. The connection it came on can still be used.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// RedisCache keeps values in a Redis server, encoded as the JSON the API sends them as, so every
+// instance of the backend using the server reads what the others stored and no longer finds what they
+// deleted. It speaks the RESP protocol over a small pool of connections. The cache is best effort: a
+// command that fails is logged and counts as a miss.
+type RedisCache struct {
+	address  string
+	tls      *tls.Config // Set for rediss:// URLs
+	username string
+	password string
+	database int
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis creates a cache kept in the Redis server at rawURL, given as redis://[user:password@]host[:port][/db],
+// or rediss:// for TLS. An unreachable server is logged, not an error: the cache is used once it is up.
+func NewRedis(rawURL string) (*RedisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Hostname() == "" {
+		return nil, fmt.Errorf("REDIS_URL must be a redis:// or rediss:// URL")
+	}
+	c := &RedisCache{address: u.Host, idle: make(chan *redisConn, redisMaxIdle)}
+	if u.Port() == "" {
+		c.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.Scheme == "rediss" {
+		c.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+		if c.password != "" {
+			c.username = u.User.Username()
+		} else {
+			c.password = u.User.Username() // redis://:password@host and redis://password@host alike
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.database, err = strconv.Atoi(db); err != nil || c.database < 0 {
+			return nil, fmt.Errorf("REDIS_URL has an invalid database number %q", db)
+		}
+	}
+
+	if _, err := c.do("PING"); err != nil {
+		logger.L.Warn("Redis cache is unreachable; reports are computed on every request until it is", "address", c.address, "error", err)
+	}
+	return c, nil
+}
+
+func (c *RedisCache) Get(key string, dest any) bool {
+	reply, err := c.do("GET", redisKeyPrefix+key)
+	if err != nil {
+		logger.L.Warn("Redis cache read failed", "key", key, "error", err)
+		return false
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return false // Not stored
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		logger.L.Warn("Redis cache value could not be decoded", "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+func (c *RedisCache) Set(key string, value any, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logger.L.Error("Redis cache value could not be encoded", "key", key, "error", err)
+		return
+	}
+	args := []string{"SET", redisKeyPrefix + key, string(data)}
+	if ttl != NoExpiration {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if _, err := c.do(args...); err != nil {
+		logger.L.Warn("Redis cache write failed", "key", key, "error", err)
+	}
+}
+
+func (c *RedisCache) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, redisKeyPrefix+key)
+	}
+	if _, err := c.do(args...); err != nil {
+		// The other instances may keep serving the deleted values until they expire.
+		logger.L.Error("Redis cache invalidation failed", "keys", keys, "error", err)
+	}
+}
+
+// do runs a command and returns its reply: a string, an int64, []byte for a bulk string or nil for a
+// missing value. The commands used here never reply with an array.
+func (c *RedisCache) do(args ...string) (any, error) {
+	rc, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := rc.command(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		rc.conn.Close()
+		return nil, err
+	}
+	c.release(rc)
+	return reply, err
+}
+
+// conn takes an idle connection, or opens one, authenticated and on the configured database.
+func (c *RedisCache) conn() (*redisConn, error) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	var conn net.Conn
+	var err error
+	if c.tls != nil {
+		dialer := tls.Dialer{Config: c.tls}
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := rc.command(auth...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.database != 0 {
+		if _, err := rc.command("SELECT", strconv.Itoa(c.database)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// release keeps a connection for the next command, or closes it when enough are kept.
+func (c *RedisCache) release(rc *redisConn) {
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+// command sends a command as an array of bulk strings and reads its reply.
+func (rc *redisConn) command(args ...string) (any, error) {
+	rc.conn.SetDeadline(time.Now().Add(redisTimeout))
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := rc.conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return rc.readReply()
+}
+
+func (rc *redisConn) readReply() (any, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2) // With the closing \r\n
+		if _, err := io.ReadFull(rc.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
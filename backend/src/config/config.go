@@ -1,9 +1,12 @@
 package config
 
 import (
+	"crypto/sha256"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,14 +18,47 @@ type AppConfig struct {
 	// Core settings
 	Port         string
 	DatabasePath string
+	DatabaseURL  string // postgres:// URL of a PostgreSQL database to use instead of the SQLite file
 	LogLevel     string
 
+	// Database connection pool settings
+	DBBusyTimeout     time.Duration
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBQueryTimeout    time.Duration // Longest a query run for a request may take
+
+	// Report cache: memory (each instance has its own) or redis (shared through the server at RedisURL)
+	CacheBackend string
+	RedisURL     string // redis:// or rediss:// URL, with the password and database number if any
+
 	// Security settings
 	JWTSecret          string
 	CSRFAuthKey        []byte
+	CSRFCookieSameSite string // SameSite mode of the CSRF cookie: lax, strict or none (cross-site frontends)
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
+	SessionIdleTimeout time.Duration // Time without use after which a session expires (0: only RefreshTokenExpiry applies)
+	AuthCookies        bool          // Deliver the session tokens in HttpOnly cookies instead of response bodies
 	MaxUploadSizeBytes int64
+	MaxUploadRows      int           // Data rows accepted in one uploaded file (0 means unlimited)
+	MaxParseTime       time.Duration // Longest parsing one uploaded file may take (0 means unlimited)
+	MaxParseMemory     int64         // Bytes parsing one uploaded file may be estimated to hold (0 means unlimited)
+	UploadBatchSize    int           // Transactions parsed and stored per batch during an upload
+	UploadWorkers      int           // Transactions of a batch enriched concurrently
+	IBKRTimeZone       string        // Time zone of the times in IBKR Flex reports, as set in the Flex Query
+	CompressMinSize    int           // Smallest response body, in bytes, sent gzipped
+	// Scanners uploaded files go through before they are parsed: heuristic and/or clamav (none by default)
+	UploadScanners     []string
+	ClamAVAddress      string        // clamd socket, as unix:/path or host:port
+	UploadScanTimeout  time.Duration // Longest a scan may take
+	UploadScanFailOpen bool          // Accept uploads when a scanner is unavailable instead of refusing them
+	// Key (32 bytes) used to encrypt stored secrets such as IBKR Flex tokens and refresh tokens
+	CredentialsEncryptionKey []byte
+	// ID recorded with each value encrypted with CredentialsEncryptionKey
+	CredentialsEncryptionKeyID string
+	// Retired keys by ID, still accepted for decryption until their values are re-encrypted
+	CredentialsPreviousKeys map[string][]byte
 
 	// Data file paths
 	CountryDataPath string
@@ -38,11 +74,33 @@ type AppConfig struct {
 	SMTPUser     string
 	SMTPPassword string
 
+	// HTTPS API providers, for hosts that block outbound SMTP
+	SendGridAPIKey     string
+	AWSRegion          string // Region of the SES endpoint
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string // Only for temporary credentials
+
 	// URL and Token Expiry settings for user actions
 	VerificationEmailBaseURL string
 	VerificationTokenExpiry  time.Duration
 	PasswordResetBaseURL     string
 	PasswordResetTokenExpiry time.Duration
+	AccountUnlockBaseURL     string
+	AccountUnlockTokenExpiry time.Duration
+
+	// Login throttling: after LoginMaxFailedAttempts consecutive failures the account is locked for
+	// LoginLockoutDuration, doubling with every further failure up to LoginLockoutMaxDuration.
+	LoginMaxFailedAttempts  int
+	LoginLockoutDuration    time.Duration
+	LoginLockoutMaxDuration time.Duration
+
+	// Password policy for local accounts. PasswordMinStrength is the lowest accepted strength score, from
+	// 0 (too guessable) to 4 (very unguessable). PasswordBreachCheck refuses passwords found in the
+	// Have I Been Pwned database, sending it only the first 5 characters of their SHA-1 hash.
+	PasswordMinLength   int
+	PasswordMinStrength int
+	PasswordBreachCheck bool
 
 	// Google OAuth settings
 	GoogleClientID     string
@@ -51,6 +109,59 @@ type AppConfig struct {
 
 	// Frontend URL for reference (e.g., CORS, redirects)
 	FrontendBaseURL string
+	// Public URL of the backend API, for links that are opened without the frontend (e.g., calendar feeds)
+	APIBaseURL string
+
+	// Origins allowed to make credentialed CORS requests. Entries may use a
+	// wildcard subdomain, e.g. "https://*.example.com".
+	AllowedOrigins []string
+
+	// Observability settings
+	MetricsToken string // Bearer token required to scrape /metrics; empty leaves it open
+	AdminToken   string // Bearer token required by /api/admin endpoints; empty disables them
+	// Address the background checks email about the problems they find; empty sends no alerts
+	OperatorAlertEmail string
+
+	// Background job settings
+	IntegrityCheckInterval  time.Duration
+	IBKRFlexSyncInterval    time.Duration
+	MaintenanceInterval     time.Duration
+	EmailOutboxInterval     time.Duration
+	AlertCheckInterval      time.Duration
+	WebhookDeliveryInterval time.Duration
+	PriceRefreshInterval    time.Duration
+	BackupInterval          time.Duration
+
+	// Database backup settings. Backups are written to BackupDir and/or uploaded to BackupS3Bucket;
+	// the S3 credentials default to those of AWS_*.
+	BackupDir               string
+	BackupKeep              int // Backups kept in BackupDir; 0 keeps all
+	BackupS3Endpoint        string
+	BackupS3Region          string
+	BackupS3Bucket          string
+	BackupS3Prefix          string
+	BackupS3AccessKeyID     string
+	BackupS3SecretAccessKey string
+
+	// Webhook settings
+	WebhookAllowPrivateURLs    bool // Accept http URLs and private addresses, for local development only
+	LargeCashMovementThreshold int  // Base currency amount from which a deposit or withdrawal is sent to webhooks
+
+	// Reporting settings
+	BenchmarkISIN string
+	RoundingMode  string // How reported amounts break ties: half-up (default) or half-even
+
+	// Billing settings. Premium endpoints are only gated while both Stripe keys are set.
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	BillingSuccessURL   string // Where Stripe Checkout returns after a successful payment
+	BillingCancelURL    string // Where Stripe Checkout returns when the user gives up
+
+	// Plan limits (0 means unlimited)
+	FreePlanUploadsPerMonth    int
+	FreePlanMaxTransactions    int
+	PremiumPlanUploadsPerMonth int
+	PremiumPlanMaxTransactions int
 }
 
 // Cfg is a global instance of the AppConfig.
@@ -86,11 +197,40 @@ func LoadConfig() {
 		log.Fatalf("FATAL: CSRF_AUTH_KEY must be at least 32 bytes long. Current length: %d", len(csrfAuthKeyStr))
 	}
 
+	credentialsKeyStr := getEnv("CREDENTIALS_ENCRYPTION_KEY", "default-insecure-credentials-key")
+	// A key file, such as one written by a secrets manager or KMS agent, takes precedence.
+	if keyFile := getEnv("CREDENTIALS_ENCRYPTION_KEY_FILE", ""); keyFile != "" {
+		keyBytes, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("FATAL: Could not read CREDENTIALS_ENCRYPTION_KEY_FILE: %v", err)
+		}
+		credentialsKeyStr = strings.TrimSpace(string(keyBytes))
+	}
+	if credentialsKeyStr == "default-insecure-credentials-key" {
+		log.Println("WARNING: Using default insecure CREDENTIALS_ENCRYPTION_KEY. Set CREDENTIALS_ENCRYPTION_KEY environment variable for production.")
+	}
+	credentialsKeyID := getEnv("CREDENTIALS_ENCRYPTION_KEY_ID", "1")
+	previousKeys := make(map[string][]byte)
+	for _, entry := range getEnvAsSlice("CREDENTIALS_PREVIOUS_KEYS", nil) {
+		id, key, found := strings.Cut(entry, "=")
+		if !found || id == "" || key == "" || strings.Contains(id, ":") {
+			log.Fatalf("FATAL: CREDENTIALS_PREVIOUS_KEYS entries must be id=key, with no ':' in the id")
+		}
+		previousKeys[id] = deriveCredentialsKey(key)
+	}
+	if strings.Contains(credentialsKeyID, ":") {
+		log.Fatalf("FATAL: CREDENTIALS_ENCRYPTION_KEY_ID must not contain ':'")
+	}
+	if _, found := previousKeys[credentialsKeyID]; found {
+		log.Fatalf("FATAL: CREDENTIALS_ENCRYPTION_KEY_ID %q is also one of CREDENTIALS_PREVIOUS_KEYS", credentialsKeyID)
+	}
+
 	// --- Token Expiry Durations ---
 	accessTokenExpiry := getEnvAsDuration("ACCESS_TOKEN_EXPIRY", 60*time.Minute)
 	refreshTokenExpiry := getEnvAsDuration("REFRESH_TOKEN_EXPIRY", 168*time.Hour) // 7 days
 	verificationTokenExpiry := getEnvAsDuration("VERIFICATION_TOKEN_EXPIRY", 24*time.Hour)
 	passwordResetTokenExpiry := getEnvAsDuration("PASSWORD_RESET_TOKEN_EXPIRY", 1*time.Hour)
+	accountUnlockTokenExpiry := getEnvAsDuration("ACCOUNT_UNLOCK_TOKEN_EXPIRY", 24*time.Hour)
 
 	// --- File Size Limits ---
 	maxUploadSizeBytesStr := getEnv("MAX_UPLOAD_SIZE_BYTES", "10485760") // 10MB default
@@ -117,6 +257,7 @@ func LoadConfig() {
 	// Derive specific URLs from the base URLs.
 	verificationEmailBaseURL := getEnv("VERIFICATION_EMAIL_BASE_URL", frontendBaseURL+"/verify-email")
 	passwordResetBaseURL := getEnv("PASSWORD_RESET_BASE_URL", frontendBaseURL+"/reset-password")
+	accountUnlockBaseURL := getEnv("ACCOUNT_UNLOCK_BASE_URL", frontendBaseURL+"/unlock-account")
 	googleRedirectURL := getEnv("GOOGLE_REDIRECT_URL", apiBaseURL+"/api/auth/google/callback")
 
 	// --- Populate the Global Config Struct ---
@@ -124,14 +265,44 @@ func LoadConfig() {
 		// Core
 		Port:         getEnv("PORT", "8080"),
 		DatabasePath: getEnv("DATABASE_PATH", "./rumoclaro.db"),
+		DatabaseURL:  getEnv("DATABASE_URL", ""),
 		LogLevel:     getEnv("LOG_LEVEL", "info"),
 
+		// Database pool
+		DBBusyTimeout:     getEnvAsDuration("DB_BUSY_TIMEOUT", 5*time.Second),
+		DBMaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 10),
+		DBMaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", time.Hour),
+		DBQueryTimeout:    getEnvAsDuration("DB_QUERY_TIMEOUT", 30*time.Second),
+
+		// Report cache
+		CacheBackend: getEnv("CACHE_BACKEND", "memory"),
+		RedisURL:     getEnv("REDIS_URL", "redis://localhost:6379/0"),
+
 		// Security
 		JWTSecret:          jwtSecret,
 		CSRFAuthKey:        []byte(csrfAuthKeyStr),
+		CSRFCookieSameSite: getEnv("CSRF_COOKIE_SAMESITE", "lax"),
 		AccessTokenExpiry:  accessTokenExpiry,
 		RefreshTokenExpiry: refreshTokenExpiry,
+		SessionIdleTimeout: getEnvAsDuration("SESSION_IDLE_TIMEOUT", 0),
+		AuthCookies:        getEnvAsBool("AUTH_COOKIES", false),
 		MaxUploadSizeBytes: maxUploadSizeBytes,
+		MaxUploadRows:      getEnvAsInt("MAX_UPLOAD_ROWS", 200000),
+		MaxParseTime:       getEnvAsDuration("MAX_PARSE_TIME", 60*time.Second),
+		MaxParseMemory:     int64(getEnvAsInt("MAX_PARSE_MEMORY_MB", 512)) << 20,
+		UploadBatchSize:    getEnvAsInt("UPLOAD_BATCH_SIZE", 500),
+		UploadWorkers:      getEnvAsInt("UPLOAD_WORKERS", runtime.NumCPU()),
+		IBKRTimeZone:       getEnv("IBKR_TIMEZONE", "America/New_York"),
+		CompressMinSize:    getEnvAsInt("COMPRESS_MIN_SIZE", 1024),
+		UploadScanners:     getEnvAsSlice("UPLOAD_SCANNERS", nil),
+		ClamAVAddress:      getEnv("CLAMAV_ADDRESS", "unix:/var/run/clamav/clamd.ctl"),
+		UploadScanTimeout:  getEnvAsDuration("UPLOAD_SCAN_TIMEOUT", 30*time.Second),
+		UploadScanFailOpen: getEnvAsBool("UPLOAD_SCAN_FAIL_OPEN", false),
+
+		CredentialsEncryptionKey:   deriveCredentialsKey(credentialsKeyStr),
+		CredentialsEncryptionKeyID: credentialsKeyID,
+		CredentialsPreviousKeys:    previousKeys,
 
 		// Data
 		CountryDataPath: getEnv("COUNTRY_DATA_PATH", "data/country.json"),
@@ -144,18 +315,85 @@ func LoadConfig() {
 		SMTPPort:             getEnvAsInt("SMTP_PORT", 587),
 		SMTPUser:             getEnv("SMTP_USER", ""),
 		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+		SendGridAPIKey:       getEnv("SENDGRID_API_KEY", ""),
+		AWSRegion:            getEnv("AWS_REGION", ""),
+		AWSAccessKeyID:       getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:   getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:      getEnv("AWS_SESSION_TOKEN", ""),
 
 		// URLs & Expiries
 		FrontendBaseURL:          frontendBaseURL,
+		APIBaseURL:               apiBaseURL,
 		VerificationEmailBaseURL: verificationEmailBaseURL,
 		VerificationTokenExpiry:  verificationTokenExpiry,
 		PasswordResetBaseURL:     passwordResetBaseURL,
 		PasswordResetTokenExpiry: passwordResetTokenExpiry,
+		AccountUnlockBaseURL:     accountUnlockBaseURL,
+		AccountUnlockTokenExpiry: accountUnlockTokenExpiry,
+
+		// Login throttling
+		LoginMaxFailedAttempts:  getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+		LoginLockoutDuration:    getEnvAsDuration("LOGIN_LOCKOUT_DURATION", time.Minute),
+		LoginLockoutMaxDuration: getEnvAsDuration("LOGIN_LOCKOUT_MAX_DURATION", 24*time.Hour),
+
+		// Password policy
+		PasswordMinLength:   getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinStrength: getEnvAsInt("PASSWORD_MIN_STRENGTH", 2),
+		PasswordBreachCheck: getEnvAsBool("PASSWORD_BREACH_CHECK", false),
+
+		// CORS
+		AllowedOrigins: getEnvAsSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000", "https://visorfinanceiro.pt"}),
 
 		// Google OAuth
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  googleRedirectURL,
+
+		// Observability
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+		AdminToken:   getEnv("ADMIN_TOKEN", ""),
+		// Operator alerts
+		OperatorAlertEmail: getEnv("OPERATOR_ALERT_EMAIL", ""),
+
+		// Background jobs
+		IntegrityCheckInterval:  getEnvAsDuration("INTEGRITY_CHECK_INTERVAL", 24*time.Hour),
+		IBKRFlexSyncInterval:    getEnvAsDuration("IBKR_FLEX_SYNC_INTERVAL", 24*time.Hour),
+		MaintenanceInterval:     getEnvAsDuration("MAINTENANCE_INTERVAL", time.Hour),
+		EmailOutboxInterval:     getEnvAsDuration("EMAIL_OUTBOX_INTERVAL", time.Minute),
+		AlertCheckInterval:      getEnvAsDuration("ALERT_CHECK_INTERVAL", 6*time.Hour),
+		WebhookDeliveryInterval: getEnvAsDuration("WEBHOOK_DELIVERY_INTERVAL", time.Minute),
+		PriceRefreshInterval:    getEnvAsDuration("PRICE_REFRESH_INTERVAL", 24*time.Hour),
+		BackupInterval:          getEnvAsDuration("BACKUP_INTERVAL", 24*time.Hour),
+
+		// Database backups
+		BackupDir:               getEnv("BACKUP_DIR", ""),
+		BackupKeep:              getEnvAsInt("BACKUP_KEEP", 7),
+		BackupS3Endpoint:        getEnv("BACKUP_S3_ENDPOINT", ""),
+		BackupS3Region:          getEnv("BACKUP_S3_REGION", getEnv("AWS_REGION", "us-east-1")),
+		BackupS3Bucket:          getEnv("BACKUP_S3_BUCKET", ""),
+		BackupS3Prefix:          getEnv("BACKUP_S3_PREFIX", ""),
+		BackupS3AccessKeyID:     getEnv("BACKUP_S3_ACCESS_KEY_ID", getEnv("AWS_ACCESS_KEY_ID", "")),
+		BackupS3SecretAccessKey: getEnv("BACKUP_S3_SECRET_ACCESS_KEY", getEnv("AWS_SECRET_ACCESS_KEY", "")),
+
+		// Webhooks
+		WebhookAllowPrivateURLs:    getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_URLS", false),
+		LargeCashMovementThreshold: getEnvAsInt("LARGE_CASH_MOVEMENT_THRESHOLD", 10000),
+
+		// Reporting
+		BenchmarkISIN: getEnv("BENCHMARK_ISIN", "IE00B4L5Y983"), // iShares Core MSCI World
+		RoundingMode:  getEnv("ROUNDING_MODE", "half-up"),
+
+		// Billing
+		StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		BillingSuccessURL:   getEnv("BILLING_SUCCESS_URL", frontendBaseURL+"/settings?billing=success"),
+		BillingCancelURL:    getEnv("BILLING_CANCEL_URL", frontendBaseURL+"/settings?billing=cancelled"),
+
+		// Plans
+		FreePlanUploadsPerMonth:    getEnvAsInt("FREE_PLAN_UPLOADS_PER_MONTH", 10),
+		FreePlanMaxTransactions:    getEnvAsInt("FREE_PLAN_MAX_TRANSACTIONS", 20000),
+		PremiumPlanUploadsPerMonth: getEnvAsInt("PREMIUM_PLAN_UPLOADS_PER_MONTH", 100),
+		PremiumPlanMaxTransactions: getEnvAsInt("PREMIUM_PLAN_MAX_TRANSACTIONS", 0),
 	}
 
 	log.Printf("Configuration loaded: Port=%s, LogLevel=%s, DBPath=%s, FrontendURL=%s",
@@ -185,6 +423,20 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvAsBool retrieves an environment variable as a boolean ("true", "1", "false", "0", ...) or returns a fallback.
+func getEnvAsBool(key string, fallback bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		// The getEnv function already logs the fallback.
+		return fallback
+	}
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	log.Printf("Invalid boolean value for %s ('%s'), using default: %t", key, valueStr, fallback)
+	return fallback
+}
+
 // getEnvAsDuration retrieves an environment variable as a time.Duration or returns a fallback.
 func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
@@ -198,3 +450,32 @@ func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
 	log.Printf("Invalid duration value for %s ('%s'), using default: %s", key, valueStr, fallback.String())
 	return fallback
 }
+
+// deriveCredentialsKey returns the configured key when it is 32 bytes long, as AES-256 needs, and
+// otherwise derives 32 bytes from it.
+func deriveCredentialsKey(key string) []byte {
+	if len(key) == 32 {
+		return []byte(key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// getEnvAsSlice retrieves a comma-separated environment variable as a slice or returns a fallback.
+func getEnvAsSlice(key string, fallback []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		// The getEnv function already logs the fallback.
+		return fallback
+	}
+	var values []string
+	for _, v := range strings.Split(valueStr, ",") {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
@@ -2,14 +2,20 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	stdlog "log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
+	migratedatabase "github.com/golang-migrate/migrate/v4/database"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/username/taxfolio/backend/src/logger"
@@ -18,29 +24,109 @@ import (
 
 var DB *sql.DB
 
-// ... InitDB function remains the same ...
-func InitDB(databasePath string) {
-	db, err := sql.Open("sqlite", databasePath)
+// postgres is set when DB is a PostgreSQL database rather than SQLite.
+var postgres bool
+
+// IsPostgres reports whether the database is PostgreSQL, for the few statements that differ from SQLite.
+func IsPostgres() bool {
+	return postgres
+}
+
+// queryTimeout bounds every query run through WithQueryTimeout.
+var queryTimeout = 30 * time.Second
+
+// Settings tunes the connection pool.
+type Settings struct {
+	BusyTimeout     time.Duration // How long a SQLite connection waits for a lock before failing with "database is locked"
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	QueryTimeout    time.Duration // Longest a query run for a request may take; 0 keeps the default
+}
+
+// WithQueryTimeout derives the context of one query from ctx, usually a request's, so the query is
+// cancelled when the client goes away or after the query timeout.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// InitDB opens the PostgreSQL database at databaseURL when it is set, and otherwise the SQLite file
+// at databasePath, in WAL mode so report reads do not block on uploads.
+// The pragmas are part of the DSN so that every pooled connection gets them.
+func InitDB(databasePath, databaseURL string, settings Settings) {
+	var db *sql.DB
+	var err error
+	if databaseURL != "" {
+		if !isPostgresURL(databaseURL) {
+			stdlog.Fatalf("DATABASE_URL must be a postgres:// or postgresql:// URL")
+		}
+		postgres = true
+		db, err = openPostgres(databaseURL)
+	} else {
+		db, err = sql.Open("sqlite", buildDSN(databasePath, settings))
+	}
 	if err != nil {
-		stdlog.Fatalf("failed to open database at %s: %v", databasePath, err)
+		stdlog.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(settings.MaxOpenConns)
+	db.SetMaxIdleConns(settings.MaxIdleConns)
+	db.SetConnMaxLifetime(settings.ConnMaxLifetime)
+	if settings.QueryTimeout > 0 {
+		queryTimeout = settings.QueryTimeout
 	}
+
 	if err = db.Ping(); err != nil {
 		stdlog.Fatalf("failed to ping database: %v", err)
 	}
 	DB = db
-	logger.L.Info("Database connection established.")
+	if postgres {
+		logger.L.Info("Database connection established.", "driver", "postgres", "maxOpenConns", settings.MaxOpenConns)
+		return
+	}
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		logger.L.Warn("Could not read SQLite journal mode", "error", err)
+	}
+	logger.L.Info("Database connection established.", "driver", "sqlite", "journalMode", journalMode, "maxOpenConns", settings.MaxOpenConns, "busyTimeout", settings.BusyTimeout.String())
+}
+
+// buildDSN appends the connection pragmas to the database path.
+// Write transactions start with BEGIN IMMEDIATE so they take the write lock up front
+// and wait on busy_timeout, instead of failing when upgrading from a read lock.
+func buildDSN(databasePath string, settings Settings) string {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", settings.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+
+	separator := "?"
+	if strings.Contains(databasePath, "?") {
+		separator = "&"
+	}
+	return databasePath + separator + params.Encode()
 }
 
-func RunMigrations(databasePath string) {
+// RunMigrations applies the migrations in db/migrations, or db/migrations/postgres for PostgreSQL.
+func RunMigrations(databasePath, databaseURL string) {
 	if DB == nil {
 		logger.L.Error("Database connection is not initialized before running migrations")
 		return
 	}
 
-	driver, err := sqlite.WithInstance(DB, &sqlite.Config{})
-	if err != nil {
-		logger.L.Error("Could not create sqlite migration driver", "error", err)
-		stdlog.Fatalf("could not create sqlite migration driver: %v", err)
+	databaseName := databasePath
+	var driver migratedatabase.Driver
+	if postgres {
+		databaseName = "postgres"
+		driver = postgresMigrationDriver(databaseURL)
+	} else {
+		sqliteDriver, err := sqlite.WithInstance(DB, &sqlite.Config{})
+		if err != nil {
+			logger.L.Error("Could not create sqlite migration driver", "error", err)
+			stdlog.Fatalf("could not create sqlite migration driver: %v", err)
+		}
+		driver = sqliteDriver
 	}
 
 	var migrationsSourceURL string
@@ -63,10 +149,13 @@ func RunMigrations(databasePath string) {
 		migrationsSourceURL = fmt.Sprintf("file://%s", filepath.ToSlash(localMigrationsPath))
 		// --- FIM DA CORREÇÃO PARA WINDOWS ---
 	}
+	if postgres {
+		migrationsSourceURL += "/postgres"
+	}
 
 	m, err := migrate.NewWithDatabaseInstance(
 		migrationsSourceURL,
-		databasePath,
+		databaseName,
 		driver,
 	)
 	if err != nil {
@@ -87,3 +176,35 @@ func RunMigrations(databasePath string) {
 		logger.L.Info("Database migrations applied successfully.")
 	}
 }
+
+// postgresMigrationDriver runs the migrations over a connection of its own: the migration files are
+// plain PostgreSQL, not to be rebound like the queries of DB.
+func postgresMigrationDriver(databaseURL string) migratedatabase.Driver {
+	migrationDB, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		stdlog.Fatalf("could not open postgres connection for migrations: %v", err)
+	}
+	driver, err := pgxmigrate.WithInstance(migrationDB, &pgxmigrate.Config{})
+	if err != nil {
+		logger.L.Error("Could not create postgres migration driver", "error", err)
+		stdlog.Fatalf("could not create postgres migration driver: %v", err)
+	}
+	return driver
+}
+
+// ErrBackupUnsupported is returned by Backup for PostgreSQL, which is backed up with its own tools
+// such as pg_dump.
+var ErrBackupUnsupported = errors.New("online backups are only supported for SQLite databases")
+
+// Backup writes a consistent copy of the SQLite database db to the file at path with VACUUM INTO,
+// while the database stays in use. The copy is compacted and does not need the WAL file. path must
+// not exist.
+func Backup(ctx context.Context, db *sql.DB, path string) error {
+	if postgres {
+		return ErrBackupUnsupported
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up the database to %s: %w", path, err)
+	}
+	return nil
+}
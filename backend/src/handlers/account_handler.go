@@ -13,6 +13,33 @@ type DeleteAccountRequest struct {
 	Password string `json:"password"`
 }
 
+// DeleteAccountResponse reports what deleting an account erased, and what it could not.
+type DeleteAccountResponse struct {
+	Deleted struct {
+		Transactions           int64 `json:"transactions"`
+		TransactionAnnotations int64 `json:"transaction_annotations"` // Tags and notes
+		SkippedTransactions    int64 `json:"skipped_transactions"`    // Quarantined rows, with their raw data
+		Sessions               int64 `json:"sessions"`
+		AlertRules             int64 `json:"alert_rules"`
+		Goals                  int64 `json:"goals"`
+		Webhooks               int64 `json:"webhooks"` // With their deliveries
+		ShareLinks             int64 `json:"share_links"`
+		Household              int64 `json:"household"` // Membership, dissolving a household of two, and invitations sent or received
+		AuditLog               int64 `json:"audit_log"`
+	} `json:"deleted"`
+	Anonymized struct {
+		OutboxEmails int64 `json:"outbox_emails"` // Emails to the account, stripped of address and contents
+	} `json:"anonymized"`
+	Retained []string `json:"retained"` // Data outside the database, which deletion does not reach
+}
+
+// accountDeletionRetained describes the data an account deletion leaves, for the response.
+var accountDeletionRetained = []string{
+	"Server logs written before the deletion are not rewritten; they expire with the host's log retention.",
+	"Metrics carry no user identifiers.",
+	"A Stripe subscription is not cancelled and stays with Stripe.",
+}
+
 func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := GetUserIDFromContext(r.Context())
 	if !ok {
@@ -33,8 +60,14 @@ func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// CORREÇÃO: Apenas verificar a password para contas locais
-	if user.AuthProvider == "local" {
+	// CORREÇÃO: Apenas verificar a password para contas com login por password
+	hasLocal, err := model.HasIdentity(database.DB, userID, model.ProviderLocal)
+	if err != nil {
+		logger.L.Error("Failed to check local identity for account deletion", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to retrieve user information", http.StatusInternalServerError)
+		return
+	}
+	if hasLocal || user.AuthProvider == model.ProviderLocal {
 		if err := user.CheckPassword(req.Password); err != nil {
 			logger.L.Warn("Password mismatch for account deletion", "userID", userID)
 			sendJSONError(w, "Incorrect password. Account deletion failed.", http.StatusForbidden)
@@ -42,8 +75,10 @@ func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	// Begin transaction
-	txDB, err := database.DB.Begin()
+	// Begin transaction; nothing is deleted when the client goes away midway
+	ctx, cancel := database.WithQueryTimeout(r.Context())
+	defer cancel()
+	txDB, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
 		logger.L.Error("Failed to begin transaction for account deletion", "userID", userID, "error", err)
 		sendJSONError(w, "Failed to delete account", http.StatusInternalServerError)
@@ -59,19 +94,126 @@ func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}()
 
-	if _, err = txDB.Exec("DELETE FROM processed_transactions WHERE user_id = ?", userID); err != nil {
+	var response DeleteAccountResponse
+	response.Retained = accountDeletionRetained
+	if response.Deleted.TransactionAnnotations, err = model.DeleteTransactionAnnotations(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete transaction notes and tags for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (transaction tags)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.Transactions, err = h.transactions.DeleteByUser(txDB, userID); err != nil {
 		logger.L.Error("Failed to delete processed transactions for user", "userID", userID, "error", err)
 		sendJSONError(w, "Failed to delete account data (transactions)", http.StatusInternalServerError)
 		return
 	}
 
-	if _, err = txDB.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+	if response.Deleted.SkippedTransactions, err = model.DeleteSkippedTransactions(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete skipped transactions for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (skipped transactions)", http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteMaterializedReports(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete materialized reports for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (reports)", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = txDB.ExecContext(ctx, "DELETE FROM broker_connections WHERE user_id = ?", userID); err != nil {
+		logger.L.Error("Failed to delete broker connections for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (broker connections)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.AlertRules, err = model.DeleteAlertRules(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete alert rules for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (alert rules)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.Goals, err = model.DeleteGoals(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete goals for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (goals)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.Webhooks, err = model.DeleteWebhooks(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete webhooks for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (webhooks)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.ShareLinks, err = model.DeleteShareLinks(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete share links for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (share links)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.Household, err = model.DeleteHouseholdData(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete household data for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (household)", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Deleted.AuditLog, err = model.DeleteAuditLog(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete audit log for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (audit log)", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = txDB.ExecContext(ctx, "DELETE FROM csv_mappings WHERE user_id = ?", userID); err != nil {
+		logger.L.Error("Failed to delete CSV mappings for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (CSV mappings)", http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteUploadUsage(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete upload usage for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (upload usage)", http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteUploadBatches(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete upload batches for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (upload batches)", http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteUserSettings(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete settings for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (settings)", http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteSubscription(txDB, userID); err != nil {
+		logger.L.Error("Failed to delete subscription for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (subscription)", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = txDB.ExecContext(ctx, "DELETE FROM user_identities WHERE user_id = ?", userID); err != nil {
+		logger.L.Error("Failed to delete identities for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (identities)", http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := txDB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
 		logger.L.Error("Failed to delete sessions for user", "userID", userID, "error", err)
 		sendJSONError(w, "Failed to delete account data (sessions)", http.StatusInternalServerError)
 		return
 	}
+	response.Deleted.Sessions, _ = sessions.RowsAffected()
 
-	if _, err = txDB.Exec("DELETE FROM users WHERE id = ?", userID); err != nil {
+	// The outbox keeps a record of the emails sent, which hold the address and the username.
+	if response.Anonymized.OutboxEmails, err = model.AnonymizeOutboxEmails(txDB, user.Email); err != nil {
+		logger.L.Error("Failed to anonymize outbox emails for user", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to delete account data (emails)", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = txDB.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID); err != nil {
 		logger.L.Error("Failed to delete user from users table", "userID", userID, "error", err)
 		sendJSONError(w, "Failed to delete user account", http.StatusInternalServerError)
 		return
@@ -84,8 +226,10 @@ func (h *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Reques
 	}
 	committed = true
 
-	logger.L.Info("Account deleted successfully", "userID", userID)
-	w.WriteHeader(http.StatusNoContent)
+	logger.L.Info("Account deleted successfully", "userID", userID, "transactions", response.Deleted.Transactions,
+		"outboxEmailsAnonymized", response.Anonymized.OutboxEmails)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *UserHandler) HandleCheckUserData(w http.ResponseWriter, r *http.Request) {
@@ -94,8 +238,7 @@ func (h *UserHandler) HandleCheckUserData(w http.ResponseWriter, r *http.Request
 		sendJSONError(w, "authentication required", http.StatusUnauthorized)
 		return
 	}
-	var count int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM processed_transactions WHERE user_id = ?", userID).Scan(&count)
+	count, err := h.transactions.CountByUser(r.Context(), userID)
 	if err != nil {
 		logger.L.Error("Error checking user data", "userID", userID, "error", err)
 		sendJSONError(w, "failed to check user data", http.StatusInternalServerError)
@@ -0,0 +1,291 @@
+// backend/src/handlers/admin_handler.go
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// AdminHandler serves operator endpoints protected by the admin token.
+type AdminHandler struct {
+	maintenanceService services.MaintenanceService
+	quotaService       services.QuotaService
+	billingService     services.BillingService
+	statusService      services.StatusService
+	uploadService      services.UploadService
+	emailService       services.EmailService
+	encryptionService  services.EncryptionService
+}
+
+// NewAdminHandler creates a new instance of AdminHandler.
+func NewAdminHandler(maintenanceService services.MaintenanceService, quotaService services.QuotaService, billingService services.BillingService, statusService services.StatusService, uploadService services.UploadService, emailService services.EmailService, encryptionService services.EncryptionService) *AdminHandler {
+	return &AdminHandler{
+		maintenanceService: maintenanceService,
+		quotaService:       quotaService,
+		billingService:     billingService,
+		statusService:      statusService,
+		uploadService:      uploadService,
+		emailService:       emailService,
+		encryptionService:  encryptionService,
+	}
+}
+
+// SetPlanRequest is the body of PUT /admin/users/{id}/plan.
+type SetPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// SetPlanPriceRequest is the body of PUT /admin/plans/{name}/price.
+type SetPlanPriceRequest struct {
+	StripePriceID string `json:"stripe_price_id"`
+}
+
+// HandleRunMaintenance runs the session and token cleanup immediately and returns what it removed.
+func (h *AdminHandler) HandleRunMaintenance(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context()).Info("Handling RunMaintenance request")
+
+	report, err := h.maintenanceService.RunCleanup()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error running maintenance cleanup", "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error running maintenance cleanup: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding maintenance report to JSON", "error", err)
+	}
+}
+
+// HandleRunBackup backs up the database immediately, for instance before a risky migration, and
+// returns where the backup went. The backup goes on when the client disconnects.
+func (h *AdminHandler) HandleRunBackup(w http.ResponseWriter, r *http.Request) {
+	report, err := h.maintenanceService.RunBackup(context.WithoutCancel(r.Context()))
+	switch {
+	case errors.Is(err, services.ErrBackupNotConfigured), errors.Is(err, database.ErrBackupUnsupported), errors.Is(err, services.ErrBackupInProgress):
+		utils.SendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("Error backing up the database", "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error backing up the database: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding backup report to JSON", "error", err)
+	}
+}
+
+// HandleReencrypt encrypts the stored secrets with the current key and returns what it changed.
+func (h *AdminHandler) HandleReencrypt(w http.ResponseWriter, r *http.Request) {
+	report, err := h.encryptionService.ReencryptAll()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error re-encrypting stored secrets", "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error re-encrypting stored secrets: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding re-encryption report to JSON", "error", err)
+	}
+}
+
+// HandleSetUserPlan moves a user to another subscription plan.
+func (h *AdminHandler) HandleSetUserPlan(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.quotaService.SetPlan(userID, req.Plan); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownPlan):
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.SendJSONError(w, "User not found", http.StatusNotFound)
+		default:
+			logger.FromContext(r.Context()).Error("Error setting user plan", "userID", userID, "error", err)
+			utils.SendJSONError(w, "Error setting user plan", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("User plan changed", "userID", userID, "plan", req.Plan)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetPlanPrice links a billing plan to the Stripe price that buys it. An empty price
+// takes the plan off sale.
+func (h *AdminHandler) HandleSetPlanPrice(w http.ResponseWriter, r *http.Request) {
+	plan := chi.URLParam(r, "name")
+
+	var req SetPlanPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.billingService.SetPlanPrice(plan, req.StripePriceID); err != nil {
+		if errors.Is(err, services.ErrUnknownPlan) {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error setting plan price", "plan", plan, "error", err)
+		utils.SendJSONError(w, "Error setting plan price", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("Plan price changed", "plan", plan, "stripePriceID", req.StripePriceID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetAnnouncement replaces the announcement shown to every user.
+func (h *AdminHandler) HandleSetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := h.statusService.SetAnnouncement(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAnnouncement) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error setting announcement", "error", err)
+		utils.SendJSONError(w, "Error setting announcement", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(announcement); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding announcement to JSON", "error", err)
+	}
+}
+
+// HandleClearAnnouncement removes the announcement shown to every user.
+func (h *AdminHandler) HandleClearAnnouncement(w http.ResponseWriter, r *http.Request) {
+	if err := h.statusService.ClearAnnouncement(); err != nil {
+		logger.FromContext(r.Context()).Error("Error clearing announcement", "error", err)
+		utils.SendJSONError(w, "Error clearing announcement", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// defaultUnknownDescriptionsLimit and maxUnknownDescriptionsLimit bound the patterns listed by
+// GET /admin/unknown-descriptions.
+const (
+	defaultUnknownDescriptionsLimit = 50
+	maxUnknownDescriptionsLimit     = 500
+)
+
+// HandleGetUnknownDescriptions lists the most frequent description patterns the parsers could not
+// classify, as reported by the users who opted in, so parser rules can be added for them.
+func (h *AdminHandler) HandleGetUnknownDescriptions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultUnknownDescriptionsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUnknownDescriptionsLimit {
+			utils.SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxUnknownDescriptionsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	patterns, err := h.uploadService.GetUnknownDescriptions(limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading unknown descriptions", "error", err)
+		utils.SendJSONError(w, "Error loading unknown descriptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(patterns); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding unknown descriptions to JSON", "error", err)
+	}
+}
+
+// defaultOutboxLimit and maxOutboxLimit bound the emails listed by GET /admin/outbox.
+const (
+	defaultOutboxLimit = 50
+	maxOutboxLimit     = 500
+)
+
+// HandleGetOutbox lists the emails of the outbox, newest first, optionally only those in the status
+// given by the status parameter, to follow up on emails that could not be sent.
+func (h *AdminHandler) HandleGetOutbox(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.OutboxPending, models.OutboxSent, models.OutboxFailed:
+	default:
+		utils.SendJSONError(w, "status must be pending, sent or failed", http.StatusBadRequest)
+		return
+	}
+	limit := defaultOutboxLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxOutboxLimit {
+			utils.SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxOutboxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	emails, err := h.emailService.GetOutboxEmails(status, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading outbox emails", "error", err)
+		utils.SendJSONError(w, "Error loading outbox emails", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(emails); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding outbox emails to JSON", "error", err)
+	}
+}
+
+// HandleRetryOutboxEmail queues a failed email to be sent again.
+func (h *AdminHandler) HandleRetryOutboxEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid email ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.emailService.RetryOutboxEmail(id); err != nil {
+		if errors.Is(err, services.ErrOutboxEmailNotFound) {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error queuing outbox email", "outboxID", id, "error", err)
+		utils.SendJSONError(w, "Error queuing outbox email", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("Outbox email queued again", "outboxID", id)
+	w.WriteHeader(http.StatusNoContent)
+}
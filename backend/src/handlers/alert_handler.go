@@ -0,0 +1,144 @@
+// backend/src/handlers/alert_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// AlertHandler manages the user's alert rules.
+type AlertHandler struct {
+	alertService services.AlertService
+}
+
+// NewAlertHandler creates a new instance of AlertHandler.
+func NewAlertHandler(alertService services.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// AlertRuleRequest is the body of a request creating or changing an alert rule.
+type AlertRuleRequest struct {
+	Kind      string  `json:"kind"`
+	ISIN      string  `json:"isin"`
+	Threshold float64 `json:"threshold"`
+	Enabled   *bool   `json:"enabled"` // Defaults to true
+}
+
+func (req AlertRuleRequest) rule() models.AlertRule {
+	rule := models.AlertRule{Kind: req.Kind, ISIN: req.ISIN, Threshold: req.Threshold, Enabled: true}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+// sendAlertError maps alert service errors to HTTP responses.
+func sendAlertError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAlertRule):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrAlertRuleNotFound):
+		utils.SendJSONError(w, "Alert rule not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling alert rules", "error", err)
+		utils.SendJSONError(w, "Error handling alert rules", http.StatusInternalServerError)
+	}
+}
+
+// HandleGetAlertRules lists the user's alert rules.
+func (h *AlertHandler) HandleGetAlertRules(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.alertService.GetRules(userID)
+	if err != nil {
+		sendAlertError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// HandleCreateAlertRule adds an alert rule.
+func (h *AlertHandler) HandleCreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule, err := h.alertService.CreateRule(userID, req.rule())
+	if err != nil {
+		sendAlertError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// HandleUpdateAlertRule replaces the condition of an alert rule, re-arming it.
+func (h *AlertHandler) HandleUpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid alert rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule, err := h.alertService.UpdateRule(userID, id, req.rule())
+	if err != nil {
+		sendAlertError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// HandleDeleteAlertRule deletes an alert rule.
+func (h *AlertHandler) HandleDeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid alert rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertService.DeleteRule(userID, id); err != nil {
+		sendAlertError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
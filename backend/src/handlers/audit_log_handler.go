@@ -0,0 +1,87 @@
+// backend/src/handlers/audit_log_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 200
+	maxAuditUserAgentLen = 512 // Longer user agents are cut, so clients cannot fill the table
+)
+
+// recordAudit adds an action on the user's account to their audit log, with the IP address and user
+// agent of the request. Failing to record it is logged and does not fail the request.
+func recordAudit(r *http.Request, userID int64, action string, details map[string]string) {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxAuditUserAgentLen {
+		userAgent = strings.ToValidUTF8(userAgent[:maxAuditUserAgentLen], "")
+	}
+
+	entry := &models.AuditLogEntry{
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+	if err := model.CreateAuditLogEntry(database.DB, entry); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to record audit log entry", "userID", userID, "action", action, "error", err)
+	}
+}
+
+// HandleGetAuditLog lists the security-relevant actions on the authenticated user's account, newest
+// first. limit caps the entries returned and before, the ID of the last entry of the previous page,
+// pages through older ones.
+func (h *UserHandler) HandleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultAuditLogLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxAuditLogLimit {
+			sendJSONError(w, "Invalid limit. Use a number from 1 to "+strconv.Itoa(maxAuditLogLimit)+".", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	var before int64
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 {
+			sendJSONError(w, "Invalid before. Use the ID of an audit log entry.", http.StatusBadRequest)
+			return
+		}
+		before = parsed
+	}
+
+	entries, err := model.GetAuditLog(database.DB, userID, before, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to get audit log", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to retrieve account activity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -7,14 +7,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/username/taxfolio/backend/src/config"
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
 )
 
 func (h *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -45,8 +49,7 @@ func (h *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request
 		sendJSONError(w, "Invalid email format", http.StatusBadRequest)
 		return
 	}
-	if !passwordRegex.MatchString(credentials.Password) {
-		sendJSONError(w, "Password must be at least 6 characters long", http.StatusBadRequest)
+	if !h.checkPassword(w, r, credentials.Password, credentials.Username, credentials.Email) {
 		return
 	}
 
@@ -102,7 +105,24 @@ func (h *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = h.emailService.SendVerificationEmail(user.Email, user.Username, verificationToken)
+	// New accounts start in the language the browser asked for; it can be changed later in the settings.
+	locale := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err := model.SetUserLocale(database.DB, user.ID, locale); err != nil {
+		logger.L.Warn("Failed to store locale for new user", "userID", user.ID, "locale", locale, "error", err)
+	}
+
+	if err := model.CreateIdentity(database.DB, &model.UserIdentity{
+		UserID:         user.ID,
+		Provider:       model.ProviderLocal,
+		ProviderUserID: user.Email,
+		Email:          user.Email,
+	}); err != nil {
+		logger.L.Error("Failed to create local identity for new user", "userID", user.ID, "error", err)
+		sendJSONError(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.emailService.SendVerificationEmail(user.Email, user.Username, verificationToken, locale)
 	if err != nil {
 		logger.L.Error("Failed to send verification email after user creation", "userEmail", user.Email, "error", err)
 		w.Header().Set("Content-Type", "application/json")
@@ -143,19 +163,50 @@ func (h *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	credentials.Email = strings.ToLower(strings.TrimSpace(credentials.Email))
 
 	logger.L.Info("Login attempt", "email", credentials.Email)
+	// Emails no account has are answered, and locked, as accounts with a wrong password are, so the
+	// response does not tell whether the account exists.
+	requestLocale := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
 	user, err := model.GetUserByEmail(database.DB, credentials.Email)
 	if err != nil {
 		logger.L.Warn("User lookup by email failed for login", "email", credentials.Email, "error", err)
+		if lockedUntil := unknownEmailLockedUntil(credentials.Email); time.Now().Before(lockedUntil) {
+			sendAccountLocked(w, requestLocale, lockedUntil)
+			return
+		}
+		checkDummyPassword(credentials.Password)
+		if lockedUntil := recordUnknownEmailLogin(credentials.Email); !lockedUntil.IsZero() {
+			sendAccountLocked(w, requestLocale, lockedUntil)
+			return
+		}
 		sendJSONError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
+	if lockedUntil, err := model.GetLockedUntil(database.DB, user.ID); err != nil {
+		logger.L.Error("Failed to load account lock for login", "userID", user.ID, "error", err)
+	} else if time.Now().Before(lockedUntil) {
+		logger.L.Warn("Login attempt on locked account", "userID", user.ID, "lockedUntil", lockedUntil)
+		sendAccountLocked(w, requestLocale, lockedUntil)
+		return
+	}
+
 	if err := user.CheckPassword(credentials.Password); err != nil {
 		logger.L.Warn("Password check failed for login", "email", credentials.Email, "error", err)
+		lockedUntil := h.recordFailedLogin(r, user)
+		if !lockedUntil.IsZero() {
+			recordAudit(r, user.ID, models.AuditLoginFailed, map[string]string{"locked_until": lockedUntil.UTC().Format(time.RFC3339)})
+			sendAccountLocked(w, requestLocale, lockedUntil)
+			return
+		}
+		recordAudit(r, user.ID, models.AuditLoginFailed, nil)
 		sendJSONError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
+	if err := model.ResetFailedLogins(database.DB, user.ID); err != nil {
+		logger.L.Error("Failed to reset failed login count", "userID", user.ID, "error", err)
+	}
+
 	if !user.IsEmailVerified {
 		logger.L.Warn("Login attempt failed: email not verified. Resending verification.", "email", credentials.Email, "userID", user.ID)
 
@@ -169,7 +220,7 @@ func (h *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 			if err := user.UpdateUserVerificationToken(database.DB, verificationToken, tokenExpiry); err != nil {
 				logger.L.Error("Failed to update verification token in DB on login attempt", "userID", user.ID, "error", err)
 			} else {
-				err = h.emailService.SendVerificationEmail(user.Email, user.Username, verificationToken)
+				err = h.emailService.SendVerificationEmail(user.Email, user.Username, verificationToken, storedLocale(r, user.ID))
 				if err != nil {
 					logger.L.Error("Failed to resend verification email on login attempt", "userEmail", user.Email, "error", err)
 				} else {
@@ -178,58 +229,60 @@ func (h *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "O teu e-mail ainda não foi verificado. Enviámos um novo link de verificação para o seu endereço de email.",
-			"code":  "EMAIL_NOT_VERIFIED",
-		})
+		utils.SendError(w, http.StatusForbidden, "EMAIL_NOT_VERIFIED",
+			"O teu e-mail ainda não foi verificado. Enviámos um novo link de verificação para o seu endereço de email.", nil)
 		return
 	}
 
-	userIDStr := fmt.Sprintf("%d", user.ID)
-	accessToken, err := h.authService.GenerateToken(userIDStr)
+	accessToken, refreshToken, err := h.createSession(r, user.ID)
 	if err != nil {
-		logger.L.Error("Failed to generate access token", "userID", user.ID, "error", err)
-		sendJSONError(w, "Failed to generate access token", http.StatusInternalServerError)
+		logger.L.Error("Failed to create session", "userID", user.ID, "error", err)
+		sendJSONError(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(r, user.ID, models.AuditLogin, map[string]string{"provider": model.ProviderLocal})
+
+	rotateCSRFToken(w, r, accessToken)
+
+	userData := map[string]interface{}{
+		"id":            user.ID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"auth_provider": user.AuthProvider,
+	}
+
+	response := sessionTokens(w, r, accessToken, refreshToken)
+	response["user"] = userData
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// createSession issues an access/refresh token pair for the user and stores the session.
+func (h *UserHandler) createSession(r *http.Request, userID int64) (string, string, error) {
+	now := time.Now()
+	accessToken, err := h.authService.GenerateToken(fmt.Sprintf("%d", userID))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
 
 	refreshToken, err := h.authService.GenerateRefreshToken()
 	if err != nil {
-		logger.L.Error("Failed to generate refresh token", "userID", user.ID, "error", err)
-		sendJSONError(w, "Failed to generate refresh token", http.StatusInternalServerError)
-		return
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
 	session := &model.Session{
-		UserID:       user.ID,
+		UserID:       userID,
 		Token:        accessToken,
 		RefreshToken: refreshToken,
 		UserAgent:    r.UserAgent(),
 		ClientIP:     r.RemoteAddr,
 		IsBlocked:    false,
-		ExpiresAt:    time.Now().Add(config.Cfg.RefreshTokenExpiry),
+		ExpiresAt:    sessionExpiry(now, now),
 	}
-	if err := model.CreateSession(database.DB, session); err != nil {
-		logger.L.Error("Failed to create session", "userID", user.ID, "error", err)
-		sendJSONError(w, "Failed to create session", http.StatusInternalServerError)
-		return
+	if err := model.CreateSession(database.DB, h.keyring, session); err != nil {
+		return "", "", err
 	}
-
-	userData := map[string]interface{}{
-		"id":            user.ID,
-		"username":      user.Username,
-		"email":         user.Email,
-		"auth_provider": user.AuthProvider,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
-		"user":          userData,
-	})
+	return accessToken, refreshToken, nil
 }
 
 func (h *UserHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -237,17 +290,21 @@ func (h *UserHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request
 		RefreshToken string `json:"refresh_token"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+	// With AUTH_COOKIES the refresh token comes in its cookie and the body may be empty.
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && !errors.Is(err, io.EOF) {
 		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if requestBody.RefreshToken == "" {
+		requestBody.RefreshToken = refreshTokenCookie(r)
+	}
 
 	if requestBody.RefreshToken == "" {
 		sendJSONError(w, "Refresh token is required", http.StatusBadRequest)
 		return
 	}
 
-	oldSession, err := model.GetSessionByRefreshToken(database.DB, requestBody.RefreshToken)
+	oldSession, err := model.GetSessionByRefreshToken(database.DB, h.keyring, requestBody.RefreshToken)
 	if err != nil {
 		logger.L.Warn("Refresh token lookup failed or token invalid/expired", "error", err)
 		sendJSONError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
@@ -273,6 +330,7 @@ func (h *UserHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	now := time.Now()
 	newSession := &model.Session{
 		UserID:       oldSession.UserID,
 		Token:        newAccessToken,
@@ -280,20 +338,18 @@ func (h *UserHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request
 		UserAgent:    r.UserAgent(),
 		ClientIP:     r.RemoteAddr,
 		IsBlocked:    false,
-		ExpiresAt:    time.Now().Add(config.Cfg.RefreshTokenExpiry),
+		ExpiresAt:    sessionExpiry(now, now),
 	}
 
-	if err := model.CreateSession(database.DB, newSession); err != nil {
+	if err := model.CreateSession(database.DB, h.keyring, newSession); err != nil {
 		logger.L.Error("Failed to create new session on refresh", "userID", oldSession.UserID, "error", err)
 		sendJSONError(w, "Failed to create new session on refresh", http.StatusInternalServerError)
 		return
 	}
 
+	rotateCSRFToken(w, r, newAccessToken)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"access_token":  newAccessToken,
-		"refresh_token": newRefreshToken,
-	})
+	json.NewEncoder(w).Encode(sessionTokens(w, r, newAccessToken, newRefreshToken))
 }
 
 func (h *UserHandler) LogoutUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -304,14 +360,7 @@ func (h *UserHandler) LogoutUserHandler(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	tokenString := ""
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-	} else {
-		tokenString = authHeader
-	}
-
+	tokenString := bearerToken(r)
 	if tokenString != "" {
 		err := model.DeleteSessionByToken(database.DB, tokenString)
 		if err != nil {
@@ -322,6 +371,9 @@ func (h *UserHandler) LogoutUserHandler(w http.ResponseWriter, r *http.Request)
 	} else {
 		logger.L.Warn("Logout attempt with no token in Authorization header")
 	}
+	if config.Cfg.AuthCookies {
+		clearAuthCookies(w, r)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
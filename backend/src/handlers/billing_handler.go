@@ -0,0 +1,150 @@
+// backend/src/handlers/billing_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// Stripe webhook payloads are small JSON documents; anything larger is not from Stripe.
+const maxWebhookPayloadBytes = 1 << 20
+
+// BillingHandler serves the plan catalog, checkout and the payment provider's webhooks.
+type BillingHandler struct {
+	billingService services.BillingService
+}
+
+// NewBillingHandler creates a new instance of BillingHandler.
+func NewBillingHandler(billingService services.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+	}
+}
+
+// HandleGetPlans returns the billing catalog and whether paid plans can be bought.
+func (h *BillingHandler) HandleGetPlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := h.billingService.GetPlans()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading billing plans", "error", err)
+		utils.SendJSONError(w, "Error loading billing plans", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"billing_enabled": h.billingService.Enabled(),
+		"plans":           plans,
+	})
+}
+
+// HandleGetSubscription returns the user's subscription, or 404 if they never subscribed.
+func (h *BillingHandler) HandleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	sub, err := h.billingService.GetSubscription(userID)
+	if errors.Is(err, model.ErrSubscriptionNotFound) {
+		utils.SendJSONError(w, "No subscription found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading subscription", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error loading subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding subscription to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleCreateCheckout starts a checkout for a paid plan and returns the URL to redirect the user to.
+func (h *BillingHandler) HandleCreateCheckout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	checkoutURL, err := h.billingService.CreateCheckoutSession(userID, req.Plan)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBillingDisabled):
+			utils.SendJSONError(w, err.Error(), http.StatusServiceUnavailable)
+		case errors.Is(err, services.ErrUnknownPlan), errors.Is(err, services.ErrPlanNotPurchasable):
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrBillingProviderFailed):
+			logger.FromContext(r.Context()).Error("Payment provider rejected checkout", "userID", userID, "error", err)
+			utils.SendJSONError(w, "Could not start checkout, please try again later", http.StatusBadGateway)
+		default:
+			logger.FromContext(r.Context()).Error("Error creating checkout session", "userID", userID, "error", err)
+			utils.SendJSONError(w, "Error creating checkout session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": checkoutURL})
+}
+
+// HandleWebhook receives subscription events from Stripe. It is authenticated by the event
+// signature rather than a user session, and answers 400 for events Stripe should not retry.
+func (h *BillingHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadBytes))
+	if err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.billingService.HandleWebhook(payload, r.Header.Get("Stripe-Signature")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrBillingDisabled):
+			utils.SendJSONError(w, "Not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrInvalidWebhookSignature):
+			logger.FromContext(r.Context()).Warn("Rejected billing webhook", "error", err)
+			utils.SendJSONError(w, "invalid signature", http.StatusBadRequest)
+		default:
+			logger.FromContext(r.Context()).Error("Error handling billing webhook", "error", err)
+			utils.SendJSONError(w, "Error handling webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPremiumAccess answers with 403 and code PREMIUM_REQUIRED unless the user's plan includes
+// premium features, and reports whether the request may go on.
+func checkPremiumAccess(w http.ResponseWriter, r *http.Request, billingService services.BillingService, userID int64) bool {
+	allowed, err := billingService.HasPremiumAccess(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error checking premium access", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error checking subscription", http.StatusInternalServerError)
+		return false
+	}
+	if allowed {
+		return true
+	}
+	logger.FromContext(r.Context()).Info("Premium feature refused", "userID", userID, "path", r.URL.Path)
+
+	utils.SendError(w, http.StatusForbidden, "PREMIUM_REQUIRED", i18n.T(i18n.FromContext(r.Context()), i18n.MsgPremiumRequired), nil)
+	return false
+}
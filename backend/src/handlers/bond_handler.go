@@ -0,0 +1,47 @@
+// backend/src/handlers/bond_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// BondHandler serves the income of the user's bonds.
+type BondHandler struct {
+	bondService services.BondService
+}
+
+// NewBondHandler creates a new instance of BondHandler.
+func NewBondHandler(bondService services.BondService) *BondHandler {
+	return &BondHandler{
+		bondService: bondService,
+	}
+}
+
+// HandleGetBondIncome returns the coupons, accrued interest and bond sale and redemption gains, with
+// their totals per tax year.
+func (h *BondHandler) HandleGetBondIncome(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetBondIncome request", "userID", userID)
+
+	report, err := h.bondService.GetBondIncome(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing bond income", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing bond income: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding bond income to JSON", "userID", userID, "error", err)
+	}
+}
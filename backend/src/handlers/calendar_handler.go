@@ -0,0 +1,105 @@
+// backend/src/handlers/calendar_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// CalendarHandler serves the calendar feed and manages its token.
+type CalendarHandler struct {
+	calendarService services.CalendarService
+}
+
+// NewCalendarHandler creates a new instance of CalendarHandler.
+func NewCalendarHandler(calendarService services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+// HandleGetCalendarFeed serves the iCalendar feed of the user the token parameter was issued to. It
+// is public, as calendar apps cannot log in: the token is the only credential.
+func (h *CalendarHandler) HandleGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.calendarService.UserForToken(r.URL.Query().Get("token"))
+	if errors.Is(err, services.ErrInvalidCalendarToken) {
+		utils.SendJSONError(w, "Invalid or revoked calendar token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error checking calendar token", "error", err)
+		utils.SendJSONError(w, "Error building calendar", http.StatusInternalServerError)
+		return
+	}
+
+	feed, err := h.calendarService.BuildFeed(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building calendar", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error building calendar", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="rumoclaro.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(feed)
+}
+
+// HandleGetCalendarToken returns whether the user's calendar feed is on and, if so, its URL.
+func (h *CalendarHandler) HandleGetCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	feed, err := h.calendarService.GetFeed(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error getting calendar feed", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error getting calendar feed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// HandleCreateCalendarToken turns the calendar feed on with a new token, revoking the previous one.
+func (h *CalendarHandler) HandleCreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	feed, err := h.calendarService.EnableFeed(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error creating calendar token", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error creating calendar token", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, userID, models.AuditCalendarFeedEnabled, nil)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// HandleDeleteCalendarToken turns the calendar feed off, revoking its token.
+func (h *CalendarHandler) HandleDeleteCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.calendarService.DisableFeed(userID); err != nil {
+		logger.FromContext(r.Context()).Error("Error revoking calendar token", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error revoking calendar token", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, userID, models.AuditCalendarFeedRevoked, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
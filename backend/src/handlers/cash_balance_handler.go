@@ -0,0 +1,70 @@
+// backend/src/handlers/cash_balance_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// CashBalanceHandler serves the cash balances rebuilt from the user's transactions.
+type CashBalanceHandler struct {
+	cashBalanceService services.CashBalanceService
+}
+
+// NewCashBalanceHandler creates a new instance of CashBalanceHandler.
+func NewCashBalanceHandler(cashBalanceService services.CashBalanceService) *CashBalanceHandler {
+	return &CashBalanceHandler{
+		cashBalanceService: cashBalanceService,
+	}
+}
+
+// HandleGetCashBalance returns the running cash balance per broker and currency, with the days its
+// reported balance does not match flagged.
+func (h *CashBalanceHandler) HandleGetCashBalance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetCashBalance request", "userID", userID)
+
+	report, err := h.cashBalanceService.GetCashBalance(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing cash balance", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing cash balance: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding cash balance to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleGetContributions returns the money deposited and withdrawn per month, with rolling averages
+// of the money put in and the largest deposit and withdrawal.
+func (h *CashBalanceHandler) HandleGetContributions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetContributions request", "userID", userID)
+
+	report, err := h.cashBalanceService.GetContributions(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing contributions", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing contributions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding contributions to JSON", "userID", userID, "error", err)
+	}
+}
@@ -1,54 +1,152 @@
 package handlers
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
-	"log/slog"
-	"strings"
-
-	// "log" // Replaced with slog from logger package
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	// To access CSRF key from config
-	"github.com/username/taxfolio/backend/src/logger" // Use new logger
+	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+	csrfTokenTTL   = time.Hour
+)
+
+var (
+	errCSRFMalformed = errors.New("malformed CSRF token")
+	errCSRFSignature = errors.New("CSRF token signature mismatch")
+	errCSRFExpired   = errors.New("CSRF token expired")
 )
 
+// A CSRF token has the form nonce.expiry.signature, where the signature is an HMAC-SHA256 over the
+// nonce, the expiry and the session the token was issued to, keyed with CSRFAuthKey. It is sent both
+// as a cookie and in the X-CSRF-Token header (double submit); the server keeps no state.
+
+// GetCSRFToken issues a fresh CSRF token bound to the caller's session.
 func GetCSRFToken(w http.ResponseWriter, r *http.Request) {
 	logger.L.Debug("Generating CSRF token", "remoteAddr", r.RemoteAddr)
-	// logger.L.Debug("Request headers for CSRF token generation", "headers", r.Header) // Can be verbose
+	token, err := setCSRFToken(w, r, bearerToken(r))
+	if err != nil {
+		logger.L.Error("Error generating CSRF token", "error", err)
+		sendJSONError(w, "Failed to generate CSRF token", http.StatusInternalServerError)
+		return
+	}
 
-	token := generateRandomToken()
-	logger.L.Debug("Generated CSRF token value (first 5 chars for brevity)", "tokenPrefix", token[:5])
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"csrfToken": token,
+	})
+}
+
+// rotateCSRFToken replaces the caller's CSRF token with one bound to a newly issued access token,
+// so the client does not have to fetch one again after logging in or refreshing its session.
+func rotateCSRFToken(w http.ResponseWriter, r *http.Request, accessToken string) {
+	if _, err := setCSRFToken(w, r, accessToken); err != nil {
+		logger.L.Error("Error rotating CSRF token", "error", err)
+	}
+}
 
+// setCSRFToken creates a token for the session and sends it as a cookie and a response header.
+func setCSRFToken(w http.ResponseWriter, r *http.Request, session string) (string, error) {
+	token, err := newCSRFToken(config.Cfg.CSRFAuthKey, session, time.Now().Add(csrfTokenTTL))
+	if err != nil {
+		return "", err
+	}
+	setCSRFCookie(w, r, token)
+	w.Header().Set(csrfHeaderName, token)
+	return token, nil
+}
+
+// setCSRFCookie stores the token in a cookie. It is Secure whenever the request reached us over
+// HTTPS, including through a TLS-terminating proxy, and always when SameSite=None is configured,
+// since browsers drop SameSite=None cookies that are not Secure.
+func setCSRFCookie(w http.ResponseWriter, r *http.Request, token string) {
+	sameSite := csrfCookieSameSite()
 	http.SetCookie(w, &http.Cookie{
-		Name:     "_gorilla_csrf",
+		Name:     csrfCookieName,
 		Value:    token,
 		Path:     "/",
-		SameSite: http.SameSiteLaxMode,
+		SameSite: sameSite,
 		HttpOnly: true,
-		Secure:   r.TLS != nil,
-		MaxAge:   3600,
+		Secure:   r.TLS != nil || sameSite == http.SameSiteNoneMode,
+		MaxAge:   int(csrfTokenTTL.Seconds()),
 	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-CSRF-Token", token)
+func csrfCookieSameSite() http.SameSite {
+	switch strings.ToLower(config.Cfg.CSRFCookieSameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"csrfToken": token,
-	})
+// bearerToken returns the access token the request is authenticated with, from the Authorization
+// header or, with AUTH_COOKIES, the access token cookie, or "" for anonymous callers. CSRF tokens are
+// bound to it, so a token issued to one session is useless in another.
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if config.Cfg.AuthCookies {
+		if cookie, err := r.Cookie(accessTokenCookieName); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func newCSRFToken(key []byte, session string, expiresAt time.Time) (string, error) {
+	nonceBytes := make([]byte, 32)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return nonce + "." + expiry + "." + signCSRFToken(key, session, nonce, expiry), nil
 }
 
-func generateRandomToken() string {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
+func signCSRFToken(key []byte, session, nonce, expiry string) string {
+	sessionHash := sha256.Sum256([]byte(session))
+	mac := hmac.New(sha256.New, key)
+	mac.Write(sessionHash[:])
+	mac.Write([]byte("|" + nonce + "|" + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validateCSRFToken checks that the token was signed with the key for this session and has not expired.
+func validateCSRFToken(key []byte, session, token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errCSRFMalformed
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
-		logger.L.Error("Error generating random bytes for CSRF token", "error", err)
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+		return errCSRFMalformed
 	}
-	return base64.StdEncoding.EncodeToString(b)
+	expected := signCSRFToken(key, session, parts[0], parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return errCSRFSignature
+	}
+	if now.Unix() >= expiresAt {
+		return errCSRFExpired
+	}
+	return nil
 }
 
 func CSRFMiddleware(csrfKey []byte) func(http.Handler) http.Handler {
@@ -74,46 +172,41 @@ func CSRFMiddleware(csrfKey []byte) func(http.Handler) http.Handler {
 				return
 			}
 
-			headerToken := r.Header.Get("X-CSRF-Token")
-			cookie, errCookie := r.Cookie("_gorilla_csrf") // Renamed err to errCookie for clarity
+			headerToken := r.Header.Get(csrfHeaderName)
+			cookie, errCookie := r.Cookie(csrfCookieName)
 
 			logger.L.Debug("CSRF validation attempt",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"headerTokenExists", headerToken != "",
-				"cookieError", errCookie, // Use errCookie
+				"cookieError", errCookie,
 			)
 
-			if headerToken != "" && errCookie == nil && headerToken == cookie.Value {
+			var validationErr error
+			switch {
+			case headerToken == "":
+				validationErr = errors.New("CSRF header missing")
+			case errCookie != nil:
+				validationErr = errCookie
+			case subtle.ConstantTimeCompare([]byte(headerToken), []byte(cookie.Value)) != 1:
+				validationErr = errors.New("CSRF header does not match cookie")
+			default:
+				validationErr = validateCSRFToken(csrfKey, bearerToken(r), headerToken, time.Now())
+			}
+			if validationErr == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Corrected logging arguments for slog
-			var cookieValForLog string
-			if errCookie == nil {
-				cookieValForLog = cookie.Value
-			} else {
-				cookieValForLog = "N/A"
-			}
-
-			// Capture the cookie error to pass to slog if it's not nil
-			var cookieErrorForLog interface{}
-			if errCookie != nil {
-				cookieErrorForLog = errCookie.Error()
-			}
-
 			logger.L.Warn("CSRF Validation Failed",
-				slog.String("method", r.Method),
-				slog.String("url", r.URL.String()),
-				slog.String("headerToken", headerToken),
-				slog.String("cookieValue", cookieValForLog), // Use the prepared string
-				slog.Any("cookieError", cookieErrorForLog),  // Use the prepared error
-				slog.String("origin", r.Header.Get("Origin")),
-				slog.String("referer", r.Header.Get("Referer")),
+				"method", r.Method,
+				"url", r.URL.String(),
+				"reason", validationErr.Error(),
+				"origin", r.Header.Get("Origin"),
+				"referer", r.Header.Get("Referer"),
 			)
 
-			http.Error(w, "CSRF token validation failed", http.StatusForbidden)
+			utils.SendError(w, http.StatusForbidden, "CSRF_INVALID", "CSRF token validation failed", nil)
 		})
 	}
 }
@@ -0,0 +1,55 @@
+// backend/src/handlers/data_quality_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+var yearParamRegex = regexp.MustCompile(`^\d{4}$`)
+
+// DataQualityHandler serves the data completeness report.
+type DataQualityHandler struct {
+	dataQualityService services.DataQualityService
+}
+
+// NewDataQualityHandler creates a new instance of DataQualityHandler.
+func NewDataQualityHandler(dataQualityService services.DataQualityService) *DataQualityHandler {
+	return &DataQualityHandler{
+		dataQualityService: dataQualityService,
+	}
+}
+
+// HandleGetDataQuality returns the completeness score and suggested fixes for a year.
+func (h *DataQualityHandler) HandleGetDataQuality(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	year := r.URL.Query().Get("year")
+	if year != "" && !yearParamRegex.MatchString(year) {
+		utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetDataQuality request", "userID", userID, "year", year)
+
+	report, err := h.dataQualityService.GetReport(r.Context(), userID, year)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing data quality report", "userID", userID, "year", year, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing data quality report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding data quality report to JSON", "userID", userID, "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+// backend/src/handlers/deemed_disposal_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// DeemedDisposalHandler serves the deemed disposal report and its per-user setting.
+type DeemedDisposalHandler struct {
+	deemedDisposalService services.DeemedDisposalService
+}
+
+// NewDeemedDisposalHandler creates a new instance of DeemedDisposalHandler.
+func NewDeemedDisposalHandler(deemedDisposalService services.DeemedDisposalService) *DeemedDisposalHandler {
+	return &DeemedDisposalHandler{
+		deemedDisposalService: deemedDisposalService,
+	}
+}
+
+type DeemedDisposalSettingRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// HandleGetDeemedDisposals returns the synthetic disposals of ETF units held for eight years or more.
+func (h *DeemedDisposalHandler) HandleGetDeemedDisposals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetDeemedDisposals request", "userID", userID)
+
+	report, err := h.deemedDisposalService.GetReport(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing deemed disposals", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing deemed disposals: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding deemed disposals to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleGetSetting returns whether the deemed disposal rule applies to the user.
+func (h *DeemedDisposalHandler) HandleGetSetting(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, err := h.deemedDisposalService.IsEnabled(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving deemed disposal setting", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving deemed disposal setting", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeemedDisposalSettingRequest{Enabled: enabled})
+}
+
+// HandleSetSetting turns the deemed disposal rule on or off for the user.
+func (h *DeemedDisposalHandler) HandleSetSetting(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeemedDisposalSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.deemedDisposalService.SetEnabled(userID, req.Enabled); err != nil {
+		logger.FromContext(r.Context()).Error("Error changing deemed disposal setting", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error changing deemed disposal setting", http.StatusInternalServerError)
+		return
+	}
+
+	h.HandleGetSetting(w, r)
+}
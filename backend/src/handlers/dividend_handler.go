@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger" // Using slog
 	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/services"
@@ -13,34 +15,54 @@ import (
 )
 
 type DividendHandler struct {
-	uploadService services.UploadService
+	uploadService    services.UploadService
+	calendarService  services.DividendCalendarService
+	tagService       services.TransactionTagService
+	householdService services.HouseholdService
 }
 
-func NewDividendHandler(service services.UploadService) *DividendHandler {
+func NewDividendHandler(service services.UploadService, calendarService services.DividendCalendarService, tagService services.TransactionTagService, householdService services.HouseholdService) *DividendHandler {
 	return &DividendHandler{
-		uploadService: service,
+		uploadService:    service,
+		calendarService:  calendarService,
+		tagService:       tagService,
+		householdService: householdService,
 	}
 }
 
+// HandleGetDividendTaxSummary returns the gross dividends and tax withheld by year and country, of the
+// user or, with scope=household, added up across the user's household.
 func (h *DividendHandler) HandleGetDividendTaxSummary(w http.ResponseWriter, r *http.Request) {
 	userID, ok := GetUserIDFromContext(r.Context()) // Assumes GetUserIDFromContext is available
 	if !ok {
 		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized) // Use utils.SendJSONError
 		return
 	}
-	logger.L.Info("Handling GetDividendTaxSummary", "userID", userID)
-	taxSummary, err := h.uploadService.GetDividendTaxSummary(userID)
+	household, ok := householdScope(w, r)
+	if !ok {
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetDividendTaxSummary", "userID", userID, "household", household)
+	if household {
+		taxSummary, err := h.householdService.GetDividendTaxSummary(r.Context(), userID)
+		if err != nil {
+			sendHouseholdError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(localizeDividendTaxResult(i18n.FromContext(r.Context()), taxSummary))
+		return
+	}
+	taxSummary, err := h.uploadService.GetDividendTaxSummary(r.Context(), userID)
 	if err != nil {
-		logger.L.Error("Error retrieving dividend tax summary", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error retrieving dividend tax summary", "userID", userID, "error", err)
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving dividend tax summary for userID %d: %v", userID, err), http.StatusInternalServerError) // Use utils.SendJSONError
 		return
 	}
-	if taxSummary == nil {
-		taxSummary = make(models.DividendTaxResult)
-	}
+	taxSummary = localizeDividendTaxResult(i18n.FromContext(r.Context()), taxSummary)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(taxSummary); err != nil {
-		logger.L.Error("Error encoding dividend tax summary to JSON", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error encoding dividend tax summary to JSON", "userID", userID, "error", err)
 	}
 }
 
@@ -50,18 +72,86 @@ func (h *DividendHandler) HandleGetDividendTransactions(w http.ResponseWriter, r
 		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized) // Use utils.SendJSONError
 		return
 	}
-	logger.L.Info("Handling GetDividendTransactions", "userID", userID)
-	dividendTransactions, err := h.uploadService.GetDividendTransactions(userID)
+	logger.FromContext(r.Context()).Info("Handling GetDividendTransactions", "userID", userID)
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
 	if err != nil {
-		logger.L.Error("Error retrieving dividend transactions", "userID", userID, "error", err)
+		sendTagError(w, r, err)
+		return
+	}
+	dividendTransactions, err := h.uploadService.GetDividendTransactions(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving dividend transactions", "userID", userID, "error", err)
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving dividend transactions for userID %d: %v", userID, err), http.StatusInternalServerError) // Use utils.SendJSONError
 		return
 	}
-	if dividendTransactions == nil {
-		dividendTransactions = []models.ProcessedTransaction{}
+	if tagFilter != nil {
+		filtered := []models.ProcessedTransaction{}
+		for _, tx := range dividendTransactions {
+			if tagFilter.MatchTransaction(tx) {
+				filtered = append(filtered, tx)
+			}
+		}
+		dividendTransactions = filtered
 	}
+	dividendTransactions = localizeTransactions(i18n.FromContext(r.Context()), dividendTransactions)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(dividendTransactions); err != nil {
-		logger.L.Error("Error encoding dividend transactions to JSON", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error encoding dividend transactions to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleGetDividendDetail returns the transactions behind one year and country of the dividend tax
+// summary, with the exchange rate each was converted at, so every figure can be justified.
+func (h *DividendHandler) HandleGetDividendDetail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	year := r.URL.Query().Get("year")
+	if !yearParamRegex.MatchString(year) {
+		utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+		return
+	}
+	country := strings.TrimSpace(r.URL.Query().Get("country"))
+	if country == "" {
+		utils.SendJSONError(w, "The country parameter is required, e.g. 840 or a label from the dividend tax summary.", http.StatusBadRequest)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetDividendDetail", "userID", userID, "year", year, "country", country)
+
+	detail, err := h.uploadService.GetDividendDetail(r.Context(), userID, year, country)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving dividend detail", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error retrieving dividend detail: %v", err), http.StatusInternalServerError)
+		return
+	}
+	detail.Country = i18n.CountryLabel(i18n.FromContext(r.Context()), detail.Country)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding dividend detail to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleGetDividendCalendar returns the dividends expected over the next twelve months.
+func (h *DividendHandler) HandleGetDividendCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetDividendCalendar request", "userID", userID)
+
+	calendar, err := h.calendarService.GetCalendar(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error projecting dividend calendar", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error projecting dividend calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(calendar); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding dividend calendar to JSON", "userID", userID, "error", err)
 	}
 }
@@ -33,13 +33,13 @@ func (h *FeeHandler) HandleGetFeeDetails(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logger.L.Info("Handling GetFeeDetails request", "userID", userID)
+	logger.FromContext(r.Context()).Info("Handling GetFeeDetails request", "userID", userID)
 
 	// Call the service layer to get the fee details.
 	// NOTE: You will need to add a `GetFeeDetails` method to your UploadService interface and implementation.
-	feeDetails, err := h.uploadService.GetFeeDetails(userID)
+	feeDetails, err := h.uploadService.GetFeeDetails(r.Context(), userID)
 	if err != nil {
-		logger.L.Error("Error retrieving fee details from service", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error retrieving fee details from service", "userID", userID, "error", err)
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving fee details: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -51,6 +51,6 @@ func (h *FeeHandler) HandleGetFeeDetails(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(feeDetails); err != nil {
-		logger.L.Error("Error encoding fee details to JSON", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error encoding fee details to JSON", "userID", userID, "error", err)
 	}
 }
@@ -0,0 +1,158 @@
+// backend/src/handlers/goal_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// GoalHandler manages the user's investment goals and serves the dashboard measuring them.
+type GoalHandler struct {
+	goalService services.GoalService
+}
+
+// NewGoalHandler creates a new instance of GoalHandler.
+func NewGoalHandler(goalService services.GoalService) *GoalHandler {
+	return &GoalHandler{
+		goalService: goalService,
+	}
+}
+
+// GoalRequest is the body of a request creating or changing a goal.
+type GoalRequest struct {
+	Name         string  `json:"name"`
+	Kind         string  `json:"kind"`
+	TargetAmount float64 `json:"target_amount"`
+	TargetDate   string  `json:"target_date"` // Optional, YYYY-MM-DD, for portfolio_value goals
+}
+
+func (req GoalRequest) goal() models.Goal {
+	return models.Goal{Name: req.Name, Kind: req.Kind, TargetAmount: req.TargetAmount, TargetDate: req.TargetDate}
+}
+
+// sendGoalError maps goal service errors to HTTP responses.
+func sendGoalError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGoal):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrGoalNotFound):
+		utils.SendJSONError(w, "Goal not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling goals", "error", err)
+		utils.SendJSONError(w, "Error handling goals", http.StatusInternalServerError)
+	}
+}
+
+// HandleGetGoals lists the user's goals.
+func (h *GoalHandler) HandleGetGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	goals, err := h.goalService.GetGoals(userID)
+	if err != nil {
+		sendGoalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+}
+
+// HandleCreateGoal adds a goal.
+func (h *GoalHandler) HandleCreateGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	goal, err := h.goalService.CreateGoal(userID, req.goal())
+	if err != nil {
+		sendGoalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// HandleUpdateGoal replaces a goal.
+func (h *GoalHandler) HandleUpdateGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	var req GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	goal, err := h.goalService.UpdateGoal(userID, id, req.goal())
+	if err != nil {
+		sendGoalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal)
+}
+
+// HandleDeleteGoal deletes a goal.
+func (h *GoalHandler) HandleDeleteGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.goalService.DeleteGoal(userID, id); err != nil {
+		sendGoalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetDashboard sums up the user's portfolio and contributions, with the progress of their goals.
+func (h *GoalHandler) HandleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	dashboard, err := h.goalService.GetDashboard(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building dashboard", "error", err)
+		utils.SendJSONError(w, "Error building dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
@@ -0,0 +1,162 @@
+// backend/src/handlers/household_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// HouseholdHandler manages the user's household and its invitations.
+type HouseholdHandler struct {
+	householdService services.HouseholdService
+}
+
+// NewHouseholdHandler creates a new instance of HouseholdHandler.
+func NewHouseholdHandler(householdService services.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{
+		householdService: householdService,
+	}
+}
+
+// HouseholdInvitationRequest is the body of a request inviting someone to the user's household.
+type HouseholdInvitationRequest struct {
+	Email string `json:"email"`
+}
+
+// sendHouseholdError maps household service errors to HTTP responses.
+func sendHouseholdError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidHouseholdInvitation):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrHouseholdCurrencyMismatch):
+		utils.SendJSONError(w, "The members of the household use different base currencies, so their reports cannot be combined", http.StatusConflict)
+	case errors.Is(err, model.ErrNotInHousehold):
+		utils.SendJSONError(w, "You are not part of a household", http.StatusNotFound)
+	case errors.Is(err, model.ErrHouseholdInvitationNotFound):
+		utils.SendJSONError(w, "Invitation not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling household", "error", err)
+		utils.SendJSONError(w, "Error handling household", http.StatusInternalServerError)
+	}
+}
+
+// householdScope reports whether a report request asks, with scope=household, for the combined report
+// of the user's household rather than the user's own. An unknown scope is answered with 400.
+func householdScope(w http.ResponseWriter, r *http.Request) (household bool, ok bool) {
+	switch r.URL.Query().Get("scope") {
+	case "", models.ReportScopeUser:
+		return false, true
+	case models.ReportScopeHousehold:
+		return true, true
+	default:
+		utils.SendJSONError(w, "Invalid scope. Use user or household.", http.StatusBadRequest)
+		return false, false
+	}
+}
+
+// HandleGetHousehold returns the members of the user's household and the pending invitations the
+// user sent or received.
+func (h *HouseholdHandler) HandleGetHousehold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	household, err := h.householdService.GetHousehold(userID)
+	if err != nil {
+		sendHouseholdError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(household)
+}
+
+// HandleLeaveHousehold takes the user out of their household.
+func (h *HouseholdHandler) HandleLeaveHousehold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.householdService.Leave(userID); err != nil {
+		sendHouseholdError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleCreateHouseholdInvitation invites the owner of an email address to the user's household.
+func (h *HouseholdHandler) HandleCreateHouseholdInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req HouseholdInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	invitation, err := h.householdService.Invite(userID, req.Email)
+	if err != nil {
+		sendHouseholdError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// HandleAcceptHouseholdInvitation makes the user join the household of an invitation sent to them.
+func (h *HouseholdHandler) HandleAcceptHouseholdInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	household, err := h.householdService.AcceptInvitation(userID, id)
+	if err != nil {
+		sendHouseholdError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(household)
+}
+
+// HandleDeleteHouseholdInvitation cancels an invitation the user sent, or declines one they received.
+func (h *HouseholdHandler) HandleDeleteHouseholdInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.householdService.DeleteInvitation(userID, id); err != nil {
+		sendHouseholdError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
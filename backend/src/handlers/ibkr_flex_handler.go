@@ -0,0 +1,136 @@
+// backend/src/handlers/ibkr_flex_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+var flexQueryIDRegex = regexp.MustCompile(`^\d{1,20}$`)
+
+// IBKRFlexHandler manages the automatic IBKR Flex Query connection.
+type IBKRFlexHandler struct {
+	flexService services.IBKRFlexService
+}
+
+// NewIBKRFlexHandler creates a new instance of IBKRFlexHandler.
+func NewIBKRFlexHandler(flexService services.IBKRFlexService) *IBKRFlexHandler {
+	return &IBKRFlexHandler{
+		flexService: flexService,
+	}
+}
+
+type SaveFlexConnectionRequest struct {
+	Token   string `json:"token"`
+	QueryID string `json:"query_id"`
+}
+
+// HandleGetFlexConnection returns the connection status. The token is never returned.
+func (h *IBKRFlexHandler) HandleGetFlexConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.flexService.GetConnection(userID)
+	if errors.Is(err, model.ErrBrokerConnectionNotFound) {
+		utils.SendJSONError(w, "IBKR Flex is not connected", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving IBKR Flex connection", "error", err)
+		utils.SendJSONError(w, "Error retrieving IBKR Flex connection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conn)
+}
+
+// HandleSaveFlexConnection stores the Flex token and query ID used for automatic imports.
+func (h *IBKRFlexHandler) HandleSaveFlexConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req SaveFlexConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	req.QueryID = strings.TrimSpace(req.QueryID)
+	if req.Token == "" || len(req.Token) > 100 {
+		utils.SendJSONError(w, "A valid Flex token is required", http.StatusBadRequest)
+		return
+	}
+	if !flexQueryIDRegex.MatchString(req.QueryID) {
+		utils.SendJSONError(w, "A numeric Flex query ID is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.flexService.SaveConnection(userID, req.Token, req.QueryID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error saving IBKR Flex connection", "error", err)
+		utils.SendJSONError(w, "Error saving IBKR Flex connection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conn)
+}
+
+// HandleDeleteFlexConnection removes the stored Flex credentials.
+func (h *IBKRFlexHandler) HandleDeleteFlexConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.flexService.DeleteConnection(userID); err != nil {
+		logger.FromContext(r.Context()).Error("Error deleting IBKR Flex connection", "error", err)
+		utils.SendJSONError(w, "Error deleting IBKR Flex connection", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSyncFlexConnection starts pulling the Flex statement immediately instead of waiting for the
+// scheduler. It answers 202 with the connection marked as running; the outcome is read from
+// HandleGetFlexConnection once the sync finishes.
+func (h *IBKRFlexHandler) HandleSyncFlexConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.flexService.StartSync(userID)
+	switch {
+	case errors.Is(err, model.ErrBrokerConnectionNotFound):
+		utils.SendJSONError(w, "IBKR Flex is not connected", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrFlexSyncInProgress):
+		utils.SendJSONError(w, "An IBKR Flex sync is already in progress", http.StatusConflict)
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("Error starting IBKR Flex sync", "error", err)
+		utils.SendJSONError(w, "Error syncing IBKR Flex statement", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(conn)
+}
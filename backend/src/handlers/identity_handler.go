@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+type AddLocalIdentityRequest struct {
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// HandleGetIdentities lists the login methods linked to the authenticated user.
+func (h *UserHandler) HandleGetIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := model.GetIdentitiesByUserID(database.DB, userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to get user identities", "error", err)
+		sendJSONError(w, "Failed to retrieve linked accounts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+// HandleAddLocalIdentity lets an account created through Google set a password,
+// so that it can also log in with email and password.
+func (h *UserHandler) HandleAddLocalIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	log := logger.FromContext(r.Context())
+
+	var req AddLocalIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		sendJSONError(w, "Passwords do not match", http.StatusBadRequest)
+		return
+	}
+
+	user, err := model.GetUserByID(database.DB, userID)
+	if err != nil {
+		log.Error("Failed to get user for local identity", "error", err)
+		sendJSONError(w, "Failed to retrieve user information", http.StatusInternalServerError)
+		return
+	}
+	hasLocal, err := model.HasIdentity(database.DB, userID, model.ProviderLocal)
+	if err != nil {
+		log.Error("Failed to check local identity", "error", err)
+		sendJSONError(w, "Failed to link password login", http.StatusInternalServerError)
+		return
+	}
+	if hasLocal {
+		sendJSONError(w, "A password is already set for this account", http.StatusConflict)
+		return
+	}
+	if !h.checkPassword(w, r, req.Password, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := h.authService.HashPassword(req.Password)
+	if err != nil {
+		log.Error("Failed to hash password for local identity", "error", err)
+		sendJSONError(w, "Failed to process password", http.StatusInternalServerError)
+		return
+	}
+	if err := user.UpdatePassword(database.DB, hashedPassword); err != nil {
+		log.Error("Failed to store password for local identity", "error", err)
+		sendJSONError(w, "Failed to link password login", http.StatusInternalServerError)
+		return
+	}
+	identity := &model.UserIdentity{
+		UserID:         userID,
+		Provider:       model.ProviderLocal,
+		ProviderUserID: user.Email,
+		Email:          user.Email,
+	}
+	if err := model.CreateIdentity(database.DB, identity); err != nil {
+		log.Error("Failed to create local identity", "error", err)
+		sendJSONError(w, "Failed to link password login", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, userID, models.AuditLoginMethodAdded, map[string]string{"provider": model.ProviderLocal})
+	log.Info("Password login linked to account")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(identity)
+}
+
+// HandleDeleteIdentity unlinks a login method. The last remaining method cannot be removed.
+func (h *UserHandler) HandleDeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	log := logger.FromContext(r.Context())
+
+	provider := chi.URLParam(r, "provider")
+	if provider != model.ProviderLocal && provider != model.ProviderGoogle {
+		sendJSONError(w, "Unknown provider", http.StatusBadRequest)
+		return
+	}
+
+	identities, err := model.GetIdentitiesByUserID(database.DB, userID)
+	if err != nil {
+		log.Error("Failed to get user identities", "error", err)
+		sendJSONError(w, "Failed to unlink account", http.StatusInternalServerError)
+		return
+	}
+	remaining := ""
+	found := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			found = true
+		} else if remaining == "" {
+			remaining = identity.Provider
+		}
+	}
+	if !found {
+		sendJSONError(w, "Provider is not linked to this account", http.StatusNotFound)
+		return
+	}
+	if remaining == "" {
+		sendJSONError(w, "Cannot remove the only login method of the account", http.StatusConflict)
+		return
+	}
+
+	user, err := model.GetUserByID(database.DB, userID)
+	if err != nil {
+		log.Error("Failed to get user for unlinking", "error", err)
+		sendJSONError(w, "Failed to retrieve user information", http.StatusInternalServerError)
+		return
+	}
+	if err := model.DeleteIdentity(database.DB, userID, provider); err != nil {
+		log.Error("Failed to delete identity", "provider", provider, "error", err)
+		sendJSONError(w, "Failed to unlink account", http.StatusInternalServerError)
+		return
+	}
+	if provider == model.ProviderLocal {
+		if err := user.UpdatePassword(database.DB, ""); err != nil {
+			log.Error("Failed to clear password after unlinking", "error", err)
+		}
+	}
+	if user.AuthProvider == provider {
+		if err := model.UpdateAuthProvider(database.DB, userID, remaining); err != nil {
+			log.Error("Failed to update primary auth provider after unlinking", "error", err)
+		}
+	}
+
+	recordAudit(r, userID, models.AuditLoginMethodRemoved, map[string]string{"provider": provider})
+	log.Info("Login method unlinked", "provider", provider)
+	w.WriteHeader(http.StatusNoContent)
+}
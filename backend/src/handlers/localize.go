@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// The helpers below render the country labels of service results in the request locale. Results may be
+// shared with the report cache, so they return localized copies instead of changing them in place.
+
+func localizeSaleDetails(locale string, sales []models.SaleDetail) []models.SaleDetail {
+	localized := make([]models.SaleDetail, len(sales))
+	for i, sale := range sales {
+		sale.CountryCode = i18n.CountryLabel(locale, sale.CountryCode)
+		localized[i] = sale
+	}
+	return localized
+}
+
+func localizeOptionSaleDetails(locale string, sales []models.OptionSaleDetail) []models.OptionSaleDetail {
+	localized := make([]models.OptionSaleDetail, len(sales))
+	for i, sale := range sales {
+		sale.CountryCode = i18n.CountryLabel(locale, sale.CountryCode)
+		localized[i] = sale
+	}
+	return localized
+}
+
+func localizeTransactions(locale string, txs []models.ProcessedTransaction) []models.ProcessedTransaction {
+	localized := make([]models.ProcessedTransaction, len(txs))
+	for i, tx := range txs {
+		tx.CountryCode = i18n.CountryLabel(locale, tx.CountryCode)
+		localized[i] = tx
+	}
+	return localized
+}
+
+func localizeDividendTaxResult(locale string, result models.DividendTaxResult) models.DividendTaxResult {
+	localized := make(models.DividendTaxResult, len(result))
+	for year, byCountry := range result {
+		localized[year] = make(map[string]models.DividendCountrySummary, len(byCountry))
+		for country, summary := range byCountry {
+			localized[year][i18n.CountryLabel(locale, country)] = summary
+		}
+	}
+	return localized
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/security"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// lockoutDuration returns how long an account is locked after the given number of consecutive failed
+// logins: not at all below the threshold, then the base duration doubling with each further failure.
+func lockoutDuration(failures int) time.Duration {
+	maxAttempts := config.Cfg.LoginMaxFailedAttempts
+	if maxAttempts <= 0 || failures < maxAttempts {
+		return 0
+	}
+	duration := config.Cfg.LoginLockoutDuration
+	for i := maxAttempts; i < failures && duration < config.Cfg.LoginLockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > config.Cfg.LoginLockoutMaxDuration {
+		duration = config.Cfg.LoginLockoutMaxDuration
+	}
+	return duration
+}
+
+// unknownEmailLogin is the count of failed logins to an email no account has, and the lock they earned.
+type unknownEmailLogin struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+// unknownEmailLogins locks emails no account has as accounts are locked, so that whether a login is
+// refused as locked does not tell an attacker that an account exists. Counts are forgotten a day
+// after the last failure.
+var (
+	unknownEmailLogins   = cache.New(24*time.Hour, time.Hour)
+	unknownEmailLoginsMu sync.Mutex
+)
+
+// unknownEmailLockedUntil returns when the lock on an email no account has ends, or the zero time.
+func unknownEmailLockedUntil(email string) time.Time {
+	unknownEmailLoginsMu.Lock()
+	defer unknownEmailLoginsMu.Unlock()
+	if cached, found := unknownEmailLogins.Get(email); found {
+		return cached.(unknownEmailLogin).lockedUntil
+	}
+	return time.Time{}
+}
+
+// recordUnknownEmailLogin counts a failed login to an email no account has, and locks it as
+// recordFailedLogin locks an account. It returns when the lock ends, or the zero time.
+func recordUnknownEmailLogin(email string) time.Time {
+	unknownEmailLoginsMu.Lock()
+	defer unknownEmailLoginsMu.Unlock()
+	var login unknownEmailLogin
+	if cached, found := unknownEmailLogins.Get(email); found {
+		login = cached.(unknownEmailLogin)
+	}
+	login.failures++
+	if duration := lockoutDuration(login.failures); duration > 0 {
+		login.lockedUntil = time.Now().Add(duration)
+	}
+	unknownEmailLogins.Set(email, login, cache.DefaultExpiration)
+	return login.lockedUntil
+}
+
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("no account has this password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// checkDummyPassword checks the password of a login to an email no account has against a hash of
+// another, so that it takes as long to refuse as a wrong password.
+func checkDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+}
+
+// recordFailedLogin counts a wrong password for the user and locks the account once the threshold is
+// reached. The first lock emails the owner a link to unlock it. It returns when the lock ends, or the
+// zero time if the account is not locked.
+func (h *UserHandler) recordFailedLogin(r *http.Request, user *model.User) time.Time {
+	failures, err := model.RecordFailedLogin(database.DB, user.ID)
+	if err != nil {
+		logger.L.Error("Failed to record failed login", "userID", user.ID, "error", err)
+		return time.Time{}
+	}
+	duration := lockoutDuration(failures)
+	if duration <= 0 {
+		return time.Time{}
+	}
+
+	lockedUntil := time.Now().Add(duration)
+	if err := model.LockAccount(database.DB, user.ID, lockedUntil); err != nil {
+		logger.L.Error("Failed to lock account", "userID", user.ID, "error", err)
+		return time.Time{}
+	}
+	logger.L.Warn("Account locked after failed logins", "userID", user.ID, "failures", failures, "lockedUntil", lockedUntil)
+
+	if failures == config.Cfg.LoginMaxFailedAttempts {
+		tokenBytes := make([]byte, 32)
+		if _, err := rand.Read(tokenBytes); err != nil {
+			logger.L.Error("Failed to generate account unlock token", "userID", user.ID, "error", err)
+			return lockedUntil
+		}
+		unlockToken := hex.EncodeToString(tokenBytes)
+		if err := model.SetAccountUnlockToken(database.DB, user.ID, security.HashToken(unlockToken), time.Now().Add(config.Cfg.AccountUnlockTokenExpiry)); err != nil {
+			logger.L.Error("Failed to store account unlock token", "userID", user.ID, "error", err)
+			return lockedUntil
+		}
+		if err := h.emailService.SendAccountLockedEmail(user.Email, user.Username, unlockToken, storedLocale(r, user.ID)); err != nil {
+			logger.L.Error("Failed to send account locked email", "userID", user.ID, "error", err)
+		}
+	}
+	return lockedUntil
+}
+
+// sendAccountLocked rejects a login to a locked account, telling the client when it may retry. The
+// message is in the locale of the request, never the account's, so it is the same whether the email
+// belongs to an account or not.
+func sendAccountLocked(w http.ResponseWriter, locale string, lockedUntil time.Time) {
+	retryAfter := int(time.Until(lockedUntil).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	utils.SendError(w, http.StatusTooManyRequests, "ACCOUNT_LOCKED",
+		i18n.T(locale, i18n.MsgAuthAccountLocked, lockedUntil.UTC().Format("02-01-2006 15:04")),
+		map[string]string{"locked_until": lockedUntil.UTC().Format(time.RFC3339)})
+}
+
+// UnlockAccountHandler lifts a login lock with the token of the link emailed when the account was
+// locked. The link opens a page of the frontend that posts the token, so following it (as mail
+// scanners do) does not unlock the account.
+func (h *UserHandler) UnlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		sendJSONError(w, "Unlock token is missing", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := model.GetUserIDByUnlockTokenHash(database.DB, security.HashToken(req.Token))
+	if err != nil {
+		logger.L.Warn("Unlock token lookup failed", "tokenPrefix", req.Token[:min(10, len(req.Token))], "error", err)
+		sendJSONError(w, "Invalid or expired unlock token.", http.StatusBadRequest)
+		return
+	}
+
+	if err := model.ResetFailedLogins(database.DB, userID); err != nil {
+		logger.L.Error("Failed to unlock account", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to unlock account. Please try again or contact support.", http.StatusInternalServerError)
+		return
+	}
+
+	logger.L.Info("Account unlocked through emailed link", "userID", userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account unlocked. You can now log in."})
+}
@@ -2,34 +2,188 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
 )
 
-func (h *UserHandler) AuthMiddleware(next http.Handler) http.Handler {
+// RequestLoggerMiddleware attaches a logger carrying the request ID to the request context and logs
+// the method, path, status and duration of every request. It must run after chi's RequestID middleware.
+func RequestLoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			logger.L.Debug("AuthMiddleware: Authorization header missing", "path", r.URL.Path)
-			sendJSONError(w, "Authorization header required", http.StatusUnauthorized)
-			return
+		start := time.Now()
+		requestID := middleware.GetReqID(r.Context())
+		if requestID != "" {
+			w.Header().Set("X-Request-ID", requestID)
+		}
+
+		reqLogger := logger.L.With("requestID", requestID)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(logger.NewContext(r.Context(), reqLogger)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		reqLogger.Info("Request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start))
+	})
+}
+
+// LocaleMiddleware stores the locale of API-generated text in the request context: the authenticated
+// user's preference, or else the Accept-Language header. It must run after AuthMiddleware.
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+		if userID, ok := GetUserIDFromContext(r.Context()); ok {
+			locale = storedLocale(r, userID)
+		}
+		next.ServeHTTP(w, r.WithContext(i18n.NewContext(r.Context(), locale)))
+	})
+}
+
+// storedLocale returns the locale saved for the user, falling back to the request's Accept-Language header.
+func storedLocale(r *http.Request, userID int64) string {
+	stored, err := model.GetUserLocale(database.DB, userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("Could not load user locale", "userID", userID, "error", err)
+	}
+	if locale, ok := i18n.Normalize(stored); ok {
+		return locale
+	}
+	return i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// AdminTokenMiddleware only lets through requests carrying token as a bearer token.
+// An empty token disables the wrapped endpoints altogether.
+func AdminTokenMiddleware(token string) func(http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				sendJSONError(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+				logger.FromContext(r.Context()).Warn("Rejected admin request with invalid token", "path", r.URL.Path)
+				sendJSONError(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DataETagMiddleware lets clients revalidate report responses without them being computed again. The
+// ETag is derived from the user's data version, which changes with their transactions and settings,
+// the locale and the URL, so a request whose If-None-Match holds it is answered 304 Not Modified
+// straight away. It must run after LocaleMiddleware.
+func DataETagMiddleware(uploadService services.UploadService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			// Household reports also change with the other members' data, which the version does not follow.
+			if !ok || r.Method != http.MethodGet || r.URL.Query().Get("scope") == models.ReportScopeHousehold {
+				next.ServeHTTP(w, r)
+				return
+			}
+			version, err := uploadService.GetDataVersion(userID)
+			if err != nil {
+				logger.FromContext(r.Context()).Warn("Could not load data version, answering without ETag", "userID", userID, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := fmt.Sprintf("%d|%d|%s|%s", userID, version, i18n.FromContext(r.Context()), r.URL.RequestURI())
+			sum := sha256.Sum256([]byte(key))
+			// Weak, since compressed and uncompressed responses share it.
+			etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			w.Header().Set("Cache-Control", "no-cache, private")
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.Header().Set("ETag", etag)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			next.ServeHTTP(&etagResponseWriter{ResponseWriter: w, etag: etag}, r)
+		})
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
 		}
+	}
+	return false
+}
 
-		tokenString := ""
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-		} else {
-			tokenString = authHeader
+// etagResponseWriter sets the ETag on successful responses only, so errors are not revalidated.
+type etagResponseWriter struct {
+	http.ResponseWriter
+	etag        string
+	wroteHeader bool
+}
+
+func (w *etagResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK {
+			w.Header().Set("ETag", w.etag)
 		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
 
+func (w *etagResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// RequirePremium only lets through users whose plan includes premium features. It must run after AuthMiddleware.
+func RequirePremium(billingService services.BillingService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if !ok {
+				sendJSONError(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !checkPremiumAccess(w, r, billingService, userID) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *UserHandler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString := bearerToken(r)
 		if tokenString == "" {
-			logger.L.Debug("AuthMiddleware: Token string empty", "path", r.URL.Path)
-			sendJSONError(w, "Malformed token", http.StatusUnauthorized)
+			logger.L.Debug("AuthMiddleware: Authorization header missing", "path", r.URL.Path)
+			sendJSONError(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
@@ -40,25 +194,14 @@ func (h *UserHandler) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		_, err = model.GetSessionByToken(database.DB, tokenString)
+		// Todos os logins (local e Google) criam uma sessão, por isso um token sem sessão é inválido.
+		session, err := model.GetSessionByToken(database.DB, h.keyring, tokenString)
 		if err != nil {
-			// Esta verificação pode falhar para tokens do Google, pois eles não criam uma sessão na nossa DB
-			// Uma abordagem melhor seria verificar o AuthProvider do utilizador
-			userIDIntCheck, _ := strconv.ParseInt(userIDStr, 10, 64)
-			user, userErr := model.GetUserByID(database.DB, userIDIntCheck)
-			if userErr != nil {
-				logger.L.Warn("AuthMiddleware: User not found for token after session check failed", "userID", userIDStr, "error", userErr)
-				sendJSONError(w, "Invalid session or user", http.StatusUnauthorized)
-				return
-			}
-			// Se o utilizador for do Google, permitimos passar sem uma sessão na nossa DB.
-			// Se for local e não tiver sessão, é um erro.
-			if user.AuthProvider == "local" {
-				logger.L.Warn("AuthMiddleware: Session validation failed for local user's access token", "path", r.URL.Path, "error", err)
-				sendJSONError(w, "Invalid or expired session", http.StatusUnauthorized)
-				return
-			}
+			logger.L.Warn("AuthMiddleware: Session validation failed for access token", "path", r.URL.Path, "error", err)
+			sendJSONError(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
 		}
+		extendSession(r, session)
 
 		userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
 		if err != nil {
@@ -68,6 +211,7 @@ func (h *UserHandler) AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), userIDContextKey, userIDInt)
+		ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("userID", userIDInt))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
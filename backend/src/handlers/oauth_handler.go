@@ -2,12 +2,20 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/patrickmn/go-cache"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 
@@ -15,6 +23,7 @@ import (
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
 )
 
 func InitializeGoogleOAuthConfig() {
@@ -27,98 +36,385 @@ func InitializeGoogleOAuthConfig() {
 	}
 }
 
+const (
+	// linkStatePrefix marks OAuth states issued to link Google to an already logged-in account.
+	linkStatePrefix = "link:"
+
+	// The state and PKCE verifier of an authorization request live in short-lived cookies
+	// scoped to the Google auth routes, so the callback can check it continues that request.
+	oauthStateCookie    = "oauth_state"
+	oauthVerifierCookie = "oauth_verifier"
+	oauthCookiePath     = "/api/auth/google"
+	oauthCookieTTL      = 10 * time.Minute
+
+	// googleLoginCodeTTL is how long the frontend has to exchange the code of a Google login for its tokens.
+	googleLoginCodeTTL = time.Minute
+)
+
+// googleLinkStates maps the state of a pending link request to the user who started it.
+var googleLinkStates = cache.New(oauthCookieTTL, 2*oauthCookieTTL)
+
+// googleLoginCodes maps the single-use code a Google login redirects the frontend with to the tokens of
+// the session it opened, so that the tokens never appear in a URL.
+var (
+	googleLoginCodes   = cache.New(googleLoginCodeTTL, 2*googleLoginCodeTTL)
+	googleLoginCodesMu sync.Mutex // Makes taking a code and deleting it one step
+)
+
+type googleLoginTokens struct {
+	accessToken  string
+	refreshToken string
+}
+
+type googleUserInfo struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Verified bool   `json:"verified_email"`
+	ID       string `json:"id"`
+}
+
 func (h *UserHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request) {
-	url := googleOauthConfig.AuthCodeURL(oauthStateString)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	authURL, _, err := startGoogleAuth(w, r, "")
+	if err != nil {
+		logger.L.Error("Failed to start Google login", "error", err)
+		http.Redirect(w, r, "/signin?error=oauth_init_failed", http.StatusTemporaryRedirect)
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+}
+
+// startGoogleAuth generates a random state and PKCE verifier, stores them in cookies
+// and returns the Google authorization URL together with the state.
+func startGoogleAuth(w http.ResponseWriter, r *http.Request, statePrefix string) (string, string, error) {
+	stateBytes := make([]byte, 32)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	state := statePrefix + hex.EncodeToString(stateBytes)
+	verifier := oauth2.GenerateVerifier()
+
+	setOAuthCookie(w, r, oauthStateCookie, state, int(oauthCookieTTL.Seconds()))
+	setOAuthCookie(w, r, oauthVerifierCookie, verifier, int(oauthCookieTTL.Seconds()))
+	return googleOauthConfig.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// consumeGoogleAuth checks the callback state against the cookie, clears the cookies
+// and returns the PKCE verifier of the request.
+func consumeGoogleAuth(w http.ResponseWriter, r *http.Request) (string, string, error) {
+	stateCookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		return "", "", errors.New("missing OAuth state cookie")
+	}
+	verifierCookie, err := r.Cookie(oauthVerifierCookie)
+	if err != nil {
+		return "", "", errors.New("missing OAuth verifier cookie")
+	}
+	setOAuthCookie(w, r, oauthStateCookie, "", -1)
+	setOAuthCookie(w, r, oauthVerifierCookie, "", -1)
+
+	state := r.FormValue("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(stateCookie.Value)) != 1 {
+		return "", "", errors.New("OAuth state does not match")
+	}
+	return state, verifierCookie.Value, nil
+}
+
+func setOAuthCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     oauthCookiePath,
+		SameSite: http.SameSiteLaxMode, // Sent on the top-level redirect back from Google
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		MaxAge:   maxAge,
+	})
 }
 
 func (h *UserHandler) HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
-	if r.FormValue("state") != oauthStateString {
-		logger.L.Warn("Invalid OAuth state from Google callback")
-		http.Redirect(w, r, "/signin?error=invalid_state", http.StatusTemporaryRedirect)
+	state, verifier, err := consumeGoogleAuth(w, r)
+	if err != nil {
+		logger.L.Warn("Invalid OAuth state from Google callback", "error", err)
+		if strings.HasPrefix(r.FormValue("state"), linkStatePrefix) {
+			redirectToSettings(w, r, "error=invalid_state")
+		} else {
+			http.Redirect(w, r, "/signin?error=invalid_state", http.StatusTemporaryRedirect)
+		}
 		return
 	}
 
-	code := r.FormValue("code")
-	token, err := googleOauthConfig.Exchange(context.Background(), code)
+	var linkUserID int64
+	if strings.HasPrefix(state, linkStatePrefix) {
+		cached, found := googleLinkStates.Get(state)
+		if !found {
+			logger.L.Warn("Unknown or expired OAuth link state from Google callback")
+			redirectToSettings(w, r, "error=invalid_state")
+			return
+		}
+		googleLinkStates.Delete(state)
+		linkUserID = cached.(int64)
+	}
+
+	googleUser, contents, err := fetchGoogleUser(r.FormValue("code"), verifier)
 	if err != nil {
-		logger.L.Error("Failed to exchange code for token", "error", err)
-		http.Redirect(w, r, "/signin?error=token_exchange_failed", http.StatusTemporaryRedirect)
+		logger.L.Error("Failed to get user info from Google", "error", err)
+		if linkUserID != 0 {
+			redirectToSettings(w, r, "error=userinfo_failed")
+		} else {
+			http.Redirect(w, r, "/signin?error=userinfo_failed", http.StatusTemporaryRedirect)
+		}
 		return
 	}
 
-	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
+	if linkUserID != 0 {
+		h.linkGoogleIdentity(w, r, linkUserID, googleUser)
+		return
+	}
+
+	if !googleUser.Verified {
+		http.Redirect(w, r, "/signin?error=email_not_verified_by_google", http.StatusTemporaryRedirect)
+		return
+	}
+
+	user, errCode := findOrCreateGoogleUser(googleUser)
+	if errCode != "" {
+		http.Redirect(w, r, "/signin?error="+errCode, http.StatusTemporaryRedirect)
+		return
+	}
+
+	// Gerar o nosso próprio par de tokens e sessão, tal como no login local
+	appToken, refreshToken, err := h.createSession(r, user.ID)
 	if err != nil {
-		logger.L.Error("Failed to get user info from Google", "error", err)
-		http.Redirect(w, r, "/signin?error=userinfo_failed", http.StatusTemporaryRedirect)
+		logger.L.Error("Failed to create session for Google user", "userID", user.ID, "error", err)
+		http.Redirect(w, r, "/signin?error=token_generation_failed", http.StatusTemporaryRedirect)
 		return
 	}
-	defer response.Body.Close()
+	recordAudit(r, user.ID, models.AuditLogin, map[string]string{"provider": model.ProviderGoogle})
 
-	contents, err := io.ReadAll(response.Body)
+	// With AUTH_COOKIES the tokens are set as cookies and kept out of the URL of the frontend's page.
+	if config.Cfg.AuthCookies {
+		setAuthCookies(w, r, appToken, refreshToken)
+		http.Redirect(w, r, config.Cfg.FrontendBaseURL+"/auth/google/callback?user="+url.QueryEscape(string(contents)), http.StatusTemporaryRedirect)
+		return
+	}
+
+	// Otherwise the frontend gets a single-use code, exchanged for the tokens by POST /auth/google/exchange.
+	code, err := newGoogleLoginCode(appToken, refreshToken)
 	if err != nil {
-		logger.L.Error("Failed to read user info response body", "error", err)
-		http.Redirect(w, r, "/signin?error=userinfo_read_failed", http.StatusTemporaryRedirect)
+		logger.L.Error("Failed to create login code for Google user", "userID", user.ID, "error", err)
+		http.Redirect(w, r, "/signin?error=token_generation_failed", http.StatusTemporaryRedirect)
 		return
 	}
+	redirectURL := fmt.Sprintf("%s/auth/google/callback?code=%s&user=%s",
+		config.Cfg.FrontendBaseURL,
+		url.QueryEscape(code),
+		url.QueryEscape(string(contents)))
+	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+}
 
-	var googleUser struct {
-		Email    string `json:"email"`
-		Name     string `json:"name"`
-		Verified bool   `json:"verified_email"`
-		ID       string `json:"id"`
+// newGoogleLoginCode stores the tokens of a Google login under a new random code.
+func newGoogleLoginCode(accessToken, refreshToken string) (string, error) {
+	codeBytes := make([]byte, 32)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return "", fmt.Errorf("failed to generate login code: %w", err)
 	}
-	if err := json.Unmarshal(contents, &googleUser); err != nil {
-		logger.L.Error("Failed to unmarshal Google user info", "error", err)
-		http.Redirect(w, r, "/signin?error=userinfo_parse_failed", http.StatusTemporaryRedirect)
+	code := hex.EncodeToString(codeBytes)
+	googleLoginCodes.Set(code, googleLoginTokens{accessToken: accessToken, refreshToken: refreshToken}, cache.DefaultExpiration)
+	return code, nil
+}
+
+// takeGoogleLoginCode returns the tokens stored under code and forgets them, so each code is used once.
+func takeGoogleLoginCode(code string) (googleLoginTokens, bool) {
+	googleLoginCodesMu.Lock()
+	defer googleLoginCodesMu.Unlock()
+	cached, found := googleLoginCodes.Get(code)
+	if !found {
+		return googleLoginTokens{}, false
+	}
+	googleLoginCodes.Delete(code)
+	return cached.(googleLoginTokens), true
+}
+
+// HandleGoogleExchange returns the tokens of a Google login for the code its callback redirected with.
+func (h *UserHandler) HandleGoogleExchange(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Code == "" {
+		sendJSONError(w, "Code is required", http.StatusBadRequest)
 		return
 	}
 
-	if !googleUser.Verified {
-		http.Redirect(w, r, "/signin?error=email_not_verified_by_google", http.StatusTemporaryRedirect)
+	tokens, found := takeGoogleLoginCode(requestBody.Code)
+	if !found {
+		sendJSONError(w, "Invalid or expired code", http.StatusUnauthorized)
+		return
+	}
+
+	rotateCSRFToken(w, r, tokens.accessToken)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionTokens(w, r, tokens.accessToken, tokens.refreshToken))
+}
+
+// HandleStartGoogleLink returns the Google authorization URL that links a Google account to the logged-in user.
+func (h *UserHandler) HandleStartGoogleLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	linked, err := model.HasIdentity(database.DB, userID, model.ProviderGoogle)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to check Google identity", "error", err)
+		sendJSONError(w, "Failed to start account linking", http.StatusInternalServerError)
+		return
+	}
+	if linked {
+		sendJSONError(w, "A Google account is already linked", http.StatusConflict)
+		return
+	}
+
+	authURL, state, err := startGoogleAuth(w, r, linkStatePrefix)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to start Google account linking", "error", err)
+		sendJSONError(w, "Failed to start account linking", http.StatusInternalServerError)
+		return
+	}
+	googleLinkStates.Set(state, userID, cache.DefaultExpiration)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": authURL})
+}
+
+// linkGoogleIdentity attaches the Google account to userID unless it already belongs to another user.
+func (h *UserHandler) linkGoogleIdentity(w http.ResponseWriter, r *http.Request, userID int64, googleUser *googleUserInfo) {
+	existing, err := model.GetIdentity(database.DB, model.ProviderGoogle, googleUser.ID)
+	switch {
+	case err == nil && existing.UserID == userID:
+		redirectToSettings(w, r, "linked=google")
+		return
+	case err == nil:
+		logger.L.Warn("Google account already linked to another user", "userID", userID, "otherUserID", existing.UserID)
+		redirectToSettings(w, r, "error=google_account_in_use")
+		return
+	case !errors.Is(err, model.ErrIdentityNotFound):
+		logger.L.Error("Failed to look up Google identity", "userID", userID, "error", err)
+		redirectToSettings(w, r, "error=link_failed")
+		return
+	}
+
+	identity := &model.UserIdentity{
+		UserID:         userID,
+		Provider:       model.ProviderGoogle,
+		ProviderUserID: googleUser.ID,
+		Email:          googleUser.Email,
+	}
+	if err := model.CreateIdentity(database.DB, identity); err != nil {
+		logger.L.Error("Failed to link Google identity", "userID", userID, "error", err)
+		redirectToSettings(w, r, "error=link_failed")
 		return
 	}
+	recordAudit(r, userID, models.AuditLoginMethodAdded, map[string]string{"provider": model.ProviderGoogle})
+	logger.L.Info("Google account linked", "userID", userID)
+	redirectToSettings(w, r, "linked=google")
+}
+
+// findOrCreateGoogleUser resolves the account for a Google login. On failure it returns
+// the error code shown by the frontend sign-in page.
+func findOrCreateGoogleUser(googleUser *googleUserInfo) (*model.User, string) {
+	identity, err := model.GetIdentity(database.DB, model.ProviderGoogle, googleUser.ID)
+	if err == nil {
+		user, err := model.GetUserByID(database.DB, identity.UserID)
+		if err != nil {
+			logger.L.Error("Failed to load user for Google identity", "userID", identity.UserID, "error", err)
+			return nil, "user_lookup_failed"
+		}
+		return user, ""
+	}
+	if !errors.Is(err, model.ErrIdentityNotFound) {
+		logger.L.Error("Failed to look up Google identity", "error", err)
+		return nil, "user_lookup_failed"
+	}
 
-	// Lógica para encontrar ou criar o utilizador
 	user, err := model.GetUserByEmail(database.DB, googleUser.Email)
 	if err != nil { // Utilizador não existe, vamos criá-lo
-		// CORREÇÃO: Usar o email como username para garantir unicidade e definir o AuthProvider
-		newUser := &model.User{
+		user = &model.User{
 			Username:        googleUser.Email, // Usar email como username garante unicidade
 			Email:           googleUser.Email,
-			Password:        "",       // Sem password para logins OAuth
-			AuthProvider:    "google", // Definir o provedor
+			Password:        "", // Sem password para logins OAuth
+			AuthProvider:    model.ProviderGoogle,
 			IsEmailVerified: true,
 		}
-
-		if err := newUser.CreateUser(database.DB); err != nil {
+		if err := user.CreateUser(database.DB); err != nil {
 			logger.L.Error("Failed to create Google user", "error", err)
-			http.Redirect(w, r, "/signin?error=user_creation_failed", http.StatusTemporaryRedirect)
-			return
+			return nil, "user_creation_failed"
 		}
-		user = newUser
-
-	} else { // Utilizador já existe
-		// CORREÇÃO: Verificar se a conta existente é local (tem password)
-		if user.AuthProvider == "local" || user.Password != "" {
-			logger.L.Warn("Google login attempt for existing local account", "email", user.Email)
-			http.Redirect(w, r, "/signin?error=email_already_exists_local", http.StatusTemporaryRedirect)
-			return
+	} else {
+		// An account with this email exists but is not linked to this Google account.
+		// Password accounts must link Google from their settings after logging in.
+		hasLocal, err := model.HasIdentity(database.DB, user.ID, model.ProviderLocal)
+		if err != nil {
+			logger.L.Error("Failed to check local identity", "userID", user.ID, "error", err)
+			return nil, "user_lookup_failed"
+		}
+		hasGoogle, err := model.HasIdentity(database.DB, user.ID, model.ProviderGoogle)
+		if err != nil {
+			logger.L.Error("Failed to check Google identity", "userID", user.ID, "error", err)
+			return nil, "user_lookup_failed"
 		}
+		if hasLocal || hasGoogle || user.AuthProvider == model.ProviderLocal {
+			logger.L.Warn("Google login attempt for existing account not linked to this Google account", "email", user.Email)
+			return nil, "email_already_exists_local"
+		}
+	}
+
+	// Google accounts created before identities were stored are linked on their first login.
+	if err := model.CreateIdentity(database.DB, &model.UserIdentity{
+		UserID:         user.ID,
+		Provider:       model.ProviderGoogle,
+		ProviderUserID: googleUser.ID,
+		Email:          googleUser.Email,
+	}); err != nil {
+		logger.L.Error("Failed to store Google identity", "userID", user.ID, "error", err)
+		return nil, "user_creation_failed"
 	}
+	return user, ""
+}
 
-	// Gerar o nosso próprio token JWT para o frontend
-	appToken, err := h.authService.GenerateToken(fmt.Sprintf("%d", user.ID))
+// fetchGoogleUser exchanges the authorization code and reads the Google profile.
+// The raw profile JSON is returned as well since it is forwarded to the frontend.
+func fetchGoogleUser(code, verifier string) (*googleUserInfo, []byte, error) {
+	token, err := googleOauthConfig.Exchange(context.Background(), code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		logger.L.Error("Failed to generate app token for Google user", "error", err)
-		http.Redirect(w, r, "/signin?error=token_generation_failed", http.StatusTemporaryRedirect)
-		return
+		return nil, nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
 
-	// Redirecionar para uma página de callback no frontend com o token
-	redirectURL := fmt.Sprintf("%s/auth/google/callback?token=%s&user=%s",
-		config.Cfg.FrontendBaseURL, // <-- USE THE CONFIG VARIABLE
-		appToken,
-		url.QueryEscape(string(contents)))
-	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
+	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to request user info: %w", err)
+	}
+	defer response.Body.Close()
+
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read user info response body: %w", err)
+	}
+
+	var googleUser googleUserInfo
+	if err := json.Unmarshal(contents, &googleUser); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal user info: %w", err)
+	}
+	if googleUser.ID == "" {
+		return nil, nil, errors.New("user info response has no account id")
+	}
+	return &googleUser, contents, nil
+}
+
+func redirectToSettings(w http.ResponseWriter, r *http.Request, query string) {
+	http.Redirect(w, r, config.Cfg.FrontendBaseURL+"/settings?"+query, http.StatusTemporaryRedirect)
 }
@@ -0,0 +1,111 @@
+// backend/src/handlers/openapi_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/apiv2"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/openapi"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// OpenAPIHandler serves the OpenAPI document of a version of the API, generated from the registered routes.
+type OpenAPIHandler struct {
+	routes  chi.Routes
+	version string
+	once    sync.Once
+	spec    []byte
+	err     error
+}
+
+// NewOpenAPIHandler creates a handler documenting the routes of an API version on the router. The
+// document is generated on the first request, once every route has been registered.
+func NewOpenAPIHandler(routes chi.Routes, version string) *OpenAPIHandler {
+	return &OpenAPIHandler{routes: routes, version: version}
+}
+
+// HandleGetOpenAPISpec returns the OpenAPI 3 document of the API.
+func (h *OpenAPIHandler) HandleGetOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		var doc *openapi.Document
+		doc, h.err = openapi.Generate(h.routes, openAPIOptions(h.version))
+		if h.err == nil {
+			h.spec, h.err = json.Marshal(doc)
+		}
+	})
+	if h.err != nil {
+		logger.FromContext(r.Context()).Error("Error generating OpenAPI document", "error", h.err)
+		utils.SendJSONError(w, "Error generating OpenAPI document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.spec)
+}
+
+// openAPIOptions describes how the middlewares in front of the handlers show up in the document of
+// an API version, and the response bodies of the version 2 handlers.
+func openAPIOptions(version string) openapi.Options {
+	opts := openapi.Options{
+		Info: openapi.Info{
+			Title:       "Taxfolio API",
+			Version:     version,
+			Description: "Errors are returned as {code, message, details}.",
+		},
+		PathPrefix: APIVersionPrefix(version),
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"session": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Access token returned by /auth/login"},
+			"admin":   {Type: "http", Scheme: "bearer", Description: "The server's ADMIN_TOKEN"},
+		},
+		Middlewares: []openapi.MiddlewareDoc{
+			{Func: (*UserHandler).AuthMiddleware, Apply: func(op *openapi.Operation) {
+				op.Security = append(op.Security, map[string][]string{"session": {}})
+			}},
+			{Func: AdminTokenMiddleware, Apply: func(op *openapi.Operation) {
+				op.Security = append(op.Security, map[string][]string{"admin": {}})
+			}},
+			{Func: CSRFMiddleware, Apply: func(op *openapi.Operation) {
+				op.Parameters = append(op.Parameters, openapi.Parameter{
+					Name: csrfHeaderName, In: "header", Required: true, Schema: openapi.Schema{Type: "string"},
+					Description: "Token from GET /auth/csrf, also sent back in its cookie",
+				})
+			}},
+			{Func: LocaleMiddleware, Apply: func(op *openapi.Operation) {
+				op.Parameters = append(op.Parameters, openapi.Parameter{
+					Name: "Accept-Language", In: "header", Schema: openapi.Schema{Type: "string"},
+					Description: "Locale of messages when the user has not saved one (pt-PT or en-US)",
+				})
+			}},
+			{Func: ShareLinkMiddleware, Apply: func(op *openapi.Operation) {
+				op.Description = "Authenticated by the share link token in the path; read-only."
+			}},
+			{Func: RequireShareScope, Apply: func(op *openapi.Operation) {
+				op.Description += " Requires the link to grant this report; a link limited to a tax year only opens that year."
+			}},
+			{Func: RequirePremium, Apply: func(op *openapi.Operation) {
+				op.Description = "Requires a premium plan when billing is enabled; otherwise answers 403 with code PREMIUM_REQUIRED."
+			}},
+		},
+	}
+	switch version {
+	case APIVersion1:
+		opts.ExcludePrefixes = []string{APIVersionPrefix(APIVersion2)}
+	case APIVersion2:
+		opts.Info.Description = "Dates are ISO 8601, YYYY-MM-DD for days and RFC 3339 in UTC for instants. " +
+			"Amounts are decimal strings, exact to the millionth. " + opts.Info.Description
+		opts.Responses = []openapi.ResponseDoc{
+			{Handler: (*V2Handler).HandleGetTransactions, Body: []apiv2.Transaction{}},
+			{Handler: (*V2Handler).HandleGetDividends, Body: []apiv2.Transaction{}},
+			{Handler: (*V2Handler).HandleGetStockSales, Body: []apiv2.StockSale{}},
+			{Handler: (*V2Handler).HandleGetStockHoldings, Body: []apiv2.Holdings{}},
+			{Handler: (*V2Handler).HandleGetFees, Body: []apiv2.Fee{}},
+			{Handler: (*V2Handler).HandleGetOptionSales, Body: []apiv2.OptionSale{}},
+			{Handler: (*V2Handler).HandleGetOptionHoldings, Body: []apiv2.OptionPosition{}},
+		}
+	}
+	return opts
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
 )
 
 type ChangePasswordRequest struct {
@@ -65,7 +66,7 @@ func (h *UserHandler) RequestPasswordResetHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = h.emailService.SendPasswordResetEmail(user.Email, user.Username, resetToken)
+	err = h.emailService.SendPasswordResetEmail(user.Email, user.Username, resetToken, storedLocale(r, user.ID))
 	if err != nil {
 		logger.L.Error("Failed to send password reset email", "userEmail", user.Email, "error", err)
 	}
@@ -94,10 +95,6 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 		sendJSONError(w, "Passwords do not match", http.StatusBadRequest)
 		return
 	}
-	if !passwordRegex.MatchString(req.Password) {
-		sendJSONError(w, "Password must be at least 6 characters long", http.StatusBadRequest)
-		return
-	}
 
 	user, err := model.GetUserByPasswordResetToken(database.DB, req.Token)
 	if err != nil {
@@ -105,6 +102,9 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 		sendJSONError(w, "Invalid or expired password reset token.", http.StatusBadRequest)
 		return
 	}
+	if !h.checkPassword(w, r, req.Password, user.Username, user.Email) {
+		return
+	}
 
 	hashedPassword, err := h.authService.HashPassword(req.Password)
 	if err != nil {
@@ -119,6 +119,12 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Proving control of the mailbox is enough to lift a login lock as well.
+	if err := model.ResetFailedLogins(database.DB, user.ID); err != nil {
+		logger.L.Error("Failed to unlock account after password reset", "userID", user.ID, "error", err)
+	}
+
+	recordAudit(r, user.ID, models.AuditPasswordReset, nil)
 	logger.L.Info("Password reset successfully", "userID", user.ID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been reset successfully. You can now log in with your new password."})
@@ -141,10 +147,6 @@ func (h *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Reque
 		sendJSONError(w, "New passwords do not match", http.StatusBadRequest)
 		return
 	}
-	if !passwordRegex.MatchString(req.NewPassword) {
-		sendJSONError(w, "New password must be at least 6 characters long", http.StatusBadRequest)
-		return
-	}
 
 	user, err := model.GetUserByID(database.DB, userID)
 	if err != nil {
@@ -153,9 +155,15 @@ func (h *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// CORREÇÃO: Impedir que utilizadores não-locais (ex: Google) mudem a password aqui
-	if user.AuthProvider != "local" {
-		logger.L.Warn("Attempt to change password for non-local account", "userID", userID, "provider", user.AuthProvider)
+	// Only accounts with a password login can change it here; Google accounts set one via /user/identities/local.
+	hasLocal, err := model.HasIdentity(database.DB, userID, model.ProviderLocal)
+	if err != nil {
+		logger.L.Error("Failed to check local identity for password change", "userID", userID, "error", err)
+		sendJSONError(w, "Failed to retrieve user information", http.StatusInternalServerError)
+		return
+	}
+	if !hasLocal {
+		logger.L.Warn("Attempt to change password for account without password login", "userID", userID, "provider", user.AuthProvider)
 		sendJSONError(w, "Password cannot be changed for accounts created via Google.", http.StatusForbidden)
 		return
 	}
@@ -165,6 +173,9 @@ func (h *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Reque
 		sendJSONError(w, "Incorrect current password", http.StatusForbidden)
 		return
 	}
+	if !h.checkPassword(w, r, req.NewPassword, user.Username, user.Email) {
+		return
+	}
 
 	hashedNewPassword, err := h.authService.HashPassword(req.NewPassword)
 	if err != nil {
@@ -179,6 +190,7 @@ func (h *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	recordAudit(r, userID, models.AuditPasswordChanged, nil)
 	logger.L.Info("Password changed successfully", "userID", userID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully."})
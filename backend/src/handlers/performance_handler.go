@@ -0,0 +1,65 @@
+// backend/src/handlers/performance_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/security/validation"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// PerformanceHandler serves portfolio return metrics.
+type PerformanceHandler struct {
+	performanceService services.PerformanceService
+}
+
+// NewPerformanceHandler creates a new instance of PerformanceHandler.
+func NewPerformanceHandler(performanceService services.PerformanceService) *PerformanceHandler {
+	return &PerformanceHandler{
+		performanceService: performanceService,
+	}
+}
+
+// HandleGetPerformance returns XIRR and time-weighted returns for the requested period (ytd, 1y or all).
+// An optional benchmark query parameter overrides the default benchmark ISIN.
+func (h *PerformanceHandler) HandleGetPerformance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = services.PeriodAll
+	}
+	benchmarkISIN := r.URL.Query().Get("benchmark")
+	if benchmarkISIN != "" {
+		if err := validation.ValidateISIN(benchmarkISIN); err != nil {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	logger.FromContext(r.Context()).Info("Handling GetPerformance request", "userID", userID, "period", period, "benchmark", benchmarkISIN)
+
+	result, err := h.performanceService.GetPerformance(r.Context(), userID, period, benchmarkISIN)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPeriod) {
+			utils.SendJSONError(w, "Invalid period. Use one of: ytd, 1y, all.", http.StatusBadRequest)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error computing performance", "userID", userID, "period", period, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing performance: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding performance to JSON", "userID", userID, "error", err)
+	}
+}
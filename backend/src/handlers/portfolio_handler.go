@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/security/validation"
 	"github.com/username/taxfolio/backend/src/services"
 	"github.com/username/taxfolio/backend/src/utils"
 )
@@ -15,12 +20,14 @@ import (
 type PortfolioHandler struct {
 	uploadService services.UploadService
 	priceService  services.PriceService
+	tagService    services.TransactionTagService
 }
 
-func NewPortfolioHandler(uploadService services.UploadService, priceService services.PriceService) *PortfolioHandler {
+func NewPortfolioHandler(uploadService services.UploadService, priceService services.PriceService, tagService services.TransactionTagService) *PortfolioHandler {
 	return &PortfolioHandler{
 		uploadService: uploadService,
 		priceService:  priceService,
+		tagService:    tagService,
 	}
 }
 
@@ -52,7 +59,7 @@ func (h *PortfolioHandler) HandleGetCurrentHoldingsValue(w http.ResponseWriter,
 	log.Printf("Handling GetCurrentHoldingsValue for userID: %d", userID)
 
 	// 1. Get all individual purchase lots.
-	holdingsByYear, err := h.uploadService.GetStockHoldings(userID)
+	holdingsByYear, err := h.uploadService.GetStockHoldings(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving stock holdings for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
@@ -95,7 +102,12 @@ func (h *PortfolioHandler) HandleGetCurrentHoldingsValue(w http.ResponseWriter,
 	}
 
 	// 4. Call the PriceService to get current prices for the unique ISINs.
-	prices, err := h.priceService.GetCurrentPrices(uniqueISINs)
+	baseCurrency, err := h.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		utils.SendJSONError(w, fmt.Sprintf("Error retrieving base currency for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
+	prices, err := h.priceService.GetCurrentPrices(uniqueISINs, baseCurrency)
 	if err != nil {
 		// Log the error but don't fail the request. We can still return holdings with purchase data.
 		log.Printf("Warning: could not fetch some or all current prices for userID %d: %v", userID, err)
@@ -121,9 +133,9 @@ func (h *PortfolioHandler) HandleGetCurrentHoldingsValue(w http.ResponseWriter,
 			ISIN:              holding.ISIN,
 			ProductName:       holding.ProductName,
 			Quantity:          holding.TotalQuantity,
-			TotalCostBasisEUR: holding.TotalCostBasisEUR,
+			TotalCostBasisEUR: utils.RoundMoney(holding.TotalCostBasisEUR),
 			CurrentPriceEUR:   currentPrice,
-			MarketValueEUR:    marketValue,
+			MarketValueEUR:    utils.RoundMoney(marketValue),
 			Status:            status,
 		})
 	}
@@ -139,14 +151,26 @@ func (h *PortfolioHandler) HandleGetStockSales(w http.ResponseWriter, r *http.Re
 		return
 	}
 	log.Printf("Handling GetStockSales for userID: %d", userID)
-	stockSales, err := h.uploadService.GetStockSaleDetails(userID)
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+	stockSales, err := h.uploadService.GetStockSaleDetails(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving stock sales for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
 	}
-	if stockSales == nil {
-		stockSales = []models.SaleDetail{}
+	if tagFilter != nil {
+		filtered := []models.SaleDetail{}
+		for _, sale := range stockSales {
+			if tagFilter.MatchSale(sale) {
+				filtered = append(filtered, sale)
+			}
+		}
+		stockSales = filtered
 	}
+	stockSales = localizeSaleDetails(i18n.FromContext(r.Context()), stockSales)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stockSales)
 }
@@ -158,15 +182,12 @@ func (h *PortfolioHandler) HandleGetOptionSales(w http.ResponseWriter, r *http.R
 		return
 	}
 	log.Printf("Handling GetOptionSales for userID: %d", userID)
-	optionSales, err := h.uploadService.GetOptionSaleDetails(userID)
+	optionSales, err := h.uploadService.GetOptionSaleDetails(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving option sales for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
 	}
-	response := map[string]interface{}{"OptionSaleDetails": optionSales}
-	if optionSales == nil {
-		response["OptionSaleDetails"] = []models.OptionSaleDetail{}
-	}
+	response := map[string]interface{}{"OptionSaleDetails": localizeOptionSaleDetails(i18n.FromContext(r.Context()), optionSales)}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -177,8 +198,25 @@ func (h *PortfolioHandler) HandleGetStockHoldings(w http.ResponseWriter, r *http
 		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	year := r.URL.Query().Get("year")
+	if year != "" {
+		if !yearParamRegex.MatchString(year) {
+			utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Handling GetStockHoldings for userID: %d, year: %s", userID, year)
+		lots, err := h.uploadService.GetStockHoldingsForYear(r.Context(), userID, year)
+		if err != nil {
+			utils.SendJSONError(w, fmt.Sprintf("Error retrieving stock holdings for userID %d: %v", userID, err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]models.PurchaseLot{year: lots})
+		return
+	}
+
 	log.Printf("Handling GetStockHoldings for userID: %d", userID)
-	stockHoldings, err := h.uploadService.GetStockHoldings(userID)
+	stockHoldings, err := h.uploadService.GetStockHoldings(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving stock holdings for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
@@ -190,6 +228,23 @@ func (h *PortfolioHandler) HandleGetStockHoldings(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(stockHoldings)
 }
 
+// HandleGetHoldingYears lists the years for which a 31-Dec holdings snapshot is available.
+func (h *PortfolioHandler) HandleGetHoldingYears(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	log.Printf("Handling GetHoldingYears for userID: %d", userID)
+	years, err := h.uploadService.GetHoldingYears(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, fmt.Sprintf("Error retrieving holding years for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(years)
+}
+
 func (h *PortfolioHandler) HandleGetOptionHoldings(w http.ResponseWriter, r *http.Request) {
 	userID, ok := GetUserIDFromContext(r.Context())
 	if !ok {
@@ -197,7 +252,7 @@ func (h *PortfolioHandler) HandleGetOptionHoldings(w http.ResponseWriter, r *htt
 		return
 	}
 	log.Printf("Handling GetOptionHoldings for userID: %d", userID)
-	optionHoldings, err := h.uploadService.GetOptionHoldings(userID)
+	optionHoldings, err := h.uploadService.GetOptionHoldings(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving option holdings for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
@@ -208,3 +263,89 @@ func (h *PortfolioHandler) HandleGetOptionHoldings(w http.ResponseWriter, r *htt
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(optionHoldings)
 }
+
+// HandleGetCostBasisAdjustments lists how return of capital distributions lowered the cost basis of open lots.
+func (h *PortfolioHandler) HandleGetCostBasisAdjustments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	adjustments, err := h.uploadService.GetCostBasisAdjustments(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving cost basis adjustments", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving cost basis adjustments", http.StatusInternalServerError)
+		return
+	}
+	if adjustments == nil {
+		adjustments = []models.CostBasisAdjustment{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adjustments)
+}
+
+// HandleAddOpeningLots records positions transferred in from another broker, with their cost basis,
+// as the oldest purchase lots of each ISIN.
+func (h *PortfolioHandler) HandleAddOpeningLots(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.OpeningLotsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.uploadService.AddOpeningLots(userID, req.Lots)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOpeningLot) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if sendQuotaExceeded(w, r, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error adding opening lots", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error adding opening lots", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// HandleGetPositionTimeline returns the buys, sells and dividends of one ISIN in date order, each with
+// the quantity and cost basis held after it.
+func (h *PortfolioHandler) HandleGetPositionTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	isin := strings.ToUpper(chi.URLParam(r, "isin"))
+	if err := validation.ValidateISIN(isin); err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := h.uploadService.GetPositionTimeline(r.Context(), userID, isin)
+	if err != nil {
+		if errors.Is(err, services.ErrPositionNotFound) {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error building position timeline", "userID", userID, "isin", isin, "error", err)
+		utils.SendJSONError(w, "Error building position timeline", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(timeline); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding position timeline to JSON", "userID", userID, "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+// backend/src/handlers/recalculation_handler.go
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// RecalculationHandler rebuilds a user's derived data on request and returns the reconciliation report.
+type RecalculationHandler struct {
+	recalculationService services.RecalculationService
+}
+
+// NewRecalculationHandler creates a new instance of RecalculationHandler.
+func NewRecalculationHandler(recalculationService services.RecalculationService) *RecalculationHandler {
+	return &RecalculationHandler{
+		recalculationService: recalculationService,
+	}
+}
+
+// HandleRecalculateUser rebuilds the data of the user in the URL. It is an operator endpoint.
+func (h *RecalculationHandler) HandleRecalculateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	h.recalculate(w, r, userID)
+}
+
+// HandleRecalculate rebuilds the data of the authenticated user.
+func (h *RecalculationHandler) HandleRecalculate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	h.recalculate(w, r, userID)
+}
+
+func (h *RecalculationHandler) recalculate(w http.ResponseWriter, r *http.Request, userID int64) {
+	logger.FromContext(r.Context()).Info("Handling Recalculate request", "userID", userID)
+
+	report, err := h.recalculationService.Recalculate(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.SendJSONError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error recalculating user data", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error recalculating data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding reconciliation report to JSON", "userID", userID, "error", err)
+	}
+}
@@ -0,0 +1,118 @@
+// backend/src/handlers/report_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// ReportHandler serves reports combining the results of several processors in one response.
+type ReportHandler struct {
+	reportService    services.ReportService
+	householdService services.HouseholdService
+}
+
+// NewReportHandler creates a new instance of ReportHandler.
+func NewReportHandler(reportService services.ReportService, householdService services.HouseholdService) *ReportHandler {
+	return &ReportHandler{
+		reportService:    reportService,
+		householdService: householdService,
+	}
+}
+
+// HandleGetAnnualReport returns the realized gains, dividends, fees, interest and year-end holdings of
+// the tax year given by the year parameter. With scope=household, it returns those of every member of
+// the user's household, and their combination.
+func (h *ReportHandler) HandleGetAnnualReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	year := r.URL.Query().Get("year")
+	if !yearParamRegex.MatchString(year) {
+		utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+		return
+	}
+	household, ok := householdScope(w, r)
+	if !ok {
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetAnnualReport request", "userID", userID, "year", year, "household", household)
+
+	if household {
+		report, err := h.householdService.GetAnnualReport(r.Context(), userID, year)
+		if err != nil {
+			sendHouseholdError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			logger.FromContext(r.Context()).Error("Error encoding household annual report to JSON", "userID", userID, "error", err)
+		}
+		return
+	}
+
+	report, err := h.reportService.GetAnnualReport(r.Context(), userID, year)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing annual report", "userID", userID, "year", year, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing annual report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding annual report to JSON", "userID", userID, "error", err)
+	}
+}
+
+// maxComparedYears bounds the years a comparison may ask for.
+const maxComparedYears = 10
+
+// HandleCompareYears sets the realized gains, dividends, fees and contributions of the tax years given
+// by the comma-separated years parameter side by side, with the change from each year to the next.
+func (h *ReportHandler) HandleCompareYears(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var years []string
+	for _, year := range strings.Split(r.URL.Query().Get("years"), ",") {
+		year = strings.TrimSpace(year)
+		if !yearParamRegex.MatchString(year) {
+			utils.SendJSONError(w, "Invalid years. Use a comma-separated list of years in the format YYYY.", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(years, year) {
+			years = append(years, year)
+		}
+	}
+	if len(years) < 2 || len(years) > maxComparedYears {
+		utils.SendJSONError(w, fmt.Sprintf("Give from 2 to %d different years to compare.", maxComparedYears), http.StatusBadRequest)
+		return
+	}
+	slices.Sort(years)
+	logger.FromContext(r.Context()).Info("Handling CompareYears request", "userID", userID, "years", years)
+
+	comparison, err := h.reportService.CompareYears(r.Context(), userID, years)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing year comparison", "userID", userID, "years", years, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing year comparison: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(comparison); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding year comparison to JSON", "userID", userID, "error", err)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	searchDefaultLimit = 50
+	searchMaxLimit     = 200
+	searchMaxTerms     = 8
+)
+
+// searchWords splits text into lowercase words of letters and digits, the way the search index does.
+// Punctuation separates words, so "US0378331005" finds "AAPL(US0378331005) Cash Dividend".
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchTerms is the words of a search query, up to searchMaxTerms.
+func searchTerms(query string) []string {
+	terms := searchWords(query)
+	if len(terms) > searchMaxTerms {
+		terms = terms[:searchMaxTerms]
+	}
+	return terms
+}
+
+// HandleSearch finds the user's transactions whose product name, ISIN, description or order ID have
+// words starting with each word of ?q=, newest first.
+func (h *TransactionHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		utils.SendJSONError(w, "q must contain at least one letter or digit", http.StatusBadRequest)
+		return
+	}
+	limit := searchDefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > searchMaxLimit {
+			utils.SendJSONError(w, "limit must be a number from 1 to "+strconv.Itoa(searchMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// One more than the limit tells whether there are more
+	found, err := h.transactions.Search(r.Context(), userID, terms, limit+1)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error searching transactions", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error searching transactions", http.StatusInternalServerError)
+		return
+	}
+	response := models.SearchResponse{Query: query, Results: []models.SearchResult{}}
+	if len(found) > limit {
+		found, response.Truncated = found[:limit], true
+	}
+
+	annotations, err := h.tagService.GetAnnotations(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error querying transaction tags", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error searching transactions", http.StatusInternalServerError)
+		return
+	}
+	for _, tx := range localizeTransactions(i18n.FromContext(r.Context()), found) {
+		if a, ok := annotations[tx.ID]; ok {
+			tx.Note, tx.Tags = a.Note, a.Tags
+		}
+		response.Results = append(response.Results, models.SearchResult{
+			Type:          searchResultType(tx),
+			MatchedFields: matchedFields(tx, terms),
+			Transaction:   tx,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding search results to JSON", "userID", userID, "error", err)
+	}
+}
+
+// searchResultType groups transactions into the kinds a user looks for: buys and sells are trades,
+// and dividends include those paid in shares and returns of capital.
+func searchResultType(tx models.ProcessedTransaction) string {
+	switch tx.TransactionType {
+	case "STOCK", "OPTION":
+		return "trade"
+	case "BOND":
+		if tx.BuySell != "" {
+			return "trade"
+		}
+		return "bond"
+	case "DIVIDEND", "SCRIP_DIVIDEND", "RETURN_OF_CAPITAL":
+		return "dividend"
+	default:
+		return strings.ToLower(tx.TransactionType)
+	}
+}
+
+// matchedFields lists the searched fields of tx containing a word that starts with one of terms,
+// ignoring accents as the SQLite index does.
+func matchedFields(tx models.ProcessedTransaction, terms []string) []string {
+	folded := make([]string, len(terms))
+	for i, term := range terms {
+		folded[i] = removeDiacritics(term)
+	}
+	fields := []struct{ name, value string }{
+		{"product_name", tx.ProductName},
+		{"isin", tx.ISIN},
+		{"description", tx.Description},
+		{"order_id", tx.OrderID},
+	}
+	matched := []string{}
+	for _, field := range fields {
+		for _, word := range searchWords(field.value) {
+			if containsPrefixOf(removeDiacritics(word), folded) {
+				matched = append(matched, field.name)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func containsPrefixOf(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDiacritics strips the accents of a word, so "ação" reads "acao".
+func removeDiacritics(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
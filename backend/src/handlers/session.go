@@ -0,0 +1,102 @@
+// backend/src/handlers/session.go
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+)
+
+const (
+	accessTokenCookieName  = "access_token"
+	refreshTokenCookieName = "refresh_token"
+	// sessionExtendInterval is how far a session in use must be from its sliding expiry before it is
+	// written again, so that a burst of requests does not update it on every one.
+	sessionExtendInterval = time.Minute
+)
+
+// sessionExpiry returns when a session created at createdAt expires if it is last used at now: after
+// SESSION_IDLE_TIMEOUT without use when it is set, and in any case REFRESH_TOKEN_EXPIRY after it was created.
+func sessionExpiry(createdAt, now time.Time) time.Time {
+	expiresAt := createdAt.Add(config.Cfg.RefreshTokenExpiry)
+	if idle := config.Cfg.SessionIdleTimeout; idle > 0 && now.Add(idle).Before(expiresAt) {
+		return now.Add(idle)
+	}
+	return expiresAt
+}
+
+// extendSession pushes back the expiry of a session being used, when sessions expire after a time
+// without use.
+func extendSession(r *http.Request, session *model.Session) {
+	if config.Cfg.SessionIdleTimeout <= 0 {
+		return
+	}
+	expiresAt := sessionExpiry(session.CreatedAt, time.Now())
+	if expiresAt.Sub(session.ExpiresAt) < sessionExtendInterval {
+		return
+	}
+	if err := model.ExtendSession(database.DB, int64(session.ID), expiresAt); err != nil {
+		logger.FromContext(r.Context()).Warn("Failed to extend session", "sessionID", session.ID, "error", err)
+	}
+}
+
+// sessionTokens returns the fields of a login or refresh response that carry the session's tokens.
+// With AUTH_COOKIES they are set as HttpOnly cookies instead, out of reach of the frontend's scripts,
+// and no field is returned.
+func sessionTokens(w http.ResponseWriter, r *http.Request, accessToken, refreshToken string) map[string]interface{} {
+	if !config.Cfg.AuthCookies {
+		return map[string]interface{}{"access_token": accessToken, "refresh_token": refreshToken}
+	}
+	setAuthCookies(w, r, accessToken, refreshToken)
+	return map[string]interface{}{}
+}
+
+// setAuthCookies stores the session's tokens in cookies. The access token cookie lasts as long as the
+// session, not the access token, so that the expired token still identifies the session the CSRF token
+// was bound to when it is refreshed. The refresh token is only sent to the auth endpoints.
+func setAuthCookies(w http.ResponseWriter, r *http.Request, accessToken, refreshToken string) {
+	maxAge := int(config.Cfg.RefreshTokenExpiry.Seconds())
+	http.SetCookie(w, authCookie(r, accessTokenCookieName, accessToken, "/", maxAge))
+	http.SetCookie(w, authCookie(r, refreshTokenCookieName, refreshToken, authCookiePath(), maxAge))
+}
+
+// clearAuthCookies removes the session's token cookies.
+func clearAuthCookies(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, authCookie(r, accessTokenCookieName, "", "/", -1))
+	http.SetCookie(w, authCookie(r, refreshTokenCookieName, "", authCookiePath(), -1))
+}
+
+// authCookie builds a token cookie with the SameSite mode and Secure rule of the CSRF cookie, which
+// must reach the same requests.
+func authCookie(r *http.Request, name, value, path string, maxAge int) *http.Cookie {
+	sameSite := csrfCookieSameSite()
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		SameSite: sameSite,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || sameSite == http.SameSiteNoneMode,
+		MaxAge:   maxAge,
+	}
+}
+
+func authCookiePath() string {
+	return APIVersionPrefix(APIVersion1) + "/auth"
+}
+
+// refreshTokenCookie returns the refresh token cookie sent with the request, or "" without AUTH_COOKIES.
+func refreshTokenCookie(r *http.Request) string {
+	if !config.Cfg.AuthCookies {
+		return ""
+	}
+	cookie, err := r.Cookie(refreshTokenCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
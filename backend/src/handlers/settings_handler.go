@@ -0,0 +1,229 @@
+// backend/src/handlers/settings_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// SettingsHandler manages per-user preferences: all of them at once, or the base currency, fiscal year and locale on their own.
+type SettingsHandler struct {
+	uploadService   services.UploadService
+	settingsService services.SettingsService
+}
+
+// NewSettingsHandler creates a new instance of SettingsHandler.
+func NewSettingsHandler(uploadService services.UploadService, settingsService services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{
+		uploadService:   uploadService,
+		settingsService: settingsService,
+	}
+}
+
+// HandleGetSettings returns all of the user's preferences.
+func (h *SettingsHandler) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving settings", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// HandleUpdateSettings changes the preferences present in the body and keeps the others. Unknown keys
+// are refused, and nothing is saved unless every value is valid.
+func (h *SettingsHandler) HandleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving settings", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving settings", http.StatusInternalServerError)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		utils.SendJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.settingsService.UpdateSettings(userID, settings)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSettings) || errors.Is(err, services.ErrUnsupportedCurrency) || errors.Is(err, services.ErrInvalidFiscalYear) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error updating settings", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error updating settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+type BaseCurrencyRequest struct {
+	BaseCurrency string `json:"base_currency"`
+}
+
+// HandleGetBaseCurrency returns the currency the user's reports are expressed in.
+func (h *SettingsHandler) HandleGetBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	currency, err := h.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving base currency", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving base currency", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BaseCurrencyRequest{BaseCurrency: currency})
+}
+
+// HandleSetBaseCurrency changes the user's reporting currency, converting all stored amounts to it.
+func (h *SettingsHandler) HandleSetBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req BaseCurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.uploadService.SetBaseCurrency(userID, req.BaseCurrency); err != nil {
+		if errors.Is(err, services.ErrUnsupportedCurrency) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error changing base currency", "userID", userID, "currency", req.BaseCurrency, "error", err)
+		utils.SendJSONError(w, "Error changing base currency. No amounts were changed.", http.StatusInternalServerError)
+		return
+	}
+
+	h.HandleGetBaseCurrency(w, r)
+}
+
+type FiscalYearRequest struct {
+	FiscalYearStart string `json:"fiscal_year_start"`
+}
+
+// HandleGetFiscalYear returns the day and month (DD-MM) the user's tax years start on.
+func (h *SettingsHandler) HandleGetFiscalYear(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	start, err := h.uploadService.GetFiscalYear(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving fiscal year", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving fiscal year", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FiscalYearRequest{FiscalYearStart: start})
+}
+
+// HandleSetFiscalYear changes the start of the user's tax years, which reports are bucketed by.
+func (h *SettingsHandler) HandleSetFiscalYear(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req FiscalYearRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.uploadService.SetFiscalYear(userID, req.FiscalYearStart); err != nil {
+		if errors.Is(err, services.ErrInvalidFiscalYear) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error changing fiscal year", "userID", userID, "start", req.FiscalYearStart, "error", err)
+		utils.SendJSONError(w, "Error changing fiscal year", http.StatusInternalServerError)
+		return
+	}
+
+	h.HandleGetFiscalYear(w, r)
+}
+
+type LocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+// HandleGetLocale returns the locale API-generated text is rendered in for the user.
+func (h *SettingsHandler) HandleGetLocale(w http.ResponseWriter, r *http.Request) {
+	if _, ok := GetUserIDFromContext(r.Context()); !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LocaleRequest{Locale: i18n.FromContext(r.Context())})
+}
+
+// HandleSetLocale changes the locale of the user's country names, report messages and emails.
+func (h *SettingsHandler) HandleSetLocale(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req LocaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	locale, ok := i18n.Normalize(req.Locale)
+	if !ok {
+		utils.SendJSONError(w, "Unsupported locale: use pt-PT or en-US", http.StatusBadRequest)
+		return
+	}
+
+	if err := model.SetUserLocale(database.DB, userID, locale); err != nil {
+		logger.FromContext(r.Context()).Error("Error changing locale", "userID", userID, "locale", locale, "error", err)
+		utils.SendJSONError(w, "Error changing locale", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LocaleRequest{Locale: locale})
+}
@@ -0,0 +1,209 @@
+// backend/src/handlers/share_link_handler.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const shareLinkContextKey contextKey = "shareLink"
+
+// ShareLinkHandler manages the user's share links and describes the link a shared request came with.
+type ShareLinkHandler struct {
+	shareLinkService services.ShareLinkService
+}
+
+// NewShareLinkHandler creates a new instance of ShareLinkHandler.
+func NewShareLinkHandler(shareLinkService services.ShareLinkService) *ShareLinkHandler {
+	return &ShareLinkHandler{
+		shareLinkService: shareLinkService,
+	}
+}
+
+// ShareLinkRequest is the body of a request creating a share link.
+type ShareLinkRequest struct {
+	Label         string   `json:"label"`
+	Scopes        []string `json:"scopes"`
+	Year          string   `json:"year"`            // Optional: the only tax year the link opens
+	ExpiresInDays int      `json:"expires_in_days"` // Defaults to 30
+}
+
+// sendShareLinkError maps share link service errors to HTTP responses.
+func sendShareLinkError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidShareLink):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrShareLinkNotFound):
+		utils.SendJSONError(w, "Share link not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling share links", "error", err)
+		utils.SendJSONError(w, "Error handling share links", http.StatusInternalServerError)
+	}
+}
+
+// shareLinkFromContext returns the share link ShareLinkMiddleware resolved for the request.
+func shareLinkFromContext(ctx context.Context) (*models.ShareLink, bool) {
+	link, ok := ctx.Value(shareLinkContextKey).(*models.ShareLink)
+	return link, ok
+}
+
+// ShareLinkMiddleware authenticates requests by the share link token in the {token} URL parameter,
+// acting as the user who created the link. Routes behind it must be read-only and guarded by
+// RequireShareScope.
+func ShareLinkMiddleware(shareLinkService services.ShareLinkService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			link, err := shareLinkService.ResolveShareLink(chi.URLParam(r, "token"))
+			if errors.Is(err, model.ErrShareLinkNotFound) {
+				sendJSONError(w, "Invalid or expired share link", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				logger.FromContext(r.Context()).Error("Error resolving share link", "error", err)
+				sendJSONError(w, "Error resolving share link", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDContextKey, link.UserID)
+			ctx = context.WithValue(ctx, shareLinkContextKey, link)
+			ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("userID", link.UserID, "shareLinkID", link.ID))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireShareScope only lets through shared requests whose link grants scope. When the link is
+// limited to a tax year, the year parameter defaults to it and may not ask for another. Reports are
+// those of the link's creator alone, never combined with their household's. Each report let through
+// is recorded as an export in the creator's audit log.
+func RequireShareScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			link, ok := shareLinkFromContext(r.Context())
+			if !ok || !slices.Contains(link.Scopes, scope) {
+				sendJSONError(w, "This share link does not grant access to this report", http.StatusForbidden)
+				return
+			}
+			if reportScope := r.URL.Query().Get("scope"); reportScope != "" && reportScope != models.ReportScopeUser {
+				sendJSONError(w, "A share link only grants access to the reports of the user who created it", http.StatusForbidden)
+				return
+			}
+			if link.Year != "" {
+				query := r.URL.Query()
+				switch query.Get("year") {
+				case "":
+					query.Set("year", link.Year)
+					r = r.Clone(r.Context())
+					r.URL.RawQuery = query.Encode()
+				case link.Year:
+				default:
+					sendJSONError(w, "This share link only grants access to the year "+link.Year, http.StatusForbidden)
+					return
+				}
+			}
+			details := map[string]string{"report": scope, "share_link_id": strconv.FormatInt(link.ID, 10)}
+			if year := r.URL.Query().Get("year"); year != "" {
+				details["year"] = year
+			}
+			recordAudit(r, link.UserID, models.AuditReportExported, details)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HandleGetShareLinks lists the user's share links, without their tokens.
+func (h *ShareLinkHandler) HandleGetShareLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	links, err := h.shareLinkService.GetShareLinks(userID)
+	if err != nil {
+		sendShareLinkError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// HandleCreateShareLink creates a share link. The response carries its token, which is not shown
+// again.
+func (h *ShareLinkHandler) HandleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req ShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	link, err := h.shareLinkService.CreateShareLink(userID, models.ShareLink{Label: req.Label, Scopes: req.Scopes, Year: req.Year}, req.ExpiresInDays)
+	if err != nil {
+		sendShareLinkError(w, r, err)
+		return
+	}
+	recordAudit(r, userID, models.AuditShareLinkCreated, map[string]string{
+		"share_link_id": strconv.FormatInt(link.ID, 10),
+		"scopes":        strings.Join(link.Scopes, ","),
+		"expires_at":    link.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// HandleDeleteShareLink revokes a share link.
+func (h *ShareLinkHandler) HandleDeleteShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid share link ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shareLinkService.DeleteShareLink(userID, id); err != nil {
+		sendShareLinkError(w, r, err)
+		return
+	}
+	recordAudit(r, userID, models.AuditShareLinkRevoked, map[string]string{"share_link_id": strconv.FormatInt(id, 10)})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetSharedLink describes the share link of a shared request: its label, the reports it opens
+// and until when, for the page the link is opened on.
+func (h *ShareLinkHandler) HandleGetSharedLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := shareLinkFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "Invalid or expired share link", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"label":      link.Label,
+		"scopes":     link.Scopes,
+		"year":       link.Year,
+		"expires_at": link.ExpiresAt,
+	})
+}
@@ -0,0 +1,40 @@
+// backend/src/handlers/status_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// StatusHandler serves the public service status the frontend polls for banners.
+type StatusHandler struct {
+	statusService services.StatusService
+}
+
+// NewStatusHandler creates a new instance of StatusHandler.
+func NewStatusHandler(statusService services.StatusService) *StatusHandler {
+	return &StatusHandler{
+		statusService: statusService,
+	}
+}
+
+// HandleGetStatus returns the service status and the active announcement, if any.
+func (h *StatusHandler) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.statusService.GetStatus()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading service status", "error", err)
+		utils.SendJSONError(w, "Error loading service status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Every open tab polls this, so let browsers and proxies reuse the answer briefly.
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding service status to JSON", "error", err)
+	}
+}
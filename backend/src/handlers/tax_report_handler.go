@@ -0,0 +1,53 @@
+// backend/src/handlers/tax_report_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// TaxReportHandler serves the taxable categories of a year under the rules of the user's tax residence.
+type TaxReportHandler struct {
+	taxReportService services.TaxReportService
+}
+
+// NewTaxReportHandler creates a new instance of TaxReportHandler.
+func NewTaxReportHandler(taxReportService services.TaxReportService) *TaxReportHandler {
+	return &TaxReportHandler{
+		taxReportService: taxReportService,
+	}
+}
+
+// HandleGetTaxReport returns the taxable categories, exemptions and tax due for the tax year given
+// by the year parameter.
+func (h *TaxReportHandler) HandleGetTaxReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	year := r.URL.Query().Get("year")
+	if !yearParamRegex.MatchString(year) {
+		utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetTaxReport request", "userID", userID, "year", year)
+
+	report, err := h.taxReportService.GetReport(r.Context(), userID, year)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing tax report", "userID", userID, "year", year, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing tax report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding tax report to JSON", "userID", userID, "error", err)
+	}
+}
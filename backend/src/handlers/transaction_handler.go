@@ -3,27 +3,47 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/services"
 	"github.com/username/taxfolio/backend/src/utils"
 )
 
 type TransactionHandler struct {
+	transactions  model.TransactionRepository
 	uploadService services.UploadService
+	tagService    services.TransactionTagService
 }
 
-func NewTransactionHandler(uploadService services.UploadService) *TransactionHandler {
+func NewTransactionHandler(transactions model.TransactionRepository, uploadService services.UploadService, tagService services.TransactionTagService) *TransactionHandler {
 	return &TransactionHandler{
+		transactions:  transactions,
 		uploadService: uploadService,
+		tagService:    tagService,
 	}
 }
 
+// tagsFromQuery reads the tag filter of a report request, given as repeated or comma-separated "tag" parameters.
+func tagsFromQuery(r *http.Request) []string {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	return tags
+}
+
 func (h *TransactionHandler) HandleGetProcessedTransactions(w http.ResponseWriter, r *http.Request) {
 	userID, ok := GetUserIDFromContext(r.Context())
 	if !ok {
@@ -32,40 +52,35 @@ func (h *TransactionHandler) HandleGetProcessedTransactions(w http.ResponseWrite
 	}
 	log.Printf("Handling GetProcessedTransactions for userID: %d", userID)
 
-	rows, err := database.DB.Query(`
-		SELECT id, date, source, product_name, isin, quantity, original_quantity, price, 
-		       transaction_type, transaction_subtype, buy_sell, description, amount, currency, commission, 
-		       order_id, exchange_rate, amount_eur, country_code, input_string, hash_id
-		FROM processed_transactions
-		WHERE user_id = ?
-		ORDER BY date DESC, id DESC`, userID)
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+	annotations, err := h.tagService.GetAnnotations(userID)
+	if err != nil {
+		utils.SendJSONError(w, fmt.Sprintf("Error querying transaction tags for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
 
+	transactions, err := h.transactions.ListByUser(r.Context(), userID)
 	if err != nil {
 		utils.SendJSONError(w, fmt.Sprintf("Error querying transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
+	// Newest first
 	var processedTransactions []models.ProcessedTransaction
-	for rows.Next() {
-		var tx models.ProcessedTransaction
-		scanErr := rows.Scan(
-			&tx.ID, &tx.Date, &tx.Source, &tx.ProductName, &tx.ISIN, &tx.Quantity, &tx.OriginalQuantity, &tx.Price,
-			&tx.TransactionType, &tx.TransactionSubType, &tx.BuySell, &tx.Description, &tx.Amount, &tx.Currency,
-			&tx.Commission, &tx.OrderID, &tx.ExchangeRate, &tx.AmountEUR, &tx.CountryCode, &tx.InputString, &tx.HashId)
-		if scanErr != nil {
-			utils.SendJSONError(w, fmt.Sprintf("Error scanning transaction for userID %d: %v", userID, scanErr), http.StatusInternalServerError)
-			return
+	for _, tx := range slices.Backward(transactions) {
+		if !tagFilter.MatchTransaction(tx) {
+			continue
+		}
+		if a, ok := annotations[tx.ID]; ok {
+			tx.Note, tx.Tags = a.Note, a.Tags
 		}
 		processedTransactions = append(processedTransactions, tx)
 	}
-	if err = rows.Err(); err != nil {
-		utils.SendJSONError(w, fmt.Sprintf("Error iterating over transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
-		return
-	}
-	if processedTransactions == nil {
-		processedTransactions = []models.ProcessedTransaction{}
-	}
+	processedTransactions = localizeTransactions(i18n.FromContext(r.Context()), processedTransactions)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(processedTransactions); err != nil {
 		log.Printf("Error generating JSON response for processed transactions userID %d: %v", userID, err)
@@ -78,49 +93,257 @@ func (h *TransactionHandler) HandleDeleteAllProcessedTransactions(w http.Respons
 		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	logger.L.Info("Handling DeleteAllProcessedTransactions", "userID", userID)
+	logger.FromContext(r.Context()).Info("Handling DeleteAllProcessedTransactions", "userID", userID)
 
-	// Use a transaction to ensure atomicity
-	txDB, err := database.DB.Begin()
+	// Use a transaction to ensure atomicity; nothing is deleted when the client goes away midway
+	ctx, cancel := database.WithQueryTimeout(r.Context())
+	defer cancel()
+	txDB, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
-		logger.L.Error("Failed to begin transaction for data deletion", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Failed to begin transaction for data deletion", "userID", userID, "error", err)
 		utils.SendJSONError(w, "Failed to delete data", http.StatusInternalServerError)
 		return
 	}
 	defer txDB.Rollback() // Rollback on any error
 
-	// 1. Delete transactions
-	result, err := txDB.Exec("DELETE FROM processed_transactions WHERE user_id = ?", userID)
+	// 1. Delete transactions, their notes and tags, and any quarantined rows
+	if _, err = model.DeleteTransactionAnnotations(txDB, userID); err != nil {
+		logger.FromContext(r.Context()).Error("Error deleting transaction notes and tags from DB", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error deleting transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
+	deleted, err := h.transactions.DeleteByUser(txDB, userID)
 	if err != nil {
-		logger.L.Error("Error deleting all processed transactions from DB", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error deleting all processed transactions from DB", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error deleting transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
+	if _, err = txDB.ExecContext(ctx, "DELETE FROM skipped_transactions WHERE user_id = ?", userID); err != nil {
+		logger.FromContext(r.Context()).Error("Error deleting skipped transactions from DB", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error deleting transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
+		return
+	}
+
+	if err = model.DeleteMaterializedReports(txDB, userID); err != nil {
+		logger.FromContext(r.Context()).Error("Error deleting materialized reports from DB", "userID", userID, "error", err)
 		utils.SendJSONError(w, fmt.Sprintf("Error deleting transactions for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
 	}
 
 	// 2. Reset the user's upload count
-	_, err = txDB.Exec("UPDATE users SET upload_count = 0 WHERE id = ?", userID)
+	_, err = txDB.ExecContext(ctx, "UPDATE users SET upload_count = 0 WHERE id = ?", userID)
 	if err != nil {
-		logger.L.Error("Failed to reset upload count for user", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Failed to reset upload count for user", "userID", userID, "error", err)
 		utils.SendJSONError(w, "Failed to reset upload count", http.StatusInternalServerError)
 		return
 	}
 
 	// 3. Commit the transaction if all operations were successful
 	if err := txDB.Commit(); err != nil {
-		logger.L.Error("Failed to commit transaction for data deletion", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Failed to commit transaction for data deletion", "userID", userID, "error", err)
 		utils.SendJSONError(w, "Failed to finalize data deletion", http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		logger.L.Error("Error getting rows affected after deleting all transactions", "userID", userID, "error", err)
-	} else {
-		logger.L.Info("Successfully deleted all processed transactions and reset upload count", "userID", userID, "rowsAffected", rowsAffected)
-	}
+	logger.FromContext(r.Context()).Info("Successfully deleted all processed transactions and reset upload count", "userID", userID, "rowsAffected", deleted)
+	recordAudit(r, userID, models.AuditTransactionsDeleted, map[string]string{"count": strconv.FormatInt(deleted, 10)})
 
 	h.uploadService.InvalidateUserCache(userID)
-	logger.L.Info("User cache invalidated after deleting all transactions", "userID", userID)
+	logger.FromContext(r.Context()).Info("User cache invalidated after deleting all transactions", "userID", userID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// HandleGetSkippedTransactions lists the rows that could not be imported from previous uploads.
+func (h *TransactionHandler) HandleGetSkippedTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetSkippedTransactions", "userID", userID)
+
+	skipped, err := h.uploadService.GetSkippedTransactions(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving skipped transactions", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving skipped transactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(skipped); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding skipped transactions to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleReprocessSkippedTransactions runs the quarantined rows through the parsers again.
+func (h *TransactionHandler) HandleReprocessSkippedTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling ReprocessSkippedTransactions", "userID", userID)
+
+	summary, err := h.uploadService.ReprocessSkippedTransactions(userID)
+	if err != nil {
+		if sendQuotaExceeded(w, r, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error reprocessing skipped transactions", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error reprocessing skipped transactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding reprocess summary to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleImportSkippedTransactionManually imports a quarantined row as the transaction the user entered
+// for it.
+func (h *TransactionHandler) HandleImportSkippedTransactionManually(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid skipped transaction ID", http.StatusBadRequest)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling ImportSkippedTransactionManually", "userID", userID, "skippedID", id)
+
+	var entry models.ManualTransaction
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.uploadService.ImportSkippedTransactionManually(userID, id, entry)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidManualEntry) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, model.ErrSkippedTransactionNotFound) {
+			utils.SendJSONError(w, "Skipped transaction not found", http.StatusNotFound)
+			return
+		}
+		if sendQuotaExceeded(w, r, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error importing skipped transaction", "userID", userID, "skippedID", id, "error", err)
+		utils.SendJSONError(w, "Error importing skipped transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+type SetTransactionTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type SetTransactionNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// sendTagError maps transaction tag service errors to HTTP responses.
+func sendTagError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAnnotation):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrTransactionNotFound):
+		utils.SendJSONError(w, "Transaction not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling transaction tags", "error", err)
+		utils.SendJSONError(w, "Error handling transaction tags", http.StatusInternalServerError)
+	}
+}
+
+// HandleGetTags lists the user's tags with the number of transactions carrying each.
+func (h *TransactionHandler) HandleGetTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tags, err := h.tagService.GetTags(userID)
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding tags to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleSetTransactionTags replaces the tags of a processed transaction.
+func (h *TransactionHandler) HandleSetTransactionTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetTransactionTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	annotation, err := h.tagService.SetTags(userID, transactionID, req.Tags)
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(annotation); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding transaction annotation to JSON", "userID", userID, "error", err)
+	}
+}
+
+// HandleSetTransactionNote sets or, with an empty note, clears the note of a processed transaction.
+func (h *TransactionHandler) HandleSetTransactionNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetTransactionNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	annotation, err := h.tagService.SetNote(userID, transactionID, req.Note)
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(annotation); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding transaction annotation to JSON", "userID", userID, "error", err)
+	}
+}
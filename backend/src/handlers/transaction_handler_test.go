@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/cache"
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/services"
+)
+
+func TestImportSkippedTransactionManually(t *testing.T) {
+	logger.InitLogger("error")
+	path := filepath.Join(t.TempDir(), "test.db")
+	database.InitDB(path, "", database.Settings{MaxOpenConns: 1, MaxIdleConns: 1})
+	t.Chdir("../..") // The migrations are read from db/migrations
+	database.RunMigrations(path, "")
+	t.Cleanup(func() { database.DB.Close() })
+
+	result, err := database.DB.Exec(`INSERT INTO users (username, password, email) VALUES ('manual', 'x', 'manual@example.com')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, _ := result.LastInsertId()
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := models.SkippedRow{RawText: "05-03-2024,10:00,05-03-2024,APPLE INC,US0378331005,Corporate action,,EUR,0.00", Reason: "unknown description"}
+	if err := model.InsertSkippedTransaction(dbTx, userID, "degiro", row); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err := model.GetSkippedTransactionsByUserID(database.DB, userID)
+	if err != nil || len(skipped) != 1 {
+		t.Fatalf("got %d skipped rows (error %v), want 1", len(skipped), err)
+	}
+
+	transactions := model.NewTransactionRepository(database.DB)
+	uploadService := services.NewUploadService(transactions, processors.NewTransactionProcessor(1), nil, nil, nil, nil, nil, nil,
+		cache.NewMemory(time.Minute, time.Minute), nil, nil, nil, 0, parsers.Limits{}, 0)
+	router := chi.NewRouter()
+	router.Post("/transactions/skipped/{id}/manual", NewTransactionHandler(transactions, uploadService, nil).HandleImportSkippedTransactionManually)
+	post := func(id int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/skipped/"+strconv.FormatInt(id, 10)+"/manual", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userIDContextKey, userID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(skipped[0].ID, `{"date":"05-03-2024","transaction_type":"STOCK","buy_sell":"HOLD","isin":"US0378331005","quantity":2,"price":150}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid entry answered %d, want 400", rec.Code)
+	}
+	rec := post(skipped[0].ID, `{"date":"05-03-2024","transaction_type":"STOCK","buy_sell":"BUY","isin":"US0378331005","product_name":"APPLE INC","quantity":2,"price":150,"commission":1,"currency":"EUR"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("manual entry answered %d: %s", rec.Code, rec.Body)
+	}
+
+	stored, err := transactions.ListByUser(context.Background(), userID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("stored %d transactions (error %v), want 1", len(stored), err)
+	}
+	if got := stored[0]; got.Source != "degiro" || got.BuySell != "BUY" || got.Quantity != 2 || got.Amount != models.NewMoney(-300) || got.InputString != row.RawText {
+		t.Errorf("stored %+v", got)
+	}
+	if left, _ := model.GetSkippedTransactionsByUserID(database.DB, userID); len(left) != 0 {
+		t.Errorf("%d rows left in quarantine, want none", len(left))
+	}
+	if rec := post(skipped[0].ID, `{"date":"05-03-2024","transaction_type":"DIVIDEND","isin":"US0378331005","amount":5}`); rec.Code != http.StatusNotFound {
+		t.Errorf("entering a row no longer quarantined answered %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,46 @@
+// backend/src/handlers/unrealized_gains_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// UnrealizedGainsHandler serves the unrealized gains report.
+type UnrealizedGainsHandler struct {
+	unrealizedGainsService services.UnrealizedGainsService
+}
+
+// NewUnrealizedGainsHandler creates a new instance of UnrealizedGainsHandler.
+func NewUnrealizedGainsHandler(unrealizedGainsService services.UnrealizedGainsService) *UnrealizedGainsHandler {
+	return &UnrealizedGainsHandler{
+		unrealizedGainsService: unrealizedGainsService,
+	}
+}
+
+// HandleGetUnrealizedGains returns the unrealized P/L of the open positions, per lot and per ISIN.
+func (h *UnrealizedGainsHandler) HandleGetUnrealizedGains(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	logger.FromContext(r.Context()).Info("Handling GetUnrealizedGains request", "userID", userID)
+
+	report, err := h.unrealizedGainsService.GetUnrealizedGains(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing unrealized gains", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error computing unrealized gains: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding unrealized gains to JSON", "userID", userID, "error", err)
+	}
+}
@@ -2,202 +2,494 @@
 package handlers
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strings"
 
 	"github.com/username/taxfolio/backend/src/config"
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/parsers/generic"
 	"github.com/username/taxfolio/backend/src/security/validation"
 	"github.com/username/taxfolio/backend/src/services"
 	"github.com/username/taxfolio/backend/src/utils"
 )
 
 type UploadHandler struct {
-	uploadService services.UploadService
+	uploadService  services.UploadService
+	billingService services.BillingService
+	scanner        validation.FileScanner
+	scanFailOpen   bool // Accept files the scanner could not scan
 }
 
-func NewUploadHandler(service services.UploadService) *UploadHandler {
+// NewUploadHandler creates an UploadHandler. Uploaded files, and each file of an archive, go through
+// scanner before they are parsed; with scanFailOpen, files are accepted when it is unavailable.
+func NewUploadHandler(service services.UploadService, billingService services.BillingService, scanner validation.FileScanner, scanFailOpen bool) *UploadHandler {
 	return &UploadHandler{
-		uploadService: service,
+		uploadService:  service,
+		billingService: billingService,
+		scanner:        scanner,
+		scanFailOpen:   scanFailOpen,
 	}
 }
 
-func (h *UploadHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
-	userID, ok := GetUserIDFromContext(r.Context())
-	if !ok {
-		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	// --- ENFORCE UPLOAD LIMIT ---
-	user, err := model.GetUserByID(database.DB, userID)
-	if err != nil {
-		logger.L.Error("Failed to get user for upload limit check", "userID", userID, "error", err)
-		utils.SendJSONError(w, "Failed to verify user permissions", http.StatusInternalServerError)
-		return
-	}
-
-	const uploadLimit = 10 // Define your limit
-	if user.UploadCount >= uploadLimit {
-		logger.L.Warn("User has reached upload limit", "userID", userID, "uploadCount", user.UploadCount)
-		utils.SendJSONError(w, "Atingiste o número máximo de carregamentos de ficheiros. Por favor, elimine os dados existentes para carregar novos ficheiros.", http.StatusForbidden)
-		return
-	}
+// uploadRequest is an uploaded file that passed validation and scanning: a single statement, or the
+// statements of a ZIP archive.
+type uploadRequest struct {
+	source   string
+	filename string
+	file     multipart.File
+	files    []services.UploadFile // The statements of an archive; nil for a single file
+}
 
+// readUpload reads the file of an upload request and the source it comes from, validating and scanning
+// it, and unpacking it when it is an archive. It writes the error response and returns false when the
+// upload cannot proceed; otherwise the caller closes the file.
+func (h *UploadHandler) readUpload(w http.ResponseWriter, r *http.Request, userID int64) (*uploadRequest, bool) {
 	if err := r.ParseMultipartForm(config.Cfg.MaxUploadSizeBytes); err != nil {
-		logger.L.Warn("Failed to parse multipart form or request too large", "userID", userID, "error", err, "limit", config.Cfg.MaxUploadSizeBytes)
+		logger.FromContext(r.Context()).Warn("Failed to parse multipart form or request too large", "userID", userID, "error", err, "limit", config.Cfg.MaxUploadSizeBytes)
 		utils.SendJSONError(w, fmt.Sprintf("Falha ao processar ou o ficheiro é demasiado grande (max %d MB)", config.Cfg.MaxUploadSizeBytes/(1024*1024)), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	source := r.FormValue("source")
 	if source == "" {
-		logger.L.Warn("Upload request missing 'source' field", "userID", userID)
+		// Uploads naming no source go to the user's default account, when one is set.
+		defaultAccount, err := model.GetUserDefaultAccount(database.DB, userID)
+		if err != nil {
+			logger.FromContext(r.Context()).Warn("Could not load default account", "userID", userID, "error", err)
+		}
+		source = defaultAccount
+	}
+	if source == "" {
+		logger.FromContext(r.Context()).Warn("Upload request missing 'source' field", "userID", userID)
 		utils.SendJSONError(w, "Broker source is required.", http.StatusBadRequest)
-		return
+		return nil, false
+	}
+	logger.FromContext(r.Context()).Info("Received upload for source", "source", source, "userID", userID)
+
+	// The IBKR parser is a premium feature.
+	if source == "ibkr" && !checkPremiumAccess(w, r, h.billingService, userID) {
+		return nil, false
+	}
+
+	if source == generic.Source && !h.prepareCSVMapping(w, r, userID) {
+		return nil, false
 	}
-	logger.L.Info("Received upload for source", "source", source, "userID", userID)
 
 	file, fileHeader, err := r.FormFile("file")
 	if err != nil {
-		logger.L.Warn("Failed to retrieve file from request", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Warn("Failed to retrieve file from request", "userID", userID, "error", err)
 		utils.SendJSONError(w, "Failed to retrieve file from request. Ensure 'file' field is used.", http.StatusBadRequest)
-		return
+		return nil, false
+	}
+	upload := &uploadRequest{source: source, filename: fileHeader.Filename, file: file}
+	if !h.validateUpload(w, r, userID, upload, fileHeader) {
+		file.Close()
+		return nil, false
 	}
-	defer file.Close()
+	return upload, true
+}
 
+// validateUpload checks the size, type and content of an uploaded file, scans it, and unpacks it into
+// upload.files when it is an archive other than a workbook. It writes the error response and returns
+// false when the file is refused.
+func (h *UploadHandler) validateUpload(w http.ResponseWriter, r *http.Request, userID int64, upload *uploadRequest, fileHeader *multipart.FileHeader) bool {
+	file := upload.file
 	if fileHeader.Size > config.Cfg.MaxUploadSizeBytes {
-		logger.L.Warn("Uploaded file header reports size too large", "userID", userID, "fileSize", fileHeader.Size, "limit", config.Cfg.MaxUploadSizeBytes)
+		logger.FromContext(r.Context()).Warn("Uploaded file header reports size too large", "userID", userID, "fileSize", fileHeader.Size, "limit", config.Cfg.MaxUploadSizeBytes)
 		utils.SendJSONError(w, fmt.Sprintf("Ficheiro demasiado grande, max %d MB (header check)", config.Cfg.MaxUploadSizeBytes/(1024*1024)), http.StatusBadRequest)
-		return
+		return false
 	}
 
 	clientContentType := fileHeader.Header.Get("Content-Type")
 	if err := validation.ValidateClientContentType(clientContentType); err != nil {
-		logger.L.Warn("Invalid client-declared file type", "userID", userID, "contentType", clientContentType, "error", err)
+		logger.FromContext(r.Context()).Warn("Invalid client-declared file type", "userID", userID, "contentType", clientContentType, "error", err)
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
-		return
+		return false
 	}
-	logger.L.Debug("Client-declared Content-Type validated", "userID", userID, "contentType", clientContentType)
+	logger.FromContext(r.Context()).Debug("Client-declared Content-Type validated", "userID", userID, "contentType", clientContentType)
 
 	detectedContentType, err := validation.ValidateFileContentByMagicBytes(file)
 	if err != nil {
-		logger.L.Warn("Server-side file content validation failed", "userID", userID, "filename", fileHeader.Filename, "error", err)
+		logger.FromContext(r.Context()).Warn("Server-side file content validation failed", "userID", userID, "filename", fileHeader.Filename, "error", err)
 		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	logger.FromContext(r.Context()).Info("File content validated by magic bytes", "userID", userID, "filename", fileHeader.Filename, "clientType", clientContentType, "detectedType", detectedContentType)
+
+	if h.scanner != nil {
+		data, err := io.ReadAll(file)
+		if err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Failed to read uploaded file for scanning", "userID", userID, "filename", fileHeader.Filename, "error", err)
+			utils.SendJSONError(w, "Failed to read the uploaded file.", http.StatusInternalServerError)
+			return false
+		}
+		if !h.scanFile(w, r, fileHeader.Filename, detectedContentType, data) {
+			return false
+		}
+	}
+
+	// XLSX workbooks are zip archives too; only other archives are unpacked into their statements.
+	if detectedContentType == "application/zip" {
+		archive, err := zip.NewReader(file, fileHeader.Size)
+		if err != nil {
+			logger.FromContext(r.Context()).Warn("Failed to open uploaded zip file", "userID", userID, "filename", fileHeader.Filename, "error", err)
+			utils.SendJSONError(w, "The file is not a valid ZIP archive.", http.StatusBadRequest)
+			return false
+		}
+		if !validation.IsWorkbook(archive) {
+			files, ok := h.readArchive(w, r, userID, archive, fileHeader.Filename, upload.source)
+			if !ok {
+				return false
+			}
+			upload.files = files
+		}
+	}
+	return true
+}
+
+func (h *UploadHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	logger.L.Info("File content validated by magic bytes", "userID", userID, "filename", fileHeader.Filename, "clientType", clientContentType, "detectedType", detectedContentType)
 
-	logger.L.Info("Processing upload request", "userID", userID, "filename", fileHeader.Filename)
+	upload, ok := h.readUpload(w, r, userID)
+	if !ok {
+		return
+	}
+	defer upload.file.Close()
 
-	result, err := h.uploadService.ProcessUpload(file, userID, source)
+	// Clients retrying over an unreliable connection send the same key, so a file is only processed once.
+	idempotencyKey := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+
+	var result *services.UploadResult
+	var err error
+	if upload.files != nil {
+		logger.FromContext(r.Context()).Info("Processing archive upload request", "userID", userID, "filename", upload.filename, "files", len(upload.files))
+		result, err = h.uploadService.ProcessArchiveUpload(upload.files, userID, upload.source, upload.filename, idempotencyKey)
+	} else {
+		logger.FromContext(r.Context()).Info("Processing upload request", "userID", userID, "filename", upload.filename)
+		result, err = h.uploadService.ProcessUpload(upload.file, userID, upload.source, upload.filename, idempotencyKey)
+	}
 	if err != nil {
-		if errors.Is(err, validation.ErrValidationFailed) {
-			logger.L.Warn("Upload processing failed due to data validation errors", "userID", userID, "filename", fileHeader.Filename, "error", err)
-			utils.SendJSONError(w, fmt.Sprintf("File content validation failed: %v", err), http.StatusBadRequest)
-		} else if errors.Is(err, services.ErrParsingFailed) {
-			logger.L.Warn("Upload processing failed due to CSV parsing errors", "userID", userID, "source", source, "filename", fileHeader.Filename, "error", err)
-			utils.SendJSONError(w, fmt.Sprintf("Error parsing %s file: %v", source, err), http.StatusBadRequest)
-		} else if errors.Is(err, services.ErrProcessingFailed) {
-			logger.L.Warn("Upload processing failed during transaction processing", "userID", userID, "filename", fileHeader.Filename, "error", err)
-			utils.SendJSONError(w, fmt.Sprintf("Error processing transactions in file: %v", err), http.StatusBadRequest)
-		} else {
-			logger.L.Error("Internal error processing upload", "userID", userID, "filename", fileHeader.Filename, "error", err)
-			utils.SendJSONError(w, "An internal error occurred while processing the file. Please try again later.", http.StatusInternalServerError)
+		sendUploadError(w, r, err, userID, upload.source, idempotencyKey, upload.filename)
+		return
+	}
+	sendUploadResult(w, r, userID, result)
+}
+
+// HandleUploadPreview parses an upload as HandleUpload would and answers how it compares with the
+// user's stored transactions: the transactions and trades it would add, those already stored, the dates
+// it covers and the ISINs it introduces. Nothing is stored, except a column mapping sent with a generic
+// CSV file, which is saved as for an upload.
+func (h *UploadHandler) HandleUploadPreview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	upload, ok := h.readUpload(w, r, userID)
+	if !ok {
+		return
+	}
+	defer upload.file.Close()
+
+	files := upload.files
+	if files == nil {
+		data, err := io.ReadAll(upload.file)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Failed to read uploaded file for preview", "userID", userID, "filename", upload.filename, "error", err)
+			utils.SendJSONError(w, "Failed to read the uploaded file.", http.StatusInternalServerError)
+			return
+		}
+		files = []services.UploadFile{{Source: upload.source, Data: data}}
+	}
+	logger.FromContext(r.Context()).Info("Previewing upload", "userID", userID, "filename", upload.filename, "files", len(files))
+
+	preview, err := h.uploadService.PreviewUpload(files, userID, upload.source)
+	if err != nil {
+		sendUploadError(w, r, err, userID, upload.source, "", upload.filename)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// readArchive reads the statements of a ZIP archive for an upload. Each file goes through the parser its
+// content is recognized by, or that of the upload's source when none recognizes it. It writes the error
+// response and returns false when the archive is refused.
+func (h *UploadHandler) readArchive(w http.ResponseWriter, r *http.Request, userID int64, archive *zip.Reader, filename, source string) ([]services.UploadFile, bool) {
+	entries, err := validation.ValidateZipArchive(archive)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("ZIP archive validation failed", "userID", userID, "filename", filename, "error", err)
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	files := make([]services.UploadFile, len(entries))
+	premiumChecked := source == "ibkr"
+	for i, entry := range entries {
+		if !h.scanFile(w, r, entry.Name, entry.ContentType, entry.Data) {
+			return nil, false
+		}
+		entrySource := parsers.DetectSource(entry.Data)
+		if entrySource == "" {
+			entrySource = source
 		}
+		// The IBKR parser is a premium feature, whether its statements come alone or in an archive.
+		if entrySource == "ibkr" && !premiumChecked {
+			if !checkPremiumAccess(w, r, h.billingService, userID) {
+				return nil, false
+			}
+			premiumChecked = true
+		}
+		files[i] = services.UploadFile{Name: entry.Name, Source: entrySource, Data: entry.Data}
+		logger.FromContext(r.Context()).Debug("Archive entry source detected", "userID", userID, "entry", entry.Name, "source", entrySource)
+	}
+	return files, true
+}
+
+// scanFile runs the upload scanner over a file about to be parsed, answering the request when the file
+// is rejected, or when it could not be scanned and scanning does not fail open. It reports whether
+// parsing may go on.
+func (h *UploadHandler) scanFile(w http.ResponseWriter, r *http.Request, name, contentType string, data []byte) bool {
+	if h.scanner == nil {
+		return true
+	}
+	err := h.scanner.Scan(r.Context(), name, contentType, data)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, validation.ErrFileRejected):
+		logger.FromContext(r.Context()).Warn("Uploaded file rejected by scan", "filename", name, "error", err)
+		utils.SendError(w, http.StatusBadRequest, "FILE_REJECTED", "The file was rejected by the security scan and was not processed.", nil)
+		return false
+	case h.scanFailOpen:
+		logger.FromContext(r.Context()).Warn("Uploaded file accepted without scan", "filename", name, "error", err)
+		return true
+	default:
+		logger.FromContext(r.Context()).Error("Uploaded file could not be scanned", "filename", name, "error", err)
+		utils.SendError(w, http.StatusServiceUnavailable, "SCAN_UNAVAILABLE", "Uploaded files cannot be scanned right now. Please try again later.", nil)
+		return false
+	}
+}
+
+// sendUploadError answers a failed upload with the status matching the cause.
+func sendUploadError(w http.ResponseWriter, r *http.Request, err error, userID int64, source, idempotencyKey, filename string) {
+	if sendQuotaExceeded(w, r, err) {
 		return
 	}
+	if errors.Is(err, services.ErrInvalidIdempotencyKey) {
+		utils.SendJSONError(w, "Idempotency-Key must be at most 255 printable ASCII characters.", http.StatusBadRequest)
+	} else if errors.Is(err, services.ErrUploadInProgress) {
+		logger.FromContext(r.Context()).Info("Upload retried while the first attempt is still processing", "userID", userID, "idempotencyKey", idempotencyKey)
+		utils.SendError(w, http.StatusConflict, "UPLOAD_IN_PROGRESS", "An upload with this Idempotency-Key is still being processed.", nil)
+	} else if errors.Is(err, services.ErrIdempotencyKeyReused) {
+		logger.FromContext(r.Context()).Warn("Idempotency key reused for a different upload", "userID", userID, "idempotencyKey", idempotencyKey, "source", source)
+		utils.SendError(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "This Idempotency-Key was already used for a different upload.", nil)
+	} else if errors.Is(err, validation.ErrValidationFailed) {
+		logger.FromContext(r.Context()).Warn("Upload processing failed due to data validation errors", "userID", userID, "filename", filename, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("File content validation failed: %v", err), http.StatusBadRequest)
+	} else if errors.Is(err, parsers.ErrParseLimit) {
+		logger.FromContext(r.Context()).Warn("Upload abandoned at a parse limit", "userID", userID, "source", source, "filename", filename, "error", err)
+		utils.SendError(w, http.StatusRequestEntityTooLarge, "UPLOAD_LIMIT_EXCEEDED", strings.TrimPrefix(err.Error(), services.ErrParsingFailed.Error()+": "), nil)
+	} else if errors.Is(err, services.ErrParsingFailed) {
+		logger.FromContext(r.Context()).Warn("Upload processing failed due to CSV parsing errors", "userID", userID, "source", source, "filename", filename, "error", err)
+		// The issues list the rows and columns at fault, so the user can fix the file rather than guess.
+		issues := []parsers.ValidationIssue{{Reason: strings.TrimPrefix(err.Error(), services.ErrParsingFailed.Error()+": ")}}
+		var validationErr *parsers.ValidationError
+		if errors.As(err, &validationErr) {
+			issues = validationErr.Issues
+		}
+		utils.SendError(w, http.StatusBadRequest, "PARSE_FAILED", fmt.Sprintf("Error parsing %s file: %v", source, err), issues)
+	} else if errors.Is(err, services.ErrProcessingFailed) {
+		logger.FromContext(r.Context()).Warn("Upload processing failed during transaction processing", "userID", userID, "filename", filename, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error processing transactions in file: %v", err), http.StatusBadRequest)
+	} else {
+		logger.FromContext(r.Context()).Error("Internal error processing upload", "userID", userID, "filename", filename, "error", err)
+		utils.SendJSONError(w, "An internal error occurred while processing the file. Please try again later.", http.StatusInternalServerError)
+	}
+}
 
-	// --- INCREMENT UPLOAD COUNT ON SUCCESS ---
-	_, errUpdate := database.DB.Exec("UPDATE users SET upload_count = upload_count + 1 WHERE id = ?", userID)
-	if errUpdate != nil {
-		// This is not a critical error for the user, as the upload succeeded.
-		// We just log it and continue.
-		logger.L.Error("Failed to increment user upload count after successful upload", "userID", userID, "error", errUpdate)
+// sendUploadResult answers a successful upload, counting it unless it was replayed for an idempotency key.
+func sendUploadResult(w http.ResponseWriter, r *http.Request, userID int64, result *services.UploadResult) {
+	if result.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	} else {
+		// --- INCREMENT UPLOAD COUNT ON SUCCESS ---
+		_, errUpdate := database.DB.Exec("UPDATE users SET upload_count = upload_count + 1 WHERE id = ?", userID)
+		if errUpdate != nil {
+			// This is not a critical error for the user, as the upload succeeded.
+			// We just log it and continue.
+			logger.FromContext(r.Context()).Error("Failed to increment user upload count after successful upload", "userID", userID, "error", errUpdate)
+		}
+		// --- END OF INCREMENT ---
 	}
-	// --- END OF INCREMENT ---
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		logger.L.Error("Error encoding JSON response for upload result", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error encoding JSON response for upload result", "userID", userID, "error", err)
+	}
+}
+
+// prepareCSVMapping saves the mapping sent with a generic CSV upload, or checks that one was saved before.
+// It writes the error response and returns false when the upload cannot proceed.
+func (h *UploadHandler) prepareCSVMapping(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	rawMapping := r.FormValue("mapping")
+	if rawMapping == "" {
+		if _, err := h.uploadService.GetCSVMapping(userID); err != nil {
+			if errors.Is(err, model.ErrCSVMappingNotFound) {
+				utils.SendJSONError(w, "A column mapping is required for generic CSV uploads.", http.StatusBadRequest)
+			} else {
+				logger.FromContext(r.Context()).Error("Failed to load CSV mapping", "userID", userID, "error", err)
+				utils.SendJSONError(w, "Failed to load CSV mapping", http.StatusInternalServerError)
+			}
+			return false
+		}
+		return true
+	}
+
+	var mapping models.CSVMapping
+	if err := json.Unmarshal([]byte(rawMapping), &mapping); err != nil {
+		utils.SendJSONError(w, "Invalid column mapping JSON", http.StatusBadRequest)
+		return false
+	}
+	return h.saveCSVMapping(w, r, userID, mapping)
+}
+
+func (h *UploadHandler) saveCSVMapping(w http.ResponseWriter, r *http.Request, userID int64, mapping models.CSVMapping) bool {
+	if err := h.uploadService.SaveCSVMapping(userID, mapping); err != nil {
+		if errors.Is(err, services.ErrInvalidCSVMapping) {
+			utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		} else {
+			logger.FromContext(r.Context()).Error("Failed to save CSV mapping", "userID", userID, "error", err)
+			utils.SendJSONError(w, "Failed to save CSV mapping", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// HandleGetUploadHistory lists the user's past uploads, newest first, with the rows each one imported,
+// found already stored or skipped.
+func (h *UploadHandler) HandleGetUploadHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	history, err := h.uploadService.GetUploadHistory(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving upload history", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving upload history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding upload history to JSON", "userID", userID, "error", err)
 	}
 }
 
+// HandleGetCSVMapping returns the column mapping saved for generic CSV uploads.
+func (h *UploadHandler) HandleGetCSVMapping(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	mapping, err := h.uploadService.GetCSVMapping(userID)
+	if errors.Is(err, model.ErrCSVMappingNotFound) {
+		utils.SendJSONError(w, "No CSV mapping saved", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving CSV mapping", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving CSV mapping", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// HandleSaveCSVMapping stores the column mapping used for generic CSV uploads without uploading a file.
+func (h *UploadHandler) HandleSaveCSVMapping(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var mapping models.CSVMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !h.saveCSVMapping(w, r, userID, mapping) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapping)
+}
+
 func (h *UploadHandler) HandleGetRealizedGainsData(w http.ResponseWriter, r *http.Request) {
 	userID, ok := GetUserIDFromContext(r.Context())
 	if !ok {
 		utils.SendJSONError(w, "authentication required or user ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	logger.L.Debug("Handling GetRealizedGainsData request with ETag support", "userID", userID)
+	logger.FromContext(r.Context()).Debug("Handling GetRealizedGainsData request", "userID", userID)
 
-	realizedgainsData, err := h.uploadService.GetLatestUploadResult(userID)
+	realizedgainsData, err := h.uploadService.GetLatestUploadResult(r.Context(), userID)
 	if err != nil {
-		logger.L.Error("Error retrieving realizedgains data from service", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error retrieving realizedgains data from service", "userID", userID, "error", err)
 		utils.SendJSONError(w, fmt.Sprintf("Error retrieving realizedgains data for userID %d: %v", userID, err), http.StatusInternalServerError)
 		return
 	}
 
 	if realizedgainsData.DividendTransactionsList != nil {
-		logger.L.Info("Data prepared for response in handler", "userID", userID, "dividendListCount", len(realizedgainsData.DividendTransactionsList))
+		logger.FromContext(r.Context()).Info("Data prepared for response in handler", "userID", userID, "dividendListCount", len(realizedgainsData.DividendTransactionsList))
 	} else {
-		logger.L.Info("Data prepared for response in handler", "userID", userID, "dividendListCount", "nil")
+		logger.FromContext(r.Context()).Info("Data prepared for response in handler", "userID", userID, "dividendListCount", "nil")
 	}
 
-	if realizedgainsData.StockSaleDetails == nil {
-		realizedgainsData.StockSaleDetails = []models.SaleDetail{}
-	}
+	locale := i18n.FromContext(r.Context())
+	realizedgainsData.StockSaleDetails = localizeSaleDetails(locale, realizedgainsData.StockSaleDetails)
 	if realizedgainsData.StockHoldings == nil {
 		realizedgainsData.StockHoldings = make(map[string][]models.PurchaseLot)
 	}
-	if realizedgainsData.OptionSaleDetails == nil {
-		realizedgainsData.OptionSaleDetails = []models.OptionSaleDetail{}
-	}
+	realizedgainsData.OptionSaleDetails = localizeOptionSaleDetails(locale, realizedgainsData.OptionSaleDetails)
 	if realizedgainsData.OptionHoldings == nil {
 		realizedgainsData.OptionHoldings = []models.OptionHolding{}
 	}
 	if realizedgainsData.CashMovements == nil {
 		realizedgainsData.CashMovements = []models.CashMovement{}
 	}
-	if realizedgainsData.DividendTransactionsList == nil {
-		realizedgainsData.DividendTransactionsList = []models.ProcessedTransaction{}
-	}
-
-	currentETag, etagErr := utils.GenerateETag(realizedgainsData)
-	if etagErr != nil {
-		logger.L.Error("Failed to generate ETag for realizedgains data", "userID", userID, "error", etagErr)
-	}
-
-	w.Header().Set("Cache-Control", "no-cache, private")
-
-	if etagErr == nil && currentETag != "" {
-		quotedETag := fmt.Sprintf("\"%s\"", currentETag)
-		w.Header().Set("ETag", quotedETag)
-		clientETag := r.Header.Get("If-None-Match")
-		clientETags := strings.Split(clientETag, ",")
-		for _, cETag := range clientETags {
-			if strings.TrimSpace(cETag) == quotedETag {
-				logger.L.Info("ETag match for realizedgains data", "userID", userID, "etag", currentETag)
-				w.WriteHeader(http.StatusNotModified)
-				return
-			}
-		}
-		if clientETag != "" {
-			logger.L.Debug("ETag mismatch", "userID", userID, "clientETags", clientETag, "serverETag", quotedETag)
-		}
-	} else {
-		logger.L.Warn("Proceeding without ETag check due to ETag generation error or empty ETag", "userID", userID)
-	}
+	realizedgainsData.DividendTransactionsList = localizeTransactions(locale, realizedgainsData.DividendTransactionsList)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(realizedgainsData); err != nil {
-		logger.L.Error("Error generating JSON response for realizedgains data", "userID", userID, "error", err)
+		logger.FromContext(r.Context()).Error("Error generating JSON response for realizedgains data", "userID", userID, "error", err)
 	}
 }
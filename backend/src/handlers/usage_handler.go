@@ -0,0 +1,66 @@
+// backend/src/handlers/usage_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// UsageHandler reports the user's consumption against their plan limits.
+type UsageHandler struct {
+	quotaService services.QuotaService
+}
+
+// NewUsageHandler creates a new instance of UsageHandler.
+func NewUsageHandler(quotaService services.QuotaService) *UsageHandler {
+	return &UsageHandler{
+		quotaService: quotaService,
+	}
+}
+
+// HandleGetUsage returns the user's plan, uploads this month and stored transactions.
+func (h *UsageHandler) HandleGetUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error loading usage", "userID", userID, "error", err)
+		utils.SendJSONError(w, fmt.Sprintf("Error loading usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding usage to JSON", "userID", userID, "error", err)
+	}
+}
+
+// sendQuotaExceeded answers with 403 and code QUOTA_EXCEEDED when err is a plan limit error,
+// and reports whether it did.
+func sendQuotaExceeded(w http.ResponseWriter, r *http.Request, err error) bool {
+	var quotaErr *services.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+	key := i18n.MsgQuotaUploads
+	if quotaErr.Limit == services.QuotaLimitTransactions {
+		key = i18n.MsgQuotaTransactions
+	}
+	logger.FromContext(r.Context()).Warn("Plan quota exceeded", "plan", quotaErr.Plan, "limit", quotaErr.Limit, "max", quotaErr.Max)
+
+	utils.SendError(w, http.StatusForbidden, "QUOTA_EXCEEDED",
+		i18n.T(i18n.FromContext(r.Context()), key, quotaErr.Max, quotaErr.Plan),
+		map[string]interface{}{"limit": quotaErr.Limit, "max": quotaErr.Max})
+	return true
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/security"
+	"github.com/username/taxfolio/backend/src/security/password"
 	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
 	"golang.org/x/oauth2"
 )
 
@@ -20,33 +22,44 @@ type contextKey string
 const userIDContextKey contextKey = "userID"
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-var passwordRegex = regexp.MustCompile(`^.{6,}$`) // Basic: at least 6 characters
 
 var (
 	googleOauthConfig *oauth2.Config
-	oauthStateString  = "random-string-for-security"
 )
 
 // UserHandler now acts as a receiver for methods defined across
 // multiple files in this package (auth_handler.go, oauth_handler.go, etc.).
 type UserHandler struct {
-	authService  *security.AuthService
-	emailService services.EmailService
+	authService    *security.AuthService
+	emailService   services.EmailService
+	transactions   model.TransactionRepository
+	passwordPolicy password.Policy
+	keyring        *security.Keyring // Encrypts the stored refresh tokens
 }
 
-func NewUserHandler(authService *security.AuthService, emailService services.EmailService) *UserHandler {
+func NewUserHandler(authService *security.AuthService, emailService services.EmailService, transactions model.TransactionRepository, passwordPolicy password.Policy, keyring *security.Keyring) *UserHandler {
 	return &UserHandler{
-		authService:  authService,
-		emailService: emailService,
+		authService:    authService,
+		emailService:   emailService,
+		transactions:   transactions,
+		passwordPolicy: passwordPolicy,
+		keyring:        keyring,
 	}
 }
 
 // sendJSONError is a helper used by multiple handlers in this package.
 func sendJSONError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	logger.L.Warn("Sending JSON error to client", "message", message, "statusCode", statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	utils.SendJSONError(w, message, statusCode)
+}
+
+// checkPassword applies the password policy, answering 400 with the reason when the password is refused.
+// userInputs are the account's username and email, which the password should not be built from.
+func (h *UserHandler) checkPassword(w http.ResponseWriter, r *http.Request, newPassword string, userInputs ...string) bool {
+	if err := h.passwordPolicy.Check(r.Context(), newPassword, userInputs...); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
 // VerifyEmailHandler remains here as a general, non-grouped user action.
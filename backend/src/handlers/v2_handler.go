@@ -0,0 +1,245 @@
+// backend/src/handlers/v2_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/username/taxfolio/backend/src/apiv2"
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// V2Handler serves the reports of version 2 of the API, typed as the apiv2 package documents them:
+// ISO 8601 dates, amounts as decimal strings and enumerated transaction types. It reads the same
+// services as the version 1 handlers.
+type V2Handler struct {
+	transactions  model.TransactionRepository
+	uploadService services.UploadService
+	tagService    services.TransactionTagService
+}
+
+// NewV2Handler creates a new instance of V2Handler.
+func NewV2Handler(transactions model.TransactionRepository, uploadService services.UploadService, tagService services.TransactionTagService) *V2Handler {
+	return &V2Handler{
+		transactions:  transactions,
+		uploadService: uploadService,
+		tagService:    tagService,
+	}
+}
+
+// writeV2JSON writes a version 2 response body.
+func writeV2JSON(w http.ResponseWriter, r *http.Request, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding v2 response", "path", r.URL.Path, "error", err)
+	}
+}
+
+// HandleGetTransactions lists the user's transactions, newest first, optionally only those with the
+// tags of the tag parameter.
+func (h *V2Handler) HandleGetTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+	annotations, err := h.tagService.GetAnnotations(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error querying transaction tags", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving transactions", http.StatusInternalServerError)
+		return
+	}
+	transactions, err := h.transactions.ListByUser(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error querying transactions", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving transactions", http.StatusInternalServerError)
+		return
+	}
+
+	var matched []models.ProcessedTransaction
+	for _, tx := range slices.Backward(transactions) {
+		if !tagFilter.MatchTransaction(tx) {
+			continue
+		}
+		if a, ok := annotations[tx.ID]; ok {
+			tx.Note, tx.Tags = a.Note, a.Tags
+		}
+		matched = append(matched, tx)
+	}
+	response := []apiv2.Transaction{}
+	for _, tx := range localizeTransactions(i18n.FromContext(r.Context()), matched) {
+		response = append(response, apiv2.TransactionOf(tx))
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetDividends lists the user's dividends and the tax withheld on them.
+func (h *V2Handler) HandleGetDividends(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+	dividends, err := h.uploadService.GetDividendTransactions(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving dividend transactions", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving dividends", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.Transaction{}
+	for _, tx := range localizeTransactions(i18n.FromContext(r.Context()), dividends) {
+		if tagFilter.MatchTransaction(tx) {
+			response = append(response, apiv2.TransactionOf(tx))
+		}
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetStockSales lists the user's sales of shares, each matched to the lot it sold from.
+func (h *V2Handler) HandleGetStockSales(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	tagFilter, err := h.tagService.NewTagFilter(userID, tagsFromQuery(r))
+	if err != nil {
+		sendTagError(w, r, err)
+		return
+	}
+	sales, err := h.uploadService.GetStockSaleDetails(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving stock sales", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving stock sales", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.StockSale{}
+	for _, sale := range localizeSaleDetails(i18n.FromContext(r.Context()), sales) {
+		if tagFilter.MatchSale(sale) {
+			response = append(response, apiv2.StockSaleOf(sale))
+		}
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetStockHoldings lists the lots held at the end of each year, oldest year first, or of the
+// year of the year parameter alone.
+func (h *V2Handler) HandleGetStockHoldings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	year := r.URL.Query().Get("year")
+	if year != "" && !yearParamRegex.MatchString(year) {
+		utils.SendJSONError(w, "Invalid year. Use the format YYYY.", http.StatusBadRequest)
+		return
+	}
+
+	var holdingsByYear map[string][]models.PurchaseLot
+	var err error
+	if year != "" {
+		var lots []models.PurchaseLot
+		lots, err = h.uploadService.GetStockHoldingsForYear(r.Context(), userID, year)
+		holdingsByYear = map[string][]models.PurchaseLot{year: lots}
+	} else {
+		holdingsByYear, err = h.uploadService.GetStockHoldings(r.Context(), userID)
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving stock holdings", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving stock holdings", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.Holdings{}
+	for _, y := range slices.Sorted(maps.Keys(holdingsByYear)) {
+		holdings := apiv2.Holdings{Year: y, Lots: []apiv2.Lot{}}
+		for _, lot := range holdingsByYear[y] {
+			holdings.Lots = append(holdings.Lots, apiv2.LotOf(lot))
+		}
+		response = append(response, holdings)
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetFees lists the fees, commissions and transaction taxes the user paid.
+func (h *V2Handler) HandleGetFees(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	fees, err := h.uploadService.GetFeeDetails(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving fee details", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving fees", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.Fee{}
+	for _, fee := range fees {
+		response = append(response, apiv2.FeeOf(fee))
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetOptionSales lists the user's closed option positions.
+func (h *V2Handler) HandleGetOptionSales(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	sales, err := h.uploadService.GetOptionSaleDetails(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving option sales", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving option sales", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.OptionSale{}
+	for _, sale := range localizeOptionSaleDetails(i18n.FromContext(r.Context()), sales) {
+		response = append(response, apiv2.OptionSaleOf(sale))
+	}
+	writeV2JSON(w, r, response)
+}
+
+// HandleGetOptionHoldings lists the user's open option positions.
+func (h *V2Handler) HandleGetOptionHoldings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	holdings, err := h.uploadService.GetOptionHoldings(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error retrieving option holdings", "userID", userID, "error", err)
+		utils.SendJSONError(w, "Error retrieving option holdings", http.StatusInternalServerError)
+		return
+	}
+
+	response := []apiv2.OptionPosition{}
+	for _, holding := range holdings {
+		response = append(response, apiv2.OptionPositionOf(holding))
+	}
+	writeV2JSON(w, r, response)
+}
@@ -0,0 +1,45 @@
+// backend/src/handlers/versioning.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIVersionHeader names the version of the API a response belongs to.
+const APIVersionHeader = "API-Version"
+
+// The versions of the API. Version 1 is served under /api, as it was before there were versions, and
+// is kept for the clients built against it; later versions are served under /api/v<version>.
+const (
+	APIVersion1 = "1"
+	APIVersion2 = "2"
+)
+
+// APIVersionPrefix returns the path the routes of an API version are mounted at.
+func APIVersionPrefix(version string) string {
+	if version == APIVersion1 {
+		return "/api"
+	}
+	return "/api/v" + version
+}
+
+// MountAPIVersion mounts the routes of an API version at its prefix, marking their responses with
+// the API-Version header. Routes of a later version take precedence over the version 1 routes whose
+// path they share a prefix with.
+func MountAPIVersion(r chi.Router, version string, routes func(r chi.Router)) {
+	r.Route(APIVersionPrefix(version), func(r chi.Router) {
+		r.Use(apiVersionMiddleware(version))
+		routes(r)
+	})
+}
+
+func apiVersionMiddleware(version string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
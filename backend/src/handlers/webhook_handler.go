@@ -0,0 +1,182 @@
+// backend/src/handlers/webhook_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/services"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+// WebhookHandler manages the user's webhooks.
+type WebhookHandler struct {
+	webhookService services.WebhookService
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler.
+func NewWebhookHandler(webhookService services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// WebhookRequest is the body of a request creating or changing a webhook.
+type WebhookRequest struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"` // Defaults to true
+}
+
+func (req WebhookRequest) webhook() models.Webhook {
+	webhook := models.Webhook{URL: req.URL, Events: req.Events, Enabled: true}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	return webhook
+}
+
+// sendWebhookError maps webhook service errors to HTTP responses.
+func sendWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrWebhookNotFound):
+		utils.SendJSONError(w, "Webhook not found", http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context()).Error("Error handling webhooks", "error", err)
+		utils.SendJSONError(w, "Error handling webhooks", http.StatusInternalServerError)
+	}
+}
+
+// HandleGetWebhooks lists the user's webhooks, without their secrets.
+func (h *WebhookHandler) HandleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	webhooks, err := h.webhookService.GetWebhooks(userID)
+	if err != nil {
+		sendWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// HandleCreateWebhook registers a webhook. The response carries its signing secret, which is not
+// shown again.
+func (h *WebhookHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.webhookService.CreateWebhook(userID, req.webhook())
+	if err != nil {
+		sendWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// HandleUpdateWebhook changes the URL, events or state of a webhook, keeping its secret.
+func (h *WebhookHandler) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.webhookService.UpdateWebhook(userID, id, req.webhook())
+	if err != nil {
+		sendWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// HandleDeleteWebhook deletes a webhook with its deliveries.
+func (h *WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(userID, id); err != nil {
+		sendWebhookError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetWebhookDeliveries lists the latest deliveries of a webhook, newest first, up to the
+// optional limit parameter.
+func (h *WebhookHandler) HandleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.SendJSONError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.SendJSONError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveriesLimit {
+			utils.SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxDeliveriesLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(userID, id, limit)
+	if err != nil {
+		sendWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
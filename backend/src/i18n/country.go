@@ -0,0 +1,43 @@
+// backend/src/i18n/country.go
+package i18n
+
+import (
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// CountryLabel renders a country label stored by utils.GetCountryCodeString ("840 - United States of America")
+// in the locale. The numeric code is kept as is, since it is the one asked for in the tax forms.
+func CountryLabel(locale, label string) string {
+	numeric, name, found := strings.Cut(label, " - ")
+	if !found {
+		return countryFallbackLabel(locale, label)
+	}
+	country, ok := utils.GetCountryByNumeric(numeric)
+	if !ok {
+		return label
+	}
+	name = strings.TrimSpace(country.Country)
+	if locale == PtPT && country.CountryPT != "" {
+		name = country.CountryPT
+	}
+	return numeric + " - " + name
+}
+
+// countryFallbackLabel translates the labels used when no country could be derived from the ISIN.
+func countryFallbackLabel(locale, label string) string {
+	english := messages[EnUS]
+	switch label {
+	case english[MsgCountryNotInitialized]:
+		return T(locale, MsgCountryNotInitialized)
+	case english[MsgCountryLoadError]:
+		return T(locale, MsgCountryLoadError)
+	case english[MsgCountryInvalidISIN]:
+		return T(locale, MsgCountryInvalidISIN)
+	}
+	if code, ok := strings.CutPrefix(label, "Unknown Code: "); ok {
+		return T(locale, MsgCountryUnknownCode, code)
+	}
+	return label
+}
@@ -0,0 +1,71 @@
+// backend/src/i18n/i18n.go
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Locales API-generated text can be rendered in.
+const (
+	PtPT = "pt-PT"
+	EnUS = "en-US"
+	// Default is used for users who have not chosen a locale and for requests without one.
+	Default = PtPT
+)
+
+type contextKey struct{}
+
+// Normalize maps a locale tag such as "pt", "pt_PT" or "en-GB" to a supported locale.
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "":
+		return "", false
+	case tag == "pt" || strings.HasPrefix(tag, "pt-") || strings.HasPrefix(tag, "pt_"):
+		return PtPT, true
+	case tag == "en" || strings.HasPrefix(tag, "en-") || strings.HasPrefix(tag, "en_"):
+		return EnUS, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage picks the first supported locale of an Accept-Language header, or Default.
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if locale, ok := Normalize(tag); ok {
+			return locale
+		}
+	}
+	return Default
+}
+
+// NewContext returns a copy of ctx carrying the locale of the request.
+func NewContext(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale stored in ctx, or Default.
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return Default
+}
+
+// T returns the message for key in the locale, formatted with args. Messages missing from the
+// locale fall back to Default, and unknown keys are returned as is.
+func T(locale, key string, args ...any) string {
+	format, ok := messages[locale][key]
+	if !ok {
+		if format, ok = messages[Default][key]; !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
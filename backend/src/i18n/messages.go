@@ -0,0 +1,107 @@
+// backend/src/i18n/messages.go
+package i18n
+
+// Message keys.
+const (
+	MsgActionUnparsedRows           = "data_quality.action.unparsed_rows"
+	MsgActionUndatedSkippedRows     = "data_quality.action.undated_skipped_rows"
+	MsgActionMissingFXRates         = "data_quality.action.missing_fx_rates"
+	MsgActionUnresolvedISINs        = "data_quality.action.unresolved_isins"
+	MsgActionUnmatchedSells         = "data_quality.action.unmatched_sells"
+	MsgActionReconciliationGap      = "data_quality.action.reconciliation_gap"
+	MsgActionNegativeHoldings       = "data_quality.action.negative_holdings"
+	MsgActionEmptyLots              = "data_quality.action.empty_lots"
+	MsgActionInconsistentQuantities = "data_quality.action.inconsistent_quantities"
+
+	MsgCountryNotInitialized = "country.not_initialized"
+	MsgCountryLoadError      = "country.load_error"
+	MsgCountryInvalidISIN    = "country.invalid_isin"
+	MsgCountryUnknownCode    = "country.unknown_code"
+
+	MsgAuthAccountLocked = "auth.account_locked"
+
+	MsgQuotaUploads      = "quota.uploads_per_month"
+	MsgQuotaTransactions = "quota.max_transactions"
+	MsgPremiumRequired   = "billing.premium_required"
+
+	MsgAlertBelowCostBasis        = "alert.below_cost_basis"
+	MsgAlertMonthlyDividendsAbove = "alert.monthly_dividends_above"
+	MsgAlertUnmatchedSell         = "alert.unmatched_sell"
+
+	MsgCalendarName           = "calendar.name"
+	MsgCalendarDividend       = "calendar.dividend"
+	MsgCalendarDividendDetail = "calendar.dividend_detail"
+	MsgCalendarIRSFilingOpens = "calendar.irs_filing_opens"
+	MsgCalendarIRSFilingEnds  = "calendar.irs_filing_ends"
+	MsgCalendarIRSPayment     = "calendar.irs_payment"
+)
+
+// messages holds the catalog of every locale, as fmt format strings.
+var messages = map[string]map[string]string{
+	PtPT: {
+		MsgActionUnparsedRows:           "%d linha(s) dos seus ficheiros não puderam ser lidas. Reveja-as nas transações ignoradas e volte a processá-las quando forem suportadas.",
+		MsgActionUndatedSkippedRows:     "%d linha(s) ignoradas não têm uma data que indique o ano a que pertencem. Reveja-as nas transações ignoradas.",
+		MsgActionMissingFXRates:         "%d transação(ões) em moeda estrangeira usaram uma taxa de câmbio por omissão de 1,0. Volte a carregar o ficheiro quando as taxas do BCE estiverem disponíveis para essas datas.",
+		MsgActionUnresolvedISINs:        "%d transação(ões) têm o ISIN em falta ou inválido, pelo que não é possível determinar o país. Verifique o produto no ficheiro da corretora.",
+		MsgActionUnmatchedSells:         "%d venda(s) não têm compra correspondente. Carregue os extratos de anos anteriores que contêm as compras originais.",
+		MsgActionReconciliationGap:      "%.2f %s de vendas em %s não estão associados a uma compra, pelo que faltam as mais-valias desse montante.",
+		MsgActionNegativeHoldings:       "%d produto(s) foram vendidos em maior quantidade do que comprados até ao fim de %s. Carregue os extratos em falta com as compras desses produtos.",
+		MsgActionEmptyLots:              "%d lote(s) em carteira não têm ações ou custo de aquisição. Verifique as compras desses produtos no ficheiro da corretora.",
+		MsgActionInconsistentQuantities: "%d lote(s) ou transação(ões) têm quantidades inconsistentes. Volte a carregar o ficheiro da corretora que as contém.",
+
+		MsgCountryNotInitialized: "Dados de países não inicializados",
+		MsgCountryLoadError:      "Erro ao carregar os dados de países",
+		MsgCountryInvalidISIN:    "ISIN inválido (demasiado curto)",
+		MsgCountryUnknownCode:    "Código desconhecido: %s",
+
+		MsgAuthAccountLocked: "Demasiadas tentativas de início de sessão falhadas. A conta está bloqueada até %s (UTC). Enviámos para o seu e-mail um link para a desbloquear.",
+
+		MsgQuotaUploads:      "Atingiu o limite de %d carregamentos de ficheiros por mês do plano %s.",
+		MsgQuotaTransactions: "Este carregamento ultrapassa o limite de %d transações guardadas do plano %s. Elimine dados antigos ou mude de plano.",
+		MsgPremiumRequired:   "Esta funcionalidade está disponível apenas nos planos pagos.",
+
+		MsgAlertBelowCostBasis:        "%s (%s) está %.1f%% abaixo do custo de aquisição: vale %.2f %s para um custo de %.2f %s.",
+		MsgAlertMonthlyDividendsAbove: "Recebeu %.2f %s em dividendos brutos em %s, acima do limite de %.2f %s.",
+		MsgAlertUnmatchedSell:         "%d venda(s) de %s não têm compra correspondente. Carregue os extratos que contêm as compras originais.",
+
+		MsgCalendarName:           "Rumo Claro: dividendos e prazos fiscais",
+		MsgCalendarDividend:       "Dividendo previsto: %s",
+		MsgCalendarDividendDetail: "%s (%s). Bruto previsto: %.2f %s; líquido previsto: %.2f %s. Com base no pagamento de %s.",
+		MsgCalendarIRSFilingOpens: "Início da entrega do IRS %d",
+		MsgCalendarIRSFilingEnds:  "Fim do prazo de entrega do IRS %d",
+		MsgCalendarIRSPayment:     "Fim do prazo de pagamento do IRS %d",
+	},
+	EnUS: {
+		MsgActionUnparsedRows:           "%d row(s) from your uploads could not be read. Review them under skipped transactions and reprocess them once supported.",
+		MsgActionUndatedSkippedRows:     "%d skipped row(s) have no date telling which year they belong to. Review them under skipped transactions.",
+		MsgActionMissingFXRates:         "%d transaction(s) in a foreign currency used a default exchange rate of 1.0. Re-upload the file once ECB rates are available for those dates.",
+		MsgActionUnresolvedISINs:        "%d transaction(s) have a missing or invalid ISIN, so their country cannot be determined. Check the product in the broker export.",
+		MsgActionUnmatchedSells:         "%d sale(s) have no matching purchase. Upload the statements from earlier years that contain the original purchases.",
+		MsgActionReconciliationGap:      "%.2f %s of sale proceeds in %s are not matched to a purchase lot, so the capital gains for that amount are missing.",
+		MsgActionNegativeHoldings:       "%d product(s) were sold in larger quantities than bought up to the end of %s. Upload the missing statements with the purchases of those products.",
+		MsgActionEmptyLots:              "%d open lot(s) have no shares or no cost basis left. Check the purchases of those products in the broker export.",
+		MsgActionInconsistentQuantities: "%d lot(s) or transaction(s) have inconsistent quantities. Upload again the broker export that contains them.",
+
+		MsgCountryNotInitialized: "Country Data Not Initialized",
+		MsgCountryLoadError:      "Error Loading Country Data",
+		MsgCountryInvalidISIN:    "Invalid ISIN (Too Short)",
+		MsgCountryUnknownCode:    "Unknown Code: %s",
+
+		MsgAuthAccountLocked: "Too many failed login attempts. The account is locked until %s (UTC). We emailed you a link to unlock it.",
+
+		MsgQuotaUploads:      "You reached the limit of %d file uploads per month of the %s plan.",
+		MsgQuotaTransactions: "This would exceed the limit of %d stored transactions of the %s plan. Delete old data or change plans.",
+		MsgPremiumRequired:   "This feature is only available on paid plans.",
+
+		MsgAlertBelowCostBasis:        "%s (%s) is %.1f%% below its cost basis: worth %.2f %s for a cost of %.2f %s.",
+		MsgAlertMonthlyDividendsAbove: "You received %.2f %s in gross dividends in %s, above your limit of %.2f %s.",
+		MsgAlertUnmatchedSell:         "%d sale(s) of %s have no matching purchase. Upload the statements that contain the original purchases.",
+
+		MsgCalendarName:           "Rumo Claro: dividends and tax deadlines",
+		MsgCalendarDividend:       "Expected dividend: %s",
+		MsgCalendarDividendDetail: "%s (%s). Expected gross: %.2f %s; expected net: %.2f %s. Based on the payment of %s.",
+		MsgCalendarIRSFilingOpens: "IRS %d filing opens",
+		MsgCalendarIRSFilingEnds:  "IRS %d filing deadline",
+		MsgCalendarIRSPayment:     "IRS %d payment deadline",
+	},
+}
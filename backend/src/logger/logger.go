@@ -53,15 +53,20 @@ func InitLogger(logLevelStr string) {
 	L.Info("Logger initialized", "level", level.String())
 }
 
-// FromContext retrieves a logger from context, or returns the default global logger.
-// This is a placeholder for more advanced context-aware logging (e.g., with request IDs).
-func FromContext(ctx context.Context) *slog.Logger {
-	// if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
-	//  return logger
-	// }
-	return L // Return global logger if none in context
+// contextKey is an unexported type for context keys defined in this package.
+type contextKey string
+
+const loggerKey = contextKey("logger")
+
+// NewContext returns a copy of ctx carrying the given logger.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
 }
 
-// Add a context key type if you plan to store loggers in context
-// type contextKey string
-// const loggerKey = contextKey("logger")
+// FromContext retrieves the request-scoped logger from context, or returns the global logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return L
+}
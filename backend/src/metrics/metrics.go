@@ -0,0 +1,172 @@
+// backend/src/metrics/metrics.go
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	uploadProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upload_processing_duration_seconds",
+		Help:    "Time taken to process an uploaded file, by broker source and outcome.",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"source", "outcome"})
+
+	cacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Cache lookups by cache name and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	externalAPICallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_api_calls_total",
+		Help: "Calls to external APIs (Yahoo, ECB) by API and outcome.",
+	}, []string{"api", "outcome"})
+
+	transactionsInsertedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transaction_insert_rows_total",
+		Help: "Processed transactions submitted for insertion by uploads, duplicates included.",
+	})
+
+	transactionInsertSeconds = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transaction_insert_seconds_total",
+		Help: "Time spent inserting processed transactions; divide the rows by it for the insert throughput.",
+	})
+
+	maintenanceRowsRemovedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "maintenance_rows_removed_total",
+		Help: "Expired sessions deleted and stale tokens cleared by the maintenance job, by kind.",
+	}, []string{"kind"})
+
+	backupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "database_backups_total",
+		Help: "Database backups taken, by outcome.",
+	}, []string{"outcome"})
+
+	backupLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "database_backup_last_success_timestamp_seconds",
+		Help: "When the last successful database backup finished, to alert on backups that stopped.",
+	})
+
+	backupSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "database_backup_size_bytes",
+		Help: "Size of the last successful database backup.",
+	})
+
+	integrityProblems = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "integrity_problems",
+		Help: "Problems found by the last database integrity check, by check: rows for the structural and orphan checks, users for the count reconciliations.",
+	}, []string{"check"})
+)
+
+// Middleware records the count and latency of every request, labelled by the chi route pattern
+// rather than the raw path so that IDs in URLs do not create unbounded label values.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler exposes the metrics in the Prometheus text format.
+// When token is non-empty, scrapers must send it as a bearer token.
+func Handler(token string) http.Handler {
+	promHandler := promhttp.Handler()
+	if token == "" {
+		return promHandler
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		promHandler.ServeHTTP(w, r)
+	})
+}
+
+// ObserveUpload records how long processing an uploaded file took.
+func ObserveUpload(source string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	uploadProcessingDuration.WithLabelValues(source, outcome).Observe(duration.Seconds())
+}
+
+// CacheLookup records a cache hit or miss.
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequestsTotal.WithLabelValues(cache, result).Inc()
+}
+
+// ExternalCall records a call to an external API. Transport errors, rate limiting and
+// server errors count as failures; other statuses (e.g. 404 for a missing ECB date) do not.
+func ExternalCall(api string, resp *http.Response, err error) {
+	outcome := "success"
+	if err != nil || resp == nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		outcome = "error"
+	}
+	externalAPICallsTotal.WithLabelValues(api, outcome).Inc()
+}
+
+// MaintenanceRowsRemoved records rows deleted or cleared by a maintenance run.
+func MaintenanceRowsRemoved(kind string, rows int64) {
+	maintenanceRowsRemovedTotal.WithLabelValues(kind).Add(float64(rows))
+}
+
+// BackupFinished records the outcome of a database backup, and the size of a successful one.
+func BackupFinished(size int64, err error) {
+	if err != nil {
+		backupsTotal.WithLabelValues("error").Inc()
+		return
+	}
+	backupsTotal.WithLabelValues("success").Inc()
+	backupLastSuccess.SetToCurrentTime()
+	backupSizeBytes.Set(float64(size))
+}
+
+// ObserveInsert records rows inserted, or skipped as duplicates, in duration.
+func ObserveInsert(rows int, duration time.Duration) {
+	transactionsInsertedTotal.Add(float64(rows))
+	transactionInsertSeconds.Add(duration.Seconds())
+}
+
+// IntegrityProblems records the problems an integrity check found.
+func IntegrityProblems(check string, count int) {
+	integrityProblems.WithLabelValues(check).Set(float64(count))
+}
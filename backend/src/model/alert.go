@@ -0,0 +1,115 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrAlertRuleNotFound is returned when the user has no alert rule with the given ID.
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+const alertRuleColumns = `id, user_id, kind, isin, threshold, enabled, triggered_key, last_triggered_at, created_at`
+
+func scanAlertRules(rows *sql.Rows) ([]models.AlertRule, error) {
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		var rule models.AlertRule
+		var lastTriggeredAt sql.NullTime
+		if err := rows.Scan(&rule.ID, &rule.UserID, &rule.Kind, &rule.ISIN, &rule.Threshold, &rule.Enabled,
+			&rule.TriggeredKey, &lastTriggeredAt, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lastTriggeredAt.Valid {
+			rule.LastTriggeredAt = &lastTriggeredAt.Time
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetAlertRules lists the user's alert rules in the order they were created.
+func GetAlertRules(db *sql.DB, userID int64) ([]models.AlertRule, error) {
+	rows, err := db.Query(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAlertRules(rows)
+}
+
+// GetEnabledAlertRules lists every user's enabled alert rules, grouped by user.
+func GetEnabledAlertRules(db *sql.DB) ([]models.AlertRule, error) {
+	rows, err := db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE enabled = TRUE ORDER BY user_id, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAlertRules(rows)
+}
+
+// CountAlertRules returns how many alert rules the user has.
+func CountAlertRules(db *sql.DB, userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM alert_rules WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// CreateAlertRule stores a new alert rule, setting its ID and creation time.
+func CreateAlertRule(db *sql.DB, rule *models.AlertRule) error {
+	rule.CreatedAt = time.Now()
+	return db.QueryRow(`
+		INSERT INTO alert_rules (user_id, kind, isin, threshold, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		rule.UserID, rule.Kind, rule.ISIN, rule.Threshold, rule.Enabled, rule.CreatedAt).Scan(&rule.ID)
+}
+
+// UpdateAlertRule changes the condition of one of the user's alert rules. The rule is re-armed, so
+// a condition that holds is notified again under its new terms.
+func UpdateAlertRule(db *sql.DB, rule *models.AlertRule) error {
+	result, err := db.Exec(`
+		UPDATE alert_rules SET kind = ?, isin = ?, threshold = ?, enabled = ?, triggered_key = ''
+		WHERE id = ? AND user_id = ?`,
+		rule.Kind, rule.ISIN, rule.Threshold, rule.Enabled, rule.ID, rule.UserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// DeleteAlertRule deletes one of the user's alert rules.
+func DeleteAlertRule(db *sql.DB, userID, id int64) error {
+	result, err := db.Exec(`DELETE FROM alert_rules WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// SetAlertRuleTriggered records the occurrence of the condition last notified, or clears it with an
+// empty key. triggeredAt is stored only when it is not nil.
+func SetAlertRuleTriggered(db *sql.DB, id int64, key string, triggeredAt *time.Time) error {
+	if triggeredAt == nil {
+		_, err := db.Exec(`UPDATE alert_rules SET triggered_key = ? WHERE id = ?`, key, id)
+		return err
+	}
+	_, err := db.Exec(`UPDATE alert_rules SET triggered_key = ?, last_triggered_at = ? WHERE id = ?`, key, *triggeredAt, id)
+	return err
+}
+
+// DeleteAlertRules deletes all the user's alert rules and returns how many there were.
+func DeleteAlertRules(tx *sql.Tx, userID int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM alert_rules WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const endAnnouncementsQuery = `UPDATE announcements SET ends_at = ? WHERE ends_at IS NULL OR ends_at > ?`
+
+// GetActiveAnnouncement returns the latest announcement that has not ended by now, or sql.ErrNoRows.
+func GetActiveAnnouncement(db *sql.DB, now time.Time) (*models.Announcement, error) {
+	var a models.Announcement
+	var endsAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, message, level, ends_at, created_at
+		FROM announcements
+		WHERE ends_at IS NULL OR ends_at > ?
+		ORDER BY id DESC LIMIT 1`, now).Scan(&a.ID, &a.Message, &a.Level, &endsAt, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if endsAt.Valid {
+		a.EndsAt = &endsAt.Time
+	}
+	return &a, nil
+}
+
+// ReplaceAnnouncement ends every active announcement and stores a new one, returning its ID.
+func ReplaceAnnouncement(db *sql.DB, a models.Announcement, now time.Time) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(endAnnouncementsQuery, now, now); err != nil {
+		return 0, err
+	}
+	var endsAt interface{}
+	if a.EndsAt != nil {
+		endsAt = *a.EndsAt
+	}
+	var id int64
+	err = tx.QueryRow(`INSERT INTO announcements (message, level, ends_at, created_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		a.Message, a.Level, endsAt, now).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// EndAnnouncements ends every active announcement now.
+func EndAnnouncements(db *sql.DB, now time.Time) error {
+	_, err := db.Exec(endAnnouncementsQuery, now, now)
+	return err
+}
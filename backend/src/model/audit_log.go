@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// AuditLogRetention is how long audit log entries are kept.
+const AuditLogRetention = 365 * 24 * time.Hour
+
+// CreateAuditLogEntry stores an audit log entry, setting its ID.
+func CreateAuditLogEntry(db *sql.DB, entry *models.AuditLogEntry) error {
+	details := ""
+	if len(entry.Details) > 0 {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+		details = string(encoded)
+	}
+	return db.QueryRow(`
+		INSERT INTO audit_log (user_id, action, details, ip_address, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		entry.UserID, entry.Action, details, entry.IPAddress, entry.UserAgent, entry.CreatedAt).Scan(&entry.ID)
+}
+
+// GetAuditLog lists up to limit of the user's audit log entries, newest first, starting after the entry
+// with ID before when it is not 0.
+func GetAuditLog(db *sql.DB, userID, before int64, limit int) ([]models.AuditLogEntry, error) {
+	query := `SELECT id, user_id, action, details, ip_address, user_agent, created_at FROM audit_log WHERE user_id = ?`
+	args := []interface{}{userID}
+	if before > 0 {
+		query += ` AND id < ?`
+		args = append(args, before)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditLogEntry{}
+	for rows.Next() {
+		var entry models.AuditLogEntry
+		var details string
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Action, &details, &entry.IPAddress, &entry.UserAgent, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if details != "" {
+			if err := json.Unmarshal([]byte(details), &entry.Details); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteAuditLog deletes the user's audit log, returning how many entries it held.
+func DeleteAuditLog(tx *sql.Tx, userID int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM audit_log WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOldAuditLogEntries removes the audit log entries created more than AuditLogRetention ago.
+func DeleteOldAuditLogEntries(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM audit_log WHERE created_at < ?`, now.Add(-AuditLogRetention))
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrSubscriptionNotFound is returned when the user never subscribed to a paid plan.
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+func scanBillingPlan(scanner interface{ Scan(...any) error }) (*models.BillingPlan, error) {
+	var plan models.BillingPlan
+	var priceID sql.NullString
+	if err := scanner.Scan(&plan.Name, &plan.DisplayName, &plan.Premium, &priceID); err != nil {
+		return nil, err
+	}
+	plan.StripePriceID = priceID.String
+	return &plan, nil
+}
+
+// GetBillingPlans lists the billing catalog, free plans first.
+func GetBillingPlans(db *sql.DB) ([]models.BillingPlan, error) {
+	rows, err := db.Query(`SELECT name, display_name, premium, stripe_price_id FROM plans ORDER BY premium, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []models.BillingPlan{}
+	for rows.Next() {
+		plan, err := scanBillingPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetBillingPlan returns the catalog entry of a plan, or sql.ErrNoRows.
+func GetBillingPlan(db *sql.DB, name string) (*models.BillingPlan, error) {
+	return scanBillingPlan(db.QueryRow(`SELECT name, display_name, premium, stripe_price_id FROM plans WHERE name = ?`, name))
+}
+
+// GetBillingPlanByStripePrice returns the plan a Stripe price buys, or sql.ErrNoRows.
+func GetBillingPlanByStripePrice(db *sql.DB, priceID string) (*models.BillingPlan, error) {
+	return scanBillingPlan(db.QueryRow(`SELECT name, display_name, premium, stripe_price_id FROM plans WHERE stripe_price_id = ?`, priceID))
+}
+
+// SetBillingPlanStripePrice links a plan to the Stripe price that buys it; an empty price unlinks it.
+// It returns sql.ErrNoRows if the plan does not exist.
+func SetBillingPlanStripePrice(db *sql.DB, name, priceID string) error {
+	rows, err := execRowsAffected(db, `UPDATE plans SET stripe_price_id = NULLIF(?, '') WHERE name = ?`, priceID, name)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UserHasPremiumPlan reports whether the user's plan includes premium features.
+func UserHasPremiumPlan(db *sql.DB, userID int64) (bool, error) {
+	var premium bool
+	err := db.QueryRow(`
+		SELECT COALESCE(p.premium, FALSE)
+		FROM users u LEFT JOIN plans p ON p.name = u.plan
+		WHERE u.id = ?`, userID).Scan(&premium)
+	return premium, err
+}
+
+// GetSubscription returns the user's subscription, or ErrSubscriptionNotFound.
+func GetSubscription(db *sql.DB, userID int64) (*models.Subscription, error) {
+	var sub models.Subscription
+	var subscriptionID, plan sql.NullString
+	var periodEnd sql.NullTime
+	err := db.QueryRow(`
+		SELECT user_id, provider, customer_id, subscription_id, plan, status, current_period_end, last_event_created, updated_at
+		FROM subscriptions WHERE user_id = ?`, userID).Scan(
+		&sub.UserID, &sub.Provider, &sub.CustomerID, &subscriptionID, &plan, &sub.Status, &periodEnd, &sub.LastEventCreated, &sub.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sub.SubscriptionID = subscriptionID.String
+	sub.Plan = plan.String
+	if periodEnd.Valid {
+		sub.CurrentPeriodEnd = &periodEnd.Time
+	}
+	return &sub, nil
+}
+
+// GetUserIDByCustomer returns the user linked to a customer of the payment provider, or sql.ErrNoRows.
+func GetUserIDByCustomer(db *sql.DB, provider, customerID string) (int64, error) {
+	var userID int64
+	err := db.QueryRow(`SELECT user_id FROM subscriptions WHERE provider = ? AND customer_id = ?`, provider, customerID).Scan(&userID)
+	return userID, err
+}
+
+// UpsertSubscription stores the latest state of the user's subscription. The creation time of the last
+// event applied only moves forward.
+func UpsertSubscription(db *sql.DB, sub models.Subscription) error {
+	var periodEnd interface{}
+	if sub.CurrentPeriodEnd != nil {
+		periodEnd = *sub.CurrentPeriodEnd
+	}
+	_, err := db.Exec(`
+		INSERT INTO subscriptions (user_id, provider, customer_id, subscription_id, plan, status, current_period_end, last_event_created, updated_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			provider = excluded.provider,
+			customer_id = excluded.customer_id,
+			subscription_id = COALESCE(excluded.subscription_id, subscriptions.subscription_id),
+			plan = COALESCE(excluded.plan, subscriptions.plan),
+			status = excluded.status,
+			current_period_end = COALESCE(excluded.current_period_end, subscriptions.current_period_end),
+			last_event_created = CASE WHEN excluded.last_event_created > subscriptions.last_event_created
+				THEN excluded.last_event_created ELSE subscriptions.last_event_created END,
+			updated_at = excluded.updated_at`,
+		sub.UserID, sub.Provider, sub.CustomerID, sub.SubscriptionID, sub.Plan, sub.Status, periodEnd, sub.LastEventCreated, time.Now())
+	return err
+}
+
+// BillingEventRetention is how long the IDs of applied webhook events are kept. The payment provider
+// stops redelivering an event well before.
+const BillingEventRetention = 30 * 24 * time.Hour
+
+// BillingEventProcessed reports whether the provider's webhook event was already applied.
+func BillingEventProcessed(db *sql.DB, provider, eventID string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM billing_events WHERE provider = ? AND event_id = ?`, provider, eventID).Scan(&count)
+	return count > 0, err
+}
+
+// RecordBillingEvent remembers that the provider's webhook event was applied. Recording it twice is a no-op.
+func RecordBillingEvent(db *sql.DB, provider, eventID, eventType string) error {
+	_, err := db.Exec(`
+		INSERT INTO billing_events (provider, event_id, event_type, processed_at)
+		VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`, provider, eventID, eventType, time.Now())
+	return err
+}
+
+// DeleteOldBillingEvents forgets the webhook events applied more than BillingEventRetention ago.
+func DeleteOldBillingEvents(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM billing_events WHERE processed_at <= ?`, now.Add(-BillingEventRetention))
+}
+
+// DeleteSubscription removes the user's subscription record.
+func DeleteSubscription(dbTx *sql.Tx, userID int64) error {
+	_, err := dbTx.Exec(`DELETE FROM subscriptions WHERE user_id = ?`, userID)
+	return err
+}
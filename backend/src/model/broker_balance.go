@@ -0,0 +1,31 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetBrokerBalances returns the cash balances the user's statements reported after their transactions,
+// in the order the transactions were stored.
+func GetBrokerBalances(db *sql.DB, userID int64) ([]models.BrokerBalance, error) {
+	rows, err := db.Query(`
+		SELECT id, date, source, broker_balance_currency, broker_balance
+		FROM processed_transactions
+		WHERE user_id = ? AND broker_balance_currency != ''
+		ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []models.BrokerBalance
+	for rows.Next() {
+		var b models.BrokerBalance
+		if err := rows.Scan(&b.TransactionID, &b.Date, &b.Source, &b.Currency, &b.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrBrokerConnectionNotFound is returned when the user has not connected the broker.
+var ErrBrokerConnectionNotFound = errors.New("broker connection not found")
+
+const brokerConnectionColumns = `id, user_id, broker, encrypted_token, query_id, last_sync_at,
+	COALESCE(last_sync_status, ''), COALESCE(last_sync_error, ''), created_at, updated_at`
+
+func scanBrokerConnection(scanner interface{ Scan(...any) error }) (*models.BrokerConnection, error) {
+	var conn models.BrokerConnection
+	var lastSyncAt sql.NullTime
+	if err := scanner.Scan(&conn.ID, &conn.UserID, &conn.Broker, &conn.EncryptedToken, &conn.QueryID, &lastSyncAt,
+		&conn.LastSyncStatus, &conn.LastSyncError, &conn.CreatedAt, &conn.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lastSyncAt.Valid {
+		conn.LastSyncAt = &lastSyncAt.Time
+	}
+	return &conn, nil
+}
+
+// GetBrokerConnection retrieves the user's connection to a broker.
+func GetBrokerConnection(db *sql.DB, userID int64, broker string) (*models.BrokerConnection, error) {
+	row := db.QueryRow(`SELECT `+brokerConnectionColumns+` FROM broker_connections WHERE user_id = ? AND broker = ?`, userID, broker)
+	conn, err := scanBrokerConnection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBrokerConnectionNotFound
+	}
+	return conn, err
+}
+
+// GetBrokerConnectionsByBroker lists every user's connection to a broker.
+func GetBrokerConnectionsByBroker(db *sql.DB, broker string) ([]models.BrokerConnection, error) {
+	rows, err := db.Query(`SELECT `+brokerConnectionColumns+` FROM broker_connections WHERE broker = ? ORDER BY id`, broker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conns []models.BrokerConnection
+	for rows.Next() {
+		conn, err := scanBrokerConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, *conn)
+	}
+	return conns, rows.Err()
+}
+
+// UpsertBrokerConnection stores new credentials for a broker, replacing any previous ones.
+func UpsertBrokerConnection(db *sql.DB, conn *models.BrokerConnection) error {
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO broker_connections (user_id, broker, encrypted_token, query_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, broker) DO UPDATE SET
+			encrypted_token = excluded.encrypted_token,
+			query_id = excluded.query_id,
+			last_sync_status = NULL,
+			last_sync_error = NULL,
+			updated_at = excluded.updated_at`,
+		conn.UserID, conn.Broker, conn.EncryptedToken, conn.QueryID, now, now)
+	return err
+}
+
+// UpdateBrokerConnectionSync records the outcome of a sync run.
+func UpdateBrokerConnectionSync(db *sql.DB, userID int64, broker, status, syncErr string) error {
+	_, err := db.Exec(`
+		UPDATE broker_connections
+		SET last_sync_at = ?, last_sync_status = ?, last_sync_error = ?
+		WHERE user_id = ? AND broker = ?`, time.Now(), status, syncErr, userID, broker)
+	return err
+}
+
+// DeleteBrokerConnection removes the user's stored credentials for a broker.
+func DeleteBrokerConnection(db *sql.DB, userID int64, broker string) error {
+	_, err := db.Exec(`DELETE FROM broker_connections WHERE user_id = ? AND broker = ?`, userID, broker)
+	return err
+}
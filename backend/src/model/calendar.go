@@ -0,0 +1,43 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetCalendarFeed returns the version of the user's calendar feed token and whether the feed is on.
+func GetCalendarFeed(db *sql.DB, userID int64) (version int64, enabled bool, err error) {
+	err = db.QueryRow(`SELECT calendar_token_version, calendar_feed_enabled FROM users WHERE id = ?`, userID).Scan(&version, &enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, ErrUserNotFound
+	}
+	return version, enabled, err
+}
+
+// EnableCalendarFeed turns the user's calendar feed on with a new token version, invalidating the
+// tokens issued before, and returns the new version.
+func EnableCalendarFeed(db *sql.DB, userID int64) (int64, error) {
+	var version int64
+	err := db.QueryRow(`
+		UPDATE users SET calendar_token_version = calendar_token_version + 1, calendar_feed_enabled = TRUE
+		WHERE id = ? RETURNING calendar_token_version`, userID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	return version, err
+}
+
+// DisableCalendarFeed turns the user's calendar feed off. The token version is moved on as well, so
+// the tokens issued before stay invalid when the feed is turned on again.
+func DisableCalendarFeed(db *sql.DB, userID int64) error {
+	rows, err := execRowsAffected(db, `
+		UPDATE users SET calendar_token_version = calendar_token_version + 1, calendar_feed_enabled = FALSE
+		WHERE id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
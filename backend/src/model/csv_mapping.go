@@ -0,0 +1,47 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrCSVMappingNotFound is returned when the user has not saved a generic CSV mapping yet.
+var ErrCSVMappingNotFound = errors.New("csv mapping not found")
+
+// GetCSVMapping retrieves the user's saved generic CSV column mapping.
+func GetCSVMapping(db *sql.DB, userID int64) (*models.CSVMapping, error) {
+	var raw string
+	err := db.QueryRow(`SELECT mapping FROM csv_mappings WHERE user_id = ?`, userID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCSVMappingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var mapping models.CSVMapping
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// UpsertCSVMapping stores the user's generic CSV column mapping, replacing any previous one.
+func UpsertCSVMapping(db *sql.DB, userID int64, mapping models.CSVMapping) error {
+	raw, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = db.Exec(`
+		INSERT INTO csv_mappings (user_id, mapping, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			mapping = excluded.mapping,
+			updated_at = excluded.updated_at`,
+		userID, string(raw), now, now)
+	return err
+}
@@ -0,0 +1,16 @@
+package model
+
+import "database/sql"
+
+// GetDataVersion returns the counter bumped whenever the user's transactions or settings change.
+func GetDataVersion(db *sql.DB, userID int64) (int64, error) {
+	var version int64
+	err := db.QueryRow(`SELECT data_version FROM users WHERE id = ?`, userID).Scan(&version)
+	return version, err
+}
+
+// BumpDataVersion marks the user's data as changed, so the ETags of their reports change with it.
+func BumpDataVersion(db *sql.DB, userID int64) error {
+	_, err := db.Exec(`UPDATE users SET data_version = data_version + 1 WHERE id = ?`, userID)
+	return err
+}
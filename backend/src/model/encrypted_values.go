@@ -0,0 +1,65 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// EncryptedValue is the stored value of an encrypted column, with the ID of its row.
+type EncryptedValue struct {
+	ID    int64
+	Value string
+	Plain bool // Stored before the column was encrypted
+}
+
+// GetBrokerTokens returns the encrypted token of every broker connection.
+func GetBrokerTokens(db *sql.DB) ([]EncryptedValue, error) {
+	return queryEncryptedValues(db, `SELECT id, encrypted_token, FALSE FROM broker_connections ORDER BY id`)
+}
+
+// ReplaceBrokerToken stores a connection's token encrypted again, unless it changed since it was read.
+func ReplaceBrokerToken(db *sql.DB, id int64, old, encrypted string) error {
+	_, err := db.Exec(`UPDATE broker_connections SET encrypted_token = ? WHERE id = ? AND encrypted_token = ?`, encrypted, id, old)
+	return err
+}
+
+// GetWebhookSecrets returns the encrypted signing secret of every webhook.
+func GetWebhookSecrets(db *sql.DB) ([]EncryptedValue, error) {
+	return queryEncryptedValues(db, `SELECT id, encrypted_secret, FALSE FROM webhooks ORDER BY id`)
+}
+
+// ReplaceWebhookSecret stores a webhook's secret encrypted again, unless it changed since it was read.
+func ReplaceWebhookSecret(db *sql.DB, id int64, old, encrypted string) error {
+	_, err := db.Exec(`UPDATE webhooks SET encrypted_secret = ? WHERE id = ? AND encrypted_secret = ?`, encrypted, id, old)
+	return err
+}
+
+// GetSessionRefreshTokens returns the refresh token of every session, plain for those created before
+// refresh tokens were encrypted.
+func GetSessionRefreshTokens(db *sql.DB) ([]EncryptedValue, error) {
+	return queryEncryptedValues(db, `SELECT id, refresh_token, refresh_token_hash IS NULL FROM sessions ORDER BY id`)
+}
+
+// ReplaceSessionRefreshToken stores a session's refresh token encrypted again, with its hash, unless
+// it changed since it was read.
+func ReplaceSessionRefreshToken(db *sql.DB, id int64, old, encrypted, hash string) error {
+	_, err := db.Exec(`UPDATE sessions SET refresh_token = ?, refresh_token_hash = ? WHERE id = ? AND refresh_token = ?`, encrypted, hash, id, old)
+	return err
+}
+
+func queryEncryptedValues(db *sql.DB, query string) ([]EncryptedValue, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []EncryptedValue
+	for rows.Next() {
+		var v EncryptedValue
+		if err := rows.Scan(&v.ID, &v.Value, &v.Plain); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
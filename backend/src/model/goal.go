@@ -0,0 +1,85 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrGoalNotFound is returned when the user has no goal with the given ID.
+var ErrGoalNotFound = errors.New("goal not found")
+
+const goalColumns = `id, user_id, name, kind, target_amount, target_date, created_at`
+
+// GetGoals lists the user's goals in the order they were created.
+func GetGoals(db *sql.DB, userID int64) ([]models.Goal, error) {
+	rows, err := db.Query(`SELECT `+goalColumns+` FROM goals WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		var goal models.Goal
+		if err := rows.Scan(&goal.ID, &goal.UserID, &goal.Name, &goal.Kind, &goal.TargetAmount, &goal.TargetDate, &goal.CreatedAt); err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// CountGoals returns how many goals the user has.
+func CountGoals(db *sql.DB, userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM goals WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// CreateGoal stores a new goal, setting its ID and creation time.
+func CreateGoal(db *sql.DB, goal *models.Goal) error {
+	goal.CreatedAt = time.Now()
+	return db.QueryRow(`
+		INSERT INTO goals (user_id, name, kind, target_amount, target_date, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		goal.UserID, goal.Name, goal.Kind, goal.TargetAmount, goal.TargetDate, goal.CreatedAt).Scan(&goal.ID)
+}
+
+// UpdateGoal changes one of the user's goals.
+func UpdateGoal(db *sql.DB, goal *models.Goal) error {
+	result, err := db.Exec(`
+		UPDATE goals SET name = ?, kind = ?, target_amount = ?, target_date = ?
+		WHERE id = ? AND user_id = ?`,
+		goal.Name, goal.Kind, goal.TargetAmount, goal.TargetDate, goal.ID, goal.UserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// DeleteGoal deletes one of the user's goals.
+func DeleteGoal(db *sql.DB, userID, id int64) error {
+	result, err := db.Exec(`DELETE FROM goals WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// DeleteGoals deletes all the user's goals and returns how many there were.
+func DeleteGoals(tx *sql.Tx, userID int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM goals WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
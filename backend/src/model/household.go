@@ -0,0 +1,237 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var (
+	// ErrNotInHousehold is returned when the user does not belong to a household.
+	ErrNotInHousehold = errors.New("not in a household")
+	// ErrHouseholdFull is returned when a household already has as many members as it may.
+	ErrHouseholdFull = errors.New("household is full")
+	// ErrHouseholdInvitationNotFound is returned when no unexpired invitation has the given ID.
+	ErrHouseholdInvitationNotFound = errors.New("household invitation not found")
+)
+
+// GetHouseholdID returns the household the user belongs to.
+func GetHouseholdID(db *sql.DB, userID int64) (int64, error) {
+	var householdID int64
+	err := db.QueryRow(`SELECT household_id FROM household_members WHERE user_id = ?`, userID).Scan(&householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotInHousehold
+	}
+	return householdID, err
+}
+
+// GetHouseholdMembers lists the members of a household in the order they joined.
+func GetHouseholdMembers(db *sql.DB, householdID int64) ([]models.HouseholdMember, error) {
+	rows, err := db.Query(`
+		SELECT m.user_id, u.username, u.email, m.joined_at
+		FROM household_members m JOIN users u ON u.id = m.user_id
+		WHERE m.household_id = ? ORDER BY m.joined_at, m.user_id`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.HouseholdMember{}
+	for rows.Next() {
+		var member models.HouseholdMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Email, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+const householdInvitationQuery = `
+	SELECT i.id, i.inviter_id, u.username, i.invitee_email, i.created_at, i.expires_at
+	FROM household_invitations i JOIN users u ON u.id = i.inviter_id`
+
+func queryHouseholdInvitations(db *sql.DB, query string, args ...interface{}) ([]models.HouseholdInvitation, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.HouseholdInvitation{}
+	for rows.Next() {
+		var inv models.HouseholdInvitation
+		if err := rows.Scan(&inv.ID, &inv.InviterID, &inv.InviterUsername, &inv.InviteeEmail, &inv.CreatedAt, &inv.ExpiresAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// GetSentHouseholdInvitations lists the unexpired invitations the user sent, oldest first.
+func GetSentHouseholdInvitations(db *sql.DB, inviterID int64, now time.Time) ([]models.HouseholdInvitation, error) {
+	return queryHouseholdInvitations(db, householdInvitationQuery+` WHERE i.inviter_id = ? AND i.expires_at > ? ORDER BY i.id`, inviterID, now)
+}
+
+// GetReceivedHouseholdInvitations lists the unexpired invitations sent to email, oldest first.
+func GetReceivedHouseholdInvitations(db *sql.DB, email string, now time.Time) ([]models.HouseholdInvitation, error) {
+	return queryHouseholdInvitations(db, householdInvitationQuery+` WHERE i.invitee_email = ? AND i.expires_at > ? ORDER BY i.id`, email, now)
+}
+
+// GetHouseholdInvitation returns an unexpired invitation.
+func GetHouseholdInvitation(db *sql.DB, id int64, now time.Time) (*models.HouseholdInvitation, error) {
+	invitations, err := queryHouseholdInvitations(db, householdInvitationQuery+` WHERE i.id = ? AND i.expires_at > ?`, id, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return nil, ErrHouseholdInvitationNotFound
+	}
+	return &invitations[0], nil
+}
+
+// CreateHouseholdInvitation stores a new invitation, setting its ID.
+func CreateHouseholdInvitation(db *sql.DB, inv *models.HouseholdInvitation) error {
+	return db.QueryRow(`
+		INSERT INTO household_invitations (inviter_id, invitee_email, created_at, expires_at)
+		VALUES (?, ?, ?, ?) RETURNING id`,
+		inv.InviterID, inv.InviteeEmail, inv.CreatedAt, inv.ExpiresAt).Scan(&inv.ID)
+}
+
+// DeleteHouseholdInvitation deletes an invitation, whether it is accepted, declined or cancelled.
+func DeleteHouseholdInvitation(db *sql.DB, id int64) error {
+	rows, err := execRowsAffected(db, `DELETE FROM household_invitations WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrHouseholdInvitationNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredHouseholdInvitations removes the invitations that expired by now.
+func DeleteExpiredHouseholdInvitations(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM household_invitations WHERE expires_at <= ?`, now)
+}
+
+// JoinHousehold adds the invitee to the inviter's household, creating it when the inviter is not in
+// one, and deletes the invitation. It fails with ErrHouseholdFull when the household already has
+// maxMembers members.
+func JoinHousehold(db *sql.DB, inv models.HouseholdInvitation, inviteeID int64, maxMembers int, now time.Time) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var householdID int64
+	err = tx.QueryRow(`SELECT household_id FROM household_members WHERE user_id = ?`, inv.InviterID).Scan(&householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err = tx.QueryRow(`INSERT INTO households (created_at) VALUES (?) RETURNING id`, now).Scan(&householdID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`INSERT INTO household_members (household_id, user_id, joined_at) VALUES (?, ?, ?)`, householdID, inv.InviterID, now); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	var members int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM household_members WHERE household_id = ?`, householdID).Scan(&members); err != nil {
+		return err
+	}
+	if members >= maxMembers {
+		err = ErrHouseholdFull
+		return err
+	}
+	if _, err = tx.Exec(`INSERT INTO household_members (household_id, user_id, joined_at) VALUES (?, ?, ?)`, householdID, inviteeID, now); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM household_invitations WHERE id = ?`, inv.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// leaveHousehold removes the user from their household, dissolving it when fewer than two members
+// would remain, and returns how many memberships of the user were deleted.
+func leaveHousehold(tx *sql.Tx, userID int64) (int64, error) {
+	var householdID int64
+	err := tx.QueryRow(`SELECT household_id FROM household_members WHERE user_id = ?`, userID).Scan(&householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM household_members WHERE user_id = ?`, userID); err != nil {
+		return 0, err
+	}
+
+	var remaining int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM household_members WHERE household_id = ?`, householdID).Scan(&remaining); err != nil {
+		return 0, err
+	}
+	if remaining < 2 {
+		if _, err := tx.Exec(`DELETE FROM household_members WHERE household_id = ?`, householdID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM households WHERE id = ?`, householdID); err != nil {
+			return 0, err
+		}
+	}
+	return 1, nil
+}
+
+// LeaveHousehold removes the user from their household, dissolving it when fewer than two members
+// would remain.
+func LeaveHousehold(db *sql.DB, userID int64) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	left, err := leaveHousehold(tx, userID)
+	if err != nil {
+		return err
+	}
+	if left == 0 {
+		err = ErrNotInHousehold
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteHouseholdData takes the user out of their household and deletes the invitations they sent or
+// received, returning how many memberships and invitations were deleted.
+func DeleteHouseholdData(tx *sql.Tx, userID int64) (int64, error) {
+	left, err := leaveHousehold(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`
+		DELETE FROM household_invitations
+		WHERE inviter_id = ? OR invitee_email = (SELECT LOWER(email) FROM users WHERE id = ?)`, userID, userID)
+	if err != nil {
+		return 0, err
+	}
+	invitations, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return left + invitations, nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Supported login providers.
+const (
+	ProviderLocal  = "local"
+	ProviderGoogle = "google"
+)
+
+// ErrIdentityNotFound is returned when no identity matches the lookup.
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// UserIdentity is one way of logging in to a user account. A user can have at most one identity per provider.
+type UserIdentity struct {
+	ID             int64     `json:"-"`
+	UserID         int64     `json:"-"`
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"-"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// GetIdentity finds the identity for an account at a provider.
+func GetIdentity(db *sql.DB, provider, providerUserID string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := db.QueryRow(`
+		SELECT id, user_id, provider, provider_user_id, email, created_at
+		FROM user_identities
+		WHERE provider = ? AND provider_user_id = ?`, provider, providerUserID).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.ProviderUserID, &identity.Email, &identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetIdentitiesByUserID lists the providers linked to a user, oldest first.
+func GetIdentitiesByUserID(db *sql.DB, userID int64) ([]UserIdentity, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, provider, provider_user_id, email, created_at
+		FROM user_identities
+		WHERE user_id = ?
+		ORDER BY created_at ASC, id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []UserIdentity{}
+	for rows.Next() {
+		var identity UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.ProviderUserID, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// HasIdentity reports whether the user can log in with the given provider.
+func HasIdentity(db *sql.DB, userID int64, provider string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM user_identities WHERE user_id = ? AND provider = ?`, userID, provider).Scan(&count)
+	return count > 0, err
+}
+
+// CreateIdentity links a provider account to a user.
+func CreateIdentity(db *sql.DB, identity *UserIdentity) error {
+	identity.CreatedAt = time.Now()
+	return db.QueryRow(`
+		INSERT INTO user_identities (user_id, provider, provider_user_id, email, created_at)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		identity.UserID, identity.Provider, identity.ProviderUserID, identity.Email, identity.CreatedAt).Scan(&identity.ID)
+}
+
+// DeleteIdentity unlinks a provider from a user.
+func DeleteIdentity(db *sql.DB, userID int64, provider string) error {
+	_, err := db.Exec(`DELETE FROM user_identities WHERE user_id = ? AND provider = ?`, userID, provider)
+	return err
+}
+
+// UpdateAuthProvider changes the provider recorded as the account's primary login method.
+func UpdateAuthProvider(db *sql.DB, userID int64, provider string) error {
+	_, err := db.Exec(`UPDATE users SET auth_provider = ?, updated_at = ? WHERE id = ?`, provider, time.Now(), userID)
+	return err
+}
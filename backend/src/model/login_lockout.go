@@ -0,0 +1,59 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetLockedUntil returns when the user's login lock ends, or the zero time if the account was never locked.
+func GetLockedUntil(db *sql.DB, userID int64) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	if err := db.QueryRow(`SELECT locked_until FROM users WHERE id = ?`, userID).Scan(&lockedUntil); err != nil {
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordFailedLogin increments the user's count of consecutive failed logins and returns the new count.
+func RecordFailedLogin(db *sql.DB, userID int64) (int, error) {
+	var failures int
+	err := db.QueryRow(`
+		UPDATE users SET failed_login_attempts = failed_login_attempts + 1
+		WHERE id = ?
+		RETURNING failed_login_attempts`, userID).Scan(&failures)
+	return failures, err
+}
+
+// LockAccount refuses logins to the user's account until the given time.
+func LockAccount(db *sql.DB, userID int64, until time.Time) error {
+	_, err := db.Exec(`UPDATE users SET locked_until = ? WHERE id = ?`, until, userID)
+	return err
+}
+
+// SetAccountUnlockToken stores the hash of the token of the emailed link that lifts the user's login
+// lock early.
+func SetAccountUnlockToken(db *sql.DB, userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE users SET account_unlock_token_hash = ?, account_unlock_token_expires_at = ?
+		WHERE id = ?`, tokenHash, expiresAt, userID)
+	return err
+}
+
+// GetUserIDByUnlockTokenHash returns the user whose unexpired unlock token has the given hash.
+func GetUserIDByUnlockTokenHash(db *sql.DB, tokenHash string) (int64, error) {
+	var userID int64
+	err := db.QueryRow(`
+		SELECT id FROM users
+		WHERE account_unlock_token_hash = ? AND account_unlock_token_expires_at > ?`, tokenHash, time.Now()).Scan(&userID)
+	return userID, err
+}
+
+// ResetFailedLogins clears the user's failed login count, login lock and unlock token.
+func ResetFailedLogins(db *sql.DB, userID int64) error {
+	_, err := db.Exec(`
+		UPDATE users
+		SET failed_login_attempts = 0, locked_until = NULL,
+		    account_unlock_token_hash = NULL, account_unlock_token_expires_at = NULL
+		WHERE id = ?`, userID)
+	return err
+}
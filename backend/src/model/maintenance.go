@@ -0,0 +1,44 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DeleteExpiredSessions removes the sessions whose refresh token expired before now.
+func DeleteExpiredSessions(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM sessions WHERE expires_at IS NOT NULL AND expires_at <= ?`, now)
+}
+
+// ClearExpiredVerificationTokens removes email verification tokens that can no longer be redeemed.
+// The user can still ask for a new verification email when logging in.
+func ClearExpiredVerificationTokens(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `
+		UPDATE users
+		SET email_verification_token = NULL, email_verification_token_expires_at = NULL
+		WHERE email_verification_token IS NOT NULL AND email_verification_token_expires_at <= ?`, now)
+}
+
+// ClearExpiredPasswordResetTokens removes password reset tokens that can no longer be redeemed.
+func ClearExpiredPasswordResetTokens(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `
+		UPDATE users
+		SET password_reset_token = NULL, password_reset_token_expires_at = NULL
+		WHERE password_reset_token IS NOT NULL AND password_reset_token_expires_at <= ?`, now)
+}
+
+// ClearExpiredUnlockTokens removes account unlock tokens that can no longer be redeemed.
+func ClearExpiredUnlockTokens(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `
+		UPDATE users
+		SET account_unlock_token_hash = NULL, account_unlock_token_expires_at = NULL
+		WHERE account_unlock_token_hash IS NOT NULL AND account_unlock_token_expires_at <= ?`, now)
+}
+
+func execRowsAffected(db *sql.DB, query string, args ...interface{}) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
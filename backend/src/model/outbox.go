@@ -0,0 +1,118 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// OutboxRetention is how long sent and failed emails are kept in the outbox.
+const OutboxRetention = 7 * 24 * time.Hour
+
+const outboxColumns = `id, kind, to_email, subject, text_body, html_body, status, attempts, last_error, next_attempt_at, created_at, sent_at`
+
+// InsertOutboxEmail stores a pending email and returns its ID.
+func InsertOutboxEmail(db *sql.DB, email models.OutboxEmail) (int64, error) {
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO outbox (kind, to_email, subject, text_body, html_body, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		email.Kind, email.ToEmail, email.Subject, email.TextBody, email.HTMLBody, models.OutboxPending, email.NextAttemptAt, email.CreatedAt).Scan(&id)
+	return id, err
+}
+
+// MarkOutboxEmailSent records a successful attempt.
+func MarkOutboxEmailSent(db *sql.DB, id int64, now time.Time) error {
+	_, err := db.Exec(`
+		UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = '', sent_at = ? WHERE id = ?`,
+		models.OutboxSent, now, id)
+	return err
+}
+
+// MarkOutboxEmailFailed records a failed attempt, leaving the email in status: pending to be tried
+// again at nextAttemptAt, or failed.
+func MarkOutboxEmailFailed(db *sql.DB, id int64, status, lastError string, nextAttemptAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, lastError, nextAttemptAt, id)
+	return err
+}
+
+// GetDueOutboxEmails returns up to limit pending emails whose next attempt is due, oldest first.
+func GetDueOutboxEmails(db *sql.DB, now time.Time, limit int) ([]models.OutboxEmail, error) {
+	return queryOutboxEmails(db, `SELECT `+outboxColumns+` FROM outbox
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		models.OutboxPending, now, limit)
+}
+
+// GetOutboxEmails returns up to limit emails, newest first, only those in status unless it is empty.
+func GetOutboxEmails(db *sql.DB, status string, limit int) ([]models.OutboxEmail, error) {
+	if status == "" {
+		return queryOutboxEmails(db, `SELECT `+outboxColumns+` FROM outbox ORDER BY id DESC LIMIT ?`, limit)
+	}
+	return queryOutboxEmails(db, `SELECT `+outboxColumns+` FROM outbox WHERE status = ? ORDER BY id DESC LIMIT ?`, status, limit)
+}
+
+// RequeueOutboxEmail makes a failed email pending again, to be sent on the worker's next run. It
+// returns sql.ErrNoRows if there is no failed email with the ID.
+func RequeueOutboxEmail(db *sql.DB, id int64, now time.Time) error {
+	rows, err := execRowsAffected(db, `
+		UPDATE outbox SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status = ?`,
+		models.OutboxPending, now, id, models.OutboxFailed)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteOldOutboxEmails removes the sent and failed emails created more than OutboxRetention ago.
+func DeleteOldOutboxEmails(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM outbox WHERE status != ? AND created_at <= ?`,
+		models.OutboxPending, now.Add(-OutboxRetention))
+}
+
+// AnonymizeOutboxEmails removes the address and contents of the emails to toEmail, as part of deleting
+// its account, and returns how many there were. Pending emails are deleted; the sent and failed ones
+// keep only their kind, status and times until they are deleted after OutboxRetention.
+func AnonymizeOutboxEmails(tx *sql.Tx, toEmail string) (int64, error) {
+	deleted, err := tx.Exec(`DELETE FROM outbox WHERE LOWER(to_email) = LOWER(?) AND status = ?`, toEmail, models.OutboxPending)
+	if err != nil {
+		return 0, err
+	}
+	redacted, err := tx.Exec(`
+		UPDATE outbox SET to_email = '', subject = '', text_body = '', html_body = '', last_error = ''
+		WHERE LOWER(to_email) = LOWER(?)`, toEmail)
+	if err != nil {
+		return 0, err
+	}
+	deletedCount, _ := deleted.RowsAffected()
+	redactedCount, _ := redacted.RowsAffected()
+	return deletedCount + redactedCount, nil
+}
+
+func queryOutboxEmails(db *sql.DB, query string, args ...interface{}) ([]models.OutboxEmail, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []models.OutboxEmail{}
+	for rows.Next() {
+		var e models.OutboxEmail
+		var sentAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Kind, &e.ToEmail, &e.Subject, &e.TextBody, &e.HTMLBody, &e.Status,
+			&e.Attempts, &e.LastError, &e.NextAttemptAt, &e.CreatedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		if sentAt.Valid {
+			e.SentAt = &sentAt.Time
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
 )
 
 // ISINTickerMap represents a row in the isin_ticker_map table.
@@ -16,7 +17,8 @@ type ISINTickerMap struct {
 	Exchange      sql.NullString // Use sql.NullString for nullable TEXT fields
 	Currency      string
 	CreatedAt     time.Time
-	LastCheckedAt sql.NullTime // Use sql.NullTime for nullable TIMESTAMP fields
+	LastCheckedAt sql.NullTime   // Use sql.NullTime for nullable TIMESTAMP fields
+	QuoteType     sql.NullString // Yahoo quoteType; NULL for mappings cached before it was recorded
 }
 
 // DailyPrice represents a cached price for a ticker on a specific day.
@@ -38,7 +40,7 @@ func GetMappingsByISINs(db *sql.DB, isins []string) (map[string]ISINTickerMap, e
 
 	// Using `IN` clause is efficient for batch lookups.
 	// We construct the query with the correct number of placeholders.
-	query := `SELECT isin, ticker_symbol, exchange, currency, created_at, last_checked_at FROM isin_ticker_map WHERE isin IN (?` + strings.Repeat(",?", len(isins)-1) + `)`
+	query := `SELECT isin, ticker_symbol, exchange, currency, created_at, last_checked_at, quote_type FROM isin_ticker_map WHERE isin IN (?` + strings.Repeat(",?", len(isins)-1) + `)`
 
 	// Convert the slice of strings to a slice of interfaces for the query arguments.
 	args := make([]interface{}, len(isins))
@@ -61,6 +63,7 @@ func GetMappingsByISINs(db *sql.DB, isins []string) (map[string]ISINTickerMap, e
 			&mapping.Currency,
 			&mapping.CreatedAt,
 			&mapping.LastCheckedAt,
+			&mapping.QuoteType,
 		); err != nil {
 			return nil, err
 		}
@@ -70,13 +73,34 @@ func GetMappingsByISINs(db *sql.DB, isins []string) (map[string]ISINTickerMap, e
 	return mappings, rows.Err()
 }
 
+// GetAssetClassesByISINs returns the asset class of each ISIN whose quote type is known.
+func GetAssetClassesByISINs(db *sql.DB, isins []string) (map[string]string, error) {
+	mappings, err := GetMappingsByISINs(db, isins)
+	if err != nil {
+		return nil, err
+	}
+	classes := make(map[string]string, len(mappings))
+	for isin, mapping := range mappings {
+		if class := models.AssetClassFromQuoteType(mapping.QuoteType.String); class != "" {
+			classes[isin] = class
+		}
+	}
+	return classes, nil
+}
+
 // InsertMapping inserts a single new ISIN-to-ticker mapping into the database.
 func InsertMapping(db *sql.DB, mapping ISINTickerMap) error {
 	query := `
-		INSERT INTO isin_ticker_map (isin, ticker_symbol, exchange, currency, last_checked_at)
-		VALUES (?, ?, ?, ?, ?)`
+		INSERT INTO isin_ticker_map (isin, ticker_symbol, exchange, currency, last_checked_at, quote_type)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := db.Exec(query, mapping.ISIN, mapping.TickerSymbol, mapping.Exchange, mapping.Currency, time.Now(), mapping.QuoteType)
+	return err
+}
 
-	_, err := db.Exec(query, mapping.ISIN, mapping.TickerSymbol, mapping.Exchange, mapping.Currency, time.Now())
+// UpdateMappingQuoteType records the Yahoo quoteType of an ISIN that was mapped before it was stored.
+func UpdateMappingQuoteType(db *sql.DB, isin, quoteType string) error {
+	_, err := db.Exec(`UPDATE isin_ticker_map SET quote_type = ?, last_checked_at = ? WHERE isin = ?`, quoteType, time.Now(), isin)
 	return err
 }
 
@@ -0,0 +1,431 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrReportNotMaterialized is returned when no stored report matches the requested data version.
+var ErrReportNotMaterialized = errors.New("report not materialized for this data version")
+
+// ReportVersion identifies the set of processed transactions a materialized report was computed from.
+type ReportVersion struct {
+	DataVersion       string
+	LastTransactionID int64 // Highest processed_transactions.id included
+	TransactionCount  int
+	PrefixHash        string // Hash of the id and hash_id of the transactions included, see HashTransactionsUpTo
+}
+
+// GetTransactionDataVersion hashes the identifiers of the user's processed transactions.
+// The hash changes whenever a transaction is added or removed.
+func GetTransactionDataVersion(ctx context.Context, db *sql.DB, userID int64) (*ReportVersion, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT id, hash_id FROM processed_transactions WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	version := &ReportVersion{}
+	hash, prefix := sha256.New(), sha256.New()
+	for rows.Next() {
+		var hashID sql.NullString
+		if err := rows.Scan(&version.LastTransactionID, &hashID); err != nil {
+			return nil, err
+		}
+		hash.Write([]byte(hashID.String))
+		hash.Write([]byte{'\n'})
+		writePrefixEntry(prefix, version.LastTransactionID, hashID.String)
+		version.TransactionCount++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	version.DataVersion = hex.EncodeToString(hash.Sum(nil))
+	version.PrefixHash = hex.EncodeToString(prefix.Sum(nil))
+	return version, nil
+}
+
+// writePrefixEntry adds a transaction to a prefix hash.
+func writePrefixEntry(h hash.Hash, id int64, hashID string) {
+	h.Write([]byte(strconv.FormatInt(id, 10)))
+	h.Write([]byte{':'})
+	h.Write([]byte(hashID))
+	h.Write([]byte{'\n'})
+}
+
+// GetReportVersion returns the version of the report currently stored for the user.
+func GetReportVersion(ctx context.Context, db *sql.DB, userID int64) (*ReportVersion, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	version := &ReportVersion{}
+	err := db.QueryRowContext(ctx, `SELECT data_version, last_transaction_id, transaction_count, prefix_hash FROM report_versions WHERE user_id = ?`, userID).Scan(
+		&version.DataVersion, &version.LastTransactionID, &version.TransactionCount, &version.PrefixHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportNotMaterialized
+	}
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// HashTransactionsUpTo hashes the id and hash_id of the user's processed transactions with an id up to
+// maxID. The hash differs from the one recorded with a report when any of them was deleted, or replaced
+// in place by a row of other content.
+func HashTransactionsUpTo(ctx context.Context, db *sql.DB, userID, maxID int64) (string, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT id, hash_id FROM processed_transactions WHERE user_id = ? AND id <= ? ORDER BY id`, userID, maxID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	prefix := sha256.New()
+	for rows.Next() {
+		var id int64
+		var hashID sql.NullString
+		if err := rows.Scan(&id, &hashID); err != nil {
+			return "", err
+		}
+		writePrefixEntry(prefix, id, hashID.String)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(prefix.Sum(nil)), nil
+}
+
+// GetMaterializedStockReport loads the stored FIFO sales and yearly holdings computed for dataVersion.
+func GetMaterializedStockReport(ctx context.Context, db *sql.DB, userID int64, dataVersion string) ([]models.SaleDetail, map[string][]models.PurchaseLot, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	var holdingYears string
+	err := db.QueryRowContext(ctx, `SELECT holding_years FROM report_versions WHERE user_id = ? AND data_version = ?`, userID, dataVersion).Scan(&holdingYears)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrReportNotMaterialized
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sales, err := getMaterializedStockSales(ctx, db, userID, dataVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	holdings, err := getMaterializedStockHoldings(ctx, db, userID, dataVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, year := range strings.Split(holdingYears, ",") {
+		if _, ok := holdings[year]; !ok && year != "" {
+			holdings[year] = nil
+		}
+	}
+	return sales, holdings, nil
+}
+
+// GetStockFIFOState loads the FIFO state saved with the report computed for dataVersion.
+func GetStockFIFOState(ctx context.Context, db *sql.DB, userID int64, dataVersion string) (*models.StockFIFOState, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	state := &models.StockFIFOState{OpenLots: make(map[string][]models.OpenLot), ShortLots: make(map[string][]models.OpenLot)}
+	err := db.QueryRowContext(ctx, `SELECT fifo_last_date, fifo_last_year FROM report_versions WHERE user_id = ? AND data_version = ?`, userID, dataVersion).Scan(
+		&state.LastDate, &state.LastYear)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportNotMaterialized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT isin, buy_date, product_name, quantity, original_quantity, price, amount, amount_eur,
+		       currency, exchange_rate, exchange_rate_date, commission, transaction_tax
+		FROM report_open_lots
+		WHERE user_id = ? AND data_version = ?
+		ORDER BY isin, position`, userID, dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lot models.OpenLot
+		if err := rows.Scan(&lot.ISIN, &lot.Date, &lot.ProductName, &lot.Quantity, &lot.OriginalQuantity, &lot.Price, &lot.Amount, &lot.AmountEUR,
+			&lot.Currency, &lot.ExchangeRate, &lot.ExchangeRateDate, &lot.Commission, &lot.TransactionTax); err != nil {
+			return nil, err
+		}
+		if lot.Quantity < 0 {
+			lot.Quantity = -lot.Quantity
+			state.ShortLots[lot.ISIN] = append(state.ShortLots[lot.ISIN], lot)
+			continue
+		}
+		state.OpenLots[lot.ISIN] = append(state.OpenLots[lot.ISIN], lot)
+	}
+	return state, rows.Err()
+}
+
+func getMaterializedStockSales(ctx context.Context, db *sql.DB, userID int64, dataVersion string) ([]models.SaleDetail, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT sale_date, buy_date, product_name, isin, quantity, sale_price, sale_amount, sale_currency,
+		       sale_amount_eur, sale_exchange_rate, buy_price, buy_amount, buy_currency, buy_amount_eur,
+		       buy_exchange_rate, commission, transaction_tax, delta, country_code, tax_year,
+		       sale_exchange_rate_date, buy_exchange_rate_date
+		FROM report_stock_sales
+		WHERE user_id = ? AND data_version = ?
+		ORDER BY position`, userID, dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []models.SaleDetail{}
+	for rows.Next() {
+		var s models.SaleDetail
+		if err := rows.Scan(&s.SaleDate, &s.BuyDate, &s.ProductName, &s.ISIN, &s.Quantity, &s.SalePrice, &s.SaleAmount, &s.SaleCurrency,
+			&s.SaleAmountEUR, &s.SaleExchangeRate, &s.BuyPrice, &s.BuyAmount, &s.BuyCurrency, &s.BuyAmountEUR,
+			&s.BuyExchangeRate, &s.Commission, &s.TransactionTax, &s.Delta, &s.CountryCode, &s.TaxYear,
+			&s.SaleExchangeRateDate, &s.BuyExchangeRateDate); err != nil {
+			return nil, err
+		}
+		sales = append(sales, s)
+	}
+	return sales, rows.Err()
+}
+
+func getMaterializedStockHoldings(ctx context.Context, db *sql.DB, userID int64, dataVersion string) (map[string][]models.PurchaseLot, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT year, buy_date, product_name, isin, quantity, buy_price, buy_amount, buy_currency, buy_amount_eur
+		FROM report_stock_holdings
+		WHERE user_id = ? AND data_version = ?
+		ORDER BY year, position`, userID, dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holdings := make(map[string][]models.PurchaseLot)
+	for rows.Next() {
+		var year string
+		var lot models.PurchaseLot
+		if err := rows.Scan(&year, &lot.BuyDate, &lot.ProductName, &lot.ISIN, &lot.Quantity, &lot.BuyPrice, &lot.BuyAmount, &lot.BuyCurrency, &lot.BuyAmountEUR); err != nil {
+			return nil, err
+		}
+		holdings[year] = append(holdings[year], lot)
+	}
+	return holdings, rows.Err()
+}
+
+// SaveMaterializedStockReport replaces the user's stored FIFO results and state with the ones computed for version.
+func SaveMaterializedStockReport(db *sql.DB, userID int64, version *ReportVersion, sales []models.SaleDetail, holdings map[string][]models.PurchaseLot, state *models.StockFIFOState) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = DeleteMaterializedReports(tx, userID); err != nil {
+		return err
+	}
+	if err = insertStockSales(tx, userID, version.DataVersion, 0, sales); err != nil {
+		return err
+	}
+	if err = insertStockHoldings(tx, userID, version.DataVersion, holdings); err != nil {
+		return err
+	}
+	if err = insertOpenLots(tx, userID, version.DataVersion, state); err != nil {
+		return err
+	}
+	if err = upsertReportVersion(tx, userID, version, mergeHoldingYears("", holdings), state); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AppendMaterializedStockReport extends the report stored for previousVersion with the results of an
+// incremental run: new sales are appended, the given holdings years are replaced and the FIFO state is swapped.
+func AppendMaterializedStockReport(db *sql.DB, userID int64, previousVersion string, version *ReportVersion, newSales []models.SaleDetail, holdings map[string][]models.PurchaseLot, state *models.StockFIFOState) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var previousYears string
+	if err = tx.QueryRow(`SELECT holding_years FROM report_versions WHERE user_id = ? AND data_version = ?`, userID, previousVersion).Scan(&previousYears); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrReportNotMaterialized
+		}
+		return err
+	}
+	var salesCount int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM report_stock_sales WHERE user_id = ?`, userID).Scan(&salesCount); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"report_stock_sales", "report_stock_holdings"} {
+		if _, err = tx.Exec(`UPDATE `+table+` SET data_version = ? WHERE user_id = ?`, version.DataVersion, userID); err != nil {
+			return err
+		}
+	}
+	for year := range holdings {
+		if _, err = tx.Exec(`DELETE FROM report_stock_holdings WHERE user_id = ? AND year = ?`, userID, year); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(`DELETE FROM report_open_lots WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	if err = insertStockSales(tx, userID, version.DataVersion, salesCount, newSales); err != nil {
+		return err
+	}
+	if err = insertStockHoldings(tx, userID, version.DataVersion, holdings); err != nil {
+		return err
+	}
+	if err = insertOpenLots(tx, userID, version.DataVersion, state); err != nil {
+		return err
+	}
+	if err = upsertReportVersion(tx, userID, version, mergeHoldingYears(previousYears, holdings), state); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// mergeHoldingYears adds the years of holdings to a comma-separated list of years.
+func mergeHoldingYears(existing string, holdings map[string][]models.PurchaseLot) string {
+	seen := make(map[string]bool)
+	for _, year := range strings.Split(existing, ",") {
+		if year != "" {
+			seen[year] = true
+		}
+	}
+	for year := range holdings {
+		seen[year] = true
+	}
+	years := make([]string, 0, len(seen))
+	for year := range seen {
+		years = append(years, year)
+	}
+	sort.Strings(years)
+	return strings.Join(years, ",")
+}
+
+func insertStockSales(tx *sql.Tx, userID int64, dataVersion string, firstPosition int, sales []models.SaleDetail) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO report_stock_sales (user_id, data_version, position, sale_date, buy_date, product_name, isin, quantity,
+			sale_price, sale_amount, sale_currency, sale_amount_eur, sale_exchange_rate, buy_price, buy_amount, buy_currency,
+			buy_amount_eur, buy_exchange_rate, commission, transaction_tax, delta, country_code, tax_year,
+			sale_exchange_rate_date, buy_exchange_rate_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, s := range sales {
+		if _, err := stmt.Exec(userID, dataVersion, firstPosition+i, s.SaleDate, s.BuyDate, s.ProductName, s.ISIN, s.Quantity,
+			s.SalePrice, s.SaleAmount, s.SaleCurrency, s.SaleAmountEUR, s.SaleExchangeRate, s.BuyPrice, s.BuyAmount, s.BuyCurrency,
+			s.BuyAmountEUR, s.BuyExchangeRate, s.Commission, s.TransactionTax, s.Delta, s.CountryCode, s.TaxYear,
+			s.SaleExchangeRateDate, s.BuyExchangeRateDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertStockHoldings(tx *sql.Tx, userID int64, dataVersion string, holdings map[string][]models.PurchaseLot) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO report_stock_holdings (user_id, data_version, year, position, buy_date, product_name, isin, quantity,
+			buy_price, buy_amount, buy_currency, buy_amount_eur)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for year, lots := range holdings {
+		for i, lot := range lots {
+			if _, err := stmt.Exec(userID, dataVersion, year, i, lot.BuyDate, lot.ProductName, lot.ISIN, lot.Quantity,
+				lot.BuyPrice, lot.BuyAmount, lot.BuyCurrency, lot.BuyAmountEUR); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertOpenLots saves the open lots of the FIFO state. Short lots share the table and are told
+// apart by a negative quantity.
+func insertOpenLots(tx *sql.Tx, userID int64, dataVersion string, state *models.StockFIFOState) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO report_open_lots (user_id, data_version, isin, position, buy_date, product_name, quantity, original_quantity,
+			price, amount, amount_eur, currency, exchange_rate, exchange_rate_date, commission, transaction_tax)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, group := range []struct {
+		lots map[string][]models.OpenLot
+		sign int
+	}{{state.OpenLots, 1}, {state.ShortLots, -1}} {
+		for isin, lots := range group.lots {
+			for i, lot := range lots {
+				if _, err := stmt.Exec(userID, dataVersion, isin, i, lot.Date, lot.ProductName, group.sign*lot.Quantity, lot.OriginalQuantity,
+					lot.Price, lot.Amount, lot.AmountEUR, lot.Currency, lot.ExchangeRate, lot.ExchangeRateDate, lot.Commission, lot.TransactionTax); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func upsertReportVersion(tx *sql.Tx, userID int64, version *ReportVersion, holdingYears string, state *models.StockFIFOState) error {
+	_, err := tx.Exec(`
+		INSERT INTO report_versions (user_id, data_version, holding_years, last_transaction_id, transaction_count,
+			prefix_hash, fifo_last_date, fifo_last_year, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			data_version = excluded.data_version,
+			holding_years = excluded.holding_years,
+			last_transaction_id = excluded.last_transaction_id,
+			transaction_count = excluded.transaction_count,
+			prefix_hash = excluded.prefix_hash,
+			fifo_last_date = excluded.fifo_last_date,
+			fifo_last_year = excluded.fifo_last_year,
+			computed_at = excluded.computed_at`,
+		userID, version.DataVersion, holdingYears, version.LastTransactionID, version.TransactionCount,
+		version.PrefixHash, state.LastDate, state.LastYear)
+	return err
+}
+
+// DeleteMaterializedReports removes all stored report rows for a user.
+func DeleteMaterializedReports(tx *sql.Tx, userID int64) error {
+	for _, table := range []string{"report_stock_sales", "report_stock_holdings", "report_open_lots", "report_versions"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
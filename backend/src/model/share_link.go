@@ -0,0 +1,107 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrShareLinkNotFound is returned when the user has no share link with the given ID, or no
+// unexpired share link has the given token.
+var ErrShareLinkNotFound = errors.New("share link not found")
+
+const shareLinkColumns = `id, user_id, token_hash, label, scopes, year, expires_at, created_at, last_used_at`
+
+func queryShareLinks(db *sql.DB, query string, args ...interface{}) ([]models.ShareLink, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		var link models.ShareLink
+		var scopes string
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&link.ID, &link.UserID, &link.TokenHash, &link.Label, &scopes, &link.Year,
+			&link.ExpiresAt, &link.CreatedAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		link.Scopes = strings.Split(scopes, ",")
+		if lastUsedAt.Valid {
+			link.LastUsedAt = &lastUsedAt.Time
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// GetShareLinks lists the user's share links, expired ones included, in the order they were created.
+func GetShareLinks(db *sql.DB, userID int64) ([]models.ShareLink, error) {
+	return queryShareLinks(db, `SELECT `+shareLinkColumns+` FROM share_links WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetShareLinkByTokenHash returns the share link whose token has the given hash, unless it expired
+// by now.
+func GetShareLinkByTokenHash(db *sql.DB, tokenHash string, now time.Time) (*models.ShareLink, error) {
+	links, err := queryShareLinks(db, `SELECT `+shareLinkColumns+` FROM share_links WHERE token_hash = ? AND expires_at > ?`, tokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, ErrShareLinkNotFound
+	}
+	return &links[0], nil
+}
+
+// CountShareLinks returns how many unexpired share links the user has.
+func CountShareLinks(db *sql.DB, userID int64, now time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM share_links WHERE user_id = ? AND expires_at > ?`, userID, now).Scan(&count)
+	return count, err
+}
+
+// CreateShareLink stores a new share link, setting its ID and creation time.
+func CreateShareLink(db *sql.DB, link *models.ShareLink) error {
+	link.CreatedAt = time.Now()
+	return db.QueryRow(`
+		INSERT INTO share_links (user_id, token_hash, label, scopes, year, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		link.UserID, link.TokenHash, link.Label, strings.Join(link.Scopes, ","), link.Year, link.ExpiresAt, link.CreatedAt).Scan(&link.ID)
+}
+
+// TouchShareLink records that a share link was used at now.
+func TouchShareLink(db *sql.DB, id int64, now time.Time) error {
+	_, err := db.Exec(`UPDATE share_links SET last_used_at = ? WHERE id = ?`, now, id)
+	return err
+}
+
+// DeleteShareLink deletes one of the user's share links, revoking it.
+func DeleteShareLink(db *sql.DB, userID, id int64) error {
+	rows, err := execRowsAffected(db, `DELETE FROM share_links WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrShareLinkNotFound
+	}
+	return nil
+}
+
+// DeleteShareLinks deletes all the user's share links and returns how many there were.
+func DeleteShareLinks(tx *sql.Tx, userID int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM share_links WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredShareLinks removes the share links that expired by now.
+func DeleteExpiredShareLinks(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM share_links WHERE expires_at <= ?`, now)
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetSkippedTransactionsByUserID retrieves all quarantined rows for a user, newest first.
+func GetSkippedTransactionsByUserID(db *sql.DB, userID int64) ([]models.SkippedTransaction, error) {
+	rows, err := db.Query(`
+		SELECT id, source, raw_text, payload, reason, created_at
+		FROM skipped_transactions
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skipped := []models.SkippedTransaction{}
+	for rows.Next() {
+		var st models.SkippedTransaction
+		if err := rows.Scan(&st.ID, &st.Source, &st.RawText, &st.Payload, &st.Reason, &st.CreatedAt); err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, st)
+	}
+	return skipped, rows.Err()
+}
+
+// ErrSkippedTransactionNotFound is returned when the user has no quarantined row with the requested ID.
+var ErrSkippedTransactionNotFound = errors.New("skipped transaction not found")
+
+// GetSkippedTransaction retrieves one of the user's quarantined rows.
+func GetSkippedTransaction(tx *sql.Tx, userID, id int64) (models.SkippedTransaction, error) {
+	var st models.SkippedTransaction
+	err := tx.QueryRow(`
+		SELECT id, source, raw_text, payload, reason, created_at
+		FROM skipped_transactions
+		WHERE id = ? AND user_id = ?`, id, userID).Scan(&st.ID, &st.Source, &st.RawText, &st.Payload, &st.Reason, &st.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, ErrSkippedTransactionNotFound
+	}
+	return st, err
+}
+
+// InsertSkippedTransaction quarantines a row. Rows already quarantined for the user are ignored.
+func InsertSkippedTransaction(tx *sql.Tx, userID int64, source string, row models.SkippedRow) error {
+	_, err := tx.Exec(`
+		INSERT INTO skipped_transactions (user_id, source, raw_text, payload, reason)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`, userID, source, row.RawText, row.Payload, row.Reason)
+	return err
+}
+
+// DeleteSkippedTransactions removes every row quarantined for the user, with its payload, and returns
+// how many there were.
+func DeleteSkippedTransactions(tx *sql.Tx, userID int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM skipped_transactions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteSkippedTransaction removes a quarantined row once it has been imported.
+func DeleteSkippedTransaction(tx *sql.Tx, userID, id int64) error {
+	_, err := tx.Exec(`DELETE FROM skipped_transactions WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// UpdateSkippedTransactionReason records why a quarantined row still fails to parse.
+func UpdateSkippedTransactionReason(tx *sql.Tx, userID, id int64, reason string) error {
+	_, err := tx.Exec(`UPDATE skipped_transactions SET reason = ? WHERE id = ? AND user_id = ?`, reason, id, userID)
+	return err
+}
@@ -0,0 +1,301 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// TransactionRepository stores the users' processed transactions. Writes take the database
+// transaction they belong to, so they commit or roll back with the rest of an upload or deletion.
+type TransactionRepository interface {
+	// ListByUser returns the user's transactions in date order, oldest first.
+	ListByUser(ctx context.Context, userID int64) ([]models.ProcessedTransaction, error)
+	// ListByUserAfter returns the user's transactions stored with an id above afterID, in date order.
+	ListByUserAfter(ctx context.Context, userID, afterID int64) ([]models.ProcessedTransaction, error)
+	// ListByUserAfterTx is ListByUserAfter within dbTx, so it includes the rows dbTx inserted.
+	ListByUserAfterTx(dbTx *sql.Tx, userID, afterID int64) ([]models.ProcessedTransaction, error)
+	// ListISINs returns the distinct ISINs of the user's transactions.
+	ListISINs(ctx context.Context, userID int64) ([]string, error)
+	// CountByUser returns how many transactions the user has.
+	CountByUser(ctx context.Context, userID int64) (int, error)
+	// LastID returns the highest id of the user's transactions, or 0 when there are none.
+	LastID(ctx context.Context, userID int64) (int64, error)
+	// Insert stores txs, leaving out those whose hash the user already has, and returns how many it stored.
+	Insert(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) (int, error)
+	// UpdateExchangeRates saves the exchange rate, its date and the base currency amount of txs.
+	UpdateExchangeRates(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) error
+	// FindSameTrades returns the user's stored trades from tx's source with its order ID, date, side,
+	// original quantity and type, in the order they were stored.
+	FindSameTrades(dbTx *sql.Tx, userID int64, tx models.ProcessedTransaction) ([]models.ProcessedTransaction, error)
+	// Replace overwrites the details of the stored transaction id with those of tx, keeping its id.
+	Replace(dbTx *sql.Tx, id int64, tx models.ProcessedTransaction) error
+	// DeleteByUser deletes all the user's transactions and returns how many there were.
+	DeleteByUser(dbTx *sql.Tx, userID int64) (int64, error)
+	// Search returns up to limit of the user's transactions whose product name, ISIN, description or
+	// order ID have words starting with each of terms, newest first.
+	Search(ctx context.Context, userID int64, terms []string, limit int) ([]models.ProcessedTransaction, error)
+}
+
+type sqliteTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewTransactionRepository creates a TransactionRepository backed by the SQLite database db.
+func NewTransactionRepository(db *sql.DB) TransactionRepository {
+	return &sqliteTransactionRepository{db: db}
+}
+
+const transactionColumns = `t.id, t.date, t.executed_at, t.source, t.product_name, t.isin, t.quantity, t.original_quantity, t.price, t.transaction_type, t.transaction_subtype, t.buy_sell, t.description, t.amount, t.currency, t.commission, t.order_id, t.exchange_rate, t.exchange_rate_date, t.amount_eur, t.country_code, t.input_string, t.hash_id, COALESCE(m.quote_type, '')`
+
+func (r *sqliteTransactionRepository) ListByUser(ctx context.Context, userID int64) ([]models.ProcessedTransaction, error) {
+	return r.ListByUserAfter(ctx, userID, 0)
+}
+
+// scanTransactions reads rows of transactionColumns.
+func scanTransactions(rows *sql.Rows, userID int64) ([]models.ProcessedTransaction, error) {
+	var transactions []models.ProcessedTransaction
+	for rows.Next() {
+		var tx models.ProcessedTransaction
+		var quoteType string
+		var executedAt sql.NullTime
+		if err := rows.Scan(&tx.ID, &tx.Date, &executedAt, &tx.Source, &tx.ProductName, &tx.ISIN, &tx.Quantity, &tx.OriginalQuantity, &tx.Price,
+			&tx.TransactionType, &tx.TransactionSubType, &tx.BuySell, &tx.Description, &tx.Amount, &tx.Currency, &tx.Commission,
+			&tx.OrderID, &tx.ExchangeRate, &tx.ExchangeRateDate, &tx.AmountEUR, &tx.CountryCode, &tx.InputString, &tx.HashId, &quoteType); err != nil {
+			return nil, fmt.Errorf("error scanning transaction row for userID %d: %w", userID, err)
+		}
+		tx.AssetClass = models.AssetClassFromQuoteType(quoteType)
+		tx.ExecutedAt = executedAt.Time.UTC()
+		transactions = append(transactions, tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over transaction rows for userID %d: %w", userID, err)
+	}
+	return transactions, nil
+}
+
+// listAfterQuery selects the user's transactions stored with an id above a given one, in date order.
+const listAfterQuery = `
+		SELECT ` + transactionColumns + `
+		FROM processed_transactions t
+		LEFT JOIN isin_ticker_map m ON m.isin = t.isin
+		WHERE t.user_id = ? AND t.id > ? ORDER BY t.executed_at ASC, t.id ASC`
+
+func (r *sqliteTransactionRepository) ListByUserAfter(ctx context.Context, userID, afterID int64) ([]models.ProcessedTransaction, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, listAfterQuery, userID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying transactions for userID %d: %w", userID, err)
+	}
+	defer rows.Close()
+	return scanTransactions(rows, userID)
+}
+
+func (r *sqliteTransactionRepository) ListByUserAfterTx(dbTx *sql.Tx, userID, afterID int64) ([]models.ProcessedTransaction, error) {
+	rows, err := dbTx.Query(listAfterQuery, userID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying transactions for userID %d: %w", userID, err)
+	}
+	defer rows.Close()
+	return scanTransactions(rows, userID)
+}
+
+func (r *sqliteTransactionRepository) ListISINs(ctx context.Context, userID int64) ([]string, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT isin FROM processed_transactions WHERE user_id = ? AND isin <> ''`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying ISINs for userID %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var isins []string
+	for rows.Next() {
+		var isin string
+		if err := rows.Scan(&isin); err != nil {
+			return nil, err
+		}
+		isins = append(isins, isin)
+	}
+	return isins, rows.Err()
+}
+
+func (r *sqliteTransactionRepository) CountByUser(ctx context.Context, userID int64) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM processed_transactions WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *sqliteTransactionRepository) LastID(ctx context.Context, userID int64) (int64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+	var id int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM processed_transactions WHERE user_id = ?`, userID).Scan(&id)
+	return id, err
+}
+
+// insertRowsPerStatement is how many transactions one INSERT stores, within SQLite's limit of 32766
+// bound parameters.
+const insertRowsPerStatement = 500
+
+// insertColumnCount is the number of columns insertTransactionsQuery sets per row.
+const insertColumnCount = 25
+
+// insertTransactionsQuery is the INSERT of rows transactions, skipping those whose hash the user
+// already has.
+func insertTransactionsQuery(rows int) string {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", insertColumnCount), ", ") + ")"
+	values := strings.TrimSuffix(strings.Repeat(placeholders+", ", rows), ", ")
+	return `INSERT INTO processed_transactions (user_id, date, executed_at, source, product_name, isin, quantity, original_quantity, price, transaction_type, transaction_subtype, buy_sell, description, amount, currency, commission, order_id, exchange_rate, exchange_rate_date, amount_eur, country_code, input_string, hash_id, broker_balance, broker_balance_currency) VALUES ` +
+		values + ` ON CONFLICT (user_id, hash_id) DO NOTHING`
+}
+
+// executedAt returns when tx was executed, in UTC and to the second, for the executed_at column: midnight
+// of its date when it carries no time, or NULL when its date cannot be read either.
+func executedAt(tx models.ProcessedTransaction) interface{} {
+	if !tx.ExecutedAt.IsZero() {
+		return tx.ExecutedAt.UTC().Truncate(time.Second)
+	}
+	if date, err := time.Parse("02-01-2006", tx.Date); err == nil {
+		return date
+	}
+	return nil
+}
+
+func (r *sqliteTransactionRepository) Insert(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) (int, error) {
+	var fullStmt *sql.Stmt // Prepared once for every chunk of insertRowsPerStatement rows
+	inserted := 0
+	for offset := 0; offset < len(txs); offset += insertRowsPerStatement {
+		chunk := txs[offset:min(offset+insertRowsPerStatement, len(txs))]
+		stmt := fullStmt
+		if stmt == nil || len(chunk) < insertRowsPerStatement {
+			var err error
+			if stmt, err = dbTx.Prepare(insertTransactionsQuery(len(chunk))); err != nil {
+				return inserted, fmt.Errorf("error preparing insert statement: %w", err)
+			}
+			defer stmt.Close()
+			if len(chunk) == insertRowsPerStatement {
+				fullStmt = stmt
+			}
+		}
+
+		args := make([]interface{}, 0, len(chunk)*insertColumnCount)
+		for _, tx := range chunk {
+			args = append(args, userID, tx.Date, executedAt(tx), tx.Source, tx.ProductName, tx.ISIN, tx.Quantity, tx.OriginalQuantity, tx.Price, tx.TransactionType, tx.TransactionSubType, tx.BuySell, tx.Description, tx.Amount, tx.Currency, tx.Commission, tx.OrderID, tx.ExchangeRate, tx.ExchangeRateDate, tx.AmountEUR, tx.CountryCode, tx.InputString, tx.HashId, tx.BrokerBalance, tx.BrokerBalanceCurrency)
+		}
+		result, err := stmt.Exec(args...)
+		if err != nil {
+			return inserted, fmt.Errorf("error inserting %d transactions: %w", len(chunk), err)
+		}
+		// Rows whose hash is already stored, or repeated in the chunk, are left out by ON CONFLICT.
+		n, err := result.RowsAffected()
+		if err != nil {
+			return inserted, fmt.Errorf("error counting inserted transactions: %w", err)
+		}
+		inserted += int(n)
+	}
+	return inserted, nil
+}
+
+func (r *sqliteTransactionRepository) UpdateExchangeRates(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) error {
+	stmt, err := dbTx.Prepare(`UPDATE processed_transactions SET exchange_rate = ?, exchange_rate_date = ?, amount_eur = ? WHERE id = ? AND user_id = ?`)
+	if err != nil {
+		return fmt.Errorf("error preparing update statement: %w", err)
+	}
+	defer stmt.Close()
+	for _, tx := range txs {
+		if _, err := stmt.Exec(tx.ExchangeRate, tx.ExchangeRateDate, tx.AmountEUR, tx.ID, userID); err != nil {
+			return fmt.Errorf("error updating transaction %d: %w", tx.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *sqliteTransactionRepository) FindSameTrades(dbTx *sql.Tx, userID int64, tx models.ProcessedTransaction) ([]models.ProcessedTransaction, error) {
+	rows, err := dbTx.Query(`SELECT id, input_string FROM processed_transactions
+		WHERE user_id = ? AND source = ? AND order_id = ? AND date = ? AND buy_sell = ? AND original_quantity = ? AND transaction_type = ?
+		ORDER BY id`, userID, tx.Source, tx.OrderID, tx.Date, tx.BuySell, tx.OriginalQuantity, tx.TransactionType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []models.ProcessedTransaction
+	for rows.Next() {
+		var trade models.ProcessedTransaction
+		if err := rows.Scan(&trade.ID, &trade.InputString); err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, rows.Err()
+}
+
+func (r *sqliteTransactionRepository) Replace(dbTx *sql.Tx, id int64, tx models.ProcessedTransaction) error {
+	_, err := dbTx.Exec(`UPDATE processed_transactions SET executed_at = ?, product_name = ?, isin = ?, quantity = ?, original_quantity = ?, price = ?, transaction_subtype = ?, description = ?, amount = ?, currency = ?, commission = ?, exchange_rate = ?, exchange_rate_date = ?, amount_eur = ?, country_code = ?, input_string = ?, hash_id = ? WHERE id = ?`,
+		executedAt(tx), tx.ProductName, tx.ISIN, tx.Quantity, tx.OriginalQuantity, tx.Price, tx.TransactionSubType, tx.Description, tx.Amount, tx.Currency, tx.Commission, tx.ExchangeRate, tx.ExchangeRateDate, tx.AmountEUR, tx.CountryCode, tx.InputString, tx.HashId, id)
+	return err
+}
+
+func (r *sqliteTransactionRepository) DeleteByUser(dbTx *sql.Tx, userID int64) (int64, error) {
+	result, err := dbTx.Exec(`DELETE FROM processed_transactions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Transactions are sorted by executed_at: date holds DD-MM-YYYY, which does not sort as text.
+const newestFirst = `ORDER BY t.executed_at DESC, t.id DESC`
+
+func (r *sqliteTransactionRepository) Search(ctx context.Context, userID int64, terms []string, limit int) ([]models.ProcessedTransaction, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var query, match string
+	if database.IsPostgres() {
+		// Must match the expression of idx_processed_transactions_search
+		query = `
+		SELECT ` + transactionColumns + `
+		FROM processed_transactions t
+		LEFT JOIN isin_ticker_map m ON m.isin = t.isin
+		WHERE t.user_id = ? AND to_tsvector('simple', COALESCE(t.product_name, '') || ' ' || COALESCE(t.isin, '') || ' ' || COALESCE(t.description, '') || ' ' || COALESCE(t.order_id, '')) @@ to_tsquery('simple', ?)
+		` + newestFirst + ` LIMIT ?`
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + ":*"
+		}
+		match = strings.Join(prefixes, " & ")
+	} else {
+		query = `
+		SELECT ` + transactionColumns + `
+		FROM processed_transactions_fts f
+		JOIN processed_transactions t ON t.id = f.rowid
+		LEFT JOIN isin_ticker_map m ON m.isin = t.isin
+		WHERE t.user_id = ? AND processed_transactions_fts MATCH ?
+		` + newestFirst + ` LIMIT ?`
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = `"` + term + `"*`
+		}
+		match = strings.Join(prefixes, " ")
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, userID, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching transactions for userID %d: %w", userID, err)
+	}
+	defer rows.Close()
+	return scanTransactions(rows, userID)
+}
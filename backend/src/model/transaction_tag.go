@@ -0,0 +1,188 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrTransactionNotFound is returned when a processed transaction does not exist or belongs to another user.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+func checkTransactionOwner(tx *sql.Tx, userID, transactionID int64) error {
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM processed_transactions WHERE id = ? AND user_id = ?`, transactionID, userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTransactionNotFound
+	}
+	return err
+}
+
+// SetTransactionTags replaces the tags of one of the user's transactions.
+func SetTransactionTags(db *sql.DB, userID, transactionID int64, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkTransactionOwner(tx, userID, transactionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM transaction_tags WHERE user_id = ? AND transaction_id = ?`, userID, transactionID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT INTO transaction_tags (user_id, transaction_id, tag) VALUES (?, ?, ?)`, userID, transactionID, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetTransactionNote stores the note on one of the user's transactions. An empty note removes it.
+func SetTransactionNote(db *sql.DB, userID, transactionID int64, note string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkTransactionOwner(tx, userID, transactionID); err != nil {
+		return err
+	}
+	if note == "" {
+		_, err = tx.Exec(`DELETE FROM transaction_notes WHERE user_id = ? AND transaction_id = ?`, userID, transactionID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO transaction_notes (transaction_id, user_id, note, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(transaction_id) DO UPDATE SET
+				note = excluded.note,
+				updated_at = excluded.updated_at`,
+			transactionID, userID, note, time.Now())
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTransactionAnnotations returns the notes and tags of the user's transactions, keyed by transaction ID.
+// Transactions without a note or tags are absent from the map.
+func GetTransactionAnnotations(db *sql.DB, userID int64) (map[int64]*models.TransactionAnnotation, error) {
+	annotations := make(map[int64]*models.TransactionAnnotation)
+	get := func(id int64) *models.TransactionAnnotation {
+		a, ok := annotations[id]
+		if !ok {
+			a = &models.TransactionAnnotation{TransactionID: id, Tags: []string{}}
+			annotations[id] = a
+		}
+		return a
+	}
+
+	rows, err := db.Query(`SELECT transaction_id, tag FROM transaction_tags WHERE user_id = ? ORDER BY transaction_id, tag`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		a := get(id)
+		a.Tags = append(a.Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	noteRows, err := db.Query(`SELECT transaction_id, note FROM transaction_notes WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer noteRows.Close()
+	for noteRows.Next() {
+		var id int64
+		var note string
+		if err := noteRows.Scan(&id, &note); err != nil {
+			return nil, err
+		}
+		get(id).Note = note
+	}
+	return annotations, noteRows.Err()
+}
+
+// GetTagCounts lists the user's tags with the number of transactions carrying each, most used first.
+func GetTagCounts(db *sql.DB, userID int64) ([]models.TagCount, error) {
+	rows, err := db.Query(`
+		SELECT tag, COUNT(*) FROM transaction_tags
+		WHERE user_id = ?
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var c models.TagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// GetTaggedTransactionRefs returns the user's transactions carrying any of the tags.
+func GetTaggedTransactionRefs(db *sql.DB, userID int64, tags []string) ([]models.TaggedTransactionRef, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	args := []any{userID}
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	rows, err := db.Query(`
+		SELECT DISTINCT pt.id, pt.date, pt.isin
+		FROM transaction_tags tt
+		JOIN processed_transactions pt ON pt.id = tt.transaction_id
+		WHERE tt.user_id = ? AND tt.tag IN (?`+strings.Repeat(", ?", len(tags)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []models.TaggedTransactionRef
+	for rows.Next() {
+		var ref models.TaggedTransactionRef
+		if err := rows.Scan(&ref.ID, &ref.Date, &ref.ISIN); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// DeleteTransactionAnnotations removes every note and tag of a user and returns how many there were.
+// It must run before the user's processed transactions are deleted.
+func DeleteTransactionAnnotations(tx *sql.Tx, userID int64) (int64, error) {
+	tags, err := tx.Exec(`DELETE FROM transaction_tags WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	notes, err := tx.Exec(`DELETE FROM transaction_notes WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	tagCount, _ := tags.RowsAffected()
+	noteCount, _ := notes.RowsAffected()
+	return tagCount + noteCount, nil
+}
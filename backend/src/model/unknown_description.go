@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// RecordUnknownDescription counts one more occurrence of an unclassified description pattern.
+func RecordUnknownDescription(tx *sql.Tx, source, hash, sample string) error {
+	_, err := tx.Exec(`
+		INSERT INTO unknown_descriptions (source, pattern_hash, sample, occurrences) VALUES (?, ?, ?, 1)
+		ON CONFLICT(source, pattern_hash) DO UPDATE SET occurrences = unknown_descriptions.occurrences + 1, last_seen = CURRENT_TIMESTAMP`,
+		source, hash, sample)
+	return err
+}
+
+// GetTopUnknownDescriptions returns the most frequent unclassified description patterns, up to limit.
+func GetTopUnknownDescriptions(db *sql.DB, limit int) ([]models.UnknownDescription, error) {
+	rows, err := db.Query(`
+		SELECT source, pattern_hash, sample, occurrences, first_seen, last_seen
+		FROM unknown_descriptions
+		ORDER BY occurrences DESC, last_seen DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	patterns := []models.UnknownDescription{}
+	for rows.Next() {
+		var p models.UnknownDescription
+		if err := rows.Scan(&p.Source, &p.Hash, &p.Sample, &p.Occurrences, &p.FirstSeen, &p.LastSeen); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, rows.Err()
+}
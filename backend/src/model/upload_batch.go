@@ -0,0 +1,117 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// IdempotencyKeyTTL is how long an upload's idempotency key is remembered.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// CreateUploadBatch records the start of an upload and returns its ID. An empty key is stored as NULL.
+// It returns sql.ErrNoRows if the user already has a batch with the same idempotency key.
+func CreateUploadBatch(db *sql.DB, userID int64, source, filename, idempotencyKey string, now time.Time) (int64, error) {
+	var key interface{}
+	if idempotencyKey != "" {
+		key = idempotencyKey
+	}
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO upload_batches (user_id, source, filename, idempotency_key, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, idempotency_key) DO NOTHING
+		RETURNING id`, userID, source, filename, key, models.UploadBatchProcessing, now).Scan(&id)
+	return id, err
+}
+
+// GetUploadBatchByKey returns the user's batch stored with the idempotency key, or sql.ErrNoRows.
+func GetUploadBatchByKey(db *sql.DB, userID int64, idempotencyKey string) (*models.UploadBatch, error) {
+	var b models.UploadBatch
+	var errMsg sql.NullString
+	err := db.QueryRow(`
+		SELECT id, source, filename, idempotency_key, status, rows_imported, duplicates, skipped, error, created_at
+		FROM upload_batches WHERE user_id = ? AND idempotency_key = ?`, userID, idempotencyKey).
+		Scan(&b.ID, &b.Source, &b.Filename, &b.IdempotencyKey, &b.Status,
+			&b.Summary.RowsImported, &b.Summary.Duplicates, &b.Summary.Skipped, &errMsg, &b.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	b.Summary.Source = b.Source
+	b.Summary.Error = errMsg.String
+	return &b, nil
+}
+
+// RestartUploadBatch marks a failed batch as processing again so its key can be retried.
+// It returns sql.ErrNoRows if the batch is no longer in the failed state.
+func RestartUploadBatch(db *sql.DB, id int64, now time.Time) error {
+	rows, err := execRowsAffected(db, `
+		UPDATE upload_batches
+		SET status = ?, rows_imported = 0, duplicates = 0, skipped = 0, error = NULL, created_at = ?, completed_at = NULL
+		WHERE id = ? AND status = ?`, models.UploadBatchProcessing, now, id, models.UploadBatchFailed)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FinishUploadBatch stores the outcome of an upload. The batch failed if the summary has an error.
+func FinishUploadBatch(db *sql.DB, id int64, summary models.UploadSummary, now time.Time) error {
+	status := models.UploadBatchCompleted
+	var errMsg interface{}
+	if summary.Error != "" {
+		status = models.UploadBatchFailed
+		errMsg = summary.Error
+	}
+	_, err := db.Exec(`
+		UPDATE upload_batches
+		SET status = ?, rows_imported = ?, duplicates = ?, skipped = ?, error = ?, completed_at = ?
+		WHERE id = ?`, status, summary.RowsImported, summary.Duplicates, summary.Skipped, errMsg, now, id)
+	return err
+}
+
+// GetUploadHistory returns the user's upload batches, newest first.
+func GetUploadHistory(db *sql.DB, userID int64) ([]models.UploadHistoryEntry, error) {
+	rows, err := db.Query(`
+		SELECT id, created_at, completed_at, filename, source, status, rows_imported, duplicates, skipped, error
+		FROM upload_batches WHERE user_id = ? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.UploadHistoryEntry{}
+	for rows.Next() {
+		var entry models.UploadHistoryEntry
+		var completedAt sql.NullTime
+		var errMsg sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt, &completedAt, &entry.Filename, &entry.Source, &entry.Status,
+			&entry.RowsImported, &entry.Duplicates, &entry.Skipped, &errMsg); err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			entry.CompletedAt = &completedAt.Time
+		}
+		entry.Error = errMsg.String
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// ClearExpiredIdempotencyKeys forgets the idempotency keys of batches started more than IdempotencyKeyTTL ago,
+// so the client may reuse them. The batches themselves are kept.
+func ClearExpiredIdempotencyKeys(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `
+		UPDATE upload_batches SET idempotency_key = NULL
+		WHERE idempotency_key IS NOT NULL AND created_at <= ?`, now.Add(-IdempotencyKeyTTL))
+}
+
+// DeleteUploadBatches removes the user's upload history.
+func DeleteUploadBatches(dbTx *sql.Tx, userID int64) error {
+	_, err := dbTx.Exec(`DELETE FROM upload_batches WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetUserPlan returns the name of the user's subscription plan.
+func GetUserPlan(db *sql.DB, userID int64) (string, error) {
+	var plan string
+	if err := db.QueryRow(`SELECT plan FROM users WHERE id = ?`, userID).Scan(&plan); err != nil {
+		return "", err
+	}
+	if plan == "" {
+		return models.PlanFree, nil
+	}
+	return plan, nil
+}
+
+// SetUserPlan moves the user to another subscription plan. It returns sql.ErrNoRows if the user does not exist.
+func SetUserPlan(db *sql.DB, userID int64, plan string) error {
+	rows, err := execRowsAffected(db, `UPDATE users SET plan = ? WHERE id = ?`, plan, userID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetMonthlyUploads returns how many files the user uploaded in the month (YYYY-MM).
+func GetMonthlyUploads(db *sql.DB, userID int64, month string) (int, error) {
+	var uploads int
+	err := db.QueryRow(`SELECT uploads FROM upload_usage WHERE user_id = ? AND month = ?`, userID, month).Scan(&uploads)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return uploads, err
+}
+
+// RecordUpload counts an uploaded file towards the user's monthly usage and lifetime upload count.
+func RecordUpload(db *sql.DB, userID int64, month string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO upload_usage (user_id, month, uploads) VALUES (?, ?, 1)
+		ON CONFLICT(user_id, month) DO UPDATE SET uploads = upload_usage.uploads + 1`, userID, month); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE users SET upload_count = upload_count + 1 WHERE id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountUserTransactions returns how many processed transactions the user has stored.
+// Pass a *sql.Tx to include rows inserted by a transaction that has not committed yet.
+func CountUserTransactions(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, userID int64) (int, error) {
+	var count int
+	err := q.QueryRow(`SELECT COUNT(*) FROM processed_transactions WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteUploadUsage removes the user's monthly upload counters.
+func DeleteUploadUsage(dbTx *sql.Tx, userID int64) error {
+	_, err := dbTx.Exec(`DELETE FROM upload_usage WHERE user_id = ?`, userID)
+	return err
+}
@@ -3,13 +3,18 @@ package model
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"log"
 
+	"github.com/username/taxfolio/backend/src/security"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrUserNotFound is returned when no user has the given ID.
+var ErrUserNotFound = errors.New("user not found")
+
 type User struct {
 	ID           int64     `json:"id"` // Changed to int64 to match GetUserIDFromContext
 	Username     string    `json:"username"`
@@ -65,7 +70,8 @@ func (u *User) CreateUser(db *sql.DB) error {
 	// CORREÇÃO: Adicionado `auth_provider` à query
 	query := `
 	INSERT INTO users (username, email, password, auth_provider, is_email_verified, email_verification_token, email_verification_token_expires_at, password_reset_token, password_reset_token_expires_at, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, NULL, NULL, ?, ?)`
+	VALUES (?, ?, ?, ?, ?, ?, ?, NULL, NULL, ?, ?)
+	RETURNING id`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return err
@@ -79,7 +85,8 @@ func (u *User) CreateUser(db *sql.DB) error {
 		emailTokenExpiresArg = u.EmailVerificationTokenExpiresAt
 	}
 
-	res, err := stmt.Exec(
+	var id int64
+	err = stmt.QueryRow(
 		u.Username,
 		u.Email,
 		u.Password,
@@ -89,11 +96,7 @@ func (u *User) CreateUser(db *sql.DB) error {
 		emailTokenExpiresArg,
 		u.CreatedAt,
 		u.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
-	id, err := res.LastInsertId()
+	).Scan(&id)
 	if err != nil {
 		return err
 	}
@@ -129,7 +132,7 @@ func GetUserByID(db *sql.DB, id int64) (*User, error) {
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -448,10 +451,15 @@ func (u *User) UpdatePassword(db *sql.DB, newPasswordHash string) error {
 	return err
 }
 
-func CreateSession(db *sql.DB, session *Session) error {
+// CreateSession stores a session, its refresh token encrypted with keyring and hashed for lookups.
+func CreateSession(db *sql.DB, keyring *security.Keyring, session *Session) error {
+	encryptedRefreshToken, err := keyring.Encrypt(session.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("error encrypting refresh token: %w", err)
+	}
 	query := `
-	INSERT INTO sessions (user_id, token, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	INSERT INTO sessions (user_id, token, refresh_token, refresh_token_hash, user_agent, client_ip, is_blocked, expires_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return err
@@ -462,7 +470,8 @@ func CreateSession(db *sql.DB, session *Session) error {
 	_, err = stmt.Exec(
 		session.UserID,
 		session.Token,
-		session.RefreshToken,
+		encryptedRefreshToken,
+		security.HashToken(session.RefreshToken),
 		session.UserAgent,
 		session.ClientIP,
 		session.IsBlocked,
@@ -472,13 +481,10 @@ func CreateSession(db *sql.DB, session *Session) error {
 	return err
 }
 
-func GetSessionByToken(db *sql.DB, token string) (*Session, error) {
-	query := `
-	SELECT id, user_id, token, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
-	FROM sessions
-	WHERE token = ? AND is_blocked = FALSE AND expires_at > ?`
+const sessionColumns = `id, user_id, token, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at`
 
-	row := db.QueryRow(query, token, time.Now())
+// scanSession reads a row of sessionColumns, decrypting the refresh token with keyring.
+func scanSession(row *sql.Row, keyring *security.Keyring) (*Session, error) {
 	var session Session
 	err := row.Scan(
 		&session.ID,
@@ -491,41 +497,51 @@ func GetSessionByToken(db *sql.DB, token string) (*Session, error) {
 		&session.ExpiresAt,
 		&session.CreatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if session.RefreshToken, err = keyring.Decrypt(session.RefreshToken); err != nil {
+		return nil, fmt.Errorf("error decrypting refresh token of session %d: %w", session.ID, err)
+	}
+	return &session, nil
+}
+
+func GetSessionByToken(db *sql.DB, keyring *security.Keyring, token string) (*Session, error) {
+	query := `
+	SELECT ` + sessionColumns + `
+	FROM sessions
+	WHERE token = ? AND is_blocked = FALSE AND expires_at > ?`
+
+	session, err := scanSession(db.QueryRow(query, token, time.Now()), keyring)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("session not found, expired, or blocked")
 		}
 		return nil, err
 	}
-	return &session, nil
+	return session, nil
 }
 
-func GetSessionByRefreshToken(db *sql.DB, refreshToken string) (*Session, error) {
+func GetSessionByRefreshToken(db *sql.DB, keyring *security.Keyring, refreshToken string) (*Session, error) {
 	query := `
-    SELECT id, user_id, token, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+    SELECT ` + sessionColumns + `
     FROM sessions
-    WHERE refresh_token = ? AND is_blocked = FALSE AND expires_at > ?`
+    WHERE refresh_token_hash = ? AND is_blocked = FALSE AND expires_at > ?`
 
-	row := db.QueryRow(query, refreshToken, time.Now())
-	var session Session
-	err := row.Scan(
-		&session.ID,
-		&session.UserID,
-		&session.Token,
-		&session.RefreshToken,
-		&session.UserAgent,
-		&session.ClientIP,
-		&session.IsBlocked,
-		&session.ExpiresAt,
-		&session.CreatedAt,
-	)
+	session, err := scanSession(db.QueryRow(query, security.HashToken(refreshToken), time.Now()), keyring)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("refresh session not found, expired, or blocked")
 		}
 		return nil, err
 	}
-	return &session, nil
+	return session, nil
+}
+
+// ExtendSession moves the expiry of a session to expiresAt.
+func ExtendSession(db *sql.DB, id int64, expiresAt time.Time) error {
+	_, err := db.Exec(`UPDATE sessions SET expires_at = ? WHERE id = ?`, expiresAt, id)
+	return err
 }
 
 func DeleteSessionByToken(db *sql.DB, token string) error {
@@ -540,13 +556,13 @@ func DeleteSessionByToken(db *sql.DB, token string) error {
 }
 
 func DeleteSessionByRefreshToken(db *sql.DB, refreshToken string) error {
-	query := `DELETE FROM sessions WHERE refresh_token = ?`
+	query := `DELETE FROM sessions WHERE refresh_token_hash = ?`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	result, err := stmt.Exec(refreshToken)
+	result, err := stmt.Exec(security.HashToken(refreshToken))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// GetUserSettings returns the user's preferences, with defaults for the ones never set.
+// It returns sql.ErrNoRows when the user does not exist.
+func GetUserSettings(db *sql.DB, userID int64) (models.UserSettings, error) {
+	var stored sql.NullString
+	err := db.QueryRow(`
+		SELECT s.settings FROM users u LEFT JOIN user_settings s ON s.user_id = u.id
+		WHERE u.id = ?`, userID).Scan(&stored)
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+
+	settings := models.DefaultUserSettings()
+	if stored.Valid {
+		if err := json.Unmarshal([]byte(stored.String), &settings); err != nil {
+			return models.UserSettings{}, fmt.Errorf("invalid stored settings: %w", err)
+		}
+	}
+	defaults := models.DefaultUserSettings()
+	if settings.BaseCurrency == "" {
+		settings.BaseCurrency = defaults.BaseCurrency
+	}
+	if settings.MatchingMethod == "" {
+		settings.MatchingMethod = defaults.MatchingMethod
+	}
+	if settings.Locale == "" {
+		settings.Locale = defaults.Locale
+	}
+	if settings.FiscalYearStart == "" {
+		settings.FiscalYearStart = defaults.FiscalYearStart
+	}
+	if settings.TaxCountry == "" {
+		settings.TaxCountry = defaults.TaxCountry
+	}
+	return settings, nil
+}
+
+// SaveUserSettings replaces all of the user's preferences. Callers validate them first.
+func SaveUserSettings(tx *sql.Tx, userID int64, settings models.UserSettings) error {
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO user_settings (user_id, settings) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET settings = excluded.settings, updated_at = CURRENT_TIMESTAMP`,
+		userID, string(encoded))
+	return err
+}
+
+// setUserSetting changes one key of the user's preferences, leaving the others as they are.
+func setUserSetting(exec interface {
+	Exec(string, ...any) (sql.Result, error)
+}, userID int64, key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if database.IsPostgres() {
+		_, err = exec.Exec(`
+			INSERT INTO user_settings (user_id, settings) VALUES (?, jsonb_build_object(?::text, ?::jsonb)::text)
+			ON CONFLICT(user_id) DO UPDATE SET settings = (user_settings.settings::jsonb || jsonb_build_object(?::text, ?::jsonb))::text, updated_at = CURRENT_TIMESTAMP`,
+			userID, key, string(encoded), key, string(encoded))
+		return err
+	}
+	path := "$." + key
+	_, err = exec.Exec(`
+		INSERT INTO user_settings (user_id, settings) VALUES (?, json_set('{}', ?, json(?)))
+		ON CONFLICT(user_id) DO UPDATE SET settings = json_set(user_settings.settings, ?, json(?)), updated_at = CURRENT_TIMESTAMP`,
+		userID, path, string(encoded), path, string(encoded))
+	return err
+}
+
+// DeleteUserSettings removes the user's preferences, as part of deleting the account.
+func DeleteUserSettings(tx *sql.Tx, userID int64) error {
+	_, err := tx.Exec(`DELETE FROM user_settings WHERE user_id = ?`, userID)
+	return err
+}
+
+// GetUserBaseCurrency returns the currency the user's amounts and reports are expressed in.
+func GetUserBaseCurrency(db *sql.DB, userID int64) (string, error) {
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return "", err
+	}
+	return settings.BaseCurrency, nil
+}
+
+// SetUserBaseCurrency changes the user's reporting currency. Stored amounts must be converted in the same transaction.
+func SetUserBaseCurrency(tx *sql.Tx, userID int64, currency string) error {
+	return setUserSetting(tx, userID, "base_currency", currency)
+}
+
+// GetUserLocale returns the locale the user's API-generated text is rendered in, e.g. "pt-PT".
+func GetUserLocale(db *sql.DB, userID int64) (string, error) {
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return "", err
+	}
+	return settings.Locale, nil
+}
+
+// SetUserLocale changes the locale of the user's API-generated text.
+func SetUserLocale(db *sql.DB, userID int64, locale string) error {
+	return setUserSetting(db, userID, "locale", locale)
+}
+
+// GetUserFiscalYear returns the boundary of the user's tax years.
+func GetUserFiscalYear(db *sql.DB, userID int64) (models.FiscalYear, error) {
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return models.FiscalYear{}, err
+	}
+	return models.ParseFiscalYearStart(settings.FiscalYearStart)
+}
+
+// SetUserFiscalYear changes the boundary of the user's tax years.
+func SetUserFiscalYear(tx *sql.Tx, userID int64, fiscalYear models.FiscalYear) error {
+	return setUserSetting(tx, userID, "fiscal_year_start", fiscalYear.String())
+}
+
+// GetUserDeemedDisposal reports whether the deemed disposal rule applies to the user's ETF holdings.
+func GetUserDeemedDisposal(db *sql.DB, userID int64) (bool, error) {
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return false, err
+	}
+	return settings.DeemedDisposal, nil
+}
+
+// SetUserDeemedDisposal turns the deemed disposal rule on or off for the user.
+func SetUserDeemedDisposal(db *sql.DB, userID int64, enabled bool) error {
+	return setUserSetting(db, userID, "deemed_disposal", enabled)
+}
+
+// GetUserDefaultAccount returns the upload source used when an upload names none, or "" when the user has not set one.
+func GetUserDefaultAccount(db *sql.DB, userID int64) (string, error) {
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return "", err
+	}
+	return settings.DefaultAccount, nil
+}
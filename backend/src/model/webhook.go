@@ -0,0 +1,248 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// ErrWebhookNotFound is returned when the user has no webhook with the given ID.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookDeliveryRetention is how long delivered and failed webhook deliveries are kept.
+const WebhookDeliveryRetention = 7 * 24 * time.Hour
+
+const webhookColumns = `id, user_id, url, encrypted_secret, events, enabled, created_at`
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at`
+
+func scanWebhooks(rows *sql.Rows) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		var events string
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.EncryptedSecret, &events,
+			&webhook.Enabled, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhook.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func queryWebhooks(db *sql.DB, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanWebhooks(rows)
+}
+
+// GetWebhooks lists the user's webhooks in the order they were created.
+func GetWebhooks(db *sql.DB, userID int64) ([]models.Webhook, error) {
+	return queryWebhooks(db, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetWebhook returns one of the user's webhooks.
+func GetWebhook(db *sql.DB, userID, id int64) (*models.Webhook, error) {
+	webhooks, err := queryWebhooks(db, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhooks[0], nil
+}
+
+// GetWebhooksByIDs returns the webhooks with the given IDs, of any user, by ID.
+func GetWebhooksByIDs(db *sql.DB, ids []int64) (map[int64]models.Webhook, error) {
+	byID := make(map[int64]models.Webhook, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	webhooks, err := queryWebhooks(db, `SELECT `+webhookColumns+` FROM webhooks WHERE id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range webhooks {
+		byID[webhook.ID] = webhook
+	}
+	return byID, nil
+}
+
+// GetSubscribedWebhooks lists the user's enabled webhooks subscribed to event.
+func GetSubscribedWebhooks(db *sql.DB, userID int64, event string) ([]models.Webhook, error) {
+	webhooks, err := queryWebhooks(db, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? AND enabled = TRUE ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(webhooks, func(webhook models.Webhook) bool {
+		return !slices.Contains(webhook.Events, event)
+	}), nil
+}
+
+// GetSubscribedUserIDs lists the users with an enabled webhook subscribed to event.
+func GetSubscribedUserIDs(db *sql.DB, event string) ([]int64, error) {
+	webhooks, err := queryWebhooks(db, `SELECT `+webhookColumns+` FROM webhooks WHERE enabled = TRUE ORDER BY user_id, id`)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := []int64{}
+	for _, webhook := range webhooks {
+		if slices.Contains(webhook.Events, event) && (len(userIDs) == 0 || userIDs[len(userIDs)-1] != webhook.UserID) {
+			userIDs = append(userIDs, webhook.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// CountWebhooks returns how many webhooks the user has.
+func CountWebhooks(db *sql.DB, userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// CreateWebhook stores a new webhook, setting its ID and creation time.
+func CreateWebhook(db *sql.DB, webhook *models.Webhook) error {
+	webhook.CreatedAt = time.Now()
+	return db.QueryRow(`
+		INSERT INTO webhooks (user_id, url, encrypted_secret, events, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		webhook.UserID, webhook.URL, webhook.EncryptedSecret, strings.Join(webhook.Events, ","), webhook.Enabled, webhook.CreatedAt).Scan(&webhook.ID)
+}
+
+// UpdateWebhook changes the URL, events and state of one of the user's webhooks, keeping its secret.
+func UpdateWebhook(db *sql.DB, webhook *models.Webhook) error {
+	rows, err := execRowsAffected(db, `UPDATE webhooks SET url = ?, events = ?, enabled = ? WHERE id = ? AND user_id = ?`,
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.Enabled, webhook.ID, webhook.UserID)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook deletes one of the user's webhooks with its deliveries.
+func DeleteWebhook(db *sql.DB, userID, id int64) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if _, err = tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE id = ? AND user_id = ?)`, id, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		err = ErrWebhookNotFound
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteWebhooks deletes all the user's webhooks with their deliveries and returns how many webhooks
+// there were.
+func DeleteWebhooks(tx *sql.Tx, userID int64) (int64, error) {
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE user_id = ?)`, userID); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// InsertWebhookDelivery queues a pending delivery and returns its ID.
+func InsertWebhookDelivery(db *sql.DB, delivery models.WebhookDelivery) (int64, error) {
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		delivery.WebhookID, delivery.Event, delivery.Payload, models.WebhookDeliveryPending, delivery.NextAttemptAt, delivery.CreatedAt).Scan(&id)
+	return id, err
+}
+
+// MarkWebhookDeliveryDelivered records a successful attempt.
+func MarkWebhookDeliveryDelivered(db *sql.DB, id int64, responseStatus int, now time.Time) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = ?, last_error = '', delivered_at = ?
+		WHERE id = ?`,
+		models.WebhookDeliveryDelivered, responseStatus, now, id)
+	return err
+}
+
+// MarkWebhookDeliveryFailed records a failed attempt, leaving the delivery in status: pending to be
+// tried again at nextAttemptAt, or failed.
+func MarkWebhookDeliveryFailed(db *sql.DB, id int64, status string, responseStatus int, lastError string, nextAttemptAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`,
+		status, responseStatus, lastError, nextAttemptAt, id)
+	return err
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries whose next attempt is due, oldest first.
+func GetDueWebhookDeliveries(db *sql.DB, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return queryWebhookDeliveries(db, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		models.WebhookDeliveryPending, now, limit)
+}
+
+// GetWebhookDeliveries returns up to limit deliveries of one of the user's webhooks, newest first.
+func GetWebhookDeliveries(db *sql.DB, userID, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	return queryWebhookDeliveries(db, `SELECT d.`+strings.ReplaceAll(webhookDeliveryColumns, ", ", ", d.")+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = ? AND w.user_id = ? ORDER BY d.id DESC LIMIT ?`, webhookID, userID, limit)
+}
+
+// DeleteOldWebhookDeliveries removes the delivered and failed deliveries created more than
+// WebhookDeliveryRetention ago.
+func DeleteOldWebhookDeliveries(db *sql.DB, now time.Time) (int64, error) {
+	return execRowsAffected(db, `DELETE FROM webhook_deliveries WHERE status != ? AND created_at <= ?`,
+		models.WebhookDeliveryPending, now.Add(-WebhookDeliveryRetention))
+}
+
+func queryWebhookDeliveries(db *sql.DB, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Kinds of alert rule.
+const (
+	AlertBelowCostBasis        = "below_cost_basis"        // A position's market value falls Threshold percent or more below its cost basis
+	AlertMonthlyDividendsAbove = "monthly_dividends_above" // Gross dividends received this month exceed Threshold in the base currency
+	AlertUnmatchedSell         = "unmatched_sell"          // A sale of the latest year has no matching purchase
+)
+
+// AlertRule is a condition the user is emailed about when it starts to hold.
+type AlertRule struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"-"`
+	Kind            string     `json:"kind"`
+	ISIN            string     `json:"isin,omitempty"` // Position watched by below_cost_basis
+	Threshold       float64    `json:"threshold"`
+	Enabled         bool       `json:"enabled"`
+	TriggeredKey    string     `json:"-"` // Occurrence last notified; empty while the condition does not hold
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Announcement levels. A maintenance announcement also reports the service status as "maintenance".
+const (
+	AnnouncementInfo        = "info"
+	AnnouncementWarning     = "warning"
+	AnnouncementMaintenance = "maintenance"
+)
+
+// Service statuses reported by GET /status.
+const (
+	ServiceStatusOK          = "ok"
+	ServiceStatusMaintenance = "maintenance"
+)
+
+// Announcement is a service-wide message shown to every user, e.g. degraded prices or planned maintenance.
+type Announcement struct {
+	ID        int64      `json:"id"`
+	Message   string     `json:"message"`
+	Level     string     `json:"level"`
+	EndsAt    *time.Time `json:"ends_at"` // Hidden from then on; nil until an admin clears it
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AnnouncementRequest is the body of PUT /admin/announcement.
+type AnnouncementRequest struct {
+	Message string     `json:"message"`
+	Level   string     `json:"level"` // Defaults to "info"
+	EndsAt  *time.Time `json:"ends_at"`
+}
+
+// ServiceStatus is the public status polled by the frontend to show a banner.
+type ServiceStatus struct {
+	Status       string        `json:"status"`
+	Announcement *Announcement `json:"announcement"`
+	ServerTime   time.Time     `json:"server_time"`
+}
@@ -0,0 +1,72 @@
+package models
+
+// InterestDetail is one interest payment received on, or charged for, cash held at the broker.
+type InterestDetail struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Source      string  `json:"source"`
+	Amount      float64 `json:"amount"`     // In the original currency; interest charged is negative
+	Currency    string  `json:"currency"`   // Original currency
+	AmountEUR   float64 `json:"amount_eur"` // Amount in the base currency
+}
+
+// AnnualReport gathers everything realized in one tax year, and the positions left at its end, in a
+// single document. Totals are summed from the rounded line items listed with them.
+type AnnualReport struct {
+	Year         string `json:"year"`
+	BaseCurrency string `json:"base_currency"` // Currency of the *_eur amounts
+
+	StockSales     []SaleDetail       `json:"stock_sales"`
+	StockGainsEUR  float64            `json:"stock_gains_eur"` // Sum of the sales' gain_eur, after commissions and transaction taxes
+	OptionSales    []OptionSaleDetail `json:"option_sales"`
+	OptionGainsEUR float64            `json:"option_gains_eur"` // Sum of the closed positions' delta less commissions
+
+	DividendsByCountry map[string]DividendCountrySummary `json:"dividends_by_country"`
+	DividendGrossEUR   float64                           `json:"dividend_gross_eur"`
+	DividendTaxEUR     float64                           `json:"dividend_tax_eur"` // Tax withheld, negative
+
+	Fees        []FeeDetail      `json:"fees"`
+	FeesEUR     float64          `json:"fees_eur"` // Commissions, transaction taxes and broker fees, negative
+	Interest    []InterestDetail `json:"interest"`
+	InterestEUR float64          `json:"interest_eur"`
+
+	Holdings []PurchaseLot `json:"holdings"` // Open stock lots at the end of the tax year, or today for the current one
+}
+
+// YearFigures are the totals of one tax year compared by the year-over-year report, worked out as in
+// the annual report.
+type YearFigures struct {
+	Year             string  `json:"year"`
+	StockGainsEUR    float64 `json:"stock_gains_eur"`
+	OptionGainsEUR   float64 `json:"option_gains_eur"`
+	RealizedGainsEUR float64 `json:"realized_gains_eur"` // Stock and option gains
+	DividendGrossEUR float64 `json:"dividend_gross_eur"`
+	DividendTaxEUR   float64 `json:"dividend_tax_eur"` // Tax withheld, negative
+	FeesEUR          float64 `json:"fees_eur"`         // Negative
+	DepositsEUR      float64 `json:"deposits_eur"`
+	WithdrawalsEUR   float64 `json:"withdrawals_eur"`   // Negative
+	ContributionsEUR float64 `json:"contributions_eur"` // Deposits less withdrawals
+}
+
+// YearDelta is the change of each figure from one compared year to the next.
+type YearDelta struct {
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	StockGainsEUR    float64 `json:"stock_gains_eur"`
+	OptionGainsEUR   float64 `json:"option_gains_eur"`
+	RealizedGainsEUR float64 `json:"realized_gains_eur"`
+	DividendGrossEUR float64 `json:"dividend_gross_eur"`
+	DividendTaxEUR   float64 `json:"dividend_tax_eur"`
+	FeesEUR          float64 `json:"fees_eur"`
+	DepositsEUR      float64 `json:"deposits_eur"`
+	WithdrawalsEUR   float64 `json:"withdrawals_eur"`
+	ContributionsEUR float64 `json:"contributions_eur"`
+}
+
+// YearComparison sets the figures of several tax years side by side, oldest first, with the change
+// between each year and the one before it.
+type YearComparison struct {
+	BaseCurrency string        `json:"base_currency"` // Currency of the *_eur amounts
+	Years        []YearFigures `json:"years"`
+	Deltas       []YearDelta   `json:"deltas"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Asset classes of an instrument. Several jurisdictions tax ETFs and funds differently from stocks.
+// Instruments that were never looked up have no asset class ("").
+const (
+	AssetClassStock = "STOCK"
+	AssetClassETF   = "ETF"
+	AssetClassFund  = "FUND"
+	AssetClassOther = "OTHER"
+)
+
+// AssetClassFromQuoteType maps the quoteType of a Yahoo Finance search result to an asset class.
+func AssetClassFromQuoteType(quoteType string) string {
+	switch quoteType {
+	case "":
+		return ""
+	case "EQUITY":
+		return AssetClassStock
+	case "ETF":
+		return AssetClassETF
+	case "MUTUALFUND":
+		return AssetClassFund
+	default:
+		return AssetClassOther
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Actions recorded in the audit log.
+const (
+	AuditLogin               = "login"        // Details: provider
+	AuditLoginFailed         = "login_failed" // A wrong password; details: locked_until when it locked the account
+	AuditPasswordChanged     = "password_changed"
+	AuditPasswordReset       = "password_reset"       // Through an emailed reset link
+	AuditLoginMethodAdded    = "login_method_added"   // Details: provider
+	AuditLoginMethodRemoved  = "login_method_removed" // Details: provider
+	AuditTransactionsDeleted = "transactions_deleted" // Details: count
+	AuditReportExported      = "report_exported"      // A report opened through a share link; details: report, year when given, share_link_id
+	AuditShareLinkCreated    = "share_link_created"   // Details: share_link_id, scopes, expires_at
+	AuditShareLinkRevoked    = "share_link_revoked"   // Details: share_link_id
+	AuditCalendarFeedEnabled = "calendar_feed_enabled"
+	AuditCalendarFeedRevoked = "calendar_feed_revoked"
+)
+
+// AuditLogEntry records a security-relevant action on a user's account and where it came from.
+type AuditLogEntry struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"-"`
+	Action    string            `json:"action"`
+	Details   map[string]string `json:"details,omitempty"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// BillingPlan is an entry of the billing catalog.
+type BillingPlan struct {
+	Name          string `json:"name"`
+	DisplayName   string `json:"display_name"`
+	Premium       bool   `json:"premium"` // Unlocks the endpoints reserved to paying users
+	StripePriceID string `json:"-"`
+}
+
+// Subscription is the latest state of a user's subscription with the payment provider.
+type Subscription struct {
+	UserID           int64      `json:"-"`
+	Provider         string     `json:"provider"`
+	CustomerID       string     `json:"-"`
+	SubscriptionID   string     `json:"-"`
+	Plan             string     `json:"plan"`
+	Status           string     `json:"status"` // Provider status, e.g. "active", "past_due", "canceled"
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
+	LastEventCreated int64      `json:"-"` // Creation time (unix seconds) of the last provider event applied
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// CheckoutRequest is the body of POST /billing/checkout.
+type CheckoutRequest struct {
+	Plan string `json:"plan"`
+}
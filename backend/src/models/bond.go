@@ -0,0 +1,34 @@
+package models
+
+// BondIncomeLine is one item of income from bonds: a coupon, the accrued interest paid when buying or
+// received when selling, or the gain of a sale or redemption against the FIFO cost of the nominal.
+type BondIncomeLine struct {
+	Date         string  `json:"date"`
+	TaxYear      string  `json:"tax_year"` // Tax year of the date, under the user's fiscal year
+	ISIN         string  `json:"isin"`
+	ProductName  string  `json:"product_name"`
+	Source       string  `json:"source"`
+	Kind         string  `json:"kind"`              // "coupon", "accrued_interest", "sale" or "redemption"
+	Nominal      int     `json:"nominal,omitempty"` // Face value sold or redeemed
+	Amount       float64 `json:"amount"`            // Cash moved, in the original currency; accrued interest paid is negative
+	Currency     string  `json:"currency"`
+	ProceedsEUR  float64 `json:"proceeds_eur,omitempty"`   // Sales and redemptions only
+	CostBasisEUR float64 `json:"cost_basis_eur,omitempty"` // Sales and redemptions only
+	AmountEUR    float64 `json:"amount_eur"`               // Interest in the base currency, or the gain of a sale or redemption
+}
+
+// BondIncomeYear totals the bond income of one tax year. Interest income is the coupons plus the
+// accrued interest, which is negative where more was paid on purchases than received on sales.
+type BondIncomeYear struct {
+	Year               string  `json:"year"`
+	CouponsEUR         float64 `json:"coupons_eur"`
+	AccruedInterestEUR float64 `json:"accrued_interest_eur"`
+	InterestIncomeEUR  float64 `json:"interest_income_eur"`
+	CapitalGainsEUR    float64 `json:"capital_gains_eur"` // Sales and redemptions
+}
+
+// BondIncomeReport is the response for the bond income endpoint.
+type BondIncomeReport struct {
+	Years []BondIncomeYear `json:"years"` // Oldest first
+	Lines []BondIncomeLine `json:"lines"` // In date order
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// BrokerConnection holds the credentials used to pull statements from a broker automatically.
+type BrokerConnection struct {
+	ID             int64      `json:"-"`
+	UserID         int64      `json:"-"`
+	Broker         string     `json:"broker"`
+	EncryptedToken string     `json:"-"`
+	QueryID        string     `json:"query_id"`
+	LastSyncAt     *time.Time `json:"last_sync_at"`     // When the last sync finished, or started while it runs
+	LastSyncStatus string     `json:"last_sync_status"` // A BrokerSync status, or empty before the first sync
+	LastSyncError  string     `json:"last_sync_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Statuses of a broker connection's last sync.
+const (
+	BrokerSyncRunning = "RUNNING"
+	BrokerSyncOK      = "OK"
+	BrokerSyncFailed  = "FAILED"
+)
@@ -0,0 +1,8 @@
+package models
+
+// CalendarFeed is the state of a user's subscribable calendar of dividends and tax deadlines.
+type CalendarFeed struct {
+	Enabled bool   `json:"enabled"`
+	Token   string `json:"token,omitempty"` // Signed token authorizing the feed, only while enabled
+	URL     string `json:"url,omitempty"`   // Feed URL, with the token, to subscribe to from a calendar app
+}
@@ -20,12 +20,17 @@ type CanonicalTransaction struct {
 	RawText            string    `json:"raw_text"`
 	SourceAmount       float64   `json:"source_amount"`        // The original, unsigned amount from the source file for reference
 	Amount             float64   `json:"amount"`               // The final, correctly signed gross transaction amount in the original currency
-	TransactionType    string    `json:"transaction_type"`     // e.g., "STOCK", "OPTION", "DIVIDEND", "FEE", "CASH"
-	TransactionSubType string    `json:"transaction_sub_type"` // e.g., "CALL", "PUT", "TAX", "DEPOSIT"
+	TransactionType    string    `json:"transaction_type"`     // e.g., "STOCK", "OPTION", "DIVIDEND", "SCRIP_DIVIDEND", "RETURN_OF_CAPITAL", "FEE", "TAX", "CASH", "INTEREST", "BOND"
+	TransactionSubType string    `json:"transaction_sub_type"` // e.g., "CALL", "PUT", "TAX", "DEPOSIT", "WITHDRAWAL", "FX", "STAMP_DUTY", "FTT", "TRADE_TAX", "COUPON", "ACCRUED_INTEREST", "REDEMPTION"
 	BuySell            string    `json:"buy_sell"`             // e.g., "BUY", "SELL"
+	Country            string    `json:"country,omitempty"`    // ISO 3166 alpha-2 country of the instrument, set when the broker exports no ISIN to derive it from
+
+	// Cash balance the statement reports after the row, when it has one (DeGiro's Saldo column)
+	BrokerBalance         float64 `json:"broker_balance"`
+	BrokerBalanceCurrency string  `json:"broker_balance_currency"` // Empty when the statement reports no balance
 
 	// --- Fields to be filled by the Enricher/Processor ---
-	ExchangeRate float64 `json:"exchange_rate"` // Exchange rate to EUR
+	ExchangeRate float64 `json:"exchange_rate"` // Exchange rate to EUR; parsers may set the broker's executed rate, otherwise the ECB rate is used
 	AmountEUR    float64 `json:"amount_eur"`    // Final amount in EUR
 	CountryCode  string  `json:"country_code"`
 	HashId       string  `json:"hash_id"`
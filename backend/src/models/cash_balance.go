@@ -0,0 +1,44 @@
+package models
+
+// Subtypes of the CASH transactions of a broker's money-market fund, such as the one DeGiro keeps
+// uninvested cash in. The units of the fund count as cash in the balance the broker reports.
+const (
+	MoneyMarketConversionSubType  = "MONEY_MARKET_CONVERSION"   // Cash converted into units of the fund or back
+	MoneyMarketPriceChangeSubType = "MONEY_MARKET_PRICE_CHANGE" // Change in the value of the units held
+)
+
+// BrokerBalance is the cash balance a statement reported after one of the user's transactions.
+type BrokerBalance struct {
+	TransactionID int64
+	Date          string // DD-MM-YYYY
+	Source        string
+	Currency      string
+	Balance       Money
+}
+
+// CashBalancePoint is the cash held in one currency at one broker at the end of a day with movements
+// or a balance reported by the broker.
+type CashBalancePoint struct {
+	Date          string   `json:"date"`
+	Change        float64  `json:"change"`                   // Net movement of the day
+	Balance       float64  `json:"balance"`                  // Rebuilt from the transactions, starting from the opening balance
+	BrokerBalance *float64 `json:"broker_balance,omitempty"` // Reported by the broker at the end of the day, when it was
+	Difference    *float64 `json:"difference,omitempty"`     // Broker balance minus the rebuilt balance
+	Discrepancy   bool     `json:"discrepancy"`              // The difference changed since the previous reported balance
+}
+
+// CashBalanceSeries is the running cash balance of one currency at one broker.
+type CashBalanceSeries struct {
+	Source                  string             `json:"source"`
+	Currency                string             `json:"currency"`
+	OpeningBalance          float64            `json:"opening_balance"`                      // Held before the first transaction, from the first reported balance
+	Balance                 float64            `json:"balance"`                              // After the last transaction
+	MoneyMarketPriceChanges float64            `json:"money_market_price_changes,omitempty"` // Net price changes of the broker's money-market fund, included in the balance
+	Points                  []CashBalancePoint `json:"points"`                               // In date order
+}
+
+// CashBalanceReport is the response for the cash balance endpoint.
+type CashBalanceReport struct {
+	Series        []CashBalanceSeries `json:"series"`
+	Discrepancies int                 `json:"discrepancies"` // Points flagged across all series
+}
@@ -0,0 +1,26 @@
+package models
+
+// MonthlyContribution is the money deposited at and withdrawn from the brokers in one month.
+type MonthlyContribution struct {
+	Month          string  `json:"month"` // YYYY-MM
+	DepositsEUR    float64 `json:"deposits_eur"`
+	WithdrawalsEUR float64 `json:"withdrawals_eur"` // Negative
+	NetEUR         float64 `json:"net_eur"`         // Deposits less withdrawals
+	CumulativeEUR  float64 `json:"cumulative_eur"`  // Net contributed up to the end of the month
+	Rolling12EUR   float64 `json:"rolling_12m_eur"` // Net contributed over the twelve months ending with this one
+	RollingAvgEUR  float64 `json:"rolling_avg_eur"` // Rolling12EUR spread over those months, or fewer at the start
+}
+
+// ContributionReport sums up the money a user put into and took out of their brokers.
+type ContributionReport struct {
+	BaseCurrency            string                `json:"base_currency"` // Currency of the *_eur amounts
+	Months                  []MonthlyContribution `json:"months"`        // Every month from the first movement to the current one
+	TotalDepositedEUR       float64               `json:"total_deposited_eur"`
+	TotalWithdrawnEUR       float64               `json:"total_withdrawn_eur"` // Negative
+	NetContributedEUR       float64               `json:"net_contributed_eur"`
+	AverageMonthlyEUR       float64               `json:"average_monthly_eur"`          // Net contributed per month since the first movement
+	AverageMonthlyLast12EUR float64               `json:"average_monthly_last_12m_eur"` // Net contributed per month over the last twelve months
+	MonthsWithContributions int                   `json:"months_with_contributions"`    // Months in which more was deposited than withdrawn
+	LargestDeposit          *CashMovement         `json:"largest_deposit,omitempty"`
+	LargestWithdrawal       *CashMovement         `json:"largest_withdrawal,omitempty"`
+}
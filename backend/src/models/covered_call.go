@@ -0,0 +1,16 @@
+package models
+
+// CoveredCall links shares of a stock lot to a short call written against them while the lot was held,
+// so an assignment can be matched to the cost basis of the lot that covered it.
+type CoveredCall struct {
+	ISIN               string  `json:"isin"` // Underlying
+	OptionProductName  string  `json:"option_product_name"`
+	OptionOpenDate     string  `json:"option_open_date"`
+	OptionOpenOrderID  string  `json:"option_open_order_id"`
+	OptionCloseDate    string  `json:"option_close_date,omitempty"`     // Empty while the call is open
+	OptionCloseOrderID string  `json:"option_close_order_id,omitempty"` // "EXPIRED" for calls that expired worthless
+	LotBuyDate         string  `json:"lot_buy_date"`
+	LotBuyPrice        float64 `json:"lot_buy_price"`
+	LotCostPerShareEUR float64 `json:"lot_cost_per_share_eur"`
+	Shares             int     `json:"shares"` // Shares of the lot set aside for the call
+}
@@ -0,0 +1,31 @@
+package models
+
+// Row types a generic CSV mapping can assign to the values of its type column.
+const (
+	CSVTypeBuy             = "BUY"
+	CSVTypeSell            = "SELL"
+	CSVTypeDividend        = "DIVIDEND"
+	CSVTypeDividendTax     = "DIVIDEND_TAX"
+	CSVTypeReturnOfCapital = "RETURN_OF_CAPITAL"
+	CSVTypeFee             = "FEE"
+	CSVTypeDeposit         = "DEPOSIT"
+	CSVTypeWithdrawal      = "WITHDRAWAL"
+)
+
+// CSVMapping describes how to read a CSV export from a broker without a dedicated parser.
+// Columns are referenced by their header name, compared case-insensitively.
+type CSVMapping struct {
+	DateColumn       string            `json:"date_column"`
+	DateFormat       string            `json:"date_format,omitempty"` // e.g. "DD-MM-YYYY" or "YYYY-MM-DD HH:mm:ss"
+	TypeColumn       string            `json:"type_column"`
+	AmountColumn     string            `json:"amount_column"`
+	ProductColumn    string            `json:"product_column,omitempty"`
+	ISINColumn       string            `json:"isin_column,omitempty"`
+	QuantityColumn   string            `json:"quantity_column,omitempty"`
+	PriceColumn      string            `json:"price_column,omitempty"`
+	CurrencyColumn   string            `json:"currency_column,omitempty"`
+	CommissionColumn string            `json:"commission_column,omitempty"`
+	OrderIDColumn    string            `json:"order_id_column,omitempty"`
+	DefaultCurrency  string            `json:"default_currency,omitempty"` // Used when there is no currency column
+	TypeValues       map[string]string `json:"type_values"`                // Value in the type column -> one of the CSVType constants
+}
@@ -0,0 +1,21 @@
+package models
+
+// DataQualityCheck is the result of a single completeness check.
+type DataQualityCheck struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count"`     // Number of affected transactions
+	Score    float64  `json:"score"`     // Points earned by this check
+	MaxScore float64  `json:"max_score"` // Points available for this check
+	Examples []string `json:"examples"`  // A few affected rows to help the user find them
+}
+
+// DataQualityReport scores how complete a user's data is for a given tax year.
+type DataQualityReport struct {
+	Year               string             `json:"year"`
+	Score              float64            `json:"score"` // 0-100, where 100 means no issues were found
+	TotalTransactions  int                `json:"total_transactions"`
+	ReconciliationGap  float64            `json:"reconciliation_gap_eur"` // Sale proceeds not matched to any purchase lot
+	UndatedSkippedRows int                `json:"undated_skipped_rows"`   // Quarantined rows with no date to tell their year, not counted in any year
+	Checks             []DataQualityCheck `json:"checks"`
+	Actions            []string           `json:"actions"` // Concrete steps to fix the issues found
+}
@@ -0,0 +1,26 @@
+package models
+
+// DeemedDisposal is a synthetic disposal of ETF units still held on a multiple of eight years after
+// they were bought. Under Irish rules the units are taxed as if sold and bought back at market value.
+type DeemedDisposal struct {
+	Date           string   `json:"date"`     // Anniversary of the purchase, DD-MM-YYYY
+	TaxYear        string   `json:"tax_year"` // Tax year of the anniversary, under the user's fiscal year
+	YearsHeld      int      `json:"years_held"`
+	ISIN           string   `json:"isin"`
+	ProductName    string   `json:"product_name"`
+	BuyDate        string   `json:"buy_date"`
+	Quantity       int      `json:"quantity"`
+	CostBasisEUR   float64  `json:"cost_basis_eur"`   // Purchase cost, or the market value at the previous deemed disposal
+	PriceEUR       *float64 `json:"price_eur"`        // Null when no price is available for the anniversary
+	MarketValueEUR *float64 `json:"market_value_eur"` // Deemed sale proceeds
+	GainEUR        *float64 `json:"gain_eur"`         // Market value minus cost basis; negative for a loss
+	PriceStatus    string   `json:"price_status"`     // "OK" or "UNAVAILABLE"
+}
+
+// DeemedDisposalReport is the response for the deemed disposals endpoint.
+type DeemedDisposalReport struct {
+	Enabled       bool               `json:"enabled"`
+	GainByTaxYear map[string]float64 `json:"gain_by_tax_year"` // Only includes priced events
+	PriceStatus   string             `json:"price_status"`     // "OK", "PARTIAL" or "UNAVAILABLE"
+	Events        []DeemedDisposal   `json:"events"`
+}
@@ -9,3 +9,28 @@ type DividendCountrySummary struct {
 // DividendTaxResult represents the final structure for the dividend tax summary endpoint.
 // map[Year]map[Country]DividendCountrySummary
 type DividendTaxResult map[string]map[string]DividendCountrySummary
+
+// DividendDetailLine is one transaction behind a dividend tax summary figure: a gross dividend or the
+// tax withheld from one.
+type DividendDetailLine struct {
+	TransactionID    int64   `json:"transaction_id"`
+	Date             string  `json:"date"`
+	ISIN             string  `json:"isin"`
+	ProductName      string  `json:"product_name"`
+	Source           string  `json:"source"`
+	Kind             string  `json:"kind"`               // "gross" or "tax"
+	Amount           float64 `json:"amount"`             // In the original currency; tax is negative
+	Currency         string  `json:"currency"`           // Original currency
+	ExchangeRate     float64 `json:"exchange_rate"`      // Units of the original currency per unit of the base currency
+	ExchangeRateDate string  `json:"exchange_rate_date"` // Day of the ECB rate used, DD-MM-YYYY; empty for broker-executed rates
+	AmountEUR        float64 `json:"amount_eur"`         // Amount in the base currency, as added to the summary
+}
+
+// DividendDetail lists the transactions adding up to one year and country of the dividend tax summary.
+type DividendDetail struct {
+	Year         string               `json:"year"`
+	Country      string               `json:"country"`
+	GrossAmt     float64              `json:"gross_amt"`
+	TaxedAmt     float64              `json:"taxed_amt"`
+	Transactions []DividendDetailLine `json:"transactions"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// DividendCalendarEntry is the dividend expected from one instrument in a given month.
+type DividendCalendarEntry struct {
+	ISIN                   string  `json:"isin"`
+	ProductName            string  `json:"product_name"`
+	ExpectedGrossEUR       float64 `json:"expected_gross_eur"`
+	ExpectedWithholdingEUR float64 `json:"expected_withholding_eur"` // Negative, based on the tax withheld on the reference payment
+	ExpectedNetEUR         float64 `json:"expected_net_eur"`
+	BasedOn                string  `json:"based_on"` // Date of the payment the projection repeats (DD-MM-YYYY)
+}
+
+// DividendCalendarMonth groups the projected dividends of one month.
+type DividendCalendarMonth struct {
+	Month         string                  `json:"month"` // YYYY-MM
+	TotalGrossEUR float64                 `json:"total_gross_eur"`
+	TotalNetEUR   float64                 `json:"total_net_eur"`
+	Entries       []DividendCalendarEntry `json:"entries"`
+}
+
+// DividendCalendar is the response for the dividend calendar endpoint.
+type DividendCalendar struct {
+	From          string                  `json:"from"` // First projected month, YYYY-MM
+	To            string                  `json:"to"`   // Last projected month, YYYY-MM
+	TotalGrossEUR float64                 `json:"total_gross_eur"`
+	TotalNetEUR   float64                 `json:"total_net_eur"`
+	Months        []DividendCalendarMonth `json:"months"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// OpenLot is the unmatched remainder of a purchase, as kept by the FIFO matcher between runs.
+// Short lots reuse it for the uncovered remainder of a sale: the fields then describe the sale.
+type OpenLot struct {
+	Date             string
+	ProductName      string
+	ISIN             string
+	Quantity         int // Shares still open
+	OriginalQuantity int // Shares bought, used to pro-rate Amount and AmountEUR
+	Price            Money
+	Amount           Money
+	AmountEUR        Money
+	Currency         string
+	ExchangeRate     float64
+	ExchangeRateDate string
+	Commission       Money // Buy commission not yet charged to a sale
+	TransactionTax   Money // Buy transaction tax not yet charged to a sale
+}
+
+// StockFIFOState is the FIFO state after the last processed stock transaction. It lets later
+// uploads resume matching instead of replaying the whole history.
+type StockFIFOState struct {
+	LastDate  string               // Date of the last stock transaction processed, DD-MM-YYYY
+	LastYear  int                  // Year of the last holdings snapshot
+	OpenLots  map[string][]OpenLot // Keyed by ISIN, oldest lot first
+	ShortLots map[string][]OpenLot // Open short positions, keyed by ISIN, oldest first
+}
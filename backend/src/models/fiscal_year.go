@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FiscalYear is the boundary of a user's tax years: the day and month each one starts on. Tax years
+// are labelled with the calendar year they start in, so with a 06-04 start, 10-01-2025 falls in 2024.
+// The zero value is the calendar year.
+type FiscalYear struct {
+	StartDay   int
+	StartMonth time.Month
+}
+
+// CalendarYear is the default fiscal year, starting on 1 January.
+var CalendarYear = FiscalYear{StartDay: 1, StartMonth: time.January}
+
+// ParseFiscalYearStart parses a DD-MM start date. 29 February is refused since it does not occur every year.
+func ParseFiscalYearStart(value string) (FiscalYear, error) {
+	start, err := time.Parse("02-01", value)
+	if err != nil || (start.Day() == 29 && start.Month() == time.February) {
+		return FiscalYear{}, fmt.Errorf("invalid fiscal year start %q, expected DD-MM", value)
+	}
+	return FiscalYear{StartDay: start.Day(), StartMonth: start.Month()}, nil
+}
+
+// String returns the start date as DD-MM.
+func (f FiscalYear) String() string {
+	f = f.normalized()
+	return fmt.Sprintf("%02d-%02d", f.StartDay, int(f.StartMonth))
+}
+
+// YearOf returns the tax year the date falls in.
+func (f FiscalYear) YearOf(date time.Time) int {
+	f = f.normalized()
+	start := time.Date(date.Year(), f.StartMonth, f.StartDay, 0, 0, 0, 0, date.Location())
+	if date.Before(start) {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// Label returns YearOf as a string, the key reports bucket results by.
+func (f FiscalYear) Label(date time.Time) string {
+	return strconv.Itoa(f.YearOf(date))
+}
+
+func (f FiscalYear) normalized() FiscalYear {
+	if f.StartMonth == 0 {
+		return CalendarYear
+	}
+	return f
+}
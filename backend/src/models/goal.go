@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Kinds of goal.
+const (
+	GoalPortfolioValue      = "portfolio_value"      // Reach a portfolio market value of TargetAmount, by TargetDate when set
+	GoalMonthlyContribution = "monthly_contribution" // Contribute at least TargetAmount every month
+)
+
+// Goal is an investment target the user measures their portfolio and contributions against.
+type Goal struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"-"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"`
+	TargetAmount float64   `json:"target_amount"`         // In the base currency
+	TargetDate   string    `json:"target_date,omitempty"` // YYYY-MM-DD, for portfolio_value goals
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GoalProgress is a goal with how far the user is from it.
+type GoalProgress struct {
+	Goal
+	CurrentAmount   float64 `json:"current_amount"`   // Market value of the portfolio, or net contributed this month
+	Progress        float64 `json:"progress"`         // CurrentAmount as a fraction of TargetAmount
+	RemainingAmount float64 `json:"remaining_amount"` // Left to reach the target; 0 once it is
+	Achieved        bool    `json:"achieved"`
+	// Of portfolio_value goals with a target date
+	MonthsLeft      *int     `json:"months_left,omitempty"`      // Months after this one up to the target date's
+	RequiredMonthly *float64 `json:"required_monthly,omitempty"` // To contribute in each month left to reach the target without market gains
+	ProjectedAmount *float64 `json:"projected_amount,omitempty"` // Market value plus the average monthly contribution of the last 12 months for each month left
+	OnTrack         *bool    `json:"on_track,omitempty"`         // ProjectedAmount reaches the target
+	// Of monthly_contribution goals
+	StreakMonths    *int `json:"streak_months,omitempty"`       // Consecutive months before this one in which the target was contributed
+	MonthsMetLast12 *int `json:"months_met_last_12m,omitempty"` // Of the 12 months before this one, those in which it was
+}
+
+// Dashboard sums up the user's portfolio and measures their goals against it.
+type Dashboard struct {
+	AsOf                    string         `json:"as_of"`
+	BaseCurrency            string         `json:"base_currency"`       // Currency of the *_eur amounts and of the goals
+	PortfolioValueEUR       float64        `json:"portfolio_value_eur"` // Market value of the open positions with a price
+	CostBasisEUR            float64        `json:"cost_basis_eur"`      // Of the same positions
+	PriceStatus             string         `json:"price_status"`        // "OK", "PARTIAL" or "UNAVAILABLE"
+	NetContributedEUR       float64        `json:"net_contributed_eur"`
+	ContributedThisMonthEUR float64        `json:"contributed_this_month_eur"`
+	AverageMonthlyLast12EUR float64        `json:"average_monthly_last_12m_eur"`
+	Goals                   []GoalProgress `json:"goals"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Values of the scope parameter of the reports that can be combined across a household.
+const (
+	ReportScopeUser      = "user" // The default
+	ReportScopeHousehold = "household"
+)
+
+// HouseholdMember is one of the users grouped in a household.
+type HouseholdMember struct {
+	UserID   int64     `json:"-"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// HouseholdInvitation asks the owner of an email address to join the inviter's household.
+type HouseholdInvitation struct {
+	ID              int64     `json:"id"`
+	InviterID       int64     `json:"-"`
+	InviterUsername string    `json:"inviter_username"`
+	InviteeEmail    string    `json:"invitee_email"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// Household is the state of a user's household: its members, none when the user is not in one, and
+// the pending invitations the user sent or received.
+type Household struct {
+	Members             []HouseholdMember     `json:"members"`
+	SentInvitations     []HouseholdInvitation `json:"sent_invitations"`
+	ReceivedInvitations []HouseholdInvitation `json:"received_invitations"`
+}
+
+// HouseholdMemberReport is the annual report of one household member.
+type HouseholdMemberReport struct {
+	Username string        `json:"username"`
+	Report   *AnnualReport `json:"report"`
+}
+
+// HouseholdAnnualReport is the annual report of a household: the line items of every member together,
+// with their totals, followed by each member's own report.
+type HouseholdAnnualReport struct {
+	Combined *AnnualReport           `json:"combined"`
+	Members  []HouseholdMemberReport `json:"members"`
+}
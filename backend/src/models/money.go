@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in fixed point, counted in millionths of the currency unit. Sums and differences
+// of amounts are exact, so splitting an amount over several FIFO matches or commissions adds back up
+// to it; float64 is only used for exchange rates and when amounts are reported.
+//
+// Amounts are JSON numbers and are stored in REAL columns, both of which carry the six decimals exactly.
+type Money int64
+
+// MoneyScale is the number of Money units in one unit of currency.
+const MoneyScale = 1_000_000
+
+// NewMoney converts a float64 amount, rounding it half away from zero to the nearest millionth.
+func NewMoney(amount float64) Money {
+	return Money(math.Round(amount * MoneyScale))
+}
+
+// Float64 returns the amount as a float64, for reporting and for calculations that are not amounts.
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// Abs returns the amount without its sign.
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// MulDiv returns the amount times num divided by den, rounded half away from zero, such as the share
+// of a lot's cost that matched shares carry. It returns 0 when den is 0.
+func (m Money) MulDiv(num, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num))
+	divisor := big.NewInt(den)
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+	if new(big.Int).Lsh(remainder.Abs(remainder), 1).Cmp(divisor.Abs(divisor)) >= 0 {
+		// Round away from zero: the sign of the exact result is that of product/den.
+		if product.Sign()*big.NewInt(den).Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return Money(quotient.Int64())
+}
+
+// Allocate returns the share of the amount that part of total units carry, with allocated being the
+// units already given their share. Shares are rounded so that, once every unit has been allocated,
+// they add up to the amount exactly: each share is the rounded cumulative amount minus what the units
+// before it got.
+func (m Money) Allocate(allocated, part, total int64) Money {
+	return m.MulDiv(allocated+part, total) - m.MulDiv(allocated, total)
+}
+
+// Mul returns the amount times a factor that is not a ratio of whole numbers, such as a price, rounded
+// to the nearest millionth.
+func (m Money) Mul(factor float64) Money {
+	return NewMoney(m.Float64() * factor)
+}
+
+// Convert returns the amount in another currency, given the units of the amount's currency one unit
+// of the other buys (the ECB's convention: a rate of 1.08 turns 108 USD into 100 EUR).
+func (m Money) Convert(rate float64) Money {
+	return NewMoney(m.Float64() / rate)
+}
+
+// String formats the amount with the decimals it has, as in 12.5 or -0.000001.
+func (m Money) String() string {
+	sign := ""
+	units := int64(m)
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	whole, fraction := units/MoneyScale, units%MoneyScale
+	if fraction == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	decimals := strings.TrimRight(fmt.Sprintf("%06d", fraction), "0")
+	return sign + strconv.FormatInt(whole, 10) + "." + decimals
+}
+
+// MarshalJSON encodes the amount as a JSON number with its exact decimals.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number, or null as zero.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || len(data) == 0 {
+		*m = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	*m = NewMoney(f)
+	return nil
+}
+
+// Value stores the amount as a REAL.
+func (m Money) Value() (driver.Value, error) {
+	return m.Float64(), nil
+}
+
+// Scan reads an amount stored as a REAL, an INTEGER or text. NULL reads as zero.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case float64:
+		*m = NewMoney(v)
+	case int64:
+		*m = Money(v * MoneyScale)
+	case []byte:
+		return m.UnmarshalJSON(v)
+	case string:
+		return m.UnmarshalJSON([]byte(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
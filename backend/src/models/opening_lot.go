@@ -0,0 +1,23 @@
+package models
+
+// OpeningBalanceSource and OpeningBalanceSubType mark the synthetic purchases created from opening lots.
+const (
+	OpeningBalanceSource  = "opening_balance"
+	OpeningBalanceSubType = "OPENING_BALANCE"
+)
+
+// OpeningLot is a position transferred in from another broker, entered by the user with its cost basis
+// so later sales can be matched against it.
+type OpeningLot struct {
+	ISIN        string  `json:"isin"`
+	ProductName string  `json:"product_name"`
+	Quantity    int     `json:"quantity"`
+	BuyDate     string  `json:"buy_date"`   // DD-MM-YYYY
+	CostBasis   float64 `json:"cost_basis"` // Total paid for the lot, commissions included, in Currency
+	Currency    string  `json:"currency"`   // Defaults to EUR
+}
+
+// OpeningLotsRequest is the body of POST /holdings/opening-lots.
+type OpeningLotsRequest struct {
+	Lots []OpeningLot `json:"lots"`
+}
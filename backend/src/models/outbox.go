@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Outbox email statuses.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed" // Rejected by the provider, or out of attempts
+)
+
+// OutboxEmail is an email stored before it is sent. Its bodies hold the links the email carries, such
+// as password reset tokens, so they are never returned by the API.
+type OutboxEmail struct {
+	ID            int64      `json:"id"`
+	Kind          string     `json:"kind"` // Template name, e.g. verification or passwordReset
+	ToEmail       string     `json:"to_email"`
+	Subject       string     `json:"subject"`
+	TextBody      string     `json:"-"`
+	HTMLBody      string     `json:"-"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"` // Only meaningful while pending
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
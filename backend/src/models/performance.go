@@ -0,0 +1,43 @@
+package models
+
+// PerformanceMetrics holds the return figures for a single instrument or for the whole portfolio.
+type PerformanceMetrics struct {
+	ISIN                string   `json:"isin,omitempty"`
+	ProductName         string   `json:"product_name,omitempty"`
+	StartValueEUR       float64  `json:"start_value_eur"`       // Market value (or cost basis when no price is available) at the start of the period
+	EndValueEUR         float64  `json:"end_value_eur"`         // Market value (or cost basis when no price is available) at the end
+	NetInvestedEUR      float64  `json:"net_invested_eur"`      // Money put in during the period: buys minus sales and dividends, or deposits minus withdrawals
+	DividendsEUR        float64  `json:"dividends_eur"`         // Dividends received during the period
+	MoneyWeightedReturn *float64 `json:"money_weighted_return"` // Annualised XIRR, null if it cannot be computed
+	ModifiedDietzReturn *float64 `json:"modified_dietz_return"` // Modified Dietz return for the period, null if it cannot be computed
+	PriceStatus         string   `json:"price_status"`          // "OK" if all end values use live prices, otherwise "PARTIAL" or "UNAVAILABLE"
+	StartPriceStatus    string   `json:"start_price_status"`    // "OK" if all start values use prices on the start date, otherwise "PARTIAL" or "COST_BASIS"
+}
+
+// Contributions the portfolio returns are measured against.
+const (
+	FlowBasisTrades        = "trades"         // Buys and sales; no deposits or withdrawals were imported
+	FlowBasisCashMovements = "cash_movements" // Deposits and withdrawals; the portfolio includes its cash
+)
+
+// BenchmarkPerformance holds the price return of the benchmark index over the same period.
+type BenchmarkPerformance struct {
+	ISIN             string   `json:"isin"`
+	StartPriceEUR    float64  `json:"start_price_eur"`
+	EndPriceEUR      float64  `json:"end_price_eur"`
+	Return           *float64 `json:"return"`            // Price return over the period, null if prices are unavailable
+	AnnualizedReturn *float64 `json:"annualized_return"` // Comparable with the money-weighted return
+	ExcessReturn     *float64 `json:"excess_return"`     // Portfolio Modified Dietz return minus the benchmark return
+	Status           string   `json:"status"`            // "OK" or "UNAVAILABLE"
+}
+
+// PerformanceResult is the response for the performance endpoint.
+type PerformanceResult struct {
+	Period    string                `json:"period"`
+	StartDate string                `json:"start_date"`
+	EndDate   string                `json:"end_date"`
+	FlowBasis string                `json:"flow_basis"` // FlowBasisTrades or FlowBasisCashMovements
+	Portfolio PerformanceMetrics    `json:"portfolio"`
+	ByISIN    []PerformanceMetrics  `json:"by_isin"`
+	Benchmark *BenchmarkPerformance `json:"benchmark,omitempty"`
+}
@@ -7,19 +7,34 @@ type SaleDetail struct {
 	ProductName      string
 	ISIN             string
 	Quantity         int
-	SalePrice        float64
-	SaleAmount       float64 // Sale amount in original currency
+	SalePrice        Money
+	SaleAmount       Money // Sale amount in original currency
 	SaleCurrency     string
-	SaleAmountEUR    float64 // Sale amount in EUR
-	BuyPrice         float64
-	BuyAmount        float64 // Purchase amount in original currency
+	SaleAmountEUR    Money // Sale amount in EUR
+	BuyPrice         Money
+	BuyAmount        Money   // Purchase amount in original currency
 	BuyExchangeRate  float64 // Exchange rate used for the buy transaction
-	Commission       float64 // Commission/fees
+	Commission       Money   // Commission/fees
+	TransactionTax   Money   // Stamp duty / FTT paid on the buy and sale, in EUR
 	BuyCurrency      string
-	BuyAmountEUR     float64 // Purchase amount in EUR
+	BuyAmountEUR     Money   // Purchase amount in EUR
 	SaleExchangeRate float64 // Exchange rate used for the sale transaction
-	Delta            float64 // Profit/Loss (SaleAmountEUR - BuyAmountEUR)
+	Delta            Money   // Profit/Loss (SaleAmountEUR - BuyAmountEUR), before commissions and taxes
 	CountryCode      string  `json:"country_code"` // Country code derived from ISIN (e.g., "840 - United States of America (the)")
+	AssetClass       string  `json:"asset_class"`  // STOCK, ETF, FUND or OTHER; empty when unknown
+	TaxYear          string  `json:"tax_year"`     // Tax year the gain is realised in, under the user's fiscal year
+
+	// Days of the ECB rates used for the buy and the sale, DD-MM-YYYY; empty for broker-executed rates
+	BuyExchangeRateDate  string `json:"buy_exchange_rate_date"`
+	SaleExchangeRateDate string `json:"sale_exchange_rate_date"`
+
+	// How the user's tax residence treats the gain, set from its holding period rules
+	HoldingDays      int     `json:"holding_days"`
+	HeldOver24Months bool    `json:"held_over_24_months"` // Held for more than 24 months, counted in calendar months
+	HoldingRule      string  `json:"holding_rule"`        // Rule the holding period falls under, e.g. "PT_SHORT_TERM"; empty for none
+	InclusionRate    float64 `json:"inclusion_rate"`      // Share of the gain that is taxable
+	GainEUR          Money   `json:"gain_eur"`            // Delta less commissions and transaction taxes
+	TaxableGainEUR   Money   `json:"taxable_gain_eur"`    // GainEUR times InclusionRate, in cents
 }
 
 // PurchaseLot represents remaining unsold purchase lots for stocks.
@@ -32,6 +47,23 @@ type PurchaseLot struct {
 	BuyAmount    float64 `json:"buy_amount"`     // Purchase amount in original currency
 	BuyCurrency  string  `json:"buy_currency"`   // Original purchase currency
 	BuyAmountEUR float64 `json:"buy_amount_eur"` // Purchase amount in EUR
+	AssetClass   string  `json:"asset_class"`    // STOCK, ETF, FUND or OTHER; empty when unknown
+	// Short calls written against the lot, when it was held at the time
+	CoveredCalls []CoveredCall `json:"covered_calls,omitempty"`
+}
+
+// CostBasisAdjustment records how a return of capital distribution lowered the cost basis of an open lot.
+// Costs are positive amounts in EUR.
+type CostBasisAdjustment struct {
+	Date          string  `json:"date"` // Date of the distribution
+	ISIN          string  `json:"isin"`
+	ProductName   string  `json:"product_name"`
+	BuyDate       string  `json:"buy_date"`             // Purchase date of the adjusted lot, empty when no lot was open
+	Quantity      int     `json:"quantity"`             // Shares of the lot open on the distribution date
+	AmountEUR     float64 `json:"amount_eur"`           // Share of the distribution allocated to the lot
+	CostBeforeEUR float64 `json:"cost_before_eur"`      // Cost basis of the open shares before the adjustment
+	CostAfterEUR  float64 `json:"cost_after_eur"`       // Cost basis of the open shares after the adjustment
+	ExcessEUR     float64 `json:"excess_eur,omitempty"` // Part of the allocation the lot could not absorb, as its cost basis reached zero
 }
 
 // OptionSaleDetail represents the details of a closed option position (buy/sell pair).
@@ -51,8 +83,11 @@ type OptionSaleDetail struct {
 	Commission     float64 `json:"commission"`       // Total commission for the round trip (or allocated portion)
 	Delta          float64 `json:"delta"`            // Profit/Loss (CloseAmountEUR - OpenAmountEUR for long, OpenAmountEUR - CloseAmountEUR for short)
 	OpenOrderID    string  `json:"open_order_id"`    // Optional: Order ID of the opening transaction
-	CloseOrderID   string  `json:"close_order_id"`   // Optional: Order ID of the closing transaction
+	CloseOrderID   string  `json:"close_order_id"`   // Optional: Order ID of the closing transaction, "EXPIRED" for positions that expired worthless
 	CountryCode    string  `json:"country_code"`     // Country code derived from ISIN (e.g., "840 - United States of America (the)")
+	TaxYear        string  `json:"tax_year"`         // Tax year of the close date, under the user's fiscal year
+	// Stock lots covering a short call when it was written; empty for other positions and naked calls
+	CoveredLots []CoveredCall `json:"covered_lots,omitempty"`
 }
 
 // OptionHolding represents an open option position (either long or short).
@@ -65,4 +100,6 @@ type OptionHolding struct {
 	OpenCurrency  string  `json:"open_currency"`
 	OpenAmountEUR float64 `json:"open_amount_eur"` // Open amount in EUR
 	OpenOrderID   string  `json:"open_order_id"`   // Optional: Order ID of the opening transaction
+	// Stock lots covering a short call when it was written; empty for other positions and naked calls
+	CoveredLots []CoveredCall `json:"covered_lots,omitempty"`
 }
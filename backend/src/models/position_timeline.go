@@ -0,0 +1,29 @@
+package models
+
+// PositionEvent is one transaction in the history of a position, with the position as it stood after it.
+type PositionEvent struct {
+	TransactionID   int64   `json:"transaction_id"`
+	Date            string  `json:"date"`
+	Type            string  `json:"type"`     // BUY, SELL, DIVIDEND, WITHHOLDING_TAX, SCRIP_DIVIDEND or RETURN_OF_CAPITAL
+	Quantity        int     `json:"quantity"` // Shares bought, sold or received; 0 for cash events
+	Price           float64 `json:"price"`
+	Amount          float64 `json:"amount"` // In the original currency, signed as stored: purchases are negative
+	Currency        string  `json:"currency"`
+	AmountEUR       float64 `json:"amount_eur"`
+	Commission      float64 `json:"commission"`
+	RealizedGainEUR float64 `json:"realized_gain_eur"` // Gain of the lots the event closed, before commissions; 0 when it closed none
+	RunningQuantity int     `json:"running_quantity"`  // Shares held after the event; negative for a short position
+	RunningCostEUR  float64 `json:"running_cost_eur"`  // Cost basis of the lots still open after the event
+}
+
+// PositionTimeline is the chronological history of one instrument.
+type PositionTimeline struct {
+	ISIN            string          `json:"isin"`
+	ProductName     string          `json:"product_name"`
+	Quantity        int             `json:"quantity"`          // Shares held now
+	CostEUR         float64         `json:"cost_eur"`          // Cost basis of the lots open now
+	RealizedGainEUR float64         `json:"realized_gain_eur"` // Sum of the events' realized gains
+	DividendsEUR    float64         `json:"dividends_eur"`     // Gross dividends, in cash or in shares
+	WithheldEUR     float64         `json:"withheld_eur"`      // Tax withheld on the dividends, negative
+	Events          []PositionEvent `json:"events"`
+}
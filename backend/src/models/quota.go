@@ -0,0 +1,22 @@
+package models
+
+// Plan names.
+const (
+	PlanFree    = "free"
+	PlanPremium = "premium"
+)
+
+// Plan holds the usage limits of a subscription plan. A limit of zero means unlimited.
+type Plan struct {
+	Name            string `json:"name"`
+	UploadsPerMonth int    `json:"uploads_per_month"`
+	MaxTransactions int    `json:"max_transactions"`
+}
+
+// Usage reports a user's consumption against the limits of their plan.
+type Usage struct {
+	Plan               Plan   `json:"plan"`
+	Month              string `json:"month"` // YYYY-MM
+	UploadsThisMonth   int    `json:"uploads_this_month"`
+	StoredTransactions int    `json:"stored_transactions"`
+}
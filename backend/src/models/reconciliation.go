@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// ReconciliationPosition compares the quantity an instrument's trades add up to with the quantity
+// left in the rebuilt holdings. Uncovered short sales also show up here, as a negative net quantity.
+type ReconciliationPosition struct {
+	ISIN           string `json:"isin"`
+	ProductName    string `json:"product_name"`
+	BoughtQuantity int    `json:"bought_quantity"` // Includes shares received as scrip dividends
+	SoldQuantity   int    `json:"sold_quantity"`
+	NetQuantity    int    `json:"net_quantity"`  // Bought minus sold
+	HeldQuantity   int    `json:"held_quantity"` // Open lots in the latest holdings snapshot
+	Difference     int    `json:"difference"`    // Net minus held
+}
+
+// UnmatchedSell is a sale whose quantity could not be fully matched to purchase lots.
+type UnmatchedSell struct {
+	Date            string `json:"date"`
+	ISIN            string `json:"isin"`
+	ProductName     string `json:"product_name"`
+	SoldQuantity    int    `json:"sold_quantity"`
+	MatchedQuantity int    `json:"matched_quantity"`
+}
+
+// NegativeLot is an open lot or sale with a quantity of zero or less, which FIFO matching never produces
+// from consistent data.
+type NegativeLot struct {
+	Year        string `json:"year,omitempty"` // Holdings snapshot the lot was found in; empty for sales
+	Date        string `json:"date"`
+	ISIN        string `json:"isin"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ReconciliationReport is the outcome of rebuilding a user's derived data from their transactions.
+type ReconciliationReport struct {
+	UserID           int64                    `json:"user_id"`
+	RecalculatedAt   time.Time                `json:"recalculated_at"`
+	TransactionCount int                      `json:"transaction_count"`
+	StockSaleCount   int                      `json:"stock_sale_count"`
+	OK               bool                     `json:"ok"` // True when no check below found anything
+	Mismatches       []ReconciliationPosition `json:"mismatches"`
+	UnmatchedSells   []UnmatchedSell          `json:"unmatched_sells"`
+	NegativeLots     []NegativeLot            `json:"negative_lots"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// SearchResult is a transaction found by a search, with what kind of transaction it is and the fields
+// the search terms were found in.
+type SearchResult struct {
+	Type          string               `json:"type"`           // trade, dividend, fee, tax, cash, interest or bond
+	MatchedFields []string             `json:"matched_fields"` // product_name, isin, description, order_id
+	Transaction   ProcessedTransaction `json:"transaction"`
+}
+
+// SearchResponse lists the results of a search, newest first.
+type SearchResponse struct {
+	Query     string         `json:"query"`
+	Results   []SearchResult `json:"results"`
+	Truncated bool           `json:"truncated"` // More transactions matched than the limit
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Reports a share link can open.
+const (
+	ShareScopeAnnualReport = "annual_report" // GET /shared/{token}/reports/annual
+	ShareScopeTaxReport    = "tax_report"    // GET /shared/{token}/tax-report
+	ShareScopeDividends    = "dividends"     // GET /shared/{token}/dividends/detail
+	ShareScopeHoldings     = "holdings"      // GET /shared/{token}/holdings/stocks
+)
+
+// ShareScopes lists every scope a share link can be given.
+var ShareScopes = []string{ShareScopeAnnualReport, ShareScopeTaxReport, ShareScopeDividends, ShareScopeHoldings}
+
+// ShareLink grants whoever holds its token read-only access to some of a user's reports until it
+// expires, for instance to hand to an accountant.
+type ShareLink struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	TokenHash  string     `json:"-"`
+	Token      string     `json:"token,omitempty"` // Returned only when the link is created
+	Label      string     `json:"label"`
+	Scopes     []string   `json:"scopes"`
+	Year       string     `json:"year,omitempty"` // Only tax year the link opens; empty for every year
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
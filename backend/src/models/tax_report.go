@@ -0,0 +1,31 @@
+package models
+
+// TaxCategory is one kind of income as a jurisdiction taxes it, such as Portuguese category E dividends,
+// with the tax due on it for the year.
+type TaxCategory struct {
+	Code                string  `json:"code"` // Stable identifier, e.g. "PT_DIVIDENDS"
+	Label               string  `json:"label"`
+	IncomeEUR           float64 `json:"income_eur"`             // Net gains, or gross income, before exemptions
+	ExemptEUR           float64 `json:"exempt_eur"`             // Part of the income left untaxed by an exemption
+	TaxableEUR          float64 `json:"taxable_eur"`            // Never negative: losses are not taxed
+	Rate                float64 `json:"rate"`                   // Rate applied, e.g. 0.28; the average rate of progressive scales
+	TaxEUR              float64 `json:"tax_eur"`                // Tax due after foreign tax credits
+	ForeignTaxCreditEUR float64 `json:"foreign_tax_credit_eur"` // Tax withheld abroad deducted from the tax due
+}
+
+// TaxExemption is an amount the jurisdiction's rules left out of the taxable income, and why.
+type TaxExemption struct {
+	Code      string  `json:"code"`
+	Label     string  `json:"label"`
+	AmountEUR float64 `json:"amount_eur"`
+}
+
+// TaxReport is the user's realized results for a tax year under the rules of their tax residence.
+type TaxReport struct {
+	Country    string         `json:"country"` // ISO 3166 alpha-2 code of the tax residence
+	TaxYear    string         `json:"tax_year"`
+	Categories []TaxCategory  `json:"categories"`
+	Exemptions []TaxExemption `json:"exemptions"`
+	TaxEUR     float64        `json:"tax_eur"` // Sum of the tax due in every category
+	Notes      []string       `json:"notes"`   // What the rules could not determine from the data, e.g. marginal rates
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RawTransaction represents a single transaction from the CSV file.
 type RawTransaction struct {
 	OrderDate    string `json:"order_date"`    // Date of the order
@@ -16,33 +18,49 @@ type RawTransaction struct {
 
 // ProcessedTransaction represents a transaction after initial processing and enrichment.
 type ProcessedTransaction struct {
-	ID                 int64   `json:"id,omitempty"` // Database primary key
-	Date               string  `json:"date"`
-	Source             string  `json:"source"` // e.g., DEGIRO, IBKR
-	ProductName        string  `json:"product_name"`
-	ISIN               string  `json:"isin"`
-	Quantity           int     `json:"quantity"`
-	OriginalQuantity   int     `json:"original_quantity"` // Original quantity of the purchase lot before any sales
-	Price              float64 `json:"price"`
-	TransactionType    string  `json:"transaction_type"`    // e.g., "STOCK", "OPTION", "DIVIDEND", "FEE", "CASH"
-	TransactionSubType string  `json:"transaction_subtype"` // e.g., "CALL", "PUT", "TAX", "DEPOSIT"
-	BuySell            string  `json:"buy_sell"`            // "BUY", "SELL", or empty
-	Description        string  `json:"description"`         // Original description from RawTransaction
-	Amount             float64 `json:"amount"`              // Transaction amount in original currency
-	Currency           string  `json:"currency"`            // Original currency (e.g., "USD", "EUR")
-	Commission         float64 `json:"commission"`          // Commission/fees
-	OrderID            string  `json:"order_id"`
-	ExchangeRate       float64 `json:"exchange_rate"`          // Exchange rate to EUR (if applicable)
-	AmountEUR          float64 `json:"amount_eur"`             // Transaction amount in EUR (calculated)
-	CountryCode        string  `json:"country_code,omitempty"` // Country code derived from ISIN
-	InputString        string  `json:"input_string"`           // The full description string for reference
-	HashId             string  `json:"hash_id"`                // Generated hash for potential duplicate checking
+	ID                 int64     `json:"id,omitempty"` // Database primary key
+	Date               string    `json:"date"`
+	ExecutedAt         time.Time `json:"executed_at"` // When it was executed, in UTC: at the time of day the broker reports, otherwise midnight of Date
+	Source             string    `json:"source"`      // e.g., DEGIRO, IBKR
+	ProductName        string    `json:"product_name"`
+	ISIN               string    `json:"isin"`
+	Quantity           int       `json:"quantity"`
+	OriginalQuantity   int       `json:"original_quantity"` // Original quantity of the purchase lot before any sales
+	Price              Money     `json:"price"`
+	TransactionType    string    `json:"transaction_type"`    // e.g., "STOCK", "OPTION", "DIVIDEND", "SCRIP_DIVIDEND", "RETURN_OF_CAPITAL", "FEE", "TAX", "CASH", "INTEREST", "BOND"
+	TransactionSubType string    `json:"transaction_subtype"` // e.g., "CALL", "PUT", "TAX", "DEPOSIT", "WITHDRAWAL", "FX", "STAMP_DUTY", "FTT", "TRADE_TAX", "COUPON", "ACCRUED_INTEREST", "REDEMPTION"
+	BuySell            string    `json:"buy_sell"`            // "BUY", "SELL", or empty
+	Description        string    `json:"description"`         // Original description from RawTransaction
+	Amount             Money     `json:"amount"`              // Transaction amount in original currency
+	Currency           string    `json:"currency"`            // Original currency (e.g., "USD", "EUR")
+	Commission         Money     `json:"commission"`          // Commission/fees
+	OrderID            string    `json:"order_id"`
+	ExchangeRate       float64   `json:"exchange_rate"`          // Exchange rate to EUR (if applicable)
+	ExchangeRateDate   string    `json:"exchange_rate_date"`     // Day of the ECB rate used, DD-MM-YYYY; empty for broker-executed rates
+	AmountEUR          Money     `json:"amount_eur"`             // Transaction amount in EUR (calculated)
+	CountryCode        string    `json:"country_code,omitempty"` // Country code derived from ISIN
+	InputString        string    `json:"input_string"`           // The full description string for reference
+	HashId             string    `json:"hash_id"`                // Generated hash for potential duplicate checking
+	AssetClass         string    `json:"asset_class,omitempty"`  // STOCK, ETF, FUND or OTHER, from the ISIN's ticker mapping
+
+	// Cash balance the statement reported after the row, stored for the cash balance check and not read
+	// back with the transaction; the currency is empty when the statement reports none
+	BrokerBalance         Money  `json:"-"`
+	BrokerBalanceCurrency string `json:"-"`
+
+	// User annotations, only filled in for the processed transactions listing
+	Note string   `json:"note,omitempty"`
+	Tags []string `json:"tags,omitempty"`
 }
 
-// CashMovement represents a cash deposit or withdrawal
+// CashMovement represents a cash deposit, withdrawal or one leg of a currency conversion
 type CashMovement struct {
-	Date     string  `json:"date"`     // Date of the movement
-	Type     string  `json:"type"`     // "deposit" or "withdrawal"
-	Amount   float64 `json:"amount"`   // Amount in original currency
-	Currency string  `json:"currency"` // Original currency
+	Date      string  `json:"date"`       // Date of the movement
+	Type      string  `json:"type"`       // "deposit", "withdrawal" or "fx_conversion"
+	Amount    float64 `json:"amount"`     // Amount in original currency; negative for withdrawals and the currency sold
+	Currency  string  `json:"currency"`   // Original currency
+	AmountEUR float64 `json:"amount_eur"` // Amount in the base currency
+	Source    string  `json:"source"`
+	OrderID   string  `json:"order_id,omitempty"` // Shared by the two legs of a conversion
+	Balance   float64 `json:"balance"`            // Net of the movements in this currency and source so far
 }
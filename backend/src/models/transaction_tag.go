@@ -0,0 +1,21 @@
+package models
+
+// TransactionAnnotation is the user's note and tags on a processed transaction.
+type TransactionAnnotation struct {
+	TransactionID int64    `json:"transaction_id"`
+	Note          string   `json:"note"`
+	Tags          []string `json:"tags"`
+}
+
+// TagCount is a tag in use and the number of transactions carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TaggedTransactionRef identifies a tagged transaction and the fields used to link it to report rows.
+type TaggedTransactionRef struct {
+	ID   int64
+	Date string
+	ISIN string
+}
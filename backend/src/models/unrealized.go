@@ -0,0 +1,38 @@
+package models
+
+// UnrealizedLot is an open purchase lot valued at the current market price.
+type UnrealizedLot struct {
+	BuyDate           string   `json:"buy_date"`
+	Quantity          int      `json:"quantity"`
+	HoldingDays       int      `json:"holding_days"`
+	CostBasisEUR      float64  `json:"cost_basis_eur"`
+	MarketValueEUR    *float64 `json:"market_value_eur"`    // Null when no price is available
+	UnrealizedGainEUR *float64 `json:"unrealized_gain_eur"` // Market value minus cost basis; negative for a loss
+	UnrealizedGainPct *float64 `json:"unrealized_gain_pct"` // Gain as a fraction of the cost basis
+}
+
+// UnrealizedPosition aggregates the open lots of one instrument.
+type UnrealizedPosition struct {
+	ISIN              string          `json:"isin"`
+	ProductName       string          `json:"product_name"`
+	Quantity          int             `json:"quantity"`
+	CostBasisEUR      float64         `json:"cost_basis_eur"`
+	CurrentPriceEUR   *float64        `json:"current_price_eur"`
+	MarketValueEUR    *float64        `json:"market_value_eur"`
+	UnrealizedGainEUR *float64        `json:"unrealized_gain_eur"`
+	UnrealizedGainPct *float64        `json:"unrealized_gain_pct"`
+	PriceStatus       string          `json:"price_status"` // "OK" or "UNAVAILABLE"
+	Lots              []UnrealizedLot `json:"lots"`
+}
+
+// UnrealizedGainsReport is the response for the unrealized gains endpoint.
+// Totals only include positions with a live price.
+type UnrealizedGainsReport struct {
+	AsOf                   string               `json:"as_of"`
+	TotalCostBasisEUR      float64              `json:"total_cost_basis_eur"`
+	TotalMarketValueEUR    float64              `json:"total_market_value_eur"`
+	TotalUnrealizedGainEUR float64              `json:"total_unrealized_gain_eur"`
+	TotalUnrealizedLossEUR float64              `json:"total_unrealized_loss_eur"` // Sum of the losing lots, the amount available for tax-loss harvesting
+	PriceStatus            string               `json:"price_status"`              // "OK", "PARTIAL" or "UNAVAILABLE"
+	Positions              []UnrealizedPosition `json:"positions"`
+}
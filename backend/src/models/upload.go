@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// SkippedRow is a row from an uploaded file that a parser could not turn into a transaction.
+type SkippedRow struct {
+	RawText string `json:"raw_text"`
+	Reason  string `json:"reason"`
+	Payload string `json:"-"` // Minimal file in the source format containing only this row
+
+	Unclassified string `json:"-"` // Description no classification rule matched; empty for rows skipped for other reasons
+}
+
+// SkippedTransaction is a quarantined row stored for the user to review.
+type SkippedTransaction struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"`
+	RawText   string    `json:"raw_text"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	Payload   string    `json:"-"`
+}
+
+// ManualTransaction is a quarantined row entered by hand: the fields the user read from it, imported
+// in its place by POST /transactions/skipped/{id}/manual.
+type ManualTransaction struct {
+	Date               string  `json:"date"`                 // DD-MM-YYYY
+	TransactionType    string  `json:"transaction_type"`     // STOCK, DIVIDEND, TAX, FEE, INTEREST or CASH
+	TransactionSubType string  `json:"transaction_sub_type"` // Optional, e.g. DEPOSIT or WITHDRAWAL for CASH
+	BuySell            string  `json:"buy_sell"`             // BUY or SELL, for STOCK
+	ISIN               string  `json:"isin"`                 // Required for STOCK and DIVIDEND
+	ProductName        string  `json:"product_name"`
+	Quantity           float64 `json:"quantity"`   // STOCK only
+	Price              float64 `json:"price"`      // STOCK only, per share
+	Commission         float64 `json:"commission"` // STOCK only
+	Amount             float64 `json:"amount"`     // Signed amount for the other types: positive when received
+	Currency           string  `json:"currency"`   // Defaults to EUR
+}
+
+// UploadSummary describes the outcome of processing a single uploaded file.
+type UploadSummary struct {
+	Source       string `json:"source"`
+	RowsImported int    `json:"rows_imported"` // New transactions stored
+	Duplicates   int    `json:"duplicates"`    // Transactions already present from a previous upload
+	Skipped      int    `json:"skipped"`       // Rows the parser could not classify
+	Error        string `json:"error,omitempty"`
+}
+
+// UploadPreview describes what importing an upload would change, worked out without storing it.
+type UploadPreview struct {
+	Source          string   `json:"source"`
+	NewTransactions int      `json:"new_transactions"` // Transactions the upload would store
+	NewTrades       int      `json:"new_trades"`       // Buys and sells among them
+	Duplicates      int      `json:"duplicates"`       // Transactions already present from a previous upload
+	Skipped         int      `json:"skipped"`          // Rows the parser could not classify
+	FirstDate       string   `json:"first_date"`       // Date of the earliest transaction in the file, DD-MM-YYYY; empty when it has none
+	LastDate        string   `json:"last_date"`        // Date of the latest transaction in the file
+	NewISINs        []string `json:"new_isins"`        // ISINs none of the user's stored transactions have, sorted
+}
+
+// UnknownDescription is an anonymized description pattern the parsers could not classify, with how often
+// it was seen across the uploads of the users who share them.
+type UnknownDescription struct {
+	Source      string    `json:"source"`
+	Hash        string    `json:"hash"`
+	Sample      string    `json:"sample"` // Normalized text with the numbers masked, truncated
+	Occurrences int       `json:"occurrences"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Upload batch statuses.
+const (
+	UploadBatchProcessing = "processing"
+	UploadBatchCompleted  = "completed"
+	UploadBatchFailed     = "failed"
+)
+
+// UploadBatch records one uploaded file and the idempotency key the client sent with it, if any.
+type UploadBatch struct {
+	ID             int64
+	Source         string
+	Filename       string
+	IdempotencyKey string
+	Status         string
+	Summary        UploadSummary
+	CreatedAt      time.Time
+}
+
+// UploadHistoryEntry is one past upload as listed in the user's upload history.
+type UploadHistoryEntry struct {
+	ID           int64      `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"` // Null while processing
+	Filename     string     `json:"filename"`     // Empty for imports that are not file uploads, such as IBKR Flex syncs
+	Source       string     `json:"source"`
+	Status       string     `json:"status"` // processing, completed or failed
+	RowsImported int        `json:"rows_imported"`
+	Duplicates   int        `json:"duplicates"`
+	Skipped      int        `json:"skipped"`
+	Error        string     `json:"error,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// MatchingFIFO matches sales against the oldest open purchases first, as required by the Portuguese tax authority.
+const MatchingFIFO = "FIFO"
+
+// UserSettings are a user's preferences, stored as one JSON object. Keys missing from the stored object
+// take the values of DefaultUserSettings.
+type UserSettings struct {
+	BaseCurrency    string `json:"base_currency"`
+	MatchingMethod  string `json:"matching_method"`
+	Locale          string `json:"locale"`
+	FiscalYearStart string `json:"fiscal_year_start"` // DD-MM
+	DefaultAccount  string `json:"default_account"`   // Upload source used when a request names none; empty for none
+	DeemedDisposal  bool   `json:"deemed_disposal"`
+	TaxCountry      string `json:"tax_country"` // ISO 3166 alpha-2 code of the tax residence whose rules the tax report applies
+
+	ShareUnknownDescriptions bool `json:"share_unknown_descriptions"` // Opt-in: report unclassified descriptions, anonymized, to improve the parsers
+}
+
+// DefaultUserSettings returns the preferences of a user who has not changed any.
+func DefaultUserSettings() UserSettings {
+	return UserSettings{
+		BaseCurrency:    "EUR",
+		MatchingMethod:  MatchingFIFO,
+		Locale:          "pt-PT",
+		FiscalYearStart: CalendarYear.String(),
+		TaxCountry:      "PT",
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Events a webhook can subscribe to.
+const (
+	WebhookUploadProcessed   = "upload.processed"    // An upload finished, successfully or not
+	WebhookLargeCashMovement = "cash_movement.large" // An upload brought a deposit or withdrawal of at least the configured amount
+	WebhookPricesRefreshed   = "prices.refreshed"    // The current prices of the user's positions were refreshed
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookUploadProcessed, WebhookLargeCashMovement, WebhookPricesRefreshed}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // Out of attempts
+)
+
+// Webhook is a URL the user registered to be posted the events it subscribes to.
+type Webhook struct {
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"-"`
+	URL             string    `json:"url"`
+	EncryptedSecret string    `json:"-"`
+	Secret          string    `json:"secret,omitempty"` // Signing key, returned only when the webhook is created
+	Events          []string  `json:"events"`
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for a webhook, with the outcome of its attempts.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"` // JSON body posted to the webhook
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"` // Only meaningful while pending
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// WebhookPayload is the body posted to a webhook.
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// LargeCashMovementEvent is the data of a cash_movement.large event.
+type LargeCashMovementEvent struct {
+	Date      string  `json:"date"`
+	Type      string  `json:"type"`   // "deposit" or "withdrawal"
+	Amount    float64 `json:"amount"` // In Currency; negative for withdrawals
+	Currency  string  `json:"currency"`
+	AmountEUR float64 `json:"amount_eur"` // In the base currency
+	Source    string  `json:"source"`
+	Threshold float64 `json:"threshold"` // Amount in the base currency from which a movement counts as large
+}
+
+// PositionPrice is the current price of one position, in a prices.refreshed event.
+type PositionPrice struct {
+	ISIN            string   `json:"isin"`
+	ProductName     string   `json:"product_name"`
+	Quantity        int      `json:"quantity"`
+	CurrentPriceEUR *float64 `json:"current_price_eur"`
+	MarketValueEUR  *float64 `json:"market_value_eur"`
+	PriceStatus     string   `json:"price_status"` // "OK" or "UNAVAILABLE"
+}
+
+// PricesRefreshedEvent is the data of a prices.refreshed event.
+type PricesRefreshedEvent struct {
+	AsOf                   string          `json:"as_of"`
+	PriceStatus            string          `json:"price_status"` // "OK", "PARTIAL" or "UNAVAILABLE"
+	TotalMarketValueEUR    float64         `json:"total_market_value_eur"`
+	TotalUnrealizedGainEUR float64         `json:"total_unrealized_gain_eur"`
+	Positions              []PositionPrice `json:"positions"`
+}
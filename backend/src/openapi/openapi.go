@@ -0,0 +1,309 @@
+// backend/src/openapi/openapi.go
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Document is an OpenAPI 3 document. Only the parts the generator fills in are modelled.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps a lower-case HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Security    []map[string][]string `json:"security"` // Empty for public operations
+	Responses   map[string]Response   `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"` // path, query or header
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Schema      Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string            `json:"$ref,omitempty"`
+	AllOf                []Schema          `json:"allOf,omitempty"`
+	Type                 string            `json:"type,omitempty"`
+	Format               string            `json:"format,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Enum                 []string          `json:"enum,omitempty"`
+	Nullable             bool              `json:"nullable,omitempty"`
+	Items                *Schema           `json:"items,omitempty"`
+	Properties           map[string]Schema `json:"properties,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+	AdditionalProperties *bool             `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// MiddlewareDoc describes what a middleware adds to the operations it wraps, such as a security
+// requirement or a required header. Func is the middleware itself, or the function that builds it.
+type MiddlewareDoc struct {
+	Func  interface{}
+	Apply func(op *Operation)
+}
+
+// ResponseDoc documents the body a handler answers with. Handler is the handler function, e.g. the
+// method expression (*V2Handler).HandleGetTransactions; Body is a value of the Go type it encodes,
+// whose schema is derived with SchemaOf.
+type ResponseDoc struct {
+	Handler interface{}
+	Body    interface{}
+}
+
+// Options configures Generate.
+type Options struct {
+	Info            Info
+	PathPrefix      string   // Only routes below it are documented; it becomes the server URL
+	ExcludePrefixes []string // Routes below these, such as those of another API version, are left out
+	SecuritySchemes map[string]SecurityScheme
+	Middlewares     []MiddlewareDoc
+	Responses       []ResponseDoc
+}
+
+// ErrorSchemaName is the component schema of the error body every operation may return.
+const ErrorSchemaName = "ErrorResponse"
+
+// Generate builds the document from the routes registered on the router. Operation IDs and summaries
+// come from the handler names, tags from the first path segment and security from the middlewares.
+func Generate(routes chi.Routes, opts Options) (*Document, error) {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    opts.Info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas:         map[string]Schema{ErrorSchemaName: errorSchema()},
+			SecuritySchemes: opts.SecuritySchemes,
+		},
+	}
+	if opts.PathPrefix != "" {
+		doc.Servers = []Server{{URL: opts.PathPrefix}}
+	}
+
+	middlewareNames := make([]string, len(opts.Middlewares))
+	for i, mw := range opts.Middlewares {
+		middlewareNames[i] = FuncName(mw.Func)
+	}
+
+	responseNames := make([]string, len(opts.Responses))
+	for i, response := range opts.Responses {
+		responseNames[i] = FuncName(response.Handler)
+	}
+
+	operationIDs := map[string]bool{}
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, opts.PathPrefix+"/") {
+			return nil
+		}
+		for _, prefix := range opts.ExcludePrefixes {
+			if strings.HasPrefix(route, prefix+"/") {
+				return nil
+			}
+		}
+		path := strings.TrimPrefix(route, opts.PathPrefix)
+		op := newOperation(method, path, handler)
+		if name := FuncName(handler); name != "" {
+			for i, response := range opts.Responses {
+				// A method value is named after its method expression with a "-fm" suffix.
+				if responseNames[i] != "" && strings.TrimSuffix(name, "-fm") == responseNames[i] {
+					op.Responses["2XX"] = Response{
+						Description: "Success",
+						Content: map[string]MediaType{
+							"application/json": {Schema: SchemaOf(response.Body, doc.Components.Schemas)},
+						},
+					}
+				}
+			}
+		}
+		if operationIDs[op.OperationID] {
+			op.OperationID += method[:1] + strings.ToLower(method[1:])
+		}
+		operationIDs[op.OperationID] = true
+
+		for _, mw := range middlewares {
+			name := FuncName(mw)
+			for i, mwDoc := range opts.Middlewares {
+				if middlewareNames[i] != "" && strings.HasPrefix(name, middlewareNames[i]) {
+					mwDoc.Apply(op)
+				}
+			}
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(method)] = op
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk routes: %w", err)
+	}
+	return doc, nil
+}
+
+// FuncName returns the fully qualified name of a function value, or "" for anything else.
+// Closures are named after the function that returned them, e.g. "pkg.Middleware.func1".
+func FuncName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
+
+func newOperation(method, path string, handler http.Handler) *Operation {
+	name := handlerName(handler)
+	if name == "" {
+		name = strings.ToLower(method) + pathName(path)
+	}
+	op := &Operation{
+		OperationID: name,
+		Summary:     sentence(name),
+		Security:    []map[string][]string{},
+		Responses: map[string]Response{
+			"2XX": {Description: "Success"},
+			"default": {
+				Description: "Error",
+				Content: map[string]MediaType{
+					"application/json": {Schema: Schema{Ref: "#/components/schemas/" + ErrorSchemaName}},
+				},
+			},
+		},
+	}
+	if segments := strings.Split(strings.Trim(path, "/"), "/"); segments[0] != "" {
+		tag, _, _ := strings.Cut(segments[0], ".")
+		op.Tags = []string{tag}
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			param, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+			op.Parameters = append(op.Parameters, Parameter{Name: param, In: "path", Required: true, Schema: Schema{Type: "string"}})
+		}
+	}
+	return op
+}
+
+// handlerName turns the name of a handler function into an operation ID,
+// e.g. "(*UploadHandler).HandleGetCSVMapping-fm" into "getCSVMapping".
+func handlerName(handler http.Handler) string {
+	name := FuncName(handler)
+	if name == "" {
+		return ""
+	}
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "-fm")
+	if strings.HasPrefix(name, "func") {
+		return "" // An anonymous function says nothing about the operation
+	}
+	name = strings.TrimPrefix(name, "Handle")
+	name = strings.TrimSuffix(name, "Handler")
+	if name == "" {
+		return ""
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// pathName names an operation after its path when the handler has no usable name, e.g. "/user/usage" as "UserUsage".
+func pathName(path string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// sentence splits a camel-case operation ID into words, e.g. "getCSVMapping" into "Get CSV mapping".
+func sentence(id string) string {
+	runes := []rune(id)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+		acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i, w := range words {
+		if !isAcronym(w) {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+func isAcronym(word string) bool {
+	return len(word) > 1 && strings.ToUpper(word) == word
+}
+
+func errorSchema() Schema {
+	allowAny := true
+	return Schema{
+		Type:     "object",
+		Required: []string{"code", "message"},
+		Properties: map[string]Schema{
+			"code":    {Type: "string", Description: "Machine-readable error code, e.g. NOT_FOUND or QUOTA_EXCEEDED"},
+			"message": {Type: "string", Description: "Human-readable message"},
+			"details": {Type: "object", Description: "Extra data specific to the code", AdditionalProperties: &allowAny},
+		},
+	}
+}
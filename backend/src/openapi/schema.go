@@ -0,0 +1,102 @@
+// backend/src/openapi/schema.go
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Describer is implemented by types whose JSON form their Go type does not tell, such as a string
+// holding a decimal number or one of a fixed set of values. It is called on the zero value.
+type Describer interface {
+	OpenAPISchema() Schema
+}
+
+var (
+	describerType = reflect.TypeOf((*Describer)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// SchemaOf returns the schema of the JSON encoding of v. Named structs are added to schemas as
+// components and referred to; fields are named after their json tags, are required unless tagged
+// omitempty and are described by their doc tags.
+func SchemaOf(v interface{}, schemas map[string]Schema) Schema {
+	return schemaOfType(reflect.TypeOf(v), schemas)
+}
+
+func schemaOfType(t reflect.Type, schemas map[string]Schema) Schema {
+	if t == nil {
+		return Schema{}
+	}
+	if t.Implements(describerType) {
+		return reflect.Zero(t).Interface().(Describer).OpenAPISchema()
+	}
+	if t == timeType {
+		return Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaOfType(t.Elem(), schemas)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		items := schemaOfType(t.Elem(), schemas)
+		return Schema{Type: "array", Items: &items}
+	case reflect.Map:
+		return Schema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = Schema{} // Reserved first, so a struct referring to itself ends
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return Schema{Ref: "#/components/schemas/" + t.Name()}
+	case reflect.String:
+		return Schema{Type: "string"}
+	case reflect.Bool:
+		return Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{Type: "number"}
+	default:
+		return Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]Schema) Schema {
+	schema := Schema{Type: "object", Properties: map[string]Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := schemaOfType(field.Type, schemas)
+		if doc := field.Tag.Get("doc"); doc != "" {
+			if property.Ref != "" {
+				// A $ref replaces its siblings, so the description goes around it.
+				property = Schema{AllOf: []Schema{property}}
+			}
+			property.Description = doc
+		}
+		schema.Properties[name] = property
+		if !strings.Contains(","+options+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
@@ -2,132 +2,375 @@
 package degiro
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/pdf"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
 )
 
 // RawTransaction holds the direct string values from a single row of a DeGiro CSV.
 // Added RawLine to store the full, unprocessed line.
 type RawTransaction struct {
 	OrderDate, OrderTime, ValueDate, Name, ISIN, Description, ExchangeRate, Currency, Amount, OrderID string
+	BalanceCurrency, Balance                                                                          string
 	RawLine                                                                                           string
+	Record                                                                                            []string
 }
 
 // DeGiroParser implements the parsers.Parser interface for DeGiro files.
-type DeGiroParser struct{}
+type DeGiroParser struct {
+	skipped []models.SkippedRow
+}
 
 // NewParser creates a new instance of the DeGiroParser.
 func NewParser() *DeGiroParser {
 	return &DeGiroParser{}
 }
 
-func normalizeDecimalString(s string) string {
-	// 1. Trim whitespace and quotes
-	cleaned := strings.TrimSpace(s)
-	cleaned = strings.Trim(cleaned, "\"")
+// column is a field of the DeGiro account statement that the parser reads.
+type column int
+
+const (
+	colDate column = iota
+	colTime
+	colValueDate
+	colProduct
+	colISIN
+	colDescription
+	colFX
+	colChange  // Currency of the change; the amount is in the unnamed column right after it
+	colBalance // Currency of the balance after the row; the amount is in the unnamed column right after it
+	colOrderID
+)
+
+// columnNames are the header names of each column in the EN, PT and NL exports, lowercased.
+var columnNames = map[column][]string{
+	colDate:        {"date", "data", "datum"},
+	colTime:        {"time", "hora", "tijd"},
+	colValueDate:   {"value date", "data valor", "valutadatum"},
+	colProduct:     {"product", "produto"},
+	colISIN:        {"isin"},
+	colDescription: {"description", "descrição", "descricao", "omschrijving"},
+	colFX:          {"fx", "taxa de câmbio", "taxa de cambio", "câmbio"},
+	colChange:      {"change", "variação", "variacao", "mutatie"},
+	colBalance:     {"balance", "saldo", "saldo conta", "saldoconta"},
+	colOrderID:     {"order id", "order_id", "id da ordem", "id ordem"},
+}
+
+// requiredColumns must be present in the header for a file to be parsed.
+var requiredColumns = []column{colDate, colProduct, colISIN, colDescription, colChange}
+
+var columnLabels = map[column]string{
+	colDate:        "Date",
+	colTime:        "Time",
+	colValueDate:   "Value date",
+	colProduct:     "Product",
+	colISIN:        "ISIN",
+	colDescription: "Description",
+	colFX:          "FX",
+	colChange:      "Change",
+	colBalance:     "Balance",
+	colOrderID:     "Order Id",
+}
+
+// columnIndex maps the columns found in a header to their position.
+type columnIndex map[column]int
+
+// mapHeader locates the known columns by name, so files with reordered or translated headers
+// parse the same way. It fails listing every required column that is missing.
+func mapHeader(header []string) (columnIndex, error) {
+	index := make(columnIndex)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		for col, names := range columnNames {
+			if _, found := index[col]; !found && slices.Contains(names, name) {
+				index[col] = i
+			}
+		}
+	}
 
-	// 2. Replace comma with a period for the decimal point
-	cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	var missing []string
+	for _, col := range requiredColumns {
+		if _, found := index[col]; !found {
+			missing = append(missing, columnLabels[col])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, spreadsheet.MissingColumns("degiro parser", 1, header, missing...)
+	}
+	return index, nil
+}
 
-	return cleaned
+// Recognizes reports whether header is the header row of a DeGiro account statement or trades export.
+func Recognizes(header []string) bool {
+	if _, ok := mapTradesHeader(header); ok {
+		return true
+	}
+	_, err := mapHeader(header)
+	return err == nil
+}
+
+// field returns the value of col in record, or "" when the column is absent.
+func (idx columnIndex) field(record []string, col column) string {
+	i, found := idx[col]
+	if !found || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// amount returns the value of the unnamed amount column that follows Change.
+func (idx columnIndex) amount(record []string) string {
+	i := idx[colChange] + 1
+	if i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// balance returns the currency and the value of the balance after the row, or "" when the file has no
+// Balance column.
+func (idx columnIndex) balance(record []string) (currency, amount string) {
+	i, found := idx[colBalance]
+	if !found || i+1 >= len(record) {
+		return "", ""
+	}
+	return record[i], record[i+1]
 }
 
 // Parse reads a DeGiro CSV file and converts its rows into a slice of CanonicalTransaction.
-// This method now contains the full logic, from reading the CSV to classifying transactions.
 func (p *DeGiroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
-	// --- CSV Reading Logic (formerly in csv_parser.go) ---
+	var canonicalTxs []models.CanonicalTransaction
+	err := p.ParseStream(file, math.MaxInt, spreadsheet.NewGuard(spreadsheet.Limits{}), func(batch []models.CanonicalTransaction) error {
+		canonicalTxs = append(canonicalTxs, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return canonicalTxs, nil
+}
+
+// ParseStream reads a DeGiro CSV file row by row and hands its transactions to handle in batches of
+// at most batchSize. DeGiro lists the rows of an order (the trade, its commission and the FX legs)
+// next to each other, so only the rows of the current order are kept in memory to resolve them.
+// PDF account statements are read into CSV rows first, see statementFromPDF, and trades exports are
+// read by parseTrades.
+func (p *DeGiroParser) ParseStream(file io.Reader, batchSize int, guard *spreadsheet.Guard, handle func([]models.CanonicalTransaction) error) error {
+	p.skipped = nil
+
+	buffered := bufio.NewReader(file)
+	if head, _ := buffered.Peek(len(pdf.Magic)); bytes.Equal(head, pdf.Magic) {
+		statement, err := p.statementFromPDF(buffered)
+		if err != nil {
+			return err
+		}
+		file = bytes.NewReader(statement)
+	} else {
+		file = buffered
+	}
+
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields per record
 
-	// Read and discard the header row
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("degiro parser: failed to read CSV header: %w", err)
+	// Read the header row; columns are located by name, and it is kept to rebuild single-row files for skipped rows
+	header, err := reader.Read()
+	if err != nil {
+		return spreadsheet.ReadError("degiro parser", err)
 	}
-
-	records, err := reader.ReadAll() // Read all records at once
+	if trades, ok := mapTradesHeader(header); ok {
+		return p.parseTrades(reader, header, trades, batchSize, guard, handle)
+	}
+	columns, err := mapHeader(header)
 	if err != nil {
-		return nil, fmt.Errorf("degiro parser: failed to read all CSV records: %w", err)
+		return err
 	}
 
-	// --- Raw Transaction Mapping ---
-	var rawTxs []RawTransaction
-	for _, record := range records {
-		if len(record) >= 12 {
-			rawTxs = append(rawTxs, RawTransaction{
-				OrderDate: record[0], OrderTime: record[1], ValueDate: record[2],
-				Name: record[3], ISIN: record[4], Description: record[5],
-				ExchangeRate: record[6], Currency: record[7], Amount: record[8],
-				OrderID: record[11],
-				// Join the record back together to get the full raw line.
-				RawLine: strings.Join(record, ","),
-			})
+	var batch []models.CanonicalTransaction
+	var order []RawTransaction
+	flushOrder := func() error {
+		start := len(batch)
+		for _, raw := range order {
+			if tx, ok := p.convert(header, raw, order); ok {
+				batch = append(batch, tx)
+			}
+		}
+		splitOrderCommission(batch[start:])
+		order = order[:0]
+		if len(batch) >= batchSize {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = nil
 		}
+		return nil
 	}
 
-	// --- Canonical Transaction Conversion ---
-	var canonicalTxs []models.CanonicalTransaction
-	for _, raw := range rawTxs {
-		date, err := time.Parse("02-01-2006", raw.OrderDate)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			log.Printf("DeGiro Parser: Skipping row due to invalid date: %s (OrderID: %s)", raw.OrderDate, raw.OrderID)
+			return spreadsheet.ReadError("degiro parser", err)
+		}
+		if err := guard.Row(); err != nil {
+			return err
+		}
+		if len(record) <= columns[colChange]+1 {
 			continue
 		}
 
-		txType, subType, buySell, productName, quantity, price := classifyDeGiroTransaction(raw)
-
-		// --- FIX START: Ignore transaction lines that are only for commissions ---
-		if txType == "COMMISSION_IGNORE" {
-			continue // Skip creating a transaction for this, it will be handled by findCommissionForOrder
+		raw := RawTransaction{
+			OrderDate: columns.field(record, colDate), OrderTime: columns.field(record, colTime), ValueDate: columns.field(record, colValueDate),
+			Name: columns.field(record, colProduct), ISIN: columns.field(record, colISIN), Description: columns.field(record, colDescription),
+			ExchangeRate: columns.field(record, colFX), Currency: columns.field(record, colChange), Amount: columns.amount(record),
+			OrderID: columns.field(record, colOrderID),
+			// Join the record back together to get the full raw line.
+			RawLine: strings.Join(record, ","),
+			Record:  record,
 		}
-		// --- FIX END ---
-
-		if txType == "UNKNOWN" {
-			log.Printf("DeGiro Parser: Skipping unknown transaction type for description: '%s'", raw.Description)
-			continue
+		raw.BalanceCurrency, raw.Balance = columns.balance(record)
+		if len(order) > 0 && (raw.OrderID == "" || raw.OrderID != order[0].OrderID) {
+			if err := flushOrder(); err != nil {
+				return err
+			}
 		}
+		order = append(order, raw)
+	}
+	if err := flushOrder(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return handle(batch)
+	}
+	return nil
+}
 
-		normalizedAmount := normalizeDecimalString(raw.Amount)
-		sourceAmt, _ := strconv.ParseFloat(normalizedAmount, 64)
-		finalAmount := sourceAmt // For DeGiro, the sign is authoritative
+// convert maps one row to a CanonicalTransaction, using the other rows of its order for the
+// commission and the executed FX rate. It reports false for rows that do not become a transaction.
+func (p *DeGiroParser) convert(header []string, raw RawTransaction, order []RawTransaction) (models.CanonicalTransaction, bool) {
+	date, err := time.Parse("02-01-2006", raw.OrderDate)
+	if err != nil {
+		log.Printf("DeGiro Parser: Skipping row due to invalid date: %s (OrderID: %s)", raw.OrderDate, raw.OrderID)
+		p.skip(header, raw, "invalid date", order...)
+		return models.CanonicalTransaction{}, false
+	}
 
-		// Enforce sign for specific types to be safe
-		if txType == "FEE" || (txType == "DIVIDEND" && subType == "TAX") {
-			finalAmount = -math.Abs(sourceAmt)
-		}
+	txType, subType, buySell, productName, quantity, price := classifyDeGiroTransaction(raw)
 
-		commission, _ := findCommissionForOrder(raw.OrderID, rawTxs)
+	// --- FIX START: Ignore transaction lines that are only for commissions ---
+	if txType == "COMMISSION_IGNORE" {
+		return models.CanonicalTransaction{}, false // Handled by findCommissionForOrder
+	}
+	// --- FIX END ---
+	// FX legs move cash between currencies, such as for an AutoFX trade; they also give the trade the
+	// rate it was executed at, see findRealizedFXRate.
+	if txType == "FX_LEG" {
+		txType, subType, productName = "CASH", "FX", "Currency conversion"
+	}
+
+	if txType == "UNKNOWN" {
+		log.Printf("DeGiro Parser: Skipping unknown transaction type for description: '%s'", raw.Description)
+		p.skip(header, raw, "unknown description", order...)
+		p.skipped[len(p.skipped)-1].Unclassified = raw.Description
+		return models.CanonicalTransaction{}, false
+	}
+
+	sourceAmt, err := spreadsheet.ParseNumber(raw.Amount)
+	if err != nil {
+		log.Printf("DeGiro Parser: Skipping row due to invalid amount: %s (OrderID: %s)", raw.Amount, raw.OrderID)
+		p.skip(header, raw, "invalid amount", order...)
+		return models.CanonicalTransaction{}, false
+	}
+	finalAmount := sourceAmt // For DeGiro, the sign is authoritative
+
+	// Enforce sign for specific types to be safe
+	if txType == "FEE" || txType == "TAX" || (txType == "DIVIDEND" && subType == "TAX") {
+		finalAmount = -math.Abs(sourceAmt)
+	}
 
-		tx := models.CanonicalTransaction{
-			Source:          "degiro",
-			TransactionDate: date,
-			ProductName:     productName,
-			ISIN:            strings.TrimSpace(raw.ISIN),
-			Quantity:        quantity,
-			Price:           price,
-			Currency:        raw.Currency,
-			OrderID:         raw.OrderID,
-			// Use the full line as RawText
-			RawText:            raw.RawLine,
-			SourceAmount:       sourceAmt,
-			Amount:             finalAmount,
-			TransactionType:    txType,
-			TransactionSubType: subType,
-			BuySell:            buySell,
-			Commission:         commission,
+	// Scrip dividends are credited as shares with no cash movement. The taxable
+	// dividend is the market value of the shares received, which is also their cost basis.
+	if txType == "SCRIP_DIVIDEND" {
+		finalAmount = math.Abs(sourceAmt)
+		if finalAmount == 0 {
+			finalAmount = math.Round(quantity*price*100) / 100
 		}
-		canonicalTxs = append(canonicalTxs, tx)
 	}
 
-	return canonicalTxs, nil
+	commission, _ := findCommissionForOrder(raw.OrderID, order)
+
+	tx := models.CanonicalTransaction{
+		Source:          "degiro",
+		TransactionDate: date,
+		ProductName:     productName,
+		ISIN:            strings.TrimSpace(raw.ISIN),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        raw.Currency,
+		OrderID:         raw.OrderID,
+		// Use the full line as RawText
+		RawText:            raw.RawLine,
+		SourceAmount:       sourceAmt,
+		Amount:             finalAmount,
+		TransactionType:    txType,
+		TransactionSubType: subType,
+		BuySell:            buySell,
+		Commission:         commission,
+	}
+	// Use the rate DeGiro actually executed the conversion at instead of the ECB reference rate.
+	if rate, ok := findRealizedFXRate(raw.OrderID, raw.Currency, order); ok {
+		tx.ExchangeRate = rate
+	}
+	if balance, err := spreadsheet.ParseNumber(raw.Balance); err == nil && strings.TrimSpace(raw.BalanceCurrency) != "" {
+		tx.BrokerBalance, tx.BrokerBalanceCurrency = balance, strings.TrimSpace(raw.BalanceCurrency)
+	}
+	// Money-market fund rows may leave the change column empty; their cash is that of the balance.
+	if tx.Currency == "" && txType == "CASH" && (subType == models.MoneyMarketConversionSubType || subType == models.MoneyMarketPriceChangeSubType) {
+		tx.Currency = strings.TrimSpace(raw.BalanceCurrency)
+	}
+	return tx, true
+}
+
+// SkippedRows returns the rows dropped by the last call to Parse.
+func (p *DeGiroParser) SkippedRows() []models.SkippedRow {
+	return p.skipped
+}
+
+// skip records a dropped row together with a CSV of it that can be parsed again later. The rows of
+// order sharing its order ID (its commission and FX legs) are written with it, in their order in the
+// file, so the trade gets them back when it is parsed again; those that are transactions of their
+// own are already stored and come out as duplicates.
+func (p *DeGiroParser) skip(header []string, raw RawTransaction, reason string, order ...RawTransaction) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(header)
+	if raw.OrderID == "" || len(order) == 0 {
+		writer.Write(raw.Record)
+	}
+	for _, other := range order {
+		if raw.OrderID != "" && other.OrderID == raw.OrderID {
+			writer.Write(other.Record)
+		}
+	}
+	writer.Flush()
+	p.skipped = append(p.skipped, models.SkippedRow{RawText: raw.RawLine, Reason: reason, Payload: buf.String()})
 }
 
 // classifyDeGiroTransaction remains the same as before.
@@ -141,6 +384,17 @@ func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, pr
 		// It will be found and attached to the main trade via findCommissionForOrder.
 		return "COMMISSION_IGNORE", "", "", "", 0, 0
 	}
+	if subType := transactionTaxSubType(lowerDesc); subType != "" {
+		// Stamp duty / FTT charged on a trade; it shares the trade's OrderID when DeGiro provides one.
+		return "TAX", subType, "", strings.TrimSpace(raw.Name), 0, 0
+	}
+	if isFXLeg(lowerDesc) {
+		return "FX_LEG", "", "", "", 0, 0
+	}
+	if subType := moneyMarketFundSubType(lowerDesc); subType != "" {
+		// Checked before trades: a conversion reads "Compra 0,0123 @ 9.950,63 EUR" like one.
+		return "CASH", subType, "", strings.TrimSpace(raw.Name), 0, 0
+	}
 	if strings.Contains(lowerDesc, "custo de conectividade") {
 		// This is a standalone fee and should be treated as such.
 		return "FEE", "", "", desc, 0, 0
@@ -148,6 +402,21 @@ func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, pr
 	// --- FIX END ---
 
 	// Handle non-trade types first
+	if isScripDividend(lowerDesc) {
+		matches := scripDividendRe.FindStringSubmatch(desc)
+		if matches == nil {
+			return "UNKNOWN", "", "", "", 0, 0
+		}
+		quantity, price, err := parseDescriptionNumbers(matches[1], matches[2])
+		if err != nil {
+			return "UNKNOWN", "", "", "", 0, 0
+		}
+		return "SCRIP_DIVIDEND", "", "BUY", strings.TrimSpace(raw.Name), quantity, price
+	}
+	if strings.Contains(lowerDesc, "reembolso de capital") || strings.Contains(lowerDesc, "return of capital") {
+		// Capital returned by the fund lowers the cost basis of the position; it is not a taxable dividend.
+		return "RETURN_OF_CAPITAL", "", "", strings.TrimSpace(raw.Name), 0, 0
+	}
 	if strings.Contains(lowerDesc, "dividendo") {
 		productName = strings.TrimSpace(raw.Name)
 		if strings.Contains(lowerDesc, "imposto sobre dividendo") {
@@ -158,6 +427,14 @@ func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, pr
 	if strings.EqualFold(lowerDesc, "depósito") || strings.Contains(lowerDesc, "flatex deposit") {
 		return "CASH", "DEPOSIT", "", "Cash Deposit", 0, 0
 	}
+	if subType := bondIncomeSubType(lowerDesc); subType != "" {
+		// Coupons and accrued interest are bond income, kept apart from dividends and cash interest.
+		return "BOND", subType, "", strings.TrimSpace(raw.Name), 0, 0
+	}
+	if strings.Contains(lowerDesc, "juros") || strings.Contains(lowerDesc, "interest") {
+		// Interest paid on, or charged for, the cash balance; the sign of the amount tells which.
+		return "INTEREST", "", "", desc, 0, 0
+	}
 
 	// This part is now removed from the FIX above and handled more specifically
 	/*
@@ -194,20 +471,186 @@ func classifyDeGiroTransaction(raw RawTransaction) (txType, subType, buySell, pr
 	priceStr := strings.ReplaceAll(matches[4], ",", ".")
 	price, _ = strconv.ParseFloat(priceStr, 64)
 
-	// Differentiate between Stock and Option
-	optionPatternRe := regexp.MustCompile(`\s+[CP]\d+(\.\d+)?\s+\d{2}[A-Z]{3}\d{2}$`)
-	if optionPatternRe.MatchString(productName) {
-		txType = "OPTION"
-		if strings.Contains(productName, " C") {
-			subType = "CALL"
-		} else if strings.Contains(productName, " P") {
-			subType = "PUT"
+	txType, subType = classifyProduct(productName)
+	return
+}
+
+var optionPatternRe = regexp.MustCompile(`\s+[CP]\d+(\.\d+)?\s+\d{2}[A-Z]{3}\d{2}$`)
+
+// classifyProduct differentiates between stocks and options by DeGiro's option naming, such as
+// "AAPL C150.00 19JAN24", returning the CALL or PUT subtype of options.
+func classifyProduct(productName string) (txType, subType string) {
+	if !optionPatternRe.MatchString(productName) {
+		return "STOCK", ""
+	}
+	if strings.Contains(productName, " C") {
+		return "OPTION", "CALL"
+	}
+	if strings.Contains(productName, " P") {
+		return "OPTION", "PUT"
+	}
+	return "OPTION", ""
+}
+
+// scripDividendPrefixRe matches the start of DeGiro's descriptions of a dividend paid in shares, such as
+// "Dividendo em ações: 1,5 XYZ @ 12,34 EUR" or "Stock dividend: 2 XYZ @ 10.50 USD".
+var scripDividendPrefixRe = regexp.MustCompile(`^(?:dividendo em ac?[çc]ões|stock dividend|scrip dividend)\b`)
+
+// scripDividendRe reads the quantity and price of the shares received out of a scrip dividend description.
+var scripDividendRe = regexp.MustCompile(`(?i)^(?:dividendo em ac?[çc]ões|stock dividend|scrip dividend)\s*:?\s*([\d.,]+)\s+.+?\s*@\s*([\d.,]+)`)
+
+// isScripDividend reports whether a description is a dividend paid in shares rather than cash.
+func isScripDividend(lowerDesc string) bool {
+	return scripDividendPrefixRe.MatchString(lowerDesc)
+}
+
+// parseDescriptionNumbers parses the quantity and price written in a description. The PT and NL exports
+// write decimals with a comma and the EN export with a dot; the decimal separator of the price tells
+// which, so a quantity "1.500" next to a price "12,34" is 1500 shares and "1.5" next to "12.34" is one
+// and a half. When the price does not tell (no separator, or three digits after it), a lone separator
+// in the quantity is its decimal point.
+func parseDescriptionNumbers(quantityStr, priceStr string) (quantity, price float64, err error) {
+	if price, err = spreadsheet.ParseNumber(priceStr); err != nil {
+		return 0, 0, err
+	}
+	switch decimals := priceStr[strings.LastIndexAny(priceStr, ".,")+1:]; {
+	case !strings.ContainsAny(priceStr, ".,") || len(decimals) == 3:
+		quantity, err = spreadsheet.ParseNumber(quantityStr)
+	case strings.LastIndexByte(priceStr, ',') > strings.LastIndexByte(priceStr, '.'):
+		quantity, err = strconv.ParseFloat(strings.NewReplacer(".", "", ",", ".").Replace(quantityStr), 64)
+	default:
+		quantity, err = strconv.ParseFloat(strings.ReplaceAll(quantityStr, ",", ""), 64)
+	}
+	return quantity, price, err
+}
+
+// transactionTaxSubType classifies transaction taxes charged on trades: "STAMP_DUTY" for
+// stamp duties (e.g. UK, "imposto de selo") and "FTT" for financial transaction taxes (France, Italy).
+// It returns an empty string for any other description.
+func transactionTaxSubType(lowerDesc string) string {
+	switch {
+	case strings.Contains(lowerDesc, "imposto de selo"),
+		strings.Contains(lowerDesc, "stamp duty"):
+		return "STAMP_DUTY"
+	case strings.Contains(lowerDesc, "transações financeiras"),
+		strings.Contains(lowerDesc, "transacções financeiras"),
+		strings.Contains(lowerDesc, "financial transaction tax"),
+		strings.Contains(lowerDesc, "taxe sur les transactions financières"),
+		strings.Contains(lowerDesc, "tobin"),
+		slices.Contains(strings.Fields(lowerDesc), "ftt"):
+		return "FTT"
+	}
+	return ""
+}
+
+// moneyMarketFundSubType classifies the rows of the money-market fund DeGiro keeps uninvested cash in:
+// models.MoneyMarketConversionSubType for cash converted into units of the fund or back ("Conversão do
+// Fundo do Mercado Monetário") and models.MoneyMarketPriceChangeSubType for the change in value of the
+// units ("Alteração do preço do Fundo do Mercado Monetário"). It returns an empty string for any other
+// description.
+func moneyMarketFundSubType(lowerDesc string) string {
+	if !strings.Contains(lowerDesc, "fundo do mercado") &&
+		!strings.Contains(lowerDesc, "fundo monetário") &&
+		!strings.Contains(lowerDesc, "money market fund") {
+		return ""
+	}
+	switch {
+	case strings.Contains(lowerDesc, "conversão"),
+		strings.Contains(lowerDesc, "conversion"):
+		return models.MoneyMarketConversionSubType
+	case strings.Contains(lowerDesc, "alteração do preço"),
+		strings.Contains(lowerDesc, "variação do preço"),
+		strings.Contains(lowerDesc, "price change"):
+		return models.MoneyMarketPriceChangeSubType
+	}
+	return ""
+}
+
+// bondIncomeSubType classifies the income of bonds: "ACCRUED_INTEREST" for the interest accrued since
+// the last coupon, paid on a purchase and received on a sale, and "COUPON" for coupons. It returns an
+// empty string for any other description.
+func bondIncomeSubType(lowerDesc string) string {
+	switch {
+	case strings.Contains(lowerDesc, "juros corridos"),
+		strings.Contains(lowerDesc, "juros decorridos"),
+		strings.Contains(lowerDesc, "accrued interest"):
+		return "ACCRUED_INTEREST"
+	case strings.Contains(lowerDesc, "cupão"),
+		strings.Contains(lowerDesc, "cupom"),
+		strings.Contains(lowerDesc, "coupon"):
+		return "COUPON"
+	}
+	return ""
+}
+
+// isFXLeg reports whether a row is one side of a currency conversion.
+func isFXLeg(lowerDesc string) bool {
+	return strings.Contains(lowerDesc, "crédito de divisa") ||
+		strings.Contains(lowerDesc, "levantamento de divisa") ||
+		strings.Contains(lowerDesc, "fx credit") ||
+		strings.Contains(lowerDesc, "fx withdrawal") ||
+		strings.Contains(lowerDesc, "fx debit")
+}
+
+// findRealizedFXRate returns the exchange rate (units of currency per EUR) at which the FX legs
+// sharing the trade's OrderID were executed. The rate is derived from the amounts of the foreign
+// and EUR legs; if one of them is missing, the rate DeGiro prints on the FX row is used.
+func findRealizedFXRate(orderID, currency string, transactions []RawTransaction) (float64, bool) {
+	currency = strings.TrimSpace(currency)
+	if orderID == "" || currency == "" || strings.EqualFold(currency, "EUR") {
+		return 0, false
+	}
+	var foreignAmount, eurAmount, printedRate float64
+	for _, transaction := range transactions {
+		if transaction.OrderID != orderID || !isFXLeg(strings.ToLower(transaction.Description)) {
+			continue
+		}
+		amount, err := spreadsheet.ParseNumber(transaction.Amount)
+		if err != nil {
+			continue
+		}
+		switch legCurrency := strings.TrimSpace(transaction.Currency); {
+		case strings.EqualFold(legCurrency, currency):
+			foreignAmount += math.Abs(amount)
+		case strings.EqualFold(legCurrency, "EUR"):
+			eurAmount += math.Abs(amount)
+		}
+		if rate, err := spreadsheet.ParseNumber(transaction.ExchangeRate); err == nil && rate > 0 {
+			printedRate = rate
 		}
-	} else {
-		txType = "STOCK"
 	}
+	if foreignAmount > 0 && eurAmount > 0 {
+		return foreignAmount / eurAmount, true
+	}
+	if printedRate > 0 {
+		return printedRate, true
+	}
+	return 0, false
+}
 
-	return
+// splitOrderCommission shares the commission of an order executed in several partial fills among
+// its trades by quantity. DeGiro charges the commission once per order, and each trade of the order
+// is given the whole of it by convert; shared out, the commission of each lot is its own and the
+// trades of the order add up to what was charged.
+func splitOrderCommission(txs []models.CanonicalTransaction) {
+	var fills []*models.CanonicalTransaction
+	var total int64
+	for i := range txs {
+		if (txs[i].TransactionType == "STOCK" || txs[i].TransactionType == "OPTION") && txs[i].Commission > 0 {
+			fills = append(fills, &txs[i])
+			total += int64(math.Round(txs[i].Quantity))
+		}
+	}
+	if len(fills) < 2 || total <= 0 {
+		return
+	}
+	commission := models.NewMoney(fills[0].Commission)
+	var allocated int64
+	for _, fill := range fills {
+		quantity := int64(math.Round(fill.Quantity))
+		fill.Commission = commission.Allocate(allocated, quantity, total).Float64()
+		allocated += quantity
+	}
 }
 
 // findCommissionForOrder remains the same as before.
@@ -218,8 +661,7 @@ func findCommissionForOrder(orderId string, transactions []RawTransaction) (floa
 	var totalCommission float64
 	for _, transaction := range transactions {
 		if transaction.OrderID == orderId && strings.Contains(transaction.Description, "Comissões de transação") {
-			normalizedAmount := normalizeDecimalString(transaction.Amount)
-			amount, err := strconv.ParseFloat(normalizedAmount, 64)
+			amount, err := spreadsheet.ParseNumber(transaction.Amount)
 			if err != nil {
 				return 0, fmt.Errorf("invalid commission amount for transaction %s: %w", transaction.OrderID, err)
 			}
@@ -0,0 +1,18 @@
+package degiro
+
+import (
+	"os"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/parsers/parsertest"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	os.Exit(m.Run())
+}
+
+func TestParseGolden(t *testing.T) {
+	parsertest.Golden(t, func() parsertest.Parser { return NewParser() }, ".csv", "scrip_dividends")
+}
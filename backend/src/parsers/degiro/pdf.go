@@ -0,0 +1,270 @@
+// backend/src/parsers/degiro/pdf.go
+package degiro
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/parsers/pdf"
+)
+
+// DeGiro also sends the account statement as a PDF, with the columns of the CSV export laid out as a
+// table. The rows of the table are read back into CSV records and parsed like the CSV export. The PDF
+// has no Order ID column, so the rows of an order (the trade, its commission and FX legs) are told
+// apart by their date and time instead.
+
+// pdfCellGap is the space, in font sizes, between texts that belong to different cells of the table.
+const pdfCellGap = 0.8
+
+// manualConfirmation prefixes the reason of rows quarantined because they were not read with certainty.
+const manualConfirmation = "needs manual confirmation: "
+
+// pdfHeader is the header of the CSV records the rows of a PDF statement are turned into.
+var pdfHeader = []string{"Date", "Time", "Value date", "Product", "ISIN", "Description", "FX", "Change", "", "Balance", "", "Order Id"}
+
+var (
+	pdfDateRe       = regexp.MustCompile(`^\d{2}-\d{2}-\d{4}$`)
+	pdfDateInTextRe = regexp.MustCompile(`\b\d{2}-\d{2}-\d{4}\b`)
+	pdfChangeRe     = regexp.MustCompile(`^([A-Z]{3})\s*(-?[\d.,]+)$`)
+)
+
+// pdfColumn is a column of the statement table, located by its header cell.
+type pdfColumn struct {
+	col   column // -1 for columns that are not read, such as the balance
+	start float64
+}
+
+// pdfRow is a row of the table, possibly spanning several lines when a cell wraps.
+type pdfRow struct {
+	cells map[column]string
+	lines []string
+}
+
+// RecognizesPDF reports whether data is a DeGiro account statement in PDF.
+func RecognizesPDF(data []byte) bool {
+	pages, err := pdf.Read(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	for _, page := range pages {
+		for _, line := range page.Lines() {
+			if _, ok := pdfTableColumns(line); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statementFromPDF reads the table of a PDF account statement into a CSV with pdfHeader. Rows that
+// cannot be read with certainty are quarantined for the user to confirm instead of being imported.
+func (p *DeGiroParser) statementFromPDF(file io.Reader) ([]byte, error) {
+	pages, err := pdf.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("degiro parser: %w", err)
+	}
+
+	var rows []pdfRow
+	var columns []pdfColumn
+	found := false
+	for _, page := range pages {
+		var current *pdfRow
+		for _, line := range page.Lines() {
+			if header, ok := pdfTableColumns(line); ok {
+				// The header is repeated on every page of the table.
+				columns, found, current = header, true, nil
+				continue
+			}
+			if columns == nil {
+				continue
+			}
+			cells := assignPDFCells(line, columns)
+			switch {
+			case pdfDateRe.MatchString(cells[colDate]):
+				rows = append(rows, pdfRow{cells: cells, lines: []string{line.String()}})
+				current = &rows[len(rows)-1]
+			case current != nil && onlyWrappedCells(cells):
+				for col, text := range cells {
+					current.cells[col] = strings.TrimSpace(current.cells[col] + " " + text)
+				}
+				current.lines = append(current.lines, line.String())
+			case pdfDateInTextRe.MatchString(line.String()):
+				// A dated line that does not fit the columns, e.g. a row whose cells ran together.
+				p.skip(pdfHeader, rawPDFRecord([]string{line.String()}, nil), manualConfirmation+"the row could not be read from the PDF")
+				current = nil
+			default:
+				current = nil // Page footers, totals and other text below the table
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("degiro parser: no account statement table found in the PDF")
+	}
+
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		record, err := row.record()
+		if err != nil {
+			p.skip(pdfHeader, rawPDFRecord(row.lines, record), manualConfirmation+err.Error())
+			continue
+		}
+		records = append(records, record)
+	}
+	records = p.linkPDFOrders(records)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(pdfHeader)
+	writer.WriteAll(records)
+	return buf.Bytes(), writer.Error()
+}
+
+// pdfTableColumns reports whether the line is the header of the statement table and locates its columns.
+func pdfTableColumns(line pdf.Line) ([]pdfColumn, bool) {
+	cells := line.Cells(pdfCellGap)
+	names := make([]string, len(cells))
+	for i, cell := range cells {
+		names[i] = cell.S
+	}
+	index, err := mapHeader(names)
+	if err != nil {
+		return nil, false
+	}
+	columns := make([]pdfColumn, len(cells))
+	for i, cell := range cells {
+		columns[i] = pdfColumn{col: -1, start: cell.X}
+	}
+	for col, i := range index {
+		columns[i].col = col
+	}
+	return columns, true
+}
+
+// assignPDFCells puts each cell of a line in the column it overlaps most. Amounts are right-aligned,
+// so a cell may start left of its header; overlap copes with both alignments.
+func assignPDFCells(line pdf.Line, columns []pdfColumn) map[column]string {
+	cells := make(map[column]string)
+	for _, cell := range line.Cells(0.25) {
+		best, bestOverlap := -1, -1.0
+		for i, c := range columns {
+			from, to := math.Inf(-1), math.Inf(1)
+			if i > 0 {
+				from = c.start
+			}
+			if i+1 < len(columns) {
+				to = columns[i+1].start
+			}
+			overlap := math.Min(cell.End(), to) - math.Max(cell.X, from)
+			if cell.Width == 0 && cell.X >= from && cell.X < to {
+				overlap = 0 // A text of unknown width belongs where it starts
+			}
+			if overlap > bestOverlap {
+				best, bestOverlap = i, overlap
+			}
+		}
+		if best < 0 || columns[best].col < 0 {
+			continue
+		}
+		col := columns[best].col
+		cells[col] = strings.TrimSpace(cells[col] + " " + cell.S)
+	}
+	return cells
+}
+
+// onlyWrappedCells reports whether a line only continues the product name or description of the row
+// above, which wrap when they are too long for their column.
+func onlyWrappedCells(cells map[column]string) bool {
+	for col := range cells {
+		if col != colProduct && col != colDescription {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+// record turns the row into a CSV record with pdfHeader. The Change cell holds the currency and the amount.
+func (row pdfRow) record() ([]string, error) {
+	c := row.cells
+	record := []string{c[colDate], c[colTime], c[colValueDate], c[colProduct], c[colISIN], c[colDescription], c[colFX], "", "", "", "", c[colOrderID]}
+	change := pdfChangeRe.FindStringSubmatch(strings.ReplaceAll(c[colChange], "\u00A0", " "))
+	if change == nil {
+		record[7] = c[colChange]
+		return record, fmt.Errorf("could not read the amount %q", c[colChange])
+	}
+	record[7], record[8] = change[1], change[2]
+	if c[colDescription] == "" {
+		return record, fmt.Errorf("the row has no description")
+	}
+	return record, nil
+}
+
+// rawPDFRecord builds the RawTransaction of a quarantined PDF row. The payload is the record when one
+// could be built, so the row can be corrected and parsed again like a CSV row.
+func rawPDFRecord(lines []string, record []string) RawTransaction {
+	if record == nil {
+		record = make([]string, len(pdfHeader))
+		record[5] = strings.Join(lines, " ")
+	}
+	return RawTransaction{RawLine: strings.Join(lines, " | "), Record: record}
+}
+
+// linkPDFOrders gives the rows of each order a shared Order ID, as the CSV export has. An order's rows
+// share the date and time of the trade; when several trades were executed in the same minute their
+// commissions and FX legs cannot be attributed, so the trades are quarantined for the user to confirm.
+func (p *DeGiroParser) linkPDFOrders(records [][]string) [][]string {
+	const orderIDField = 11
+	linked := make([][]string, 0, len(records))
+	for start := 0; start < len(records); {
+		end := start + 1
+		for end < len(records) && records[end][0] == records[start][0] && records[end][1] == records[start][1] {
+			end++
+		}
+		group := records[start:end]
+		start = end
+
+		var trades []int
+		for i, record := range group {
+			if isPDFTrade(record) && record[orderIDField] == "" {
+				trades = append(trades, i)
+			}
+		}
+		orderID := ""
+		if len(trades) == 1 && group[0][1] != "" {
+			orderID = "pdf-" + group[0][0] + "-" + group[0][1]
+		}
+		for i, record := range group {
+			if len(trades) > 1 && slices.Contains(trades, i) {
+				p.skip(pdfHeader, RawTransaction{RawLine: strings.Join(record, ","), Record: record},
+					manualConfirmation+"several trades were executed at the same time, so their commissions and exchange rates cannot be told apart")
+				continue
+			}
+			if orderID != "" && record[orderIDField] == "" && belongsToOrder(record) {
+				record[orderIDField] = orderID
+			}
+			linked = append(linked, record)
+		}
+	}
+	return linked
+}
+
+func isPDFTrade(record []string) bool {
+	txType, _, _, _, _, _ := classifyDeGiroTransaction(pdfRaw(record))
+	return txType == "STOCK" || txType == "OPTION"
+}
+
+// belongsToOrder reports whether a row is part of a trade's order: the trade itself, its commission
+// or one of the FX legs converting its amount.
+func belongsToOrder(record []string) bool {
+	txType, _, _, _, _, _ := classifyDeGiroTransaction(pdfRaw(record))
+	return txType == "STOCK" || txType == "OPTION" || txType == "COMMISSION_IGNORE" || txType == "FX_LEG"
+}
+
+func pdfRaw(record []string) RawTransaction {
+	return RawTransaction{Name: record[3], ISIN: record[4], Description: record[5]}
+}
@@ -0,0 +1,210 @@
+// backend/src/parsers/degiro/transactions.go
+package degiro
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
+)
+
+// Besides the account statement, DeGiro exports the trades alone ("Transações" / "Transactions"), one
+// row per execution with explicit quantity, price and costs columns. It has no dividends, fees
+// or deposits, but its quantities and commissions are read as printed rather than out of a description.
+// Its trades carry the OrderID of the account statement, so importing both merges them, see
+// IsTradesExportRow.
+
+// TradesRawPrefix starts the raw text of the transactions read from the trades export.
+const TradesRawPrefix = "DeGiroTrade|"
+
+// IsTradesExportRow reports whether a transaction's raw text comes from the trades export.
+func IsTradesExportRow(rawText string) bool {
+	return strings.HasPrefix(rawText, TradesRawPrefix)
+}
+
+// tradeColumn is a field of the trades export that the parser reads.
+type tradeColumn int
+
+const (
+	tradeColDate tradeColumn = iota
+	tradeColProduct
+	tradeColISIN
+	tradeColQuantity   // Negative for sales
+	tradeColPrice      // Price per unit; its currency is in the unnamed column right after it
+	tradeColLocalValue // Signed value in the trade currency, negative for purchases; currency right after it
+	tradeColFX
+	tradeColCosts // Commission and third-party fees, negative, in EUR
+	tradeColOrderID
+)
+
+// tradeColumnNames are the header names of each column in the EN, PT and NL exports, lowercased.
+var tradeColumnNames = map[tradeColumn][]string{
+	tradeColDate:       {"date", "data", "datum"},
+	tradeColProduct:    {"product", "produto"},
+	tradeColISIN:       {"isin"},
+	tradeColQuantity:   {"quantity", "quantidade", "aantal"},
+	tradeColPrice:      {"price", "preço", "preços", "koers"},
+	tradeColLocalValue: {"local value", "valor local", "lokale waarde"},
+	tradeColFX:         {"exchange rate", "taxa de câmbio", "taxa de cambio", "wisselkoers"},
+	tradeColCosts: {"transaction and/or third party fees", "transaction costs", "transaction and/or third party costs",
+		"custos de transação", "custos de transacção", "custos de transação e/ou taxas de terceiros",
+		"transactiekosten en/of kosten van derden", "transactiekosten"},
+	tradeColOrderID: {"order id", "order_id", "id da ordem", "id ordem"},
+}
+
+// requiredTradeColumns must be present in the header for a file to be read as a trades export.
+var requiredTradeColumns = []tradeColumn{tradeColDate, tradeColProduct, tradeColISIN, tradeColQuantity, tradeColPrice, tradeColOrderID}
+
+// tradeColumnIndex maps the columns found in a trades export header to their position.
+type tradeColumnIndex map[tradeColumn]int
+
+// mapTradesHeader locates the columns of a trades export by name. It reports false when a required
+// column is missing, such as for the account statement.
+func mapTradesHeader(header []string) (tradeColumnIndex, bool) {
+	index := make(tradeColumnIndex)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		for col, names := range tradeColumnNames {
+			if _, found := index[col]; !found && slices.Contains(names, name) {
+				index[col] = i
+			}
+		}
+	}
+	for _, col := range requiredTradeColumns {
+		if _, found := index[col]; !found {
+			return nil, false
+		}
+	}
+	return index, true
+}
+
+// field returns the trimmed value of col in record, or "" when the column is absent.
+func (idx tradeColumnIndex) field(record []string, col tradeColumn) string {
+	i, found := idx[col]
+	if !found || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// currencyAfter returns the currency in the unnamed column following col.
+func (idx tradeColumnIndex) currencyAfter(record []string, col tradeColumn) string {
+	i, found := idx[col]
+	if !found || i+1 >= len(record) {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(record[i+1]))
+}
+
+// parseTrades reads the rows of a trades export after its header, handing the trades to handle in
+// batches of at most batchSize.
+func (p *DeGiroParser) parseTrades(reader *csv.Reader, header []string, columns tradeColumnIndex, batchSize int, guard *spreadsheet.Guard, handle func([]models.CanonicalTransaction) error) error {
+	var batch []models.CanonicalTransaction
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return spreadsheet.ReadError("degiro parser", err)
+		}
+		if err := guard.Row(); err != nil {
+			return err
+		}
+		if columns.field(record, tradeColDate) == "" && columns.field(record, tradeColOrderID) == "" {
+			continue
+		}
+
+		tx, err := convertTrade(columns, record)
+		if err != nil {
+			p.skip(header, RawTransaction{RawLine: strings.Join(record, ","), Record: record}, err.Error())
+			continue
+		}
+		batch = append(batch, tx)
+		if len(batch) >= batchSize {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		return handle(batch)
+	}
+	return nil
+}
+
+// convertTrade maps one row of the trades export to a CanonicalTransaction.
+func convertTrade(columns tradeColumnIndex, record []string) (models.CanonicalTransaction, error) {
+	// Only the day is kept, as for the account statement, so the trades of both files match.
+	date, err := time.Parse("02-01-2006", columns.field(record, tradeColDate))
+	if err != nil {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid date")
+	}
+	quantity, err := spreadsheet.ParseNumber(columns.field(record, tradeColQuantity))
+	if err != nil || quantity == 0 {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid quantity")
+	}
+	price, err := spreadsheet.ParseNumber(columns.field(record, tradeColPrice))
+	if err != nil || price < 0 {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid price")
+	}
+	orderID := columns.field(record, tradeColOrderID)
+	if orderID == "" {
+		return models.CanonicalTransaction{}, fmt.Errorf("missing order id")
+	}
+
+	// The local value is what the trade moved in its own currency; without it, price times quantity.
+	currency := columns.currencyAfter(record, tradeColLocalValue)
+	amount, err := spreadsheet.ParseNumber(columns.field(record, tradeColLocalValue))
+	if err != nil || amount == 0 || currency == "" {
+		amount = -quantity * price
+		currency = columns.currencyAfter(record, tradeColPrice)
+	}
+	if currency == "" {
+		return models.CanonicalTransaction{}, fmt.Errorf("missing currency")
+	}
+	costs, err := spreadsheet.ParseNumber(columns.field(record, tradeColCosts))
+	if err != nil {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid transaction costs")
+	}
+
+	productName := columns.field(record, tradeColProduct)
+	txType, subType := classifyProduct(productName)
+	buySell := "BUY"
+	if quantity < 0 {
+		buySell = "SELL"
+	}
+
+	tx := models.CanonicalTransaction{
+		Source:             "degiro",
+		TransactionDate:    date,
+		ProductName:        productName,
+		ISIN:               columns.field(record, tradeColISIN),
+		Quantity:           math.Abs(quantity),
+		Price:              price,
+		Commission:         math.Abs(costs), // DeGiro charges the costs in EUR, like the account statement
+		Currency:           currency,
+		OrderID:            orderID,
+		RawText:            TradesRawPrefix + strings.Join(record, ","),
+		SourceAmount:       amount,
+		Amount:             amount,
+		TransactionType:    txType,
+		TransactionSubType: subType,
+		BuySell:            buySell,
+	}
+	// The rate DeGiro executed the conversion at, in units of the trade currency per EUR.
+	if rate, err := spreadsheet.ParseNumber(columns.field(record, tradeColFX)); err == nil && rate > 0 && currency != "EUR" {
+		tx.ExchangeRate = rate
+	}
+	return tx, nil
+}
@@ -0,0 +1,43 @@
+// backend/src/parsers/detect.go
+package parsers
+
+import (
+	"bytes"
+
+	"github.com/username/taxfolio/backend/src/parsers/degiro"
+	"github.com/username/taxfolio/backend/src/parsers/etoro"
+	"github.com/username/taxfolio/backend/src/parsers/ibkr"
+	"github.com/username/taxfolio/backend/src/parsers/pdf"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
+	"github.com/username/taxfolio/backend/src/parsers/xtb"
+)
+
+// DetectSource returns the upload source whose parser reads data, judging by its content rather than
+// its file name, or "" when no broker export is recognized. Generic CSV files are never detected, as
+// only the user's column mapping can tell them apart.
+func DetectSource(data []byte) string {
+	if ibkr.Recognizes(data) {
+		return "ibkr"
+	}
+	if bytes.HasPrefix(data, pdf.Magic) {
+		if degiro.RecognizesPDF(data) {
+			return "degiro"
+		}
+		return ""
+	}
+	sheets, err := spreadsheet.Read(bytes.NewReader(data))
+	if err != nil || len(sheets) == 0 {
+		return ""
+	}
+	// DeGiro exports are CSV files with the header on the first row.
+	if rows := sheets[0].Rows; len(sheets) == 1 && len(rows) > 0 && degiro.Recognizes(rows[0]) {
+		return "degiro"
+	}
+	if xtb.Recognizes(sheets) {
+		return "xtb"
+	}
+	if etoro.Recognizes(sheets) {
+		return "etoro"
+	}
+	return ""
+}
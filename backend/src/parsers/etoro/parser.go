@@ -0,0 +1,275 @@
+// backend/src/parsers/etoro/parser.go
+package etoro
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
+)
+
+const (
+	source = "etoro"
+	// eToro accounts are held in USD; every amount in the statement is in USD.
+	accountCurrency = "USD"
+)
+
+var (
+	activityColumns = []string{"date", "type", "details", "amount", "position id"}
+	dividendColumns = []string{"date of payment", "instrument name", "net dividend received (usd)"}
+	closedColumns   = []string{"position id", "isin"}
+	dateLayouts     = []string{"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006", "2006-01-02 15:04:05", "2006-01-02"}
+)
+
+// EToroParser implements the parsers.Parser interface for the eToro account statement,
+// as XLSX or as a CSV export of one of its sheets.
+type EToroParser struct {
+	skipped []models.SkippedRow
+}
+
+// NewParser creates a new instance of the EToroParser.
+func NewParser() *EToroParser {
+	return &EToroParser{}
+}
+
+// table is a sheet of the statement with its header located.
+type table struct {
+	header    spreadsheet.Header
+	headerRow []string
+	rows      [][]string
+}
+
+// Parse reads an eToro account statement. Trades, cash movements and fees come from the
+// "Account Activity" sheet; dividends come from the "Dividends" sheet when present, since it
+// carries the ISIN and the withholding tax. ISINs of trades are looked up by position ID.
+func (p *EToroParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	p.skipped = nil
+
+	sheets, err := spreadsheet.Read(file)
+	if err != nil {
+		return nil, spreadsheet.ReadError("etoro parser", err)
+	}
+
+	var activity, dividends []table
+	isinByPosition := make(map[string]string)
+	for _, sheet := range sheets {
+		if t, ok := findTable(sheet, activityColumns); ok {
+			activity = append(activity, t)
+		} else if t, ok := findTable(sheet, dividendColumns); ok {
+			dividends = append(dividends, t)
+		}
+		if t, ok := findTable(sheet, closedColumns); ok {
+			for _, row := range t.rows {
+				if isin := t.header.Get(row, "isin"); isin != "" {
+					isinByPosition[t.header.Get(row, "position id")] = isin
+				}
+			}
+		}
+	}
+	if len(activity) == 0 && len(dividends) == 0 {
+		return nil, spreadsheet.HeaderNotFound("etoro parser", sheets, activityColumns...)
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	for _, t := range dividends {
+		for _, row := range t.rows {
+			if t.header.Get(row, "date of payment") == "" {
+				continue
+			}
+			if isin := t.header.Get(row, "isin"); isin != "" {
+				isinByPosition[t.header.Get(row, "position id")] = isin
+			}
+			txs, err := p.convertDividend(t.header, row)
+			if err != nil {
+				p.skip(t, row, err)
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, txs...)
+		}
+	}
+	for _, t := range activity {
+		for _, row := range t.rows {
+			if t.header.Get(row, "date") == "" || t.header.Get(row, "type") == "" {
+				continue
+			}
+			activityType := strings.ToLower(t.header.Get(row, "type"))
+			if activityType == "dividend" && len(dividends) > 0 {
+				continue // Already imported, with ISIN and withholding tax, from the Dividends sheet
+			}
+			tx, err := p.convertActivity(t.header, row, isinByPosition)
+			if err != nil {
+				p.skip(t, row, err)
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, tx)
+		}
+	}
+	return canonicalTxs, nil
+}
+
+// SkippedRows returns the rows dropped by the last call to Parse.
+func (p *EToroParser) SkippedRows() []models.SkippedRow {
+	return p.skipped
+}
+
+func (p *EToroParser) skip(t table, row []string, err error) {
+	logger.L.Warn("eToro Parser: Skipping row", "error", err)
+	p.skipped = append(p.skipped, models.SkippedRow{
+		RawText: strings.Join(row, ","),
+		Reason:  err.Error(),
+		Payload: spreadsheet.Payload(t.headerRow, row),
+	})
+}
+
+// Recognizes reports whether any of the sheets is the Account Activity or Dividends sheet of an eToro
+// account statement.
+func Recognizes(sheets []spreadsheet.Sheet) bool {
+	for _, sheet := range sheets {
+		if _, ok := findTable(sheet, activityColumns); ok {
+			return true
+		}
+		if _, ok := findTable(sheet, dividendColumns); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func findTable(sheet spreadsheet.Sheet, required []string) (table, bool) {
+	headerRow, header, ok := spreadsheet.FindHeader(sheet.Rows, required...)
+	if !ok {
+		return table{}, false
+	}
+	return table{header: header, headerRow: sheet.Rows[headerRow], rows: sheet.Rows[headerRow+1:]}, true
+}
+
+// convertActivity maps one Account Activity row to a CanonicalTransaction.
+func (p *EToroParser) convertActivity(header spreadsheet.Header, row []string, isinByPosition map[string]string) (models.CanonicalTransaction, error) {
+	date, err := spreadsheet.ParseDate(header.Get(row, "date"), dateLayouts...)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	amount, err := spreadsheet.ParseNumber(header.Get(row, "amount"))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	activityType := strings.ToLower(header.Get(row, "type"))
+	details := header.Get(row, "details")
+	positionID := header.Get(row, "position id")
+	assetType := strings.ToLower(header.Get(row, "asset type"))
+
+	// Details hold the instrument as "SYMBOL/CURRENCY". The ISIN stays empty when neither the closed
+	// positions nor the dividends list the position.
+	symbol := strings.TrimSpace(strings.Split(details, "/")[0])
+
+	tx := models.CanonicalTransaction{
+		Source:          source,
+		TransactionDate: date,
+		ProductName:     symbol,
+		ISIN:            isinByPosition[positionID],
+		Currency:        accountCurrency,
+		OrderID:         positionID,
+		RawText:         "AccountActivity|" + strings.Join(row, "|"),
+		SourceAmount:    amount,
+	}
+
+	switch activityType {
+	case "open position", "position closed":
+		if assetType != "" && assetType != "stocks" && assetType != "etf" {
+			return models.CanonicalTransaction{}, fmt.Errorf("unsupported asset type %q", header.Get(row, "asset type"))
+		}
+		units, err := spreadsheet.ParseNumber(header.Get(row, "units", "units / contracts"))
+		if err != nil || units == 0 {
+			return models.CanonicalTransaction{}, fmt.Errorf("invalid units %q", header.Get(row, "units", "units / contracts"))
+		}
+		if units != math.Trunc(units) {
+			return models.CanonicalTransaction{}, fmt.Errorf("fractional units %q are not supported", header.Get(row, "units", "units / contracts"))
+		}
+		tx.TransactionType = "STOCK"
+		tx.Quantity = math.Abs(units)
+		tx.Price = math.Abs(amount) / tx.Quantity
+		// Opening and closing share the position ID; suffix it so stamp duty only attaches to the purchase.
+		if activityType == "open position" {
+			tx.BuySell = "BUY"
+			tx.Amount = -math.Abs(amount)
+			tx.OrderID = positionID + "-open"
+		} else {
+			tx.BuySell = "SELL"
+			tx.Amount = math.Abs(amount)
+			tx.OrderID = positionID + "-close"
+		}
+	case "sdrt":
+		tx.TransactionType = "TAX"
+		tx.TransactionSubType = "STAMP_DUTY"
+		tx.OrderID = positionID + "-open"
+		tx.Amount = -math.Abs(amount)
+	case "dividend":
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = math.Abs(amount)
+	case "deposit":
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "DEPOSIT"
+		tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		tx.Amount = math.Abs(amount)
+	case "withdraw request":
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "WITHDRAWAL"
+		tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+		tx.Amount = -math.Abs(amount)
+	case "withdraw fee", "overnight fee", "conversion fee":
+		tx.TransactionType = "FEE"
+		tx.ProductName = header.Get(row, "type")
+		tx.Amount = -math.Abs(amount)
+	default:
+		return models.CanonicalTransaction{}, fmt.Errorf("unsupported activity type %q", header.Get(row, "type"))
+	}
+	return tx, nil
+}
+
+// convertDividend maps one Dividends row to the gross dividend and, when tax was withheld, its withholding tax.
+func (p *EToroParser) convertDividend(header spreadsheet.Header, row []string) ([]models.CanonicalTransaction, error) {
+	date, err := spreadsheet.ParseDate(header.Get(row, "date of payment"), dateLayouts...)
+	if err != nil {
+		return nil, err
+	}
+	net, err := spreadsheet.ParseNumber(header.Get(row, "net dividend received (usd)"))
+	if err != nil {
+		return nil, err
+	}
+	withheld, err := spreadsheet.ParseNumber(header.Get(row, "withholding tax amount (usd)"))
+	if err != nil {
+		return nil, err
+	}
+
+	name := header.Get(row, "instrument name")
+	positionID := header.Get(row, "position id")
+	rawText := "Dividend|" + strings.Join(row, "|")
+
+	dividend := models.CanonicalTransaction{
+		Source:          source,
+		TransactionDate: date,
+		ProductName:     name,
+		ISIN:            header.Get(row, "isin"),
+		Currency:        accountCurrency,
+		OrderID:         positionID,
+		RawText:         rawText,
+		SourceAmount:    net,
+		Amount:          math.Abs(net) + math.Abs(withheld),
+		TransactionType: "DIVIDEND",
+	}
+	txs := []models.CanonicalTransaction{dividend}
+	if withheld != 0 {
+		tax := dividend
+		tax.RawText = rawText + "|WithholdingTax"
+		tax.SourceAmount = withheld
+		tax.Amount = -math.Abs(withheld)
+		tax.TransactionSubType = "TAX"
+		txs = append(txs, tax)
+	}
+	return txs, nil
+}
@@ -0,0 +1,39 @@
+package etoro
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/parsers/parsertest"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	os.Exit(m.Run())
+}
+
+func TestParseGolden(t *testing.T) {
+	parsertest.Golden(t, func() parsertest.Parser { return NewParser() }, ".csv", "account_activity", "dividends")
+}
+
+// Without a closed positions or dividends sheet to find an ISIN in, a trade is named by its symbol and
+// keeps no ISIN; fractional units are not imported.
+func TestParseNamesBySymbolAndSkipsFractionalTrades(t *testing.T) {
+	parser := NewParser()
+	txs := parsertest.Parse(t, parser, `Date,Type,Details,Amount,Units,Position ID,Asset type
+03/01/2024 14:30:00,Open Position,MSFT/USD,740.00,2,2650000010,Stocks
+03/01/2024 14:31:00,Open Position,NVDA/USD,50.00,0.1,2650000011,Stocks
+`)
+	if len(txs) != 1 {
+		t.Fatalf("got %d transactions, want 1: %+v", len(txs), txs)
+	}
+	if tx := txs[0]; tx.ISIN != "" || tx.ProductName != "MSFT" || tx.Quantity != 2 || tx.BuySell != "BUY" {
+		t.Errorf("trade parsed as ISIN %q, product %q, quantity %v, %s", tx.ISIN, tx.ProductName, tx.Quantity, tx.BuySell)
+	}
+	skipped := parser.SkippedRows()
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Reason, "fractional") {
+		t.Errorf("skipped %+v, want the fractional trade", skipped)
+	}
+}
@@ -5,15 +5,27 @@ import (
 	"fmt"
 
 	"github.com/username/taxfolio/backend/src/parsers/degiro"
+	"github.com/username/taxfolio/backend/src/parsers/etoro"
+	"github.com/username/taxfolio/backend/src/parsers/generic"
 	"github.com/username/taxfolio/backend/src/parsers/ibkr"
+	"github.com/username/taxfolio/backend/src/parsers/xtb"
 )
 
+// Sources lists the upload sources a file can be imported from.
+var Sources = []string{"degiro", "ibkr", "xtb", "etoro", generic.Source}
+
 func GetParser(source string) (Parser, error) {
 	switch source {
 	case "degiro":
 		return degiro.NewParser(), nil
 	case "ibkr":
 		return ibkr.NewParser(), nil
+	case "xtb":
+		return xtb.NewParser(), nil
+	case "etoro":
+		return etoro.NewParser(), nil
+	case generic.Source:
+		return nil, fmt.Errorf("the generic CSV source requires a column mapping")
 	default:
 		return nil, fmt.Errorf("no parser available for source: %s", source)
 	}
@@ -0,0 +1,267 @@
+// backend/src/parsers/generic/parser.go
+package generic
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
+)
+
+// Source is the upload source name of the generic CSV parser.
+const Source = "generic"
+
+// defaultDateLayouts are tried when the mapping does not set a date format.
+var defaultDateLayouts = []string{"02-01-2006", "2006-01-02", "02/01/2006", "02.01.2006", "2006-01-02 15:04:05", "02-01-2006 15:04:05", "02/01/2006 15:04:05"}
+
+// dateTokens translates the user-facing date format tokens into Go layout elements.
+var dateTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02", "HH", "15", "mm", "04", "ss", "05")
+
+// GenericParser implements the parsers.Parser interface for CSV files described by a user-defined column mapping.
+type GenericParser struct {
+	mapping     models.CSVMapping
+	dateLayouts []string
+	skipped     []models.SkippedRow
+}
+
+// NewParser validates the mapping and creates a GenericParser for it.
+func NewParser(mapping models.CSVMapping) (*GenericParser, error) {
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+	layouts := defaultDateLayouts
+	if mapping.DateFormat != "" {
+		layouts = []string{dateTokens.Replace(mapping.DateFormat)}
+	}
+	return &GenericParser{mapping: mapping, dateLayouts: layouts}, nil
+}
+
+// ValidateMapping checks that the mapping names the required columns and only uses known row types.
+func ValidateMapping(mapping models.CSVMapping) error {
+	var missing []string
+	if strings.TrimSpace(mapping.DateColumn) == "" {
+		missing = append(missing, "date_column")
+	}
+	if strings.TrimSpace(mapping.TypeColumn) == "" {
+		missing = append(missing, "type_column")
+	}
+	if strings.TrimSpace(mapping.AmountColumn) == "" {
+		missing = append(missing, "amount_column")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("mapping is missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if len(mapping.TypeValues) == 0 {
+		return fmt.Errorf("mapping must define at least one entry in type_values")
+	}
+	for value, rowType := range mapping.TypeValues {
+		switch rowType {
+		case models.CSVTypeBuy, models.CSVTypeSell, models.CSVTypeDividend, models.CSVTypeDividendTax,
+			models.CSVTypeReturnOfCapital, models.CSVTypeFee, models.CSVTypeDeposit, models.CSVTypeWithdrawal:
+		default:
+			return fmt.Errorf("type_values[%q]: unknown type %q", value, rowType)
+		}
+	}
+	hasInstrument := strings.TrimSpace(mapping.ISINColumn) != "" || strings.TrimSpace(mapping.ProductColumn) != ""
+	if (hasType(mapping, models.CSVTypeBuy) || hasType(mapping, models.CSVTypeSell)) &&
+		(strings.TrimSpace(mapping.QuantityColumn) == "" || !hasInstrument) {
+		return fmt.Errorf("mappings with BUY or SELL rows need quantity_column and isin_column or product_column")
+	}
+	if strings.TrimSpace(mapping.CurrencyColumn) == "" && len(strings.TrimSpace(mapping.DefaultCurrency)) != 3 {
+		return fmt.Errorf("mapping needs currency_column or a three-letter default_currency")
+	}
+	return nil
+}
+
+func hasType(mapping models.CSVMapping, rowType string) bool {
+	for _, t := range mapping.TypeValues {
+		if t == rowType {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads the CSV and converts each row according to the mapping.
+func (p *GenericParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	p.skipped = nil
+
+	sheets, err := spreadsheet.Read(file)
+	if err != nil {
+		return nil, spreadsheet.ReadError("generic parser", err)
+	}
+	required := []string{columnKey(p.mapping.DateColumn), columnKey(p.mapping.TypeColumn), columnKey(p.mapping.AmountColumn)}
+
+	var canonicalTxs []models.CanonicalTransaction
+	found := false
+	for _, sheet := range sheets {
+		headerRow, header, ok := spreadsheet.FindHeader(sheet.Rows, required...)
+		if !ok {
+			continue
+		}
+		found = true
+		for _, row := range sheet.Rows[headerRow+1:] {
+			if p.get(header, row, p.mapping.DateColumn) == "" {
+				continue // Blank or total lines
+			}
+			tx, err := p.convert(header, row)
+			if err != nil {
+				logger.L.Warn("Generic Parser: Skipping row", "error", err)
+				p.skipped = append(p.skipped, models.SkippedRow{
+					RawText: strings.Join(row, ","),
+					Reason:  err.Error(),
+					Payload: spreadsheet.Payload(sheet.Rows[headerRow], row),
+				})
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, tx)
+		}
+	}
+	if !found {
+		return nil, spreadsheet.HeaderNotFound("generic parser", sheets, required...)
+	}
+	return canonicalTxs, nil
+}
+
+// SkippedRows returns the rows dropped by the last call to Parse.
+func (p *GenericParser) SkippedRows() []models.SkippedRow {
+	return p.skipped
+}
+
+func columnKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// get returns the value of a mapped column, or "" when the column is not mapped or absent.
+func (p *GenericParser) get(header spreadsheet.Header, row []string, column string) string {
+	if column == "" {
+		return ""
+	}
+	return header.Get(row, columnKey(column))
+}
+
+// convert maps one row to a CanonicalTransaction. Signs in the file are ignored: the row type decides them.
+func (p *GenericParser) convert(header spreadsheet.Header, row []string) (models.CanonicalTransaction, error) {
+	m := p.mapping
+	date, err := spreadsheet.ParseDate(p.get(header, row, m.DateColumn), p.dateLayouts...)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	amount, err := spreadsheet.ParseNumber(p.get(header, row, m.AmountColumn))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	quantity, err := spreadsheet.ParseNumber(p.get(header, row, m.QuantityColumn))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	price, err := spreadsheet.ParseNumber(p.get(header, row, m.PriceColumn))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	commission, err := spreadsheet.ParseNumber(p.get(header, row, m.CommissionColumn))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	typeValue := p.get(header, row, m.TypeColumn)
+	rowType, ok := lookupType(m.TypeValues, typeValue)
+	if !ok {
+		return models.CanonicalTransaction{}, fmt.Errorf("type %q is not mapped", typeValue)
+	}
+
+	currency := strings.ToUpper(p.get(header, row, m.CurrencyColumn))
+	if currency == "" {
+		currency = strings.ToUpper(strings.TrimSpace(m.DefaultCurrency))
+	}
+	if currency == "" {
+		return models.CanonicalTransaction{}, fmt.Errorf("missing currency")
+	}
+
+	product := p.get(header, row, m.ProductColumn)
+	isin := p.get(header, row, m.ISINColumn)
+	if isin == "" {
+		isin = product
+	}
+	if product == "" {
+		product = isin
+	}
+
+	tx := models.CanonicalTransaction{
+		Source:          Source,
+		TransactionDate: date,
+		ProductName:     product,
+		ISIN:            isin,
+		Currency:        currency,
+		OrderID:         p.get(header, row, m.OrderIDColumn),
+		Commission:      math.Abs(commission),
+		RawText:         "Generic|" + strings.Join(row, "|"),
+		SourceAmount:    amount,
+	}
+
+	switch rowType {
+	case models.CSVTypeBuy, models.CSVTypeSell:
+		if quantity == 0 {
+			return models.CanonicalTransaction{}, fmt.Errorf("missing quantity")
+		}
+		if isin == "" {
+			return models.CanonicalTransaction{}, fmt.Errorf("missing product or ISIN")
+		}
+		tx.TransactionType = "STOCK"
+		tx.BuySell = rowType
+		tx.Quantity = math.Abs(quantity)
+		tx.Price = math.Abs(price)
+		if tx.Price == 0 {
+			tx.Price = math.Abs(amount) / tx.Quantity
+		}
+		if rowType == models.CSVTypeBuy {
+			tx.Amount = -math.Abs(amount)
+		} else {
+			tx.Amount = math.Abs(amount)
+		}
+	case models.CSVTypeDividend:
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = math.Abs(amount)
+	case models.CSVTypeDividendTax:
+		tx.TransactionType = "DIVIDEND"
+		tx.TransactionSubType = "TAX"
+		tx.Amount = -math.Abs(amount)
+	case models.CSVTypeReturnOfCapital:
+		tx.TransactionType = "RETURN_OF_CAPITAL"
+		tx.Amount = math.Abs(amount)
+	case models.CSVTypeFee:
+		tx.TransactionType = "FEE"
+		if tx.ProductName == "" {
+			tx.ProductName = typeValue
+		}
+		tx.Amount = -math.Abs(amount)
+	case models.CSVTypeDeposit:
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "DEPOSIT"
+		tx.ProductName, tx.ISIN = "Cash Deposit", ""
+		tx.Amount = math.Abs(amount)
+	case models.CSVTypeWithdrawal:
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "WITHDRAWAL"
+		tx.ProductName, tx.ISIN = "Cash Withdrawal", ""
+		tx.Amount = -math.Abs(amount)
+	}
+	return tx, nil
+}
+
+// lookupType finds the row type for a type column value, ignoring case and surrounding spaces.
+func lookupType(typeValues map[string]string, value string) (string, bool) {
+	if rowType, ok := typeValues[value]; ok {
+		return rowType, true
+	}
+	for key, rowType := range typeValues {
+		if strings.EqualFold(strings.TrimSpace(key), value) {
+			return rowType, true
+		}
+	}
+	return "", false
+}
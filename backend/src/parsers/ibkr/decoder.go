@@ -0,0 +1,111 @@
+package ibkr
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Bounds on the XML of an uploaded Flex report. Genuine reports nest five elements deep, carry at
+// most a few hundred attributes per row and short values, so anything beyond is a crafted file.
+const (
+	maxElementDepth     = 16
+	maxElementAttrs     = 512
+	maxTokenSize        = 64 << 10 // Bytes of text, a comment or an attribute value
+	maxAttributesLength = 1 << 20  // Bytes of the attribute values of one element
+	// maxTokenBytes bounds the bytes read for one token, markup and names included, so that an
+	// oversized token is refused while it is read rather than once the decoder has buffered it whole.
+	maxTokenBytes = maxAttributesLength + maxTokenSize
+)
+
+// newDecoder returns a decoder of the Flex XML in r that rejects what a Flex report never holds:
+// DTDs, and with them entity declarations and external entities, documents in an encoding other
+// than UTF-8, elements nested deeper than maxElementDepth and oversized tokens. It also returns
+// the underlying decoder, for the position of errors.
+func newDecoder(r io.Reader) (*xml.Decoder, *xml.Decoder) {
+	budget := &tokenBudget{reader: r, remaining: maxTokenBytes}
+	raw := xml.NewDecoder(budget)
+	raw.Strict = true
+	raw.Entity = nil        // Only the five predefined entities are expanded
+	raw.CharsetReader = nil // Other encodings are refused rather than converted
+	budget.decoder = raw
+	return xml.NewTokenDecoder(&strictTokens{decoder: raw, budget: budget}), raw
+}
+
+// tokenBudget reads for the decoder no more than maxTokenBytes between two tokens.
+type tokenBudget struct {
+	reader    io.Reader
+	decoder   *xml.Decoder
+	remaining int
+}
+
+func (b *tokenBudget) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		line, _ := b.decoder.InputPos()
+		return 0, &xml.SyntaxError{Msg: fmt.Sprintf("token longer than %d bytes", maxTokenBytes), Line: line}
+	}
+	if len(p) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.reader.Read(p)
+	b.remaining -= n
+	return n, err
+}
+
+// strictTokens passes on the tokens of a decoder, failing with an xml.SyntaxError at the first that
+// breaks the bounds above.
+type strictTokens struct {
+	decoder *xml.Decoder
+	budget  *tokenBudget
+	depth   int
+}
+
+func (s *strictTokens) Token() (xml.Token, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return token, err
+	}
+	s.budget.remaining = maxTokenBytes
+	switch t := token.(type) {
+	case xml.StartElement:
+		s.depth++
+		if s.depth > maxElementDepth {
+			return nil, s.syntaxError("elements are nested more than %d levels deep", maxElementDepth)
+		}
+		if len(t.Attr) > maxElementAttrs {
+			return nil, s.syntaxError("element <%s> has more than %d attributes", t.Name.Local, maxElementAttrs)
+		}
+		total := 0
+		for _, attr := range t.Attr {
+			if len(attr.Value) > maxTokenSize {
+				return nil, s.syntaxError("attribute %s of <%s> is longer than %d bytes", attr.Name.Local, t.Name.Local, maxTokenSize)
+			}
+			total += len(attr.Value)
+		}
+		if total > maxAttributesLength {
+			return nil, s.syntaxError("the attributes of <%s> are longer than %d bytes", t.Name.Local, maxAttributesLength)
+		}
+	case xml.EndElement:
+		s.depth--
+	case xml.CharData:
+		if len(t) > maxTokenSize {
+			return nil, s.syntaxError("text longer than %d bytes", maxTokenSize)
+		}
+	case xml.Comment:
+		if len(t) > maxTokenSize {
+			return nil, s.syntaxError("comment longer than %d bytes", maxTokenSize)
+		}
+	case xml.Directive:
+		return nil, s.syntaxError("DTDs and entity declarations are not allowed")
+	case xml.ProcInst:
+		if len(t.Inst) > maxTokenSize {
+			return nil, s.syntaxError("processing instruction longer than %d bytes", maxTokenSize)
+		}
+	}
+	return token, nil
+}
+
+func (s *strictTokens) syntaxError(format string, args ...any) error {
+	line, _ := s.decoder.InputPos()
+	return &xml.SyntaxError{Msg: fmt.Sprintf(format, args...), Line: line}
+}
@@ -0,0 +1,92 @@
+package ibkr
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	os.Exit(m.Run())
+}
+
+const flexReport = `<?xml version="1.0" encoding="UTF-8"?>
+<FlexQueryResponse queryName="taxfolio" type="AF">
+<FlexStatements count="1">
+<FlexStatement accountId="U1234567" fromDate="20240101" toDate="20241231">
+<Trades>
+<Trade assetCategory="STK" symbol="AAPL" description="APPLE INC" isin="US0378331005" dateTime="20240103;153010" tradeDate="20240103" quantity="5" tradePrice="185.2" tradeMoney="926" currency="USD" exchange="NASDAQ" ibCommission="-1" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="1001" />
+<Trade assetCategory="CASH" symbol="EUR.USD" dateTime="20240103;153000" quantity="900" tradePrice="1.09" tradeMoney="981" currency="USD" exchange="IDEALFX" ibCommission="-2" ibCommissionCurrency="USD" buySell="BUY" ibOrderID="1000" />
+</Trades>
+<CashTransactions>
+<CashTransaction type="Dividends" description="AAPL CASH DIVIDEND" dateTime="20240215" amount="1.2" currency="USD" levelOfDetail="DETAIL" isin="US0378331005" symbol="AAPL" />
+<CashTransaction type="Withholding Tax" description="AAPL US TAX" dateTime="20240215" amount="-0.18" currency="USD" levelOfDetail="DETAIL" isin="US0378331005" symbol="AAPL" />
+</CashTransactions>
+</FlexStatement>
+</FlexStatements>
+</FlexQueryResponse>`
+
+// FuzzParse feeds the parser arbitrary documents: it must return an error or transactions, never
+// panic, and never accept a DTD.
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(flexReport))
+	f.Add([]byte(`<FlexQueryResponse><FlexStatements><FlexStatement accountId="U1"/></FlexStatements></FlexQueryResponse>`))
+	f.Add([]byte(`<?xml version="1.0"?><!DOCTYPE x [<!ENTITY a "aaaa">]><FlexQueryResponse>&a;</FlexQueryResponse>`))
+	f.Add([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><FlexQueryResponse/>`))
+	f.Add([]byte(strings.Repeat("<a>", maxElementDepth+1)))
+	f.Add([]byte(`<FlexQueryResponse><FlexStatements><FlexStatement><Trades><Trade quantity="x"/></Trades></FlexStatement></FlexStatements></FlexQueryResponse>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := NewParser().Parse(bytes.NewReader(data))
+		if err == nil && bytes.Contains(data, []byte("<!")) && !bytes.Contains(data, []byte("<![CDATA[")) && !bytes.Contains(data, []byte("<!--")) {
+			t.Fatalf("accepted a document with a declaration: %q", data)
+		}
+	})
+}
+
+func TestParseReport(t *testing.T) {
+	txs, err := NewParser().Parse(strings.NewReader(flexReport))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(txs) == 0 {
+		t.Fatal("Parse returned no transactions")
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	reader io.Reader
+	n      int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += n
+	return n, err
+}
+
+// TestOversizedTokenStopsReading checks a token past the bounds is refused once maxTokenBytes have
+// been read, rather than after the decoder has buffered it whole.
+func TestOversizedTokenStopsReading(t *testing.T) {
+	for name, doc := range map[string]string{
+		"attribute": `<FlexQueryResponse a="` + strings.Repeat("x", 32<<20) + `"/>`,
+		"text":      `<FlexQueryResponse>` + strings.Repeat("x", 32<<20) + `</FlexQueryResponse>`,
+		"comment":   `<FlexQueryResponse><!--` + strings.Repeat("x", 32<<20) + `--></FlexQueryResponse>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := &countingReader{reader: strings.NewReader(doc)}
+			if _, err := NewParser().Parse(input); err == nil {
+				t.Fatal("Parse accepted an oversized token")
+			}
+			if limit := maxTokenBytes + 64<<10; input.n > limit {
+				t.Errorf("read %d bytes before refusing the token, want at most %d", input.n, limit)
+			}
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package ibkr
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // Time zones for SetTimeZone, which the server image may not have
 
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
 )
 
 // --- XML Data Structures ---
@@ -27,6 +31,7 @@ type FlexStatement struct {
 	AccountId        string            `xml:"accountId,attr"`
 	Trades           []Trade           `xml:"Trades>Trade"`
 	CashTransactions []CashTransaction `xml:"CashTransactions>CashTransaction"`
+	CorporateActions []CorporateAction `xml:"CorporateActions>CorporateAction"`
 }
 
 // Trade represents a stock or option trade transaction.
@@ -47,6 +52,7 @@ type Trade struct {
 	Exchange             string  `xml:"exchange,attr"`
 	IBCommission         float64 `xml:"ibCommission,attr"`
 	IBCommissionCurrency string  `xml:"ibCommissionCurrency,attr"`
+	Taxes                float64 `xml:"taxes,attr"` // Transaction taxes such as stamp duty or FTT, in the trade currency
 	BuySell              string  `xml:"buySell,attr"`
 	IBOrderID            string  `xml:"ibOrderID,attr"`
 	PutCall              string  `xml:"putCall,attr"` // For Options
@@ -64,40 +70,87 @@ type CashTransaction struct {
 	Symbol        string  `xml:"symbol,attr"`
 }
 
+// CorporateAction represents a corporate event such as a split, merger or stock dividend.
+type CorporateAction struct {
+	Type          string  `xml:"type,attr"` // e.g., "SD" for stock dividends, "FS" for forward splits
+	AssetCategory string  `xml:"assetCategory,attr"`
+	Description   string  `xml:"description,attr"`
+	DateTime      string  `xml:"dateTime,attr"`
+	Quantity      float64 `xml:"quantity,attr"`
+	Value         float64 `xml:"value,attr"` // Market value of the shares received
+	Currency      string  `xml:"currency,attr"`
+	ISIN          string  `xml:"isin,attr"`
+	Symbol        string  `xml:"symbol,attr"`
+	ActionID      string  `xml:"actionID,attr"`
+	Proceeds      float64 `xml:"proceeds,attr"` // Cash paid out, such as the redemption of a bond
+}
+
 // --- IBKR Parser Implementation ---
 
 // IBKRParser implements the parsers.Parser interface for IBKR Flex Query XML files.
-type IBKRParser struct{}
+type IBKRParser struct {
+	skipped []models.SkippedRow
+}
 
 // NewParser creates a new instance of the IBKRParser.
 func NewParser() *IBKRParser {
 	return &IBKRParser{}
 }
 
-// Parse reads an IBKR XML file and converts its rows into a slice of CanonicalTransaction.
+// Recognizes reports whether data is an IBKR Flex Query report. Only the start of the document is
+// looked at: the root element comes right after the XML declaration.
+func Recognizes(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("<FlexQueryResponse"))
+}
+
+// Parse reads an IBKR XML file and converts its rows into a slice of CanonicalTransaction. The XML is
+// read by a decoder hardened against crafted files, see newDecoder.
 func (p *IBKRParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
 	var response FlexQueryResponse
-	decoder := xml.NewDecoder(file)
+	decoder, raw := newDecoder(file)
 	if err := decoder.Decode(&response); err != nil {
-		return nil, fmt.Errorf("ibkr parser: failed to decode XML: %w", err)
+		if errors.Is(err, spreadsheet.ErrParseLimit) {
+			return nil, fmt.Errorf("ibkr parser: %w", err)
+		}
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) {
+			return nil, spreadsheet.ReadError("ibkr parser", err)
+		}
+		// Values that do not fit their attribute, such as a quantity that is not a number
+		line, _ := raw.InputPos()
+		return nil, &spreadsheet.ValidationError{Parser: "ibkr parser", Issues: []spreadsheet.ValidationIssue{{Row: line, Reason: err.Error()}}}
 	}
 
+	p.skipped = nil
 	var canonicalTxs []models.CanonicalTransaction
 
 	for _, stmt := range response.FlexStatements {
+		seen := make(map[string]int) // Raw texts of the statement's dividends and withholding taxes, see distinctRawText
+
 		// Process Trades (Stocks and Options)
 		for _, trade := range stmt.Trades {
-			// As requested, ignore internal currency exchange transactions
-			if trade.Exchange == "IDEALFX" {
+			// Currency conversions move cash between two currencies rather than buying an asset.
+			if trade.Exchange == "IDEALFX" || trade.AssetCategory == "CASH" {
+				legs, err := p.processFXTrade(trade)
+				if err != nil {
+					logger.L.Warn("IBKR Parser: Skipping FX conversion due to processing error", "ibOrderID", trade.IBOrderID, "error", err)
+					p.skip(fmt.Sprintf("FX|%s|%s|%s", trade.IBOrderID, trade.DateTime, trade.Symbol), FlexStatement{AccountId: stmt.AccountId, Trades: []Trade{trade}}, err)
+					continue
+				}
+				canonicalTxs = append(canonicalTxs, legs...)
 				continue
 			}
 
 			tx, err := p.processTrade(trade)
 			if err != nil {
 				logger.L.Warn("IBKR Parser: Skipping trade due to processing error", "ibOrderID", trade.IBOrderID, "error", err)
+				p.skip(fmt.Sprintf("Trade|%s|%s|%s", trade.IBOrderID, trade.DateTime, trade.Description), FlexStatement{AccountId: stmt.AccountId, Trades: []Trade{trade}}, err)
 				continue
 			}
 			canonicalTxs = append(canonicalTxs, tx)
+			if trade.Taxes != 0 && tx.TransactionType == "STOCK" {
+				canonicalTxs = append(canonicalTxs, p.processTradeTax(trade, tx))
+			}
 		}
 
 		// Process Cash Transactions (Dividends, Deposits, etc.)
@@ -113,6 +166,33 @@ func (p *IBKRParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error
 				tx, err := p.processDividend(cashTx)
 				if err != nil {
 					logger.L.Warn("IBKR Parser: Skipping dividend due to processing error", "description", cashTx.Description, "error", err)
+					p.skip(fmt.Sprintf("Dividend|%s|%s", cashTx.DateTime, cashTx.Description), FlexStatement{AccountId: stmt.AccountId, CashTransactions: []CashTransaction{cashTx}}, err)
+					continue
+				}
+				tx.RawText = distinctRawText(seen, tx.RawText)
+				canonicalTxs = append(canonicalTxs, tx)
+			case "Withholding Tax":
+				tx, err := p.processWithholdingTax(cashTx)
+				if err != nil {
+					logger.L.Warn("IBKR Parser: Skipping withholding tax due to processing error", "description", cashTx.Description, "error", err)
+					p.skip(fmt.Sprintf("WithholdingTax|%s|%s", cashTx.DateTime, cashTx.Description), FlexStatement{AccountId: stmt.AccountId, CashTransactions: []CashTransaction{cashTx}}, err)
+					continue
+				}
+				tx.RawText = distinctRawText(seen, tx.RawText)
+				canonicalTxs = append(canonicalTxs, tx)
+			case "Bond Interest Received", "Bond Interest Paid":
+				tx, err := p.processBondInterest(cashTx)
+				if err != nil {
+					logger.L.Warn("IBKR Parser: Skipping bond interest due to processing error", "description", cashTx.Description, "error", err)
+					p.skip(fmt.Sprintf("BondInterest|%s|%s", cashTx.DateTime, cashTx.Description), FlexStatement{AccountId: stmt.AccountId, CashTransactions: []CashTransaction{cashTx}}, err)
+					continue
+				}
+				canonicalTxs = append(canonicalTxs, tx)
+			case "Broker Interest Received", "Broker Interest Paid":
+				tx, err := p.processInterest(cashTx)
+				if err != nil {
+					logger.L.Warn("IBKR Parser: Skipping interest due to processing error", "description", cashTx.Description, "error", err)
+					p.skip(fmt.Sprintf("Interest|%s|%s", cashTx.DateTime, cashTx.Description), FlexStatement{AccountId: stmt.AccountId, CashTransactions: []CashTransaction{cashTx}}, err)
 					continue
 				}
 				canonicalTxs = append(canonicalTxs, tx)
@@ -120,16 +200,89 @@ func (p *IBKRParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error
 				tx, err := p.processCashMovement(cashTx)
 				if err != nil {
 					logger.L.Warn("IBKR Parser: Skipping cash movement due to processing error", "description", cashTx.Description, "error", err)
+					p.skip(fmt.Sprintf("CashMovement|%s|%s", cashTx.DateTime, cashTx.Description), FlexStatement{AccountId: stmt.AccountId, CashTransactions: []CashTransaction{cashTx}}, err)
 					continue
 				}
 				canonicalTxs = append(canonicalTxs, tx)
 			}
 		}
+
+		// Process Corporate Actions (stock dividends and bond maturities are currently supported)
+		for _, action := range stmt.CorporateActions {
+			if action.Type == "BM" && action.AssetCategory == "BOND" {
+				tx, err := p.processBondRedemption(action)
+				if err != nil {
+					logger.L.Warn("IBKR Parser: Skipping bond redemption due to processing error", "actionID", action.ActionID, "error", err)
+					p.skip(fmt.Sprintf("BondRedemption|%s|%s|%s", action.ActionID, action.DateTime, action.Description), FlexStatement{AccountId: stmt.AccountId, CorporateActions: []CorporateAction{action}}, err)
+					continue
+				}
+				canonicalTxs = append(canonicalTxs, tx)
+				continue
+			}
+			if action.Type != "SD" || action.AssetCategory != "STK" {
+				continue
+			}
+			tx, err := p.processStockDividend(action)
+			if err != nil {
+				logger.L.Warn("IBKR Parser: Skipping stock dividend due to processing error", "actionID", action.ActionID, "error", err)
+				p.skip(fmt.Sprintf("StockDividend|%s|%s|%s", action.ActionID, action.DateTime, action.Description), FlexStatement{AccountId: stmt.AccountId, CorporateActions: []CorporateAction{action}}, err)
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, tx)
+		}
 	}
 
 	return canonicalTxs, nil
 }
 
+// SkippedRows returns the records dropped by the last call to Parse.
+func (p *IBKRParser) SkippedRows() []models.SkippedRow {
+	return p.skipped
+}
+
+// skip records a dropped record together with a single-record Flex report that can be parsed again later.
+func (p *IBKRParser) skip(rawText string, stmt FlexStatement, err error) {
+	payload, marshalErr := xml.Marshal(FlexQueryResponse{FlexStatements: []FlexStatement{stmt}})
+	if marshalErr != nil {
+		logger.L.Warn("IBKR Parser: Could not build payload for skipped record", "error", marshalErr)
+	}
+	p.skipped = append(p.skipped, models.SkippedRow{RawText: rawText, Reason: err.Error(), Payload: string(payload)})
+}
+
+// processTradeTax creates the TAX transaction for the transaction taxes charged on a trade.
+// It shares the trade's OrderID so the tax can be allocated to it.
+func (p *IBKRParser) processTradeTax(trade Trade, tradeTx models.CanonicalTransaction) models.CanonicalTransaction {
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    tradeTx.TransactionDate,
+		ProductName:        trade.Description,
+		ISIN:               tradeTx.ISIN,
+		Currency:           trade.Currency,
+		OrderID:            tradeTx.OrderID,
+		RawText:            fmt.Sprintf("TradeTax|%s|%s|%f", trade.IBOrderID, trade.DateTime, trade.Taxes),
+		SourceAmount:       trade.Taxes,
+		Amount:             -math.Abs(trade.Taxes),
+		TransactionType:    "TAX",
+		TransactionSubType: tradeTaxSubType(tradeTx.ISIN),
+	}
+}
+
+// tradeTaxSubType classifies the transaction taxes IBKR reports on a trade, which come without a
+// description, by the country of the instrument's ISIN: "STAMP_DUTY" for the UK and Ireland, "FTT"
+// for the financial transaction taxes of France, Italy and Spain, and "TRADE_TAX" for any other.
+func tradeTaxSubType(isin string) string {
+	if len(isin) < 2 {
+		return "TRADE_TAX"
+	}
+	switch strings.ToUpper(isin[:2]) {
+	case "GB", "IE":
+		return "STAMP_DUTY"
+	case "FR", "IT", "ES":
+		return "FTT"
+	}
+	return "TRADE_TAX"
+}
+
 // processTrade converts an IBKR Trade record to a CanonicalTransaction.
 func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, error) {
 	date, err := parseIBKRDateTime(trade.DateTime)
@@ -175,6 +328,10 @@ func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, err
 		} else if trade.PutCall == "C" {
 			tx.TransactionSubType = "CALL"
 		}
+	} else if trade.AssetCategory == "BOND" {
+		// Bonds trade in nominal at a price in percent of par; tradeMoney is the clean price, as the
+		// accrued interest comes as a separate Bond Interest cash transaction, see processBondInterest.
+		tx.TransactionType = "BOND"
 	} else {
 		tx.TransactionType = strings.ToUpper(trade.AssetCategory)
 	}
@@ -182,6 +339,50 @@ func (p *IBKRParser) processTrade(trade Trade) (models.CanonicalTransaction, err
 	return tx, nil
 }
 
+// processFXTrade converts a currency conversion into its two cash legs, CASH transactions of subtype
+// FX sharing the trade's OrderID: the symbol "EUR.USD" buys quantity EUR (the base currency, negative
+// when sold) for tradeMoney USD (the quote currency, the trade's currency). The commission is put on
+// the base leg when charged in the base currency, and on the quote leg otherwise.
+func (p *IBKRParser) processFXTrade(trade Trade) ([]models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(trade.DateTime)
+	if err != nil {
+		return nil, err
+	}
+	base, quote, found := strings.Cut(trade.Symbol, ".")
+	if !found || len(base) != 3 || len(quote) != 3 {
+		return nil, fmt.Errorf("unrecognized currency pair %q", trade.Symbol)
+	}
+	if trade.Currency != "" && trade.Currency != quote {
+		return nil, fmt.Errorf("currency pair %q quoted in %s", trade.Symbol, trade.Currency)
+	}
+	if trade.Quantity == 0 {
+		return nil, fmt.Errorf("currency conversion with zero quantity")
+	}
+
+	legs := []models.CanonicalTransaction{
+		{Currency: base, Amount: trade.Quantity, SourceAmount: trade.Quantity},
+		{Currency: quote, Amount: -trade.TradeMoney, SourceAmount: trade.TradeMoney}, // tradeMoney is positive when buying the base currency
+	}
+	for i := range legs {
+		legs[i].Source = "ibkr"
+		legs[i].TransactionDate = date
+		legs[i].ProductName = fmt.Sprintf("FX %s", trade.Symbol)
+		legs[i].OrderID = trade.IBOrderID
+		legs[i].Price = trade.TradePrice
+		legs[i].TransactionType = "CASH"
+		legs[i].TransactionSubType = "FX"
+		legs[i].RawText = fmt.Sprintf("FX|%s|%s|%s|%f|%f|%f|%f|%s|%s",
+			trade.IBOrderID, trade.DateTime, trade.Symbol, trade.Quantity, trade.TradePrice, trade.TradeMoney, trade.IBCommission, trade.IBCommissionCurrency, legs[i].Currency,
+		)
+	}
+	commissionLeg := 1
+	if trade.IBCommissionCurrency == base {
+		commissionLeg = 0
+	}
+	legs[commissionLeg].Commission = math.Abs(trade.IBCommission)
+	return legs, nil
+}
+
 // processDividend converts an IBKR Dividend CashTransaction to a CanonicalTransaction.
 func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTransaction, error) {
 	date, err := parseIBKRDateTime(cashTx.DateTime)
@@ -194,9 +395,8 @@ func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTr
 		cashTx.DateTime, cashTx.Description, cashTx.Symbol, cashTx.Amount, cashTx.Currency, cashTx.ISIN,
 	)
 
-	// Note: IBKR reports do not always separate withholding tax into a distinct transaction.
-	// We are treating the dividend amount as the gross amount received. If tax is withheld,
-	// it might be a negative dividend transaction or require manual adjustment based on full statements.
+	// The amount is the gross dividend; the tax withheld on it comes as a separate Withholding Tax
+	// record, see processWithholdingTax. A reversed dividend is a negative amount.
 	tx := models.CanonicalTransaction{
 		Source:          "ibkr",
 		TransactionDate: date,
@@ -208,9 +408,161 @@ func (p *IBKRParser) processDividend(cashTx CashTransaction) (models.CanonicalTr
 		RawText:         rawText,
 		TransactionType: "DIVIDEND",
 	}
+	// Distributions flagged as return of capital lower the cost basis instead of being taxed as dividends.
+	if strings.Contains(strings.ToLower(cashTx.Description), "return of capital") {
+		tx.TransactionType = "RETURN_OF_CAPITAL"
+	}
+	return tx, nil
+}
+
+// processWithholdingTax converts the tax withheld on a dividend into a DIVIDEND transaction of subtype
+// TAX, counted against the country of the dividend's ISIN. The amount keeps IBKR's sign: negative when
+// withheld, positive when a withholding is reversed or refunded.
+func (p *IBKRParser) processWithholdingTax(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	if cashTx.ISIN == "" {
+		return models.CanonicalTransaction{}, fmt.Errorf("withholding tax without ISIN")
+	}
+
+	rawText := fmt.Sprintf("WithholdingTax|%s|%s|%s|%f|%s|%s",
+		cashTx.DateTime, cashTx.Description, cashTx.Symbol, cashTx.Amount, cashTx.Currency, cashTx.ISIN,
+	)
+
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    date,
+		ProductName:        cashTx.Symbol,
+		ISIN:               cashTx.ISIN,
+		Amount:             cashTx.Amount,
+		SourceAmount:       cashTx.Amount,
+		Currency:           cashTx.Currency,
+		RawText:            rawText,
+		TransactionType:    "DIVIDEND",
+		TransactionSubType: "TAX",
+	}, nil
+}
+
+// distinctRawText numbers the repeats of a raw text within a statement. When IBKR corrects a dividend
+// or its tax it reverses the record and posts it again with the same text, and without the number the
+// repost would be dropped as a duplicate of the original, leaving only the reversal.
+func distinctRawText(seen map[string]int, rawText string) string {
+	seen[rawText]++
+	if n := seen[rawText]; n > 1 {
+		return fmt.Sprintf("%s|#%d", rawText, n)
+	}
+	return rawText
+}
+
+// processStockDividend converts a stock dividend corporate action into a SCRIP_DIVIDEND CanonicalTransaction.
+// The market value of the shares received is both the taxable dividend and the cost basis of the new lot.
+func (p *IBKRParser) processStockDividend(action CorporateAction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(action.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	if action.Quantity <= 0 {
+		return models.CanonicalTransaction{}, fmt.Errorf("stock dividend with non-positive quantity %f", action.Quantity)
+	}
+
+	rawText := fmt.Sprintf("StockDividend|%s|%s|%s|%s|%f|%f|%s|%s",
+		action.ActionID, action.DateTime, action.Description, action.Symbol, action.Quantity, action.Value, action.Currency, action.ISIN,
+	)
+
+	value := math.Abs(action.Value)
+	tx := models.CanonicalTransaction{
+		Source:          "ibkr",
+		TransactionDate: date,
+		ProductName:     action.Symbol,
+		ISIN:            action.ISIN,
+		Quantity:        action.Quantity,
+		Price:           value / action.Quantity,
+		Amount:          value,
+		SourceAmount:    action.Value,
+		Currency:        action.Currency,
+		OrderID:         action.ActionID,
+		RawText:         rawText,
+		TransactionType: "SCRIP_DIVIDEND",
+		BuySell:         "BUY",
+	}
 	return tx, nil
 }
 
+// processBondInterest converts bond interest into a BOND transaction: ACCRUED_INTEREST for the interest
+// accrued since the last coupon, paid to the seller on a purchase (negative) and received on a sale,
+// which IBKR describes as "PURCHASE ACCRUED INT" and "SALE ACCRUED INT"; COUPON for any other.
+func (p *IBKRParser) processBondInterest(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	if cashTx.ISIN == "" {
+		return models.CanonicalTransaction{}, fmt.Errorf("bond interest without ISIN")
+	}
+
+	rawText := fmt.Sprintf("BondInterest|%s|%s|%s|%f|%s|%s",
+		cashTx.DateTime, cashTx.Description, cashTx.Symbol, cashTx.Amount, cashTx.Currency, cashTx.ISIN,
+	)
+
+	subType := "COUPON"
+	if strings.Contains(strings.ToUpper(cashTx.Description), "ACCRUED INT") {
+		subType = "ACCRUED_INTEREST"
+	}
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    date,
+		ProductName:        cashTx.Symbol,
+		ISIN:               cashTx.ISIN,
+		Amount:             cashTx.Amount,
+		SourceAmount:       cashTx.Amount,
+		Currency:           cashTx.Currency,
+		RawText:            rawText,
+		TransactionType:    "BOND",
+		TransactionSubType: subType,
+	}, nil
+}
+
+// processBondRedemption converts the maturity of a bond into a BOND transaction of subtype REDEMPTION
+// selling the nominal redeemed. IBKR reports the nominal removed as a negative quantity and the cash
+// repaid as proceeds; without proceeds, the bond is taken as repaid at par.
+func (p *IBKRParser) processBondRedemption(action CorporateAction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(action.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	nominal := math.Abs(action.Quantity)
+	if nominal == 0 {
+		return models.CanonicalTransaction{}, fmt.Errorf("bond redemption with zero quantity")
+	}
+
+	rawText := fmt.Sprintf("BondRedemption|%s|%s|%s|%s|%f|%f|%s|%s",
+		action.ActionID, action.DateTime, action.Description, action.Symbol, action.Quantity, action.Proceeds, action.Currency, action.ISIN,
+	)
+
+	proceeds := math.Abs(action.Proceeds)
+	if proceeds == 0 {
+		proceeds = nominal
+	}
+	return models.CanonicalTransaction{
+		Source:             "ibkr",
+		TransactionDate:    date,
+		ProductName:        action.Symbol,
+		ISIN:               action.ISIN,
+		Quantity:           nominal,
+		Price:              proceeds / nominal * 100, // Percent of par, as bond prices are quoted
+		Amount:             proceeds,
+		SourceAmount:       action.Proceeds,
+		Currency:           action.Currency,
+		OrderID:            action.ActionID,
+		RawText:            rawText,
+		TransactionType:    "BOND",
+		TransactionSubType: "REDEMPTION",
+		BuySell:            "SELL",
+	}, nil
+}
+
 // processCashMovement converts a Deposit/Withdrawal to a CanonicalTransaction.
 func (p *IBKRParser) processCashMovement(cashTx CashTransaction) (models.CanonicalTransaction, error) {
 	date, err := parseIBKRDateTime(cashTx.DateTime)
@@ -242,15 +594,54 @@ func (p *IBKRParser) processCashMovement(cashTx CashTransaction) (models.Canonic
 	return tx, nil
 }
 
-// parseIBKRDateTime converts IBKR's "YYYYMMDD;HHMMSS" format to time.Time.
+// processInterest converts the interest credited on, or debited for, the cash balance to a CanonicalTransaction.
+func (p *IBKRParser) processInterest(cashTx CashTransaction) (models.CanonicalTransaction, error) {
+	date, err := parseIBKRDateTime(cashTx.DateTime)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	rawText := fmt.Sprintf("Interest|%s|%s|%s|%f|%s",
+		cashTx.Type, cashTx.DateTime, cashTx.Description, cashTx.Amount, cashTx.Currency,
+	)
+
+	return models.CanonicalTransaction{
+		Source:          "ibkr",
+		TransactionDate: date,
+		ProductName:     cashTx.Description,
+		Amount:          cashTx.Amount, // Positive when received, negative when paid
+		SourceAmount:    cashTx.Amount,
+		Currency:        cashTx.Currency,
+		RawText:         rawText,
+		TransactionType: "INTEREST",
+	}, nil
+}
+
+// timeZone is the time zone of the times in Flex reports, see SetTimeZone.
+var timeZone = time.UTC
+
+// SetTimeZone sets the time zone the times of Flex reports are read in, by its IANA name: the one
+// chosen in the Flex Query, US Eastern time unless changed. It is meant to be called once at startup.
+func SetTimeZone(name string) error {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	timeZone = location
+	return nil
+}
+
+// parseIBKRDateTime converts IBKR's "YYYYMMDD;HHMMSS" format to time.Time. A time of day is read in
+// timeZone, so that the transaction keeps the date IBKR reports and converts to the right instant in
+// UTC; a date alone is midnight UTC.
 func parseIBKRDateTime(datetime string) (time.Time, error) {
 	// Handle cases with and without time
-	layout := "20060102;150405"
+	layout, location := "20060102;150405", timeZone
 	if !strings.Contains(datetime, ";") {
-		layout = "20060102"
+		layout, location = "20060102", time.UTC
 	}
 
-	t, err := time.Parse(layout, datetime)
+	t, err := time.ParseInLocation(layout, datetime, location)
 	if err != nil {
 		return time.Time{}, fmt.Errorf("could not parse ibkr datetime '%s': %w", datetime, err)
 	}
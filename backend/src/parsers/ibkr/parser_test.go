@@ -0,0 +1,39 @@
+package ibkr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTradeTaxSubType(t *testing.T) {
+	tests := []struct {
+		name, isin, want string
+	}{
+		{"UK stamp duty", "GB0007980591", "STAMP_DUTY"},
+		{"Irish stamp duty", "IE00BLP1HW54", "STAMP_DUTY"},
+		{"French FTT", "FR0000120271", "FTT"},
+		{"Italian FTT", "IT0003132476", "FTT"},
+		{"Spanish FTT", "ES0144580Y14", "FTT"},
+		{"other trade tax", "HK0000069689", "TRADE_TAX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := fmt.Sprintf(`<FlexQueryResponse><FlexStatements><FlexStatement accountId="U1234567"><Trades>
+<Trade assetCategory="STK" symbol="X" description="SHARE" isin=%q dateTime="20240103;153010" quantity="10" tradePrice="20" tradeMoney="200" currency="EUR" ibCommission="-1" ibCommissionCurrency="EUR" buySell="BUY" ibOrderID="1001" taxes="-0.6" />
+</Trades></FlexStatement></FlexStatements></FlexQueryResponse>`, tt.isin)
+			txs, err := NewParser().Parse(strings.NewReader(report))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(txs) != 2 {
+				t.Fatalf("got %d transactions, want the trade and its tax", len(txs))
+			}
+			tax := txs[1]
+			if tax.TransactionType != "TAX" || tax.TransactionSubType != tt.want || tax.Amount != -0.6 || tax.OrderID != txs[0].OrderID {
+				t.Errorf("tax parsed as %s/%s %v for order %q, want TAX/%s -0.6 for order %q",
+					tax.TransactionType, tax.TransactionSubType, tax.Amount, tax.OrderID, tt.want, txs[0].OrderID)
+			}
+		})
+	}
+}
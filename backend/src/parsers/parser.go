@@ -2,11 +2,93 @@
 package parsers
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
 )
 
+// DefaultBatchSize is the number of transactions handed over at a time when no batch size is configured.
+const DefaultBatchSize = 500
+
+// wholeFileMemoryFactor estimates the memory a parser loading a file whole holds per byte of the file:
+// the bytes themselves, then the cells, XML elements or PDF objects read from them.
+const wholeFileMemoryFactor = 8
+
+var (
+	// ErrParseLimit is wrapped by ErrTooManyRows, ErrParseTimeout and ErrParseMemory.
+	ErrParseLimit = spreadsheet.ErrParseLimit
+	// ErrTooManyRows is returned when a file has more rows than an upload may contain.
+	ErrTooManyRows = spreadsheet.ErrTooManyRows
+	// ErrParseTimeout is returned when parsing a file takes longer than an upload may.
+	ErrParseTimeout = spreadsheet.ErrParseTimeout
+	// ErrParseMemory is returned when parsing a file would hold more memory than an upload may.
+	ErrParseMemory = spreadsheet.ErrParseMemory
+)
+
+// Limits bounds the rows, parse time and memory of parsing one uploaded file.
+type Limits = spreadsheet.Limits
+
+// Guard enforces Limits over the parsing of one file.
+type Guard = spreadsheet.Guard
+
+// ValidationError is returned by the parsers when a file cannot be read, listing where and why.
+type ValidationError = spreadsheet.ValidationError
+
+// ValidationIssue is one problem listed in a ValidationError.
+type ValidationIssue = spreadsheet.ValidationIssue
+
 type Parser interface {
 	Parse(file io.Reader) ([]models.CanonicalTransaction, error)
 }
+
+// StreamParser is implemented by parsers that read a file row by row instead of loading it whole.
+// Transactions are handed to handle in batches of at most batchSize; parsing stops with the first
+// error handle returns, or with the error guard.Row returns for a data row past the limits.
+type StreamParser interface {
+	ParseStream(file io.Reader, batchSize int, guard *Guard, handle func([]models.CanonicalTransaction) error) error
+}
+
+// Stream parses file with parser in batches, within limits. The file is read through a Guard, so
+// parsing stops early once it takes longer or holds more memory than allowed; the time spent
+// handling batches does not count. Parsers that cannot stream parse the whole file first, and
+// MaxRows is applied to the transactions they return.
+func Stream(parser Parser, file io.Reader, batchSize int, limits Limits, handle func([]models.CanonicalTransaction) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	guard := spreadsheet.NewGuard(limits)
+	if streamer, ok := parser.(StreamParser); ok {
+		return streamer.ParseStream(guard.Reader(file, 1), batchSize, guard, func(batch []models.CanonicalTransaction) error {
+			guard.Pause()
+			defer guard.Resume()
+			return handle(batch)
+		})
+	}
+
+	txs, err := parser.Parse(guard.Reader(file, wholeFileMemoryFactor))
+	if err != nil {
+		return err
+	}
+	// The file is read before it is parsed, so the parse may have run over after the last read.
+	if err := guard.Check(); err != nil {
+		return err
+	}
+	if limits.MaxRows > 0 && len(txs) > limits.MaxRows {
+		return fmt.Errorf("%w: %d transactions, the limit is %d", ErrTooManyRows, len(txs), limits.MaxRows)
+	}
+	for start := 0; start < len(txs); start += batchSize {
+		end := min(start+batchSize, len(txs))
+		if err := handle(txs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipReporter is implemented by parsers that keep track of the rows they had to drop,
+// e.g. because the description could not be classified.
+type SkipReporter interface {
+	SkippedRows() []models.SkippedRow
+}
@@ -0,0 +1,76 @@
+// Package parsertest holds the helpers shared by the broker parser tests.
+package parsertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Parser is what the golden tests need of a broker parser.
+type Parser interface {
+	Parse(file io.Reader) ([]models.CanonicalTransaction, error)
+	SkippedRows() []models.SkippedRow
+}
+
+// Golden parses each sample export testdata/<name><ext> with a parser from newParser and compares the
+// transactions and skipped rows with testdata/<name>.golden.json. Run with -update to rewrite the golden
+// files after an intended change.
+func Golden(t *testing.T, newParser func() Parser, ext string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", name+ext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			parser := newParser()
+			txs, err := parser.Parse(file)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := json.MarshalIndent(struct {
+				Transactions []models.CanonicalTransaction `json:"transactions"`
+				Skipped      []models.SkippedRow           `json:"skipped"`
+			}{txs, parser.SkippedRows()}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Parse output differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// Parse parses a sample export given as text, failing the test if it cannot be read.
+func Parse(t *testing.T, parser Parser, text string) []models.CanonicalTransaction {
+	t.Helper()
+	txs, err := parser.Parse(bytes.NewReader([]byte(text)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return txs
+}
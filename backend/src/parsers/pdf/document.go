@@ -0,0 +1,283 @@
+// backend/src/parsers/pdf/document.go
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// maxInputSize caps the size of the whole file read into memory.
+	maxInputSize = 32 << 20
+	// maxDecodedSize caps the decompressed size of all the streams of a file, as a guard against
+	// compression bombs.
+	maxDecodedSize = 256 << 20
+	// maxDepth bounds the nesting of page trees and form XObjects, which malformed files may make cyclic.
+	maxDepth = 32
+)
+
+// ErrUnsupported is returned for PDF files this reader cannot extract text from, such as encrypted ones.
+var ErrUnsupported = errors.New("unsupported PDF file")
+
+// Magic is the signature every PDF file starts with.
+var Magic = []byte("%PDF-")
+
+var (
+	objectHeaderRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	trailerRe      = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// document holds the objects of a PDF file by number. The cross-reference table is not read: the objects
+// are found by scanning the file, which also copes with the broken offsets common in generated files.
+type document struct {
+	objects map[int]any
+	root    dict
+	decoded int // Bytes decompressed so far, see maxDecodedSize
+}
+
+func load(data []byte) (*document, error) {
+	if !bytes.HasPrefix(data, Magic) {
+		return nil, fmt.Errorf("%w: missing PDF header", ErrUnsupported)
+	}
+	doc := &document{objects: make(map[int]any)}
+
+	// Objects are read in file order, so the revisions appended by incremental updates replace the originals.
+	var rootRef any
+	for _, m := range objectHeaderRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &lexer{data: data, pos: m[1]}
+		obj, err := l.next()
+		if err != nil {
+			continue
+		}
+		if d, ok := obj.(dict); ok {
+			if s, ok := doc.streamAfter(l, d); ok {
+				obj = s
+				if d[name("Type")] == name("XRef") && d[name("Root")] != nil {
+					if d[name("Encrypt")] != nil {
+						return nil, fmt.Errorf("%w: the file is encrypted", ErrUnsupported)
+					}
+					rootRef = d[name("Root")]
+				}
+			}
+		}
+		doc.objects[num] = obj
+	}
+
+	for _, m := range trailerRe.FindAllIndex(data, -1) {
+		l := &lexer{data: data, pos: m[1] - 2}
+		if trailer, err := l.next(); err == nil {
+			if d, ok := trailer.(dict); ok {
+				if d[name("Encrypt")] != nil {
+					return nil, fmt.Errorf("%w: the file is encrypted", ErrUnsupported)
+				}
+				if d[name("Root")] != nil {
+					rootRef = d[name("Root")]
+				}
+			}
+		}
+	}
+
+	doc.loadObjectStreams()
+	root, _ := doc.resolve(rootRef).(dict)
+	if root == nil {
+		// Without a usable trailer, fall back to any catalog in the file.
+		for _, obj := range doc.objects {
+			if d, ok := obj.(dict); ok && d[name("Type")] == name("Catalog") {
+				root = d
+				break
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: no document catalog found", ErrUnsupported)
+	}
+	doc.root = root
+	return doc, nil
+}
+
+// streamAfter reads the stream data following a dictionary, if any. The /Length is used when it is a
+// direct number that ends right before "endstream"; otherwise the data runs up to that keyword.
+func (doc *document) streamAfter(l *lexer, d dict) (*stream, bool) {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return nil, false
+	}
+	start := l.pos + len("stream")
+	if bytes.HasPrefix(l.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(l.data) && (l.data[start] == '\n' || l.data[start] == '\r') {
+		start++
+	}
+	if length, ok := d[name("Length")].(float64); ok && length >= 0 {
+		end := start + int(length)
+		if end <= len(l.data) {
+			rest := bytes.TrimLeft(l.data[end:min(end+16, len(l.data))], "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return &stream{dict: d, data: l.data[start:end]}, true
+			}
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, false
+	}
+	return &stream{dict: d, data: bytes.TrimRight(l.data[start:start+end], "\r\n")}, true
+}
+
+// loadObjectStreams adds the objects compressed into object streams (PDF 1.5 and later) that were
+// not found in the file directly.
+func (doc *document) loadObjectStreams() {
+	var containers []*stream
+	for _, obj := range doc.objects {
+		if s, ok := obj.(*stream); ok && s.dict[name("Type")] == name("ObjStm") {
+			containers = append(containers, s)
+		}
+	}
+	for _, s := range containers {
+		data, err := doc.decode(s)
+		if err != nil {
+			continue
+		}
+		count, first := int(number(s.dict[name("N")])), int(number(s.dict[name("First")]))
+		if first < 0 || first > len(data) {
+			continue
+		}
+		header := &lexer{data: data[:first]}
+		for i := 0; i < count; i++ {
+			num, err1 := header.token()
+			offset, err2 := header.token()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			n, isNum := num.(float64)
+			o, isOffset := offset.(float64)
+			if !isNum || !isOffset || first+int(o) > len(data) {
+				break
+			}
+			if _, exists := doc.objects[int(n)]; exists {
+				continue
+			}
+			body := &lexer{data: data, pos: first + int(o)}
+			if obj, err := body.next(); err == nil {
+				doc.objects[int(n)] = obj
+			}
+		}
+	}
+}
+
+// resolve follows references until it reaches a direct object. Missing objects resolve to nil.
+func (doc *document) resolve(v any) any {
+	for i := 0; i < maxDepth; i++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+		v = doc.objects[r.num]
+	}
+	return nil
+}
+
+func (doc *document) dict(v any) dict {
+	switch obj := doc.resolve(v).(type) {
+	case dict:
+		return obj
+	case *stream:
+		return obj.dict
+	}
+	return nil
+}
+
+// decode returns the decompressed data of a stream. Only FlateDecode is supported, which is what
+// text content is compressed with; streams using other filters, such as images, fail.
+func (doc *document) decode(s *stream) ([]byte, error) {
+	var filters array
+	switch f := doc.resolve(s.dict[name("Filter")]).(type) {
+	case name:
+		filters = array{f}
+	case array:
+		filters = f
+	}
+
+	data := s.data
+	for _, f := range filters {
+		switch doc.resolve(f) {
+		case name("FlateDecode"), name("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("pdf: invalid compressed stream: %w", err)
+			}
+			out, err := io.ReadAll(io.LimitReader(zr, int64(maxDecodedSize-doc.decoded+1)))
+			// Truncated streams are common in generated files; keep what could be decompressed.
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && len(out) == 0 {
+				return nil, fmt.Errorf("pdf: invalid compressed stream: %w", err)
+			}
+			doc.decoded += len(out)
+			if doc.decoded > maxDecodedSize {
+				return nil, fmt.Errorf("%w: streams expand to more than %d MB", ErrUnsupported, maxDecodedSize>>20)
+			}
+			data = out
+		default:
+			return nil, fmt.Errorf("%w: stream filter %v", ErrUnsupported, f)
+		}
+	}
+	return data, nil
+}
+
+// pages returns the page dictionaries in order, with the inheritable resources filled in from their parents.
+func (doc *document) pages() []dict {
+	var pages []dict
+	var walk func(node dict, resources any, depth int)
+	walk = func(node dict, resources any, depth int) {
+		if node == nil || depth > maxDepth {
+			return
+		}
+		if r, ok := node[name("Resources")]; ok {
+			resources = r
+		}
+		if kids, ok := doc.resolve(node[name("Kids")]).(array); ok {
+			for _, kid := range kids {
+				walk(doc.dict(kid), resources, depth+1)
+			}
+			return
+		}
+		page := make(dict, len(node)+1)
+		for k, v := range node {
+			page[k] = v
+		}
+		page[name("Resources")] = resources
+		pages = append(pages, page)
+	}
+	walk(doc.dict(doc.root[name("Pages")]), nil, 0)
+	return pages
+}
+
+// contents returns the concatenated content streams of a page.
+func (doc *document) contents(page dict) ([]byte, error) {
+	var parts array
+	switch c := doc.resolve(page[name("Contents")]).(type) {
+	case *stream:
+		parts = array{c}
+	case array:
+		parts = c
+	}
+	var buf bytes.Buffer
+	for _, part := range parts {
+		s, ok := doc.resolve(part).(*stream)
+		if !ok {
+			continue
+		}
+		data, err := doc.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n') // Content streams may split anywhere between tokens
+	}
+	return buf.Bytes(), nil
+}
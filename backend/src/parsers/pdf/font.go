@@ -0,0 +1,274 @@
+// backend/src/parsers/pdf/font.go
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// font decodes the strings shown with a font into text and measures them.
+type font struct {
+	codeLength   int // Bytes per character code: 1 for simple fonts, 2 for composite (Type0) fonts
+	toUnicode    map[uint32]string
+	encoding     *[256]rune // Simple fonts without a ToUnicode map
+	widths       map[uint32]float64
+	defaultWidth float64 // In thousandths of the font size
+}
+
+// glyph is one character code of a shown string.
+type glyph struct {
+	text  string
+	width float64 // In thousandths of the font size
+	space bool    // The single-byte code 32, which word spacing applies to
+}
+
+func (f *font) glyphs(s str) []glyph {
+	codeLength := max(f.codeLength, 1)
+	glyphs := make([]glyph, 0, len(s)/codeLength)
+	for i := 0; i+codeLength <= len(s); i += codeLength {
+		var code uint32
+		for j := 0; j < codeLength; j++ {
+			code = code<<8 | uint32(s[i+j])
+		}
+		g := glyph{width: f.defaultWidth, space: codeLength == 1 && code == 32}
+		if w, ok := f.widths[code]; ok {
+			g.width = w
+		}
+		switch {
+		case f.toUnicode != nil:
+			g.text = f.toUnicode[code]
+		case f.encoding != nil:
+			if r := f.encoding[code&0xff]; r != 0 {
+				g.text = string(r)
+			}
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// loadFont reads a font dictionary. Fonts the reader cannot map to Unicode, such as composite fonts
+// without a ToUnicode map, still measure correctly but decode to empty text.
+func (doc *document) loadFont(d dict) *font {
+	f := &font{codeLength: 1, defaultWidth: 500}
+	if d == nil {
+		f.encoding = &winAnsiEncoding
+		return f
+	}
+
+	if d[name("Subtype")] == name("Type0") {
+		f.codeLength = 2
+		f.defaultWidth = 1000
+		if descendants, ok := doc.resolve(d[name("DescendantFonts")]).(array); ok && len(descendants) > 0 {
+			descendant := doc.dict(descendants[0])
+			if dw, ok := doc.resolve(descendant[name("DW")]).(float64); ok {
+				f.defaultWidth = dw
+			}
+			f.widths = doc.cidWidths(descendant[name("W")])
+		}
+	} else {
+		first := int(number(doc.resolve(d[name("FirstChar")])))
+		if widths, ok := doc.resolve(d[name("Widths")]).(array); ok {
+			f.widths = make(map[uint32]float64, len(widths))
+			for i, w := range widths {
+				f.widths[uint32(first+i)] = number(doc.resolve(w))
+			}
+		}
+		f.encoding = doc.simpleEncoding(d[name("Encoding")])
+	}
+
+	if s, ok := doc.resolve(d[name("ToUnicode")]).(*stream); ok {
+		if data, err := doc.decode(s); err == nil {
+			f.toUnicode, f.codeLength = parseCMap(data, f.codeLength)
+		}
+	}
+	return f
+}
+
+// cidWidths reads the /W array of a CID font: "c [w1 w2 ...]" or "cFirst cLast w" entries.
+func (doc *document) cidWidths(v any) map[uint32]float64 {
+	w, _ := doc.resolve(v).(array)
+	widths := make(map[uint32]float64)
+	for i := 0; i+1 < len(w); {
+		first := uint32(number(doc.resolve(w[i])))
+		if list, ok := doc.resolve(w[i+1]).(array); ok {
+			for j, width := range list {
+				widths[first+uint32(j)] = number(doc.resolve(width))
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			break
+		}
+		last := uint32(number(doc.resolve(w[i+1])))
+		width := number(doc.resolve(w[i+2]))
+		for c := first; c <= last && c-first < 1<<16; c++ {
+			widths[c] = width
+		}
+		i += 3
+	}
+	return widths
+}
+
+// simpleEncoding builds the code to rune table of a simple font from its base encoding and /Differences.
+func (doc *document) simpleEncoding(v any) *[256]rune {
+	encoding := winAnsiEncoding
+	switch e := doc.resolve(v).(type) {
+	case name:
+		if e == "StandardEncoding" || e == "MacRomanEncoding" {
+			encoding = latin1Encoding
+		}
+	case dict:
+		if base, _ := doc.resolve(e[name("BaseEncoding")]).(name); base == "StandardEncoding" || base == "MacRomanEncoding" {
+			encoding = latin1Encoding
+		}
+		if differences, ok := doc.resolve(e[name("Differences")]).(array); ok {
+			code := 0
+			for _, item := range differences {
+				switch item := doc.resolve(item).(type) {
+				case float64:
+					code = int(item)
+				case name:
+					if code >= 0 && code < 256 {
+						encoding[code] = glyphRune(string(item))
+					}
+					code++
+				}
+			}
+		}
+	}
+	return &encoding
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap. The code length comes from its
+// codespace ranges, defaulting to codeLength.
+func parseCMap(data []byte, codeLength int) (map[uint32]string, int) {
+	mapping := make(map[uint32]string)
+	l := &lexer{data: data}
+	var operands []any
+	for {
+		obj, err := l.next()
+		if err != nil {
+			break
+		}
+		kw, isKeyword := obj.(keyword)
+		if !isKeyword {
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			operands = operands[:0]
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(str); ok && len(lo) > 0 {
+					codeLength = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(str)
+				dst, ok2 := operands[i+1].(str)
+				if ok1 && ok2 {
+					mapping[codeOf(src)] = utf16String(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(str)
+				hi, ok2 := operands[i+1].(str)
+				if !ok1 || !ok2 {
+					continue
+				}
+				first, last := codeOf(lo), codeOf(hi)
+				switch dst := operands[i+2].(type) {
+				case str:
+					base := []rune(utf16String(dst))
+					for c := first; c <= last && c-first < 1<<16 && len(base) > 0; c++ {
+						r := append([]rune(nil), base...)
+						r[len(r)-1] += rune(c - first)
+						mapping[c] = string(r)
+					}
+				case array:
+					for j, item := range dst {
+						if s, ok := item.(str); ok && first+uint32(j) <= last {
+							mapping[first+uint32(j)] = utf16String(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return mapping, codeLength
+}
+
+func codeOf(s str) uint32 {
+	var code uint32
+	for i := 0; i < len(s); i++ {
+		code = code<<8 | uint32(s[i])
+	}
+	return code
+}
+
+func utf16String(s str) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// glyphRune maps a glyph name from an encoding's /Differences to its character.
+func glyphRune(glyphName string) rune {
+	if r, ok := glyphNames[glyphName]; ok {
+		return r
+	}
+	if len(glyphName) == 1 {
+		return rune(glyphName[0])
+	}
+	if hex, ok := strings.CutPrefix(glyphName, "uni"); ok && len(hex) == 4 {
+		if v, err := strconv.ParseUint(hex, 16, 16); err == nil {
+			return rune(v)
+		}
+	}
+	return 0
+}
+
+// glyphNames covers the glyph names that encodings of Latin text commonly redefine.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '\'', "parenleft": '(', "parenright": ')',
+	"asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "minus": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7',
+	"eight": '8', "nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"underscore": '_', "endash": '–', "emdash": '—', "Euro": '€', "sterling": '£',
+	"degree": '°', "ordfeminine": 'ª', "ordmasculine": 'º', "nbspace": ' ',
+	"Agrave": 'À', "Aacute": 'Á', "Acircumflex": 'Â', "Atilde": 'Ã', "Adieresis": 'Ä', "Ccedilla": 'Ç',
+	"Egrave": 'È', "Eacute": 'É', "Ecircumflex": 'Ê', "Iacute": 'Í', "Oacute": 'Ó', "Ocircumflex": 'Ô',
+	"Otilde": 'Õ', "Odieresis": 'Ö', "Uacute": 'Ú', "Udieresis": 'Ü',
+	"agrave": 'à', "aacute": 'á', "acircumflex": 'â', "atilde": 'ã', "adieresis": 'ä', "ccedilla": 'ç',
+	"egrave": 'è', "eacute": 'é', "ecircumflex": 'ê', "edieresis": 'ë', "iacute": 'í', "idieresis": 'ï',
+	"ntilde": 'ñ', "oacute": 'ó', "ocircumflex": 'ô', "otilde": 'õ', "odieresis": 'ö', "uacute": 'ú',
+	"ugrave": 'ù', "udieresis": 'ü', "germandbls": 'ß',
+}
+
+// latin1Encoding maps every code to the Latin-1 character of the same value.
+var latin1Encoding = func() (e [256]rune) {
+	for i := 32; i < 256; i++ {
+		e[i] = rune(i)
+	}
+	return e
+}()
+
+// winAnsiEncoding is Latin-1 with the Windows-1252 characters in 0x80-0x9F, the default of simple fonts.
+var winAnsiEncoding = func() (e [256]rune) {
+	e = latin1Encoding
+	for i, r := range []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ") {
+		e[0x80+i] = r
+	}
+	return e
+}()
@@ -0,0 +1,294 @@
+// backend/src/parsers/pdf/object.go
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// The PDF object types, as produced by the lexer. Numbers are float64, booleans bool and null nil.
+type (
+	name   string
+	str    string
+	array  []any
+	dict   map[name]any
+	ref    struct{ num, gen int }
+	stream struct {
+		dict dict
+		data []byte // Still encoded
+	}
+	// keyword is a bare word: an operator in a content stream, or obj, R, stream... in a file.
+	keyword string
+)
+
+// maxNesting bounds how deeply arrays and dictionaries may nest. Genuine files nest a few levels;
+// a file of nothing but opening brackets would otherwise exhaust the stack of the parsing goroutine.
+const maxNesting = 64
+
+// lexer reads PDF objects from a buffer.
+type lexer struct {
+	data  []byte
+	pos   int
+	depth int // Arrays and dictionaries being read
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// errEOF is returned when the data ends before an object does.
+var errEOF = fmt.Errorf("pdf: unexpected end of data")
+
+// errNesting is returned when arrays and dictionaries nest deeper than maxNesting.
+var errNesting = fmt.Errorf("%w: objects nested more than %d levels deep", ErrUnsupported, maxNesting)
+
+// enter counts one more array or dictionary being read, failing past maxNesting. leave undoes it.
+func (l *lexer) enter() error {
+	if l.depth++; l.depth > maxNesting {
+		return errNesting
+	}
+	return nil
+}
+
+func (l *lexer) leave() {
+	l.depth--
+}
+
+// next returns the next object, resolving "num gen R" into a ref. Arrays and dictionaries are read whole.
+func (l *lexer) next() (any, error) {
+	obj, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	num, ok := obj.(float64)
+	if !ok || num != float64(int(num)) || num < 0 {
+		return obj, nil
+	}
+	// Look ahead for "gen R" without consuming anything else.
+	save := l.pos
+	gen, err := l.token()
+	if g, ok := gen.(float64); err == nil && ok && g == float64(int(g)) {
+		if kw, err := l.token(); err == nil && kw == keyword("R") {
+			return ref{num: int(num), gen: int(g)}, nil
+		}
+	}
+	l.pos = save
+	return obj, nil
+}
+
+func (l *lexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.dictionary()
+	case c == '<':
+		return l.hexString()
+	case c == '[':
+		l.pos++
+		return l.array()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.pos++
+		if c == '>' && l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+			return keyword(">>"), nil
+		}
+		return keyword(l.data[l.pos-1 : l.pos]), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return keyword(word), nil
+}
+
+func (l *lexer) name() name {
+	l.pos++ // The slash
+	var buf []byte
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				buf = append(buf, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		buf = append(buf, c)
+		l.pos++
+	}
+	return name(buf)
+}
+
+func (l *lexer) literalString() (str, error) {
+	l.pos++ // The opening parenthesis
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return str(buf), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return "", errEOF
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue // Line continuation
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return "", errEOF
+}
+
+func (l *lexer) hexString() (str, error) {
+	l.pos++ // The opening angle bracket
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			buf := make([]byte, len(digits)/2)
+			for i := range buf {
+				v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("pdf: invalid hex string")
+				}
+				buf[i] = byte(v)
+			}
+			return str(buf), nil
+		}
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return "", errEOF
+}
+
+func (l *lexer) array() (array, error) {
+	defer l.leave()
+	if err := l.enter(); err != nil {
+		return nil, err
+	}
+	var arr array
+	for {
+		obj, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if obj == keyword("]") {
+			return arr, nil
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *lexer) dictionary() (dict, error) {
+	defer l.leave()
+	if err := l.enter(); err != nil {
+		return nil, err
+	}
+	d := make(dict)
+	for {
+		key, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if key == keyword(">>") {
+			return d, nil
+		}
+		k, ok := key.(name)
+		if !ok {
+			return nil, fmt.Errorf("pdf: dictionary key is not a name")
+		}
+		value, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		d[k] = value
+	}
+}
+
+// number returns v as a float64, or 0 when it is not a number.
+func number(v any) float64 {
+	n, _ := v.(float64)
+	return n
+}
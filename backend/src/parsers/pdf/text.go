@@ -0,0 +1,322 @@
+// backend/src/parsers/pdf/text.go
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Text is a string drawn on a page, positioned in points from the bottom left corner.
+type Text struct {
+	X, Y  float64 // Start of the baseline
+	Width float64
+	Size  float64 // Font size as drawn, used to judge gaps between texts
+	S     string
+}
+
+// End returns the X coordinate where the text ends.
+func (t Text) End() float64 {
+	return t.X + t.Width
+}
+
+// Page is the text drawn on one page, in drawing order.
+type Page struct {
+	Texts []Text
+}
+
+// Line is a row of texts sharing a baseline, sorted left to right.
+type Line []Text
+
+// Read extracts the text of every page of a PDF file. Only what a text layer holds is found: scanned
+// statements, which are images, read as empty pages.
+func Read(r io.Reader) ([]Page, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxInputSize {
+		return nil, fmt.Errorf("%w: file larger than %d bytes", ErrUnsupported, maxInputSize)
+	}
+	doc, err := load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []Page
+	for _, pageDict := range doc.pages() {
+		content, err := doc.contents(pageDict)
+		if err != nil {
+			return nil, err
+		}
+		ex := &extractor{doc: doc}
+		ex.run(content, doc.dict(pageDict[name("Resources")]), identity, 0)
+		pages = append(pages, Page{Texts: ex.texts})
+	}
+	return pages, nil
+}
+
+// Lines groups the texts of the page into lines, top to bottom. Texts belong to the same line when
+// their baselines are closer than a third of the font size.
+func (p Page) Lines() []Line {
+	texts := append([]Text(nil), p.Texts...)
+	sort.SliceStable(texts, func(i, j int) bool { return texts[i].Y > texts[j].Y })
+
+	var lines []Line
+	var baseline float64
+	for _, t := range texts {
+		if strings.TrimSpace(t.S) == "" {
+			continue
+		}
+		if n := len(lines); n > 0 && math.Abs(baseline-t.Y) <= math.Max(1, t.Size/3) {
+			lines[n-1] = append(lines[n-1], t)
+			continue
+		}
+		lines = append(lines, Line{t})
+		baseline = t.Y
+	}
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].X < line[j].X })
+	}
+	return lines
+}
+
+// Cells merges the texts of the line that are closer than gap times the font size, as the words of a
+// table cell are, and returns the merged texts.
+func (l Line) Cells(gap float64) []Text {
+	var cells []Text
+	for _, t := range l {
+		if n := len(cells); n > 0 {
+			last := &cells[n-1]
+			if space := t.X - last.End(); space < gap*math.Max(t.Size, 1) {
+				if space > 0.15*t.Size && !strings.HasSuffix(last.S, " ") && !strings.HasPrefix(t.S, " ") {
+					last.S += " "
+				}
+				last.S += t.S
+				last.Width = math.Max(last.Width, t.End()-last.X)
+				continue
+			}
+		}
+		cells = append(cells, t)
+	}
+	for i := range cells {
+		cells[i].S = strings.Join(strings.Fields(cells[i].S), " ")
+	}
+	return cells
+}
+
+// String joins the texts of the line with single spaces.
+func (l Line) String() string {
+	parts := make([]string, 0, len(l))
+	for _, cell := range l.Cells(0.25) {
+		parts = append(parts, cell.S)
+	}
+	return strings.Join(parts, " ")
+}
+
+// matrix is an affine transformation [a b c d e f], as in PDF.
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n: m applied first, then n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{1, 0, 0, 1, tx, ty}
+}
+
+// extractor runs content streams, recording where text is shown. Only the operators that position
+// text are interpreted; graphics are ignored.
+type extractor struct {
+	doc   *document
+	texts []Text
+}
+
+type textState struct {
+	font                                           *font
+	size, charSpacing, wordSpacing, scale, leading float64
+}
+
+func (ex *extractor) run(content []byte, resources dict, ctm matrix, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	fontCache := make(map[name]*font)
+	state := textState{scale: 1}
+	var stack []struct {
+		ctm   matrix
+		state textState
+	}
+	var tm, tlm matrix
+
+	fontNamed := func(n name) *font {
+		if f, ok := fontCache[n]; ok {
+			return f
+		}
+		fonts := ex.doc.dict(resources[name("Font")])
+		f := ex.doc.loadFont(ex.doc.dict(fonts[n]))
+		fontCache[n] = f
+		return f
+	}
+	show := func(s str) {
+		if state.font == nil {
+			state.font = ex.doc.loadFont(nil)
+		}
+		trm := matrix{state.size * state.scale, 0, 0, state.size, 0, 0}.multiply(tm).multiply(ctm)
+		var text strings.Builder
+		advance := 0.0
+		for _, g := range state.font.glyphs(s) {
+			text.WriteString(g.text)
+			tx := g.width/1000*state.size + state.charSpacing
+			if g.space {
+				tx += state.wordSpacing
+			}
+			advance += tx * state.scale
+		}
+		width := advance * math.Hypot(tm[0], tm[1]) * math.Hypot(ctm[0], ctm[1])
+		ex.texts = append(ex.texts, Text{
+			X: trm[4], Y: trm[5], Width: width,
+			Size: math.Hypot(trm[2], trm[3]),
+			S:    text.String(),
+		})
+		tm = translate(advance, 0).multiply(tm)
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = translate(tx, ty).multiply(tlm)
+		tm = tlm
+	}
+
+	l := &lexer{data: content}
+	var operands []any
+	for {
+		obj, err := l.next()
+		if err != nil {
+			return
+		}
+		op, isOperator := obj.(keyword)
+		if !isOperator {
+			operands = append(operands, obj)
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				return number(operands[i])
+			}
+			return 0
+		}
+		last := func() any {
+			if len(operands) == 0 {
+				return nil
+			}
+			return operands[len(operands)-1]
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, struct {
+				ctm   matrix
+				state textState
+			}{ctm, state})
+		case "Q":
+			if n := len(stack); n > 0 {
+				ctm, state = stack[n-1].ctm, stack[n-1].state
+				stack = stack[:n-1]
+			}
+		case "cm":
+			if len(operands) == 6 {
+				ctm = matrix{num(0), num(1), num(2), num(3), num(4), num(5)}.multiply(ctm)
+			}
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tf":
+			if len(operands) == 2 {
+				if n, ok := operands[0].(name); ok {
+					state.font, state.size = fontNamed(n), num(1)
+				}
+			}
+		case "Tc":
+			state.charSpacing = num(0)
+		case "Tw":
+			state.wordSpacing = num(0)
+		case "Tz":
+			state.scale = num(0) / 100
+		case "TL":
+			state.leading = num(0)
+		case "Td":
+			nextLine(num(0), num(1))
+		case "TD":
+			state.leading = -num(1)
+			nextLine(num(0), num(1))
+		case "Tm":
+			if len(operands) == 6 {
+				tm = matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+				tlm = tm
+			}
+		case "T*":
+			nextLine(0, -state.leading)
+		case "Tj":
+			if s, ok := last().(str); ok {
+				show(s)
+			}
+		case "'":
+			nextLine(0, -state.leading)
+			if s, ok := last().(str); ok {
+				show(s)
+			}
+		case "\"":
+			state.wordSpacing, state.charSpacing = num(0), num(1)
+			nextLine(0, -state.leading)
+			if s, ok := last().(str); ok {
+				show(s)
+			}
+		case "TJ":
+			items, _ := last().(array)
+			for _, item := range items {
+				switch item := item.(type) {
+				case str:
+					show(item)
+				case float64:
+					tm = translate(-item/1000*state.size*state.scale, 0).multiply(tm)
+				}
+			}
+		case "Do":
+			if n, ok := last().(name); ok {
+				ex.runForm(ex.doc.dict(resources[name("XObject")])[n], resources, ctm, depth)
+			}
+		case "BI":
+			// Inline image data is binary; skip to the end of it.
+			if end := strings.Index(string(content[l.pos:]), "EI"); end >= 0 {
+				l.pos += end + 2
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm runs the content of a form XObject, which generators use to reuse page headers and footers.
+func (ex *extractor) runForm(v any, resources dict, ctm matrix, depth int) {
+	form, ok := ex.doc.resolve(v).(*stream)
+	if !ok || form.dict[name("Subtype")] != name("Form") {
+		return
+	}
+	content, err := ex.doc.decode(form)
+	if err != nil {
+		return
+	}
+	if own := ex.doc.dict(form.dict[name("Resources")]); own != nil {
+		resources = own
+	}
+	if m, ok := ex.doc.resolve(form.dict[name("Matrix")]).(array); ok && len(m) == 6 {
+		ctm = matrix{number(m[0]), number(m[1]), number(m[2]), number(m[3]), number(m[4]), number(m[5])}.multiply(ctm)
+	}
+	ex.run(content, resources, ctm, depth+1)
+}
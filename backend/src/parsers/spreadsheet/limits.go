@@ -0,0 +1,114 @@
+// backend/src/parsers/spreadsheet/limits.go
+package spreadsheet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrParseLimit is wrapped by the errors returned when parsing a file exceeds one of its Limits.
+var ErrParseLimit = errors.New("upload limit exceeded")
+
+var (
+	// ErrParseTimeout is returned when parsing a file takes longer than Limits.MaxParseTime.
+	ErrParseTimeout = fmt.Errorf("%w: parsing took too long", ErrParseLimit)
+	// ErrParseMemory is returned when parsing a file would hold more than Limits.MaxMemory bytes.
+	ErrParseMemory = fmt.Errorf("%w: the file needs too much memory to parse", ErrParseLimit)
+)
+
+// Limits bounds the work parsing one uploaded file may take, so that a file built to exhaust the
+// server is abandoned early instead of parsed to the end. A zero field means no limit.
+type Limits struct {
+	MaxRows      int           // Data rows
+	MaxParseTime time.Duration // Wall time spent parsing, not counting the handling of the batches parsed
+	MaxMemory    int64         // Bytes held at once while parsing, as estimated from the bytes read
+}
+
+// Guard enforces Limits over the parsing of one file. Parsers count the rows they read with Row;
+// reading the file through Reader checks the time spent and estimates the memory held.
+type Guard struct {
+	limits  Limits
+	rows    int
+	memory  int64
+	spent   time.Duration // Parse time up to when the clock was last started
+	started time.Time     // When the clock was last started; zero while it is stopped
+}
+
+// NewGuard starts enforcing limits.
+func NewGuard(limits Limits) *Guard {
+	return &Guard{limits: limits, started: time.Now()}
+}
+
+// Pause stops the parse clock, while the rows parsed so far are handled.
+func (g *Guard) Pause() {
+	if !g.started.IsZero() {
+		g.spent += time.Since(g.started)
+		g.started = time.Time{}
+	}
+}
+
+// Resume starts the parse clock again.
+func (g *Guard) Resume() {
+	if g.started.IsZero() {
+		g.started = time.Now()
+	}
+}
+
+// Check fails with ErrParseTimeout once parsing has taken longer than MaxParseTime.
+func (g *Guard) Check() error {
+	if g.limits.MaxParseTime <= 0 {
+		return nil
+	}
+	spent := g.spent
+	if !g.started.IsZero() {
+		spent += time.Since(g.started)
+	}
+	if spent > g.limits.MaxParseTime {
+		return fmt.Errorf("%w: the limit is %s", ErrParseTimeout, g.limits.MaxParseTime)
+	}
+	return nil
+}
+
+// Row counts a data row read, failing with ErrTooManyRows past MaxRows and as Check does.
+func (g *Guard) Row() error {
+	g.rows++
+	if g.limits.MaxRows > 0 && g.rows > g.limits.MaxRows {
+		return fmt.Errorf("%w: the limit is %d", ErrTooManyRows, g.limits.MaxRows)
+	}
+	return g.Check()
+}
+
+// Hold counts n more bytes held in memory, failing with ErrParseMemory past MaxMemory.
+func (g *Guard) Hold(n int64) error {
+	g.memory += n
+	if g.limits.MaxMemory > 0 && g.memory > g.limits.MaxMemory {
+		return fmt.Errorf("%w: the limit is %d MB", ErrParseMemory, g.limits.MaxMemory>>20)
+	}
+	return nil
+}
+
+// Reader returns r checking the parse time on every read. Each byte read counts as memoryPerByte
+// bytes held: parsers that load a file whole hold several times its size once it is parsed, and
+// those reading it row by row hardly more than what they read.
+func (g *Guard) Reader(r io.Reader, memoryPerByte int64) io.Reader {
+	return &guardedReader{r: r, guard: g, memoryPerByte: memoryPerByte}
+}
+
+type guardedReader struct {
+	r             io.Reader
+	guard         *Guard
+	memoryPerByte int64
+}
+
+func (gr *guardedReader) Read(p []byte) (int, error) {
+	if err := gr.guard.Check(); err != nil {
+		return 0, err
+	}
+	n, err := gr.r.Read(p)
+	if holdErr := gr.guard.Hold(int64(n) * gr.memoryPerByte); holdErr != nil {
+		return 0, holdErr
+	}
+	return n, err
+}
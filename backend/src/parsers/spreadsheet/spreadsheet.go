@@ -0,0 +1,337 @@
+// backend/src/parsers/spreadsheet/spreadsheet.go
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPartSize caps the uncompressed size of each XLSX part we read, as a guard against zip bombs.
+	maxPartSize = 64 << 20
+	// maxInputSize caps the size of the whole file read into memory.
+	maxInputSize = 32 << 20
+)
+
+// ErrUnsupportedFormat is returned when the input is neither an XLSX workbook nor CSV text.
+var ErrUnsupportedFormat = errors.New("unsupported spreadsheet format")
+
+// ErrTooManyRows is returned when a file has more data rows than an upload may contain.
+var ErrTooManyRows = fmt.Errorf("%w: file has too many rows", ErrParseLimit)
+
+// Sheet is a named table of cell values. Rows may have different lengths.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// Read returns the worksheets of an XLSX workbook, or a single unnamed sheet for CSV input.
+// CSV files may use either comma or semicolon as the delimiter.
+func Read(r io.Reader) ([]Sheet, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxInputSize {
+		return nil, fmt.Errorf("%w: file larger than %d bytes", ErrUnsupportedFormat, maxInputSize)
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return readXLSX(data)
+	}
+	rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	return []Sheet{{Rows: rows}}, nil
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	return rows, nil
+}
+
+// --- XLSX ---
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([]Sheet, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheets := make([]Sheet, 0, len(workbook.Sheets))
+	for _, s := range workbook.Sheets {
+		var ws xlsxWorksheet
+		if err := decodePart(files, targets[s.RID], &ws); err != nil {
+			return nil, err
+		}
+		sheet := Sheet{Name: s.Name}
+		for _, row := range ws.Rows {
+			var values []string
+			for i, cell := range row.Cells {
+				col := i
+				if cell.Ref != "" {
+					col = columnIndex(cell.Ref)
+				}
+				for len(values) < col {
+					values = append(values, "")
+				}
+				value := cell.Value
+				switch cell.Type {
+				case "s":
+					if idx, err := strconv.Atoi(cell.Value); err == nil && idx >= 0 && idx < len(shared.Items) {
+						value = shared.Items[idx].String()
+					}
+				case "inlineStr":
+					value = cell.Inline.String()
+				}
+				if col < len(values) {
+					values[col] = value
+				} else {
+					values = append(values, value)
+				}
+			}
+			sheet.Rows = append(sheet.Rows, values)
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+func decodePart(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrUnsupportedFormat, name)
+	}
+	if f.UncompressedSize64 > maxPartSize {
+		return fmt.Errorf("%w: %s is too large", ErrUnsupportedFormat, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a zero-based column index.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// --- Helpers shared by the spreadsheet-based parsers ---
+
+// Header maps lowercased column names to their position in a header row.
+type Header map[string]int
+
+// FindHeader returns the index of the first row that contains all the required column names
+// (compared case-insensitively) together with its column map. Broker exports often put summary
+// lines above the table, so the header is not necessarily the first row.
+func FindHeader(rows [][]string, required ...string) (int, Header, bool) {
+	for i, row := range rows {
+		header := make(Header, len(row))
+		for j, name := range row {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, exists := header[name]; name != "" && !exists {
+				header[name] = j
+			}
+		}
+		found := true
+		for _, name := range required {
+			if _, ok := header[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i, header, true
+		}
+	}
+	return -1, nil, false
+}
+
+// Get returns the trimmed value of the first of names present in the header, or "".
+func (h Header) Get(row []string, names ...string) string {
+	for _, name := range names {
+		if i, ok := h[name]; ok {
+			if i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// ParseNumber parses numbers in both the English (1,234.56) and the continental (1.234,56)
+// notation. When both separators appear, the last one is the decimal point; a lone separator
+// repeated more than once is a thousands separator. An empty value parses as zero.
+func ParseNumber(s string) (float64, error) {
+	cleaned := strings.Trim(strings.TrimSpace(s), "\"")
+	cleaned = strings.NewReplacer(" ", "", "\u00A0", "", "'", "").Replace(cleaned)
+	if cleaned == "" {
+		return 0, nil
+	}
+
+	lastComma, lastDot := strings.LastIndex(cleaned, ","), strings.LastIndex(cleaned, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		if lastComma > lastDot {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		} else {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		}
+	case lastComma >= 0:
+		if strings.Count(cleaned, ",") > 1 {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		} else {
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		}
+	case lastDot >= 0:
+		if strings.Count(cleaned, ".") > 1 {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+		}
+	}
+
+	value, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return value, nil
+}
+
+// excelEpoch is day zero of the Excel 1900 date system (accounting for the 1900 leap year bug).
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseDate parses a cell holding either an Excel serial date or text in one of the layouts.
+func ParseDate(value string, layouts ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		days := math.Floor(serial)
+		seconds := math.Round((serial - days) * 86400)
+		return excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second), nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// Payload writes rows as a small CSV, so a skipped row can be stored with its header and parsed again later.
+func Payload(rows ...[]string) string {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.WriteAll(rows)
+	return buf.String()
+}
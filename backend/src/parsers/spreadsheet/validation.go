@@ -0,0 +1,129 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExcerpt is the number of characters of a row quoted in a validation issue.
+const maxExcerpt = 120
+
+// ValidationIssue is one problem that kept a file from being read, located as precisely as the
+// parser could, so the user can fix the file.
+type ValidationIssue struct {
+	File    string `json:"file,omitempty"`    // Entry of an archive
+	Sheet   string `json:"sheet,omitempty"`   // Worksheet of an XLSX workbook
+	Row     int    `json:"row,omitempty"`     // Line or row number, from 1; absent when the issue concerns the whole file
+	Column  string `json:"column,omitempty"`  // Column name
+	Reason  string `json:"reason"`            // What is wrong
+	Excerpt string `json:"excerpt,omitempty"` // Start of the row's content
+}
+
+// ValidationError is returned when a file cannot be read, with the issues found in it.
+type ValidationError struct {
+	Parser string // Prefixes the message, e.g. "degiro parser"
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 0 {
+		return e.Parser + ": invalid file"
+	}
+	first := e.Issues[0]
+	var where []string
+	if first.Row > 0 {
+		where = append(where, fmt.Sprintf("row %d", first.Row))
+	}
+	if first.Column != "" {
+		where = append(where, fmt.Sprintf("column %q", first.Column))
+	}
+	msg := e.Parser + ": " + first.Reason
+	if len(where) > 0 {
+		msg += " (" + strings.Join(where, ", ") + ")"
+	}
+	if len(e.Issues) > 1 {
+		msg += fmt.Sprintf(" and %d more issues", len(e.Issues)-1)
+	}
+	return msg
+}
+
+// MissingColumns reports required columns absent from the header at row.
+func MissingColumns(parser string, row int, header []string, columns ...string) *ValidationError {
+	e := &ValidationError{Parser: parser}
+	for _, column := range columns {
+		e.Issues = append(e.Issues, ValidationIssue{Row: row, Column: column, Reason: "required column not found", Excerpt: Excerpt(header)})
+	}
+	return e
+}
+
+// HeaderNotFound reports a table whose header row is in none of the sheets. The row holding the most of
+// the required column names, if any, is taken for the intended header, and the names it lacks are
+// reported against it.
+func HeaderNotFound(parser string, sheets []Sheet, required ...string) *ValidationError {
+	bestSheet, bestRow, bestFound := "", -1, 0
+	var bestMissing []string
+	var bestCells []string
+	for _, sheet := range sheets {
+		for i, row := range sheet.Rows {
+			names := make(map[string]bool, len(row))
+			for _, name := range row {
+				names[strings.ToLower(strings.TrimSpace(name))] = true
+			}
+			var missing []string
+			for _, name := range required {
+				if !names[name] {
+					missing = append(missing, name)
+				}
+			}
+			if found := len(required) - len(missing); found > bestFound {
+				bestSheet, bestRow, bestFound, bestMissing, bestCells = sheet.Name, i, found, missing, row
+			}
+		}
+	}
+	if bestRow < 0 {
+		return &ValidationError{Parser: parser, Issues: []ValidationIssue{{
+			Reason: fmt.Sprintf("no table with the columns %s found", strings.Join(required, ", ")),
+		}}}
+	}
+	e := MissingColumns(parser, bestRow+1, bestCells, bestMissing...)
+	for i := range e.Issues {
+		e.Issues[i].Sheet = bestSheet
+	}
+	return e
+}
+
+// ReadError turns a CSV or XML syntax error into a ValidationError on the line it was found at, and
+// the end of an empty file into one for the whole file. Other errors are returned wrapped with the
+// parser's name.
+func ReadError(parser string, err error) error {
+	if errors.Is(err, io.EOF) {
+		return &ValidationError{Parser: parser, Issues: []ValidationIssue{{Reason: "the file is empty"}}}
+	}
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		return &ValidationError{Parser: parser, Issues: []ValidationIssue{{
+			Row:    csvErr.Line,
+			Reason: fmt.Sprintf("%v at character %d", csvErr.Err, csvErr.Column),
+		}}}
+	}
+	var xmlErr *xml.SyntaxError
+	if errors.As(err, &xmlErr) {
+		return &ValidationError{Parser: parser, Issues: []ValidationIssue{{Row: xmlErr.Line, Reason: xmlErr.Msg}}}
+	}
+	return fmt.Errorf("%s: %w", parser, err)
+}
+
+// Excerpt quotes the start of a row, joined as in a CSV file.
+func Excerpt(row []string) string {
+	text := strings.Join(row, ",")
+	if utf8.RuneCountInString(text) <= maxExcerpt {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxExcerpt]) + "…"
+}
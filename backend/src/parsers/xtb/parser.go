@@ -0,0 +1,221 @@
+// backend/src/parsers/xtb/parser.go
+package xtb
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers/spreadsheet"
+)
+
+const source = "xtb"
+
+var (
+	requiredColumns = []string{"id", "type", "time", "amount"}
+	tradeCommentRe  = regexp.MustCompile(`(?i)(OPEN|CLOSE)\s+BUY\s+([\d.,]+)(?:/[\d.,]+)?\s*@\s*([\d.,]+)`)
+	dateLayouts     = []string{"02.01.2006 15:04:05", "02.01.2006 15:04", "2006-01-02 15:04:05", "02/01/2006 15:04:05", "02.01.2006"}
+	// marketCountries maps the market suffix of xStation symbols to the ISO 3166 alpha-2 code of its country.
+	marketCountries = map[string]string{
+		"US": "US", "UK": "GB", "DE": "DE", "FR": "FR", "NL": "NL", "ES": "ES", "IT": "IT", "PT": "PT",
+		"BE": "BE", "CH": "CH", "DK": "DK", "SE": "SE", "NO": "NO", "FI": "FI", "PL": "PL", "CZ": "CZ",
+	}
+)
+
+// XTBParser implements the parsers.Parser interface for the xStation "Cash operations" export,
+// as XLSX or as CSV.
+type XTBParser struct {
+	skipped []models.SkippedRow
+}
+
+// NewParser creates a new instance of the XTBParser.
+func NewParser() *XTBParser {
+	return &XTBParser{}
+}
+
+// Parse reads the cash operations of an XTB export and converts them into CanonicalTransactions.
+// Trades, dividends, withholding taxes, fees and cash transfers are all listed there.
+func (p *XTBParser) Parse(file io.Reader) ([]models.CanonicalTransaction, error) {
+	p.skipped = nil
+
+	sheets, err := spreadsheet.Read(file)
+	if err != nil {
+		return nil, spreadsheet.ReadError("xtb parser", err)
+	}
+
+	var canonicalTxs []models.CanonicalTransaction
+	found := false
+	for _, sheet := range sheets {
+		headerRow, header, ok := spreadsheet.FindHeader(sheet.Rows, requiredColumns...)
+		if !ok {
+			continue
+		}
+		found = true
+		currency := accountCurrency(sheet.Rows[:headerRow])
+
+		for _, row := range sheet.Rows[headerRow+1:] {
+			if header.Get(row, "id") == "" || header.Get(row, "type") == "" {
+				continue // Blank or total lines
+			}
+			tx, err := p.convert(header, row, currency)
+			if err != nil {
+				logger.L.Warn("XTB Parser: Skipping row", "id", header.Get(row, "id"), "error", err)
+				p.skipped = append(p.skipped, models.SkippedRow{
+					RawText: strings.Join(row, ","),
+					Reason:  err.Error(),
+					Payload: spreadsheet.Payload([]string{"Currency", currency}, sheet.Rows[headerRow], row),
+				})
+				continue
+			}
+			canonicalTxs = append(canonicalTxs, tx)
+		}
+	}
+	if !found {
+		return nil, spreadsheet.HeaderNotFound("xtb parser", sheets, requiredColumns...)
+	}
+	return canonicalTxs, nil
+}
+
+// SkippedRows returns the rows dropped by the last call to Parse.
+func (p *XTBParser) SkippedRows() []models.SkippedRow {
+	return p.skipped
+}
+
+// Recognizes reports whether any of the sheets holds XTB cash operations.
+func Recognizes(sheets []spreadsheet.Sheet) bool {
+	for _, sheet := range sheets {
+		if _, _, ok := spreadsheet.FindHeader(sheet.Rows, requiredColumns...); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// convert maps one cash operation to a CanonicalTransaction.
+func (p *XTBParser) convert(header spreadsheet.Header, row []string, currency string) (models.CanonicalTransaction, error) {
+	date, err := spreadsheet.ParseDate(header.Get(row, "time"), dateLayouts...)
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+	amount, err := spreadsheet.ParseNumber(header.Get(row, "amount"))
+	if err != nil {
+		return models.CanonicalTransaction{}, err
+	}
+
+	id := header.Get(row, "id")
+	symbol := header.Get(row, "symbol", "instrument")
+	comment := header.Get(row, "comment")
+	opType := strings.ToLower(header.Get(row, "type"))
+
+	// XTB does not export ISINs: the symbol (e.g. "AAPL.US") names the instrument, and its market
+	// suffix stands in for the country the ISIN would give.
+	tx := models.CanonicalTransaction{
+		Source:          source,
+		TransactionDate: date,
+		ProductName:     symbol,
+		Country:         symbolCountry(symbol),
+		Currency:        currency,
+		OrderID:         id,
+		RawText:         fmt.Sprintf("CashOperation|%s|%s|%s|%s|%s|%s", id, opType, header.Get(row, "time"), symbol, comment, header.Get(row, "amount")),
+		SourceAmount:    amount,
+	}
+
+	switch {
+	case strings.Contains(opType, "purchase"), strings.Contains(opType, "sale"):
+		matches := tradeCommentRe.FindStringSubmatch(comment)
+		if matches == nil {
+			return models.CanonicalTransaction{}, fmt.Errorf("unrecognised trade comment %q", comment)
+		}
+		quantity, err := spreadsheet.ParseNumber(matches[2])
+		if err != nil || quantity <= 0 {
+			return models.CanonicalTransaction{}, fmt.Errorf("invalid quantity in trade comment %q", comment)
+		}
+		if quantity != math.Trunc(quantity) {
+			return models.CanonicalTransaction{}, fmt.Errorf("fractional quantity %s is not supported", matches[2])
+		}
+		price, _ := spreadsheet.ParseNumber(matches[3])
+		tx.TransactionType = "STOCK"
+		tx.Quantity = quantity
+		tx.Price = price
+		if strings.EqualFold(matches[1], "OPEN") {
+			tx.BuySell = "BUY"
+			tx.Amount = -math.Abs(amount)
+		} else {
+			tx.BuySell = "SELL"
+			tx.Amount = math.Abs(amount)
+		}
+	case strings.Contains(opType, "withholding tax"):
+		tx.TransactionType = "DIVIDEND"
+		tx.TransactionSubType = "TAX"
+		tx.Amount = -math.Abs(amount)
+	case strings.HasPrefix(opType, "divident"), strings.HasPrefix(opType, "dividend"):
+		tx.TransactionType = "DIVIDEND"
+		tx.Amount = math.Abs(amount)
+	case opType == "deposit":
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "DEPOSIT"
+		tx.ProductName, tx.Country = "Cash Deposit", ""
+		tx.Amount = math.Abs(amount)
+	case opType == "withdrawal":
+		tx.TransactionType = "CASH"
+		tx.TransactionSubType = "WITHDRAWAL"
+		tx.ProductName, tx.Country = "Cash Withdrawal", ""
+		tx.Amount = -math.Abs(amount)
+	case strings.Contains(opType, "interest"):
+		// Free-funds interest, and the tax withheld from it
+		tx.TransactionType = "INTEREST"
+		tx.ProductName, tx.Country = header.Get(row, "type"), ""
+		tx.Amount = amount
+		if strings.Contains(opType, "tax") {
+			tx.TransactionSubType = "TAX"
+			tx.Amount = -math.Abs(amount)
+		}
+	case strings.Contains(opType, "fee"), strings.Contains(opType, "commission"):
+		tx.TransactionType = "FEE"
+		if tx.ProductName == "" {
+			tx.ProductName = header.Get(row, "type")
+		}
+		tx.Amount = -math.Abs(amount)
+	default:
+		return models.CanonicalTransaction{}, fmt.Errorf("unsupported operation type %q", header.Get(row, "type"))
+	}
+	return tx, nil
+}
+
+// symbolCountry returns the country of the market an xStation symbol is listed on, or "" when its
+// suffix is not known.
+func symbolCountry(symbol string) string {
+	i := strings.LastIndex(symbol, ".")
+	if i < 0 {
+		return ""
+	}
+	return marketCountries[strings.ToUpper(symbol[i+1:])]
+}
+
+// accountCurrency looks for the account currency in the summary lines above the table,
+// either next to or below a "Currency" label. XTB accounts of Portuguese users default to EUR.
+func accountCurrency(rows [][]string) string {
+	for i, row := range rows {
+		for j, cell := range row {
+			if !strings.EqualFold(strings.TrimSpace(cell), "currency") {
+				continue
+			}
+			if j+1 < len(row) && isCurrencyCode(row[j+1]) {
+				return strings.TrimSpace(row[j+1])
+			}
+			if i+1 < len(rows) && j < len(rows[i+1]) && isCurrencyCode(rows[i+1][j]) {
+				return strings.TrimSpace(rows[i+1][j])
+			}
+		}
+	}
+	return "EUR"
+}
+
+func isCurrencyCode(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) == 3 && strings.ToUpper(s) == s && strings.ToLower(s) != s
+}
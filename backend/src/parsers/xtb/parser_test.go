@@ -0,0 +1,38 @@
+package xtb
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/parsers/parsertest"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	os.Exit(m.Run())
+}
+
+func TestParseGolden(t *testing.T) {
+	parsertest.Golden(t, func() parsertest.Parser { return NewParser() }, ".csv", "cash_operations")
+}
+
+// XTB exports no ISINs: instruments are named by their symbol, and only whole shares are imported.
+func TestParseNamesBySymbolAndSkipsFractionalTrades(t *testing.T) {
+	parser := NewParser()
+	txs := parsertest.Parse(t, parser, `ID;Type;Time;Symbol;Comment;Amount
+1;Stocks/ETF purchase;03.01.2024 15:30:10;SAP.DE;OPEN BUY 3 @ 140.00;-420.00
+2;Stocks/ETF purchase;04.01.2024 10:00:00;VWCE.DE;OPEN BUY 0.5 @ 105.10;-52.55
+`)
+	if len(txs) != 1 {
+		t.Fatalf("got %d transactions, want 1: %+v", len(txs), txs)
+	}
+	if tx := txs[0]; tx.ISIN != "" || tx.ProductName != "SAP.DE" || tx.Country != "DE" || tx.Quantity != 3 {
+		t.Errorf("trade parsed as ISIN %q, product %q, country %q, quantity %v", tx.ISIN, tx.ProductName, tx.Country, tx.Quantity)
+	}
+	skipped := parser.SkippedRows()
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Reason, "fractional") {
+		t.Errorf("skipped %+v, want the fractional trade", skipped)
+	}
+}
@@ -0,0 +1,126 @@
+package processors
+
+import (
+	"sort"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// bondLot is nominal bought in one purchase that is still held, with its cost in the base currency.
+type bondLot struct {
+	nominal int64
+	costEUR models.Money
+}
+
+type bondProcessorImpl struct{}
+
+// NewBondProcessor creates a new BondProcessor.
+func NewBondProcessor() BondProcessor {
+	return &bondProcessorImpl{}
+}
+
+// Process implements the BondProcessor interface. BOND transactions without a subtype are purchases
+// and sales, with the nominal as quantity; COUPON and ACCRUED_INTEREST ones are interest, taken as
+// received or paid; REDEMPTION ones are repayments at maturity, matched like sales. Sales and
+// redemptions are matched against the purchases first in, first out. Commissions are left out, as
+// they are reported with the fees.
+func (p *bondProcessorImpl) Process(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) models.BondIncomeReport {
+	var bondTxs []models.ProcessedTransaction
+	for _, tx := range transactions {
+		if tx.TransactionType == "BOND" {
+			bondTxs = append(bondTxs, tx)
+		}
+	}
+	sort.SliceStable(bondTxs, func(i, j int) bool {
+		dateI, dateJ := utils.ParseDate(bondTxs[i].Date), utils.ParseDate(bondTxs[j].Date)
+		if dateI.Equal(dateJ) {
+			return bondTxs[i].BuySell == "BUY" && bondTxs[j].BuySell != "BUY"
+		}
+		return dateI.Before(dateJ)
+	})
+
+	report := models.BondIncomeReport{Years: []models.BondIncomeYear{}, Lines: []models.BondIncomeLine{}}
+	lots := make(map[string][]bondLot)
+	years := make(map[string]*models.BondIncomeYear)
+	for _, tx := range bondTxs {
+		line := models.BondIncomeLine{
+			Date:        tx.Date,
+			TaxYear:     fiscalYear.Label(utils.ParseDate(tx.Date)),
+			ISIN:        tx.ISIN,
+			ProductName: tx.ProductName,
+			Source:      tx.Source,
+			Amount:      tx.Amount.Float64(),
+			Currency:    tx.Currency,
+		}
+		switch {
+		case tx.TransactionSubType == "COUPON":
+			line.Kind = "coupon"
+			line.AmountEUR = utils.RoundAmount(tx.AmountEUR).Float64()
+		case tx.TransactionSubType == "ACCRUED_INTEREST":
+			line.Kind = "accrued_interest"
+			line.AmountEUR = utils.RoundAmount(tx.AmountEUR).Float64()
+		case tx.BuySell == "BUY":
+			lots[tx.ISIN] = append(lots[tx.ISIN], bondLot{nominal: int64(tx.Quantity), costEUR: tx.AmountEUR.Abs()})
+			continue
+		case tx.BuySell == "SELL" || tx.TransactionSubType == "REDEMPTION":
+			line.Kind = "sale"
+			if tx.TransactionSubType == "REDEMPTION" {
+				line.Kind = "redemption"
+			}
+			var cost models.Money
+			lots[tx.ISIN], cost = matchBondLots(lots[tx.ISIN], int64(tx.Quantity))
+			line.Nominal = tx.Quantity
+			line.ProceedsEUR = utils.RoundAmount(tx.AmountEUR.Abs()).Float64()
+			line.CostBasisEUR = utils.RoundAmount(cost).Float64()
+			line.AmountEUR = utils.RoundMoney(line.ProceedsEUR - line.CostBasisEUR)
+		default:
+			continue
+		}
+		report.Lines = append(report.Lines, line)
+		addBondIncome(years, line)
+	}
+
+	for _, year := range years {
+		report.Years = append(report.Years, *year)
+	}
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].Year < report.Years[j].Year })
+	return report
+}
+
+// matchBondLots takes nominal from the oldest lots, returning the lots left and the cost of what was
+// taken. Nominal beyond what the lots hold, such as bonds bought before the first statement, has no cost.
+func matchBondLots(lots []bondLot, nominal int64) ([]bondLot, models.Money) {
+	var cost models.Money
+	for nominal > 0 && len(lots) > 0 {
+		lot := &lots[0]
+		taken := min(nominal, lot.nominal)
+		share := lot.costEUR.MulDiv(taken, lot.nominal)
+		cost += share
+		lot.costEUR -= share
+		lot.nominal -= taken
+		nominal -= taken
+		if lot.nominal == 0 {
+			lots = lots[1:]
+		}
+	}
+	return lots, cost
+}
+
+// addBondIncome adds a line to the totals of its tax year.
+func addBondIncome(years map[string]*models.BondIncomeYear, line models.BondIncomeLine) {
+	year, ok := years[line.TaxYear]
+	if !ok {
+		year = &models.BondIncomeYear{Year: line.TaxYear}
+		years[line.TaxYear] = year
+	}
+	switch line.Kind {
+	case "coupon":
+		year.CouponsEUR = utils.RoundMoney(year.CouponsEUR + line.AmountEUR)
+	case "accrued_interest":
+		year.AccruedInterestEUR = utils.RoundMoney(year.AccruedInterestEUR + line.AmountEUR)
+	default:
+		year.CapitalGainsEUR = utils.RoundMoney(year.CapitalGainsEUR + line.AmountEUR)
+	}
+	year.InterestIncomeEUR = utils.RoundMoney(year.CouponsEUR + year.AccruedInterestEUR)
+}
@@ -0,0 +1,160 @@
+package processors
+
+import (
+	"sort"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// cashBalanceTolerance is the difference between the rebuilt and the reported balance put down to rounding.
+var cashBalanceTolerance = models.NewMoney(0.01)
+
+// cashSeriesKey identifies the cash of one currency at one broker.
+type cashSeriesKey struct {
+	source, currency string
+}
+
+// cashDay is what happened to one series on one day.
+type cashDay struct {
+	change   models.Money
+	reported *models.Money
+}
+
+type cashBalanceProcessorImpl struct{}
+
+// NewCashBalanceProcessor creates a new CashBalanceProcessor.
+func NewCashBalanceProcessor() CashBalanceProcessor {
+	return &cashBalanceProcessorImpl{}
+}
+
+// Process implements the CashBalanceProcessor interface. Every transaction moves its signed amount in
+// its currency, except scrip dividends, opening lots and conversions into or out of a money-market fund,
+// which move no cash; a trade's commission (the
+// share of its order's, for a partial fill) is taken in EUR for DeGiro and in the trade currency for
+// the other brokers, as the fee report does. The first reported balance of a series sets its opening
+// balance, and a point is flagged when the difference to the reported balance changes, that is when
+// cash moved that no imported transaction explains.
+func (p *cashBalanceProcessorImpl) Process(transactions []models.ProcessedTransaction, reported []models.BrokerBalance) models.CashBalanceReport {
+	days := make(map[cashSeriesKey]map[time.Time]*cashDay)
+	day := func(key cashSeriesKey, date string) *cashDay {
+		if days[key] == nil {
+			days[key] = make(map[time.Time]*cashDay)
+		}
+		d := utils.ParseDate(date)
+		if days[key][d] == nil {
+			days[key][d] = &cashDay{}
+		}
+		return days[key][d]
+	}
+
+	moneyMarketFund := newMoneyMarketFundProcessor()
+	for _, tx := range transactions {
+		if tx.TransactionType == "SCRIP_DIVIDEND" || tx.TransactionSubType == models.OpeningBalanceSubType || utils.ParseDate(tx.Date).IsZero() {
+			continue
+		}
+		key := cashSeriesKey{tx.Source, tx.Currency}
+		if change, ok := moneyMarketFund.cashChange(key, tx); ok {
+			if change != 0 {
+				day(key, tx.Date).change += change
+			}
+			continue
+		}
+		day(key, tx.Date).change += tx.Amount
+
+		// Each partial fill of an order carries its share of the order's commission.
+		if tx.Commission > 0 && tx.OrderID != "" {
+			currency := tx.Currency
+			if tx.Source == "degiro" {
+				currency = "EUR"
+			}
+			day(cashSeriesKey{tx.Source, currency}, tx.Date).change -= tx.Commission
+		}
+	}
+
+	// Statements list the newest rows first, as DeGiro's does, so the first balance stored for a day is
+	// the one at its end.
+	for _, b := range reported {
+		if utils.ParseDate(b.Date).IsZero() {
+			continue
+		}
+		d := day(cashSeriesKey{b.Source, b.Currency}, b.Date)
+		if d.reported == nil {
+			balance := b.Balance
+			d.reported = &balance
+		}
+	}
+
+	report := models.CashBalanceReport{Series: []models.CashBalanceSeries{}}
+	for key, byDate := range days {
+		series := cashBalanceSeries(key, byDate)
+		series.MoneyMarketPriceChanges = utils.RoundAmount(moneyMarketFund.priceChanges[key]).Float64()
+		for _, point := range series.Points {
+			if point.Discrepancy {
+				report.Discrepancies++
+			}
+		}
+		report.Series = append(report.Series, series)
+	}
+	sort.Slice(report.Series, func(i, j int) bool {
+		if report.Series[i].Source != report.Series[j].Source {
+			return report.Series[i].Source < report.Series[j].Source
+		}
+		return report.Series[i].Currency < report.Series[j].Currency
+	})
+	return report
+}
+
+// cashBalanceSeries runs the balance of one series through its days in date order.
+func cashBalanceSeries(key cashSeriesKey, byDate map[time.Time]*cashDay) models.CashBalanceSeries {
+	dates := make([]time.Time, 0, len(byDate))
+	for date := range byDate {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	// The balance is rebuilt from zero first, so the opening balance is known once the first reported
+	// balance is found.
+	var rebuilt models.Money
+	var opening, previousDifference models.Money
+	seenReported := false
+	points := make([]models.CashBalancePoint, 0, len(dates))
+	balances := make([]models.Money, 0, len(dates))
+	differences := make([]*models.Money, 0, len(dates))
+	for _, date := range dates {
+		d := byDate[date]
+		rebuilt += d.change
+		point := models.CashBalancePoint{Date: date.Format("02-01-2006"), Change: utils.RoundAmount(d.change).Float64()}
+		var difference *models.Money
+		if d.reported != nil {
+			diff := *d.reported - rebuilt
+			if !seenReported {
+				opening, previousDifference, seenReported = diff, diff, true
+			}
+			point.Discrepancy = (diff - previousDifference).Abs() > cashBalanceTolerance
+			previousDifference = diff
+			reported := utils.RoundAmount(*d.reported).Float64()
+			point.BrokerBalance = &reported
+			difference = &diff
+		}
+		points = append(points, point)
+		balances = append(balances, rebuilt)
+		differences = append(differences, difference)
+	}
+
+	for i := range points {
+		points[i].Balance = utils.RoundAmount(balances[i] + opening).Float64()
+		if differences[i] != nil {
+			difference := utils.RoundAmount(*differences[i] - opening).Float64()
+			points[i].Difference = &difference
+		}
+	}
+	return models.CashBalanceSeries{
+		Source:         key.source,
+		Currency:       key.currency,
+		OpeningBalance: utils.RoundAmount(opening).Float64(),
+		Balance:        utils.RoundAmount(rebuilt + opening).Float64(),
+		Points:         points,
+	}
+}
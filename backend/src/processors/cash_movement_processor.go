@@ -1,9 +1,11 @@
 package processors
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
 )
 
 // cashMovementProcessor implements the CashMovementProcessor interface.
@@ -14,25 +16,44 @@ func NewCashMovementProcessor() CashMovementProcessor {
 	return &cashMovementProcessor{}
 }
 
-// Process identifies cash deposits and withdrawals from the list of processed transactions.
-func (p *cashMovementProcessor) Process(transactions []models.ProcessedTransaction) []models.CashMovement {
-	var cashMovements []models.CashMovement
+// cashMovementTypes maps the subtypes of CASH transactions to the type of their movement.
+var cashMovementTypes = map[string]string{
+	"DEPOSIT":    "deposit",
+	"WITHDRAWAL": "withdrawal",
+	"FX":         "fx_conversion",
+}
 
+// Process lists the cash deposits, withdrawals and currency conversions in date order. A conversion
+// is listed as two movements sharing its OrderID, one per currency. Each movement carries the running
+// balance of its currency at its broker, so money moved between currencies shows up where it went.
+func (p *cashMovementProcessor) Process(transactions []models.ProcessedTransaction) []models.CashMovement {
+	var cashTxs []models.ProcessedTransaction
 	for _, tx := range transactions {
-		// Check for cash deposits
-		if strings.ToLower(tx.TransactionType) == "cash" && strings.ToLower(tx.TransactionSubType) == "deposit" {
-			movement := models.CashMovement{
-				Date:     tx.Date,
-				Type:     "deposit", // Currently only handling deposits
-				Amount:   tx.Amount,
-				Currency: tx.Currency,
+		if strings.EqualFold(tx.TransactionType, "CASH") {
+			if _, ok := cashMovementTypes[strings.ToUpper(tx.TransactionSubType)]; ok {
+				cashTxs = append(cashTxs, tx)
 			}
-			cashMovements = append(cashMovements, movement)
 		}
-		// TODO: Add logic for withdrawals if needed, e.g., check for a specific OrderType or Description
 	}
-
-	// TODO: Consider sorting cashMovements by date if necessary
-
+	sort.SliceStable(cashTxs, func(i, j int) bool {
+		return utils.ParseDate(cashTxs[i].Date).Before(utils.ParseDate(cashTxs[j].Date))
+	})
+
+	cashMovements := []models.CashMovement{}
+	balances := make(map[[2]string]models.Money) // By source and currency
+	for _, tx := range cashTxs {
+		key := [2]string{tx.Source, tx.Currency}
+		balances[key] += tx.Amount
+		cashMovements = append(cashMovements, models.CashMovement{
+			Date:      tx.Date,
+			Type:      cashMovementTypes[strings.ToUpper(tx.TransactionSubType)],
+			Amount:    tx.Amount.Float64(),
+			Currency:  tx.Currency,
+			AmountEUR: utils.RoundAmount(tx.AmountEUR).Float64(),
+			Source:    tx.Source,
+			OrderID:   tx.OrderID,
+			Balance:   balances[key].Float64(),
+		})
+	}
 	return cashMovements
 }
@@ -0,0 +1,99 @@
+package processors
+
+import (
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// contributionWindow is the number of months of the rolling statistics.
+const contributionWindow = 12
+
+type contributionProcessorImpl struct{}
+
+// NewContributionProcessor creates a new ContributionProcessor.
+func NewContributionProcessor() ContributionProcessor {
+	return &contributionProcessorImpl{}
+}
+
+// Process implements the ContributionProcessor interface. Only deposits and withdrawals count:
+// currency conversions move money within the broker. Months without movements are listed with zeros
+// so the averages spread the money over the time it took to invest it.
+func (p *contributionProcessorImpl) Process(movements []models.CashMovement, now time.Time) models.ContributionReport {
+	report := models.ContributionReport{Months: []models.MonthlyContribution{}}
+
+	byMonth := make(map[time.Time]*models.MonthlyContribution)
+	var first time.Time
+	for i := range movements {
+		m := movements[i]
+		if m.Type != "deposit" && m.Type != "withdrawal" {
+			continue
+		}
+		date := utils.ParseDate(m.Date)
+		if date.IsZero() {
+			continue
+		}
+		month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		line, ok := byMonth[month]
+		if !ok {
+			line = &models.MonthlyContribution{}
+			byMonth[month] = line
+		}
+		if m.AmountEUR >= 0 {
+			line.DepositsEUR = utils.RoundMoney(line.DepositsEUR + m.AmountEUR)
+			if report.LargestDeposit == nil || m.AmountEUR > report.LargestDeposit.AmountEUR {
+				report.LargestDeposit = &movements[i]
+			}
+		} else {
+			line.WithdrawalsEUR = utils.RoundMoney(line.WithdrawalsEUR + m.AmountEUR)
+			if report.LargestWithdrawal == nil || m.AmountEUR < report.LargestWithdrawal.AmountEUR {
+				report.LargestWithdrawal = &movements[i]
+			}
+		}
+	}
+	if first.IsZero() {
+		return report
+	}
+
+	last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month := range byMonth {
+		if month.After(last) {
+			last = month // Movements dated in the future still count
+		}
+	}
+
+	var cumulative float64
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		line := models.MonthlyContribution{Month: month.Format("2006-01")}
+		if found, ok := byMonth[month]; ok {
+			line.DepositsEUR, line.WithdrawalsEUR = found.DepositsEUR, found.WithdrawalsEUR
+		}
+		line.NetEUR = utils.RoundMoney(line.DepositsEUR + line.WithdrawalsEUR)
+		cumulative = utils.RoundMoney(cumulative + line.NetEUR)
+		line.CumulativeEUR = cumulative
+
+		window := report.Months[max(0, len(report.Months)-(contributionWindow-1)):]
+		line.Rolling12EUR = line.NetEUR
+		for _, previous := range window {
+			line.Rolling12EUR += previous.NetEUR
+		}
+		line.Rolling12EUR = utils.RoundMoney(line.Rolling12EUR)
+		line.RollingAvgEUR = utils.RoundMoney(line.Rolling12EUR / float64(len(window)+1))
+
+		report.TotalDepositedEUR = utils.RoundMoney(report.TotalDepositedEUR + line.DepositsEUR)
+		report.TotalWithdrawnEUR = utils.RoundMoney(report.TotalWithdrawnEUR + line.WithdrawalsEUR)
+		if line.NetEUR > 0 {
+			report.MonthsWithContributions++
+		}
+		report.Months = append(report.Months, line)
+	}
+
+	report.NetContributedEUR = cumulative
+	report.AverageMonthlyEUR = utils.RoundMoney(cumulative / float64(len(report.Months)))
+	report.AverageMonthlyLast12EUR = report.Months[len(report.Months)-1].RollingAvgEUR
+	return report
+}
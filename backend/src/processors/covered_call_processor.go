@@ -0,0 +1,175 @@
+package processors
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// OptionContractShares is the number of shares one option contract is written on.
+const OptionContractShares = 100
+
+type coveredCallProcessorImpl struct{}
+
+// NewCoveredCallProcessor creates a new CoveredCallProcessor.
+func NewCoveredCallProcessor() CoveredCallProcessor {
+	return &coveredCallProcessorImpl{}
+}
+
+// shortCall is one short call position found in the option processor output: a closed sale or an open holding.
+type shortCall struct {
+	isin        string
+	productName string
+	openDate    string
+	openOrderID string
+	closeDate   string // Empty while open
+	closeOrder  string
+	shares      int
+	links       *[]models.CoveredCall // CoveredLots of the sale or holding the call came from
+	reserved    map[*models.ProcessedTransaction]int
+}
+
+// Process implements the CoveredCallProcessor interface. It replays the FIFO matching of the stock
+// transactions and, when each short call is written, sets aside shares of the open lots of its
+// underlying, oldest first, that are not already covering another call still open on that day.
+func (p *coveredCallProcessorImpl) Process(transactions []models.ProcessedTransaction, optionSales []models.OptionSaleDetail, optionHoldings []models.OptionHolding) []models.CoveredCall {
+	calls := findShortCalls(transactions, optionSales, optionHoldings)
+	links := []models.CoveredCall{}
+	if len(calls) == 0 {
+		return links
+	}
+
+	stockTransactions := filterAndSortStockTransactions(transactions)
+	matcher := newFIFOMatcher(nil, collectTransactionTaxes(transactions), models.CalendarYear)
+	reserved := make(map[*models.ProcessedTransaction]int)
+	var open []*shortCall
+	next := 0
+	for _, call := range calls {
+		writtenOn := utils.ParseDate(call.openDate)
+		// Shares bought on the day the call is written cover it.
+		for ; next < len(stockTransactions) && !utils.ParseDate(stockTransactions[next].Date).After(writtenOn); next++ {
+			matcher.apply(stockTransactions[next])
+		}
+		// Calls closed by then no longer hold on to their shares.
+		stillOpen := open[:0]
+		for _, o := range open {
+			if o.closeDate != "" && !utils.ParseDate(o.closeDate).After(writtenOn) {
+				for lot, shares := range o.reserved {
+					reserved[lot] -= shares
+				}
+				continue
+			}
+			stillOpen = append(stillOpen, o)
+		}
+		open = stillOpen
+
+		needed := call.shares
+		for _, lot := range matcher.openPurchasesByISIN[call.isin] {
+			if needed == 0 {
+				break
+			}
+			shares := utils.MinInt(needed, lot.Quantity-reserved[lot])
+			if shares <= 0 {
+				continue
+			}
+			needed -= shares
+			reserved[lot] += shares
+			call.reserved[lot] = shares
+			*call.links = append(*call.links, coveredCallLink(call, lot, shares))
+		}
+		if len(call.reserved) > 0 {
+			links = append(links, *call.links...)
+			open = append(open, call)
+		}
+	}
+	return links
+}
+
+// findShortCalls collects the short calls among the option sales and holdings, ordered by the date they were written.
+// The underlying is the ISIN of the transaction that opened the call.
+func findShortCalls(transactions []models.ProcessedTransaction, optionSales []models.OptionSaleDetail, optionHoldings []models.OptionHolding) []*shortCall {
+	opens := make(map[string]models.ProcessedTransaction)
+	for _, tx := range transactions {
+		if strings.ToUpper(tx.TransactionType) == "OPTION" {
+			opens[tx.ProductName+"|"+tx.OrderID] = tx
+		}
+	}
+	isWrittenCall := func(productName, orderID string) (string, bool) {
+		tx, ok := opens[productName+"|"+orderID]
+		if !ok || tx.ISIN == "" || tx.TransactionSubType != "CALL" || strings.ToUpper(tx.BuySell) != "SELL" {
+			return "", false
+		}
+		return tx.ISIN, true
+	}
+
+	var calls []*shortCall
+	for i := range optionSales {
+		sale := &optionSales[i]
+		if isin, ok := isWrittenCall(sale.ProductName, sale.OpenOrderID); ok {
+			calls = append(calls, &shortCall{
+				isin: isin, productName: sale.ProductName, openDate: sale.OpenDate, openOrderID: sale.OpenOrderID,
+				closeDate: sale.CloseDate, closeOrder: sale.CloseOrderID,
+				shares: sale.Quantity * OptionContractShares, links: &sale.CoveredLots,
+			})
+		}
+	}
+	for i := range optionHoldings {
+		holding := &optionHoldings[i]
+		if holding.Quantity >= 0 {
+			continue
+		}
+		if isin, ok := isWrittenCall(holding.ProductName, holding.OpenOrderID); ok {
+			calls = append(calls, &shortCall{
+				isin: isin, productName: holding.ProductName, openDate: holding.OpenDate, openOrderID: holding.OpenOrderID,
+				shares: -holding.Quantity * OptionContractShares, links: &holding.CoveredLots,
+			})
+		}
+	}
+	for _, call := range calls {
+		call.reserved = make(map[*models.ProcessedTransaction]int)
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		return utils.ParseDate(calls[i].openDate).Before(utils.ParseDate(calls[j].openDate))
+	})
+	return calls
+}
+
+func coveredCallLink(call *shortCall, lot *models.ProcessedTransaction, shares int) models.CoveredCall {
+	var costPerShare float64
+	if lot.OriginalQuantity > 0 {
+		costPerShare = -lot.AmountEUR.Float64() / float64(lot.OriginalQuantity) // Purchases are stored as negative amounts
+	}
+	return models.CoveredCall{
+		ISIN:               call.isin,
+		OptionProductName:  call.productName,
+		OptionOpenDate:     call.openDate,
+		OptionOpenOrderID:  call.openOrderID,
+		OptionCloseDate:    call.closeDate,
+		OptionCloseOrderID: call.closeOrder,
+		LotBuyDate:         lot.Date,
+		LotBuyPrice:        lot.Price.Float64(),
+		LotCostPerShareEUR: utils.RoundFloat(costPerShare, 4),
+		Shares:             shares,
+	}
+}
+
+// AnnotateCoveredLots returns a copy of the lots with the covered calls of each attached, matching
+// lots by ISIN, purchase date and price.
+func AnnotateCoveredLots(lots []models.PurchaseLot, links []models.CoveredCall) []models.PurchaseLot {
+	if len(links) == 0 {
+		return lots
+	}
+	annotated := make([]models.PurchaseLot, len(lots))
+	for i, lot := range lots {
+		lot.CoveredCalls = nil
+		for _, link := range links {
+			if link.ISIN == lot.ISIN && link.LotBuyDate == lot.BuyDate && link.LotBuyPrice == lot.BuyPrice {
+				lot.CoveredCalls = append(lot.CoveredCalls, link)
+			}
+		}
+		annotated[i] = lot
+	}
+	return annotated
+}
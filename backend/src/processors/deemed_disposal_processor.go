@@ -0,0 +1,108 @@
+package processors
+
+import (
+	"sort"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// DeemedDisposalYears is the holding period after which ETF units are deemed disposed of under Irish
+// rules, and again after every further period of the same length.
+const DeemedDisposalYears = 8
+
+type deemedDisposalProcessorImpl struct{}
+
+// NewDeemedDisposalProcessor creates a new DeemedDisposalProcessor.
+func NewDeemedDisposalProcessor() DeemedDisposalProcessor {
+	return &deemedDisposalProcessorImpl{}
+}
+
+// Process implements the DeemedDisposalProcessor interface. It replays the FIFO matching and, on each
+// anniversary up to asOf, reports the units of every ETF lot still open at the start of that day.
+func (p *deemedDisposalProcessorImpl) Process(transactions []models.ProcessedTransaction, asOf time.Time, fiscalYear models.FiscalYear) []models.DeemedDisposal {
+	etfISINs := make(map[string]bool)
+	for _, tx := range transactions {
+		if tx.AssetClass == models.AssetClassETF {
+			etfISINs[tx.ISIN] = true
+		}
+	}
+	disposals := []models.DeemedDisposal{}
+	if len(etfISINs) == 0 {
+		return disposals
+	}
+
+	stockTransactions := filterAndSortStockTransactions(transactions)
+	anniversaries := deemedDisposalDates(stockTransactions, etfISINs, asOf)
+	matcher := newFIFOMatcher(nil, collectTransactionTaxes(transactions), fiscalYear)
+	next := 0
+	for _, tx := range stockTransactions {
+		txDate := utils.ParseDate(tx.Date)
+		for ; next < len(anniversaries) && !anniversaries[next].After(txDate); next++ {
+			disposals = append(disposals, matcher.deemedDisposals(anniversaries[next], etfISINs)...)
+		}
+		matcher.apply(tx)
+	}
+	for ; next < len(anniversaries); next++ {
+		disposals = append(disposals, matcher.deemedDisposals(anniversaries[next], etfISINs)...)
+	}
+	return disposals
+}
+
+// deemedDisposalDates lists, in order, every anniversary up to asOf of the dates ETF lots were opened on.
+func deemedDisposalDates(transactions []models.ProcessedTransaction, etfISINs map[string]bool, asOf time.Time) []time.Time {
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+	for _, tx := range transactions {
+		buyDate := utils.ParseDate(tx.Date)
+		if !etfISINs[tx.ISIN] || buyDate.IsZero() {
+			continue
+		}
+		for years := DeemedDisposalYears; ; years += DeemedDisposalYears {
+			anniversary := buyDate.AddDate(years, 0, 0)
+			if anniversary.After(asOf) {
+				break
+			}
+			if !seen[anniversary] {
+				seen[anniversary] = true
+				dates = append(dates, anniversary)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// deemedDisposals returns the open ETF lots whose anniversary falls on date, at their purchase cost.
+func (m *fifoMatcher) deemedDisposals(date time.Time, etfISINs map[string]bool) []models.DeemedDisposal {
+	var disposals []models.DeemedDisposal
+	for _, lot := range collectAndCopyHoldings(m.openPurchasesByISIN) {
+		if !etfISINs[lot.ISIN] {
+			continue
+		}
+		buyDate := utils.ParseDate(lot.BuyDate)
+		for years := DeemedDisposalYears; !buyDate.AddDate(years, 0, 0).After(date); years += DeemedDisposalYears {
+			if !buyDate.AddDate(years, 0, 0).Equal(date) {
+				continue
+			}
+			disposals = append(disposals, models.DeemedDisposal{
+				Date:         date.Format(utils.DefaultDateFormat),
+				TaxYear:      m.fiscalYear.Label(date),
+				YearsHeld:    years,
+				ISIN:         lot.ISIN,
+				ProductName:  lot.ProductName,
+				BuyDate:      lot.BuyDate,
+				Quantity:     lot.Quantity,
+				CostBasisEUR: utils.RoundMoney(-lot.BuyAmountEUR), // Purchases are stored as negative amounts
+			})
+		}
+	}
+	sort.Slice(disposals, func(i, j int) bool {
+		if disposals[i].ISIN != disposals[j].ISIN {
+			return disposals[i].ISIN < disposals[j].ISIN
+		}
+		return utils.ParseDate(disposals[i].BuyDate).Before(utils.ParseDate(disposals[j].BuyDate))
+	})
+	return disposals
+}
@@ -1,7 +1,7 @@
 package processors
 
 import (
-	"math"
+	"sort"
 	"strings"
 	"time" // Import time package
 
@@ -25,6 +25,9 @@ func (p *dividendProcessorImpl) Calculate(transactions []models.ProcessedTransac
 
 	for _, t := range transactions {
 		transactionType := strings.ToLower(t.TransactionType)
+		if transactionType == "scrip_dividend" {
+			transactionType = "dividend" // Dividends paid in shares are taxed like cash dividends
+		}
 		if transactionType != "dividend" {
 			continue
 		}
@@ -36,11 +39,11 @@ func (p *dividendProcessorImpl) Calculate(transactions []models.ProcessedTransac
 		}
 		year := parsedTime.Format("2006") // Extract the year as string "YYYY"
 
-		if len(t.ISIN) < 2 {
+		countryFormattedString, ok := countryOf(t)
+		if !ok {
 			continue
 		}
-		countryFormattedString := utils.GetCountryCodeString(t.ISIN)
-		amount := roundToTwoDecimalPlaces(t.AmountEUR)
+		amount := utils.RoundAmount(t.AmountEUR).Float64()
 
 		if _, ok := result[year]; !ok {
 			result[year] = make(map[string]map[string]float64)
@@ -61,32 +64,12 @@ func (p *dividendProcessorImpl) Calculate(transactions []models.ProcessedTransac
 }
 
 // CalculateTaxSummary processes transactions and returns dividend data aggregated for tax reporting.
-func (p *dividendProcessorImpl) CalculateTaxSummary(transactions []models.ProcessedTransaction) models.DividendTaxResult {
+// Dividends are grouped by the tax year they were paid in under the given fiscal year.
+func (p *dividendProcessorImpl) CalculateTaxSummary(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) models.DividendTaxResult {
 	result := make(models.DividendTaxResult)
 
-	for _, t := range transactions {
-		transactionType := strings.ToLower(t.TransactionType)
-		if transactionType != "dividend" {
-			continue // Skip other transaction types
-		}
-
-		// Extract the year from the Date field (assuming DD-MM-YYYY format)
-		parsedTime, err := time.Parse("02-01-2006", t.Date)
-		if err != nil {
-			// Handle or log the error if the date format is incorrect
-			// For now, skip this transaction
-			continue
-		}
-		year := parsedTime.Format("2006") // Extract the year as string "YYYY"
-
-		// Get the formatted country string (e.g., "840 - United States of America (the)")
-		if len(t.ISIN) < 2 {
-			continue // Skip invalid ISINs
-		}
-		countryFormattedString := utils.GetCountryCodeString(t.ISIN)
-
-		// Use AmountEUR directly and round it
-		amount := roundToTwoDecimalPlaces(t.AmountEUR)
+	for _, l := range dividendTaxLines(transactions, fiscalYear) {
+		line, year, country := l.line, l.year, l.country
 
 		// Initialize maps if they don't exist
 		if _, ok := result[year]; !ok {
@@ -94,24 +77,24 @@ func (p *dividendProcessorImpl) CalculateTaxSummary(transactions []models.Proces
 		}
 
 		// Get the current summary for the country, or initialize if it doesn't exist
-		summary := result[year][countryFormattedString] // This works even if the key doesn't exist yet (returns zero-value struct)
+		summary := result[year][country] // This works even if the key doesn't exist yet (returns zero-value struct)
 
 		// Add the amount to the appropriate field
-		if transactionType == "dividend" && t.TransactionSubType != "TAX" {
-			summary.GrossAmt += amount
-		} else if transactionType == "dividend" && t.TransactionSubType == "TAX" {
-			summary.TaxedAmt += amount // Tax is usually negative, so += works
+		if line.Kind == dividendKindTax {
+			summary.TaxedAmt += line.AmountEUR // Tax is usually negative, so += works
+		} else {
+			summary.GrossAmt += line.AmountEUR
 		}
 
 		// Update the map with the modified summary
-		result[year][countryFormattedString] = summary
+		result[year][country] = summary
 	}
 
 	// Optional: Round final aggregated amounts again if needed due to potential floating point inaccuracies
 	for year, countries := range result {
 		for country, summary := range countries {
-			summary.GrossAmt = roundToTwoDecimalPlaces(summary.GrossAmt)
-			summary.TaxedAmt = roundToTwoDecimalPlaces(summary.TaxedAmt)
+			summary.GrossAmt = utils.RoundMoney(summary.GrossAmt)
+			summary.TaxedAmt = utils.RoundMoney(summary.TaxedAmt)
 			result[year][country] = summary
 		}
 	}
@@ -119,7 +102,173 @@ func (p *dividendProcessorImpl) CalculateTaxSummary(transactions []models.Proces
 	return result
 }
 
-// roundToTwoDecimalPlaces rounds a float64 to 2 decimal places.
-func roundToTwoDecimalPlaces(value float64) float64 {
-	return math.Round(value*100) / 100
+// CalculateDetail lists the transactions CalculateTaxSummary adds up for one tax year and country. The
+// country is matched on the numeric code its labels start with, so "620" and "620 - Portugal" both work.
+func (p *dividendProcessorImpl) CalculateDetail(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear, year, country string) models.DividendDetail {
+	detail := models.DividendDetail{Year: year, Country: country, Transactions: []models.DividendDetailLine{}}
+	wanted := countryKey(country)
+
+	for _, l := range dividendTaxLines(transactions, fiscalYear) {
+		line, lineCountry := l.line, l.country
+		if l.year != year || countryKey(lineCountry) != wanted {
+			continue
+		}
+		detail.Country = lineCountry
+		if line.Kind == dividendKindTax {
+			detail.TaxedAmt += line.AmountEUR
+		} else {
+			detail.GrossAmt += line.AmountEUR
+		}
+		detail.Transactions = append(detail.Transactions, line)
+	}
+
+	detail.GrossAmt = utils.RoundMoney(detail.GrossAmt)
+	detail.TaxedAmt = utils.RoundMoney(detail.TaxedAmt)
+	sort.SliceStable(detail.Transactions, func(i, j int) bool {
+		return utils.ParseDate(detail.Transactions[i].Date).Before(utils.ParseDate(detail.Transactions[j].Date))
+	})
+	return detail
+}
+
+const (
+	dividendKindGross = "gross"
+	dividendKindTax   = "tax"
+)
+
+// ibkrWithholdingWindow is how long after a dividend IBKR may book the tax withheld on it.
+const ibkrWithholdingWindow = 120 * 24 * time.Hour
+
+// dividendTaxLine is a line of the dividend tax summary with the tax year and country it counts in.
+type dividendTaxLine struct {
+	tx      models.ProcessedTransaction
+	line    models.DividendDetailLine
+	year    string
+	country string
+}
+
+// dividendTaxLines classifies the transactions for the dividend tax summary, following the way each
+// broker books dividends. DeGiro and the other brokers book the gross dividend and the tax withheld on
+// it on the same day, so each line counts in the tax year of its own date. IBKR books the withholding
+// as a separate record, at times days or weeks after the dividend, and when a dividend paid late in
+// December has its tax booked in January that tax would land in the wrong year, leaving one year with
+// gross and no tax and the next with tax and no gross. An IBKR withholding is therefore counted in the
+// tax year of the IBKR dividend it was withheld from.
+func dividendTaxLines(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) []dividendTaxLine {
+	lines := make([]dividendTaxLine, 0)
+	ibkrDividends := make(map[string][]models.ProcessedTransaction) // Gross IBKR dividends by ISIN
+	for _, t := range transactions {
+		line, year, country, ok := taxSummaryLine(t, fiscalYear)
+		if !ok {
+			continue
+		}
+		if t.Source == "ibkr" && line.Kind == dividendKindGross {
+			ibkrDividends[t.ISIN] = append(ibkrDividends[t.ISIN], t)
+		}
+		lines = append(lines, dividendTaxLine{tx: t, line: line, year: year, country: country})
+	}
+
+	for i := range lines {
+		l := &lines[i]
+		if l.line.Source != "ibkr" || l.line.Kind != dividendKindTax {
+			continue
+		}
+		if dividend, ok := ibkrWithheldFrom(ibkrDividends[l.tx.ISIN], l.tx); ok {
+			l.year = fiscalYear.Label(utils.ParseDate(dividend.Date))
+		}
+	}
+	return lines
+}
+
+// ibkrWithheldFrom finds the IBKR dividend a withholding line was withheld from among the dividends
+// on its ISIN: preferably one whose description the withholding's repeats, as in "AAPL(US0378331005)
+// Cash Dividend USD 0.24 per Share - US Tax", otherwise the latest paid before it within
+// ibkrWithholdingWindow.
+func ibkrWithheldFrom(dividends []models.ProcessedTransaction, tax models.ProcessedTransaction) (models.ProcessedTransaction, bool) {
+	taxDate := utils.ParseDate(tax.Date)
+	taxDescription := ibkrRawDescription(tax.Description)
+	if cut := strings.LastIndex(taxDescription, " - "); cut > 0 {
+		taxDescription = taxDescription[:cut]
+	} else {
+		taxDescription = ""
+	}
+
+	var latest, described models.ProcessedTransaction
+	var foundLatest, foundDescribed bool
+	for _, d := range dividends {
+		paid := utils.ParseDate(d.Date)
+		if paid.After(taxDate) || taxDate.Sub(paid) > ibkrWithholdingWindow {
+			continue
+		}
+		if !foundLatest || !paid.Before(utils.ParseDate(latest.Date)) {
+			latest, foundLatest = d, true
+		}
+		if taxDescription != "" && strings.HasPrefix(ibkrRawDescription(d.Description), taxDescription) &&
+			(!foundDescribed || !paid.Before(utils.ParseDate(described.Date))) {
+			described, foundDescribed = d, true
+		}
+	}
+	if foundDescribed {
+		return described, true
+	}
+	return latest, foundLatest
+}
+
+// ibkrRawDescription is IBKR's own description of a cash transaction, the third field of the raw
+// text the IBKR parser stores, such as "Dividend|20240102;202000|AAPL(US0378331005) Cash Dividend ...".
+func ibkrRawDescription(rawText string) string {
+	fields := strings.SplitN(rawText, "|", 4)
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[2]
+}
+
+// taxSummaryLine classifies a transaction for the dividend tax summary, returning the line it adds
+// with the tax year and country label it is grouped under. ok is false for transactions left out.
+func taxSummaryLine(t models.ProcessedTransaction, fiscalYear models.FiscalYear) (line models.DividendDetailLine, year, country string, ok bool) {
+	// Dividends paid in shares are taxed like cash dividends
+	transactionType := strings.ToLower(t.TransactionType)
+	if transactionType != "dividend" && transactionType != "scrip_dividend" {
+		return line, "", "", false
+	}
+
+	// Work out the tax year from the Date field (assuming DD-MM-YYYY format)
+	parsedTime, err := time.Parse("02-01-2006", t.Date)
+	if err != nil {
+		return line, "", "", false
+	}
+	country, ok = countryOf(t)
+	if !ok {
+		return line, "", "", false // Skip dividends of no known country
+	}
+
+	kind := dividendKindGross
+	if t.TransactionSubType == "TAX" {
+		kind = dividendKindTax
+	}
+	line = models.DividendDetailLine{
+		TransactionID:    t.ID,
+		Date:             t.Date,
+		ISIN:             t.ISIN,
+		ProductName:      t.ProductName,
+		Source:           t.Source,
+		Kind:             kind,
+		Amount:           t.Amount.Float64(),
+		Currency:         t.Currency,
+		ExchangeRate:     t.ExchangeRate,
+		ExchangeRateDate: t.ExchangeRateDate,
+		AmountEUR:        utils.RoundAmount(t.AmountEUR).Float64(),
+	}
+	// The country label looks like "840 - United States of America (the)"
+	return line, fiscalYear.Label(parsedTime), country, true
+}
+
+// countryKey is the part of a country label identifying the country in any locale: the numeric code
+// when present, otherwise the whole label.
+func countryKey(label string) string {
+	numeric, _, found := strings.Cut(label, " - ")
+	if !found {
+		return strings.TrimSpace(label)
+	}
+	return strings.TrimSpace(numeric)
 }
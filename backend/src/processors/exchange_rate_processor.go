@@ -4,112 +4,243 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/patrickmn/go-cache" // Import the cache library
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
 	"github.com/username/taxfolio/backend/src/models"
 )
 
-// Initialize a new cache for exchange rates.
-var rateCache = cache.New(24*time.Hour, 48*time.Hour)
-
 // LoadHistoricalRates is now obsolete and can be removed or left empty.
 func LoadHistoricalRates(filePath string) error {
 	logger.L.Info("Historical rates are now fetched via API; local file is not used.")
 	return nil
 }
 
+const (
+	// ecbHistoryStart is the first day of the ECB reference rates.
+	ecbHistoryStart = "1999-01-04"
+	// maxRateFallbackDays is how far back a rate is looked for when the ECB published none on a day,
+	// for weekends and holidays.
+	maxRateFallbackDays = 7
+	// rateSeriesRefresh is how old a series may be before a lookup of a day it does not reach yet
+	// downloads it again, to pick up the rates published since.
+	rateSeriesRefresh = time.Hour
+	// rateFetchRetry is how long to wait after a failed download before trying again.
+	rateFetchRetry = time.Minute
+)
+
+// observedRate is an ECB reference rate with the day it was published for.
+type observedRate struct {
+	rate float64
+	date time.Time // Midnight UTC
+}
+
+// rateSeries is the whole history of a currency's ECB reference rates, sorted by day, so a rate is
+// found by binary search instead of a request per day.
+type rateSeries struct {
+	observations []observedRate
+	through      time.Time // Day the series was downloaded; later days are not in it yet
+	fetchedAt    time.Time
+}
+
+// rateIndex holds the series downloaded so far, by currency.
+type rateIndex struct {
+	mu       sync.RWMutex
+	series   map[string]*rateSeries
+	failedAt map[string]time.Time // Last failed download of a currency
+
+	fetchMu sync.Mutex // Downloads one series at a time, so concurrent lookups do not repeat it
+}
+
+var rates = &rateIndex{series: map[string]*rateSeries{}, failedAt: map[string]time.Time{}}
+
+var ecbClient = http.Client{Timeout: 60 * time.Second}
+
 // GetExchangeRate retrieves the exchange rate for a given currency and date from the ECB API.
-// It uses a cache to store results and has a fallback to find the last available rate.
+// It falls back to the last rate published in the week before, for weekends and holidays.
 func GetExchangeRate(currency string, date time.Time) (float64, error) {
+	rate, _, err := GetExchangeRateWithDate(currency, date)
+	return rate, err
+}
+
+// GetExchangeRateWithDate is GetExchangeRate that also returns the day of the ECB observation used,
+// which is earlier than date when date was a weekend or a holiday.
+func GetExchangeRateWithDate(currency string, date time.Time) (float64, time.Time, error) {
 	if currency == "EUR" {
-		return 1.0, nil
-	}
-
-	// 1. Check Cache First
-	cacheKey := fmt.Sprintf("rate-%s-%s", currency, date.Format("2006-01-02"))
-	if rate, found := rateCache.Get(cacheKey); found {
-		logger.L.Debug("Exchange rate cache hit", "key", cacheKey)
-		return rate.(float64), nil
-	}
-	logger.L.Debug("Exchange rate cache miss", "key", cacheKey)
-
-	// 2. Fallback Loop: If no rate for today, check yesterday, etc. (up to 7 days)
-	for i := 0; i < 7; i++ {
-		queryDate := date.AddDate(0, 0, -i)
-		dateStr := queryDate.Format("2006-01-02")
-
-		// Construct the API URL
-		// Key structure is D.{CURRENCY}.EUR.SP00.A for daily rates vs Euro
-		seriesKey := fmt.Sprintf("D.%s.EUR.SP00.A", currency)
-		url := fmt.Sprintf(
-			"https://data-api.ecb.europa.eu/service/data/EXR/%s?startPeriod=%s&endPeriod=%s&format=jsondata",
-			seriesKey,
-			dateStr,
-			dateStr,
-		)
-
-		// Make the HTTP request
-		resp, err := http.Get(url)
-		if err != nil {
-			logger.L.Warn("Failed to make ECB API request", "url", url, "error", err)
-			continue // Try the previous day
-		}
-		defer resp.Body.Close()
+		return 1.0, date, nil
+	}
 
-		// If we get a 404, it means no data for this day (weekend/holiday), so we continue to the previous day.
-		if resp.StatusCode == http.StatusNotFound {
-			logger.L.Debug("No exchange rate found for date, trying previous day", "currency", currency, "date", dateStr)
-			continue
-		}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	series, err := rates.seriesFor(currency, day)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("exchange rate not found for %s on or before %s: %w", currency, day.Format("2006-01-02"), err)
+	}
+	observed, ok := series.latestOnOrBefore(day)
+	if !ok || day.Sub(observed.date) >= maxRateFallbackDays*24*time.Hour {
+		return 0, time.Time{}, fmt.Errorf("exchange rate not found for %s on or before %s", currency, day.Format("2006-01-02"))
+	}
+	return observed.rate, time.Date(observed.date.Year(), observed.date.Month(), observed.date.Day(), 0, 0, 0, 0, date.Location()), nil
+}
 
-		if resp.StatusCode != http.StatusOK {
-			logger.L.Warn("ECB API returned non-OK status", "status", resp.Status, "url", url)
-			continue // Try the previous day
-		}
+// latestOnOrBefore returns the last observation on or before day.
+func (s *rateSeries) latestOnOrBefore(day time.Time) (observedRate, bool) {
+	i := sort.Search(len(s.observations), func(i int) bool { return s.observations[i].date.After(day) })
+	if i == 0 {
+		return observedRate{}, false
+	}
+	return s.observations[i-1], true
+}
 
-		// Decode the JSON response
-		var ecbData models.ECBResponse
-		if err := json.NewDecoder(resp.Body).Decode(&ecbData); err != nil {
-			logger.L.Warn("Failed to decode ECB API response", "url", url, "error", err)
-			continue // Try the previous day
+// seriesFor returns the series of currency, downloading it when it has not been yet, or when day is
+// not before the day it was downloaded and it is older than rateSeriesRefresh. A stale series is
+// returned when the download fails.
+func (idx *rateIndex) seriesFor(currency string, day time.Time) (*rateSeries, error) {
+	idx.mu.RLock()
+	series, found := idx.series[currency]
+	idx.mu.RUnlock()
+	fresh := found && (day.Before(series.through) || time.Since(series.fetchedAt) < rateSeriesRefresh)
+	metrics.CacheLookup("exchange_rate", fresh)
+	if fresh {
+		return series, nil
+	}
+
+	idx.fetchMu.Lock()
+	defer idx.fetchMu.Unlock()
+	// Another lookup may have downloaded it, or failed to, while this one waited.
+	idx.mu.RLock()
+	series, found = idx.series[currency]
+	failedAt := idx.failedAt[currency]
+	idx.mu.RUnlock()
+	if found && time.Since(series.fetchedAt) < rateSeriesRefresh {
+		return series, nil
+	}
+	if time.Since(failedAt) < rateFetchRetry {
+		if found {
+			return series, nil
 		}
+		return nil, fmt.Errorf("ECB rates for %s unavailable", currency)
+	}
 
-		// Extract the rate
-		rate, err := extractRateFromResponse(ecbData)
-		if err != nil {
-			logger.L.Warn("Could not extract rate from ECB response", "date", dateStr, "error", err)
-			continue // Try the previous day
+	downloaded, err := fetchRateSeries(currency)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err != nil {
+		idx.failedAt[currency] = time.Now()
+		logger.L.Warn("Failed to download ECB exchange rates", "currency", currency, "error", err)
+		if found {
+			return series, nil
 		}
+		return nil, err
+	}
+	idx.series[currency] = downloaded
+	return downloaded, nil
+}
+
+// fetchRateSeries downloads every daily ECB reference rate of currency against the euro.
+func fetchRateSeries(currency string) (*rateSeries, error) {
+	// Key structure is D.{CURRENCY}.EUR.SP00.A for daily rates vs Euro
+	url := fmt.Sprintf("https://data-api.ecb.europa.eu/service/data/EXR/D.%s.EUR.SP00.A?startPeriod=%s&format=jsondata",
+		currency, ecbHistoryStart)
+	now := time.Now().UTC()
+	resp, err := ecbClient.Get(url)
+	metrics.ExternalCall("ecb", resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("ECB API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ECB API returned %s", resp.Status)
+	}
 
-		// 3. Success: Store in cache and return
-		logger.L.Info("Successfully fetched exchange rate from ECB API", "currency", currency, "requestedDate", date.Format("2006-01-02"), "foundDate", dateStr, "rate", rate)
-		rateCache.Set(cacheKey, rate, cache.DefaultExpiration)
-		return rate, nil
+	var ecbData models.ECBResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ecbData); err != nil {
+		return nil, fmt.Errorf("failed to decode ECB API response: %w", err)
+	}
+	observations, err := extractSeriesFromResponse(ecbData)
+	if err != nil {
+		return nil, err
 	}
+	logger.L.Info("Downloaded ECB exchange rates", "currency", currency, "observations", len(observations))
+	return &rateSeries{
+		observations: observations,
+		through:      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		fetchedAt:    time.Now(),
+	}, nil
+}
 
-	// 4. Failure after all fallbacks
-	return 0, fmt.Errorf("exchange rate not found for %s on or before %s", currency, date.Format("2006-01-02"))
+// GetExchangeRateTo returns how many units of currency are worth one unit of base on the given date,
+// crossing the ECB euro reference rates when neither currency is EUR.
+func GetExchangeRateTo(currency, base string, date time.Time) (float64, error) {
+	rate, _, err := GetExchangeRateToWithDate(currency, base, date)
+	return rate, err
 }
 
-// extractRateFromResponse safely navigates the complex ECB JSON structure to find the rate.
-func extractRateFromResponse(data models.ECBResponse) (float64, error) {
+// GetExchangeRateToWithDate is GetExchangeRateTo that also returns the day of the ECB observation used.
+// When the two legs of a cross rate fell back to different days, the earlier one is returned.
+func GetExchangeRateToWithDate(currency, base string, date time.Time) (float64, time.Time, error) {
+	if currency == base {
+		return 1.0, date, nil
+	}
+	currencyRate, currencyDate, err := GetExchangeRateWithDate(currency, date)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	baseRate, baseDate, err := GetExchangeRateWithDate(base, date)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if baseRate == 0 {
+		return 0, time.Time{}, fmt.Errorf("exchange rate for %s on %s is zero", base, date.Format("2006-01-02"))
+	}
+	if baseDate.Before(currencyDate) {
+		currencyDate = baseDate
+	}
+	return currencyRate / baseRate, currencyDate, nil
+}
+
+// SupportedBaseCurrencies are the currencies with ECB reference rates, which users can report in.
+var SupportedBaseCurrencies = map[string]bool{
+	"EUR": true, "USD": true, "GBP": true, "CHF": true, "JPY": true, "SEK": true, "NOK": true, "DKK": true,
+	"PLN": true, "CZK": true, "HUF": true, "RON": true, "BGN": true, "ISK": true, "TRY": true, "AUD": true,
+	"CAD": true, "NZD": true, "BRL": true, "CNY": true, "HKD": true, "IDR": true, "ILS": true, "INR": true,
+	"KRW": true, "MXN": true, "MYR": true, "PHP": true, "SGD": true, "THB": true, "ZAR": true,
+}
+
+// extractSeriesFromResponse reads the observations of an ECB JSON response, sorted by day. The keys
+// of the observations index the days listed in the structure's observation dimension.
+func extractSeriesFromResponse(data models.ECBResponse) ([]observedRate, error) {
 	if len(data.DataSets) == 0 {
-		return 0, fmt.Errorf("no dataSets in response")
+		return nil, fmt.Errorf("no dataSets in response")
+	}
+	if len(data.Structure.Dimensions.Observation) == 0 {
+		return nil, fmt.Errorf("no observation dimension in response")
 	}
+	days := data.Structure.Dimensions.Observation[0].Values
 
-	seriesMap := data.DataSets[0].Series
+	var observations []observedRate
 	// The series key is "0:0:0:0:0". We iterate to be safe.
-	for _, seriesData := range seriesMap {
-		if observations, ok := seriesData.Observations["0"]; ok {
-			if len(observations) > 0 {
-				return observations[0], nil
+	for _, seriesData := range data.DataSets[0].Series {
+		for key, values := range seriesData.Observations {
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(days) || len(values) == 0 || values[0] <= 0 {
+				continue
+			}
+			day, err := time.Parse("2006-01-02", days[i].ID)
+			if err != nil {
+				continue
 			}
+			observations = append(observations, observedRate{rate: values[0], date: day})
 		}
 	}
-
-	return 0, fmt.Errorf("observation value not found in the expected structure")
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("observation values not found in the expected structure")
+	}
+	sort.Slice(observations, func(i, j int) bool { return observations[i].date.Before(observations[j].date) })
+	return observations, nil
 }
 
 // Note: The old GetExchangeRate logic and the historicalRates variable can be deleted.
@@ -14,7 +14,6 @@ func NewFeeProcessor() FeeProcessor {
 
 func (p *feeProcessorImpl) Process(transactions []models.ProcessedTransaction) []models.FeeDetail {
 	var feeDetails []models.FeeDetail
-	processedCommissions := make(map[string]bool) // Map to track processed order IDs for commissions
 
 	for _, tx := range transactions {
 		// Case 1: Dedicated Fee Transactions (e.g., Degiro "custo de conectividade")
@@ -22,17 +21,28 @@ func (p *feeProcessorImpl) Process(transactions []models.ProcessedTransaction) [
 			feeDetails = append(feeDetails, models.FeeDetail{
 				Date:        tx.Date,
 				Description: tx.ProductName,
-				AmountEUR:   tx.AmountEUR, // This is already calculated in EUR
+				AmountEUR:   tx.AmountEUR.Float64(), // This is already calculated in EUR
 				Source:      tx.Source,
 				Category:    "Brokerage Fee",
 			})
 		}
 
-		// Case 2: Commissions from Trades
-		// This check prevents adding the total commission for a single order multiple times
-		// if the order was executed in several partial trades.
-		if tx.Commission > 0 && tx.OrderID != "" && !processedCommissions[tx.OrderID] {
-			var commissionEUR float64
+		// Case 2: Transaction taxes (stamp duty, FTT) charged on trades
+		if tx.TransactionType == "TAX" {
+			feeDetails = append(feeDetails, models.FeeDetail{
+				Date:        tx.Date,
+				Description: tx.ProductName,
+				AmountEUR:   utils.RoundAmount(tx.AmountEUR).Float64(),
+				Source:      tx.Source,
+				Category:    "Transaction Tax",
+			})
+		}
+
+		// Case 3: Commissions from Trades
+		// An order executed in several partial trades carries its share of the commission on each,
+		// so the commissions of all of them are added up.
+		if tx.Commission > 0 && tx.OrderID != "" {
+			var commissionEUR models.Money
 
 			// DEGIRO CSVs report commissions in EUR, even for foreign currency trades.
 			// IBKR reports commissions in the trade's currency.
@@ -42,7 +52,7 @@ func (p *feeProcessorImpl) Process(transactions []models.ProcessedTransaction) [
 				// For other brokers, we assume the commission is in the transaction's currency
 				// and needs to be converted using the provided exchange rate.
 				if tx.ExchangeRate > 0 {
-					commissionEUR = tx.Commission / tx.ExchangeRate
+					commissionEUR = tx.Commission.Convert(tx.ExchangeRate)
 				} else {
 					commissionEUR = tx.Commission // Fallback if rate is missing
 				}
@@ -50,12 +60,11 @@ func (p *feeProcessorImpl) Process(transactions []models.ProcessedTransaction) [
 
 			feeDetails = append(feeDetails, models.FeeDetail{
 				Date:        tx.Date,
-				Description: tx.ProductName,                      // Use the product name for context
-				AmountEUR:   utils.RoundFloat(-commissionEUR, 2), // Commissions are a cost (negative)
+				Description: tx.ProductName,                              // Use the product name for context
+				AmountEUR:   utils.RoundAmount(-commissionEUR).Float64(), // Commissions are a cost (negative)
 				Source:      tx.Source,
 				Category:    "Trade Commission",
 			})
-			processedCommissions[tx.OrderID] = true // Mark this OrderID as processed
 		}
 	}
 	return feeDetails
@@ -1,6 +1,8 @@
 package processors
 
 import (
+	"time"
+
 	"github.com/username/taxfolio/backend/src/models"
 )
 
@@ -11,26 +13,76 @@ type DividendResult map[string]map[string]map[string]float64
 // DividendProcessor defines the interface for calculating dividend results.
 type DividendProcessor interface {
 	Calculate(transactions []models.ProcessedTransaction) DividendResult // Deprecated: Use CalculateTaxSummary for tax-specific format
-	CalculateTaxSummary(transactions []models.ProcessedTransaction) models.DividendTaxResult
+	// CalculateTaxSummary aggregates dividends per tax year, under the given fiscal year, and country.
+	CalculateTaxSummary(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) models.DividendTaxResult
+	// CalculateDetail lists the transactions behind one tax year and country of the summary.
+	CalculateDetail(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear, year, country string) models.DividendDetail
 }
 
 // StockProcessor defines the interface for processing stock transactions.
 type StockProcessor interface {
 	// Process takes a full list of transactions and returns all derived data:
 	// 1. A complete list of all calculated sale details.
-	// 2. A map of open purchase lots, keyed by year, for historical views.
+	// 2. A map of open purchase lots, keyed by calendar year, for historical views.
 	Process(transactions []models.ProcessedTransaction) ([]models.SaleDetail, map[string][]models.PurchaseLot)
+	// ProcessWithState works like Process with holdings keyed by tax year under the given fiscal year,
+	// and also returns the FIFO state reached at the end.
+	ProcessWithState(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.SaleDetail, map[string][]models.PurchaseLot, *models.StockFIFOState)
+	// Resume continues from a saved state with transactions appended after it. It returns only the new
+	// sales and the holdings snapshots of the years it touched, or ErrOutOfOrderTransaction when a
+	// transaction is not dated after the state, in which case a full Process is required. The fiscal
+	// year must be the one the state was computed with.
+	Resume(state *models.StockFIFOState, newTransactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.SaleDetail, map[string][]models.PurchaseLot, *models.StockFIFOState, error)
+	// CostBasisAdjustments replays the transactions and returns the audit trail of the cost basis
+	// reductions made by return of capital distributions, in the order they were applied.
+	CostBasisAdjustments(transactions []models.ProcessedTransaction) []models.CostBasisAdjustment
+	// Timeline lists the trades and dividends of one ISIN in date order, each with the quantity and
+	// cost basis held after it.
+	Timeline(transactions []models.ProcessedTransaction, isin string) models.PositionTimeline
 }
 
 // OptionProcessor defines the interface for processing option transactions.
 type OptionProcessor interface {
-	Process(transactions []models.ProcessedTransaction) ([]models.OptionSaleDetail, []models.OptionHolding)
+	// Process matches option trades; each sale is labelled with its tax year under the given fiscal year.
+	Process(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.OptionSaleDetail, []models.OptionHolding)
+}
+
+// CoveredCallProcessor links short calls to the stock lots of the underlying held when they were written.
+type CoveredCallProcessor interface {
+	// Process sets CoveredLots on the short calls among the option processor's sales and holdings and
+	// returns every link, for annotating the stock lots.
+	Process(transactions []models.ProcessedTransaction, optionSales []models.OptionSaleDetail, optionHoldings []models.OptionHolding) []models.CoveredCall
 }
 
-// CashMovementProcessor defines the interface for processing cash deposits and withdrawals.
+// DeemedDisposalProcessor defines the interface for the 8-year deemed disposal rule on ETF holdings.
+type DeemedDisposalProcessor interface {
+	// Process returns the deemed disposals up to asOf, at purchase cost and without market values,
+	// each labelled with its tax year under the given fiscal year.
+	Process(transactions []models.ProcessedTransaction, asOf time.Time, fiscalYear models.FiscalYear) []models.DeemedDisposal
+}
+
+// BondProcessor defines the interface for the income of bonds: coupons, accrued interest and the
+// gains of sales and redemptions, each labelled with its tax year under the given fiscal year.
+type BondProcessor interface {
+	Process(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) models.BondIncomeReport
+}
+
+// CashBalanceProcessor defines the interface for rebuilding the running cash balance of each currency
+// at each broker, checked against the balances the statements reported.
+type CashBalanceProcessor interface {
+	Process(transactions []models.ProcessedTransaction, reported []models.BrokerBalance) models.CashBalanceReport
+}
+
+// CashMovementProcessor defines the interface for processing cash deposits, withdrawals and currency conversions.
 type CashMovementProcessor interface {
 	Process(transactions []models.ProcessedTransaction) []models.CashMovement
 }
+
+// ContributionProcessor defines the interface for the monthly deposits and withdrawals of cash, as
+// listed by a CashMovementProcessor, up to the month of now.
+type ContributionProcessor interface {
+	Process(movements []models.CashMovement, now time.Time) models.ContributionReport
+}
 type FeeProcessor interface {
 	Process(transactions []models.ProcessedTransaction) []models.FeeDetail
 }
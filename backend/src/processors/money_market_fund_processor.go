@@ -0,0 +1,30 @@
+package processors
+
+import "github.com/username/taxfolio/backend/src/models"
+
+// moneyMarketFundProcessor accounts for the rows of a broker's money-market fund, such as the one DeGiro
+// keeps uninvested cash in, while a cash balance is rebuilt. The broker reports the units of the fund as
+// cash, so converting cash into units or back moves none, while a change in the price of the units
+// changes the balance by its amount.
+type moneyMarketFundProcessor struct {
+	priceChanges map[cashSeriesKey]models.Money // Net price changes of each series
+}
+
+func newMoneyMarketFundProcessor() *moneyMarketFundProcessor {
+	return &moneyMarketFundProcessor{priceChanges: make(map[cashSeriesKey]models.Money)}
+}
+
+// cashChange returns the cash tx moves in the series key, and reports whether tx is a row of the fund.
+func (p *moneyMarketFundProcessor) cashChange(key cashSeriesKey, tx models.ProcessedTransaction) (models.Money, bool) {
+	if tx.TransactionType != "CASH" {
+		return 0, false
+	}
+	switch tx.TransactionSubType {
+	case models.MoneyMarketConversionSubType:
+		return 0, true
+	case models.MoneyMarketPriceChangeSubType:
+		p.priceChanges[key] += tx.Amount
+		return tx.Amount, true
+	}
+	return 0, false
+}
@@ -2,26 +2,71 @@ package processors
 
 import (
 	"log"
+	"regexp"
 	"sort"
 	"strings" // Ensure strings package is imported
+	"time"
 
 	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/utils" // Import the new utils package
 	// "time" // No longer needed directly if using utils.ParseDate
 )
 
+// ExpiredOptionOrderID is the CloseOrderID of option positions closed because they expired worthless.
+const ExpiredOptionOrderID = "EXPIRED"
+
+// optionExpiryRe finds the expiry date in option product names, e.g. "FLW P31.00 18MAR22" or "AAPL 17JUL20 100 C".
+var optionExpiryRe = regexp.MustCompile(`(?i)\b(\d{1,2}(?:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)\d{2})\b`)
+
 // optionProcessorImpl implements the OptionProcessor interface.
-type optionProcessorImpl struct{}
+type optionProcessorImpl struct {
+	now func() time.Time // Decides which positions have expired
+}
 
 // NewOptionProcessor creates a new instance of OptionProcessor.
 func NewOptionProcessor() OptionProcessor { // Return the interface type
-	return &optionProcessorImpl{} // Return the implementation struct
+	return &optionProcessorImpl{now: time.Now} // Return the implementation struct
+}
+
+// parseOptionExpiry extracts the expiry date from an option product name.
+func parseOptionExpiry(productName string) (time.Time, bool) {
+	match := optionExpiryRe.FindStringSubmatch(productName)
+	if match == nil {
+		return time.Time{}, false
+	}
+	expiry, err := time.Parse("2Jan06", match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry, true
+}
+
+// expirePositions closes positions still open after their expiry date at zero premium: the buyer loses
+// what was paid and the writer keeps what was received.
+func expirePositions(positions []*models.ProcessedTransaction, expiry time.Time, isLongPosition bool) []models.OptionSaleDetail {
+	var details []models.OptionSaleDetail
+	for _, pos := range positions {
+		closeTx := models.ProcessedTransaction{
+			Date:             expiry.Format("02-01-2006"),
+			ProductName:      pos.ProductName,
+			ISIN:             pos.ISIN,
+			Quantity:         pos.Quantity,
+			TransactionType:  pos.TransactionType,
+			Currency:         pos.Currency,
+			ExchangeRate:     pos.ExchangeRate,
+			ExchangeRateDate: pos.ExchangeRateDate,
+			OrderID:          ExpiredOptionOrderID,
+		}
+		details = append(details, createOptionSaleDetail(pos, &closeTx, pos.Quantity, isLongPosition))
+	}
+	return details
 }
 
 // Process implements the OptionProcessor interface.
 // It processes a list of transactions to identify and match option trades,
 // returning details of closed option trades and currently open option holdings.
-func (p *optionProcessorImpl) Process(transactions []models.ProcessedTransaction) ([]models.OptionSaleDetail, []models.OptionHolding) {
+// Each closed trade is labelled with the tax year of its close date under fiscalYear.
+func (p *optionProcessorImpl) Process(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.OptionSaleDetail, []models.OptionHolding) {
 	optionTransactions := filterOptionTransactions(transactions)
 	transactionsByProduct := groupTransactionsByProduct(optionTransactions)
 
@@ -109,6 +154,21 @@ func (p *optionProcessorImpl) Process(transactions []models.ProcessedTransaction
 			}
 		}
 
+		// Positions left open past the expiry date expired worthless; close them instead of keeping stale holdings.
+		if expiry, ok := parseOptionExpiry(txs[0].ProductName); ok {
+			now := p.now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			if expiry.Before(today) {
+				closedDetails = append(closedDetails, expirePositions(openLongPositions, expiry, true)...)
+				closedDetails = append(closedDetails, expirePositions(openShortPositions, expiry, false)...)
+				openLongPositions, openShortPositions = nil, nil
+			}
+		}
+
+		for i := range closedDetails {
+			closedDetails[i].TaxYear = fiscalYear.Label(utils.ParseDate(closedDetails[i].CloseDate))
+		}
+
 		// Add closed details for this product to the overall list
 		allOptionSaleDetails = append(allOptionSaleDetails, closedDetails...)
 
@@ -174,7 +234,7 @@ func sortTransactionsByDate(transactions []models.ProcessedTransaction) {
 // Creates an OptionSaleDetail from opening and closing transactions.
 // isLongPosition indicates if the openTx represented buying to open (long).
 func createOptionSaleDetail(openTx, closeTx *models.ProcessedTransaction, quantity int, isLongPosition bool) models.OptionSaleDetail {
-	var delta float64
+	var delta models.Money
 	// Ensure quantities are not zero before division
 	// Use OriginalQuantity for per-unit calculations of the opening leg
 	openOriginalQty := openTx.OriginalQuantity
@@ -195,61 +255,32 @@ func createOptionSaleDetail(openTx, closeTx *models.ProcessedTransaction, quanti
 		closeQty = 1
 	}
 
-	// Calculate amounts per unit for the matched quantity
-	openAmountPerUnit := 0.0
-	if openOriginalQty != 0 {
-		openAmountPerUnit = openTx.Amount / float64(openOriginalQty) // Use Original Qty
-	}
-	closeAmountPerUnit := 0.0
+	// Pro-rate the amounts to the matched quantity. Amounts are fixed point, so the pro-rated shares
+	// are exact to the millionth rather than built from rounded per-unit amounts.
+	matched := int64(quantity)
+	openAmountMatched := openTx.Amount.MulDiv(matched, int64(openOriginalQty)) // Use Original Qty
+	var closeAmountMatched models.Money
 	// Handle cases like exercise/assignment where Amount might be 0 but Price isn't necessarily
-	if closeTx.Amount != 0 && closeQty != 0 {
-		closeAmountPerUnit = closeTx.Amount / float64(closeQty)
+	if closeTx.Amount != 0 {
+		closeAmountMatched = closeTx.Amount.MulDiv(matched, int64(closeQty))
 	} else if closeTx.Price != 0 { // If amount is 0, use price as per-unit value
-		closeAmountPerUnit = closeTx.Price
-	}
-	// If both Amount and Price are 0 for closeTx, closeAmountPerUnit remains 0
-
-	// Calculate EUR amounts per unit for the matched quantity
-	openAmountEURPerUnit := 0.0
-	if openOriginalQty != 0 { // Use Original Qty
-		if openTx.ExchangeRate != 0 {
-			openAmountEURPerUnit = (openTx.Amount / float64(openOriginalQty)) / openTx.ExchangeRate
-		} else {
-			openAmountEURPerUnit = openAmountPerUnit // Assume 1:1 if rate is missing/zero
-		}
+		closeAmountMatched = closeTx.Price * models.Money(matched)
 	}
+	// If both Amount and Price are 0 for closeTx, closeAmountMatched remains 0
 
-	closeAmountEURPerUnit := 0.0
-	if closeQty != 0 { // Use closeQty for closing leg
-		if closeTx.ExchangeRate != 0 {
-			// Base EUR calculation on Amount if available, otherwise Price
-			if closeTx.Amount != 0 {
-				closeAmountEURPerUnit = (closeTx.Amount / float64(closeQty)) / closeTx.ExchangeRate
-			} else if closeTx.Price != 0 {
-				// Assume Price is in the original currency if Amount is 0
-				closeAmountEURPerUnit = closeTx.Price / closeTx.ExchangeRate
-			}
-		} else {
-			closeAmountEURPerUnit = closeAmountPerUnit // Assume 1:1 if rate is missing/zero
-		}
+	// Calculate EUR amounts for the matched quantity
+	openAmountEURMatched := openAmountMatched // Assume 1:1 if rate is missing/zero
+	if openTx.ExchangeRate != 0 {
+		openAmountEURMatched = openAmountMatched.Convert(openTx.ExchangeRate)
+	}
+	closeAmountEURMatched := closeAmountMatched // Assume 1:1 if rate is missing/zero
+	if closeTx.ExchangeRate != 0 {
+		// Assume Price is in the original currency if Amount is 0
+		closeAmountEURMatched = closeAmountMatched.Convert(closeTx.ExchangeRate)
 	}
-
-	// Calculate total amounts for the matched quantity
-	openAmountMatched := openAmountPerUnit * float64(quantity)
-	closeAmountMatched := closeAmountPerUnit * float64(quantity)
-	openAmountEURMatched := openAmountEURPerUnit * float64(quantity)
-	closeAmountEURMatched := closeAmountEURPerUnit * float64(quantity)
 
 	// Commission allocation (simple prorata based on quantity matched)
-	openCommissionPerUnit := 0.0
-	if openOriginalQty != 0 { // Use Original Qty
-		openCommissionPerUnit = openTx.Commission / float64(openOriginalQty)
-	}
-	closeCommissionPerUnit := 0.0
-	if closeQty != 0 { // Use closeQty for closing leg
-		closeCommissionPerUnit = closeTx.Commission / float64(closeQty)
-	}
-	totalCommissionMatched := (openCommissionPerUnit + closeCommissionPerUnit) * float64(quantity)
+	totalCommissionMatched := openTx.Commission.MulDiv(matched, int64(openOriginalQty)) + closeTx.Commission.MulDiv(matched, int64(closeQty))
 
 	delta = openAmountEURMatched + closeAmountEURMatched
 
@@ -258,16 +289,16 @@ func createOptionSaleDetail(openTx, closeTx *models.ProcessedTransaction, quanti
 		CloseDate:      closeTx.Date,
 		ProductName:    openTx.ProductName, // Should be the same
 		Quantity:       quantity,
-		OpenPrice:      openTx.Price,
-		OpenAmount:     openAmountMatched, // Matched portion
+		OpenPrice:      openTx.Price.Float64(),
+		OpenAmount:     openAmountMatched.Float64(), // Matched portion
 		OpenCurrency:   openTx.Currency,
-		OpenAmountEUR:  openAmountEURMatched, // Matched portion
-		ClosePrice:     closeTx.Price,
-		CloseAmount:    closeAmountMatched, // Matched portion
+		OpenAmountEUR:  openAmountEURMatched.Float64(), // Matched portion
+		ClosePrice:     closeTx.Price.Float64(),
+		CloseAmount:    closeAmountMatched.Float64(), // Matched portion
 		CloseCurrency:  closeTx.Currency,
-		CloseAmountEUR: closeAmountEURMatched,  // Matched portion
-		Commission:     totalCommissionMatched, // Matched portion
-		Delta:          delta,
+		CloseAmountEUR: closeAmountEURMatched.Float64(),  // Matched portion
+		Commission:     totalCommissionMatched.Float64(), // Matched portion
+		Delta:          delta.Float64(),
 		OpenOrderID:    openTx.OrderID,
 		CloseOrderID:   closeTx.OrderID,
 		CountryCode:    utils.GetCountryCodeString(openTx.ISIN), // Add country code using the utility function
@@ -286,10 +317,10 @@ func createOptionHolding(tx *models.ProcessedTransaction, quantity int) models.O
 		OpenDate:      tx.Date,
 		ProductName:   tx.ProductName,
 		Quantity:      quantity, // Signed quantity (+long, -short)
-		OpenPrice:     tx.Price,
-		OpenAmount:    (tx.Amount / float64(originalQty)) * float64(utils.AbsInt(quantity)), // Use utils.AbsInt
+		OpenPrice:     tx.Price.Float64(),
+		OpenAmount:    tx.Amount.MulDiv(int64(utils.AbsInt(quantity)), int64(originalQty)).Float64(), // Use utils.AbsInt
 		OpenCurrency:  tx.Currency,
-		OpenAmountEUR: (tx.AmountEUR / float64(originalQty)) * float64(utils.AbsInt(quantity)), // Use utils.AbsInt
+		OpenAmountEUR: tx.AmountEUR.MulDiv(int64(utils.AbsInt(quantity)), int64(originalQty)).Float64(), // Use utils.AbsInt
 		OpenOrderID:   tx.OrderID,
 	}
 }
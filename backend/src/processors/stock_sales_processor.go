@@ -1,6 +1,7 @@
 package processors
 
 import (
+	"errors"
 	"sort"
 	"strconv"
 
@@ -14,104 +15,594 @@ func NewStockProcessor() StockProcessor {
 	return &stockProcessorImpl{}
 }
 
+// ErrOutOfOrderTransaction is returned by Resume when a new transaction is not dated after the saved state.
+var ErrOutOfOrderTransaction = errors.New("transaction dated on or before the saved FIFO state")
+
 // Process implements the StockProcessor interface.
 // This is the restored, correct logic that processes the entire transaction list in one pass.
 func (p *stockProcessorImpl) Process(transactions []models.ProcessedTransaction) ([]models.SaleDetail, map[string][]models.PurchaseLot) {
-	stockTransactions := filterAndSortStockTransactions(transactions)
-	if len(stockTransactions) == 0 {
-		return []models.SaleDetail{}, make(map[string][]models.PurchaseLot)
+	saleDetails, holdingsByYear, _ := p.ProcessWithState(transactions, models.CalendarYear)
+	return saleDetails, holdingsByYear
+}
+
+// ProcessWithState implements the StockProcessor interface.
+func (p *stockProcessorImpl) ProcessWithState(transactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.SaleDetail, map[string][]models.PurchaseLot, *models.StockFIFOState) {
+	matcher := newFIFOMatcher(nil, collectTransactionTaxes(transactions), fiscalYear)
+	for _, tx := range filterAndSortStockTransactions(transactions) {
+		matcher.apply(tx)
 	}
-	return calculateSalesAndYearlyHoldings(stockTransactions)
+	return matcher.finish()
 }
 
-// calculateSalesAndYearlyHoldings contains the original, correct FIFO and snapshot logic.
-func calculateSalesAndYearlyHoldings(transactions []models.ProcessedTransaction) ([]models.SaleDetail, map[string][]models.PurchaseLot) {
-	saleDetails := []models.SaleDetail{}
-	holdingsByYear := make(map[string][]models.PurchaseLot)
-	openPurchasesByISIN := make(map[string][]*models.ProcessedTransaction)
+// CostBasisAdjustments implements the StockProcessor interface.
+func (p *stockProcessorImpl) CostBasisAdjustments(transactions []models.ProcessedTransaction) []models.CostBasisAdjustment {
+	matcher := newFIFOMatcher(nil, collectTransactionTaxes(transactions), models.CalendarYear)
+	for _, tx := range filterAndSortStockTransactions(transactions) {
+		matcher.apply(tx)
+	}
+	return matcher.adjustments
+}
 
-	if len(transactions) == 0 {
-		return saleDetails, holdingsByYear
+// Timeline implements the StockProcessor interface. The trades of the ISIN are replayed through the
+// FIFO matching so the running cost is that of the holdings report; dividends only add events.
+func (p *stockProcessorImpl) Timeline(transactions []models.ProcessedTransaction, isin string) models.PositionTimeline {
+	timeline := models.PositionTimeline{ISIN: isin, Events: []models.PositionEvent{}}
+	var trades, dividends []models.ProcessedTransaction
+	for _, tx := range transactions {
+		if tx.ISIN != isin {
+			continue
+		}
+		if tx.TransactionType == "DIVIDEND" {
+			dividends = append(dividends, tx)
+		} else {
+			trades = append(trades, tx)
+		}
 	}
+	// Trades keep the FIFO order; a dividend comes after the trades of its day.
+	ordered := append(filterAndSortStockTransactions(trades), dividends...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return utils.ParseDate(ordered[i].Date).Before(utils.ParseDate(ordered[j].Date))
+	})
 
-	lastProcessedYear := utils.ParseDate(transactions[0].Date).Year()
+	matcher := newFIFOMatcher(nil, collectTransactionTaxes(transactions), models.CalendarYear)
+	for _, tx := range ordered {
+		event := models.PositionEvent{
+			TransactionID: tx.ID,
+			Date:          tx.Date,
+			Type:          tx.TransactionType,
+			Price:         tx.Price.Float64(),
+			Amount:        tx.Amount.Float64(),
+			Currency:      tx.Currency,
+			AmountEUR:     utils.RoundAmount(tx.AmountEUR).Float64(),
+			Commission:    tx.Commission.Float64(),
+		}
+		switch {
+		case tx.TransactionType == "STOCK":
+			event.Type, event.Quantity = tx.BuySell, tx.Quantity
+		case tx.TransactionType == "SCRIP_DIVIDEND":
+			event.Quantity = tx.Quantity
+			timeline.DividendsEUR = utils.RoundMoney(timeline.DividendsEUR + event.AmountEUR)
+		case tx.TransactionSubType == "TAX":
+			event.Type = "WITHHOLDING_TAX"
+			timeline.WithheldEUR = utils.RoundMoney(timeline.WithheldEUR + event.AmountEUR)
+		case tx.TransactionType == "DIVIDEND":
+			timeline.DividendsEUR = utils.RoundMoney(timeline.DividendsEUR + event.AmountEUR)
+		}
 
-	for _, tx := range transactions {
-		txDate := utils.ParseDate(tx.Date)
-		currentYear := txDate.Year()
-
-		// If the year changes, take a snapshot of the current holdings for the previous year(s).
-		if currentYear > lastProcessedYear {
-			snapshot := collectAndCopyHoldings(openPurchasesByISIN)
-			for year := lastProcessedYear; year < currentYear; year++ {
-				holdingsByYear[strconv.Itoa(year)] = snapshot
-			}
+		matched := len(matcher.saleDetails)
+		matcher.apply(tx)
+		realized := models.Money(0)
+		for _, sale := range matcher.saleDetails[matched:] {
+			realized += sale.Delta
 		}
+		event.RealizedGainEUR = realized.Float64()
+		timeline.RealizedGainEUR = utils.RoundMoney(timeline.RealizedGainEUR + event.RealizedGainEUR)
+		event.RunningQuantity, event.RunningCostEUR = matcher.position(isin)
 
-		// Process the current transaction (buy or sell).
-		if tx.TransactionType == "STOCK" && tx.BuySell == "BUY" {
-			purchaseCopy := tx
-			openPurchasesByISIN[tx.ISIN] = append(openPurchasesByISIN[tx.ISIN], &purchaseCopy)
-		} else if tx.TransactionType == "STOCK" && tx.BuySell == "SELL" {
-			remainingQty := tx.Quantity
-			purchaseLots := openPurchasesByISIN[tx.ISIN]
-
-			for remainingQty > 0 && len(purchaseLots) > 0 {
-				currentPurchase := purchaseLots[0]
-				matchedQty := utils.MinInt(remainingQty, currentPurchase.Quantity)
-
-				saleRatio := float64(matchedQty) / float64(tx.Quantity)
-				var purchaseRatio float64
-				if currentPurchase.OriginalQuantity > 0 {
-					purchaseRatio = float64(matchedQty) / float64(currentPurchase.OriginalQuantity)
-				}
-				buyCommissionToAdd := 0.0
-				if currentPurchase.Commission > 0 {
-					buyCommissionToAdd = currentPurchase.Commission
-					currentPurchase.Commission = 0
-				}
-				totalDetailCommission := (tx.Commission * saleRatio) + buyCommissionToAdd
-				buyAmountEUR := utils.RoundFloat(currentPurchase.AmountEUR*purchaseRatio, 2)
-				saleAmountEUR := utils.RoundFloat(tx.AmountEUR*saleRatio, 2)
-
-				saleDetails = append(saleDetails, models.SaleDetail{
-					SaleDate:         tx.Date,
-					BuyDate:          currentPurchase.Date,
-					ProductName:      tx.ProductName,
-					ISIN:             tx.ISIN,
-					Quantity:         matchedQty,
-					SaleAmount:       tx.Amount * saleRatio,
-					SaleCurrency:     tx.Currency,
-					SaleAmountEUR:    saleAmountEUR,
-					SalePrice:        tx.Price,
-					SaleExchangeRate: tx.ExchangeRate,
-					BuyAmount:        currentPurchase.Amount * purchaseRatio,
-					BuyCurrency:      currentPurchase.Currency,
-					BuyAmountEUR:     buyAmountEUR,
-					BuyPrice:         currentPurchase.Price,
-					BuyExchangeRate:  currentPurchase.ExchangeRate,
-					Commission:       utils.RoundFloat(totalDetailCommission, 2),
-					Delta:            utils.RoundFloat(buyAmountEUR+saleAmountEUR, 2),
-					CountryCode:      utils.GetCountryCodeString(tx.ISIN),
-				})
+		if tx.ProductName != "" {
+			timeline.ProductName = tx.ProductName
+		}
+		timeline.Events = append(timeline.Events, event)
+	}
+	timeline.Quantity, timeline.CostEUR = matcher.position(isin)
+	return timeline
+}
+
+// position returns the shares of the ISIN held, net of short positions, and the cost basis of its open
+// purchase lots.
+func (m *fifoMatcher) position(isin string) (int, float64) {
+	quantity, cost := 0, models.Money(0)
+	for _, lot := range m.openPurchasesByISIN[isin] {
+		quantity += lot.Quantity
+		cost += lotShareCents(lot.AmountEUR, lot, lot.Quantity).Abs()
+	}
+	for _, lot := range m.openShortsByISIN[isin] {
+		quantity -= lot.Quantity
+	}
+	return quantity, cost.Float64()
+}
 
-				remainingQty -= matchedQty
-				currentPurchase.Quantity -= matchedQty
-				if currentPurchase.Quantity == 0 {
-					purchaseLots = purchaseLots[1:]
-				}
-				openPurchasesByISIN[tx.ISIN] = purchaseLots
+// Resume implements the StockProcessor interface.
+// Transactions on the same day as the saved state are rejected too, because the same-day ordering
+// (buys before sells, then by order ID) may place them before already matched sales. New opening
+// balances are rejected as well: they jump ahead of the saved lots, so the history has to be replayed.
+func (p *stockProcessorImpl) Resume(state *models.StockFIFOState, newTransactions []models.ProcessedTransaction, fiscalYear models.FiscalYear) ([]models.SaleDetail, map[string][]models.PurchaseLot, *models.StockFIFOState, error) {
+	for _, tx := range newTransactions {
+		if isOpeningLot(&tx) {
+			return nil, nil, nil, ErrOutOfOrderTransaction
+		}
+	}
+	if state.LastDate != "" {
+		lastDate := utils.ParseDate(state.LastDate)
+		for _, tx := range newTransactions {
+			if isFIFORelevant(tx) && !utils.ParseDate(tx.Date).After(lastDate) {
+				return nil, nil, nil, ErrOutOfOrderTransaction
 			}
 		}
+	}
 
-		lastProcessedYear = currentYear
+	matcher := newFIFOMatcher(state, collectTransactionTaxes(newTransactions), fiscalYear)
+	for _, tx := range filterAndSortStockTransactions(newTransactions) {
+		matcher.apply(tx)
 	}
+	saleDetails, holdingsByYear, newState := matcher.finish()
+	return saleDetails, holdingsByYear, newState, nil
+}
 
-	// Take the final snapshot for the very last year processed.
-	finalSnapshot := collectAndCopyHoldings(openPurchasesByISIN)
-	holdingsByYear[strconv.Itoa(lastProcessedYear)] = finalSnapshot
+// isFIFORelevant reports whether a transaction affects the FIFO matching.
+func isFIFORelevant(tx models.ProcessedTransaction) bool {
+	return tx.TransactionType == "STOCK" || tx.TransactionType == "SCRIP_DIVIDEND" || tx.TransactionType == "RETURN_OF_CAPITAL" || tx.TransactionType == "TAX"
+}
 
-	return saleDetails, holdingsByYear
+// transactionTaxes holds the stamp duty / FTT in EUR charged per order, together with the
+// quantity traded in that order so the tax can be split across partial fills.
+type transactionTaxes struct {
+	amountEUR map[string]models.Money
+	quantity  map[string]int
+}
+
+// collectTransactionTaxes sums the transaction taxes per trade. Taxes are keyed by OrderID,
+// or by ISIN and date when the broker does not link them to an order.
+func collectTransactionTaxes(transactions []models.ProcessedTransaction) transactionTaxes {
+	taxes := transactionTaxes{amountEUR: make(map[string]models.Money), quantity: make(map[string]int)}
+	for _, tx := range transactions {
+		switch tx.TransactionType {
+		case "TAX":
+			taxes.amountEUR[transactionTaxKey(tx)] += tx.AmountEUR.Abs()
+		case "STOCK":
+			taxes.quantity[transactionTaxKey(tx)] += tx.Quantity
+		}
+	}
+	return taxes
+}
+
+// forTrade returns the share of its order's transaction tax that applies to a single fill.
+func (t transactionTaxes) forTrade(tx models.ProcessedTransaction) models.Money {
+	key := transactionTaxKey(tx)
+	tax, orderQty := t.amountEUR[key], t.quantity[key]
+	if tax == 0 || orderQty <= 0 {
+		return 0
+	}
+	return tax.MulDiv(int64(tx.Quantity), int64(orderQty))
+}
+
+func transactionTaxKey(tx models.ProcessedTransaction) string {
+	if tx.OrderID != "" {
+		return tx.OrderID
+	}
+	return tx.ISIN + "|" + tx.Date
+}
+
+// positionKey returns the key the lots of a trade are matched under: its ISIN, or its broker and product
+// when the broker exports no ISIN, so that instruments without one are not matched against each other.
+func positionKey(tx models.ProcessedTransaction) string {
+	if tx.ISIN != "" {
+		return tx.ISIN
+	}
+	return tx.Source + "|" + tx.ProductName
+}
+
+// fifoMatcher holds the FIFO and snapshot state while transactions are applied in date order.
+type fifoMatcher struct {
+	taxes               transactionTaxes
+	saleDetails         []models.SaleDetail
+	holdingsByYear      map[string][]models.PurchaseLot
+	openPurchasesByISIN map[string][]*models.ProcessedTransaction
+	// Short positions: the part of a sale not covered by open purchases, closed by later buys.
+	openShortsByISIN map[string][]*models.ProcessedTransaction
+	// Transaction tax paid on each open lot, added to the first match against it (like the commission).
+	buyTaxes          map[*models.ProcessedTransaction]models.Money
+	adjustments       []models.CostBasisAdjustment
+	fiscalYear        models.FiscalYear // Holdings are snapshotted at the end of each tax year
+	lastProcessedYear int
+	lastDate          string
+}
+
+// newFIFOMatcher creates a matcher, optionally starting from a saved state.
+func newFIFOMatcher(state *models.StockFIFOState, taxes transactionTaxes, fiscalYear models.FiscalYear) *fifoMatcher {
+	m := &fifoMatcher{
+		taxes:               taxes,
+		fiscalYear:          fiscalYear,
+		saleDetails:         []models.SaleDetail{},
+		holdingsByYear:      make(map[string][]models.PurchaseLot),
+		openPurchasesByISIN: make(map[string][]*models.ProcessedTransaction),
+		openShortsByISIN:    make(map[string][]*models.ProcessedTransaction),
+		buyTaxes:            make(map[*models.ProcessedTransaction]models.Money),
+	}
+	if state == nil {
+		return m
+	}
+	m.lastProcessedYear = state.LastYear
+	m.lastDate = state.LastDate
+	for isin, lots := range state.OpenLots {
+		for _, lot := range lots {
+			m.openPurchasesByISIN[isin] = append(m.openPurchasesByISIN[isin], m.restoreLot(lot))
+		}
+	}
+	for isin, lots := range state.ShortLots {
+		for _, lot := range lots {
+			m.openShortsByISIN[isin] = append(m.openShortsByISIN[isin], m.restoreLot(lot))
+		}
+	}
+	return m
+}
+
+// restoreLot rebuilds an open lot of a saved state, together with its pending transaction tax.
+func (m *fifoMatcher) restoreLot(lot models.OpenLot) *models.ProcessedTransaction {
+	restored := &models.ProcessedTransaction{
+		Date:             lot.Date,
+		ProductName:      lot.ProductName,
+		ISIN:             lot.ISIN,
+		Quantity:         lot.Quantity,
+		OriginalQuantity: lot.OriginalQuantity,
+		Price:            lot.Price,
+		Amount:           lot.Amount,
+		AmountEUR:        lot.AmountEUR,
+		Currency:         lot.Currency,
+		ExchangeRate:     lot.ExchangeRate,
+		ExchangeRateDate: lot.ExchangeRateDate,
+		Commission:       lot.Commission,
+	}
+	if lot.TransactionTax != 0 {
+		m.buyTaxes[restored] = lot.TransactionTax
+	}
+	return restored
+}
+
+// apply processes one buy, scrip dividend or sell.
+func (m *fifoMatcher) apply(tx models.ProcessedTransaction) {
+	txDate := utils.ParseDate(tx.Date)
+	currentYear := m.fiscalYear.YearOf(txDate)
+	if m.lastProcessedYear == 0 {
+		m.lastProcessedYear = currentYear
+	}
+
+	// If the year changes, take a snapshot of the current holdings for the previous year(s).
+	if currentYear > m.lastProcessedYear {
+		snapshot := collectAndCopyHoldings(m.openPurchasesByISIN)
+		for year := m.lastProcessedYear; year < currentYear; year++ {
+			m.holdingsByYear[strconv.Itoa(year)] = snapshot
+		}
+	}
+
+	// Process the current transaction (buy or sell).
+	if tx.TransactionType == "STOCK" && tx.BuySell == "BUY" {
+		m.matchPurchase(tx)
+	} else if tx.TransactionType == "SCRIP_DIVIDEND" {
+		// Shares received as a dividend open a new lot whose cost basis is the taxable dividend value.
+		// The dividend amount is income (positive), so flip the sign to match a purchase.
+		purchaseCopy := tx
+		purchaseCopy.Amount = -tx.Amount.Abs()
+		purchaseCopy.AmountEUR = -tx.AmountEUR.Abs()
+		key := positionKey(tx)
+		m.openPurchasesByISIN[key] = append(m.openPurchasesByISIN[key], &purchaseCopy)
+	} else if tx.TransactionType == "RETURN_OF_CAPITAL" {
+		m.applyReturnOfCapital(tx)
+	} else if tx.TransactionType == "STOCK" && tx.BuySell == "SELL" {
+		m.matchSale(tx)
+	}
+
+	m.lastProcessedYear = currentYear
+	m.lastDate = tx.Date
+}
+
+// applyReturnOfCapital spreads a return of capital over the open lots of the ISIN by quantity and lowers
+// their cost basis, never below zero. Each lot adjusted leaves a CostBasisAdjustment for the audit trail.
+func (m *fifoMatcher) applyReturnOfCapital(tx models.ProcessedTransaction) {
+	distribution := tx.AmountEUR.Abs()
+	if distribution == 0 {
+		return
+	}
+	lots := m.openPurchasesByISIN[positionKey(tx)]
+	totalQty := 0
+	for _, lot := range lots {
+		totalQty += lot.Quantity
+	}
+	if totalQty <= 0 {
+		m.adjustments = append(m.adjustments, models.CostBasisAdjustment{
+			Date:        tx.Date,
+			ISIN:        tx.ISIN,
+			ProductName: tx.ProductName,
+			AmountEUR:   utils.RoundAmount(distribution).Float64(),
+			ExcessEUR:   utils.RoundAmount(distribution).Float64(),
+		})
+		return
+	}
+
+	allocatedQty := 0
+	for _, lot := range lots {
+		if lot.Quantity <= 0 || lot.OriginalQuantity <= 0 {
+			continue
+		}
+		// The lots' allocations add up to the distribution exactly.
+		allocated := distribution.Allocate(int64(allocatedQty), int64(lot.Quantity), int64(totalQty))
+		allocatedQty += lot.Quantity
+		costBefore := remainingCost(lot.AmountEUR, lot).Abs()
+		reduction := min(allocated, costBefore)
+		// Amounts are pro-rated over OriginalQuantity, so scaling them scales the cost of the open shares.
+		if reduction < costBefore {
+			lot.Amount = lot.Amount.MulDiv(int64(costBefore-reduction), int64(costBefore))
+			lot.AmountEUR = lot.AmountEUR.MulDiv(int64(costBefore-reduction), int64(costBefore))
+		} else {
+			lot.Amount, lot.AmountEUR = 0, 0
+		}
+
+		m.adjustments = append(m.adjustments, models.CostBasisAdjustment{
+			Date:          tx.Date,
+			ISIN:          tx.ISIN,
+			ProductName:   lot.ProductName,
+			BuyDate:       lot.Date,
+			Quantity:      lot.Quantity,
+			AmountEUR:     utils.RoundAmount(allocated).Float64(),
+			CostBeforeEUR: utils.RoundAmount(costBefore).Float64(),
+			CostAfterEUR:  utils.RoundAmount(costBefore - reduction).Float64(),
+			ExcessEUR:     utils.RoundAmount(allocated - reduction).Float64(),
+		})
+	}
+}
+
+// matchSale consumes the oldest open lots of the ISIN and records one SaleDetail per lot matched.
+func (m *fifoMatcher) matchSale(tx models.ProcessedTransaction) {
+	key := positionKey(tx)
+	country, _ := countryOf(tx)
+	remainingQty := tx.Quantity
+	purchaseLots := m.openPurchasesByISIN[key]
+	saleTax := m.taxes.forTrade(tx)
+
+	for remainingQty > 0 && len(purchaseLots) > 0 {
+		currentPurchase := purchaseLots[0]
+		matchedQty := utils.MinInt(remainingQty, currentPurchase.Quantity)
+		soldQty := tx.Quantity - remainingQty // Shares of the sale matched against earlier lots
+
+		buyCommissionToAdd := models.Money(0)
+		if currentPurchase.Commission > 0 {
+			buyCommissionToAdd = currentPurchase.Commission
+			currentPurchase.Commission = 0
+		}
+		totalDetailCommission := share(tx.Commission, soldQty, matchedQty, tx.Quantity) + buyCommissionToAdd
+		totalDetailTax := share(saleTax, soldQty, matchedQty, tx.Quantity) + m.buyTaxes[currentPurchase]
+		delete(m.buyTaxes, currentPurchase)
+		buyAmountEUR := lotShareCents(currentPurchase.AmountEUR, currentPurchase, matchedQty)
+		saleAmountEUR := shareCents(tx.AmountEUR, soldQty, matchedQty, tx.Quantity)
+
+		m.saleDetails = append(m.saleDetails, models.SaleDetail{
+			SaleDate:         tx.Date,
+			BuyDate:          currentPurchase.Date,
+			ProductName:      tx.ProductName,
+			ISIN:             tx.ISIN,
+			Quantity:         matchedQty,
+			SaleAmount:       share(tx.Amount, soldQty, matchedQty, tx.Quantity),
+			SaleCurrency:     tx.Currency,
+			SaleAmountEUR:    saleAmountEUR,
+			SalePrice:        tx.Price,
+			SaleExchangeRate: tx.ExchangeRate,
+			BuyAmount:        lotShare(currentPurchase.Amount, currentPurchase, matchedQty),
+			BuyCurrency:      currentPurchase.Currency,
+			BuyAmountEUR:     buyAmountEUR,
+			BuyPrice:         currentPurchase.Price,
+			BuyExchangeRate:  currentPurchase.ExchangeRate,
+			Commission:       utils.RoundAmount(totalDetailCommission),
+			TransactionTax:   utils.RoundAmount(totalDetailTax),
+			Delta:            buyAmountEUR + saleAmountEUR,
+			CountryCode:      country,
+			AssetClass:       tx.AssetClass,
+			TaxYear:          m.fiscalYear.Label(utils.ParseDate(tx.Date)),
+
+			BuyExchangeRateDate:  currentPurchase.ExchangeRateDate,
+			SaleExchangeRateDate: tx.ExchangeRateDate,
+		})
+
+		remainingQty -= matchedQty
+		currentPurchase.Quantity -= matchedQty
+		if currentPurchase.Quantity == 0 {
+			purchaseLots = purchaseLots[1:]
+		}
+		m.openPurchasesByISIN[key] = purchaseLots
+	}
+
+	// Shares sold beyond the open purchases open a short position, carrying their share of the sale costs.
+	if remainingQty > 0 {
+		soldQty := tx.Quantity - remainingQty
+		shortCopy := tx
+		shortCopy.Quantity = remainingQty
+		shortCopy.OriginalQuantity = tx.Quantity
+		shortCopy.Commission = share(tx.Commission, soldQty, remainingQty, tx.Quantity)
+		if saleTax != 0 {
+			m.buyTaxes[&shortCopy] = share(saleTax, soldQty, remainingQty, tx.Quantity)
+		}
+		m.openShortsByISIN[key] = append(m.openShortsByISIN[key], &shortCopy)
+	}
+}
+
+// matchPurchase first covers the oldest open short positions of the ISIN, recording one SaleDetail
+// per short lot closed, and opens a purchase lot with the shares left over.
+func (m *fifoMatcher) matchPurchase(tx models.ProcessedTransaction) {
+	key := positionKey(tx)
+	country, _ := countryOf(tx)
+	remainingQty := tx.Quantity
+	shortLots := m.openShortsByISIN[key]
+	buyTax := m.taxes.forTrade(tx)
+
+	for remainingQty > 0 && len(shortLots) > 0 {
+		currentShort := shortLots[0]
+		matchedQty := utils.MinInt(remainingQty, currentShort.Quantity)
+		boughtQty := tx.Quantity - remainingQty // Shares of the purchase that covered earlier shorts
+
+		saleCommissionToAdd := currentShort.Commission
+		currentShort.Commission = 0
+		totalDetailCommission := share(tx.Commission, boughtQty, matchedQty, tx.Quantity) + saleCommissionToAdd
+		totalDetailTax := share(buyTax, boughtQty, matchedQty, tx.Quantity) + m.buyTaxes[currentShort]
+		delete(m.buyTaxes, currentShort)
+		buyAmountEUR := shareCents(tx.AmountEUR, boughtQty, matchedQty, tx.Quantity)
+		saleAmountEUR := lotShareCents(currentShort.AmountEUR, currentShort, matchedQty)
+
+		// The sale opened the position and the purchase closes it, so BuyDate falls after SaleDate.
+		m.saleDetails = append(m.saleDetails, models.SaleDetail{
+			SaleDate:         currentShort.Date,
+			BuyDate:          tx.Date,
+			ProductName:      currentShort.ProductName,
+			ISIN:             tx.ISIN,
+			Quantity:         matchedQty,
+			SaleAmount:       lotShare(currentShort.Amount, currentShort, matchedQty),
+			SaleCurrency:     currentShort.Currency,
+			SaleAmountEUR:    saleAmountEUR,
+			SalePrice:        currentShort.Price,
+			SaleExchangeRate: currentShort.ExchangeRate,
+			BuyAmount:        share(tx.Amount, boughtQty, matchedQty, tx.Quantity),
+			BuyCurrency:      tx.Currency,
+			BuyAmountEUR:     buyAmountEUR,
+			BuyPrice:         tx.Price,
+			BuyExchangeRate:  tx.ExchangeRate,
+			Commission:       utils.RoundAmount(totalDetailCommission),
+			TransactionTax:   utils.RoundAmount(totalDetailTax),
+			Delta:            buyAmountEUR + saleAmountEUR,
+			CountryCode:      country,
+			AssetClass:       tx.AssetClass,
+			TaxYear:          m.fiscalYear.Label(utils.ParseDate(tx.Date)),
+
+			BuyExchangeRateDate:  tx.ExchangeRateDate,
+			SaleExchangeRateDate: currentShort.ExchangeRateDate,
+		})
+
+		remainingQty -= matchedQty
+		currentShort.Quantity -= matchedQty
+		if currentShort.Quantity == 0 {
+			shortLots = shortLots[1:]
+		}
+		m.openShortsByISIN[key] = shortLots
+	}
+
+	if remainingQty == 0 {
+		return
+	}
+	boughtQty := tx.Quantity - remainingQty
+	purchaseCopy := tx
+	if remainingQty < tx.Quantity {
+		purchaseCopy.Quantity = remainingQty
+		purchaseCopy.OriginalQuantity = tx.Quantity
+		purchaseCopy.Commission = share(tx.Commission, boughtQty, remainingQty, tx.Quantity)
+	}
+	m.buyTaxes[&purchaseCopy] = share(buyTax, boughtQty, remainingQty, tx.Quantity)
+	m.openPurchasesByISIN[key] = insertLot(m.openPurchasesByISIN[key], &purchaseCopy)
+}
+
+// share returns the part of an amount carried by the shares from done to done+part of a trade of total
+// shares. The parts of an amount add up to it exactly, however the trade is split.
+func share(amount models.Money, done, part, total int) models.Money {
+	return amount.Allocate(int64(done), int64(part), int64(total))
+}
+
+// shareCents is share in whole cents. The parts are differences of rounded running totals, so they add
+// up to the amount rounded, with no cent lost to rounding each part on its own.
+func shareCents(amount models.Money, done, part, total int) models.Money {
+	return utils.RoundAmount(amount.MulDiv(int64(done+part), int64(total))) - utils.RoundAmount(amount.MulDiv(int64(done), int64(total)))
+}
+
+// lotShare returns the part of a lot amount, such as its cost, carried by the next shares taken from it.
+// Lot amounts are for OriginalQuantity shares, of which OriginalQuantity - Quantity were taken already.
+func lotShare(amount models.Money, lot *models.ProcessedTransaction, shares int) models.Money {
+	if lot.OriginalQuantity <= 0 {
+		return 0
+	}
+	return share(amount, lot.OriginalQuantity-lot.Quantity, shares, lot.OriginalQuantity)
+}
+
+// lotShareCents is lotShare in whole cents, see shareCents.
+func lotShareCents(amount models.Money, lot *models.ProcessedTransaction, shares int) models.Money {
+	if lot.OriginalQuantity <= 0 {
+		return 0
+	}
+	return shareCents(amount, lot.OriginalQuantity-lot.Quantity, shares, lot.OriginalQuantity)
+}
+
+// remainingCost returns the part of a lot amount carried by its shares still open.
+func remainingCost(amount models.Money, lot *models.ProcessedTransaction) models.Money {
+	return lotShare(amount, lot, lot.Quantity)
+}
+
+// insertLot queues a purchase lot. Opening balances entered by the user are the oldest lots, so they
+// go ahead of regular purchases (after earlier opening balances) and are matched first.
+func insertLot(lots []*models.ProcessedTransaction, lot *models.ProcessedTransaction) []*models.ProcessedTransaction {
+	if !isOpeningLot(lot) {
+		return append(lots, lot)
+	}
+	pos := 0
+	for pos < len(lots) && isOpeningLot(lots[pos]) {
+		pos++
+	}
+	lots = append(lots, nil)
+	copy(lots[pos+1:], lots[pos:])
+	lots[pos] = lot
+	return lots
+}
+
+func isOpeningLot(lot *models.ProcessedTransaction) bool {
+	return lot.TransactionSubType == models.OpeningBalanceSubType
+}
+
+// finish takes the snapshot for the last year processed and exports the state reached.
+func (m *fifoMatcher) finish() ([]models.SaleDetail, map[string][]models.PurchaseLot, *models.StockFIFOState) {
+	state := &models.StockFIFOState{
+		LastDate:  m.lastDate,
+		LastYear:  m.lastProcessedYear,
+		OpenLots:  make(map[string][]models.OpenLot),
+		ShortLots: make(map[string][]models.OpenLot),
+	}
+	if m.lastProcessedYear == 0 {
+		return m.saleDetails, m.holdingsByYear, state
+	}
+
+	m.holdingsByYear[strconv.Itoa(m.lastProcessedYear)] = collectAndCopyHoldings(m.openPurchasesByISIN)
+
+	m.exportLots(m.openPurchasesByISIN, state.OpenLots)
+	m.exportLots(m.openShortsByISIN, state.ShortLots)
+	return m.saleDetails, m.holdingsByYear, state
+}
+
+// exportLots copies the lots still open into the saved state.
+func (m *fifoMatcher) exportLots(lotsByISIN map[string][]*models.ProcessedTransaction, dst map[string][]models.OpenLot) {
+	for isin, lots := range lotsByISIN {
+		for _, lot := range lots {
+			if lot.Quantity <= 0 {
+				continue
+			}
+			dst[isin] = append(dst[isin], models.OpenLot{
+				Date:             lot.Date,
+				ProductName:      lot.ProductName,
+				ISIN:             lot.ISIN,
+				Quantity:         lot.Quantity,
+				OriginalQuantity: lot.OriginalQuantity,
+				Price:            lot.Price,
+				Amount:           lot.Amount,
+				AmountEUR:        lot.AmountEUR,
+				Currency:         lot.Currency,
+				ExchangeRate:     lot.ExchangeRate,
+				ExchangeRateDate: lot.ExchangeRateDate,
+				Commission:       lot.Commission,
+				TransactionTax:   m.buyTaxes[lot],
+			})
+		}
+	}
 }
 
 // collectAndCopyHoldings is a helper to create the PurchaseLot view model from the internal state.
@@ -120,22 +611,16 @@ func collectAndCopyHoldings(holdingsMap map[string][]*models.ProcessedTransactio
 	for _, lots := range holdingsMap {
 		for _, lot := range lots {
 			if lot.Quantity > 0 {
-				var lotAmount, lotAmountEUR float64
-				if lot.OriginalQuantity > 0 {
-					ratio := float64(lot.Quantity) / float64(lot.OriginalQuantity)
-					lotAmount = lot.Amount * ratio
-					lotAmountEUR = lot.AmountEUR * ratio
-				}
-
 				snapshot = append(snapshot, models.PurchaseLot{
 					BuyDate:      lot.Date,
 					ProductName:  lot.ProductName,
 					ISIN:         lot.ISIN,
 					Quantity:     lot.Quantity,
-					BuyAmount:    lotAmount,
+					BuyAmount:    remainingCost(lot.Amount, lot).Float64(),
 					BuyCurrency:  lot.Currency,
-					BuyAmountEUR: utils.RoundFloat(lotAmountEUR, 2),
-					BuyPrice:     lot.Price,
+					BuyAmountEUR: lotShareCents(lot.AmountEUR, lot, lot.Quantity).Float64(),
+					BuyPrice:     lot.Price.Float64(),
+					AssetClass:   lot.AssetClass,
 				})
 			}
 		}
@@ -147,7 +632,7 @@ func collectAndCopyHoldings(holdingsMap map[string][]*models.ProcessedTransactio
 func filterAndSortStockTransactions(transactions []models.ProcessedTransaction) []models.ProcessedTransaction {
 	var stockTx []models.ProcessedTransaction
 	for _, tx := range transactions {
-		if tx.TransactionType == "STOCK" {
+		if tx.TransactionType == "STOCK" || tx.TransactionType == "SCRIP_DIVIDEND" || tx.TransactionType == "RETURN_OF_CAPITAL" {
 			stockTx = append(stockTx, tx)
 		}
 	}
@@ -161,6 +646,9 @@ func filterAndSortStockTransactions(transactions []models.ProcessedTransaction)
 			if stockTx[i].BuySell == "BUY" && stockTx[j].BuySell == "SELL" {
 				return true
 			}
+			if openI, openJ := isOpeningLot(&stockTx[i]), isOpeningLot(&stockTx[j]); openI != openJ {
+				return openI
+			}
 			return stockTx[i].OrderID < stockTx[j].OrderID
 		}
 		return dateI.Before(dateJ)
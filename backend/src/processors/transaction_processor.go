@@ -4,7 +4,7 @@ package processors
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
+	"sync"
 
 	"github.com/username/taxfolio/backend/src/logger"
 	"github.com/username/taxfolio/backend/src/models"
@@ -12,72 +12,150 @@ import (
 )
 
 // TransactionProcessor enriches canonical transactions with data that is not source-specific.
-type TransactionProcessor struct{}
+type TransactionProcessor struct {
+	workers int // Transactions enriched at once
+}
+
+// NewTransactionProcessor creates a TransactionProcessor enriching up to workers transactions at once.
+func NewTransactionProcessor(workers int) *TransactionProcessor {
+	return &TransactionProcessor{workers: max(workers, 1)}
+}
 
-func NewTransactionProcessor() *TransactionProcessor { return &TransactionProcessor{} }
+// minParallelBatch is the smallest batch worth spreading over the workers.
+const minParallelBatch = 64
 
 // Process iterates through canonical transactions and enriches them.
 // It no longer calculates the amount, trusting the value provided by the specific parser.
-func (p *TransactionProcessor) Process(txs []models.CanonicalTransaction) []models.ProcessedTransaction {
-	var processedTxs []models.ProcessedTransaction
-	for _, tx := range txs {
-		// --- Enrichment Stage ---
+// ExchangeRate and AmountEUR are expressed against the user's base currency (EUR unless configured otherwise),
+// so every processor downstream reports in that currency.
+// Large batches are enriched by a pool of workers; the output keeps the order of txs.
+func (p *TransactionProcessor) Process(txs []models.CanonicalTransaction, baseCurrency string) []models.ProcessedTransaction {
+	if len(txs) == 0 {
+		return nil
+	}
+	processedTxs := make([]models.ProcessedTransaction, len(txs))
+	if p.workers <= 1 || len(txs) < minParallelBatch {
+		for i, tx := range txs {
+			processedTxs[i] = enrich(tx, baseCurrency)
+		}
+		return processedTxs
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(p.workers, len(txs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				processedTxs[i] = enrich(txs[i], baseCurrency)
+			}
+		}()
+	}
+	for i := range txs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return processedTxs
+}
 
-		// 1. Enrich with Exchange Rate.
-		rate, err := GetExchangeRate(tx.Currency, tx.TransactionDate)
+// enrich adds the exchange rate, the amount in the base currency, the country and the hash to a
+// canonical transaction and maps it to a ProcessedTransaction.
+func enrich(tx models.CanonicalTransaction, baseCurrency string) models.ProcessedTransaction {
+	// --- Enrichment Stage ---
+
+	// 1. Enrich with Exchange Rate, unless the parser found the rate the broker actually executed at.
+	// rateDate records the day of the ECB observation used, which may precede a weekend or holiday.
+	var rateDate string
+	if tx.ExchangeRate <= 0 {
+		rate, observed, err := GetExchangeRateToWithDate(tx.Currency, baseCurrency, tx.TransactionDate)
 		if err != nil {
-			logger.L.Warn("Could not find exchange rate, defaulting to 1.0", "currency", tx.Currency, "date", tx.TransactionDate, "orderID", tx.OrderID, "error", err)
+			logger.L.Warn("Could not find exchange rate, defaulting to 1.0", "currency", tx.Currency, "base", baseCurrency, "date", tx.TransactionDate, "orderID", tx.OrderID, "error", err)
 			tx.ExchangeRate = 1.0
 		} else {
 			tx.ExchangeRate = rate
+			rateDate = observed.Format("02-01-2006")
 		}
-
-		// 2. Enrich with Amount in EUR.
-		// This now uses the pre-calculated, signed `Amount` from the canonical transaction.
-		if tx.ExchangeRate > 0 {
-			tx.AmountEUR = tx.Amount / tx.ExchangeRate
+	} else if baseCurrency != "EUR" {
+		// Executed rates from the parsers are quoted against EUR; carry them over to the base currency.
+		eurPerBase, observed, err := GetExchangeRateToWithDate("EUR", baseCurrency, tx.TransactionDate)
+		if err != nil {
+			logger.L.Warn("Could not convert executed exchange rate to base currency, defaulting to 1.0", "currency", tx.Currency, "base", baseCurrency, "date", tx.TransactionDate, "orderID", tx.OrderID, "error", err)
+			tx.ExchangeRate = 1.0
 		} else {
-			tx.AmountEUR = tx.Amount // Fallback if exchange rate is somehow zero
+			tx.ExchangeRate *= eurPerBase
+			rateDate = observed.Format("02-01-2006")
 		}
+	}
+
+	// 2. Enrich with Amount in the base currency (stored as AmountEUR).
+	// This now uses the pre-calculated, signed `Amount` from the canonical transaction.
+	// Amounts become fixed point here, so the processors downstream add them up exactly.
+	amount := models.NewMoney(tx.Amount)
+	amountEUR := amount // Fallback if exchange rate is somehow zero
+	if tx.ExchangeRate > 0 {
+		amountEUR = amount.Convert(tx.ExchangeRate)
+	}
+	tx.AmountEUR = amountEUR.Float64()
 
-		// 3. Enrich with Country Code from ISIN.
+	// 3. Enrich with Country Code from ISIN, or from the country the parser found when there is none.
+	if tx.ISIN == "" && tx.Country != "" {
+		tx.CountryCode = utils.GetCountryCodeStringByAlpha2(tx.Country)
+	} else {
 		tx.CountryCode = utils.GetCountryCodeString(tx.ISIN)
+	}
 
-		// 4. Enrich with a unique Hash ID.
-		tx.HashId = generateHash(tx)
-
-		// --- Final Mapping ---
-		// Map the fully-enriched CanonicalTransaction to the final ProcessedTransaction.
-		processed := models.ProcessedTransaction{
-			Date:               tx.TransactionDate.Format("02-01-2006"),
-			Source:             tx.Source,
-			ProductName:        tx.ProductName,
-			ISIN:               tx.ISIN,
-			Quantity:           int(tx.Quantity),
-			OriginalQuantity:   int(tx.Quantity),
-			Price:              tx.Price,
-			TransactionType:    tx.TransactionType,
-			TransactionSubType: tx.TransactionSubType,
-			BuySell:            tx.BuySell,
-			Description:        tx.RawText,
-			Amount:             tx.Amount, // This is now the correct signed amount from the parser
-			Currency:           tx.Currency,
-			Commission:         tx.Commission,
-			OrderID:            tx.OrderID,
-			ExchangeRate:       tx.ExchangeRate,
-			AmountEUR:          tx.AmountEUR, // This is the correctly converted EUR amount
-			CountryCode:        tx.CountryCode,
-			InputString:        tx.RawText,
-			HashId:             tx.HashId,
-		}
-		processedTxs = append(processedTxs, processed)
+	// 4. Enrich with a unique Hash ID.
+	tx.HashId = generateHash(tx)
+
+	// --- Final Mapping ---
+	// Map the fully-enriched CanonicalTransaction to the final ProcessedTransaction.
+	return models.ProcessedTransaction{
+		Date:               tx.TransactionDate.Format("02-01-2006"),
+		ExecutedAt:         tx.TransactionDate.UTC(),
+		Source:             tx.Source,
+		ProductName:        tx.ProductName,
+		ISIN:               tx.ISIN,
+		Quantity:           int(tx.Quantity),
+		OriginalQuantity:   int(tx.Quantity),
+		Price:              models.NewMoney(tx.Price),
+		TransactionType:    tx.TransactionType,
+		TransactionSubType: tx.TransactionSubType,
+		BuySell:            tx.BuySell,
+		Description:        tx.RawText,
+		Amount:             amount, // This is now the correct signed amount from the parser
+		Currency:           tx.Currency,
+		Commission:         models.NewMoney(tx.Commission),
+		OrderID:            tx.OrderID,
+		ExchangeRate:       tx.ExchangeRate,
+		ExchangeRateDate:   rateDate,
+		AmountEUR:          amountEUR, // Converted to the base currency
+		CountryCode:        tx.CountryCode,
+		InputString:        tx.RawText,
+		HashId:             tx.HashId,
+
+		BrokerBalance:         models.NewMoney(tx.BrokerBalance),
+		BrokerBalanceCurrency: tx.BrokerBalanceCurrency,
 	}
-	return processedTxs
+}
+
+// countryOf returns the country label of a transaction: derived from its ISIN, or the one stored with it
+// when the broker exports no ISIN and the parser found the country another way. ok is false when
+// neither gives a country.
+func countryOf(tx models.ProcessedTransaction) (label string, ok bool) {
+	if len(tx.ISIN) >= 2 {
+		return utils.GetCountryCodeString(tx.ISIN), true
+	}
+	if _, found := utils.GetCountryByNumeric(countryKey(tx.CountryCode)); found {
+		return tx.CountryCode, true
+	}
+	return "", false
 }
 
 // generateHash creates a unique hash for the transaction based on key source data.
 func generateHash(tx models.CanonicalTransaction) string {
-	input := fmt.Sprintf(tx.RawText)
+	input := tx.RawText
 	hash := sha256.Sum256([]byte(input))
 	return hex.EncodeToString(hash[:])
 }
@@ -0,0 +1,128 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidCiphertext is returned when a value cannot be decrypted with the given key.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encrypt seals plaintext with AES-GCM and returns the nonce and ciphertext as base64.
+// The key must be 16, 24 or 32 bytes long.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(key []byte, encoded string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ErrUnknownKey is returned when a value was encrypted with a key the keyring does not have.
+var ErrUnknownKey = errors.New("value encrypted with an unknown key")
+
+// keyIDSeparator ends the key ID that prefixes the values a Keyring encrypts. It is not part of the
+// base64 alphabet, so values encrypted before key IDs were recorded have none.
+const keyIDSeparator = ":"
+
+// Keyring encrypts with its current key and decrypts with any of its keys, so the key can be rotated:
+// the new key becomes the current one, the old one stays among the previous keys until every value
+// has been encrypted again with the new one.
+type Keyring struct {
+	currentID string
+	keys      map[string][]byte // By key ID, including the current key
+}
+
+// NewKeyring creates a Keyring encrypting with current, recorded as currentID, that can still decrypt
+// the values encrypted with the previous keys.
+func NewKeyring(currentID string, current []byte, previous map[string][]byte) *Keyring {
+	keys := make(map[string][]byte, len(previous)+1)
+	for id, key := range previous {
+		keys[id] = key
+	}
+	keys[currentID] = current
+	return &Keyring{currentID: currentID, keys: keys}
+}
+
+// Encrypt seals plaintext with the current key, prefixed with its ID.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	sealed, err := Encrypt(k.keys[k.currentID], plaintext)
+	if err != nil {
+		return "", err
+	}
+	return k.currentID + keyIDSeparator + sealed, nil
+}
+
+// Decrypt opens a value encrypted by Encrypt with any key of the keyring. A value without key ID,
+// encrypted before they were recorded, is tried with each key, the current one first.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if id, sealed, found := strings.Cut(value, keyIDSeparator); found {
+		key, ok := k.keys[id]
+		if !ok {
+			return "", ErrUnknownKey
+		}
+		return Decrypt(key, sealed)
+	}
+	if plaintext, err := Decrypt(k.keys[k.currentID], value); err == nil {
+		return plaintext, nil
+	}
+	for id, key := range k.keys {
+		if id == k.currentID {
+			continue
+		}
+		if plaintext, err := Decrypt(key, value); err == nil {
+			return plaintext, nil
+		}
+	}
+	return "", ErrInvalidCiphertext
+}
+
+// IsCurrent reports whether value was encrypted with the current key.
+func (k *Keyring) IsCurrent(value string) bool {
+	return strings.HasPrefix(value, k.currentID+keyIDSeparator)
+}
+
+// HashToken returns the hex SHA-256 of a random token, such as a refresh token, for looking it up
+// without storing it in clear. The token's own entropy makes a salt or key unnecessary.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
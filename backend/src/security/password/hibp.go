@@ -0,0 +1,78 @@
+// backend/src/security/password/hibp.go
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BreachChecker reports how often a password appears in known data breaches.
+type BreachChecker interface {
+	BreachCount(ctx context.Context, password string) (int, error)
+}
+
+// hibpRangeURL is the k-anonymity endpoint of Have I Been Pwned. Only the first 5 characters of the
+// password's SHA-1 hash are sent; the matching suffixes come back and are compared locally.
+const hibpRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// HIBPClient checks passwords against the Have I Been Pwned password range API.
+type HIBPClient struct {
+	baseURL    string
+	httpClient http.Client
+}
+
+// NewHIBPClient creates a client for the public Have I Been Pwned API.
+func NewHIBPClient() *HIBPClient {
+	return &HIBPClient{
+		baseURL:    hibpRangeURL,
+		httpClient: http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// BreachCount returns the number of times the password was seen in breaches, 0 when it never was.
+func (c *HIBPClient) BreachCount(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Padding hides the number of suffixes sharing the prefix from anyone watching the response size.
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "taxfolio-password-check")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("breach lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach lookup returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid breach count %q", count)
+		}
+		return n, nil // Padding entries have a count of 0
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("error reading breach lookup response: %w", err)
+	}
+	return 0, nil
+}
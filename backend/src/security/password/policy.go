@@ -0,0 +1,55 @@
+// backend/src/security/password/policy.go
+package password
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+// MaxLength is the longest password accepted, in bytes. bcrypt ignores anything beyond it.
+const MaxLength = 72
+
+// Policy decides which passwords local accounts may use.
+type Policy struct {
+	MinLength   int           // In characters
+	MinStrength int           // Lowest accepted Strength.Score
+	Breaches    BreachChecker // nil skips the breach check
+}
+
+// Error is a refused password. Its message is meant for the user.
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Reason
+}
+
+// Check returns an *Error explaining why the password is refused, or nil. userInputs are strings tied
+// to the account, such as the username and email, that make a password easier to guess. The breach
+// check fails open: when the lookup fails the password is judged on its strength alone.
+func (p Policy) Check(ctx context.Context, password string, userInputs ...string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &Error{Reason: fmt.Sprintf("Password must be at least %d characters long", p.MinLength)}
+	}
+	if len(password) > MaxLength {
+		return &Error{Reason: fmt.Sprintf("Password must be at most %d bytes long", MaxLength)}
+	}
+
+	if strength := EstimateStrength(password, userInputs...); strength.Score < p.MinStrength {
+		return &Error{Reason: fmt.Sprintf("Password is too easy to guess. %s.", strength.Warning)}
+	}
+
+	if p.Breaches != nil {
+		count, err := p.Breaches.BreachCount(ctx, password)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Password breach check unavailable, skipping it", "error", err)
+		} else if count > 0 {
+			return &Error{Reason: "This password has appeared in a data breach and cannot be used. Please choose another one."}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// backend/src/security/password/strength.go
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength estimates how hard a password is to guess, in the manner of zxcvbn: the password is split
+// into the cheapest sequence of patterns an attacker would try (common passwords, account details,
+// repeats, sequences, keyboard rows, years) and the guesses of each pattern are multiplied.
+type Strength struct {
+	Score   int     // 0 (too guessable) to 4 (very unguessable)
+	Guesses float64 // log10 of the estimated number of guesses
+	Warning string  // Why the password is weak, or "" when nothing stands out
+}
+
+// maxScoredLength bounds the characters examined, keeping the quadratic matching cheap. Longer
+// passwords are strong enough on their prefix alone.
+const maxScoredLength = 100
+
+// Guess thresholds of each score, as log10 of the number of guesses.
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords ranks frequent passwords and words, 1 being the most common.
+var commonPasswords = func() map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(commonPasswordList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "!@#$%^&*()"}
+
+// leetSubstitutions maps characters commonly swapped into words back to the letters they stand for.
+var leetSubstitutions = map[rune]rune{'@': 'a', '4': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't'}
+
+const (
+	warningCommon     = "This is a very common password"
+	warningUserInput  = "Avoid using your username or email in your password"
+	warningRepeat     = `Repeated characters like "aaa" are easy to guess`
+	warningSequence   = "Sequences like abc or 6543 are easy to guess"
+	warningKeyboard   = "Straight rows of keys are easy to guess"
+	warningYear       = "Years are easy to guess"
+	warningShortWords = "Add another word or two; uncommon words are better"
+)
+
+// match is one pattern found in the password, covering runes [i, j).
+type match struct {
+	i, j    int
+	guesses float64 // log10
+	warning string
+}
+
+// EstimateStrength scores the password. userInputs are strings tied to the account, such as the
+// username and email, which an attacker targeting it would try first.
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) > maxScoredLength {
+		runes = runes[:maxScoredLength]
+	}
+	if len(runes) == 0 {
+		return Strength{Warning: warningShortWords}
+	}
+
+	matches := findMatches(runes, accountWords(userInputs))
+
+	// best[j] is the cheapest split of the first j runes. Each extra pattern multiplies the guesses by
+	// the number of patterns so far, as the attacker must also guess how many were combined.
+	type step struct {
+		guesses float64
+		count   int
+		last    *match
+		from    int
+	}
+	best := make([]step, len(runes)+1)
+	for j := 1; j <= len(runes); j++ {
+		best[j] = step{guesses: math.Inf(1)}
+		for i := 0; i < j; i++ {
+			// Anything not matched is brute-forced at ten guesses a character.
+			candidate := best[i].guesses + float64(j-i) + math.Log10(float64(best[i].count+1))
+			if candidate < best[j].guesses {
+				best[j] = step{guesses: candidate, count: best[i].count + 1, from: i}
+			}
+		}
+		for k := range matches {
+			m := &matches[k]
+			if m.j != j {
+				continue
+			}
+			candidate := best[m.i].guesses + m.guesses + math.Log10(float64(best[m.i].count+1))
+			if candidate < best[j].guesses {
+				best[j] = step{guesses: candidate, count: best[m.i].count + 1, last: m, from: m.i}
+			}
+		}
+	}
+
+	strength := Strength{Guesses: best[len(runes)].guesses}
+	for strength.Score < len(scoreThresholds) && strength.Guesses >= scoreThresholds[strength.Score] {
+		strength.Score++
+	}
+	if strength.Score < 3 {
+		// Warn about the weakest pattern the password was split into.
+		weakest := math.Inf(1)
+		for j := len(runes); j > 0; j = best[j].from {
+			if m := best[j].last; m != nil && m.guesses < weakest {
+				weakest = m.guesses
+				strength.Warning = m.warning
+			}
+		}
+		if strength.Warning == "" {
+			strength.Warning = warningShortWords
+		}
+	}
+	return strength
+}
+
+// accountWords splits the user inputs into the words worth matching, e.g. an email into its local part
+// and domain name.
+func accountWords(userInputs []string) map[string]bool {
+	words := make(map[string]bool)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		for _, word := range append([]string{input}, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...) {
+			if len([]rune(word)) >= 3 {
+				words[word] = true
+			}
+		}
+	}
+	return words
+}
+
+func findMatches(runes []rune, userWords map[string]bool) []match {
+	var matches []match
+	lower := []rune(strings.ToLower(string(runes)))
+	n := len(runes)
+
+	// Dictionary words, plain, reversed or with leet substitutions, in any capitalization.
+	for i := 0; i < n; i++ {
+		for j := i + 3; j <= n; j++ {
+			word := string(lower[i:j])
+			variations := capitalizationVariations(runes[i:j])
+			candidates := []struct {
+				word  string
+				extra float64
+			}{
+				{word, 0},
+				{reverse(word), math.Log10(2)},
+				{unleet(lower[i:j], 'i'), math.Log10(4)},
+				{unleet(lower[i:j], 'l'), math.Log10(4)},
+			}
+			for _, c := range candidates {
+				if userWords[c.word] {
+					matches = append(matches, match{i: i, j: j, guesses: variations + c.extra, warning: warningUserInput})
+				} else if rank, ok := commonPasswords[c.word]; ok {
+					matches = append(matches, match{i: i, j: j, guesses: math.Log10(float64(rank)) + variations + c.extra, warning: warningCommon})
+				}
+			}
+		}
+	}
+
+	// Runs of one character.
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && lower[j] == lower[i] {
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{i: i, j: j, guesses: math.Log10(cardinality(lower[i]) * float64(j-i)), warning: warningRepeat})
+		}
+		i = j
+	}
+
+	// Ascending or descending sequences, such as abcd or 9876.
+	for i := 0; i < n-2; {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j < n && lower[j]-lower[j-1] == delta && sameClass(lower[j], lower[i]) {
+				j++
+			}
+		}
+		if j-i >= 3 {
+			base := cardinality(lower[i])
+			if strings.ContainsRune("a1z9", lower[i]) {
+				base = 4 // Obvious starting points
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, match{i: i, j: j, guesses: math.Log10(base * float64(j-i)), warning: warningSequence})
+			i = j - 1
+			continue
+		}
+		i++
+	}
+
+	// Stretches of a keyboard row, left to right or right to left.
+	for i := 0; i < n; i++ {
+		for j := i + 4; j <= n; j++ {
+			stretch := string(lower[i:j])
+			for _, row := range keyboardRows {
+				if strings.Contains(row, stretch) || strings.Contains(row, reverse(stretch)) {
+					matches = append(matches, match{i: i, j: j, guesses: math.Log10(50 * float64(j-i)), warning: warningKeyboard})
+					break
+				}
+			}
+		}
+	}
+
+	// Years from 1900 to 2049.
+	for i := 0; i+4 <= n; i++ {
+		year := string(lower[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && isDigits(year) && year < "2050" {
+			matches = append(matches, match{i: i, j: i + 4, guesses: math.Log10(150), warning: warningYear})
+		}
+	}
+	return matches
+}
+
+// capitalizationVariations returns the log10 of the guesses needed to find the capitalization of a
+// word: none for lower case, one bit for a leading capital or all capitals, more for mixed case.
+func capitalizationVariations(word []rune) float64 {
+	upper := 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(word) || (upper == 1 && unicode.IsUpper(word[0])):
+		return math.Log10(2)
+	}
+	return math.Min(float64(len(word)), float64(upper)) * math.Log10(float64(len(word)))
+}
+
+func unleet(word []rune, one rune) string {
+	out := make([]rune, len(word))
+	for i, r := range word {
+		if sub, ok := leetSubstitutions[r]; ok {
+			if r == '1' {
+				sub = one
+			}
+			r = sub
+		}
+		out[i] = r
+	}
+	return string(out)
+}
+
+// cardinality is the number of characters in the class of r: digits, letters or anything else.
+func cardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLetter(r):
+		return 26
+	}
+	return 33
+}
+
+func sameClass(a, b rune) bool {
+	return cardinality(a) == cardinality(b)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
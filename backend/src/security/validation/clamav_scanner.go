@@ -0,0 +1,104 @@
+// backend/src/security/validation/clamav_scanner.go
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+// clamAVChunkSize is the size of the chunks a file is streamed to clamd in.
+const clamAVChunkSize = 64 << 10
+
+// ClamAVScanner scans files with a ClamAV daemon (clamd), streaming them over its INSTREAM command.
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd listening at address: a Unix socket given as
+// unix:/path/to/clamd.sock, or a TCP address given as host:port or tcp://host:port. A scan that takes
+// longer than timeout fails with ErrScanUnavailable.
+func NewClamAVScanner(address string, timeout time.Duration) *ClamAVScanner {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		network, address = "unix", strings.TrimPrefix(path, "//")
+	} else {
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	return &ClamAVScanner{network: network, address: address, timeout: timeout}
+}
+
+// Scan streams the file to clamd and rejects it when clamd finds a signature in it.
+func (s *ClamAVScanner) Scan(ctx context.Context, name, _ string, data []byte) error {
+	reply, err := s.instream(ctx, data)
+	if err != nil {
+		logger.L.Error("ClamAV scan failed", "filename", name, "error", err)
+		return fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+	}
+
+	// clamd answers "stream: OK", "stream: <signature> FOUND" or "<reason> ERROR".
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		logger.L.Warn("Upload rejected: ClamAV found a signature", "filename", name, "signature", signature)
+		return fmt.Errorf("%w: '%s' is infected (%s)", ErrFileRejected, name, signature)
+	default:
+		logger.L.Error("ClamAV could not scan file", "filename", name, "reply", reply)
+		return fmt.Errorf("%w: clamd replied %q", ErrScanUnavailable, reply)
+	}
+}
+
+// instream sends data to clamd with the INSTREAM command and returns its reply.
+func (s *ClamAVScanner) instream(ctx context.Context, data []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The z prefix makes clamd read and write NUL-terminated lines.
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", err
+	}
+	size := make([]byte, 4)
+	for len(data) > 0 {
+		chunk := data[:min(len(data), clamAVChunkSize)]
+		data = data[len(chunk):]
+		binary.BigEndian.PutUint32(size, uint32(len(chunk)))
+		if _, err := conn.Write(size); err != nil {
+			return "", err
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return "", err
+		}
+	}
+	// A zero-length chunk ends the stream.
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return "", err
+	}
+
+	reply, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil && len(reply) == 0 {
+		return "", err
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
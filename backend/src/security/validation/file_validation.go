@@ -1,9 +1,15 @@
 package validation
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/username/taxfolio/backend/src/logger"
@@ -17,14 +23,140 @@ var AllowedClientContentTypes = map[string]bool{
 	"application/vnd.ms-excel": true, // Often used for CSV by older Excel
 	"text/plain":               true, // CSVs are often plain text
 	"application/octet-stream": true, // Fallback, but be more cautious
-	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": false, // .xlsx, explicitly disallow for CSV endpoint
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true, // .xlsx, used by the XTB and eToro exports
+	"application/zip":              true, // Archives of statements, such as a year of monthly exports
+	"application/x-zip-compressed": true, // What Windows browsers send for .zip files
+	"application/pdf":              true, // DeGiro account statements
+}
+
+// allowedDetectedTypes are the content types, as sniffed from the magic bytes, of the files the parsers read.
+var allowedDetectedTypes = map[string]bool{
+	// For CSV, we are primarily concerned it's text-based and not something malicious like an executable.
+	// "text/plain" is a very common and acceptable detected type for CSV.
+	// "application/csv" might be detected by some systems.
+	// "application/octet-stream" is a generic fallback, can be risky if not followed by strict parsing.
+	// We allow octet-stream here but rely on later parsing to fail if it's not actually CSV.
+	"text/plain":               true,
+	"text/csv":                 true,
+	"text/xml":                 true, // IBKR Flex Query statements
+	"application/csv":          true,
+	"application/octet-stream": true, // Be cautious with this; strict parsing is key later
+	"application/zip":          true, // XLSX workbooks are zip archives; the spreadsheet reader caps their expanded size
+	"application/pdf":          true, // DeGiro account statements; the PDF reader caps their expanded size
+}
+
+const (
+	// maxArchiveEntries and maxArchiveSize bound the files of a ZIP upload and their total uncompressed
+	// size, as a guard against zip bombs.
+	maxArchiveEntries = 100
+	maxArchiveSize    = 128 << 20
+)
+
+var (
+	// ErrFileRejected is returned by a FileScanner for a file that must not be parsed.
+	ErrFileRejected = errors.New("file rejected by scan")
+	// ErrScanUnavailable is returned by a FileScanner that could not scan a file, such as when the
+	// ClamAV daemon cannot be reached.
+	ErrScanUnavailable = errors.New("file scanner unavailable")
+)
+
+const (
+	// Text statements run at 4 to 6 bits of entropy per byte. Encrypted, packed or random content
+	// approaches 8, so a text file above maxTextEntropy is not a statement. Below minEntropySample
+	// bytes the measure is too noisy to judge by.
+	maxTextEntropy   = 7.0
+	minEntropySample = 4096
+)
+
+// executableSignatures are the magic bytes of the executable and script formats an upload is
+// rejected for, whatever its detected content type.
+var executableSignatures = [][]byte{
+	[]byte("MZ"),             // Windows PE
+	[]byte("\x7fELF"),        // Linux ELF
+	{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32-bit
+	{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit, little-endian
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit, little-endian
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal binary
+	[]byte("#!"),             // Shell scripts
+}
+
+// FileScanner inspects an uploaded file before it is parsed. It returns an error wrapping
+// ErrFileRejected for a file that must not be parsed, and one wrapping ErrScanUnavailable when it
+// could not tell.
+type FileScanner interface {
+	Scan(ctx context.Context, name, contentType string, data []byte) error
+}
+
+// Scanners runs several scanners in turn, stopping at the first error.
+type Scanners []FileScanner
+
+// Scan scans the file with each scanner.
+func (s Scanners) Scan(ctx context.Context, name, contentType string, data []byte) error {
+	for _, scanner := range s {
+		if err := scanner.Scan(ctx, name, contentType, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HeuristicScanner rejects files that start like an executable, and text files whose entropy shows
+// they hold encrypted or packed content rather than a statement. It needs no external service.
+type HeuristicScanner struct{}
+
+// Scan applies the heuristics to the file. Compressed formats (XLSX, ZIP, PDF) are checked for
+// executable signatures only, as compression gives them a high entropy anyway.
+func (HeuristicScanner) Scan(_ context.Context, name, contentType string, data []byte) error {
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(data, signature) {
+			logger.L.Warn("Upload rejected: executable signature", "filename", name)
+			return fmt.Errorf("%w: '%s' looks like an executable", ErrFileRejected, name)
+		}
+	}
+
+	switch contentType {
+	case "application/zip", "application/pdf":
+		return nil
+	}
+	if len(data) >= minEntropySample {
+		if entropy := shannonEntropy(data); entropy > maxTextEntropy {
+			logger.L.Warn("Upload rejected: entropy too high for a statement", "filename", name, "entropy", entropy)
+			return fmt.Errorf("%w: '%s' holds encrypted or binary content rather than a statement", ErrFileRejected, name)
+		}
+	}
+	return nil
+}
+
+// shannonEntropy returns the entropy of data in bits per byte, from 0 to 8.
+func shannonEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// ArchiveFile is a file extracted from an uploaded ZIP archive.
+type ArchiveFile struct {
+	Name        string
+	ContentType string // As sniffed from its magic bytes
+	Data        []byte
 }
 
 // ValidateClientContentType checks the Content-Type header provided by the client.
 func ValidateClientContentType(contentType string) error {
 	if allowed, exists := AllowedClientContentTypes[strings.ToLower(contentType)]; !exists || !allowed {
 		logger.L.Warn("Disallowed client-declared Content-Type", "contentType", contentType)
-		return fmt.Errorf("client-declared file type '%s' is not allowed for CSV upload", contentType)
+		return fmt.Errorf("client-declared file type '%s' is not allowed for upload", contentType)
 	}
 	return nil
 }
@@ -48,26 +180,75 @@ func ValidateFileContentByMagicBytes(file io.ReadSeeker) (string, error) {
 		return "", fmt.Errorf("failed to reset file read pointer: %w", seekErr)
 	}
 
-	detectedContentType := http.DetectContentType(buffer[:n])
-	detectedContentType = strings.ToLower(strings.Split(detectedContentType, ";")[0]) // Normalize (e.g. "text/plain; charset=utf-8")
+	return validateDetectedContentType(buffer[:n])
+}
 
-	// For CSV, we are primarily concerned it's text-based and not something malicious like an executable.
-	// "text/plain" is a very common and acceptable detected type for CSV.
-	// "application/csv" might be detected by some systems.
-	// "application/octet-stream" is a generic fallback, can be risky if not followed by strict parsing.
-	// We allow octet-stream here but rely on later parsing to fail if it's not actually CSV.
-	allowedDetectedTypes := map[string]bool{
-		"text/plain":               true,
-		"text/csv":                 true,
-		"application/csv":          true,
-		"application/octet-stream": true, // Be cautious with this; strict parsing is key later
-	}
+// validateDetectedContentType sniffs the content type of the start of a file and checks it is one the
+// parsers read.
+func validateDetectedContentType(head []byte) (string, error) {
+	detectedContentType := http.DetectContentType(head)
+	detectedContentType = strings.ToLower(strings.Split(detectedContentType, ";")[0]) // Normalize (e.g. "text/plain; charset=utf-8")
 
 	if !allowedDetectedTypes[detectedContentType] {
 		logger.L.Warn("Disallowed detected file content type (magic bytes)", "detectedContentType", detectedContentType)
-		return detectedContentType, fmt.Errorf("detected file content type '%s' is not consistent with a CSV file", detectedContentType)
+		return detectedContentType, fmt.Errorf("detected file content type '%s' is not consistent with a supported statement file", detectedContentType)
 	}
 
 	logger.L.Debug("File content type (magic bytes) validated", "detectedContentType", detectedContentType)
 	return detectedContentType, nil
 }
+
+// IsWorkbook reports whether a ZIP archive is an XLSX workbook rather than an archive of statements.
+func IsWorkbook(archive *zip.Reader) bool {
+	for _, f := range archive.File {
+		if f.Name == "[Content_Types].xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateZipArchive extracts the files of an uploaded ZIP archive, checking each by its magic bytes like
+// a file uploaded on its own. Directories and the metadata macOS adds to archives are left out. The
+// number of files and their total uncompressed size are capped; archive/zip fails reading an entry
+// that expands beyond the size it declares, so the cap cannot be dodged by lying in the headers.
+func ValidateZipArchive(archive *zip.Reader) ([]ArchiveFile, error) {
+	var files []ArchiveFile
+	var total uint64
+	for _, f := range archive.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		if len(files) == maxArchiveEntries {
+			return nil, fmt.Errorf("archive has more than %d files", maxArchiveEntries)
+		}
+		total += f.UncompressedSize64
+		if total > maxArchiveSize {
+			return nil, fmt.Errorf("archive expands to more than %d MB", maxArchiveSize>>20)
+		}
+
+		data, err := readArchiveFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s' from archive: %w", f.Name, err)
+		}
+		contentType, err := validateDetectedContentType(data[:min(len(data), 512)])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		files = append(files, ArchiveFile{Name: f.Name, ContentType: contentType, Data: data})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("archive contains no files")
+	}
+	return files, nil
+}
+
+func readArchiveFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,288 @@
+// backend/src/services/alert_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/security/validation"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// ErrInvalidAlertRule is returned when an alert rule is of an unknown kind or lacks what its kind needs.
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+// maxAlertRules bounds the alert rules one user can define.
+const maxAlertRules = 50
+
+type alertServiceImpl struct {
+	db                     *sql.DB
+	transactions           model.TransactionRepository
+	uploadService          UploadService
+	unrealizedGainsService UnrealizedGainsService
+	dataQualityService     DataQualityService
+	emailService           EmailService
+}
+
+// NewAlertService creates a new AlertService.
+func NewAlertService(db *sql.DB, transactions model.TransactionRepository, uploadService UploadService,
+	unrealizedGainsService UnrealizedGainsService, dataQualityService DataQualityService, emailService EmailService) AlertService {
+	return &alertServiceImpl{
+		db:                     db,
+		transactions:           transactions,
+		uploadService:          uploadService,
+		unrealizedGainsService: unrealizedGainsService,
+		dataQualityService:     dataQualityService,
+		emailService:           emailService,
+	}
+}
+
+func (s *alertServiceImpl) GetRules(userID int64) ([]models.AlertRule, error) {
+	return model.GetAlertRules(s.db, userID)
+}
+
+func (s *alertServiceImpl) CreateRule(userID int64, rule models.AlertRule) (*models.AlertRule, error) {
+	if err := validateAlertRule(&rule); err != nil {
+		return nil, err
+	}
+	count, err := model.CountAlertRules(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAlertRules {
+		return nil, fmt.Errorf("%w: no more than %d alert rules are allowed", ErrInvalidAlertRule, maxAlertRules)
+	}
+	rule.UserID = userID
+	if err := model.CreateAlertRule(s.db, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *alertServiceImpl) UpdateRule(userID, id int64, rule models.AlertRule) (*models.AlertRule, error) {
+	if err := validateAlertRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID, rule.UserID = id, userID
+	if err := model.UpdateAlertRule(s.db, &rule); err != nil {
+		return nil, err
+	}
+	rules, err := model.GetAlertRules(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i], nil
+		}
+	}
+	return nil, model.ErrAlertRuleNotFound
+}
+
+func (s *alertServiceImpl) DeleteRule(userID, id int64) error {
+	return model.DeleteAlertRule(s.db, userID, id)
+}
+
+// validateAlertRule checks a rule has what its kind needs, and clears what it does not use.
+func validateAlertRule(rule *models.AlertRule) error {
+	rule.ISIN = strings.ToUpper(strings.TrimSpace(rule.ISIN))
+	switch rule.Kind {
+	case models.AlertBelowCostBasis:
+		if err := validation.ValidateISIN(rule.ISIN); err != nil {
+			return fmt.Errorf("%w: %s needs the ISIN of the position to watch", ErrInvalidAlertRule, rule.Kind)
+		}
+		if rule.Threshold < 0 || rule.Threshold >= 100 {
+			return fmt.Errorf("%w: the threshold of %s is a percentage from 0 to 100", ErrInvalidAlertRule, rule.Kind)
+		}
+	case models.AlertMonthlyDividendsAbove:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("%w: %s needs a positive threshold", ErrInvalidAlertRule, rule.Kind)
+		}
+		rule.ISIN = ""
+	case models.AlertUnmatchedSell:
+		rule.ISIN, rule.Threshold = "", 0
+	default:
+		return fmt.Errorf("%w: unknown kind %q, expected %s, %s or %s", ErrInvalidAlertRule, rule.Kind,
+			models.AlertBelowCostBasis, models.AlertMonthlyDividendsAbove, models.AlertUnmatchedSell)
+	}
+	return nil
+}
+
+// StartScheduler periodically evaluates the enabled alert rules of every user.
+func (s *alertServiceImpl) StartScheduler(interval time.Duration) {
+	StartPeriodicJob("alerts", interval, s.EvaluateAll)
+}
+
+// EvaluateAll checks the enabled alert rules of every user and emails each user the ones that started
+// to hold since the last run, in a single message.
+func (s *alertServiceImpl) EvaluateAll() {
+	rules, err := model.GetEnabledAlertRules(s.db)
+	if err != nil {
+		logger.L.Error("Failed to list alert rules", "error", err)
+		return
+	}
+	for start := 0; start < len(rules); {
+		end := start
+		for end < len(rules) && rules[end].UserID == rules[start].UserID {
+			end++
+		}
+		if err := s.evaluateUser(rules[start].UserID, rules[start:end]); err != nil {
+			logger.L.Error("Failed to evaluate alert rules", "userID", rules[start].UserID, "error", err)
+		}
+		start = end
+	}
+}
+
+// alertState is the outcome of evaluating one rule: the occurrence of its condition, empty when it
+// does not hold, and the message telling the user about it.
+type alertState struct {
+	key     string
+	message string
+}
+
+// alertInputs loads the data the rules of one user look at, each part only when a rule needs it.
+type alertInputs struct {
+	ctx          context.Context
+	s            *alertServiceImpl
+	userID       int64
+	locale       string
+	baseCurrency string
+	now          time.Time
+
+	unrealized   *models.UnrealizedGainsReport
+	dividendsEUR *float64
+	dataQuality  *models.DataQualityReport
+}
+
+func (s *alertServiceImpl) evaluateUser(userID int64, rules []models.AlertRule) error {
+	baseCurrency, err := s.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		return err
+	}
+	in := &alertInputs{ctx: context.Background(), s: s, userID: userID, locale: userLocale(userID), baseCurrency: baseCurrency, now: time.Now()}
+
+	var messages []string
+	var triggered []models.AlertRule
+	var keys []string
+	for _, rule := range rules {
+		state, err := in.evaluate(rule)
+		if err != nil {
+			logger.L.Warn("Could not evaluate alert rule", "userID", userID, "ruleID", rule.ID, "kind", rule.Kind, "error", err)
+			continue
+		}
+		switch {
+		case state.key == rule.TriggeredKey:
+			// Already notified, or still not holding
+		case state.key == "":
+			// The condition stopped holding: notify it again when it next does
+			if err := model.SetAlertRuleTriggered(s.db, rule.ID, "", nil); err != nil {
+				logger.L.Error("Failed to re-arm alert rule", "ruleID", rule.ID, "error", err)
+			}
+		default:
+			messages = append(messages, state.message)
+			triggered = append(triggered, rule)
+			keys = append(keys, state.key)
+		}
+	}
+	if len(triggered) == 0 {
+		return nil
+	}
+
+	user, err := model.GetUserByID(s.db, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.emailService.SendAlertEmail(user.Email, user.Username, messages, in.locale); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err) // Tried again on the next run
+	}
+	for i, rule := range triggered {
+		if err := model.SetAlertRuleTriggered(s.db, rule.ID, keys[i], &in.now); err != nil {
+			logger.L.Error("Failed to record triggered alert rule", "ruleID", rule.ID, "error", err)
+		}
+	}
+	logger.L.Info("Alert rules triggered", "userID", userID, "count", len(triggered))
+	return nil
+}
+
+func (in *alertInputs) evaluate(rule models.AlertRule) (alertState, error) {
+	switch rule.Kind {
+	case models.AlertBelowCostBasis:
+		if in.unrealized == nil {
+			report, err := in.s.unrealizedGainsService.GetUnrealizedGains(in.ctx, in.userID)
+			if err != nil {
+				return alertState{}, err
+			}
+			in.unrealized = report
+		}
+		for _, pos := range in.unrealized.Positions {
+			if pos.ISIN != rule.ISIN || pos.MarketValueEUR == nil || pos.CostBasisEUR <= 0 {
+				continue
+			}
+			drop := (pos.CostBasisEUR - *pos.MarketValueEUR) / pos.CostBasisEUR * 100
+			if drop <= 0 || drop < rule.Threshold {
+				return alertState{}, nil
+			}
+			return alertState{
+				key: "below",
+				message: i18n.T(in.locale, i18n.MsgAlertBelowCostBasis, pos.ProductName, pos.ISIN, utils.RoundFloat(drop, 1),
+					*pos.MarketValueEUR, in.baseCurrency, pos.CostBasisEUR, in.baseCurrency),
+			}, nil
+		}
+		return alertState{}, nil // Not held, or no price: nothing to compare
+
+	case models.AlertMonthlyDividendsAbove:
+		if in.dividendsEUR == nil {
+			transactions, err := in.s.transactions.ListByUser(in.ctx, in.userID)
+			if err != nil {
+				return alertState{}, err
+			}
+			var total float64
+			for _, tx := range transactions {
+				date := utils.ParseDate(tx.Date)
+				if (tx.TransactionType == "DIVIDEND" || tx.TransactionType == "SCRIP_DIVIDEND") && tx.TransactionSubType != "TAX" &&
+					date.Year() == in.now.Year() && date.Month() == in.now.Month() {
+					total += utils.RoundAmount(tx.AmountEUR).Float64()
+				}
+			}
+			total = utils.RoundMoney(total)
+			in.dividendsEUR = &total
+		}
+		if *in.dividendsEUR <= rule.Threshold {
+			return alertState{}, nil
+		}
+		month := in.now.Format("2006-01")
+		return alertState{
+			key:     month,
+			message: i18n.T(in.locale, i18n.MsgAlertMonthlyDividendsAbove, *in.dividendsEUR, in.baseCurrency, month, rule.Threshold, in.baseCurrency),
+		}, nil
+
+	case models.AlertUnmatchedSell:
+		if in.dataQuality == nil {
+			report, err := in.s.dataQualityService.GetReport(in.ctx, in.userID, "")
+			if err != nil {
+				return alertState{}, err
+			}
+			in.dataQuality = report
+		}
+		for _, check := range in.dataQuality.Checks {
+			if check.Name != CheckUnmatchedSells || check.Count == 0 {
+				continue
+			}
+			// A new unmatched sale changes the key and is notified again
+			return alertState{
+				key:     fmt.Sprintf("%s:%d", in.dataQuality.Year, check.Count),
+				message: i18n.T(in.locale, i18n.MsgAlertUnmatchedSell, check.Count, in.dataQuality.Year),
+			}, nil
+		}
+		return alertState{}, nil
+	}
+	return alertState{}, fmt.Errorf("unknown alert kind %q", rule.Kind)
+}
@@ -0,0 +1,431 @@
+// backend/src/services/billing_service.go
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const (
+	billingProviderStripe = "stripe"
+
+	stripeAPIBaseURL = "https://api.stripe.com/v1"
+
+	// Webhooks signed longer ago than this are rejected, so a captured request cannot be replayed later.
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrBillingDisabled is returned when no Stripe keys are configured.
+	ErrBillingDisabled = errors.New("billing is not configured")
+	// ErrPlanNotPurchasable is returned for plans that have no Stripe price attached.
+	ErrPlanNotPurchasable = errors.New("plan cannot be purchased")
+	// ErrInvalidWebhookSignature is returned when a webhook was not signed with our endpoint secret.
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrBillingProviderFailed is returned when the Stripe API rejects a request.
+	ErrBillingProviderFailed = errors.New("billing provider request failed")
+)
+
+// Subscription statuses that keep the user on the plan they paid for. past_due keeps access while
+// Stripe retries the payment; every other status drops the user back to the free plan.
+var entitledSubscriptionStatuses = map[string]bool{
+	"active":   true,
+	"trialing": true,
+	"past_due": true,
+}
+
+// stripeCanceledStatus is the final status of a subscription; Stripe never reactivates one.
+const stripeCanceledStatus = "canceled"
+
+// stripeEvent is the envelope of every Stripe webhook.
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"` // Unix seconds
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeCheckoutSession struct {
+	ClientReferenceID string `json:"client_reference_id"`
+	Customer          string `json:"customer"`
+	Subscription      string `json:"subscription"`
+}
+
+type stripeSubscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+	Items            struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+type billingServiceImpl struct {
+	db            *sql.DB
+	quotaService  QuotaService
+	secretKey     string
+	webhookSecret string
+	successURL    string
+	cancelURL     string
+	httpClient    http.Client
+}
+
+// NewBillingService creates a new BillingService. Billing stays disabled, and every user keeps
+// access to premium endpoints, unless both the Stripe secret key and webhook secret are set.
+func NewBillingService(db *sql.DB, quotaService QuotaService, secretKey, webhookSecret, successURL, cancelURL string) BillingService {
+	return &billingServiceImpl{
+		db:            db,
+		quotaService:  quotaService,
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		successURL:    successURL,
+		cancelURL:     cancelURL,
+		httpClient:    http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Enabled reports whether subscriptions are sold through Stripe.
+func (s *billingServiceImpl) Enabled() bool {
+	return s.secretKey != "" && s.webhookSecret != ""
+}
+
+// HasPremiumAccess reports whether the user may use premium endpoints.
+func (s *billingServiceImpl) HasPremiumAccess(userID int64) (bool, error) {
+	if !s.Enabled() {
+		return true, nil
+	}
+	return model.UserHasPremiumPlan(s.db, userID)
+}
+
+// GetPlans lists the billing catalog.
+func (s *billingServiceImpl) GetPlans() ([]models.BillingPlan, error) {
+	return model.GetBillingPlans(s.db)
+}
+
+// GetSubscription returns the user's subscription, or model.ErrSubscriptionNotFound.
+func (s *billingServiceImpl) GetSubscription(userID int64) (*models.Subscription, error) {
+	return model.GetSubscription(s.db, userID)
+}
+
+// SetPlanPrice links a plan to the Stripe price that buys it.
+func (s *billingServiceImpl) SetPlanPrice(plan, priceID string) error {
+	err := model.SetBillingPlanStripePrice(s.db, plan, priceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %q", ErrUnknownPlan, plan)
+	}
+	return err
+}
+
+// CreateCheckoutSession starts a Stripe Checkout for the plan and returns the URL to send the user to.
+func (s *billingServiceImpl) CreateCheckoutSession(userID int64, planName string) (string, error) {
+	if !s.Enabled() {
+		return "", ErrBillingDisabled
+	}
+	plan, err := model.GetBillingPlan(s.db, planName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownPlan, planName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error loading plan: %w", err)
+	}
+	if plan.StripePriceID == "" {
+		return "", fmt.Errorf("%w: %q", ErrPlanNotPurchasable, planName)
+	}
+
+	userRef := strconv.FormatInt(userID, 10)
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("line_items[0][price]", plan.StripePriceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("client_reference_id", userRef)
+	form.Set("subscription_data[metadata][user_id]", userRef)
+	form.Set("success_url", s.successURL)
+	form.Set("cancel_url", s.cancelURL)
+
+	// Returning subscribers keep their Stripe customer; new ones are created from their email.
+	sub, err := model.GetSubscription(s.db, userID)
+	switch {
+	case err == nil && sub.Provider == billingProviderStripe && sub.CustomerID != "":
+		form.Set("customer", sub.CustomerID)
+	case err == nil || errors.Is(err, model.ErrSubscriptionNotFound):
+		user, err := model.GetUserByID(s.db, userID)
+		if err != nil {
+			return "", fmt.Errorf("error loading user: %w", err)
+		}
+		form.Set("customer_email", user.Email)
+	default:
+		return "", fmt.Errorf("error loading subscription: %w", err)
+	}
+
+	body, err := s.post("/checkout/sessions", form)
+	if err != nil {
+		return "", err
+	}
+	var session struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &session); err != nil || session.URL == "" {
+		return "", fmt.Errorf("%w: unexpected checkout session response", ErrBillingProviderFailed)
+	}
+	logger.L.Info("Created checkout session", "userID", userID, "plan", planName)
+	return session.URL, nil
+}
+
+func (s *billingServiceImpl) post(path string, form url.Values) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, stripeAPIBaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall("stripe", resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &apiErr)
+		return nil, fmt.Errorf("%w: status %d: %s", ErrBillingProviderFailed, resp.StatusCode, apiErr.Error.Message)
+	}
+	return body, nil
+}
+
+// HandleWebhook verifies a Stripe webhook and applies the subscription change it announces.
+// Events the service does not care about are accepted and ignored. Stripe may deliver an event more
+// than once and out of order: an event already applied is skipped, and so is a subscription event
+// older than the last one applied to the user's subscription.
+func (s *billingServiceImpl) HandleWebhook(payload []byte, signatureHeader string) error {
+	if !s.Enabled() {
+		return ErrBillingDisabled
+	}
+	if err := verifyStripeSignature(payload, signatureHeader, s.webhookSecret, time.Now()); err != nil {
+		return err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to parse webhook event: %w", err)
+	}
+	logger.L.Debug("Received billing webhook", "eventID", event.ID, "type", event.Type)
+
+	if event.ID != "" {
+		processed, err := model.BillingEventProcessed(s.db, billingProviderStripe, event.ID)
+		if err != nil {
+			return fmt.Errorf("error checking webhook event: %w", err)
+		}
+		if processed {
+			logger.L.Info("Skipping billing webhook already applied", "eventID", event.ID, "type", event.Type)
+			return nil
+		}
+	}
+	if err := s.applyEvent(event); err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if err := model.RecordBillingEvent(s.db, billingProviderStripe, event.ID, event.Type); err != nil {
+			return fmt.Errorf("error recording webhook event: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyEvent applies the change a verified webhook event announces.
+func (s *billingServiceImpl) applyEvent(event stripeEvent) error {
+	switch event.Type {
+	case "checkout.session.completed":
+		var session stripeCheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return fmt.Errorf("failed to parse checkout session: %w", err)
+		}
+		return s.linkCustomer(session)
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var sub stripeSubscription
+		if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+			return fmt.Errorf("failed to parse subscription: %w", err)
+		}
+		return s.applySubscription(sub, event.Type == "customer.subscription.deleted", event.Created)
+	}
+	return nil
+}
+
+// linkCustomer remembers which Stripe customer paid for a checkout, so later subscription events
+// can be matched to the user even without our metadata.
+func (s *billingServiceImpl) linkCustomer(session stripeCheckoutSession) error {
+	userID, err := strconv.ParseInt(session.ClientReferenceID, 10, 64)
+	if err != nil || session.Customer == "" {
+		logger.L.Warn("Ignoring checkout session without user reference", "clientReferenceID", session.ClientReferenceID)
+		return nil
+	}
+	// The subscription events may arrive first; their status is more accurate than ours.
+	if _, err := model.GetSubscription(s.db, userID); err == nil {
+		return nil
+	} else if !errors.Is(err, model.ErrSubscriptionNotFound) {
+		return fmt.Errorf("error loading subscription: %w", err)
+	}
+	return model.UpsertSubscription(s.db, models.Subscription{
+		UserID:         userID,
+		Provider:       billingProviderStripe,
+		CustomerID:     session.Customer,
+		SubscriptionID: session.Subscription,
+		Status:         "incomplete",
+	})
+}
+
+// applySubscription moves the user to the plan the subscription pays for while it is in good
+// standing, and back to the free plan once it lapses or is deleted. created is when the event was
+// created; events older than the last one applied, and changes to a subscription already canceled,
+// are ignored.
+func (s *billingServiceImpl) applySubscription(sub stripeSubscription, deleted bool, created int64) error {
+	userID, err := s.subscriptionUser(sub)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		logger.L.Warn("Ignoring subscription event for unknown customer", "subscriptionID", sub.ID, "customer", sub.Customer)
+		return nil
+	}
+
+	current, err := model.GetSubscription(s.db, userID)
+	switch {
+	case errors.Is(err, model.ErrSubscriptionNotFound):
+	case err != nil:
+		return fmt.Errorf("error loading subscription: %w", err)
+	case created < current.LastEventCreated:
+		logger.L.Info("Ignoring subscription event older than the last one applied", "userID", userID, "subscriptionID", sub.ID, "created", created, "lastApplied", current.LastEventCreated)
+		return nil
+	case current.SubscriptionID == sub.ID && current.Status == stripeCanceledStatus && !deleted:
+		logger.L.Info("Ignoring change to a canceled subscription", "userID", userID, "subscriptionID", sub.ID, "status", sub.Status)
+		return nil
+	}
+
+	var priceID string
+	periodEnd := sub.CurrentPeriodEnd
+	if len(sub.Items.Data) > 0 {
+		priceID = sub.Items.Data[0].Price.ID
+		if periodEnd == 0 {
+			periodEnd = sub.Items.Data[0].CurrentPeriodEnd
+		}
+	}
+	plan, err := model.GetBillingPlanByStripePrice(s.db, priceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.L.Warn("Ignoring subscription for a price not linked to any plan", "subscriptionID", sub.ID, "priceID", priceID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading plan: %w", err)
+	}
+
+	userPlan := models.PlanFree
+	if !deleted && entitledSubscriptionStatuses[sub.Status] {
+		userPlan = plan.Name
+	}
+	if err := s.quotaService.SetPlan(userID, userPlan); err != nil {
+		return fmt.Errorf("error updating plan of user %d: %w", userID, err)
+	}
+
+	record := models.Subscription{
+		UserID:           userID,
+		Provider:         billingProviderStripe,
+		CustomerID:       sub.Customer,
+		SubscriptionID:   sub.ID,
+		Plan:             plan.Name,
+		Status:           sub.Status,
+		LastEventCreated: created,
+	}
+	if periodEnd > 0 {
+		end := time.Unix(periodEnd, 0).UTC()
+		record.CurrentPeriodEnd = &end
+	}
+	if err := model.UpsertSubscription(s.db, record); err != nil {
+		return fmt.Errorf("error saving subscription: %w", err)
+	}
+	logger.L.Info("Applied subscription change", "userID", userID, "status", sub.Status, "plan", userPlan)
+	return nil
+}
+
+// subscriptionUser finds the user a subscription belongs to, from the metadata set at checkout or
+// from the linked customer. It returns 0 if neither matches.
+func (s *billingServiceImpl) subscriptionUser(sub stripeSubscription) (int64, error) {
+	if ref, ok := sub.Metadata["user_id"]; ok {
+		if userID, err := strconv.ParseInt(ref, 10, 64); err == nil {
+			return userID, nil
+		}
+	}
+	userID, err := model.GetUserIDByCustomer(s.db, billingProviderStripe, sub.Customer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error looking up customer: %w", err)
+	}
+	return userID, nil
+}
+
+// verifyStripeSignature checks a Stripe-Signature header of the form "t=<unix>,v1=<hex>[,v1=<hex>]",
+// where each v1 is an HMAC-SHA256 of "<t>.<payload>" keyed with the endpoint secret.
+func verifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidWebhookSignature)
+	}
+	if age := now.Sub(time.Unix(signedAt, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// newTestDB opens a migrated SQLite database in a temporary directory as database.DB.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	database.InitDB(path, "", database.Settings{MaxOpenConns: 1, MaxIdleConns: 1})
+	t.Chdir("../..") // The migrations are read from db/migrations
+	database.RunMigrations(path, "")
+	t.Cleanup(func() { database.DB.Close() })
+	return database.DB
+}
+
+const testWebhookSecret = "whsec_test"
+
+func newTestBillingService(t *testing.T) (*billingServiceImpl, int64) {
+	t.Helper()
+	db := newTestDB(t)
+	result, err := db.Exec(`INSERT INTO users (username, password, email) VALUES ('billing', 'x', 'billing@example.com')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, _ := result.LastInsertId()
+	if err := model.SetBillingPlanStripePrice(db, models.PlanPremium, "price_premium"); err != nil {
+		t.Fatal(err)
+	}
+	quota := &quotaServiceImpl{db: db, plans: map[string]models.Plan{models.PlanFree: {Name: models.PlanFree}, models.PlanPremium: {Name: models.PlanPremium}}}
+	return &billingServiceImpl{db: db, quotaService: quota, secretKey: "sk_test", webhookSecret: testWebhookSecret}, userID
+}
+
+// sendSubscriptionEvent signs and handles a subscription webhook event for the user.
+func sendSubscriptionEvent(t *testing.T, s *billingServiceImpl, userID int64, id, eventType, status string, created int64) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"id":%q,"type":%q,"created":%d,"data":{"object":{"id":"sub_1","customer":"cus_1","status":%q,`+
+		`"metadata":{"user_id":"%d"},"items":{"data":[{"price":{"id":"price_premium"}}]}}}}`, id, eventType, created, status, userID))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	if err := s.HandleWebhook(payload, "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil))); err != nil {
+		t.Fatalf("event %s: %v", id, err)
+	}
+}
+
+func userPlan(t *testing.T, s *billingServiceImpl, userID int64) string {
+	t.Helper()
+	plan, err := model.GetUserPlan(s.db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestHandleWebhookIgnoresEventsAfterDeletion(t *testing.T) {
+	s, userID := newTestBillingService(t)
+
+	sendSubscriptionEvent(t, s, userID, "evt_1", "customer.subscription.created", "active", 100)
+	if plan := userPlan(t, s, userID); plan != models.PlanPremium {
+		t.Fatalf("plan after creation is %q, want premium", plan)
+	}
+	sendSubscriptionEvent(t, s, userID, "evt_3", "customer.subscription.deleted", "canceled", 300)
+	// An update sent before the deletion but delivered after it, then one Stripe could not have sent.
+	sendSubscriptionEvent(t, s, userID, "evt_2", "customer.subscription.updated", "active", 200)
+	sendSubscriptionEvent(t, s, userID, "evt_4", "customer.subscription.updated", "active", 400)
+
+	if plan := userPlan(t, s, userID); plan != models.PlanFree {
+		t.Errorf("plan after deletion is %q, want free", plan)
+	}
+	if sub, err := model.GetSubscription(s.db, userID); err != nil || sub.Status != "canceled" {
+		t.Errorf("subscription is %+v (error %v), want canceled", sub, err)
+	}
+}
+
+func TestHandleWebhookSkipsRedeliveredEvents(t *testing.T) {
+	s, userID := newTestBillingService(t)
+
+	sendSubscriptionEvent(t, s, userID, "evt_1", "customer.subscription.created", "active", 100)
+	if err := model.SetUserPlan(s.db, userID, models.PlanFree); err != nil {
+		t.Fatal(err)
+	}
+	sendSubscriptionEvent(t, s, userID, "evt_1", "customer.subscription.created", "active", 100)
+	if plan := userPlan(t, s, userID); plan != models.PlanFree {
+		t.Errorf("redelivered event changed the plan to %q", plan)
+	}
+}
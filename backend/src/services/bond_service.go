@@ -0,0 +1,33 @@
+// backend/src/services/bond_service.go
+package services
+
+import (
+	"context"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+type bondServiceImpl struct {
+	transactions model.TransactionRepository
+	processor    processors.BondProcessor
+}
+
+// NewBondService creates a new BondService.
+func NewBondService(transactions model.TransactionRepository, processor processors.BondProcessor) BondService {
+	return &bondServiceImpl{
+		transactions: transactions,
+		processor:    processor,
+	}
+}
+
+// GetBondIncome lists the user's coupons, accrued interest and bond sales and redemptions, with their
+// totals per tax year.
+func (s *bondServiceImpl) GetBondIncome(ctx context.Context, userID int64) (*models.BondIncomeReport, error) {
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	report := s.processor.Process(transactions, userFiscalYear(userID))
+	return &report, nil
+}
@@ -0,0 +1,245 @@
+// backend/src/services/calendar_service.go
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// ErrInvalidCalendarToken is returned for a calendar feed token that is malformed, forged, revoked
+// or belongs to a feed that is off.
+var ErrInvalidCalendarToken = errors.New("invalid calendar token")
+
+// icsLineLength is the longest content line, in octets, before it is folded (RFC 5545, 3.1).
+const icsLineLength = 75
+
+type calendarServiceImpl struct {
+	db                      *sql.DB
+	tokenKey                []byte
+	feedURL                 string
+	uidDomain               string
+	dividendCalendarService DividendCalendarService
+	uploadService           UploadService
+}
+
+// NewCalendarService creates a new CalendarService. Feed tokens are signed with a key derived from
+// secret, and their URLs are built on apiBaseURL.
+func NewCalendarService(db *sql.DB, secret, apiBaseURL string, dividendCalendarService DividendCalendarService, uploadService UploadService) CalendarService {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("calendar-feed"))
+	uidDomain := "rumoclaro"
+	if u, err := url.Parse(apiBaseURL); err == nil && u.Hostname() != "" {
+		uidDomain = u.Hostname()
+	}
+	return &calendarServiceImpl{
+		db:                      db,
+		tokenKey:                mac.Sum(nil),
+		feedURL:                 strings.TrimRight(apiBaseURL, "/") + "/api/calendar.ics",
+		uidDomain:               uidDomain,
+		dividendCalendarService: dividendCalendarService,
+		uploadService:           uploadService,
+	}
+}
+
+// sign returns the token of version of the user's feed: the user ID, the version and their HMAC.
+func (s *calendarServiceImpl) sign(userID, version int64) string {
+	mac := hmac.New(sha256.New, s.tokenKey)
+	fmt.Fprintf(mac, "calendar:%d:%d", userID, version)
+	return fmt.Sprintf("%d.%d.%s", userID, version, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (s *calendarServiceImpl) feed(userID, version int64) *models.CalendarFeed {
+	token := s.sign(userID, version)
+	return &models.CalendarFeed{Enabled: true, Token: token, URL: s.feedURL + "?token=" + url.QueryEscape(token)}
+}
+
+// GetFeed returns the state of the user's calendar feed, with its URL when it is on.
+func (s *calendarServiceImpl) GetFeed(userID int64) (*models.CalendarFeed, error) {
+	version, enabled, err := model.GetCalendarFeed(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return &models.CalendarFeed{}, nil
+	}
+	return s.feed(userID, version), nil
+}
+
+// EnableFeed turns the user's calendar feed on with a new token. Calendars subscribed with the
+// previous token stop receiving updates.
+func (s *calendarServiceImpl) EnableFeed(userID int64) (*models.CalendarFeed, error) {
+	version, err := model.EnableCalendarFeed(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.feed(userID, version), nil
+}
+
+// DisableFeed turns the user's calendar feed off, revoking its token.
+func (s *calendarServiceImpl) DisableFeed(userID int64) error {
+	return model.DisableCalendarFeed(s.db, userID)
+}
+
+// UserForToken returns the user whose feed token is token, provided it is the current one.
+func (s *calendarServiceImpl) UserForToken(token string) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidCalendarToken
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCalendarToken
+	}
+	version, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCalendarToken
+	}
+	if !hmac.Equal([]byte(s.sign(userID, version)), []byte(token)) {
+		return 0, ErrInvalidCalendarToken
+	}
+
+	current, enabled, err := model.GetCalendarFeed(s.db, userID)
+	if errors.Is(err, model.ErrUserNotFound) {
+		return 0, ErrInvalidCalendarToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !enabled || current != version {
+		return 0, ErrInvalidCalendarToken
+	}
+	return userID, nil
+}
+
+// BuildFeed renders the user's calendar as an iCalendar (RFC 5545) document: the dividends projected
+// for the next twelve months and, for tax residents of Portugal, the IRS filing and payment deadlines
+// of this year and the next. Every event lasts the whole day.
+func (s *calendarServiceImpl) BuildFeed(ctx context.Context, userID int64) ([]byte, error) {
+	locale := userLocale(userID)
+	settings, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency, err := s.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := s.dividendCalendarService.GetCalendar(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	w := &icsWriter{stamp: now.Format("20060102T150405Z")}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//Rumo Claro//Calendar feed//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.text("X-WR-CALNAME", i18n.T(locale, i18n.MsgCalendarName))
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", "PT12H")
+	w.line("X-PUBLISHED-TTL", "PT12H")
+
+	for _, month := range calendar.Months {
+		first, err := time.Parse("2006-01", month.Month)
+		if err != nil {
+			logger.L.Warn("Skipping dividend calendar month", "userID", userID, "month", month.Month, "error", err)
+			continue
+		}
+		for _, entry := range month.Entries {
+			day := utils.ParseDate(entry.BasedOn).Day()
+			if last := first.AddDate(0, 1, -1).Day(); day > last {
+				day = last
+			} else if day < 1 {
+				day = 1
+			}
+			w.event(
+				fmt.Sprintf("dividend-%d-%s-%s@%s", userID, entry.ISIN, first.Format("200601"), s.uidDomain),
+				first.AddDate(0, 0, day-1),
+				i18n.T(locale, i18n.MsgCalendarDividend, entry.ProductName),
+				i18n.T(locale, i18n.MsgCalendarDividendDetail, entry.ProductName, entry.ISIN,
+					entry.ExpectedGrossEUR, baseCurrency, entry.ExpectedNetEUR, baseCurrency, entry.BasedOn),
+			)
+		}
+	}
+
+	if settings.TaxCountry == "PT" {
+		for year := now.Year(); year <= now.Year()+1; year++ {
+			incomeYear := year - 1
+			deadlines := []struct {
+				kind  string
+				date  time.Time
+				title string
+			}{
+				{"filing-opens", time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC), i18n.T(locale, i18n.MsgCalendarIRSFilingOpens, incomeYear)},
+				{"filing-ends", time.Date(year, time.June, 30, 0, 0, 0, 0, time.UTC), i18n.T(locale, i18n.MsgCalendarIRSFilingEnds, incomeYear)},
+				{"payment", time.Date(year, time.August, 31, 0, 0, 0, 0, time.UTC), i18n.T(locale, i18n.MsgCalendarIRSPayment, incomeYear)},
+			}
+			for _, deadline := range deadlines {
+				w.event(fmt.Sprintf("irs-%d-%s-%d@%s", incomeYear, deadline.kind, userID, s.uidDomain), deadline.date, deadline.title, "")
+			}
+		}
+	}
+
+	w.line("END", "VCALENDAR")
+	return []byte(w.b.String()), nil
+}
+
+// icsWriter writes iCalendar content lines, CRLF-terminated and folded at icsLineLength octets.
+type icsWriter struct {
+	b     strings.Builder
+	stamp string // DTSTAMP of every event
+}
+
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	limit := icsLineLength
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.b.WriteString(line[:cut])
+		w.b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLength - 1 // The leading space of a continuation counts towards its length
+	}
+	w.b.WriteString(line)
+	w.b.WriteString("\r\n")
+}
+
+// text writes a property whose value is TEXT, escaping it.
+func (w *icsWriter) text(name, value string) {
+	w.line(name, strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value))
+}
+
+// event writes an all-day event on date.
+func (w *icsWriter) event(uid string, date time.Time, summary, description string) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", uid)
+	w.line("DTSTAMP", w.stamp)
+	w.line("DTSTART;VALUE=DATE", date.Format("20060102"))
+	w.line("DTEND;VALUE=DATE", date.AddDate(0, 0, 1).Format("20060102"))
+	w.text("SUMMARY", summary)
+	if description != "" {
+		w.text("DESCRIPTION", description)
+	}
+	w.line("TRANSP", "TRANSPARENT")
+	w.line("END", "VEVENT")
+}
@@ -0,0 +1,65 @@
+// backend/src/services/cash_balance_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+type cashBalanceServiceImpl struct {
+	transactions          model.TransactionRepository
+	db                    *sql.DB
+	processor             processors.CashBalanceProcessor
+	cashMovementProcessor processors.CashMovementProcessor
+	contributionProcessor processors.ContributionProcessor
+}
+
+// NewCashBalanceService creates a new CashBalanceService.
+func NewCashBalanceService(db *sql.DB, transactions model.TransactionRepository, processor processors.CashBalanceProcessor,
+	cashMovementProcessor processors.CashMovementProcessor, contributionProcessor processors.ContributionProcessor) CashBalanceService {
+	return &cashBalanceServiceImpl{
+		transactions:          transactions,
+		db:                    db,
+		processor:             processor,
+		cashMovementProcessor: cashMovementProcessor,
+		contributionProcessor: contributionProcessor,
+	}
+}
+
+// GetCashBalance rebuilds the user's cash balance per broker and currency from deposits, withdrawals,
+// conversions, trades, fees, taxes, dividends and interest, flagging where it departs from the balance
+// the statements reported.
+func (s *cashBalanceServiceImpl) GetCashBalance(ctx context.Context, userID int64) (*models.CashBalanceReport, error) {
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reported, err := model.GetBrokerBalances(s.db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading reported balances: %w", err)
+	}
+	report := s.processor.Process(transactions, reported)
+	return &report, nil
+}
+
+// GetContributions sums the user's deposits and withdrawals per month, with rolling averages of the
+// money put in and the largest single deposit and withdrawal.
+func (s *cashBalanceServiceImpl) GetContributions(ctx context.Context, userID int64) (*models.ContributionReport, error) {
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+	report := s.contributionProcessor.Process(s.cashMovementProcessor.Process(transactions), time.Now())
+	report.BaseCurrency = settings.BaseCurrency
+	return &report, nil
+}
@@ -0,0 +1,381 @@
+// backend/src/services/data_quality_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/security/validation"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// Names of the individual data quality checks.
+const (
+	CheckUnparsedRows      = "unparsed_rows"
+	CheckMissingFXRates    = "missing_fx_rates"
+	CheckUnmatchedSells    = "unmatched_sells"
+	CheckUnresolvedISINs   = "unresolved_isins"
+	CheckReconciliationGap = "reconciliation_gap"
+
+	CheckNegativeHoldings       = "negative_holdings"
+	CheckEmptyLots              = "empty_lots"
+	CheckInconsistentQuantities = "inconsistent_quantities"
+)
+
+const (
+	maxDataQualityExamples  = 5
+	reconciliationTolerance = 0.01 // EUR
+)
+
+type dataQualityServiceImpl struct {
+	transactions   model.TransactionRepository
+	stockProcessor processors.StockProcessor
+}
+
+// NewDataQualityService creates a new DataQualityService.
+func NewDataQualityService(transactions model.TransactionRepository, stockProcessor processors.StockProcessor) DataQualityService {
+	return &dataQualityServiceImpl{transactions: transactions, stockProcessor: stockProcessor}
+}
+
+// GetReport scores the completeness of the user's transactions for a year and suggests fixes.
+// An empty year selects the most recent year with transactions.
+func (s *dataQualityServiceImpl) GetReport(ctx context.Context, userID int64, year string) (*models.DataQualityReport, error) {
+	allTxns, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if year == "" {
+		year = latestTransactionYear(allTxns)
+	}
+
+	var yearTxns []models.ProcessedTransaction
+	for _, tx := range allTxns {
+		if transactionYear(tx) == year {
+			yearTxns = append(yearTxns, tx)
+		}
+	}
+
+	report := &models.DataQualityReport{
+		Year:              year,
+		TotalTransactions: len(yearTxns),
+		Checks:            []models.DataQualityCheck{},
+		Actions:           []string{},
+	}
+
+	unparsedCheck, undated, err := checkUnparsedRows(userID, year, len(yearTxns))
+	if err != nil {
+		return nil, err
+	}
+	report.UndatedSkippedRows = undated
+	fxCheck := checkMissingFXRates(yearTxns, baseCurrency)
+	isinCheck := checkUnresolvedISINs(yearTxns)
+	sellCheck, gapCheck, gap := s.checkSaleMatching(allTxns, year)
+	report.ReconciliationGap = utils.RoundMoney(gap)
+	negativeCheck, emptyCheck, quantityCheck := s.checkHoldings(allTxns, yearTxns, year)
+	report.Checks = append(report.Checks, unparsedCheck, fxCheck, isinCheck, sellCheck, gapCheck, negativeCheck, emptyCheck, quantityCheck)
+
+	locale := userLocale(userID)
+	if unparsedCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionUnparsedRows, unparsedCheck.Count))
+	}
+	if undated > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionUndatedSkippedRows, undated))
+	}
+	if fxCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionMissingFXRates, fxCheck.Count))
+	}
+	if isinCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionUnresolvedISINs, isinCheck.Count))
+	}
+	if sellCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionUnmatchedSells, sellCheck.Count))
+	}
+	if gap > reconciliationTolerance {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionReconciliationGap, gap, baseCurrency, year))
+	}
+	if negativeCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionNegativeHoldings, negativeCheck.Count, year))
+	}
+	if emptyCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionEmptyLots, emptyCheck.Count))
+	}
+	if quantityCheck.Count > 0 {
+		report.Actions = append(report.Actions, i18n.T(locale, i18n.MsgActionInconsistentQuantities, quantityCheck.Count))
+	}
+
+	// Checks produce a 0-1 score; each one is worth an equal share of 100 points.
+	maxScore := 100 / float64(len(report.Checks))
+	total := 0.0
+	for i := range report.Checks {
+		check := &report.Checks[i]
+		check.MaxScore = utils.RoundFloat(maxScore, 2)
+		check.Score = utils.RoundFloat(check.Score*maxScore, 2)
+		total += check.Score
+	}
+	report.Score = utils.RoundFloat(total, 1)
+	return report, nil
+}
+
+// checkSaleMatching replays FIFO across all years and reports sales in the given year that could not be
+// fully matched to purchase lots, together with the EUR proceeds of the unmatched quantity.
+func (s *dataQualityServiceImpl) checkSaleMatching(allTxns []models.ProcessedTransaction, year string) (models.DataQualityCheck, models.DataQualityCheck, float64) {
+	sales, _ := s.stockProcessor.Process(allTxns)
+	matchedQty := make(map[string]int)
+	matchedEUR := make(map[string]float64)
+	for _, sale := range sales {
+		key := sale.ISIN + "|" + sale.SaleDate
+		matchedQty[key] += sale.Quantity
+		matchedEUR[key] += sale.SaleAmountEUR.Float64()
+	}
+
+	sellCheck := newDataQualityCheck(CheckUnmatchedSells)
+	var keys []string
+	soldQty := make(map[string]int)
+	soldEUR := make(map[string]float64)
+	names := make(map[string]string)
+	var sellProceeds float64
+	for _, tx := range allTxns {
+		if tx.TransactionType != "STOCK" || tx.BuySell != "SELL" || transactionYear(tx) != year {
+			continue
+		}
+		key := tx.ISIN + "|" + tx.Date
+		if _, ok := soldQty[key]; !ok {
+			keys = append(keys, key)
+			names[key] = tx.Date + " " + tx.ProductName
+		}
+		soldQty[key] += tx.Quantity
+		soldEUR[key] += tx.AmountEUR.Float64()
+		sellProceeds += tx.AmountEUR.Abs().Float64()
+	}
+
+	var gap float64
+	for _, key := range keys {
+		if soldQty[key] <= matchedQty[key] {
+			continue
+		}
+		sellCheck.Count++
+		gap += math.Abs(soldEUR[key] - matchedEUR[key])
+		addExample(&sellCheck, fmt.Sprintf("%s: sold %d, matched %d", names[key], soldQty[key], matchedQty[key]))
+	}
+	sellCheck.Score = ratioScore(sellCheck.Count, len(keys))
+
+	gapCheck := newDataQualityCheck(CheckReconciliationGap)
+	gapCheck.Score = 1
+	if gap > reconciliationTolerance {
+		gapCheck.Count = sellCheck.Count
+		gapCheck.Score = math.Max(0, 1-gap/sellProceeds)
+	}
+	return sellCheck, gapCheck, gap
+}
+
+// checkHoldings replays FIFO over the transactions up to the end of the year and validates the
+// position it ends with: ISINs sold beyond what was bought (a negative holding, left as open short
+// lots), open lots with no shares or no cost basis left, and lots or stock trades of the year whose
+// quantities do not add up. Each points to statements missing from the import or rows read wrongly.
+func (s *dataQualityServiceImpl) checkHoldings(allTxns, yearTxns []models.ProcessedTransaction, year string) (models.DataQualityCheck, models.DataQualityCheck, models.DataQualityCheck) {
+	var txns []models.ProcessedTransaction
+	for _, tx := range allTxns {
+		if y := transactionYear(tx); y != "" && y <= year {
+			txns = append(txns, tx)
+		}
+	}
+	_, _, state := s.stockProcessor.ProcessWithState(txns, models.CalendarYear)
+
+	// A return of capital may lower a lot's cost basis to zero; such lots are not flagged.
+	writtenOff := make(map[string]bool)
+	for _, adjustment := range s.stockProcessor.CostBasisAdjustments(txns) {
+		if adjustment.BuyDate != "" && adjustment.CostAfterEUR == 0 {
+			writtenOff[adjustment.ISIN+"|"+adjustment.BuyDate] = true
+		}
+	}
+
+	negativeCheck := newDataQualityCheck(CheckNegativeHoldings)
+	emptyCheck := newDataQualityCheck(CheckEmptyLots)
+	quantityCheck := newDataQualityCheck(CheckInconsistentQuantities)
+	isins := make(map[string]bool)
+	lotCount := 0
+	if state != nil {
+		for _, isin := range slices.Sorted(maps.Keys(state.ShortLots)) {
+			short := 0
+			for _, lot := range state.ShortLots[isin] {
+				short += lot.Quantity
+			}
+			if short <= 0 {
+				continue
+			}
+			negativeCheck.Count++
+			addExample(&negativeCheck, fmt.Sprintf("%s %s: sold %d more than bought", isin, state.ShortLots[isin][0].ProductName, short))
+		}
+		for _, isin := range slices.Sorted(maps.Keys(state.OpenLots)) {
+			isins[isin] = true
+			for _, lot := range state.OpenLots[isin] {
+				lotCount++
+				cost := -lot.AmountEUR
+				if lot.OriginalQuantity > 0 {
+					cost = cost.MulDiv(int64(lot.Quantity), int64(lot.OriginalQuantity))
+				}
+				if lot.Quantity <= 0 || cost < 0 || (cost == 0 && !writtenOff[isin+"|"+lot.Date]) {
+					emptyCheck.Count++
+					addExample(&emptyCheck, fmt.Sprintf("%s %s (%s): %d shares left costing %s", lot.Date, lot.ProductName, isin, lot.Quantity, utils.RoundAmount(cost)))
+				}
+				if lot.OriginalQuantity <= 0 || lot.Quantity > lot.OriginalQuantity {
+					quantityCheck.Count++
+					addExample(&quantityCheck, fmt.Sprintf("%s %s (%s): %d of %d shares left", lot.Date, lot.ProductName, isin, lot.Quantity, lot.OriginalQuantity))
+				}
+			}
+		}
+		for isin := range state.ShortLots {
+			isins[isin] = true
+		}
+	}
+
+	trades := 0
+	for _, tx := range yearTxns {
+		if tx.TransactionType != "STOCK" {
+			continue
+		}
+		trades++
+		if tx.Quantity <= 0 || tx.OriginalQuantity != tx.Quantity {
+			quantityCheck.Count++
+			addExample(&quantityCheck, fmt.Sprintf("%s %s %s: quantity %d, original quantity %d", tx.Date, tx.BuySell, tx.ProductName, tx.Quantity, tx.OriginalQuantity))
+		}
+	}
+
+	negativeCheck.Score = ratioScore(negativeCheck.Count, len(isins))
+	emptyCheck.Score = ratioScore(emptyCheck.Count, lotCount)
+	quantityCheck.Score = ratioScore(quantityCheck.Count, lotCount+trades)
+	return negativeCheck, emptyCheck, quantityCheck
+}
+
+// checkUnparsedRows counts the quarantined rows of the user dated in year, by the first date found
+// in their text. It also returns how many rows have no date it can read: they cannot be told apart
+// by year, so they are reported once for the report rather than counted against every year.
+func checkUnparsedRows(userID int64, year string, yearTxnCount int) (models.DataQualityCheck, int, error) {
+	check := newDataQualityCheck(CheckUnparsedRows)
+	skipped, err := model.GetSkippedTransactionsByUserID(database.DB, userID)
+	if err != nil {
+		return check, 0, fmt.Errorf("error loading skipped transactions: %w", err)
+	}
+	undated := 0
+	for _, row := range skipped {
+		rowYear, ok := skippedRowYear(row.RawText)
+		if !ok {
+			undated++
+			continue
+		}
+		if rowYear == year {
+			check.Count++
+			addExample(&check, row.Reason+": "+row.RawText)
+		}
+	}
+	check.Score = ratioScore(check.Count, check.Count+yearTxnCount)
+	return check, undated, nil
+}
+
+// skippedRowDate matches the days brokers write in their rows: DD-MM-YYYY (also with / or .) or
+// YYYY-MM-DD.
+var skippedRowDate = regexp.MustCompile(`\b(?:\d{2}[-/.]\d{2}[-/.](\d{4})|(\d{4})-\d{2}-\d{2})\b`)
+
+// skippedRowYear returns the year of the first day in the text of a skipped row.
+func skippedRowYear(rawText string) (string, bool) {
+	for _, match := range skippedRowDate.FindAllStringSubmatch(rawText, -1) {
+		day, layout := match[0], "2006-01-02"
+		if match[1] != "" {
+			day, layout = strings.NewReplacer("/", "-", ".", "-").Replace(match[0]), "02-01-2006"
+		}
+		if t, err := time.Parse(layout, day); err == nil {
+			return strconv.Itoa(t.Year()), true
+		}
+	}
+	return "", false
+}
+
+// checkMissingFXRates flags transactions outside the base currency that fell back to the default 1.0 rate.
+func checkMissingFXRates(txns []models.ProcessedTransaction, baseCurrency string) models.DataQualityCheck {
+	check := newDataQualityCheck(CheckMissingFXRates)
+	foreign := 0
+	for _, tx := range txns {
+		if tx.Currency == "" || strings.EqualFold(tx.Currency, baseCurrency) {
+			continue
+		}
+		foreign++
+		if tx.ExchangeRate == 1.0 {
+			check.Count++
+			addExample(&check, fmt.Sprintf("%s %s (%s)", tx.Date, tx.ProductName, tx.Currency))
+		}
+	}
+	check.Score = ratioScore(check.Count, foreign)
+	return check
+}
+
+// checkUnresolvedISINs flags security transactions whose ISIN is missing or malformed.
+func checkUnresolvedISINs(txns []models.ProcessedTransaction) models.DataQualityCheck {
+	check := newDataQualityCheck(CheckUnresolvedISINs)
+	securities := 0
+	for _, tx := range txns {
+		switch tx.TransactionType {
+		case "STOCK", "DIVIDEND", "SCRIP_DIVIDEND", "RETURN_OF_CAPITAL":
+		default:
+			continue
+		}
+		securities++
+		if strings.TrimSpace(tx.ISIN) == "" || validation.ValidateISIN(tx.ISIN) != nil {
+			check.Count++
+			addExample(&check, fmt.Sprintf("%s %s (ISIN %q)", tx.Date, tx.ProductName, tx.ISIN))
+		}
+	}
+	check.Score = ratioScore(check.Count, securities)
+	return check
+}
+
+func newDataQualityCheck(name string) models.DataQualityCheck {
+	return models.DataQualityCheck{Name: name, Examples: []string{}}
+}
+
+func addExample(check *models.DataQualityCheck, example string) {
+	if len(check.Examples) < maxDataQualityExamples {
+		check.Examples = append(check.Examples, example)
+	}
+}
+
+// ratioScore returns 1 when nothing is affected and scales down with the affected share.
+func ratioScore(affected, total int) float64 {
+	if total == 0 || affected == 0 {
+		return 1
+	}
+	return 1 - float64(affected)/float64(total)
+}
+
+func transactionYear(tx models.ProcessedTransaction) string {
+	d := utils.ParseDate(tx.Date)
+	if d.IsZero() {
+		return ""
+	}
+	return strconv.Itoa(d.Year())
+}
+
+func latestTransactionYear(txns []models.ProcessedTransaction) string {
+	latest := ""
+	for _, tx := range txns {
+		if y := transactionYear(tx); y > latest {
+			latest = y
+		}
+	}
+	return latest
+}
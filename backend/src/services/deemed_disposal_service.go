@@ -0,0 +1,108 @@
+// backend/src/services/deemed_disposal_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type deemedDisposalServiceImpl struct {
+	transactions  model.TransactionRepository
+	db            *sql.DB
+	processor     processors.DeemedDisposalProcessor
+	uploadService UploadService
+	priceService  PriceService
+}
+
+// NewDeemedDisposalService creates a new DeemedDisposalService.
+func NewDeemedDisposalService(db *sql.DB, transactions model.TransactionRepository, processor processors.DeemedDisposalProcessor, uploadService UploadService, priceService PriceService) DeemedDisposalService {
+	return &deemedDisposalServiceImpl{
+		transactions:  transactions,
+		db:            db,
+		processor:     processor,
+		uploadService: uploadService,
+		priceService:  priceService,
+	}
+}
+
+// IsEnabled reports whether the user asked for the deemed disposal rule to be applied.
+func (s *deemedDisposalServiceImpl) IsEnabled(userID int64) (bool, error) {
+	return model.GetUserDeemedDisposal(s.db, userID)
+}
+
+// SetEnabled turns the deemed disposal rule on or off for the user.
+func (s *deemedDisposalServiceImpl) SetEnabled(userID int64, enabled bool) error {
+	return model.SetUserDeemedDisposal(s.db, userID, enabled)
+}
+
+// GetReport lists the user's deemed disposals valued at the closing price on each anniversary. The
+// units are treated as bought back at that value, so a later anniversary of the same lot is measured
+// from it. Users who have not enabled the rule get an empty report.
+func (s *deemedDisposalServiceImpl) GetReport(ctx context.Context, userID int64) (*models.DeemedDisposalReport, error) {
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.DeemedDisposalReport{
+		Enabled:       enabled,
+		GainByTaxYear: map[string]float64{},
+		PriceStatus:   "OK",
+		Events:        []models.DeemedDisposal{},
+	}
+	if !enabled {
+		return report, nil
+	}
+
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency, err := s.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	report.Events = s.processor.Process(transactions, time.Now(), userFiscalYear(userID))
+
+	// Price per unit at the previous deemed disposal of each lot, keyed by ISIN and buy date.
+	reacquiredAt := make(map[string]float64)
+	priced := 0
+	for i := range report.Events {
+		event := &report.Events[i]
+		lotKey := event.ISIN + "|" + event.BuyDate
+		if price, ok := reacquiredAt[lotKey]; ok {
+			event.CostBasisEUR = utils.RoundMoney(price * float64(event.Quantity))
+		}
+
+		event.PriceStatus = "UNAVAILABLE"
+		price, err := s.priceService.GetPriceOnDate(event.ISIN, utils.ParseDate(event.Date), baseCurrency)
+		if err != nil || price.Status != "OK" || price.Price <= 0 {
+			logger.L.Warn("Could not price deemed disposal", "userID", userID, "isin", event.ISIN, "date", event.Date, "error", err)
+			continue
+		}
+		priced++
+		reacquiredAt[lotKey] = price.Price
+		marketValue := price.Price * float64(event.Quantity)
+		event.PriceStatus = "OK"
+		event.PriceEUR = roundedPtr(price.Price, 4)
+		event.MarketValueEUR = roundedPtr(marketValue, 2)
+		event.GainEUR = roundedPtr(marketValue-event.CostBasisEUR, 2)
+		report.GainByTaxYear[event.TaxYear] = utils.RoundMoney(report.GainByTaxYear[event.TaxYear] + *event.GainEUR)
+	}
+
+	switch {
+	case priced == len(report.Events):
+		report.PriceStatus = "OK"
+	case priced == 0:
+		report.PriceStatus = "UNAVAILABLE"
+	default:
+		report.PriceStatus = "PARTIAL"
+	}
+	return report, nil
+}
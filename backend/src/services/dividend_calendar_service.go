@@ -0,0 +1,134 @@
+// backend/src/services/dividend_calendar_service.go
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// dividendCalendarMonths is how far ahead the calendar projects.
+const dividendCalendarMonths = 12
+
+type dividendCalendarServiceImpl struct {
+	uploadService UploadService
+}
+
+// NewDividendCalendarService creates a new DividendCalendarService.
+func NewDividendCalendarService(uploadService UploadService) DividendCalendarService {
+	return &dividendCalendarServiceImpl{uploadService: uploadService}
+}
+
+// dividendPayment is a gross dividend and the tax withheld on it, grouped by ISIN and payment date.
+type dividendPayment struct {
+	date        time.Time
+	productName string
+	grossEUR    float64
+	taxEUR      float64
+}
+
+// GetCalendar projects the dividends of the next twelve months. Every payment received in the last
+// twelve months from an instrument that is still held is expected to repeat in the same month,
+// with the same gross amount and withholding rate. Changes in the number of shares held are not
+// taken into account.
+func (s *dividendCalendarServiceImpl) GetCalendar(ctx context.Context, userID int64) (*models.DividendCalendar, error) {
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lookbackStart := firstMonth.AddDate(-1, 0, 0)
+
+	calendar := &models.DividendCalendar{
+		From:   firstMonth.Format("2006-01"),
+		To:     firstMonth.AddDate(0, dividendCalendarMonths-1, 0).Format("2006-01"),
+		Months: []models.DividendCalendarMonth{},
+	}
+
+	lots, err := s.uploadService.GetStockHoldingsForYear(ctx, userID, strconv.Itoa(now.Year()))
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[string]bool)
+	for _, lot := range lots {
+		if lot.Quantity > 0 {
+			held[lot.ISIN] = true
+		}
+	}
+
+	dividends, err := s.uploadService.GetDividendTransactions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	payments := make(map[string]map[string]*dividendPayment) // ISIN -> payment date -> payment
+	for _, tx := range dividends {
+		txDate := utils.ParseDate(tx.Date)
+		if !held[tx.ISIN] || txDate.Before(lookbackStart) || !txDate.Before(firstMonth) {
+			continue
+		}
+		byDate, ok := payments[tx.ISIN]
+		if !ok {
+			byDate = make(map[string]*dividendPayment)
+			payments[tx.ISIN] = byDate
+		}
+		payment, ok := byDate[tx.Date]
+		if !ok {
+			payment = &dividendPayment{date: txDate, productName: tx.ProductName}
+			byDate[tx.Date] = payment
+		}
+		if tx.TransactionSubType == "TAX" {
+			payment.taxEUR += tx.AmountEUR.Float64()
+		} else {
+			payment.grossEUR += tx.AmountEUR.Float64()
+		}
+	}
+
+	byMonth := make(map[string]*models.DividendCalendarMonth)
+	for isin, byDate := range payments {
+		for _, payment := range byDate {
+			if payment.grossEUR <= 0 {
+				continue
+			}
+			month := payment.date.AddDate(1, 0, 0).Format("2006-01")
+			calendarMonth, ok := byMonth[month]
+			if !ok {
+				calendarMonth = &models.DividendCalendarMonth{Month: month}
+				byMonth[month] = calendarMonth
+			}
+			entry := models.DividendCalendarEntry{
+				ISIN:                   isin,
+				ProductName:            payment.productName,
+				ExpectedGrossEUR:       utils.RoundMoney(payment.grossEUR),
+				ExpectedWithholdingEUR: utils.RoundMoney(payment.taxEUR),
+				BasedOn:                payment.date.Format(utils.DefaultDateFormat),
+			}
+			entry.ExpectedNetEUR = utils.RoundMoney(entry.ExpectedGrossEUR + entry.ExpectedWithholdingEUR)
+			calendarMonth.Entries = append(calendarMonth.Entries, entry)
+			calendarMonth.TotalGrossEUR += entry.ExpectedGrossEUR
+			calendarMonth.TotalNetEUR += entry.ExpectedNetEUR
+		}
+	}
+
+	for month := firstMonth; len(calendar.Months) < dividendCalendarMonths; month = month.AddDate(0, 1, 0) {
+		key := month.Format("2006-01")
+		calendarMonth, ok := byMonth[key]
+		if !ok {
+			calendarMonth = &models.DividendCalendarMonth{Month: key}
+		}
+		if calendarMonth.Entries == nil {
+			calendarMonth.Entries = []models.DividendCalendarEntry{}
+		}
+		sort.Slice(calendarMonth.Entries, func(i, j int) bool {
+			return calendarMonth.Entries[i].ExpectedGrossEUR > calendarMonth.Entries[j].ExpectedGrossEUR
+		})
+		calendarMonth.TotalGrossEUR = utils.RoundMoney(calendarMonth.TotalGrossEUR)
+		calendarMonth.TotalNetEUR = utils.RoundMoney(calendarMonth.TotalNetEUR)
+		calendar.TotalGrossEUR += calendarMonth.TotalGrossEUR
+		calendar.TotalNetEUR += calendarMonth.TotalNetEUR
+		calendar.Months = append(calendar.Months, *calendarMonth)
+	}
+	calendar.TotalGrossEUR = utils.RoundMoney(calendar.TotalGrossEUR)
+	calendar.TotalNetEUR = utils.RoundMoney(calendar.TotalNetEUR)
+	return calendar, nil
+}
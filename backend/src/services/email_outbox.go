@@ -0,0 +1,145 @@
+// backend/src/services/email_outbox.go
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// Emails are stored in the outbox before they are sent, so one that cannot be sent during, say,
+// registration is retried in the background rather than lost.
+const (
+	outboxMaxAttempts   = 8
+	outboxRetryDelay    = time.Minute // After the first failed attempt, doubling for each further one
+	outboxMaxRetryDelay = time.Hour
+	outboxBatchSize     = 50 // Emails retried per run of the worker
+)
+
+// send stores an email in the outbox and attempts it. When it fails for a reason that may pass, the
+// email stays in the outbox for the worker and no error is returned.
+func (s *ProviderEmailService) send(kind, toEmail, subject, textBody, htmlBody string) error {
+	if s.db == nil {
+		if err := s.deliver(toEmail, subject, textBody, htmlBody); err != nil {
+			return err
+		}
+		logger.L.Info("Email sent", "provider", s.sender.name(), "kind", kind, "to", toEmail)
+		return nil
+	}
+
+	now := time.Now()
+	email := models.OutboxEmail{
+		Kind:     kind,
+		ToEmail:  toEmail,
+		Subject:  subject,
+		TextBody: textBody,
+		HTMLBody: htmlBody,
+		// Keeps the worker away while the first attempt runs
+		NextAttemptAt: now.Add(outboxRetryDelay),
+		CreatedAt:     now,
+	}
+	id, err := model.InsertOutboxEmail(s.db, email)
+	if err != nil {
+		logger.L.Error("Failed to store email in the outbox, sending it without", "kind", kind, "to", toEmail, "error", err)
+		return s.deliver(toEmail, subject, textBody, htmlBody)
+	}
+	email.ID = id
+	_, err = s.attemptOutboxEmail(email)
+	return err
+}
+
+// attemptOutboxEmail delivers an outbox email and records the outcome, scheduling the next attempt
+// after a failure. It reports whether the email was sent, and returns an error only when it was
+// given up on.
+func (s *ProviderEmailService) attemptOutboxEmail(email models.OutboxEmail) (bool, error) {
+	err := s.deliver(email.ToEmail, email.Subject, email.TextBody, email.HTMLBody)
+	now := time.Now()
+	if err == nil {
+		if err := model.MarkOutboxEmailSent(s.db, email.ID, now); err != nil {
+			logger.L.Error("Failed to mark outbox email as sent", "outboxID", email.ID, "error", err)
+		}
+		logger.L.Info("Email sent", "provider", s.sender.name(), "kind", email.Kind, "to", email.ToEmail, "outboxID", email.ID)
+		return true, nil
+	}
+
+	attempts := email.Attempts + 1
+	status, nextAttemptAt := models.OutboxPending, now.Add(outboxBackoff(attempts))
+	var rejected *emailRejectedError
+	if errors.As(err, &rejected) || attempts >= outboxMaxAttempts {
+		status = models.OutboxFailed
+	}
+	if markErr := model.MarkOutboxEmailFailed(s.db, email.ID, status, err.Error(), nextAttemptAt); markErr != nil {
+		logger.L.Error("Failed to record outbox email attempt", "outboxID", email.ID, "error", markErr)
+	}
+	if status == models.OutboxFailed {
+		logger.L.Error("Gave up on email", "kind", email.Kind, "to", email.ToEmail, "outboxID", email.ID, "attempts", attempts)
+		return false, err
+	}
+	logger.L.Warn("Email kept in the outbox for a retry", "kind", email.Kind, "to", email.ToEmail, "outboxID", email.ID,
+		"attempts", attempts, "nextAttemptAt", nextAttemptAt)
+	return false, nil
+}
+
+// outboxBackoff is the wait before the attempt following the given number of failed ones.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxRetryDelay
+	for i := 1; i < attempts && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxRetryDelay)
+}
+
+// StartOutboxWorker retries the pending emails in the background.
+func (s *ProviderEmailService) StartOutboxWorker(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+	StartPeriodicJob("email-outbox", interval, func() {
+		if _, err := s.RetryOutbox(); err != nil {
+			logger.L.Error("Email outbox run failed", "error", err)
+		}
+	})
+}
+
+// RetryOutbox attempts the pending emails whose retry is due and returns how many were sent.
+func (s *ProviderEmailService) RetryOutbox() (int, error) {
+	emails, err := model.GetDueOutboxEmails(s.db, time.Now(), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, email := range emails {
+		if ok, _ := s.attemptOutboxEmail(email); ok {
+			sent++
+		}
+	}
+	if len(emails) > 0 {
+		logger.L.Info("Email outbox retried", "attempted", len(emails), "sent", sent)
+	}
+	return sent, nil
+}
+
+// GetOutboxEmails lists up to limit outbox emails, newest first, only those in status unless it is
+// empty.
+func (s *ProviderEmailService) GetOutboxEmails(status string, limit int) ([]models.OutboxEmail, error) {
+	if s.db == nil {
+		return []models.OutboxEmail{}, nil
+	}
+	return model.GetOutboxEmails(s.db, status, limit)
+}
+
+// RetryOutboxEmail queues a failed email again, for the worker's next run.
+func (s *ProviderEmailService) RetryOutboxEmail(id int64) error {
+	if s.db == nil {
+		return ErrOutboxEmailNotFound
+	}
+	err := model.RequeueOutboxEmail(s.db, id, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOutboxEmailNotFound
+	}
+	return err
+}
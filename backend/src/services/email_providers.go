@@ -0,0 +1,209 @@
+// backend/src/services/email_providers.go
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+// Many hosts block outbound SMTP, so emails can be sent through the HTTPS APIs of SendGrid or AWS SES
+// instead, with EMAIL_SERVICE_PROVIDER=sendgrid or ses.
+
+const (
+	emailMaxAttempts = 3
+	emailRetryDelay  = time.Second
+)
+
+// emailRejectedError is a failure the provider will repeat on a retry, such as a recipient it
+// refuses or wrong credentials.
+type emailRejectedError struct {
+	Status int // HTTP status, or SMTP reply code
+	Reason string
+}
+
+func (e *emailRejectedError) Error() string {
+	return fmt.Sprintf("email rejected with status %d: %s", e.Status, e.Reason)
+}
+
+// logRejectedEmail logs a rejection as a configuration error when the provider refused the
+// credentials, and otherwise as a bounce of the recipient.
+func logRejectedEmail(provider, toEmail string, rejected *emailRejectedError) {
+	switch rejected.Status {
+	case http.StatusUnauthorized, http.StatusForbidden, 530, 535:
+		logger.L.Error("Email provider refused the credentials", "provider", provider, "status", rejected.Status, "reason", rejected.Reason)
+	default:
+		logger.L.Warn("Email bounced", "provider", provider, "to", toEmail, "status", rejected.Status, "reason", rejected.Reason)
+	}
+}
+
+// checkEmailResponse turns an unsuccessful API response into an error: an emailRejectedError for
+// client errors, except 429, and a plain error, worth retrying, for rate limits and server errors.
+func checkEmailResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	reason := strings.TrimSpace(string(body))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &emailRejectedError{Status: resp.StatusCode, Reason: reason}
+	}
+	return fmt.Errorf("%s responded with status %d: %s", provider, resp.StatusCode, reason)
+}
+
+// sendGridSender sends emails through the SendGrid v3 Mail Send API.
+type sendGridSender struct {
+	apiKey      string
+	senderEmail string
+	senderName  string
+	baseURL     string
+	httpClient  http.Client
+}
+
+func newSendGridSender(apiKey, senderEmail, senderName string) *sendGridSender {
+	return &sendGridSender{
+		apiKey:      apiKey,
+		senderEmail: senderEmail,
+		senderName:  senderName,
+		baseURL:     "https://api.sendgrid.com",
+		httpClient:  http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *sendGridSender) name() string { return "sendgrid" }
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"` // Plain text must come before HTML
+}
+
+func (s *sendGridSender) send(toEmail, subject, textBody, htmlBody string) error {
+	message := sendGridMessage{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: toEmail}}}},
+		From:             sendGridAddress{Email: s.senderEmail, Name: s.senderName},
+		Subject:          subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: textBody}, {Type: "text/html", Value: htmlBody}},
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email via SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkEmailResponse("SendGrid", resp)
+}
+
+// sesSender sends emails through the AWS SES v2 SendEmail API, signing its requests with AWS
+// Signature Version 4.
+type sesSender struct {
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	from            string // Sender as an RFC 5322 address, with the sender name
+	baseURL         string
+	httpClient      http.Client
+}
+
+func newSESSender(region, accessKeyID, secretAccessKey, sessionToken, senderEmail, senderName string) *sesSender {
+	return &sesSender{
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		from:            (&mail.Address{Name: senderName, Address: senderEmail}).String(),
+		baseURL:         "https://email." + region + ".amazonaws.com",
+		httpClient:      http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *sesSender) name() string { return "ses" }
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesMessage struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent `json:"Text"`
+				Html sesContent `json:"Html"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+func (s *sesSender) send(toEmail, subject, textBody, htmlBody string) error {
+	var message sesMessage
+	message.FromEmailAddress = s.from
+	message.Destination.ToAddresses = []string{toEmail}
+	message.Content.Simple.Subject = sesContent{Data: subject, Charset: "UTF-8"}
+	message.Content.Simple.Body.Text = sesContent{Data: textBody, Charset: "UTF-8"}
+	message.Content.Simple.Body.Html = sesContent{Data: htmlBody, Charset: "UTF-8"}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, payload, time.Now().UTC())
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email via SES: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkEmailResponse("SES", resp)
+}
+
+// sign adds the AWS Signature Version 4 headers to a request to SES.
+func (s *sesSender) sign(req *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	creds := awsCredentials{accessKeyID: s.accessKeyID, secretAccessKey: s.secretAccessKey, sessionToken: s.sessionToken}
+	signAWSRequest(req, creds, "ses", s.region, hex.EncodeToString(payloadHash[:]), now)
+}
@@ -4,16 +4,22 @@ package services
 import (
 	"bytes"
 	"crypto/rand"
+	"database/sql"
+	"errors"
 	"fmt"
 	htmltemplate "html/template" // Corrected alias syntax
 	"log/slog"
 	"math/big"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 	texttemplate "text/template" // Corrected alias syntax
+	"time"
 
 	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
 )
 
 // EmailData holds the dynamic data for an email template.
@@ -21,6 +27,8 @@ type EmailData struct {
 	Username string
 	Link     string
 	Expiry   string
+	Upload   models.UploadSummary
+	Alerts   []string
 }
 
 // EmailTemplate defines the structure for an email template.
@@ -30,28 +38,106 @@ type EmailTemplate struct {
 	HTMLBody string
 }
 
-// Email templates are now centralized.
-var emailTemplates = map[string]EmailTemplate{
-	"verification": {
-		Subject:  "Confirme o seu endereço de e-mail para o VisorFinanceiro",
-		TextBody: `Olá {{.Username}}, Bem-vindo ao VisorFinanceiro! Por favor, confirme o seu endereço de e-mail clicando no link abaixo: {{.Link}} Se não criou uma conta com este endereço de e-mail, por favor ignore esta mensagem. Obrigado, A equipa do VisorFinanceiro`,
-		HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Bem-vindo ao VisorFinanceiro! Por favor, confirme o seu endereço de e-mail clicando no link abaixo:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Confirmar endereço de e-mail</a></p><p>Se o botão acima não funcionar, pode copiar e colar o seguinte URL na barra de endereços do seu navegador.</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Se não criou uma conta com este endereço de e-mail, por favor ignore este e-mail.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+// Email templates are centralized, per locale and then per template name.
+var emailTemplates = map[string]map[string]EmailTemplate{
+	i18n.PtPT: {
+		"verification": {
+			Subject:  "Confirme o seu endereço de e-mail para o VisorFinanceiro",
+			TextBody: `Olá {{.Username}}, Bem-vindo ao VisorFinanceiro! Por favor, confirme o seu endereço de e-mail clicando no link abaixo: {{.Link}} Se não criou uma conta com este endereço de e-mail, por favor ignore esta mensagem. Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Bem-vindo ao VisorFinanceiro! Por favor, confirme o seu endereço de e-mail clicando no link abaixo:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Confirmar endereço de e-mail</a></p><p>Se o botão acima não funcionar, pode copiar e colar o seguinte URL na barra de endereços do seu navegador.</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Se não criou uma conta com este endereço de e-mail, por favor ignore este e-mail.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
+		"passwordReset": {
+			Subject:  "Pedido de redefinição da palavra-passe para o VisorFinanceiro",
+			TextBody: `Olá {{.Username}}, Recebemos um pedido para repor a palavra-passe da sua conta VisorFinanceiro. Por favor, clique no seguinte link para repor a sua palavra-passe: {{.Link}} Se não pediu a reposição da palavra-passe, por favor ignore este e-mail. Este link expira em {{.Expiry}}. Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Recebemos um pedido para repor a palavra-passe da sua conta VisorFinanceiro. Por favor, clique no seguinte link para repor a sua palavra-passe:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Redefinir palavra-passe</a></p><p>Se o botão acima não funcionar, copie e cole este link no seu navegador:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Se não solicitou esta reposição, por favor ignore este e-mail. Este link irá expirar dentro de {{.Expiry}}.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
+		"accountLocked": {
+			Subject:  "A sua conta VisorFinanceiro foi bloqueada temporariamente",
+			TextBody: `Olá {{.Username}}, Registámos várias tentativas de início de sessão falhadas na sua conta VisorFinanceiro, pelo que bloqueámos temporariamente novos inícios de sessão. Se foi você, pode desbloquear a conta já através do seguinte link: {{.Link}} Este link expira em {{.Expiry}}. Se não foi você, recomendamos que altere a sua palavra-passe. Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Registámos várias tentativas de início de sessão falhadas na sua conta VisorFinanceiro, pelo que bloqueámos temporariamente novos inícios de sessão.</p><p>Se foi você, pode desbloquear a conta já:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Desbloquear conta</a></p><p>Se o botão acima não funcionar, copie e cole este link no seu navegador:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Este link irá expirar dentro de {{.Expiry}}. Se não foi você, recomendamos que altere a sua palavra-passe.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
+		"uploadProcessed": {
+			Subject:  "O seu ficheiro foi processado no VisorFinanceiro",
+			TextBody: `Olá {{.Username}}, O processamento do seu ficheiro {{.Upload.Source}} terminou. Transações importadas: {{.Upload.RowsImported}}. Duplicadas (já existentes): {{.Upload.Duplicates}}. Linhas ignoradas por descrição desconhecida: {{.Upload.Skipped}}. Pode consultar os resultados em: {{.Link}} Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>O processamento do seu ficheiro <strong>{{.Upload.Source}}</strong> terminou.</p><ul><li>Transações importadas: {{.Upload.RowsImported}}</li><li>Duplicadas (já existentes): {{.Upload.Duplicates}}</li><li>Linhas ignoradas por descrição desconhecida: {{.Upload.Skipped}}</li></ul><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Ver resultados</a></p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
+		"uploadFailed": {
+			Subject:  "Não foi possível processar o seu ficheiro no VisorFinanceiro",
+			TextBody: `Olá {{.Username}}, Não foi possível processar o seu ficheiro {{.Upload.Source}}. Motivo: {{.Upload.Error}} Verifique se exportou o ficheiro no formato correto e tente novamente em: {{.Link}} Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Não foi possível processar o seu ficheiro <strong>{{.Upload.Source}}</strong>.</p><p>Motivo: {{.Upload.Error}}</p><p>Verifique se exportou o ficheiro no formato correto e tente novamente.</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
+		"alerts": {
+			Subject:  "Alertas da sua carteira no VisorFinanceiro",
+			TextBody: `Olá {{.Username}}, Os seguintes alertas que definiu foram ativados:{{range .Alerts}} - {{.}}{{end}} Pode rever ou alterar os seus alertas em: {{.Link}} Obrigado, A equipa do VisorFinanceiro`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Os seguintes alertas que definiu foram ativados:</p><ul>{{range .Alerts}}<li>{{.}}</li>{{end}}</ul><p>Pode rever ou alterar os seus alertas em <a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a>.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+		},
 	},
-	"passwordReset": {
-		Subject:  "Pedido de redefinição da palavra-passe para o VisorFinanceiro",
-		TextBody: `Olá {{.Username}}, Recebemos um pedido para repor a palavra-passe da sua conta VisorFinanceiro. Por favor, clique no seguinte link para repor a sua palavra-passe: {{.Link}} Se não pediu a reposição da palavra-passe, por favor ignore este e-mail. Este link expira em {{.Expiry}}. Obrigado, A equipa do VisorFinanceiro`,
-		HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Olá {{.Username}},</p><p>Recebemos um pedido para repor a palavra-passe da sua conta VisorFinanceiro. Por favor, clique no seguinte link para repor a sua palavra-passe:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Redefinir palavra-passe</a></p><p>Se o botão acima não funcionar, copie e cole este link no seu navegador:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Se não solicitou esta reposição, por favor ignore este e-mail. Este link irá expirar dentro de {{.Expiry}}.</p><p>Obrigado,<br>A equipa do VisorFinanceiro</p></body></html>`,
+	i18n.EnUS: {
+		"verification": {
+			Subject:  "Confirm your email address for VisorFinanceiro",
+			TextBody: `Hello {{.Username}}, Welcome to VisorFinanceiro! Please confirm your email address by clicking the link below: {{.Link}} If you did not create an account with this email address, please ignore this message. Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>Welcome to VisorFinanceiro! Please confirm your email address by clicking the link below:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Confirm email address</a></p><p>If the button above does not work, copy and paste the following URL into your browser's address bar.</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>If you did not create an account with this email address, please ignore this email.</p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		"passwordReset": {
+			Subject:  "Password reset request for VisorFinanceiro",
+			TextBody: `Hello {{.Username}}, We received a request to reset the password of your VisorFinanceiro account. Please click the following link to reset your password: {{.Link}} If you did not request a password reset, please ignore this email. This link expires in {{.Expiry}}. Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>We received a request to reset the password of your VisorFinanceiro account. Please click the following link to reset your password:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Reset password</a></p><p>If the button above does not work, copy and paste this link into your browser:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>If you did not request this reset, please ignore this email. This link will expire in {{.Expiry}}.</p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		"accountLocked": {
+			Subject:  "Your VisorFinanceiro account was temporarily locked",
+			TextBody: `Hello {{.Username}}, We noticed several failed login attempts on your VisorFinanceiro account, so new logins are temporarily blocked. If this was you, you can unlock the account right away with the following link: {{.Link}} This link expires in {{.Expiry}}. If this was not you, we recommend changing your password. Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>We noticed several failed login attempts on your VisorFinanceiro account, so new logins are temporarily blocked.</p><p>If this was you, you can unlock the account right away:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">Unlock account</a></p><p>If the button above does not work, copy and paste this link into your browser:</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>This link will expire in {{.Expiry}}. If this was not you, we recommend changing your password.</p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		"uploadProcessed": {
+			Subject:  "Your file was processed on VisorFinanceiro",
+			TextBody: `Hello {{.Username}}, Your {{.Upload.Source}} file has finished processing. Transactions imported: {{.Upload.RowsImported}}. Duplicates (already imported): {{.Upload.Duplicates}}. Rows skipped for an unknown description: {{.Upload.Skipped}}. You can see the results at: {{.Link}} Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>Your <strong>{{.Upload.Source}}</strong> file has finished processing.</p><ul><li>Transactions imported: {{.Upload.RowsImported}}</li><li>Duplicates (already imported): {{.Upload.Duplicates}}</li><li>Rows skipped for an unknown description: {{.Upload.Skipped}}</li></ul><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8; text-decoration: none; font-weight: bold; padding: 10px 15px; border: 1px solid #1a73e8; border-radius: 4px; background-color: #e8f0fe;">See results</a></p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		"uploadFailed": {
+			Subject:  "Your file could not be processed on VisorFinanceiro",
+			TextBody: `Hello {{.Username}}, Your {{.Upload.Source}} file could not be processed. Reason: {{.Upload.Error}} Check that the file was exported in the right format and try again at: {{.Link}} Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>Your <strong>{{.Upload.Source}}</strong> file could not be processed.</p><p>Reason: {{.Upload.Error}}</p><p>Check that the file was exported in the right format and try again.</p><p><a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a></p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		"alerts": {
+			Subject:  "Alerts on your portfolio at VisorFinanceiro",
+			TextBody: `Hello {{.Username}}, The following alerts you set up were triggered:{{range .Alerts}} - {{.}}{{end}} You can review or change your alerts at: {{.Link}} Thank you, The VisorFinanceiro team`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>Hello {{.Username}},</p><p>The following alerts you set up were triggered:</p><ul>{{range .Alerts}}<li>{{.}}</li>{{end}}</ul><p>You can review or change your alerts at <a href="{{.Link}}" target="_blank" style="color: #1a73e8;">{{.Link}}</a>.</p><p>Thank you,<br>The VisorFinanceiro team</p></body></html>`,
+		},
+		// Sent to the operator, not to users, so only in English.
+		"operatorAlert": {
+			Subject:  "[VisorFinanceiro] Database integrity problems detected",
+			TextBody: `The scheduled database integrity check found problems:{{range .Alerts}} - {{.}}{{end}} The server logs give the full report and the users affected.`,
+			HTMLBody: `<html><body style="font-family: Arial, sans-serif; line-height: 1.6;"><p>The scheduled database integrity check found problems:</p><ul>{{range .Alerts}}<li>{{.}}</li>{{end}}</ul><p>The server logs give the full report and the users affected.</p></body></html>`,
+		},
 	},
 }
 
+// emailTemplate returns the named template in the locale, falling back to the default locale.
+func emailTemplate(locale, name string) EmailTemplate {
+	if template, ok := emailTemplates[locale][name]; ok {
+		return template
+	}
+	return emailTemplates[i18n.Default][name]
+}
+
 // EmailService defines the interface for sending emails.
 type EmailService interface {
-	SendVerificationEmail(toEmail, username, token string) error
-	SendPasswordResetEmail(toEmail, username, token string) error
+	SendVerificationEmail(toEmail, username, token, locale string) error
+	SendPasswordResetEmail(toEmail, username, token, locale string) error
+	SendAccountLockedEmail(toEmail, username, token, locale string) error
+	SendUploadSummaryEmail(toEmail, username string, summary models.UploadSummary, locale string) error
+	SendAlertEmail(toEmail, username string, alerts []string, locale string) error
+	SendOperatorAlertEmail(toEmail string, problems []string) error
+
+	// Outbox of the emails stored before they are sent
+	GetOutboxEmails(status string, limit int) ([]models.OutboxEmail, error)
+	RetryOutboxEmail(id int64) error
+	StartOutboxWorker(interval time.Duration)
 }
 
-// NewEmailService initializes the email service based on the configuration.
-func NewEmailService() EmailService {
+// NewEmailService initializes the email service based on the configuration. Emails are stored in the
+// outbox of db before they are sent.
+func NewEmailService(db *sql.DB) EmailService {
 	if config.Cfg == nil {
 		slog.Error("Configuration (config.Cfg) is nil. Email service will default to mock.")
 		return &MockEmailService{}
@@ -60,41 +146,192 @@ func NewEmailService() EmailService {
 	provider := strings.ToLower(config.Cfg.EmailServiceProvider)
 	logger.L.Info("Initializing email service", "provider", provider)
 
+	var sender emailSender
 	switch provider {
 	case "smtp":
 		if config.Cfg.SMTPServer == "" || config.Cfg.SMTPUser == "" || config.Cfg.SMTPPassword == "" || config.Cfg.SenderEmail == "" {
 			logger.L.Warn("SMTP configuration incomplete. Falling back to MockEmailService.")
 			return &MockEmailService{}
 		}
-		return &SMTPEmailService{
-			SMTPServer:               config.Cfg.SMTPServer,
-			SMTPPort:                 config.Cfg.SMTPPort,
-			SMTPUser:                 config.Cfg.SMTPUser,
-			SMTPPassword:             config.Cfg.SMTPPassword,
-			SenderEmail:              config.Cfg.SenderEmail,
-			VerificationEmailBaseURL: config.Cfg.VerificationEmailBaseURL,
-			PasswordResetBaseURL:     config.Cfg.PasswordResetBaseURL,
+		sender = &smtpSender{
+			server:      config.Cfg.SMTPServer,
+			port:        config.Cfg.SMTPPort,
+			user:        config.Cfg.SMTPUser,
+			password:    config.Cfg.SMTPPassword,
+			senderEmail: config.Cfg.SenderEmail,
+		}
+	case "sendgrid":
+		if config.Cfg.SendGridAPIKey == "" || config.Cfg.SenderEmail == "" {
+			logger.L.Warn("SendGrid configuration incomplete. Falling back to MockEmailService.")
+			return &MockEmailService{}
+		}
+		sender = newSendGridSender(config.Cfg.SendGridAPIKey, config.Cfg.SenderEmail, config.Cfg.SenderName)
+	case "ses":
+		if config.Cfg.AWSRegion == "" || config.Cfg.AWSAccessKeyID == "" || config.Cfg.AWSSecretAccessKey == "" || config.Cfg.SenderEmail == "" {
+			logger.L.Warn("SES configuration incomplete. Falling back to MockEmailService.")
+			return &MockEmailService{}
 		}
+		sender = newSESSender(config.Cfg.AWSRegion, config.Cfg.AWSAccessKeyID, config.Cfg.AWSSecretAccessKey,
+			config.Cfg.AWSSessionToken, config.Cfg.SenderEmail, config.Cfg.SenderName)
 	default:
 		logger.L.Info("Defaulting to MockEmailService.")
 		return &MockEmailService{}
 	}
+	return &ProviderEmailService{
+		db:                       db,
+		sender:                   sender,
+		retryDelay:               emailRetryDelay,
+		VerificationEmailBaseURL: config.Cfg.VerificationEmailBaseURL,
+		PasswordResetBaseURL:     config.Cfg.PasswordResetBaseURL,
+		AccountUnlockBaseURL:     config.Cfg.AccountUnlockBaseURL,
+		FrontendBaseURL:          config.Cfg.FrontendBaseURL,
+	}
 }
 
-// SMTPEmailService sends emails using SMTP.
-type SMTPEmailService struct {
-	SMTPServer               string
-	SMTPPort                 int
-	SMTPUser                 string
-	SMTPPassword             string
-	SenderEmail              string
+// emailSender delivers a rendered email through one provider.
+type emailSender interface {
+	name() string
+	send(toEmail, subject, textBody, htmlBody string) error
+}
+
+// ProviderEmailService renders the email templates and delivers them through the configured provider,
+// retrying failures that may be temporary.
+type ProviderEmailService struct {
+	db                       *sql.DB // Holds the outbox; emails are sent without one when nil
+	sender                   emailSender
+	retryDelay               time.Duration // Before the second attempt, doubling for each further one
 	VerificationEmailBaseURL string
 	PasswordResetBaseURL     string
+	AccountUnlockBaseURL     string
+	FrontendBaseURL          string
+}
+
+// deliver sends an email, making up to emailMaxAttempts attempts. Permanent failures, such as a
+// recipient the provider refuses, are logged as bounces and not retried.
+func (s *ProviderEmailService) deliver(toEmail, subject, textBody, htmlBody string) error {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; attempt <= emailMaxAttempts; attempt++ {
+		if err = s.sender.send(toEmail, subject, textBody, htmlBody); err == nil {
+			return nil
+		}
+		var rejected *emailRejectedError
+		if errors.As(err, &rejected) {
+			logRejectedEmail(s.sender.name(), toEmail, rejected)
+			return err
+		}
+		if attempt < emailMaxAttempts {
+			logger.L.Warn("Email delivery failed, retrying", "provider", s.sender.name(), "to", toEmail, "attempt", attempt, "retryIn", delay, "error", err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	logger.L.Error("Failed to send email", "provider", s.sender.name(), "to", toEmail, "attempts", emailMaxAttempts, "error", err)
+	return err
 }
 
+func (s *ProviderEmailService) SendVerificationEmail(toEmail, username, token, locale string) error {
+	template := emailTemplate(locale, "verification")
+	verificationLink := fmt.Sprintf("%s?token=%s", s.VerificationEmailBaseURL, token)
+	data := EmailData{Username: username, Link: verificationLink}
+
+	textBody, htmlBody, err := parseTemplates(template, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send("verification", toEmail, template.Subject, textBody, htmlBody)
+}
+
+func (s *ProviderEmailService) SendPasswordResetEmail(toEmail, username, token, locale string) error {
+	template := emailTemplate(locale, "passwordReset")
+	resetLink := fmt.Sprintf("%s?token=%s", s.PasswordResetBaseURL, token)
+	data := EmailData{
+		Username: username,
+		Link:     resetLink,
+		Expiry:   config.Cfg.PasswordResetTokenExpiry.String(),
+	}
+
+	textBody, htmlBody, err := parseTemplates(template, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send("passwordReset", toEmail, template.Subject, textBody, htmlBody)
+}
+
+// SendAccountLockedEmail tells the user their account was locked after repeated failed logins,
+// with a link that lifts the lock early.
+func (s *ProviderEmailService) SendAccountLockedEmail(toEmail, username, token, locale string) error {
+	template := emailTemplate(locale, "accountLocked")
+	data := EmailData{
+		Username: username,
+		Link:     fmt.Sprintf("%s?token=%s", s.AccountUnlockBaseURL, token),
+		Expiry:   config.Cfg.AccountUnlockTokenExpiry.String(),
+	}
+
+	textBody, htmlBody, err := parseTemplates(template, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send("accountLocked", toEmail, template.Subject, textBody, htmlBody)
+}
+
+// SendUploadSummaryEmail notifies the user that an uploaded file finished processing, or failed to.
+func (s *ProviderEmailService) SendUploadSummaryEmail(toEmail, username string, summary models.UploadSummary, locale string) error {
+	templateName := "uploadProcessed"
+	if summary.Error != "" {
+		templateName = "uploadFailed"
+	}
+	template := emailTemplate(locale, templateName)
+	data := EmailData{Username: username, Link: s.FrontendBaseURL, Upload: summary}
+
+	textBody, htmlBody, err := parseTemplates(template, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send(templateName, toEmail, template.Subject, textBody, htmlBody)
+}
+
+// SendAlertEmail tells the user which of their alert rules were triggered, one message per rule.
+func (s *ProviderEmailService) SendAlertEmail(toEmail, username string, alerts []string, locale string) error {
+	template := emailTemplate(locale, "alerts")
+	data := EmailData{Username: username, Link: s.FrontendBaseURL, Alerts: alerts}
+
+	textBody, htmlBody, err := parseTemplates(template, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send("alerts", toEmail, template.Subject, textBody, htmlBody)
+}
+
+// SendOperatorAlertEmail tells the operator about the problems a background check found.
+func (s *ProviderEmailService) SendOperatorAlertEmail(toEmail string, problems []string) error {
+	template := emailTemplate("en", "operatorAlert")
+	textBody, htmlBody, err := parseTemplates(template, EmailData{Alerts: problems})
+	if err != nil {
+		return err
+	}
+	return s.send("operatorAlert", toEmail, template.Subject, textBody, htmlBody)
+}
+
+// smtpSender sends emails using SMTP.
+type smtpSender struct {
+	server      string
+	port        int
+	user        string
+	password    string
+	senderEmail string
+}
+
+func (s *smtpSender) name() string { return "smtp" }
+
 // send method for SMTP now handles multipart (HTML + Text) emails.
-func (s *SMTPEmailService) send(toEmail, subject, textBody, htmlBody string) error {
-	from := s.SenderEmail
+func (s *smtpSender) send(toEmail, subject, textBody, htmlBody string) error {
+	from := s.senderEmail
 	to := []string{toEmail}
 
 	// Generate a unique boundary
@@ -133,56 +370,21 @@ func (s *SMTPEmailService) send(toEmail, subject, textBody, htmlBody string) err
 	msg.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
 
 	// Send the email
-	auth := smtp.PlainAuth("", s.SMTPUser, s.SMTPPassword, s.SMTPServer)
-	addr := fmt.Sprintf("%s:%d", s.SMTPServer, s.SMTPPort)
+	auth := smtp.PlainAuth("", s.user, s.password, s.server)
+	addr := fmt.Sprintf("%s:%d", s.server, s.port)
 	err := smtp.SendMail(addr, auth, from, to, msg.Bytes())
 
 	if err != nil {
-		logger.L.Error("Failed to send email via SMTP", "error", err, "to", toEmail)
+		// 5xx replies, such as 550 for an unknown mailbox, will not succeed on a retry.
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return &emailRejectedError{Status: reply.Code, Reason: reply.Msg}
+		}
 		return fmt.Errorf("failed to send email via SMTP: %w", err)
 	}
 	return nil
 }
 
-func (s *SMTPEmailService) SendVerificationEmail(toEmail, username, token string) error {
-	template := emailTemplates["verification"]
-	verificationLink := fmt.Sprintf("%s?token=%s", s.VerificationEmailBaseURL, token)
-	data := EmailData{Username: username, Link: verificationLink}
-
-	textBody, htmlBody, err := parseTemplates(template, data)
-	if err != nil {
-		return err
-	}
-
-	if err := s.send(toEmail, template.Subject, textBody, htmlBody); err != nil {
-		return err
-	}
-
-	logger.L.Info("Verification email sent successfully via SMTP", "to", toEmail)
-	return nil
-}
-
-func (s *SMTPEmailService) SendPasswordResetEmail(toEmail, username, token string) error {
-	template := emailTemplates["passwordReset"]
-	resetLink := fmt.Sprintf("%s?token=%s", s.PasswordResetBaseURL, token)
-	data := EmailData{
-		Username: username,
-		Link:     resetLink,
-		Expiry:   config.Cfg.PasswordResetTokenExpiry.String(),
-	}
-
-	textBody, htmlBody, err := parseTemplates(template, data)
-	if err != nil {
-		return err
-	}
-
-	if err := s.send(toEmail, template.Subject, textBody, htmlBody); err != nil {
-		return err
-	}
-	logger.L.Info("Password reset email sent successfully via SMTP", "to", toEmail)
-	return nil
-}
-
 // parseTemplates is a helper function to parse both text and HTML templates
 func parseTemplates(template EmailTemplate, data EmailData) (string, string, error) {
 	var textBody, htmlBody bytes.Buffer
@@ -211,17 +413,54 @@ func parseTemplates(template EmailTemplate, data EmailData) (string, string, err
 // MockEmailService is a mock implementation of EmailService for testing.
 type MockEmailService struct{}
 
-func (m *MockEmailService) SendVerificationEmail(toEmail, username, token string) error {
+func (m *MockEmailService) SendVerificationEmail(toEmail, username, token, locale string) error {
 	verificationLink := fmt.Sprintf("%s?token=%s", config.Cfg.VerificationEmailBaseURL, token)
 	logMsg := "MockEmailService: Would send verification email."
 	logger.L.Info(logMsg, "to", toEmail, "username", username, "verificationLink", verificationLink)
 	return nil
 }
 
-func (m *MockEmailService) SendPasswordResetEmail(toEmail, username, token string) error {
+func (m *MockEmailService) SendPasswordResetEmail(toEmail, username, token, locale string) error {
 	resetLink := fmt.Sprintf("%s?token=%s", config.Cfg.PasswordResetBaseURL, token)
 	expiry := config.Cfg.PasswordResetTokenExpiry.String()
 	logMsg := "MockEmailService: Would send password reset email."
 	logger.L.Info(logMsg, "to", toEmail, "username", username, "resetLink", resetLink, "expiresIn", expiry)
 	return nil
 }
+
+func (m *MockEmailService) SendAccountLockedEmail(toEmail, username, token, locale string) error {
+	unlockLink := fmt.Sprintf("%s?token=%s", config.Cfg.AccountUnlockBaseURL, token)
+	logMsg := "MockEmailService: Would send account locked email."
+	logger.L.Info(logMsg, "to", toEmail, "username", username, "unlockLink", unlockLink)
+	return nil
+}
+
+func (m *MockEmailService) SendUploadSummaryEmail(toEmail, username string, summary models.UploadSummary, locale string) error {
+	logMsg := "MockEmailService: Would send upload summary email."
+	logger.L.Info(logMsg, "to", toEmail, "username", username, "source", summary.Source,
+		"rowsImported", summary.RowsImported, "duplicates", summary.Duplicates, "skipped", summary.Skipped, "error", summary.Error)
+	return nil
+}
+
+func (m *MockEmailService) SendAlertEmail(toEmail, username string, alerts []string, locale string) error {
+	logMsg := "MockEmailService: Would send alert email."
+	logger.L.Info(logMsg, "to", toEmail, "username", username, "alerts", alerts)
+	return nil
+}
+
+func (m *MockEmailService) SendOperatorAlertEmail(toEmail string, problems []string) error {
+	logMsg := "MockEmailService: Would send operator alert email."
+	logger.L.Info(logMsg, "to", toEmail, "problems", problems)
+	return nil
+}
+
+// GetOutboxEmails returns no emails, since the mock sends none.
+func (m *MockEmailService) GetOutboxEmails(status string, limit int) ([]models.OutboxEmail, error) {
+	return []models.OutboxEmail{}, nil
+}
+
+func (m *MockEmailService) RetryOutboxEmail(id int64) error {
+	return ErrOutboxEmailNotFound
+}
+
+func (m *MockEmailService) StartOutboxWorker(interval time.Duration) {}
@@ -0,0 +1,116 @@
+// backend/src/services/encryption_service.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/security"
+)
+
+// ReencryptionReport counts the stored secrets a re-encryption run changed.
+type ReencryptionReport struct {
+	RanAt          time.Time `json:"ran_at"`
+	BrokerTokens   int       `json:"broker_tokens"`
+	RefreshTokens  int       `json:"refresh_tokens"`
+	WebhookSecrets int       `json:"webhook_secrets"`
+	Failed         int       `json:"failed"` // Values none of the keys could decrypt
+}
+
+type encryptionServiceImpl struct {
+	db      *sql.DB
+	keyring *security.Keyring
+}
+
+// NewEncryptionService creates a new EncryptionService for the secrets stored in db.
+func NewEncryptionService(db *sql.DB, keyring *security.Keyring) EncryptionService {
+	return &encryptionServiceImpl{db: db, keyring: keyring}
+}
+
+// ReencryptAll encrypts with the current key every stored secret encrypted with a previous key, and
+// encrypts the refresh tokens stored before they were. Once it reports no failures, the previous
+// keys can be removed from the configuration.
+func (s *encryptionServiceImpl) ReencryptAll() (*ReencryptionReport, error) {
+	report := &ReencryptionReport{RanAt: time.Now()}
+
+	tokens, err := model.GetBrokerTokens(s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read broker tokens: %w", err)
+	}
+	for _, token := range tokens {
+		if s.keyring.IsCurrent(token.Value) {
+			continue
+		}
+		plaintext, err := s.keyring.Decrypt(token.Value)
+		if err != nil {
+			logger.L.Error("Could not decrypt broker token with any key", "connectionID", token.ID, "error", err)
+			report.Failed++
+			continue
+		}
+		encrypted, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		if err := model.ReplaceBrokerToken(s.db, token.ID, token.Value, encrypted); err != nil {
+			return nil, fmt.Errorf("failed to store broker token %d: %w", token.ID, err)
+		}
+		report.BrokerTokens++
+	}
+
+	secrets, err := model.GetWebhookSecrets(s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook secrets: %w", err)
+	}
+	for _, secret := range secrets {
+		if s.keyring.IsCurrent(secret.Value) {
+			continue
+		}
+		plaintext, err := s.keyring.Decrypt(secret.Value)
+		if err != nil {
+			logger.L.Error("Could not decrypt webhook secret with any key", "webhookID", secret.ID, "error", err)
+			report.Failed++
+			continue
+		}
+		encrypted, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		if err := model.ReplaceWebhookSecret(s.db, secret.ID, secret.Value, encrypted); err != nil {
+			return nil, fmt.Errorf("failed to store secret of webhook %d: %w", secret.ID, err)
+		}
+		report.WebhookSecrets++
+	}
+
+	refreshTokens, err := model.GetSessionRefreshTokens(s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read refresh tokens: %w", err)
+	}
+	for _, token := range refreshTokens {
+		plaintext := token.Value
+		if !token.Plain {
+			if s.keyring.IsCurrent(token.Value) {
+				continue
+			}
+			if plaintext, err = s.keyring.Decrypt(token.Value); err != nil {
+				logger.L.Error("Could not decrypt refresh token with any key", "sessionID", token.ID, "error", err)
+				report.Failed++
+				continue
+			}
+		}
+		encrypted, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		if err := model.ReplaceSessionRefreshToken(s.db, token.ID, token.Value, encrypted, security.HashToken(plaintext)); err != nil {
+			return nil, fmt.Errorf("failed to store refresh token of session %d: %w", token.ID, err)
+		}
+		report.RefreshTokens++
+	}
+
+	logger.L.Info("Stored secrets re-encrypted", "brokerTokens", report.BrokerTokens,
+		"refreshTokens", report.RefreshTokens, "webhookSecrets", report.WebhookSecrets, "failed", report.Failed)
+	return report, nil
+}
@@ -0,0 +1,224 @@
+// backend/src/services/goal_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// ErrInvalidGoal is returned when a goal is of an unknown kind or its target is not valid.
+var ErrInvalidGoal = errors.New("invalid goal")
+
+const (
+	maxGoals          = 20  // Goals one user can define
+	maxGoalNameLength = 100 // Characters
+)
+
+type goalServiceImpl struct {
+	db                     *sql.DB
+	unrealizedGainsService UnrealizedGainsService
+	cashBalanceService     CashBalanceService
+}
+
+// NewGoalService creates a new GoalService.
+func NewGoalService(db *sql.DB, unrealizedGainsService UnrealizedGainsService, cashBalanceService CashBalanceService) GoalService {
+	return &goalServiceImpl{
+		db:                     db,
+		unrealizedGainsService: unrealizedGainsService,
+		cashBalanceService:     cashBalanceService,
+	}
+}
+
+func (s *goalServiceImpl) GetGoals(userID int64) ([]models.Goal, error) {
+	return model.GetGoals(s.db, userID)
+}
+
+func (s *goalServiceImpl) CreateGoal(userID int64, goal models.Goal) (*models.Goal, error) {
+	if err := validateGoal(&goal, time.Now()); err != nil {
+		return nil, err
+	}
+	count, err := model.CountGoals(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxGoals {
+		return nil, fmt.Errorf("%w: no more than %d goals are allowed", ErrInvalidGoal, maxGoals)
+	}
+	goal.UserID = userID
+	if err := model.CreateGoal(s.db, &goal); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (s *goalServiceImpl) UpdateGoal(userID, id int64, goal models.Goal) (*models.Goal, error) {
+	if err := validateGoal(&goal, time.Now()); err != nil {
+		return nil, err
+	}
+	goal.ID, goal.UserID = id, userID
+	if err := model.UpdateGoal(s.db, &goal); err != nil {
+		return nil, err
+	}
+	goals, err := model.GetGoals(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals {
+		if goals[i].ID == id {
+			return &goals[i], nil
+		}
+	}
+	return nil, model.ErrGoalNotFound
+}
+
+func (s *goalServiceImpl) DeleteGoal(userID, id int64) error {
+	return model.DeleteGoal(s.db, userID, id)
+}
+
+// validateGoal checks a goal has a positive target and a target date its kind accepts, and clears
+// what its kind does not use.
+func validateGoal(goal *models.Goal, now time.Time) error {
+	goal.Name = strings.TrimSpace(goal.Name)
+	if len(goal.Name) > maxGoalNameLength {
+		return fmt.Errorf("%w: the name must be at most %d characters", ErrInvalidGoal, maxGoalNameLength)
+	}
+	if goal.TargetAmount <= 0 {
+		return fmt.Errorf("%w: the target amount must be positive", ErrInvalidGoal)
+	}
+	goal.TargetAmount = utils.RoundMoney(goal.TargetAmount)
+	goal.TargetDate = strings.TrimSpace(goal.TargetDate)
+	switch goal.Kind {
+	case models.GoalPortfolioValue:
+		if goal.TargetDate == "" {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", goal.TargetDate); err != nil {
+			return fmt.Errorf("%w: the target date must be given as YYYY-MM-DD", ErrInvalidGoal)
+		}
+		if goal.TargetDate < now.Format("2006-01-02") {
+			return fmt.Errorf("%w: the target date must not be in the past", ErrInvalidGoal)
+		}
+	case models.GoalMonthlyContribution:
+		goal.TargetDate = ""
+	default:
+		return fmt.Errorf("%w: unknown kind %q, expected %s or %s", ErrInvalidGoal, goal.Kind,
+			models.GoalPortfolioValue, models.GoalMonthlyContribution)
+	}
+	return nil
+}
+
+// GetDashboard values the user's portfolio at current prices, sums up their contributions and measures
+// each of their goals against both.
+func (s *goalServiceImpl) GetDashboard(ctx context.Context, userID int64) (*models.Dashboard, error) {
+	goals, err := model.GetGoals(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	valuation, err := s.unrealizedGainsService.GetUnrealizedGains(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error valuing portfolio: %w", err)
+	}
+	contributions, err := s.cashBalanceService.GetContributions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error summing contributions: %w", err)
+	}
+
+	now := time.Now()
+	dashboard := &models.Dashboard{
+		AsOf:                    valuation.AsOf,
+		BaseCurrency:            contributions.BaseCurrency,
+		PortfolioValueEUR:       valuation.TotalMarketValueEUR,
+		CostBasisEUR:            valuation.TotalCostBasisEUR,
+		PriceStatus:             valuation.PriceStatus,
+		NetContributedEUR:       contributions.NetContributedEUR,
+		ContributedThisMonthEUR: contributedInMonth(contributions.Months, now.Format("2006-01")),
+		AverageMonthlyLast12EUR: contributions.AverageMonthlyLast12EUR,
+		Goals:                   make([]models.GoalProgress, 0, len(goals)),
+	}
+	for _, goal := range goals {
+		progress := models.GoalProgress{Goal: goal}
+		switch goal.Kind {
+		case models.GoalPortfolioValue:
+			measurePortfolioValueGoal(&progress, dashboard, now)
+		case models.GoalMonthlyContribution:
+			measureMonthlyContributionGoal(&progress, contributions.Months, now)
+		}
+		dashboard.Goals = append(dashboard.Goals, progress)
+	}
+	return dashboard, nil
+}
+
+// measurePortfolioValueGoal measures a portfolio value goal against the market value of the portfolio
+// and, when it has a target date, against what contributing as in the last 12 months would bring by then.
+func measurePortfolioValueGoal(progress *models.GoalProgress, dashboard *models.Dashboard, now time.Time) {
+	setProgress(progress, dashboard.PortfolioValueEUR)
+	targetDate, err := time.Parse("2006-01-02", progress.TargetDate)
+	if err != nil {
+		return
+	}
+	monthsLeft := max(0, (targetDate.Year()-now.Year())*12+int(targetDate.Month())-int(now.Month()))
+	projected := utils.RoundMoney(dashboard.PortfolioValueEUR + dashboard.AverageMonthlyLast12EUR*float64(monthsLeft))
+	onTrack := projected >= progress.TargetAmount
+	progress.MonthsLeft = &monthsLeft
+	progress.ProjectedAmount = &projected
+	progress.OnTrack = &onTrack
+	if monthsLeft > 0 {
+		required := utils.RoundMoney(progress.RemainingAmount / float64(monthsLeft))
+		progress.RequiredMonthly = &required
+	}
+}
+
+// measureMonthlyContributionGoal measures a monthly contribution goal against the net contributed this
+// month, and counts the earlier months in which the target was met.
+func measureMonthlyContributionGoal(progress *models.GoalProgress, months []models.MonthlyContribution, now time.Time) {
+	current := now.Format("2006-01")
+	setProgress(progress, contributedInMonth(months, current))
+
+	streak, metLast12, counted := 0, 0, 0
+	streakEnded := false
+	for i := len(months) - 1; i >= 0; i-- {
+		if months[i].Month >= current {
+			continue
+		}
+		met := months[i].NetEUR >= progress.TargetAmount
+		if !met {
+			streakEnded = true
+		} else if !streakEnded {
+			streak++
+		}
+		if counted < 12 {
+			counted++
+			if met {
+				metLast12++
+			}
+		}
+	}
+	progress.StreakMonths = &streak
+	progress.MonthsMetLast12 = &metLast12
+}
+
+// setProgress records how far current is from the goal's target.
+func setProgress(progress *models.GoalProgress, current float64) {
+	progress.CurrentAmount = current
+	progress.Progress = utils.RoundFloat(current/progress.TargetAmount, 4)
+	progress.RemainingAmount = utils.RoundMoney(max(0, progress.TargetAmount-current))
+	progress.Achieved = current >= progress.TargetAmount
+}
+
+// contributedInMonth returns the net contributed in month (YYYY-MM), or 0 without movements then.
+func contributedInMonth(months []models.MonthlyContribution, month string) float64 {
+	for i := len(months) - 1; i >= 0; i-- {
+		if months[i].Month == month {
+			return months[i].NetEUR
+		}
+	}
+	return 0
+}
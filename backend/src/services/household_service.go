@@ -0,0 +1,288 @@
+// backend/src/services/household_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+var (
+	// ErrInvalidHouseholdInvitation is returned when an invitation cannot be sent or accepted.
+	ErrInvalidHouseholdInvitation = errors.New("invalid household invitation")
+	// ErrHouseholdCurrencyMismatch is returned when the members of a household report in different
+	// base currencies, so their amounts cannot be added up.
+	ErrHouseholdCurrencyMismatch = errors.New("household members use different base currencies")
+)
+
+// A household groups two users filing jointly.
+const (
+	maxHouseholdMembers       = 2
+	maxPendingInvitations     = 5
+	householdInvitationExpiry = 14 * 24 * time.Hour
+)
+
+type householdServiceImpl struct {
+	db            *sql.DB
+	reportService ReportService
+	uploadService UploadService
+}
+
+// NewHouseholdService creates a new HouseholdService.
+func NewHouseholdService(db *sql.DB, reportService ReportService, uploadService UploadService) HouseholdService {
+	return &householdServiceImpl{
+		db:            db,
+		reportService: reportService,
+		uploadService: uploadService,
+	}
+}
+
+// GetHousehold returns the members of the user's household and the invitations the user sent or
+// received that are still pending.
+func (s *householdServiceImpl) GetHousehold(userID int64) (*models.Household, error) {
+	user, err := model.GetUserByID(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	household := &models.Household{Members: []models.HouseholdMember{}}
+	if householdID, err := model.GetHouseholdID(s.db, userID); err == nil {
+		if household.Members, err = model.GetHouseholdMembers(s.db, householdID); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, model.ErrNotInHousehold) {
+		return nil, err
+	}
+
+	now := time.Now()
+	if household.SentInvitations, err = model.GetSentHouseholdInvitations(s.db, userID, now); err != nil {
+		return nil, err
+	}
+	if household.ReceivedInvitations, err = model.GetReceivedHouseholdInvitations(s.db, strings.ToLower(user.Email), now); err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// Invite invites the owner of email to join the user's household. Nothing tells the user whether an
+// account exists for that address: the invitation waits for whoever registers or logs in with it.
+func (s *householdServiceImpl) Invite(userID int64, email string) (*models.HouseholdInvitation, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a valid email address", ErrInvalidHouseholdInvitation, email)
+	}
+	email = strings.ToLower(address.Address)
+
+	user, err := model.GetUserByID(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if email == strings.ToLower(user.Email) {
+		return nil, fmt.Errorf("%w: you cannot invite yourself", ErrInvalidHouseholdInvitation)
+	}
+	if householdID, err := model.GetHouseholdID(s.db, userID); err == nil {
+		members, err := model.GetHouseholdMembers(s.db, householdID)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) >= maxHouseholdMembers {
+			return nil, fmt.Errorf("%w: a household has at most %d members", ErrInvalidHouseholdInvitation, maxHouseholdMembers)
+		}
+	} else if !errors.Is(err, model.ErrNotInHousehold) {
+		return nil, err
+	}
+
+	now := time.Now()
+	pending, err := model.GetSentHouseholdInvitations(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range pending {
+		if inv.InviteeEmail == email {
+			return &inv, nil
+		}
+	}
+	if len(pending) >= maxPendingInvitations {
+		return nil, fmt.Errorf("%w: no more than %d invitations may be pending at once", ErrInvalidHouseholdInvitation, maxPendingInvitations)
+	}
+
+	inv := &models.HouseholdInvitation{
+		InviterID:       userID,
+		InviterUsername: user.Username,
+		InviteeEmail:    email,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(householdInvitationExpiry),
+	}
+	if err := model.CreateHouseholdInvitation(s.db, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// AcceptInvitation makes the user, who the invitation must have been sent to, join the inviter's
+// household.
+func (s *householdServiceImpl) AcceptInvitation(userID, invitationID int64) (*models.Household, error) {
+	inv, user, err := s.receivedInvitation(userID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := model.GetHouseholdID(s.db, user.ID); err == nil {
+		return nil, fmt.Errorf("%w: leave your current household first", ErrInvalidHouseholdInvitation)
+	} else if !errors.Is(err, model.ErrNotInHousehold) {
+		return nil, err
+	}
+
+	if err := model.JoinHousehold(s.db, *inv, user.ID, maxHouseholdMembers, time.Now()); err != nil {
+		if errors.Is(err, model.ErrHouseholdFull) {
+			return nil, fmt.Errorf("%w: the household already has %d members", ErrInvalidHouseholdInvitation, maxHouseholdMembers)
+		}
+		return nil, err
+	}
+	return s.GetHousehold(userID)
+}
+
+// DeleteInvitation cancels an invitation the user sent, or declines one the user received.
+func (s *householdServiceImpl) DeleteInvitation(userID, invitationID int64) error {
+	inv, err := model.GetHouseholdInvitation(s.db, invitationID, time.Now())
+	if err != nil {
+		return err
+	}
+	if inv.InviterID != userID {
+		if _, _, err := s.receivedInvitation(userID, invitationID); err != nil {
+			return err
+		}
+	}
+	return model.DeleteHouseholdInvitation(s.db, invitationID)
+}
+
+// receivedInvitation returns an invitation sent to the user's email, and the user.
+func (s *householdServiceImpl) receivedInvitation(userID, invitationID int64) (*models.HouseholdInvitation, *model.User, error) {
+	user, err := model.GetUserByID(s.db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	inv, err := model.GetHouseholdInvitation(s.db, invitationID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.InviteeEmail != strings.ToLower(user.Email) {
+		return nil, nil, model.ErrHouseholdInvitationNotFound
+	}
+	return inv, user, nil
+}
+
+// Leave takes the user out of their household. With two members, the household is dissolved.
+func (s *householdServiceImpl) Leave(userID int64) error {
+	return model.LeaveHousehold(s.db, userID)
+}
+
+// members returns the members of the user's household.
+func (s *householdServiceImpl) members(userID int64) ([]models.HouseholdMember, error) {
+	householdID, err := model.GetHouseholdID(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return model.GetHouseholdMembers(s.db, householdID)
+}
+
+// GetAnnualReport returns the annual report of every member of the user's household and their
+// combination: the line items of all members, with totals summed from the members' totals.
+func (s *householdServiceImpl) GetAnnualReport(ctx context.Context, userID int64, year string) (*models.HouseholdAnnualReport, error) {
+	members, err := s.members(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.HouseholdAnnualReport{Members: make([]models.HouseholdMemberReport, 0, len(members))}
+	for _, member := range members {
+		report, err := s.reportService.GetAnnualReport(ctx, member.UserID, year)
+		if err != nil {
+			return nil, fmt.Errorf("error computing the annual report of %s: %w", member.Username, err)
+		}
+		result.Members = append(result.Members, models.HouseholdMemberReport{Username: member.Username, Report: report})
+	}
+
+	combined := &models.AnnualReport{
+		Year:               year,
+		StockSales:         []models.SaleDetail{},
+		OptionSales:        []models.OptionSaleDetail{},
+		DividendsByCountry: map[string]models.DividendCountrySummary{},
+		Fees:               []models.FeeDetail{},
+		Interest:           []models.InterestDetail{},
+		Holdings:           []models.PurchaseLot{},
+	}
+	for i, member := range result.Members {
+		report := member.Report
+		if i == 0 {
+			combined.BaseCurrency = report.BaseCurrency
+		} else if report.BaseCurrency != combined.BaseCurrency {
+			return nil, ErrHouseholdCurrencyMismatch
+		}
+		combined.StockSales = append(combined.StockSales, report.StockSales...)
+		combined.StockGainsEUR = utils.RoundMoney(combined.StockGainsEUR + report.StockGainsEUR)
+		combined.OptionSales = append(combined.OptionSales, report.OptionSales...)
+		combined.OptionGainsEUR = utils.RoundMoney(combined.OptionGainsEUR + report.OptionGainsEUR)
+		for country, summary := range report.DividendsByCountry {
+			total := combined.DividendsByCountry[country]
+			total.GrossAmt = utils.RoundMoney(total.GrossAmt + summary.GrossAmt)
+			total.TaxedAmt = utils.RoundMoney(total.TaxedAmt + summary.TaxedAmt)
+			combined.DividendsByCountry[country] = total
+		}
+		combined.DividendGrossEUR = utils.RoundMoney(combined.DividendGrossEUR + report.DividendGrossEUR)
+		combined.DividendTaxEUR = utils.RoundMoney(combined.DividendTaxEUR + report.DividendTaxEUR)
+		combined.Fees = append(combined.Fees, report.Fees...)
+		combined.FeesEUR = utils.RoundMoney(combined.FeesEUR + report.FeesEUR)
+		combined.Interest = append(combined.Interest, report.Interest...)
+		combined.InterestEUR = utils.RoundMoney(combined.InterestEUR + report.InterestEUR)
+		combined.Holdings = append(combined.Holdings, report.Holdings...)
+	}
+	result.Combined = combined
+	return result, nil
+}
+
+// GetDividendTaxSummary adds up the dividend tax summaries of the members of the user's household,
+// by year and country.
+func (s *householdServiceImpl) GetDividendTaxSummary(ctx context.Context, userID int64) (models.DividendTaxResult, error) {
+	members, err := s.members(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	combined := models.DividendTaxResult{}
+	baseCurrency := ""
+	for i, member := range members {
+		currency, err := s.uploadService.GetBaseCurrency(member.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			baseCurrency = currency
+		} else if currency != baseCurrency {
+			return nil, ErrHouseholdCurrencyMismatch
+		}
+
+		summary, err := s.uploadService.GetDividendTaxSummary(ctx, member.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("error computing the dividend tax summary of %s: %w", member.Username, err)
+		}
+		for year, countries := range summary {
+			if combined[year] == nil {
+				combined[year] = map[string]models.DividendCountrySummary{}
+			}
+			for country, amounts := range countries {
+				total := combined[year][country]
+				total.GrossAmt = utils.RoundMoney(total.GrossAmt + amounts.GrossAmt)
+				total.TaxedAmt = utils.RoundMoney(total.TaxedAmt + amounts.TaxedAmt)
+				combined[year][country] = total
+			}
+		}
+	}
+	return combined, nil
+}
@@ -0,0 +1,270 @@
+// backend/src/services/ibkr_flex_service.go
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/security"
+)
+
+const (
+	brokerIBKR = "ibkr"
+
+	ibkrFlexBaseURL = "https://ndcdyn.interactivebrokers.com/AccountManagement/FlexWebService"
+	ibkrFlexVersion = "3"
+
+	// IBKR answers GetStatement with this code while the report is still being generated.
+	ibkrFlexInProgressCode = "1019"
+	ibkrFlexPollAttempts   = 10
+	ibkrFlexPollDelay      = 5 * time.Second
+)
+
+// ErrFlexStatementFailed is returned when the IBKR Flex Web Service rejects a request.
+var ErrFlexStatementFailed = errors.New("ibkr flex statement request failed")
+
+// ErrFlexSyncInProgress is returned when a sync is started while the user's previous one is running.
+var ErrFlexSyncInProgress = errors.New("ibkr flex sync already in progress")
+
+// flexStatementResponse is the envelope IBKR uses for SendRequest replies and GetStatement errors.
+type flexStatementResponse struct {
+	XMLName       xml.Name `xml:"FlexStatementResponse"`
+	Status        string   `xml:"Status"`
+	ReferenceCode string   `xml:"ReferenceCode"`
+	URL           string   `xml:"Url"`
+	ErrorCode     string   `xml:"ErrorCode"`
+	ErrorMessage  string   `xml:"ErrorMessage"`
+}
+
+type ibkrFlexServiceImpl struct {
+	db            *sql.DB
+	uploadService UploadService
+	keyring       *security.Keyring
+	httpClient    http.Client
+	pollDelay     time.Duration
+	maxBodyBytes  int64
+	syncing       sync.Map // User IDs whose sync is running
+}
+
+// NewIBKRFlexService creates a new IBKRFlexService. Flex tokens are encrypted at rest with keyring.
+func NewIBKRFlexService(db *sql.DB, uploadService UploadService, keyring *security.Keyring) IBKRFlexService {
+	return &ibkrFlexServiceImpl{
+		db:            db,
+		uploadService: uploadService,
+		keyring:       keyring,
+		httpClient:    http.Client{Timeout: 60 * time.Second},
+		pollDelay:     ibkrFlexPollDelay,
+		maxBodyBytes:  config.Cfg.MaxUploadSizeBytes,
+	}
+}
+
+// SaveConnection stores the user's Flex token and query ID, replacing any previous ones.
+func (s *ibkrFlexServiceImpl) SaveConnection(userID int64, token, queryID string) (*models.BrokerConnection, error) {
+	encrypted, err := s.keyring.Encrypt(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt flex token: %w", err)
+	}
+	conn := &models.BrokerConnection{
+		UserID:         userID,
+		Broker:         brokerIBKR,
+		EncryptedToken: encrypted,
+		QueryID:        queryID,
+	}
+	if err := model.UpsertBrokerConnection(s.db, conn); err != nil {
+		return nil, fmt.Errorf("failed to save flex connection: %w", err)
+	}
+	return model.GetBrokerConnection(s.db, userID, brokerIBKR)
+}
+
+// GetConnection returns the user's Flex connection without the token.
+func (s *ibkrFlexServiceImpl) GetConnection(userID int64) (*models.BrokerConnection, error) {
+	return model.GetBrokerConnection(s.db, userID, brokerIBKR)
+}
+
+// DeleteConnection removes the user's stored Flex credentials.
+func (s *ibkrFlexServiceImpl) DeleteConnection(userID int64) error {
+	return model.DeleteBrokerConnection(s.db, userID, brokerIBKR)
+}
+
+// SyncUser downloads the latest Flex statement for the user and imports it like a manual upload.
+func (s *ibkrFlexServiceImpl) SyncUser(userID int64) error {
+	if _, running := s.syncing.LoadOrStore(userID, true); running {
+		return ErrFlexSyncInProgress
+	}
+	defer s.syncing.Delete(userID)
+
+	conn, err := model.GetBrokerConnection(s.db, userID, brokerIBKR)
+	if err != nil {
+		return err
+	}
+	return s.syncConnection(conn)
+}
+
+// StartSync starts syncing the user in the background and returns the connection marked as running.
+// The outcome is recorded on the connection when the sync finishes.
+func (s *ibkrFlexServiceImpl) StartSync(userID int64) (*models.BrokerConnection, error) {
+	conn, err := model.GetBrokerConnection(s.db, userID, brokerIBKR)
+	if err != nil {
+		return nil, err
+	}
+	if _, running := s.syncing.LoadOrStore(userID, true); running {
+		return nil, ErrFlexSyncInProgress
+	}
+	if err := model.UpdateBrokerConnectionSync(s.db, userID, brokerIBKR, models.BrokerSyncRunning, ""); err != nil {
+		s.syncing.Delete(userID)
+		return nil, fmt.Errorf("failed to record flex sync start: %w", err)
+	}
+	go func() {
+		defer s.syncing.Delete(userID)
+		_ = s.syncConnection(conn)
+	}()
+	return model.GetBrokerConnection(s.db, userID, brokerIBKR)
+}
+
+// syncConnection syncs a connection and records the outcome on it.
+func (s *ibkrFlexServiceImpl) syncConnection(conn *models.BrokerConnection) error {
+	userID := conn.UserID
+
+	syncErr := s.sync(conn)
+	status, message := models.BrokerSyncOK, ""
+	if syncErr != nil {
+		status, message = models.BrokerSyncFailed, syncErr.Error()
+		logger.L.Warn("IBKR Flex sync failed", "userID", userID, "error", syncErr)
+	} else {
+		logger.L.Info("IBKR Flex sync finished", "userID", userID)
+	}
+	if err := model.UpdateBrokerConnectionSync(s.db, userID, brokerIBKR, status, message); err != nil {
+		logger.L.Error("Failed to record IBKR Flex sync status", "userID", userID, "error", err)
+	}
+	return syncErr
+}
+
+// SyncAll syncs every connected user in turn. Failures are recorded per connection.
+func (s *ibkrFlexServiceImpl) SyncAll() {
+	conns, err := model.GetBrokerConnectionsByBroker(s.db, brokerIBKR)
+	if err != nil {
+		logger.L.Error("Failed to list IBKR Flex connections", "error", err)
+		return
+	}
+	for _, conn := range conns {
+		_ = s.SyncUser(conn.UserID)
+	}
+}
+
+// StartScheduler periodically pulls the Flex statements of all connected users.
+func (s *ibkrFlexServiceImpl) StartScheduler(interval time.Duration) {
+	StartPeriodicJob("ibkr-flex-sync", interval, s.SyncAll)
+}
+
+func (s *ibkrFlexServiceImpl) sync(conn *models.BrokerConnection) error {
+	token, err := s.keyring.Decrypt(conn.EncryptedToken)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt flex token: %w", err)
+	}
+	statement, err := s.fetchStatement(token, conn.QueryID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.uploadService.ProcessUpload(bytes.NewReader(statement), conn.UserID, brokerIBKR, "", ""); err != nil {
+		return fmt.Errorf("failed to import flex statement: %w", err)
+	}
+	return nil
+}
+
+// fetchStatement runs the two-step Flex Web Service flow: SendRequest returns a reference code,
+// then GetStatement is polled until the report is ready.
+func (s *ibkrFlexServiceImpl) fetchStatement(token, queryID string) ([]byte, error) {
+	params := url.Values{"t": {token}, "q": {queryID}, "v": {ibkrFlexVersion}}
+	body, err := s.get(ibkrFlexBaseURL+"/SendRequest?"+params.Encode(), "ibkr_flex_send_request")
+	if err != nil {
+		return nil, err
+	}
+	var sendResp flexStatementResponse
+	if err := xml.Unmarshal(body, &sendResp); err != nil {
+		return nil, fmt.Errorf("failed to parse flex SendRequest response: %w", err)
+	}
+	if !strings.EqualFold(sendResp.Status, "Success") || sendResp.ReferenceCode == "" {
+		return nil, fmt.Errorf("%w: %s (code %s)", ErrFlexStatementFailed, sendResp.ErrorMessage, sendResp.ErrorCode)
+	}
+
+	statementURL := ibkrFlexBaseURL + "/GetStatement"
+	if sendResp.URL != "" {
+		// The token is sent to this URL, so it must be IBKR's own.
+		if err := checkFlexStatementURL(sendResp.URL); err != nil {
+			return nil, err
+		}
+		statementURL = sendResp.URL
+	}
+	params = url.Values{"t": {token}, "q": {sendResp.ReferenceCode}, "v": {ibkrFlexVersion}}
+	for attempt := 0; attempt < ibkrFlexPollAttempts; attempt++ {
+		time.Sleep(s.pollDelay)
+		body, err := s.get(statementURL+"?"+params.Encode(), "ibkr_flex_get_statement")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(body, []byte("<FlexStatementResponse")) {
+			return body, nil
+		}
+		var stmtResp flexStatementResponse
+		if err := xml.Unmarshal(body, &stmtResp); err != nil {
+			return nil, fmt.Errorf("failed to parse flex GetStatement response: %w", err)
+		}
+		if stmtResp.ErrorCode != ibkrFlexInProgressCode {
+			return nil, fmt.Errorf("%w: %s (code %s)", ErrFlexStatementFailed, stmtResp.ErrorMessage, stmtResp.ErrorCode)
+		}
+	}
+	return nil, fmt.Errorf("%w: statement not ready after %d attempts", ErrFlexStatementFailed, ibkrFlexPollAttempts)
+}
+
+// checkFlexStatementURL accepts the GetStatement URL of a SendRequest reply only over HTTPS on the
+// host of the Flex Web Service.
+func checkFlexStatementURL(statementURL string) error {
+	base, _ := url.Parse(ibkrFlexBaseURL)
+	u, err := url.Parse(statementURL)
+	if err != nil || u.Scheme != "https" || !strings.EqualFold(u.Host, base.Host) || u.User != nil {
+		return fmt.Errorf("%w: unexpected statement URL %q", ErrFlexStatementFailed, statementURL)
+	}
+	return nil
+}
+
+func (s *ibkrFlexServiceImpl) get(requestURL, api string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Rumoclaro/1.0")
+	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall(api, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("flex web service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("flex web service returned status %d", resp.StatusCode)
+	}
+	if s.maxBodyBytes <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > s.maxBodyBytes {
+		return nil, fmt.Errorf("%w: statement larger than %d bytes", ErrFlexStatementFailed, s.maxBodyBytes)
+	}
+	return body, nil
+}
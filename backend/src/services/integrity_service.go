@@ -0,0 +1,275 @@
+// backend/src/services/integrity_service.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+)
+
+// Names of the per-user count reconciliations.
+const (
+	// CountCheckUploads flags users with transactions but no upload counted.
+	CountCheckUploads = "upload_count"
+	// CountCheckReport flags materialized reports built from a number of transactions the user no
+	// longer has up to the last transaction they include.
+	CountCheckReport = "report_transaction_count"
+)
+
+// CountMismatch is a user whose stored transactions disagree with a count kept of them.
+type CountMismatch struct {
+	UserID   int64  `json:"user_id"`
+	Check    string `json:"check"`
+	Expected int    `json:"expected"` // What the counter or report holds
+	Actual   int    `json:"actual"`   // Transactions stored
+	Delta    int    `json:"delta"`    // Actual less expected
+}
+
+// IntegrityReport summarises the outcome of a database integrity check run.
+type IntegrityReport struct {
+	CheckedAt            time.Time       `json:"checked_at"`
+	IntegrityOK          bool            `json:"integrity_ok"`
+	IntegrityMessages    []string        `json:"integrity_messages"`
+	ForeignKeyViolations int             `json:"foreign_key_violations"`
+	OrphanSessions       int             `json:"orphan_sessions"`
+	OrphanTransactions   int             `json:"orphan_transactions"`
+	StaleReportRows      int             `json:"stale_report_rows"`
+	CountMismatches      []CountMismatch `json:"count_mismatches"`
+}
+
+// HasProblems reports whether any check in the report failed.
+func (r *IntegrityReport) HasProblems() bool {
+	return !r.IntegrityOK || r.ForeignKeyViolations > 0 || r.OrphanSessions > 0 ||
+		r.OrphanTransactions > 0 || r.StaleReportRows > 0 || len(r.CountMismatches) > 0
+}
+
+// problemCounts counts the problems found by each check, by the check's name.
+func (r *IntegrityReport) problemCounts() map[string]int {
+	counts := map[string]int{
+		"integrity_check":        0,
+		"foreign_key_violations": r.ForeignKeyViolations,
+		"orphan_sessions":        r.OrphanSessions,
+		"orphan_transactions":    r.OrphanTransactions,
+		"stale_report_rows":      r.StaleReportRows,
+		CountCheckUploads:        0,
+		CountCheckReport:         0,
+	}
+	if !r.IntegrityOK {
+		counts["integrity_check"] = len(r.IntegrityMessages)
+	}
+	for _, mismatch := range r.CountMismatches {
+		counts[mismatch.Check]++
+	}
+	return counts
+}
+
+// problems describes each check that found problems, in a stable order.
+func (r *IntegrityReport) problems() []string {
+	counts := r.problemCounts()
+	var problems []string
+	if !r.IntegrityOK {
+		problems = append(problems, "integrity_check: "+strings.Join(r.IntegrityMessages, "; "))
+	}
+	for _, check := range []string{"foreign_key_violations", "orphan_sessions", "orphan_transactions", "stale_report_rows"} {
+		if count := counts[check]; count > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d rows", check, count))
+		}
+	}
+	for _, check := range []string{CountCheckUploads, CountCheckReport} {
+		if count := counts[check]; count > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d users", check, count))
+		}
+	}
+	return problems
+}
+
+type integrityServiceImpl struct {
+	db            *sql.DB
+	emailService  EmailService
+	operatorEmail string
+
+	alertMu   sync.Mutex
+	lastAlert string // Problems the operator was last alerted about, to alert again only when they change
+}
+
+// NewIntegrityService creates a new IntegrityService bound to the given database. Problems found are
+// emailed to operatorEmail, unless it is empty.
+func NewIntegrityService(db *sql.DB, emailService EmailService, operatorEmail string) IntegrityService {
+	return &integrityServiceImpl{db: db, emailService: emailService, operatorEmail: operatorEmail}
+}
+
+// StartScheduler runs the integrity checks periodically in the background.
+func (s *integrityServiceImpl) StartScheduler(interval time.Duration) {
+	StartPeriodicJob("integrity-check", interval, func() {
+		if _, err := s.RunChecks(); err != nil {
+			logger.L.Error("Scheduled integrity check failed to run", "error", err)
+		}
+	})
+}
+
+// RunChecks executes all integrity checks, exports the problems found by each as a metric, logs them
+// and alerts the operator about them.
+func (s *integrityServiceImpl) RunChecks() (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		CheckedAt:         time.Now(),
+		IntegrityMessages: []string{},
+		CountMismatches:   []CountMismatch{},
+	}
+
+	// 1. SQLite's own structural check of the database file. PostgreSQL has no equivalent, and always
+	// enforces the foreign keys checked in step 2.
+	report.IntegrityOK, report.IntegrityMessages = true, []string{"ok"}
+	var err error
+	if !database.IsPostgres() {
+		messages, err := s.integrityCheck()
+		if err != nil {
+			return nil, err
+		}
+		report.IntegrityMessages = messages
+		report.IntegrityOK = len(messages) == 1 && messages[0] == "ok"
+
+		// 2. Declared foreign keys (only enforced when PRAGMA foreign_keys is on, so check explicitly).
+		if report.ForeignKeyViolations, err = s.countRows(`SELECT COUNT(*) FROM pragma_foreign_key_check`); err != nil {
+			return nil, fmt.Errorf("foreign key check failed: %w", err)
+		}
+	}
+
+	// 3. Orphan rows referencing deleted users.
+	if report.OrphanSessions, err = s.countRows(`SELECT COUNT(*) FROM sessions WHERE user_id NOT IN (SELECT id FROM users)`); err != nil {
+		return nil, fmt.Errorf("orphan sessions check failed: %w", err)
+	}
+	if report.OrphanTransactions, err = s.countRows(`SELECT COUNT(*) FROM processed_transactions WHERE user_id NOT IN (SELECT id FROM users)`); err != nil {
+		return nil, fmt.Errorf("orphan transactions check failed: %w", err)
+	}
+
+	// 4. Materialized report rows left behind by an older data version.
+	if report.StaleReportRows, err = s.countRows(`
+		SELECT
+			(SELECT COUNT(*) FROM report_stock_sales rs LEFT JOIN report_versions rv ON rv.user_id = rs.user_id
+			 WHERE rv.data_version IS NULL OR rv.data_version != rs.data_version) +
+			(SELECT COUNT(*) FROM report_stock_holdings rh LEFT JOIN report_versions rv ON rv.user_id = rh.user_id
+			 WHERE rv.data_version IS NULL OR rv.data_version != rh.data_version) +
+			(SELECT COUNT(*) FROM report_open_lots ro LEFT JOIN report_versions rv ON rv.user_id = ro.user_id
+			 WHERE rv.data_version IS NULL OR rv.data_version != ro.data_version)`); err != nil {
+		return nil, fmt.Errorf("stale report rows check failed: %w", err)
+	}
+
+	// 5. Per-user counters and materialized reports reconciled against the stored transactions.
+	if report.CountMismatches, err = s.reconcileUserCounts(); err != nil {
+		return nil, fmt.Errorf("user count reconciliation failed: %w", err)
+	}
+	for _, mismatch := range report.CountMismatches {
+		logger.L.Error("User transaction count mismatch", "userID", mismatch.UserID, "check", mismatch.Check,
+			"expected", mismatch.Expected, "actual", mismatch.Actual, "delta", mismatch.Delta)
+	}
+
+	if report.HasProblems() {
+		logger.L.Error("Database integrity problems detected",
+			"integrityOK", report.IntegrityOK,
+			"integrityMessages", report.IntegrityMessages,
+			"foreignKeyViolations", report.ForeignKeyViolations,
+			"orphanSessions", report.OrphanSessions,
+			"orphanTransactions", report.OrphanTransactions,
+			"staleReportRows", report.StaleReportRows,
+			"countMismatches", len(report.CountMismatches))
+	} else {
+		logger.L.Info("Database integrity check passed")
+	}
+	for check, count := range report.problemCounts() {
+		metrics.IntegrityProblems(check, count)
+	}
+	s.alertOperator(report.problems())
+	return report, nil
+}
+
+// alertOperator emails the problems to the operator when they differ from those last alerted about.
+// Once the problems are gone, the next ones are alerted about even if they are the same again.
+func (s *integrityServiceImpl) alertOperator(problems []string) {
+	s.alertMu.Lock()
+	defer s.alertMu.Unlock()
+	summary := strings.Join(problems, "\n")
+	if summary == s.lastAlert {
+		return
+	}
+	if len(problems) > 0 && s.operatorEmail != "" {
+		if err := s.emailService.SendOperatorAlertEmail(s.operatorEmail, problems); err != nil {
+			logger.L.Error("Failed to alert the operator about integrity problems", "error", err)
+			return
+		}
+	}
+	s.lastAlert = summary
+}
+
+func (s *integrityServiceImpl) integrityCheck() ([]string, error) {
+	rows, err := s.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("integrity_check failed: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, fmt.Errorf("error scanning integrity_check row: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// reconcileUserCounts flags users whose upload counter or materialized report disagrees with their
+// stored transactions.
+func (s *integrityServiceImpl) reconcileUserCounts() ([]CountMismatch, error) {
+	mismatches := []CountMismatch{}
+	queries := []struct {
+		check string
+		query string // Selects the user, the count kept and the transactions stored
+	}{
+		{CountCheckUploads, `
+			SELECT u.id, 0, COUNT(pt.id)
+			FROM users u
+			LEFT JOIN processed_transactions pt ON pt.user_id = u.id
+			GROUP BY u.id, u.upload_count
+			HAVING (u.upload_count = 0 AND COUNT(pt.id) > 0)`},
+		{CountCheckReport, `
+			SELECT rv.user_id, rv.transaction_count, COUNT(pt.id)
+			FROM report_versions rv
+			LEFT JOIN processed_transactions pt ON pt.user_id = rv.user_id AND pt.id <= rv.last_transaction_id
+			GROUP BY rv.user_id, rv.transaction_count
+			HAVING COUNT(pt.id) != rv.transaction_count`},
+	}
+	for _, q := range queries {
+		rows, err := s.db.Query(q.query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", q.check, err)
+		}
+		for rows.Next() {
+			mismatch := CountMismatch{Check: q.check}
+			if err := rows.Scan(&mismatch.UserID, &mismatch.Expected, &mismatch.Actual); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			mismatch.Delta = mismatch.Actual - mismatch.Expected
+			mismatches = append(mismatches, mismatch)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
+func (s *integrityServiceImpl) countRows(query string) (int, error) {
+	var count int
+	err := s.db.QueryRow(query).Scan(&count)
+	return count, err
+}
@@ -2,8 +2,11 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/username/taxfolio/backend/src/models"
 )
@@ -18,35 +21,266 @@ type UploadResult struct {
 	CashMovements            []models.CashMovement           `json:"CashMovements"`
 	DividendTransactionsList []models.ProcessedTransaction   `json:"DividendTransactionsList"`
 	FeeDetails               []models.FeeDetail              `json:"FeeDetails"`
+	Replayed                 bool                            `json:"-"` // Answered from an earlier upload with the same idempotency key
+}
+
+// UploadFile is one statement of a multi-file upload, such as an entry of a ZIP archive.
+type UploadFile struct {
+	Name   string
+	Source string // Upload source whose parser reads the file
+	Data   []byte
 }
 
 // Define common service errors
 var (
-	ErrParsingFailed    = errors.New("csv parsing failed")
-	ErrProcessingFailed = errors.New("transaction processing failed")
+	ErrParsingFailed         = errors.New("csv parsing failed")
+	ErrProcessingFailed      = errors.New("transaction processing failed")
+	ErrInvalidCSVMapping     = errors.New("invalid csv mapping")
+	ErrUnsupportedCurrency   = errors.New("unsupported base currency")
+	ErrInvalidOpeningLot     = errors.New("invalid opening lot")
+	ErrInvalidManualEntry    = errors.New("invalid manual transaction")
+	ErrQuotaExceeded         = errors.New("plan quota exceeded")
+	ErrUnknownPlan           = errors.New("unknown plan")
+	ErrInvalidFiscalYear     = errors.New("invalid fiscal year start")
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key already used for a different upload")
+	ErrUploadInProgress      = errors.New("an upload with this idempotency key is still being processed")
+	ErrInvalidSettings       = errors.New("invalid settings")
+	ErrPositionNotFound      = errors.New("no transactions for this ISIN")
+	ErrOutboxEmailNotFound   = errors.New("no failed email with this ID in the outbox")
 )
 
 // UploadService defines the interface for the core upload processing logic.
 type UploadService interface {
-	ProcessUpload(fileReader io.Reader, userID int64, source string) (*UploadResult, error)
-	GetLatestUploadResult(userID int64) (*UploadResult, error)
-	GetDividendTaxSummary(userID int64) (models.DividendTaxResult, error)
-	GetDividendTransactions(userID int64) ([]models.ProcessedTransaction, error)
-	GetStockHoldings(userID int64) (map[string][]models.PurchaseLot, error)
-	GetOptionHoldings(userID int64) ([]models.OptionHolding, error)
-	GetStockSaleDetails(userID int64) ([]models.SaleDetail, error)
-	GetOptionSaleDetails(userID int64) ([]models.OptionSaleDetail, error)
-	GetFeeDetails(userID int64) ([]models.FeeDetail, error)
+	ProcessUpload(fileReader io.Reader, userID int64, source, filename, idempotencyKey string) (*UploadResult, error)
+	ProcessArchiveUpload(files []UploadFile, userID int64, source, filename, idempotencyKey string) (*UploadResult, error)
+	PreviewUpload(files []UploadFile, userID int64, source string) (*models.UploadPreview, error)
+	GetLatestUploadResult(ctx context.Context, userID int64) (*UploadResult, error)
+	GetUploadHistory(userID int64) ([]models.UploadHistoryEntry, error)
+	GetDividendTaxSummary(ctx context.Context, userID int64) (models.DividendTaxResult, error)
+	GetDividendTransactions(ctx context.Context, userID int64) ([]models.ProcessedTransaction, error)
+	GetDividendDetail(ctx context.Context, userID int64, year, country string) (models.DividendDetail, error)
+	GetStockHoldings(ctx context.Context, userID int64) (map[string][]models.PurchaseLot, error)
+	GetStockHoldingsForYear(ctx context.Context, userID int64, year string) ([]models.PurchaseLot, error)
+	GetHoldingYears(ctx context.Context, userID int64) ([]string, error)
+	GetCostBasisAdjustments(ctx context.Context, userID int64) ([]models.CostBasisAdjustment, error)
+	GetPositionTimeline(ctx context.Context, userID int64, isin string) (*models.PositionTimeline, error)
+	GetOptionHoldings(ctx context.Context, userID int64) ([]models.OptionHolding, error)
+	GetStockSaleDetails(ctx context.Context, userID int64) ([]models.SaleDetail, error)
+	GetOptionSaleDetails(ctx context.Context, userID int64) ([]models.OptionSaleDetail, error)
+	GetFeeDetails(ctx context.Context, userID int64) ([]models.FeeDetail, error)
+	GetSkippedTransactions(userID int64) ([]models.SkippedTransaction, error)
+	ReprocessSkippedTransactions(userID int64) (*models.UploadSummary, error)
+	ImportSkippedTransactionManually(userID, id int64, entry models.ManualTransaction) (*models.UploadSummary, error)
+	GetUnknownDescriptions(limit int) ([]models.UnknownDescription, error)
+	GetCSVMapping(userID int64) (*models.CSVMapping, error)
+	SaveCSVMapping(userID int64, mapping models.CSVMapping) error
+	GetBaseCurrency(userID int64) (string, error)
+	SetBaseCurrency(userID int64, currency string) error
+	GetFiscalYear(userID int64) (string, error)
+	SetFiscalYear(userID int64, start string) error
+	AddOpeningLots(userID int64, lots []models.OpeningLot) (*models.UploadSummary, error)
 	InvalidateUserCache(userID int64)
+	GetDataVersion(userID int64) (int64, error)
 }
 
 type PriceInfo struct {
 	Status   string  // "OK" or "UNAVAILABLE"
-	Price    float64 // Price in EUR
-	Currency string  // Should always be "EUR" in the final result
+	Price    float64 // Price in the requested base currency
+	Currency string  // The requested base currency
 }
 
 // PriceService defines the interface for fetching current market prices.
 type PriceService interface {
-	GetCurrentPrices(isins []string) (map[string]PriceInfo, error)
+	GetCurrentPrices(isins []string, baseCurrency string) (map[string]PriceInfo, error)
+	GetPriceOnDate(isin string, date time.Time, baseCurrency string) (PriceInfo, error)
+}
+
+// IntegrityService defines the interface for database consistency checks.
+type IntegrityService interface {
+	RunChecks() (*IntegrityReport, error)
+	StartScheduler(interval time.Duration)
+}
+
+// MaintenanceService defines the interface for pruning expired sessions and tokens and backing up the database.
+type MaintenanceService interface {
+	RunCleanup() (*MaintenanceReport, error)
+	StartScheduler(interval time.Duration)
+	RunBackup(ctx context.Context) (*BackupReport, error)
+	StartBackupScheduler(interval time.Duration)
+}
+
+// EncryptionService defines the interface for re-encrypting stored secrets with the current key.
+type EncryptionService interface {
+	ReencryptAll() (*ReencryptionReport, error)
+}
+
+// QuotaService defines the interface for plan limits and usage tracking.
+type QuotaService interface {
+	GetUsage(userID int64) (*models.Usage, error)
+	CheckUpload(userID int64) error
+	RecordUpload(userID int64) error
+	CheckTransactionLimit(dbTx *sql.Tx, userID int64) error
+	SetPlan(userID int64, plan string) error
+}
+
+// StatusService defines the interface for the public service status and admin announcements.
+type StatusService interface {
+	GetStatus() (*models.ServiceStatus, error)
+	SetAnnouncement(req models.AnnouncementRequest) (*models.Announcement, error)
+	ClearAnnouncement() error
+}
+
+// BillingService defines the interface for paid subscriptions and premium access.
+type BillingService interface {
+	Enabled() bool
+	HasPremiumAccess(userID int64) (bool, error)
+	GetPlans() ([]models.BillingPlan, error)
+	GetSubscription(userID int64) (*models.Subscription, error)
+	SetPlanPrice(plan, priceID string) error
+	CreateCheckoutSession(userID int64, plan string) (string, error)
+	HandleWebhook(payload []byte, signatureHeader string) error
+}
+
+// PerformanceService defines the interface for portfolio return calculations.
+type PerformanceService interface {
+	GetPerformance(ctx context.Context, userID int64, period, benchmarkISIN string) (*models.PerformanceResult, error)
+}
+
+// DataQualityService defines the interface for scoring the completeness of a user's data.
+type DataQualityService interface {
+	GetReport(ctx context.Context, userID int64, year string) (*models.DataQualityReport, error)
+}
+
+// IBKRFlexService defines the interface for automatic imports through the IBKR Flex Web Service.
+type IBKRFlexService interface {
+	SaveConnection(userID int64, token, queryID string) (*models.BrokerConnection, error)
+	GetConnection(userID int64) (*models.BrokerConnection, error)
+	DeleteConnection(userID int64) error
+	SyncUser(userID int64) error
+	StartSync(userID int64) (*models.BrokerConnection, error)
+	SyncAll()
+	StartScheduler(interval time.Duration)
+}
+
+// UnrealizedGainsService defines the interface for valuing open positions at market prices.
+type UnrealizedGainsService interface {
+	GetUnrealizedGains(ctx context.Context, userID int64) (*models.UnrealizedGainsReport, error)
+}
+
+// RecalculationService defines the interface for rebuilding a user's derived data and reconciling it.
+type RecalculationService interface {
+	Recalculate(ctx context.Context, userID int64) (*models.ReconciliationReport, error)
+}
+
+// DeemedDisposalService defines the interface for the optional 8-year deemed disposal rule on ETF holdings.
+type DeemedDisposalService interface {
+	GetReport(ctx context.Context, userID int64) (*models.DeemedDisposalReport, error)
+	IsEnabled(userID int64) (bool, error)
+	SetEnabled(userID int64, enabled bool) error
+}
+
+// BondService defines the interface for reporting the income of bonds.
+type BondService interface {
+	GetBondIncome(ctx context.Context, userID int64) (*models.BondIncomeReport, error)
+}
+
+// CashBalanceService defines the interface for reconstructing cash balances over time.
+type CashBalanceService interface {
+	GetCashBalance(ctx context.Context, userID int64) (*models.CashBalanceReport, error)
+	GetContributions(ctx context.Context, userID int64) (*models.ContributionReport, error)
+}
+
+// DividendCalendarService defines the interface for projecting upcoming dividends.
+type DividendCalendarService interface {
+	GetCalendar(ctx context.Context, userID int64) (*models.DividendCalendar, error)
+}
+
+// TransactionTagService defines the interface for user notes and tags on processed transactions.
+type TransactionTagService interface {
+	GetTags(userID int64) ([]models.TagCount, error)
+	GetAnnotations(userID int64) (map[int64]*models.TransactionAnnotation, error)
+	SetTags(userID, transactionID int64, tags []string) (*models.TransactionAnnotation, error)
+	SetNote(userID, transactionID int64, note string) (*models.TransactionAnnotation, error)
+	NewTagFilter(userID int64, tags []string) (*TagFilter, error)
+}
+
+// SettingsService defines the interface for reading and changing a user's preferences.
+type SettingsService interface {
+	GetSettings(userID int64) (models.UserSettings, error)
+	UpdateSettings(userID int64, settings models.UserSettings) (models.UserSettings, error)
+}
+
+// TaxReportService defines the interface for applying the rules of a user's tax residence to a tax year.
+type TaxReportService interface {
+	GetReport(ctx context.Context, userID int64, year string) (*models.TaxReport, error)
+}
+
+// AlertService defines the interface for the conditions users ask to be emailed about.
+type AlertService interface {
+	GetRules(userID int64) ([]models.AlertRule, error)
+	CreateRule(userID int64, rule models.AlertRule) (*models.AlertRule, error)
+	UpdateRule(userID, id int64, rule models.AlertRule) (*models.AlertRule, error)
+	DeleteRule(userID, id int64) error
+	EvaluateAll()
+	StartScheduler(interval time.Duration)
+}
+
+// GoalService defines the interface for the user's investment goals and the dashboard measuring them.
+type GoalService interface {
+	GetGoals(userID int64) ([]models.Goal, error)
+	CreateGoal(userID int64, goal models.Goal) (*models.Goal, error)
+	UpdateGoal(userID, id int64, goal models.Goal) (*models.Goal, error)
+	DeleteGoal(userID, id int64) error
+	GetDashboard(ctx context.Context, userID int64) (*models.Dashboard, error)
+}
+
+// WebhookService defines the interface for the URLs users register to be posted account events.
+type WebhookService interface {
+	GetWebhooks(userID int64) ([]models.Webhook, error)
+	CreateWebhook(userID int64, webhook models.Webhook) (*models.Webhook, error)
+	UpdateWebhook(userID, id int64, webhook models.Webhook) (*models.Webhook, error)
+	DeleteWebhook(userID, id int64) error
+	GetDeliveries(userID, webhookID int64, limit int) ([]models.WebhookDelivery, error)
+	Dispatch(userID int64, event string, data interface{})
+	SubscribedUsers(event string) ([]int64, error)
+	RetryDeliveries() (int, error)
+	StartDeliveryWorker(interval time.Duration)
+}
+
+// CalendarService defines the interface for the iCalendar feed of projected dividends and tax
+// deadlines that calendar apps subscribe to with a signed token.
+type CalendarService interface {
+	GetFeed(userID int64) (*models.CalendarFeed, error)
+	EnableFeed(userID int64) (*models.CalendarFeed, error)
+	DisableFeed(userID int64) error
+	UserForToken(token string) (int64, error)
+	BuildFeed(ctx context.Context, userID int64) ([]byte, error)
+}
+
+// ShareLinkService defines the interface for the expiring links granting read-only access to some of
+// a user's reports.
+type ShareLinkService interface {
+	GetShareLinks(userID int64) ([]models.ShareLink, error)
+	CreateShareLink(userID int64, link models.ShareLink, expiresInDays int) (*models.ShareLink, error)
+	DeleteShareLink(userID, id int64) error
+	ResolveShareLink(token string) (*models.ShareLink, error)
+}
+
+// HouseholdService defines the interface for grouping users who file jointly and combining their
+// reports.
+type HouseholdService interface {
+	GetHousehold(userID int64) (*models.Household, error)
+	Invite(userID int64, email string) (*models.HouseholdInvitation, error)
+	AcceptInvitation(userID, invitationID int64) (*models.Household, error)
+	DeleteInvitation(userID, invitationID int64) error
+	Leave(userID int64) error
+	GetAnnualReport(ctx context.Context, userID int64, year string) (*models.HouseholdAnnualReport, error)
+	GetDividendTaxSummary(ctx context.Context, userID int64) (models.DividendTaxResult, error)
+}
+
+// ReportService defines the interface for reports combining the results of several processors.
+type ReportService interface {
+	GetAnnualReport(ctx context.Context, userID int64, year string) (*models.AnnualReport, error)
+	CompareYears(ctx context.Context, userID int64, years []string) (*models.YearComparison, error)
 }
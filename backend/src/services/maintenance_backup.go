@@ -0,0 +1,249 @@
+// backend/src/services/maintenance_backup.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+)
+
+var (
+	// ErrBackupNotConfigured is returned by RunBackup when neither a backup directory nor a bucket is set.
+	ErrBackupNotConfigured = errors.New("backups are not configured: set BACKUP_DIR or BACKUP_S3_BUCKET")
+	// ErrBackupInProgress is returned by RunBackup while another backup is being taken.
+	ErrBackupInProgress = errors.New("a backup is already in progress")
+)
+
+// backupFilePrefix and backupFileSuffix frame the name of a backup file, which holds the UTC time it
+// was taken at, so that the names of backups sort by age.
+const (
+	backupFilePrefix = "rumoclaro-"
+	backupFileSuffix = ".db"
+)
+
+// BackupConfig says where database backups go: to files in Dir, of which the newest Keep are kept,
+// and to the S3-compatible bucket of S3 when it is set.
+type BackupConfig struct {
+	Dir  string
+	Keep int // 0 keeps every backup
+	S3   *S3Config
+}
+
+// S3Config locates a bucket of AWS S3 or of an S3-compatible store such as MinIO, Cloudflare R2 or
+// Backblaze B2. Objects are addressed by path (endpoint/bucket/key), which they all support.
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com
+	Region          string
+	Bucket          string
+	Prefix          string // Prepended to the object names, e.g. "backups/"
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Only for temporary credentials
+}
+
+// BackupReport describes a backup taken.
+type BackupReport struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	File      string    `json:"file"` // Name of the backup file
+	SizeBytes int64     `json:"size_bytes"`
+	Path      string    `json:"path,omitempty"` // Where the file was kept, when a backup directory is set
+	S3URL     string    `json:"s3_url,omitempty"`
+	Pruned    []string  `json:"pruned,omitempty"` // Older backups removed from the directory
+}
+
+// StartBackupScheduler takes a backup periodically in the background, when backups are configured.
+func (s *maintenanceServiceImpl) StartBackupScheduler(interval time.Duration) {
+	if s.backup.Dir == "" && s.backup.S3 == nil {
+		logger.L.Info("Database backups disabled: neither BACKUP_DIR nor BACKUP_S3_BUCKET is set")
+		return
+	}
+	if database.IsPostgres() {
+		logger.L.Info("Database backups disabled: PostgreSQL is backed up with its own tools")
+		return
+	}
+	StartPeriodicJob("maintenance-backup", interval, func() {
+		if _, err := s.RunBackup(context.Background()); err != nil {
+			logger.L.Error("Scheduled database backup failed", "error", err)
+		}
+	})
+}
+
+// RunBackup writes a consistent copy of the SQLite database while it stays in use, with VACUUM INTO,
+// to the backup directory or, without one, a temporary file, and uploads it to the bucket when one is
+// configured. Only one backup is taken at a time.
+func (s *maintenanceServiceImpl) RunBackup(ctx context.Context) (*BackupReport, error) {
+	if s.backup.Dir == "" && s.backup.S3 == nil {
+		return nil, ErrBackupNotConfigured
+	}
+	if database.IsPostgres() {
+		return nil, database.ErrBackupUnsupported
+	}
+	select {
+	case s.backupRunning <- struct{}{}:
+		defer func() { <-s.backupRunning }()
+	default:
+		return nil, ErrBackupInProgress
+	}
+
+	report, err := s.runBackup(ctx)
+	if err != nil {
+		metrics.BackupFinished(0, err)
+		return nil, err
+	}
+	metrics.BackupFinished(report.SizeBytes, nil)
+	logger.L.Info("Database backup finished", "file", report.File, "sizeBytes", report.SizeBytes,
+		"path", report.Path, "s3URL", report.S3URL, "pruned", len(report.Pruned), "duration", report.Duration)
+	return report, nil
+}
+
+func (s *maintenanceServiceImpl) runBackup(ctx context.Context) (*BackupReport, error) {
+	startedAt := time.Now().UTC()
+	report := &BackupReport{StartedAt: startedAt, File: backupFilePrefix + startedAt.Format("20060102T150405Z") + backupFileSuffix}
+
+	dir := s.backup.Dir
+	if dir == "" {
+		temp, err := os.MkdirTemp("", "rumoclaro-backup-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a temporary backup directory: %w", err)
+		}
+		defer os.RemoveAll(temp)
+		dir = temp
+	} else if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the backup directory: %w", err)
+	}
+
+	// The copy is written under a temporary name, so an interrupted backup is never taken for a
+	// complete one.
+	path := filepath.Join(dir, report.File)
+	partial := path + ".partial"
+	os.Remove(partial)
+	if err := database.Backup(ctx, s.db, partial); err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to move the backup into place: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the backup: %w", err)
+	}
+	report.SizeBytes = info.Size()
+	if s.backup.Dir != "" {
+		report.Path = path
+	}
+
+	if s.backup.S3 != nil {
+		report.S3URL, err = s.uploadBackup(ctx, path, report.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.backup.Dir != "" {
+		report.Pruned, err = pruneBackups(s.backup.Dir, s.backup.Keep)
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Duration = time.Since(startedAt).Round(time.Millisecond).String()
+	return report, nil
+}
+
+// pruneBackups removes the backups in dir beyond the newest keep, returning the names removed.
+func pruneBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the backup directory: %w", err)
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, backupFilePrefix) && strings.HasSuffix(name, backupFileSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= keep {
+		return nil, nil
+	}
+	slices.Sort(backups)
+	var pruned []string
+	for _, name := range backups[:len(backups)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return pruned, fmt.Errorf("failed to remove old backup %s: %w", name, err)
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
+
+// uploadBackup puts the backup file at path into the bucket as an object named after it, returning
+// its s3:// URL. The request is signed with AWS Signature Version 4 over the file's SHA-256, which S3
+// checks against what it receives.
+func (s *maintenanceServiceImpl) uploadBackup(ctx context.Context, path, name string) (string, error) {
+	cfg := s.backup.S3
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the backup: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the backup: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read the backup: %w", err)
+	}
+
+	key := cfg.Prefix + name
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/") + "/" + url.PathEscape(cfg.Bucket) + "/" + escapeObjectKey(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, file)
+	if err != nil {
+		return "", fmt.Errorf("invalid backup bucket endpoint: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	creds := awsCredentials{accessKeyID: cfg.AccessKeyID, secretAccessKey: cfg.SecretAccessKey, sessionToken: cfg.SessionToken}
+	signAWSRequest(req, creds, "s3", cfg.Region, payloadHash, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the backup: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to upload the backup: the bucket answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return "s3://" + cfg.Bucket + "/" + key, nil
+}
+
+// escapeObjectKey escapes an object key for a URL path, keeping the slashes that separate its parts.
+func escapeObjectKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,106 @@
+// backend/src/services/maintenance_service.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+	"github.com/username/taxfolio/backend/src/model"
+)
+
+// MaintenanceReport summarises the rows removed by a cleanup run.
+type MaintenanceReport struct {
+	RanAt                       time.Time `json:"ran_at"`
+	ExpiredSessions             int64     `json:"expired_sessions"`
+	ExpiredVerificationTokens   int64     `json:"expired_verification_tokens"`
+	ExpiredPasswordResetTokens  int64     `json:"expired_password_reset_tokens"`
+	ExpiredUnlockTokens         int64     `json:"expired_unlock_tokens"`
+	ExpiredIdempotencyKeys      int64     `json:"expired_idempotency_keys"`
+	OldOutboxEmails             int64     `json:"old_outbox_emails"`
+	OldWebhookDeliveries        int64     `json:"old_webhook_deliveries"`
+	ExpiredShareLinks           int64     `json:"expired_share_links"`
+	ExpiredHouseholdInvitations int64     `json:"expired_household_invitations"`
+	OldAuditLogEntries          int64     `json:"old_audit_log_entries"`
+	OldBillingEvents            int64     `json:"old_billing_events"`
+}
+
+type maintenanceServiceImpl struct {
+	db            *sql.DB
+	backup        BackupConfig
+	backupRunning chan struct{} // Holds a token while a backup is taken
+	httpClient    http.Client
+}
+
+// NewMaintenanceService creates a new MaintenanceService bound to the given database, backing it up
+// as backup says.
+func NewMaintenanceService(db *sql.DB, backup BackupConfig) MaintenanceService {
+	return &maintenanceServiceImpl{
+		db:            db,
+		backup:        backup,
+		backupRunning: make(chan struct{}, 1),
+		httpClient:    http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// StartScheduler runs the cleanup periodically in the background.
+func (s *maintenanceServiceImpl) StartScheduler(interval time.Duration) {
+	StartPeriodicJob("maintenance-cleanup", interval, func() {
+		if _, err := s.RunCleanup(); err != nil {
+			logger.L.Error("Scheduled maintenance cleanup failed", "error", err)
+		}
+	})
+}
+
+// RunCleanup deletes expired sessions, clears expired email verification, password reset and unlock tokens,
+// forgets upload idempotency keys older than model.IdempotencyKeyTTL and deletes the sent or failed
+// outbox emails older than model.OutboxRetention, the finished webhook deliveries older than
+// model.WebhookDeliveryRetention, the expired share links and household invitations, and the audit log
+// entries older than model.AuditLogRetention and the billing webhook events older than model.BillingEventRetention.
+func (s *maintenanceServiceImpl) RunCleanup() (*MaintenanceReport, error) {
+	now := time.Now()
+	report := &MaintenanceReport{RanAt: now}
+
+	steps := []struct {
+		kind  string
+		run   func(*sql.DB, time.Time) (int64, error)
+		count *int64
+	}{
+		{"sessions", model.DeleteExpiredSessions, &report.ExpiredSessions},
+		{"verification_tokens", model.ClearExpiredVerificationTokens, &report.ExpiredVerificationTokens},
+		{"password_reset_tokens", model.ClearExpiredPasswordResetTokens, &report.ExpiredPasswordResetTokens},
+		{"unlock_tokens", model.ClearExpiredUnlockTokens, &report.ExpiredUnlockTokens},
+		{"idempotency_keys", model.ClearExpiredIdempotencyKeys, &report.ExpiredIdempotencyKeys},
+		{"outbox_emails", model.DeleteOldOutboxEmails, &report.OldOutboxEmails},
+		{"webhook_deliveries", model.DeleteOldWebhookDeliveries, &report.OldWebhookDeliveries},
+		{"share_links", model.DeleteExpiredShareLinks, &report.ExpiredShareLinks},
+		{"household_invitations", model.DeleteExpiredHouseholdInvitations, &report.ExpiredHouseholdInvitations},
+		{"audit_log", model.DeleteOldAuditLogEntries, &report.OldAuditLogEntries},
+		{"billing_events", model.DeleteOldBillingEvents, &report.OldBillingEvents},
+	}
+	for _, step := range steps {
+		rows, err := step.run(s.db, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clean up %s: %w", step.kind, err)
+		}
+		*step.count = rows
+		metrics.MaintenanceRowsRemoved(step.kind, rows)
+	}
+
+	logger.L.Info("Maintenance cleanup finished",
+		"expiredSessions", report.ExpiredSessions,
+		"expiredVerificationTokens", report.ExpiredVerificationTokens,
+		"expiredPasswordResetTokens", report.ExpiredPasswordResetTokens,
+		"expiredUnlockTokens", report.ExpiredUnlockTokens,
+		"expiredIdempotencyKeys", report.ExpiredIdempotencyKeys,
+		"oldOutboxEmails", report.OldOutboxEmails,
+		"oldWebhookDeliveries", report.OldWebhookDeliveries,
+		"expiredShareLinks", report.ExpiredShareLinks,
+		"expiredHouseholdInvitations", report.ExpiredHouseholdInvitations,
+		"oldAuditLogEntries", report.OldAuditLogEntries,
+		"oldBillingEvents", report.OldBillingEvents)
+	return report, nil
+}
@@ -0,0 +1,430 @@
+// backend/src/services/performance_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// Supported performance periods.
+const (
+	PeriodYTD     = "ytd"
+	PeriodOneYear = "1y"
+	PeriodAll     = "all"
+)
+
+// ErrInvalidPeriod is returned when an unsupported period is requested.
+var ErrInvalidPeriod = errors.New("invalid performance period")
+
+type performanceServiceImpl struct {
+	transactions          model.TransactionRepository
+	stockProcessor        processors.StockProcessor
+	cashMovementProcessor processors.CashMovementProcessor
+	priceService          PriceService
+	defaultBenchmarkISIN  string
+}
+
+// NewPerformanceService creates a new PerformanceService.
+// defaultBenchmarkISIN is the index the portfolio is compared against when the caller does not pick one;
+// an empty value disables the comparison.
+func NewPerformanceService(transactions model.TransactionRepository, stockProcessor processors.StockProcessor, cashMovementProcessor processors.CashMovementProcessor, priceService PriceService, defaultBenchmarkISIN string) PerformanceService {
+	return &performanceServiceImpl{
+		transactions:          transactions,
+		stockProcessor:        stockProcessor,
+		cashMovementProcessor: cashMovementProcessor,
+		priceService:          priceService,
+		defaultBenchmarkISIN:  defaultBenchmarkISIN,
+	}
+}
+
+// isinFlows accumulates the dated flows and totals for one instrument.
+type isinFlows struct {
+	productName   string
+	tradeFlows    []utils.CashFlow // Investor perspective: buys negative, sales positive
+	dividends     []utils.CashFlow
+	startValue    float64
+	endValue      float64
+	hasStartPrice bool
+	hasPrice      bool
+	hasHolding    bool
+	heldAtStart   bool
+}
+
+// GetPerformance computes money-weighted and Modified Dietz returns per ISIN and for the whole portfolio,
+// together with the return of the benchmark index over the same period.
+func (s *performanceServiceImpl) GetPerformance(ctx context.Context, userID int64, period, benchmarkISIN string) (*models.PerformanceResult, error) {
+	allTxns, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.performance(allTxns, baseCurrency, period, benchmarkISIN, time.Now())
+}
+
+// performance computes the returns of the transactions over the period ending at end.
+func (s *performanceServiceImpl) performance(allTxns []models.ProcessedTransaction, baseCurrency, period, benchmarkISIN string, end time.Time) (*models.PerformanceResult, error) {
+	start, err := periodStart(period, end, allTxns)
+	if err != nil {
+		return nil, err
+	}
+
+	byISIN := make(map[string]*isinFlows)
+	get := func(isin, name string) *isinFlows {
+		f, ok := byISIN[isin]
+		if !ok {
+			f = &isinFlows{productName: name}
+			byISIN[isin] = f
+		}
+		return f
+	}
+
+	// 1. Starting positions valued at their price on the start date, so gains made before the period
+	// are not counted in it. A position without a price that day is valued at cost, and flagged.
+	startCost := make(map[string]float64)
+	startQuantities := make(map[string]int)
+	for _, lot := range s.holdingsBefore(allTxns, start) {
+		get(lot.ISIN, lot.ProductName).heldAtStart = true
+		startCost[lot.ISIN] += -lot.BuyAmountEUR
+		startQuantities[lot.ISIN] += lot.Quantity
+	}
+	for isin, cost := range startCost {
+		f := byISIN[isin]
+		f.startValue = cost
+		if strings.HasPrefix(strings.ToLower(isin), "unknown") {
+			continue
+		}
+		price, err := s.priceService.GetPriceOnDate(isin, start, baseCurrency)
+		if err != nil || price.Status != "OK" || price.Price <= 0 {
+			logger.L.Warn("Could not get start price for performance, valuing at cost", "isin", isin, "date", start.Format("2006-01-02"), "error", err)
+			continue
+		}
+		f.startValue = price.Price * float64(startQuantities[isin])
+		f.hasStartPrice = true
+	}
+
+	// 2. Trades and dividends inside the period.
+	for _, tx := range allTxns {
+		if tx.ISIN == "" {
+			continue
+		}
+		txDate := utils.ParseDate(tx.Date)
+		if txDate.Before(start) || txDate.After(end) {
+			continue
+		}
+		switch tx.TransactionType {
+		case "STOCK":
+			amount := (tx.AmountEUR - commissionEUR(tx)).Float64()
+			f := get(tx.ISIN, tx.ProductName)
+			f.tradeFlows = append(f.tradeFlows, utils.CashFlow{Date: txDate, Amount: amount})
+		case "DIVIDEND", "RETURN_OF_CAPITAL":
+			f := get(tx.ISIN, tx.ProductName)
+			f.dividends = append(f.dividends, utils.CashFlow{Date: txDate, Amount: tx.AmountEUR.Float64()})
+		case "SCRIP_DIVIDEND":
+			// Income that is immediately reinvested: a dividend plus a purchase of the same value.
+			f := get(tx.ISIN, tx.ProductName)
+			f.dividends = append(f.dividends, utils.CashFlow{Date: txDate, Amount: tx.AmountEUR.Float64()})
+			f.tradeFlows = append(f.tradeFlows, utils.CashFlow{Date: txDate, Amount: -tx.AmountEUR.Float64()})
+		}
+	}
+
+	// 3. Current positions valued at market prices where available.
+	endLots := s.holdingsBefore(allTxns, end.AddDate(0, 0, 1))
+	var isins []string
+	costBasis := make(map[string]float64)
+	quantities := make(map[string]int)
+	for _, lot := range endLots {
+		get(lot.ISIN, lot.ProductName).hasHolding = true
+		costBasis[lot.ISIN] += -lot.BuyAmountEUR
+		quantities[lot.ISIN] += lot.Quantity
+	}
+	for isin := range costBasis {
+		if !strings.HasPrefix(strings.ToLower(isin), "unknown") {
+			isins = append(isins, isin)
+		}
+	}
+	prices, err := s.priceService.GetCurrentPrices(isins, baseCurrency)
+	if err != nil {
+		logger.L.Warn("Could not fetch some or all current prices for performance", "error", err)
+	}
+	for isin, cost := range costBasis {
+		f := byISIN[isin]
+		if p, ok := prices[isin]; ok && p.Status == "OK" {
+			f.endValue = p.Price * float64(quantities[isin])
+			f.hasPrice = true
+		} else {
+			f.endValue = cost
+		}
+	}
+
+	// 4. Compute metrics per ISIN and for the aggregate portfolio.
+	result := &models.PerformanceResult{
+		Period:    period,
+		StartDate: start.Format(utils.DefaultDateFormat),
+		EndDate:   end.Format(utils.DefaultDateFormat),
+		ByISIN:    []models.PerformanceMetrics{},
+		FlowBasis: models.FlowBasisTrades,
+	}
+	portfolio := &isinFlows{hasPrice: true}
+	pricedCount, heldCount := 0, 0
+	startPricedCount, heldAtStartCount := 0, 0
+	var dividends float64
+	for isin, f := range byISIN {
+		metrics := computeMetrics(f, start, end)
+		metrics.ISIN = isin
+		metrics.ProductName = f.productName
+		result.ByISIN = append(result.ByISIN, metrics)
+
+		portfolio.tradeFlows = append(portfolio.tradeFlows, f.tradeFlows...)
+		portfolio.dividends = append(portfolio.dividends, f.dividends...)
+		portfolio.startValue += f.startValue
+		portfolio.endValue += f.endValue
+		for _, flow := range f.dividends {
+			dividends += flow.Amount
+		}
+		if f.hasHolding {
+			heldCount++
+			if f.hasPrice {
+				pricedCount++
+			}
+		}
+		if f.heldAtStart {
+			heldAtStartCount++
+			if f.hasStartPrice {
+				startPricedCount++
+			}
+		}
+	}
+	sort.Slice(result.ByISIN, func(i, j int) bool { return result.ByISIN[i].ISIN < result.ByISIN[j].ISIN })
+
+	// With deposits and withdrawals imported, the portfolio is the securities plus the cash they were
+	// bought with, and only money moved in or out of the broker counts as a contribution.
+	if external, ok := s.externalFlows(allTxns, start, end); ok {
+		portfolio.tradeFlows = external
+		portfolio.dividends = nil
+		portfolio.startValue += cashBalanceEUR(allTxns, start)
+		portfolio.endValue += cashBalanceEUR(allTxns, end.AddDate(0, 0, 1))
+		result.FlowBasis = models.FlowBasisCashMovements
+	}
+	result.Portfolio = computeMetrics(portfolio, start, end)
+	result.Portfolio.DividendsEUR = utils.RoundMoney(dividends)
+	switch {
+	case heldCount == 0 || pricedCount == heldCount:
+		result.Portfolio.PriceStatus = "OK"
+	case pricedCount == 0:
+		result.Portfolio.PriceStatus = "UNAVAILABLE"
+	default:
+		result.Portfolio.PriceStatus = "PARTIAL"
+	}
+	switch {
+	case heldAtStartCount == 0 || startPricedCount == heldAtStartCount:
+		result.Portfolio.StartPriceStatus = "OK"
+	case startPricedCount == 0:
+		result.Portfolio.StartPriceStatus = "COST_BASIS"
+	default:
+		result.Portfolio.StartPriceStatus = "PARTIAL"
+	}
+
+	// 5. Benchmark index over the same period.
+	if benchmarkISIN == "" {
+		benchmarkISIN = s.defaultBenchmarkISIN
+	}
+	if benchmarkISIN != "" {
+		result.Benchmark = s.computeBenchmark(benchmarkISIN, baseCurrency, start, end, result.Portfolio.ModifiedDietzReturn)
+	}
+	return result, nil
+}
+
+// externalFlows returns the deposits into and withdrawals from the brokers inside the period, from the
+// investor's perspective, and whether the user imported any at all. Opening lots entered in the period
+// are positions brought in from elsewhere, so they count as deposits of their cost.
+func (s *performanceServiceImpl) externalFlows(txns []models.ProcessedTransaction, start, end time.Time) ([]utils.CashFlow, bool) {
+	found := false
+	flows := []utils.CashFlow{}
+	for _, movement := range s.cashMovementProcessor.Process(txns) {
+		if movement.Type != "deposit" && movement.Type != "withdrawal" {
+			continue
+		}
+		found = true
+		if date := utils.ParseDate(movement.Date); !date.Before(start) && !date.After(end) {
+			flows = append(flows, utils.CashFlow{Date: date, Amount: -movement.AmountEUR})
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	for _, tx := range txns {
+		if tx.TransactionSubType != models.OpeningBalanceSubType {
+			continue
+		}
+		if date := utils.ParseDate(tx.Date); !date.Before(start) && !date.After(end) {
+			flows = append(flows, utils.CashFlow{Date: date, Amount: tx.AmountEUR.Float64()})
+		}
+	}
+	return flows, true
+}
+
+// cashBalanceEUR returns the cash held at the brokers before cutoff, in EUR at the rate of each
+// transaction: every transaction moves its amount, less the commission of trades, except scrip
+// dividends and opening lots, which move no cash.
+func cashBalanceEUR(txns []models.ProcessedTransaction, cutoff time.Time) float64 {
+	var balance models.Money
+	for _, tx := range txns {
+		date := utils.ParseDate(tx.Date)
+		if date.IsZero() || !date.Before(cutoff) || tx.TransactionType == "SCRIP_DIVIDEND" || tx.TransactionSubType == models.OpeningBalanceSubType {
+			continue
+		}
+		balance += tx.AmountEUR
+		if tx.TransactionType == "STOCK" {
+			balance -= commissionEUR(tx)
+		}
+	}
+	return balance.Float64()
+}
+
+// computeBenchmark compares the portfolio against the price return of a benchmark instrument.
+// Failures are reported through the Status field so the rest of the report is still returned.
+func (s *performanceServiceImpl) computeBenchmark(isin, baseCurrency string, start, end time.Time, portfolioReturn *float64) *models.BenchmarkPerformance {
+	benchmark := &models.BenchmarkPerformance{ISIN: isin, Status: "UNAVAILABLE"}
+
+	startPrice, err := s.priceService.GetPriceOnDate(isin, start, baseCurrency)
+	if err != nil || startPrice.Status != "OK" || startPrice.Price <= 0 {
+		logger.L.Warn("Could not get benchmark start price", "isin", isin, "date", start.Format("2006-01-02"), "error", err)
+		return benchmark
+	}
+	endPrice, err := s.priceService.GetPriceOnDate(isin, end, baseCurrency)
+	if err != nil || endPrice.Status != "OK" || endPrice.Price <= 0 {
+		logger.L.Warn("Could not get benchmark end price", "isin", isin, "date", end.Format("2006-01-02"), "error", err)
+		return benchmark
+	}
+
+	benchmark.StartPriceEUR = utils.RoundFloat(startPrice.Price, 4)
+	benchmark.EndPriceEUR = utils.RoundFloat(endPrice.Price, 4)
+	benchmark.Status = "OK"
+
+	periodReturn := endPrice.Price/startPrice.Price - 1
+	roundedReturn := utils.RoundFloat(periodReturn, 6)
+	benchmark.Return = &roundedReturn
+
+	if days := end.Sub(start).Hours() / 24; days > 0 {
+		annualized := utils.RoundFloat(math.Pow(1+periodReturn, 365/days)-1, 6)
+		benchmark.AnnualizedReturn = &annualized
+	}
+	if portfolioReturn != nil {
+		excess := utils.RoundFloat(*portfolioReturn-periodReturn, 6)
+		benchmark.ExcessReturn = &excess
+	}
+	return benchmark
+}
+
+// holdingsBefore returns the open purchase lots resulting from all transactions dated before cutoff.
+func (s *performanceServiceImpl) holdingsBefore(txns []models.ProcessedTransaction, cutoff time.Time) []models.PurchaseLot {
+	var before []models.ProcessedTransaction
+	for _, tx := range txns {
+		if utils.ParseDate(tx.Date).Before(cutoff) {
+			before = append(before, tx)
+		}
+	}
+	if len(before) == 0 {
+		return nil
+	}
+	_, holdingsByYear := s.stockProcessor.Process(before)
+	latestYear := ""
+	for year := range holdingsByYear {
+		if year > latestYear {
+			latestYear = year
+		}
+	}
+	return holdingsByYear[latestYear]
+}
+
+func computeMetrics(f *isinFlows, start, end time.Time) models.PerformanceMetrics {
+	metrics := models.PerformanceMetrics{
+		StartValueEUR:    utils.RoundMoney(f.startValue),
+		EndValueEUR:      utils.RoundMoney(f.endValue),
+		PriceStatus:      "OK",
+		StartPriceStatus: "OK",
+	}
+	if f.hasHolding && !f.hasPrice {
+		metrics.PriceStatus = "UNAVAILABLE"
+	}
+	if f.heldAtStart && !f.hasStartPrice {
+		metrics.StartPriceStatus = "COST_BASIS"
+	}
+
+	// Money-weighted: investor perspective, starting value is an outflow and ending value an inflow.
+	var xirrFlows []utils.CashFlow
+	if f.startValue != 0 {
+		xirrFlows = append(xirrFlows, utils.CashFlow{Date: start, Amount: -f.startValue})
+	}
+	var netInvested, dividends float64
+	contributions := make([]utils.CashFlow, 0, len(f.tradeFlows))
+	for _, flow := range f.tradeFlows {
+		xirrFlows = append(xirrFlows, flow)
+		netInvested -= flow.Amount
+		contributions = append(contributions, utils.CashFlow{Date: flow.Date, Amount: -flow.Amount})
+	}
+	for _, flow := range f.dividends {
+		xirrFlows = append(xirrFlows, flow)
+		dividends += flow.Amount
+	}
+	if f.endValue != 0 {
+		xirrFlows = append(xirrFlows, utils.CashFlow{Date: end, Amount: f.endValue})
+	}
+	metrics.NetInvestedEUR = utils.RoundMoney(netInvested - dividends)
+	metrics.DividendsEUR = utils.RoundMoney(dividends)
+
+	if rate, err := utils.XIRR(xirrFlows); err == nil {
+		rounded := utils.RoundFloat(rate, 6)
+		metrics.MoneyWeightedReturn = &rounded
+	}
+	// Modified Dietz: dividends are income, trades are external contributions to the position.
+	if rate, err := utils.ModifiedDietz(f.startValue, f.endValue+dividends, start, end, contributions); err == nil {
+		rounded := utils.RoundFloat(rate, 6)
+		metrics.ModifiedDietzReturn = &rounded
+	}
+	return metrics
+}
+
+// periodStart resolves the start date of a performance period.
+func periodStart(period string, end time.Time, txns []models.ProcessedTransaction) (time.Time, error) {
+	switch period {
+	case PeriodYTD:
+		return time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	case PeriodOneYear:
+		return end.AddDate(-1, 0, 0), nil
+	case PeriodAll:
+		earliest := end
+		for _, tx := range txns {
+			if d := utils.ParseDate(tx.Date); !d.IsZero() && d.Before(earliest) {
+				earliest = d
+			}
+		}
+		return earliest, nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, period)
+	}
+}
+
+// commissionEUR converts a transaction's commission to EUR, mirroring the fee processor rules:
+// DeGiro reports commissions in EUR, other brokers in the trade currency.
+func commissionEUR(tx models.ProcessedTransaction) models.Money {
+	if tx.Source == "degiro" || tx.ExchangeRate <= 0 {
+		return tx.Commission
+	}
+	return tx.Commission.Convert(tx.ExchangeRate)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+// fixedPriceService prices every ISIN at the price of the latest date on or before the one asked for.
+type fixedPriceService struct {
+	prices map[string]float64 // By date, as YYYY-MM-DD
+}
+
+func (p fixedPriceService) priceOn(date time.Time) PriceInfo {
+	latest := ""
+	for day := range p.prices {
+		if day <= date.Format("2006-01-02") && day > latest {
+			latest = day
+		}
+	}
+	if latest == "" {
+		return PriceInfo{Status: "UNAVAILABLE"}
+	}
+	return PriceInfo{Status: "OK", Price: p.prices[latest], Currency: "EUR"}
+}
+
+func (p fixedPriceService) GetCurrentPrices(isins []string, baseCurrency string) (map[string]PriceInfo, error) {
+	prices := make(map[string]PriceInfo)
+	for _, isin := range isins {
+		prices[isin] = p.priceOn(time.Now())
+	}
+	return prices, nil
+}
+
+func (p fixedPriceService) GetPriceOnDate(isin string, date time.Time, baseCurrency string) (PriceInfo, error) {
+	return p.priceOn(date), nil
+}
+
+func newTestPerformanceService(prices map[string]float64) *performanceServiceImpl {
+	return &performanceServiceImpl{
+		stockProcessor:        processors.NewStockProcessor(),
+		cashMovementProcessor: processors.NewCashMovementProcessor(),
+		priceService:          fixedPriceService{prices: prices},
+	}
+}
+
+func performanceTransaction(date, transactionType, subType string, quantity int, amountEUR float64) models.ProcessedTransaction {
+	tx := models.ProcessedTransaction{
+		Date:               date,
+		Source:             "degiro",
+		TransactionType:    transactionType,
+		TransactionSubType: subType,
+		Quantity:           quantity,
+		OriginalQuantity:   quantity,
+		Amount:             models.NewMoney(amountEUR),
+		AmountEUR:          models.NewMoney(amountEUR),
+		Currency:           "EUR",
+		ExchangeRate:       1,
+	}
+	if transactionType == "STOCK" {
+		tx.ISIN, tx.ProductName, tx.BuySell = "IE00B4L5Y983", "iShares Core MSCI World", "BUY"
+	}
+	return tx
+}
+
+func TestPerformanceIgnoresGainsBeforeThePeriod(t *testing.T) {
+	end := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+	buy := performanceTransaction("01-03-2024", "STOCK", "", 10, -1000)
+	deposit := performanceTransaction("01-02-2024", "CASH", "DEPOSIT", 0, 1000)
+	laterDeposit := performanceTransaction("03-03-2025", "CASH", "DEPOSIT", 0, 500)
+	// The position gained 50% in 2024 and nothing since.
+	prices := map[string]float64{"2024-03-01": 100, "2024-12-31": 150}
+
+	tests := []struct {
+		name          string
+		txns          []models.ProcessedTransaction
+		prices        map[string]float64
+		flowBasis     string
+		startValue    float64
+		startStatus   string
+		wantNoReturns bool
+	}{
+		{"trades", []models.ProcessedTransaction{buy}, prices, models.FlowBasisTrades, 1500, "OK", true},
+		{"cash movements", []models.ProcessedTransaction{deposit, buy, laterDeposit}, prices, models.FlowBasisCashMovements, 1500, "OK", true},
+		{"no start price", []models.ProcessedTransaction{buy}, map[string]float64{"2025-01-02": 150}, models.FlowBasisTrades, 1000, "COST_BASIS", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newTestPerformanceService(tt.prices).performance(tt.txns, "EUR", PeriodYTD, "", end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			portfolio := result.Portfolio
+			if result.FlowBasis != tt.flowBasis || portfolio.StartValueEUR != tt.startValue || portfolio.StartPriceStatus != tt.startStatus {
+				t.Errorf("got flow basis %q, start value %v (%s), want %q, %v (%s)", result.FlowBasis, portfolio.StartValueEUR, portfolio.StartPriceStatus, tt.flowBasis, tt.startValue, tt.startStatus)
+			}
+			if !tt.wantNoReturns {
+				return
+			}
+			for name, rate := range map[string]*float64{"Modified Dietz": portfolio.ModifiedDietzReturn, "money-weighted": portfolio.MoneyWeightedReturn} {
+				if rate == nil || *rate != 0 {
+					t.Errorf("%s return is %v, want 0", name, rate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// backend/src/services/price_refresh.go
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// StartPriceRefresh refreshes, every interval, the current prices of the positions of the users with
+// a webhook subscribed to prices.refreshed, and sends the prices to those webhooks.
+func StartPriceRefresh(interval time.Duration, webhookService WebhookService, unrealizedGainsService UnrealizedGainsService) {
+	StartPeriodicJob("price-refresh", interval, func() {
+		RefreshPrices(webhookService, unrealizedGainsService)
+	})
+}
+
+// RefreshPrices runs one price refresh. Users without open positions are skipped.
+func RefreshPrices(webhookService WebhookService, unrealizedGainsService UnrealizedGainsService) {
+	userIDs, err := webhookService.SubscribedUsers(models.WebhookPricesRefreshed)
+	if err != nil {
+		logger.L.Error("Failed to list users for the price refresh", "error", err)
+		return
+	}
+	for _, userID := range userIDs {
+		report, err := unrealizedGainsService.GetUnrealizedGains(context.Background(), userID)
+		if err != nil {
+			logger.L.Warn("Could not refresh prices", "userID", userID, "error", err)
+			continue
+		}
+		if len(report.Positions) == 0 {
+			continue
+		}
+		event := models.PricesRefreshedEvent{
+			AsOf:                   report.AsOf,
+			PriceStatus:            report.PriceStatus,
+			TotalMarketValueEUR:    report.TotalMarketValueEUR,
+			TotalUnrealizedGainEUR: report.TotalUnrealizedGainEUR,
+			Positions:              make([]models.PositionPrice, 0, len(report.Positions)),
+		}
+		for _, pos := range report.Positions {
+			event.Positions = append(event.Positions, models.PositionPrice{
+				ISIN:            pos.ISIN,
+				ProductName:     pos.ProductName,
+				Quantity:        pos.Quantity,
+				CurrentPriceEUR: pos.CurrentPriceEUR,
+				MarketValueEUR:  pos.MarketValueEUR,
+				PriceStatus:     pos.PriceStatus,
+			})
+		}
+		webhookService.Dispatch(userID, models.WebhookPricesRefreshed, event)
+	}
+	if len(userIDs) > 0 {
+		logger.L.Info("Prices refreshed", "users", len(userIDs))
+	}
+}
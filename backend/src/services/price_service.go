@@ -4,6 +4,7 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,21 +15,27 @@ import (
 
 	"github.com/username/taxfolio/backend/src/database"
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
 	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/processors"
 	"golang.org/x/net/publicsuffix"
 )
 
+// errTickerNotFound is returned when the Yahoo search has no instrument for an ISIN.
+var errTickerNotFound = errors.New("no ticker symbol found on Yahoo Finance")
+
 // ... (struct definitions for yahooSearchResponse and yahooChartResponse remain the same)
 // Struct for the v1 search API to convert ISIN to Ticker
 type yahooSearchResponse struct {
-	Quotes []struct {
-		Symbol    string `json:"symbol"`
-		Exchange  string `json:"exchange"`
-		Shortname string `json:"shortname"`
-		QuoteType string `json:"quoteType"`
-		Currency  string `json:"currency"`
-	} `json:"quotes"`
+	Quotes []yahooSearchQuote `json:"quotes"`
+}
+
+type yahooSearchQuote struct {
+	Symbol    string `json:"symbol"`
+	Exchange  string `json:"exchange"`
+	Shortname string `json:"shortname"`
+	QuoteType string `json:"quoteType"` // EQUITY, ETF, MUTUALFUND, ...
+	Currency  string `json:"currency"`
 }
 
 // Struct for the v8 chart/quote API to get the price
@@ -40,6 +47,12 @@ type yahooChartResponse struct {
 				Symbol             string  `json:"symbol"`
 				RegularMarketPrice float64 `json:"regularMarketPrice"`
 			} `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Close []*float64 `json:"close"`
+				} `json:"quote"`
+			} `json:"indicators"`
 		} `json:"result"`
 		Error interface{} `json:"error"`
 	} `json:"chart"`
@@ -79,6 +92,7 @@ func (s *priceServiceImpl) initializeYahooSession() {
 	req, _ := http.NewRequest("GET", initURL, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
 	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall("yahoo_session", resp, err)
 	if err != nil {
 		logger.L.Error("Failed session init request", "error", err)
 		return
@@ -93,7 +107,8 @@ func (s *priceServiceImpl) initializeYahooSession() {
 	}
 }
 
-func (s *priceServiceImpl) GetCurrentPrices(isins []string) (map[string]PriceInfo, error) {
+// GetCurrentPrices returns the latest prices of the instruments, converted to baseCurrency.
+func (s *priceServiceImpl) GetCurrentPrices(isins []string, baseCurrency string) (map[string]PriceInfo, error) {
 	s.mu.Lock()
 	if !s.isInitialized {
 		s.mu.Unlock()
@@ -122,7 +137,7 @@ func (s *priceServiceImpl) GetCurrentPrices(isins []string) (map[string]PriceInf
 		return results, err
 	}
 
-	// 3. Combine results and convert to EUR
+	// 3. Combine results and convert to the base currency
 	for _, isin := range isins {
 		ticker, ok := isinToTickerMap[isin]
 		if !ok {
@@ -133,19 +148,19 @@ func (s *priceServiceImpl) GetCurrentPrices(isins []string) (map[string]PriceInf
 			continue
 		}
 
-		priceEUR := priceInfo.Price
-		if strings.ToUpper(priceInfo.Currency) != "EUR" {
-			rate, err := processors.GetExchangeRate(priceInfo.Currency, time.Now())
+		price := priceInfo.Price
+		if strings.ToUpper(priceInfo.Currency) != baseCurrency {
+			rate, err := processors.GetExchangeRateTo(strings.ToUpper(priceInfo.Currency), baseCurrency, time.Now())
 			if err != nil || rate == 0 {
-				logger.L.Warn("Could not get exchange rate to convert price", "currency", priceInfo.Currency, "ticker", ticker, "error", err)
+				logger.L.Warn("Could not get exchange rate to convert price", "currency", priceInfo.Currency, "base", baseCurrency, "ticker", ticker, "error", err)
 				continue
 			}
-			priceEUR = priceInfo.Price / rate
+			price = priceInfo.Price / rate
 		}
 		results[isin] = PriceInfo{
 			Status:   "OK",
-			Price:    priceEUR,
-			Currency: "EUR",
+			Price:    price,
+			Currency: baseCurrency,
 		}
 	}
 
@@ -160,9 +175,15 @@ func (s *priceServiceImpl) getIsinToTickerMap(isins []string) (map[string]string
 	}
 
 	isinsToFetch := []string{}
+	isinsToClassify := map[string]bool{}
 	for _, isin := range isins {
 		if mapping, ok := dbMappings[isin]; ok {
 			isinToTickerMap[isin] = mapping.TickerSymbol
+			// Mappings cached before the quote type was recorded are looked up once more to classify them.
+			if !mapping.QuoteType.Valid {
+				isinsToFetch = append(isinsToFetch, isin)
+				isinsToClassify[isin] = true
+			}
 		} else {
 			isinsToFetch = append(isinsToFetch, isin)
 		}
@@ -171,17 +192,26 @@ func (s *priceServiceImpl) getIsinToTickerMap(isins []string) (map[string]string
 	if len(isinsToFetch) > 0 {
 		for _, isin := range isinsToFetch {
 			time.Sleep(250 * time.Millisecond)
-			ticker, exchange, currency, err := s.fetchTickerForISIN(isin)
+			quote, err := s.fetchTickerForISIN(isin)
 			if err != nil {
 				logger.L.Warn("Could not get ticker for ISIN from API", "isin", isin, "error", err)
+				// Stop looking up mapped instruments Yahoo no longer lists; they stay unclassified.
+				if isinsToClassify[isin] && errors.Is(err, errTickerNotFound) {
+					model.UpdateMappingQuoteType(database.DB, isin, "")
+				}
 				continue
 			}
-			isinToTickerMap[isin] = ticker
+			if isinsToClassify[isin] {
+				model.UpdateMappingQuoteType(database.DB, isin, quote.QuoteType)
+				continue
+			}
+			isinToTickerMap[isin] = quote.Symbol
 			newMapping := model.ISINTickerMap{
 				ISIN:         isin,
-				TickerSymbol: ticker,
-				Exchange:     sql.NullString{String: exchange, Valid: exchange != ""},
-				Currency:     currency,
+				TickerSymbol: quote.Symbol,
+				Exchange:     sql.NullString{String: quote.Exchange, Valid: quote.Exchange != ""},
+				Currency:     quote.Currency,
+				QuoteType:    sql.NullString{String: quote.QuoteType, Valid: true},
 			}
 			model.InsertMapping(database.DB, newMapping)
 		}
@@ -209,7 +239,9 @@ func (s *priceServiceImpl) getTickerToPriceMap(isinToTickerMap map[string]string
 
 	tickersToFetch := []string{}
 	for _, ticker := range tickerList {
-		if price, ok := cachedPrices[ticker]; ok {
+		price, ok := cachedPrices[ticker]
+		metrics.CacheLookup("daily_price", ok)
+		if ok {
 			tickerToPriceMap[ticker] = price
 		} else {
 			tickersToFetch = append(tickersToFetch, ticker)
@@ -238,37 +270,37 @@ func (s *priceServiceImpl) getTickerToPriceMap(isinToTickerMap map[string]string
 }
 
 // ... (fetchTickerForISIN and getPriceForTicker functions remain the same as in the previous response)
-// fetchTickerForISIN calls Yahoo and returns ticker, exchange, and currency.
-func (s *priceServiceImpl) fetchTickerForISIN(isin string) (string, string, string, error) {
+// fetchTickerForISIN calls Yahoo and returns the best match for the ISIN: its ticker, exchange, currency and quote type.
+func (s *priceServiceImpl) fetchTickerForISIN(isin string) (yahooSearchQuote, error) {
 	searchURL := fmt.Sprintf("https://query1.finance.yahoo.com/v1/finance/search?q=%s&quotesCount=1&lang=en-US", isin)
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
-		return "", "", "", err
+		return yahooSearchQuote{}, err
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
 
 	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall("yahoo_search", resp, err)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to call Yahoo search API for ISIN %s: %w", isin, err)
+		return yahooSearchQuote{}, fmt.Errorf("failed to call Yahoo search API for ISIN %s: %w", isin, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		logger.L.Error("Yahoo search API returned non-OK status", "status", resp.Status, "isin", isin, "responseBody", string(bodyBytes))
-		return "", "", "", fmt.Errorf("yahoo search API returned non-OK status %d for ISIN %s", resp.StatusCode, isin)
+		return yahooSearchQuote{}, fmt.Errorf("yahoo search API returned non-OK status %d for ISIN %s", resp.StatusCode, isin)
 	}
 
 	var searchData yahooSearchResponse
 	if err := json.NewDecoder(resp.Body).Decode(&searchData); err != nil {
-		return "", "", "", fmt.Errorf("failed to decode Yahoo search response for ISIN %s: %w", isin, err)
+		return yahooSearchQuote{}, fmt.Errorf("failed to decode Yahoo search response for ISIN %s: %w", isin, err)
 	}
 
 	if len(searchData.Quotes) == 0 || searchData.Quotes[0].Symbol == "" {
-		return "", "", "", fmt.Errorf("no ticker symbol found for ISIN %s on Yahoo Finance", isin)
+		return yahooSearchQuote{}, fmt.Errorf("%w for ISIN %s", errTickerNotFound, isin)
 	}
-	quote := searchData.Quotes[0]
-	return quote.Symbol, quote.Exchange, quote.Currency, nil
+	return searchData.Quotes[0], nil
 }
 
 // getPriceForTicker remains largely the same
@@ -281,6 +313,7 @@ func (s *priceServiceImpl) getPriceForTicker(ticker string) (float64, string, er
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
 
 	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall("yahoo_chart", resp, err)
 	if err != nil {
 		return 0, "", fmt.Errorf("failed to call Yahoo chart API for ticker %s: %w", ticker, err)
 	}
@@ -317,3 +350,100 @@ func (s *priceServiceImpl) getPriceForTicker(ticker string) (float64, string, er
 
 	return price, currency, nil
 }
+
+// GetPriceOnDate returns the closing price of an instrument in baseCurrency on the given date,
+// or on the last trading day before it. Prices are cached in the daily_prices table.
+func (s *priceServiceImpl) GetPriceOnDate(isin string, date time.Time, baseCurrency string) (PriceInfo, error) {
+	s.mu.Lock()
+	if !s.isInitialized {
+		s.mu.Unlock()
+		s.initializeYahooSession()
+	} else {
+		s.mu.Unlock()
+	}
+
+	isinToTickerMap, err := s.getIsinToTickerMap([]string{isin})
+	if err != nil {
+		return PriceInfo{Status: "UNAVAILABLE"}, err
+	}
+	ticker, ok := isinToTickerMap[isin]
+	if !ok {
+		return PriceInfo{Status: "UNAVAILABLE"}, fmt.Errorf("no ticker mapping for ISIN %s", isin)
+	}
+
+	dateStr := date.Format("2006-01-02")
+	var dailyPrice model.DailyPrice
+	cachedPrices, err := model.GetPricesByTickersAndDate(database.DB, []string{ticker}, dateStr)
+	if err != nil {
+		logger.L.Error("Failed to get daily prices from DB", "error", err)
+	}
+	cached, found := cachedPrices[ticker]
+	metrics.CacheLookup("daily_price", found)
+	if found {
+		dailyPrice = cached
+	} else {
+		price, currency, err := s.getHistoricalPriceForTicker(ticker, date)
+		if err != nil {
+			return PriceInfo{Status: "UNAVAILABLE"}, err
+		}
+		dailyPrice = model.DailyPrice{TickerSymbol: ticker, Date: dateStr, Price: price, Currency: currency}
+		model.InsertOrUpdatePrice(database.DB, dailyPrice)
+	}
+
+	price := dailyPrice.Price
+	if strings.ToUpper(dailyPrice.Currency) != baseCurrency {
+		rate, err := processors.GetExchangeRateTo(strings.ToUpper(dailyPrice.Currency), baseCurrency, date)
+		if err != nil || rate == 0 {
+			return PriceInfo{Status: "UNAVAILABLE"}, fmt.Errorf("could not convert %s price for %s to %s: %v", dailyPrice.Currency, ticker, baseCurrency, err)
+		}
+		price = dailyPrice.Price / rate
+	}
+	return PriceInfo{Status: "OK", Price: price, Currency: baseCurrency}, nil
+}
+
+// getHistoricalPriceForTicker fetches the last daily close on or before date from the Yahoo chart API.
+func (s *priceServiceImpl) getHistoricalPriceForTicker(ticker string, date time.Time) (float64, string, error) {
+	dayEnd := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, time.UTC)
+	period1 := dayEnd.AddDate(0, 0, -10).Unix()
+	quoteURL := fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d", ticker, period1, dayEnd.Unix())
+	req, err := http.NewRequest("GET", quoteURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+
+	resp, err := s.httpClient.Do(req)
+	metrics.ExternalCall("yahoo_chart", resp, err)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to call Yahoo chart API for ticker %s: %w", ticker, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("yahoo chart API returned non-OK status %d for ticker %s", resp.StatusCode, ticker)
+	}
+
+	var chartData yahooChartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chartData); err != nil {
+		return 0, "", fmt.Errorf("failed to decode Yahoo chart response for ticker %s: %w", ticker, err)
+	}
+	if chartData.Chart.Error != nil {
+		errorJSON, _ := json.Marshal(chartData.Chart.Error)
+		return 0, "", fmt.Errorf("yahoo chart API returned an error for ticker %s: %s", ticker, string(errorJSON))
+	}
+	if len(chartData.Chart.Result) == 0 || len(chartData.Chart.Result[0].Indicators.Quote) == 0 {
+		return 0, "", fmt.Errorf("no historical data found for ticker %s", ticker)
+	}
+
+	result := chartData.Chart.Result[0]
+	closes := result.Indicators.Quote[0].Close
+	for i := len(result.Timestamp) - 1; i >= 0; i-- {
+		if i < len(closes) && closes[i] != nil && result.Timestamp[i] <= dayEnd.Unix() {
+			if result.Meta.Currency == "" {
+				return 0, "", fmt.Errorf("currency not found in API response for ticker %s", ticker)
+			}
+			return *closes[i], result.Meta.Currency, nil
+		}
+	}
+	return 0, "", fmt.Errorf("no close price on or before %s for ticker %s", date.Format("2006-01-02"), ticker)
+}
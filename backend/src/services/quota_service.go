@@ -0,0 +1,140 @@
+// backend/src/services/quota_service.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/config"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// Limits reported by QuotaExceededError.
+const (
+	QuotaLimitUploads      = "uploads_per_month"
+	QuotaLimitTransactions = "max_transactions"
+)
+
+// QuotaExceededError reports which limit of the user's plan an operation would exceed.
+type QuotaExceededError struct {
+	Plan  string
+	Limit string
+	Max   int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v: %s limit of %d reached on the %s plan", ErrQuotaExceeded, e.Limit, e.Max, e.Plan)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type quotaServiceImpl struct {
+	db    *sql.DB
+	plans map[string]models.Plan
+}
+
+// NewQuotaService creates a new QuotaService with the plan limits from the configuration.
+func NewQuotaService(db *sql.DB) QuotaService {
+	plans := map[string]models.Plan{
+		models.PlanFree: {
+			Name:            models.PlanFree,
+			UploadsPerMonth: config.Cfg.FreePlanUploadsPerMonth,
+			MaxTransactions: config.Cfg.FreePlanMaxTransactions,
+		},
+		models.PlanPremium: {
+			Name:            models.PlanPremium,
+			UploadsPerMonth: config.Cfg.PremiumPlanUploadsPerMonth,
+			MaxTransactions: config.Cfg.PremiumPlanMaxTransactions,
+		},
+	}
+	return &quotaServiceImpl{db: db, plans: plans}
+}
+
+// currentMonth is the key of the monthly upload counter, in UTC.
+func currentMonth() string {
+	return time.Now().UTC().Format("2006-01")
+}
+
+// planFor returns the limits of the user's plan. Users on a plan that is no longer configured get the free limits.
+func (s *quotaServiceImpl) planFor(userID int64) (models.Plan, error) {
+	name, err := model.GetUserPlan(s.db, userID)
+	if err != nil {
+		return models.Plan{}, fmt.Errorf("error loading plan: %w", err)
+	}
+	if plan, ok := s.plans[name]; ok {
+		return plan, nil
+	}
+	return s.plans[models.PlanFree], nil
+}
+
+// GetUsage reports the user's uploads this month and stored transactions against their plan.
+func (s *quotaServiceImpl) GetUsage(userID int64) (*models.Usage, error) {
+	plan, err := s.planFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage := &models.Usage{Plan: plan, Month: currentMonth()}
+	if usage.UploadsThisMonth, err = model.GetMonthlyUploads(s.db, userID, usage.Month); err != nil {
+		return nil, fmt.Errorf("error loading monthly uploads: %w", err)
+	}
+	if usage.StoredTransactions, err = model.CountUserTransactions(s.db, userID); err != nil {
+		return nil, fmt.Errorf("error counting transactions: %w", err)
+	}
+	return usage, nil
+}
+
+// CheckUpload returns a QuotaExceededError if the user has used up this month's uploads.
+func (s *quotaServiceImpl) CheckUpload(userID int64) error {
+	plan, err := s.planFor(userID)
+	if err != nil {
+		return err
+	}
+	if plan.UploadsPerMonth <= 0 {
+		return nil
+	}
+	uploads, err := model.GetMonthlyUploads(s.db, userID, currentMonth())
+	if err != nil {
+		return fmt.Errorf("error loading monthly uploads: %w", err)
+	}
+	if uploads >= plan.UploadsPerMonth {
+		return &QuotaExceededError{Plan: plan.Name, Limit: QuotaLimitUploads, Max: plan.UploadsPerMonth}
+	}
+	return nil
+}
+
+// RecordUpload counts an uploaded file towards this month's quota.
+func (s *quotaServiceImpl) RecordUpload(userID int64) error {
+	return model.RecordUpload(s.db, userID, currentMonth())
+}
+
+// CheckTransactionLimit returns a QuotaExceededError if, counting the rows dbTx inserted so far,
+// the user stores more transactions than their plan allows. The caller should then roll dbTx back.
+func (s *quotaServiceImpl) CheckTransactionLimit(dbTx *sql.Tx, userID int64) error {
+	plan, err := s.planFor(userID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxTransactions <= 0 {
+		return nil
+	}
+	count, err := model.CountUserTransactions(dbTx, userID)
+	if err != nil {
+		return fmt.Errorf("error counting transactions: %w", err)
+	}
+	if count > plan.MaxTransactions {
+		return &QuotaExceededError{Plan: plan.Name, Limit: QuotaLimitTransactions, Max: plan.MaxTransactions}
+	}
+	return nil
+}
+
+// SetPlan moves the user to one of the configured plans.
+func (s *quotaServiceImpl) SetPlan(userID int64, plan string) error {
+	if _, ok := s.plans[plan]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownPlan, plan)
+	}
+	return model.SetUserPlan(s.db, userID, plan)
+}
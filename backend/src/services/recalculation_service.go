@@ -0,0 +1,160 @@
+// backend/src/services/recalculation_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type recalculationServiceImpl struct {
+	transactions  model.TransactionRepository
+	db            *sql.DB
+	uploadService UploadService
+}
+
+// NewRecalculationService creates a new RecalculationService bound to the given database.
+func NewRecalculationService(db *sql.DB, transactions model.TransactionRepository, uploadService UploadService) RecalculationService {
+	return &recalculationServiceImpl{transactions: transactions, db: db, uploadService: uploadService}
+}
+
+// Recalculate drops the user's materialized reports and cached results, rebuilds them from the stored
+// transactions and reconciles the result. Returns sql.ErrNoRows when the user does not exist.
+func (s *recalculationServiceImpl) Recalculate(ctx context.Context, userID int64) (*models.ReconciliationReport, error) {
+	if _, err := model.GetUserBaseCurrency(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	dbTx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+	if err := model.DeleteMaterializedReports(dbTx, userID); err != nil {
+		return nil, fmt.Errorf("error clearing materialized reports: %w", err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing report cleanup: %w", err)
+	}
+	s.uploadService.InvalidateUserCache(userID)
+
+	result, err := s.uploadService.GetLatestUploadResult(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error rebuilding reports: %w", err)
+	}
+	if _, err := s.uploadService.GetDividendTaxSummary(ctx, userID); err != nil {
+		return nil, fmt.Errorf("error rebuilding dividend summary: %w", err)
+	}
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := reconcile(transactions, result.StockSaleDetails, result.StockHoldings)
+	report.UserID = userID
+	report.RecalculatedAt = time.Now()
+	if report.OK {
+		logger.L.Info("Recalculated user reports", "userID", userID, "transactions", report.TransactionCount)
+	} else {
+		logger.L.Warn("Recalculated user reports with reconciliation problems", "userID", userID,
+			"mismatches", len(report.Mismatches), "unmatchedSells", len(report.UnmatchedSells), "negativeLots", len(report.NegativeLots))
+	}
+	return report, nil
+}
+
+// reconcile checks the rebuilt stock report against the transactions it was computed from.
+func reconcile(transactions []models.ProcessedTransaction, sales []models.SaleDetail, holdingsByYear map[string][]models.PurchaseLot) *models.ReconciliationReport {
+	report := &models.ReconciliationReport{
+		TransactionCount: len(transactions),
+		StockSaleCount:   len(sales),
+		Mismatches:       []models.ReconciliationPosition{},
+		UnmatchedSells:   []models.UnmatchedSell{},
+		NegativeLots:     []models.NegativeLot{},
+	}
+
+	positions := make(map[string]*models.ReconciliationPosition)
+	position := func(isin, productName string) *models.ReconciliationPosition {
+		pos, ok := positions[isin]
+		if !ok {
+			pos = &models.ReconciliationPosition{ISIN: isin, ProductName: productName}
+			positions[isin] = pos
+		}
+		return pos
+	}
+
+	soldQty := make(map[string]int)
+	var sells []models.UnmatchedSell
+	for _, tx := range transactions {
+		switch {
+		case tx.TransactionType == "STOCK" && tx.BuySell == "BUY", tx.TransactionType == "SCRIP_DIVIDEND":
+			position(tx.ISIN, tx.ProductName).BoughtQuantity += tx.Quantity
+		case tx.TransactionType == "STOCK" && tx.BuySell == "SELL":
+			position(tx.ISIN, tx.ProductName).SoldQuantity += tx.Quantity
+			key := tx.ISIN + "|" + tx.Date
+			if _, ok := soldQty[key]; !ok {
+				sells = append(sells, models.UnmatchedSell{Date: tx.Date, ISIN: tx.ISIN, ProductName: tx.ProductName})
+			}
+			soldQty[key] += tx.Quantity
+		}
+	}
+
+	matchedQty := make(map[string]int)
+	for _, sale := range sales {
+		matchedQty[sale.ISIN+"|"+sale.SaleDate] += sale.Quantity
+		if sale.Quantity <= 0 {
+			report.NegativeLots = append(report.NegativeLots, models.NegativeLot{
+				Date: sale.SaleDate, ISIN: sale.ISIN, ProductName: sale.ProductName, Quantity: sale.Quantity,
+			})
+		}
+	}
+	for _, sell := range sells {
+		key := sell.ISIN + "|" + sell.Date
+		if soldQty[key] > matchedQty[key] {
+			sell.SoldQuantity = soldQty[key]
+			sell.MatchedQuantity = matchedQty[key]
+			report.UnmatchedSells = append(report.UnmatchedSells, sell)
+		}
+	}
+
+	years := make([]string, 0, len(holdingsByYear))
+	for year := range holdingsByYear {
+		years = append(years, year)
+	}
+	sort.Strings(years)
+	for _, year := range years {
+		for _, lot := range holdingsByYear[year] {
+			if lot.Quantity <= 0 {
+				report.NegativeLots = append(report.NegativeLots, models.NegativeLot{
+					Year: year, Date: lot.BuyDate, ISIN: lot.ISIN, ProductName: lot.ProductName, Quantity: lot.Quantity,
+				})
+			}
+		}
+	}
+	if len(years) > 0 {
+		for _, lot := range holdingsByYear[years[len(years)-1]] {
+			position(lot.ISIN, lot.ProductName).HeldQuantity += lot.Quantity
+		}
+	}
+
+	for _, pos := range positions {
+		pos.NetQuantity = pos.BoughtQuantity - pos.SoldQuantity
+		pos.Difference = pos.NetQuantity - pos.HeldQuantity
+		if pos.Difference != 0 {
+			report.Mismatches = append(report.Mismatches, *pos)
+		}
+	}
+	sort.Slice(report.Mismatches, func(i, j int) bool { return report.Mismatches[i].ISIN < report.Mismatches[j].ISIN })
+	sort.SliceStable(report.UnmatchedSells, func(i, j int) bool {
+		return utils.ParseDate(report.UnmatchedSells[i].Date).Before(utils.ParseDate(report.UnmatchedSells[j].Date))
+	})
+
+	report.OK = len(report.Mismatches) == 0 && len(report.UnmatchedSells) == 0 && len(report.NegativeLots) == 0
+	return report
+}
@@ -0,0 +1,231 @@
+// backend/src/services/report_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type reportServiceImpl struct {
+	transactions  model.TransactionRepository
+	db            *sql.DB
+	uploadService UploadService
+}
+
+// NewReportService creates a new ReportService.
+func NewReportService(db *sql.DB, transactions model.TransactionRepository, uploadService UploadService) ReportService {
+	return &reportServiceImpl{
+		transactions:  transactions,
+		db:            db,
+		uploadService: uploadService,
+	}
+}
+
+// GetAnnualReport assembles the realized stock and option gains, dividends by country, fees and interest
+// of a tax year, and the stock lots still held at its end.
+func (s *reportServiceImpl) GetAnnualReport(ctx context.Context, userID int64, year string) (*models.AnnualReport, error) {
+	settings, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+	fiscalYear, err := models.ParseFiscalYearStart(settings.FiscalYearStart)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AnnualReport{
+		Year:               year,
+		BaseCurrency:       settings.BaseCurrency,
+		StockSales:         []models.SaleDetail{},
+		OptionSales:        []models.OptionSaleDetail{},
+		DividendsByCountry: map[string]models.DividendCountrySummary{},
+		Fees:               []models.FeeDetail{},
+		Interest:           []models.InterestDetail{},
+	}
+
+	sales, err := s.uploadService.GetStockSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if sale.TaxYear == year {
+			report.StockSales = append(report.StockSales, sale)
+			report.StockGainsEUR = utils.RoundMoney(report.StockGainsEUR + utils.RoundAmount(sale.GainEUR).Float64())
+		}
+	}
+
+	options, err := s.uploadService.GetOptionSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if option.TaxYear == year {
+			report.OptionSales = append(report.OptionSales, option)
+			report.OptionGainsEUR = utils.RoundMoney(report.OptionGainsEUR + utils.RoundMoney(option.Delta-option.Commission))
+		}
+	}
+
+	dividends, err := s.uploadService.GetDividendTaxSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for country, summary := range dividends[year] {
+		report.DividendsByCountry[country] = summary
+		report.DividendGrossEUR = utils.RoundMoney(report.DividendGrossEUR + summary.GrossAmt)
+		report.DividendTaxEUR = utils.RoundMoney(report.DividendTaxEUR + summary.TaxedAmt)
+	}
+
+	fees, err := s.uploadService.GetFeeDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fee := range fees {
+		if fiscalYear.Label(utils.ParseDate(fee.Date)) == year {
+			report.Fees = append(report.Fees, fee)
+			report.FeesEUR = utils.RoundMoney(report.FeesEUR + fee.AmountEUR)
+		}
+	}
+
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range transactions {
+		if tx.TransactionType != "INTEREST" || fiscalYear.Label(utils.ParseDate(tx.Date)) != year {
+			continue
+		}
+		line := models.InterestDetail{
+			Date:        tx.Date,
+			Description: tx.ProductName,
+			Source:      tx.Source,
+			Amount:      tx.Amount.Float64(),
+			Currency:    tx.Currency,
+			AmountEUR:   utils.RoundAmount(tx.AmountEUR).Float64(),
+		}
+		report.Interest = append(report.Interest, line)
+		report.InterestEUR = utils.RoundMoney(report.InterestEUR + line.AmountEUR)
+	}
+
+	if report.Holdings, err = s.uploadService.GetStockHoldingsForYear(ctx, userID, year); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// CompareYears totals the realized gains, dividends, fees and net cash contributions of each of the
+// given tax years, and the change of each total from one year to the next. years must be sorted.
+func (s *reportServiceImpl) CompareYears(ctx context.Context, userID int64, years []string) (*models.YearComparison, error) {
+	settings, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+	fiscalYear, err := models.ParseFiscalYearStart(settings.FiscalYearStart)
+	if err != nil {
+		return nil, err
+	}
+
+	figures := make(map[string]*models.YearFigures, len(years))
+	comparison := &models.YearComparison{
+		BaseCurrency: settings.BaseCurrency,
+		Years:        make([]models.YearFigures, len(years)),
+		Deltas:       []models.YearDelta{},
+	}
+	for i, year := range years {
+		comparison.Years[i].Year = year
+		figures[year] = &comparison.Years[i]
+	}
+
+	sales, err := s.uploadService.GetStockSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if f, ok := figures[sale.TaxYear]; ok {
+			f.StockGainsEUR = utils.RoundMoney(f.StockGainsEUR + utils.RoundAmount(sale.GainEUR).Float64())
+		}
+	}
+
+	options, err := s.uploadService.GetOptionSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if f, ok := figures[option.TaxYear]; ok {
+			f.OptionGainsEUR = utils.RoundMoney(f.OptionGainsEUR + utils.RoundMoney(option.Delta-option.Commission))
+		}
+	}
+
+	dividends, err := s.uploadService.GetDividendTaxSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for year, countries := range dividends {
+		f, ok := figures[year]
+		if !ok {
+			continue
+		}
+		for _, summary := range countries {
+			f.DividendGrossEUR = utils.RoundMoney(f.DividendGrossEUR + summary.GrossAmt)
+			f.DividendTaxEUR = utils.RoundMoney(f.DividendTaxEUR + summary.TaxedAmt)
+		}
+	}
+
+	fees, err := s.uploadService.GetFeeDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fee := range fees {
+		if f, ok := figures[fiscalYear.Label(utils.ParseDate(fee.Date))]; ok {
+			f.FeesEUR = utils.RoundMoney(f.FeesEUR + fee.AmountEUR)
+		}
+	}
+
+	transactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range transactions {
+		if tx.TransactionType != "CASH" {
+			continue
+		}
+		f, ok := figures[fiscalYear.Label(utils.ParseDate(tx.Date))]
+		if !ok {
+			continue
+		}
+		switch tx.TransactionSubType {
+		case "DEPOSIT":
+			f.DepositsEUR = utils.RoundMoney(f.DepositsEUR + utils.RoundAmount(tx.AmountEUR).Float64())
+		case "WITHDRAWAL":
+			f.WithdrawalsEUR = utils.RoundMoney(f.WithdrawalsEUR + utils.RoundAmount(tx.AmountEUR).Float64())
+		}
+	}
+
+	for i := range comparison.Years {
+		f := &comparison.Years[i]
+		f.RealizedGainsEUR = utils.RoundMoney(f.StockGainsEUR + f.OptionGainsEUR)
+		f.ContributionsEUR = utils.RoundMoney(f.DepositsEUR + f.WithdrawalsEUR)
+		if i == 0 {
+			continue
+		}
+		prev := comparison.Years[i-1]
+		comparison.Deltas = append(comparison.Deltas, models.YearDelta{
+			From:             prev.Year,
+			To:               f.Year,
+			StockGainsEUR:    utils.RoundMoney(f.StockGainsEUR - prev.StockGainsEUR),
+			OptionGainsEUR:   utils.RoundMoney(f.OptionGainsEUR - prev.OptionGainsEUR),
+			RealizedGainsEUR: utils.RoundMoney(f.RealizedGainsEUR - prev.RealizedGainsEUR),
+			DividendGrossEUR: utils.RoundMoney(f.DividendGrossEUR - prev.DividendGrossEUR),
+			DividendTaxEUR:   utils.RoundMoney(f.DividendTaxEUR - prev.DividendTaxEUR),
+			FeesEUR:          utils.RoundMoney(f.FeesEUR - prev.FeesEUR),
+			DepositsEUR:      utils.RoundMoney(f.DepositsEUR - prev.DepositsEUR),
+			WithdrawalsEUR:   utils.RoundMoney(f.WithdrawalsEUR - prev.WithdrawalsEUR),
+			ContributionsEUR: utils.RoundMoney(f.ContributionsEUR - prev.ContributionsEUR),
+		})
+	}
+	return comparison, nil
+}
@@ -0,0 +1,39 @@
+// backend/src/services/scheduler.go
+package services
+
+import (
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+)
+
+// StartPeriodicJob runs job in a background goroutine every interval.
+// A non-positive interval disables the job. Panics inside the job are recovered
+// and logged so a single failing run does not take down the server.
+func StartPeriodicJob(name string, interval time.Duration, job func()) {
+	if interval <= 0 {
+		logger.L.Info("Periodic job disabled", "job", name)
+		return
+	}
+
+	logger.L.Info("Scheduling periodic job", "job", name, "interval", interval.String())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runJob(name, job)
+		}
+	}()
+}
+
+// runJob executes a single run of a periodic job with panic protection.
+func runJob(name string, job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.L.Error("Periodic job panicked", "job", name, "panic", r)
+		}
+	}()
+	startTime := time.Now()
+	job()
+	logger.L.Debug("Periodic job finished", "job", name, "duration", time.Since(startTime))
+}
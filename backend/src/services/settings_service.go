@@ -0,0 +1,118 @@
+// backend/src/services/settings_service.go
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/username/taxfolio/backend/src/i18n"
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/taxrules"
+)
+
+type settingsServiceImpl struct {
+	db            *sql.DB
+	uploadService UploadService
+}
+
+// NewSettingsService creates a new SettingsService.
+func NewSettingsService(db *sql.DB, uploadService UploadService) SettingsService {
+	return &settingsServiceImpl{
+		db:            db,
+		uploadService: uploadService,
+	}
+}
+
+// GetSettings returns the user's preferences, with defaults for the ones never set.
+func (s *settingsServiceImpl) GetSettings(userID int64) (models.UserSettings, error) {
+	return model.GetUserSettings(s.db, userID)
+}
+
+// UpdateSettings validates every preference before changing any. A new base currency or fiscal year
+// goes through the upload service, which converts stored amounts and drops the derived reports.
+func (s *settingsServiceImpl) UpdateSettings(userID int64, settings models.UserSettings) (models.UserSettings, error) {
+	settings, err := normalizeSettings(settings)
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	current, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return models.UserSettings{}, fmt.Errorf("error loading settings: %w", err)
+	}
+
+	if settings.BaseCurrency != current.BaseCurrency {
+		if err := s.uploadService.SetBaseCurrency(userID, settings.BaseCurrency); err != nil {
+			return models.UserSettings{}, err
+		}
+	}
+	if settings.FiscalYearStart != current.FiscalYearStart {
+		if err := s.uploadService.SetFiscalYear(userID, settings.FiscalYearStart); err != nil {
+			return models.UserSettings{}, err
+		}
+	}
+
+	dbTx, err := s.db.Begin()
+	if err != nil {
+		return models.UserSettings{}, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err := model.SaveUserSettings(dbTx, userID, settings); err != nil {
+		return models.UserSettings{}, fmt.Errorf("error saving settings: %w", err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		return models.UserSettings{}, fmt.Errorf("error committing settings: %w", err)
+	}
+	if settings.TaxCountry != current.TaxCountry {
+		// Cached sales carry the holding period rules of the previous tax residence.
+		s.uploadService.InvalidateUserCache(userID)
+	} else if err := model.BumpDataVersion(s.db, userID); err != nil {
+		logger.L.Error("Failed to bump data version; report ETags may be stale", "userID", userID, "error", err)
+	}
+
+	logger.L.Info("Updated user settings", "userID", userID)
+	return settings, nil
+}
+
+// normalizeSettings checks each preference against the values the processors support and returns them
+// in their canonical form.
+func normalizeSettings(settings models.UserSettings) (models.UserSettings, error) {
+	settings.BaseCurrency = strings.ToUpper(strings.TrimSpace(settings.BaseCurrency))
+	if !processors.SupportedBaseCurrencies[settings.BaseCurrency] {
+		return settings, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, settings.BaseCurrency)
+	}
+
+	settings.MatchingMethod = strings.ToUpper(strings.TrimSpace(settings.MatchingMethod))
+	if settings.MatchingMethod != models.MatchingFIFO {
+		return settings, fmt.Errorf("%w: unsupported matching method %q, only %s is available", ErrInvalidSettings, settings.MatchingMethod, models.MatchingFIFO)
+	}
+
+	locale, ok := i18n.Normalize(settings.Locale)
+	if !ok {
+		return settings, fmt.Errorf("%w: unsupported locale %q, use pt-PT or en-US", ErrInvalidSettings, settings.Locale)
+	}
+	settings.Locale = locale
+
+	fiscalYear, err := models.ParseFiscalYearStart(strings.TrimSpace(settings.FiscalYearStart))
+	if err != nil {
+		return settings, fmt.Errorf("%w: %v", ErrInvalidFiscalYear, err)
+	}
+	settings.FiscalYearStart = fiscalYear.String()
+
+	settings.DefaultAccount = strings.ToLower(strings.TrimSpace(settings.DefaultAccount))
+	if settings.DefaultAccount != "" && !slices.Contains(parsers.Sources, settings.DefaultAccount) {
+		return settings, fmt.Errorf("%w: unknown default account %q, use one of %s", ErrInvalidSettings, settings.DefaultAccount, strings.Join(parsers.Sources, ", "))
+	}
+
+	settings.TaxCountry = strings.ToUpper(strings.TrimSpace(settings.TaxCountry))
+	if !slices.Contains(taxrules.Countries, settings.TaxCountry) {
+		return settings, fmt.Errorf("%w: no tax rules for country %q, use one of %s", ErrInvalidSettings, settings.TaxCountry, strings.Join(taxrules.Countries, ", "))
+	}
+	return settings, nil
+}
@@ -0,0 +1,135 @@
+// backend/src/services/share_link_service.go
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/security"
+)
+
+// ErrInvalidShareLink is returned when a share link's scopes, year or lifetime are not acceptable.
+var ErrInvalidShareLink = errors.New("invalid share link")
+
+const (
+	maxShareLinks             = 20 // Unexpired links per user
+	defaultShareLinkDays      = 30
+	maxShareLinkDays          = 365
+	maxShareLinkLabelLength   = 100
+	shareLinkTokenPrefix      = "shr_"
+	shareLinkTouchGranularity = time.Minute // last_used_at is not rewritten more often than this
+)
+
+type shareLinkServiceImpl struct {
+	db *sql.DB
+}
+
+// NewShareLinkService creates a new ShareLinkService.
+func NewShareLinkService(db *sql.DB) ShareLinkService {
+	return &shareLinkServiceImpl{db: db}
+}
+
+func (s *shareLinkServiceImpl) GetShareLinks(userID int64) ([]models.ShareLink, error) {
+	return model.GetShareLinks(s.db, userID)
+}
+
+// CreateShareLink creates a link opening the reports of link.Scopes, of link.Year only when it is
+// set, for expiresInDays days (defaultShareLinkDays when 0). Its token is returned in the result only
+// this once; the database keeps its hash.
+func (s *shareLinkServiceImpl) CreateShareLink(userID int64, link models.ShareLink, expiresInDays int) (*models.ShareLink, error) {
+	if err := validateShareLink(&link); err != nil {
+		return nil, err
+	}
+	if expiresInDays == 0 {
+		expiresInDays = defaultShareLinkDays
+	}
+	if expiresInDays < 1 || expiresInDays > maxShareLinkDays {
+		return nil, fmt.Errorf("%w: the link must expire in 1 to %d days", ErrInvalidShareLink, maxShareLinkDays)
+	}
+
+	now := time.Now()
+	count, err := model.CountShareLinks(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxShareLinks {
+		return nil, fmt.Errorf("%w: no more than %d share links may be active at once", ErrInvalidShareLink, maxShareLinks)
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate share link token: %w", err)
+	}
+	token := shareLinkTokenPrefix + base64.RawURLEncoding.EncodeToString(tokenBytes)
+	link.UserID = userID
+	link.TokenHash = security.HashToken(token)
+	link.ExpiresAt = now.AddDate(0, 0, expiresInDays)
+	if err := model.CreateShareLink(s.db, &link); err != nil {
+		return nil, err
+	}
+	link.Token = token
+	return &link, nil
+}
+
+func (s *shareLinkServiceImpl) DeleteShareLink(userID, id int64) error {
+	return model.DeleteShareLink(s.db, userID, id)
+}
+
+// ResolveShareLink returns the unexpired link whose token is token, recording that it was used.
+func (s *shareLinkServiceImpl) ResolveShareLink(token string) (*models.ShareLink, error) {
+	if !strings.HasPrefix(token, shareLinkTokenPrefix) {
+		return nil, model.ErrShareLinkNotFound
+	}
+	now := time.Now()
+	link, err := model.GetShareLinkByTokenHash(s.db, security.HashToken(token), now)
+	if err != nil {
+		return nil, err
+	}
+	if link.LastUsedAt == nil || now.Sub(*link.LastUsedAt) >= shareLinkTouchGranularity {
+		if err := model.TouchShareLink(s.db, link.ID, now); err != nil {
+			logger.L.Warn("Could not record share link use", "shareLinkID", link.ID, "error", err)
+		}
+		link.LastUsedAt = &now
+	}
+	return link, nil
+}
+
+// validateShareLink checks a share link's label, scopes and year, removing repeated scopes.
+func validateShareLink(link *models.ShareLink) error {
+	link.Label = strings.TrimSpace(link.Label)
+	if len(link.Label) > maxShareLinkLabelLength {
+		return fmt.Errorf("%w: the label must be at most %d characters", ErrInvalidShareLink, maxShareLinkLabelLength)
+	}
+
+	if len(link.Scopes) == 0 {
+		return fmt.Errorf("%w: grant at least one of %s", ErrInvalidShareLink, strings.Join(models.ShareScopes, ", "))
+	}
+	scopes := []string{}
+	for _, scope := range link.Scopes {
+		if !slices.Contains(models.ShareScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q, expected one of %s", ErrInvalidShareLink, scope, strings.Join(models.ShareScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	link.Scopes = scopes
+
+	link.Year = strings.TrimSpace(link.Year)
+	if link.Year != "" {
+		if _, err := strconv.Atoi(link.Year); err != nil || len(link.Year) != 4 {
+			return fmt.Errorf("%w: the year must use the format YYYY", ErrInvalidShareLink)
+		}
+	}
+	return nil
+}
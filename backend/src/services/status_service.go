@@ -0,0 +1,96 @@
+// backend/src/services/status_service.go
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// Announcements are shown in a banner, so they are kept short.
+const maxAnnouncementLength = 500
+
+// ErrInvalidAnnouncement is returned when an announcement is empty, too long, has an unknown
+// level or ends in the past.
+var ErrInvalidAnnouncement = errors.New("invalid announcement")
+
+var announcementLevels = map[string]bool{
+	models.AnnouncementInfo:        true,
+	models.AnnouncementWarning:     true,
+	models.AnnouncementMaintenance: true,
+}
+
+type statusServiceImpl struct {
+	db *sql.DB
+}
+
+// NewStatusService creates a new StatusService bound to the given database.
+func NewStatusService(db *sql.DB) StatusService {
+	return &statusServiceImpl{db: db}
+}
+
+// GetStatus returns the service status and the active announcement, if any.
+func (s *statusServiceImpl) GetStatus() (*models.ServiceStatus, error) {
+	now := time.Now()
+	status := &models.ServiceStatus{Status: models.ServiceStatusOK, ServerTime: now.UTC()}
+
+	announcement, err := model.GetActiveAnnouncement(s.db, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading announcement: %w", err)
+	}
+	status.Announcement = announcement
+	if announcement.Level == models.AnnouncementMaintenance {
+		status.Status = models.ServiceStatusMaintenance
+	}
+	return status, nil
+}
+
+// SetAnnouncement replaces the active announcement.
+func (s *statusServiceImpl) SetAnnouncement(req models.AnnouncementRequest) (*models.Announcement, error) {
+	now := time.Now()
+	announcement := models.Announcement{
+		Message:   strings.TrimSpace(req.Message),
+		Level:     req.Level,
+		EndsAt:    req.EndsAt,
+		CreatedAt: now,
+	}
+	if announcement.Level == "" {
+		announcement.Level = models.AnnouncementInfo
+	}
+	switch {
+	case announcement.Message == "":
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidAnnouncement)
+	case len(announcement.Message) > maxAnnouncementLength:
+		return nil, fmt.Errorf("%w: message is longer than %d characters", ErrInvalidAnnouncement, maxAnnouncementLength)
+	case !announcementLevels[announcement.Level]:
+		return nil, fmt.Errorf("%w: unknown level %q", ErrInvalidAnnouncement, announcement.Level)
+	case announcement.EndsAt != nil && !announcement.EndsAt.After(now):
+		return nil, fmt.Errorf("%w: ends_at is in the past", ErrInvalidAnnouncement)
+	}
+
+	id, err := model.ReplaceAnnouncement(s.db, announcement, now)
+	if err != nil {
+		return nil, fmt.Errorf("error saving announcement: %w", err)
+	}
+	announcement.ID = id
+	logger.L.Info("Announcement set", "id", id, "level", announcement.Level, "endsAt", announcement.EndsAt)
+	return &announcement, nil
+}
+
+// ClearAnnouncement ends the active announcement.
+func (s *statusServiceImpl) ClearAnnouncement() error {
+	if err := model.EndAnnouncements(s.db, time.Now()); err != nil {
+		return fmt.Errorf("error clearing announcement: %w", err)
+	}
+	logger.L.Info("Announcement cleared")
+	return nil
+}
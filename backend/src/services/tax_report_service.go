@@ -0,0 +1,80 @@
+// backend/src/services/tax_report_service.go
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/taxrules"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type taxReportServiceImpl struct {
+	db            *sql.DB
+	uploadService UploadService
+}
+
+// NewTaxReportService creates a new TaxReportService.
+func NewTaxReportService(db *sql.DB, uploadService UploadService) TaxReportService {
+	return &taxReportServiceImpl{
+		db:            db,
+		uploadService: uploadService,
+	}
+}
+
+// GetReport applies the rules of the user's tax residence to the sales, closed options, dividends and
+// fees of a tax year.
+func (s *taxReportServiceImpl) GetReport(ctx context.Context, userID int64, year string) (*models.TaxReport, error) {
+	settings, err := model.GetUserSettings(s.db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+	rules, err := taxrules.Get(settings.TaxCountry)
+	if err != nil {
+		return nil, err
+	}
+
+	in := taxrules.Input{TaxYear: year}
+	sales, err := s.uploadService.GetStockSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if sale.TaxYear == year {
+			in.Sales = append(in.Sales, sale)
+		}
+	}
+	options, err := s.uploadService.GetOptionSaleDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if option.TaxYear == year {
+			in.Options = append(in.Options, option)
+		}
+	}
+	dividends, err := s.uploadService.GetDividendTaxSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	in.Dividends = dividends[year]
+	fees, err := s.uploadService.GetFeeDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	fiscalYear, err := models.ParseFiscalYearStart(settings.FiscalYearStart)
+	if err != nil {
+		return nil, err
+	}
+	for _, fee := range fees {
+		if fiscalYear.Label(utils.ParseDate(fee.Date)) == year {
+			in.Fees = append(in.Fees, fee)
+		}
+	}
+
+	report := rules.Apply(in)
+	return &report, nil
+}
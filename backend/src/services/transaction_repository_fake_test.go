@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// memoryTransactionRepository is a model.TransactionRepository kept in memory, for testing the
+// services without a database. The database transactions its writes take are ignored: writes apply
+// at once, and are not rolled back. Transactions are listed in the order they were stored.
+type memoryTransactionRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   []memoryTransaction
+}
+
+type memoryTransaction struct {
+	userID int64
+	tx     models.ProcessedTransaction
+}
+
+var _ model.TransactionRepository = (*memoryTransactionRepository)(nil)
+
+func newMemoryTransactionRepository() *memoryTransactionRepository {
+	return &memoryTransactionRepository{nextID: 1}
+}
+
+// list returns the user's transactions with an id above afterID that keep returns true for.
+func (r *memoryTransactionRepository) list(userID, afterID int64, keep func(models.ProcessedTransaction) bool) []models.ProcessedTransaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var txs []models.ProcessedTransaction
+	for _, row := range r.rows {
+		if row.userID == userID && row.tx.ID > afterID && (keep == nil || keep(row.tx)) {
+			txs = append(txs, row.tx)
+		}
+	}
+	return txs
+}
+
+func (r *memoryTransactionRepository) ListByUser(ctx context.Context, userID int64) ([]models.ProcessedTransaction, error) {
+	return r.list(userID, 0, nil), nil
+}
+
+func (r *memoryTransactionRepository) ListByUserAfter(ctx context.Context, userID, afterID int64) ([]models.ProcessedTransaction, error) {
+	return r.list(userID, afterID, nil), nil
+}
+
+func (r *memoryTransactionRepository) ListByUserAfterTx(dbTx *sql.Tx, userID, afterID int64) ([]models.ProcessedTransaction, error) {
+	return r.list(userID, afterID, nil), nil
+}
+
+func (r *memoryTransactionRepository) ListISINs(ctx context.Context, userID int64) ([]string, error) {
+	var isins []string
+	for _, tx := range r.list(userID, 0, nil) {
+		if tx.ISIN != "" && !slices.Contains(isins, tx.ISIN) {
+			isins = append(isins, tx.ISIN)
+		}
+	}
+	return isins, nil
+}
+
+func (r *memoryTransactionRepository) CountByUser(ctx context.Context, userID int64) (int, error) {
+	return len(r.list(userID, 0, nil)), nil
+}
+
+func (r *memoryTransactionRepository) LastID(ctx context.Context, userID int64) (int64, error) {
+	var last int64
+	for _, tx := range r.list(userID, 0, nil) {
+		last = max(last, tx.ID)
+	}
+	return last, nil
+}
+
+func (r *memoryTransactionRepository) Insert(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inserted := 0
+	for _, tx := range txs {
+		if slices.ContainsFunc(r.rows, func(row memoryTransaction) bool {
+			return row.userID == userID && row.tx.HashId == tx.HashId
+		}) {
+			continue
+		}
+		tx.ID = r.nextID
+		r.nextID++
+		r.rows = append(r.rows, memoryTransaction{userID: userID, tx: tx})
+		inserted++
+	}
+	return inserted, nil
+}
+
+// update applies change to the user's transaction id, when there is one.
+func (r *memoryTransactionRepository) update(userID, id int64, change func(*models.ProcessedTransaction)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.rows {
+		if r.rows[i].tx.ID == id && (userID == 0 || r.rows[i].userID == userID) {
+			change(&r.rows[i].tx)
+		}
+	}
+}
+
+func (r *memoryTransactionRepository) UpdateExchangeRates(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction) error {
+	for _, tx := range txs {
+		r.update(userID, tx.ID, func(stored *models.ProcessedTransaction) {
+			stored.ExchangeRate, stored.ExchangeRateDate, stored.AmountEUR = tx.ExchangeRate, tx.ExchangeRateDate, tx.AmountEUR
+		})
+	}
+	return nil
+}
+
+func (r *memoryTransactionRepository) FindSameTrades(dbTx *sql.Tx, userID int64, tx models.ProcessedTransaction) ([]models.ProcessedTransaction, error) {
+	return r.list(userID, 0, func(stored models.ProcessedTransaction) bool {
+		return stored.Source == tx.Source && stored.OrderID == tx.OrderID && stored.Date == tx.Date && stored.BuySell == tx.BuySell &&
+			stored.OriginalQuantity == tx.OriginalQuantity && stored.TransactionType == tx.TransactionType
+	}), nil
+}
+
+func (r *memoryTransactionRepository) Replace(dbTx *sql.Tx, id int64, tx models.ProcessedTransaction) error {
+	r.update(0, id, func(stored *models.ProcessedTransaction) {
+		// Replace keeps what identifies the row and where it came from, as the SQL UPDATE does.
+		tx.ID, tx.Date, tx.Source, tx.TransactionType, tx.BuySell, tx.OrderID = stored.ID, stored.Date, stored.Source, stored.TransactionType, stored.BuySell, stored.OrderID
+		*stored = tx
+	})
+	return nil
+}
+
+func (r *memoryTransactionRepository) DeleteByUser(dbTx *sql.Tx, userID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.rows)
+	r.rows = slices.DeleteFunc(r.rows, func(row memoryTransaction) bool { return row.userID == userID })
+	return int64(before - len(r.rows)), nil
+}
+
+func (r *memoryTransactionRepository) Search(ctx context.Context, userID int64, terms []string, limit int) ([]models.ProcessedTransaction, error) {
+	found := r.list(userID, 0, func(tx models.ProcessedTransaction) bool {
+		text := strings.ToLower(strings.Join([]string{tx.ProductName, tx.ISIN, tx.Description, tx.OrderID}, " "))
+		for _, term := range terms {
+			if !strings.Contains(text, strings.ToLower(term)) {
+				return false
+			}
+		}
+		return true
+	})
+	slices.Reverse(found)
+	return found[:min(limit, len(found))], nil
+}
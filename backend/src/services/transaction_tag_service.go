@@ -0,0 +1,137 @@
+// backend/src/services/transaction_tag_service.go
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const (
+	maxTagsPerTransaction = 10
+	maxTagLength          = 32
+	maxNoteLength         = 1000
+)
+
+// ErrInvalidAnnotation is returned when a note or tag list breaks the length limits.
+var ErrInvalidAnnotation = errors.New("invalid transaction annotation")
+
+type transactionTagServiceImpl struct {
+	db *sql.DB
+}
+
+// NewTransactionTagService creates a new TransactionTagService.
+func NewTransactionTagService(db *sql.DB) TransactionTagService {
+	return &transactionTagServiceImpl{db: db}
+}
+
+func (s *transactionTagServiceImpl) GetTags(userID int64) ([]models.TagCount, error) {
+	return model.GetTagCounts(s.db, userID)
+}
+
+func (s *transactionTagServiceImpl) GetAnnotations(userID int64) (map[int64]*models.TransactionAnnotation, error) {
+	return model.GetTransactionAnnotations(s.db, userID)
+}
+
+// SetTags replaces the tags of a transaction. Tags are trimmed and duplicates dropped.
+func (s *transactionTagServiceImpl) SetTags(userID, transactionID int64, tags []string) (*models.TransactionAnnotation, error) {
+	cleaned, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.SetTransactionTags(s.db, userID, transactionID, cleaned); err != nil {
+		return nil, err
+	}
+	if err := model.BumpDataVersion(s.db, userID); err != nil {
+		return nil, err
+	}
+	return s.annotation(userID, transactionID)
+}
+
+// SetNote stores the note of a transaction; an empty note removes it.
+func (s *transactionTagServiceImpl) SetNote(userID, transactionID int64, note string) (*models.TransactionAnnotation, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidAnnotation, maxNoteLength)
+	}
+	if err := model.SetTransactionNote(s.db, userID, transactionID, note); err != nil {
+		return nil, err
+	}
+	if err := model.BumpDataVersion(s.db, userID); err != nil {
+		return nil, err
+	}
+	return s.annotation(userID, transactionID)
+}
+
+func (s *transactionTagServiceImpl) annotation(userID, transactionID int64) (*models.TransactionAnnotation, error) {
+	annotations, err := model.GetTransactionAnnotations(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := annotations[transactionID]; ok {
+		return a, nil
+	}
+	return &models.TransactionAnnotation{TransactionID: transactionID, Tags: []string{}}, nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidAnnotation, tag, maxTagLength)
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	if len(cleaned) > maxTagsPerTransaction {
+		return nil, fmt.Errorf("%w: at most %d tags per transaction", ErrInvalidAnnotation, maxTagsPerTransaction)
+	}
+	sort.Strings(cleaned)
+	return cleaned, nil
+}
+
+// TagFilter selects report rows linked to transactions carrying any of the requested tags.
+// Computed rows such as stock sales carry no transaction ID, so they are linked by date and ISIN.
+type TagFilter struct {
+	ids  map[int64]bool
+	keys map[string]bool
+}
+
+// NewTagFilter builds the filter for the tags. It returns nil when no tags are given; a nil filter matches everything.
+func (s *transactionTagServiceImpl) NewTagFilter(userID int64, tags []string) (*TagFilter, error) {
+	cleaned, err := normalizeTags(tags)
+	if err != nil || len(cleaned) == 0 {
+		return nil, err
+	}
+	refs, err := model.GetTaggedTransactionRefs(s.db, userID, cleaned)
+	if err != nil {
+		return nil, err
+	}
+	f := &TagFilter{ids: make(map[int64]bool, len(refs)), keys: make(map[string]bool, len(refs))}
+	for _, ref := range refs {
+		f.ids[ref.ID] = true
+		f.keys[ref.Date+"|"+ref.ISIN] = true
+	}
+	return f, nil
+}
+
+// MatchTransaction reports whether the transaction itself carries one of the tags.
+func (f *TagFilter) MatchTransaction(tx models.ProcessedTransaction) bool {
+	return f == nil || f.ids[tx.ID]
+}
+
+// MatchSale reports whether the sale or the purchase it closes carries one of the tags.
+func (f *TagFilter) MatchSale(sale models.SaleDetail) bool {
+	return f == nil || f.keys[sale.SaleDate+"|"+sale.ISIN] || f.keys[sale.BuyDate+"|"+sale.ISIN]
+}
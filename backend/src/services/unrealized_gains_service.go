@@ -0,0 +1,140 @@
+// backend/src/services/unrealized_gains_service.go
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+type unrealizedGainsServiceImpl struct {
+	uploadService UploadService
+	priceService  PriceService
+}
+
+// NewUnrealizedGainsService creates a new UnrealizedGainsService.
+func NewUnrealizedGainsService(uploadService UploadService, priceService PriceService) UnrealizedGainsService {
+	return &unrealizedGainsServiceImpl{
+		uploadService: uploadService,
+		priceService:  priceService,
+	}
+}
+
+// GetUnrealizedGains values the current open lots at live prices, per lot and per ISIN.
+func (s *unrealizedGainsServiceImpl) GetUnrealizedGains(ctx context.Context, userID int64) (*models.UnrealizedGainsReport, error) {
+	now := time.Now()
+	lots, err := s.uploadService.GetStockHoldingsForYear(ctx, userID, strconv.Itoa(now.Year()))
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]*models.UnrealizedPosition)
+	var isins []string
+	for _, lot := range lots {
+		if lot.ISIN == "" || lot.Quantity <= 0 {
+			continue
+		}
+		pos, ok := positions[lot.ISIN]
+		if !ok {
+			pos = &models.UnrealizedPosition{ISIN: lot.ISIN, ProductName: lot.ProductName, PriceStatus: "UNAVAILABLE"}
+			positions[lot.ISIN] = pos
+			if !strings.HasPrefix(strings.ToLower(lot.ISIN), "unknown") {
+				isins = append(isins, lot.ISIN)
+			}
+		}
+		costBasis := utils.RoundMoney(-lot.BuyAmountEUR) // Purchases are stored as negative amounts
+		pos.Quantity += lot.Quantity
+		pos.CostBasisEUR += costBasis
+
+		unrealizedLot := models.UnrealizedLot{
+			BuyDate:      lot.BuyDate,
+			Quantity:     lot.Quantity,
+			CostBasisEUR: costBasis,
+		}
+		if buyDate := utils.ParseDate(lot.BuyDate); !buyDate.IsZero() {
+			unrealizedLot.HoldingDays = int(now.Sub(buyDate).Hours() / 24)
+		}
+		pos.Lots = append(pos.Lots, unrealizedLot)
+	}
+
+	baseCurrency, err := s.uploadService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.priceService.GetCurrentPrices(isins, baseCurrency)
+	if err != nil {
+		logger.L.Warn("Could not fetch some or all current prices for unrealized gains", "userID", userID, "error", err)
+	}
+
+	report := &models.UnrealizedGainsReport{
+		AsOf:      now.Format(utils.DefaultDateFormat),
+		Positions: []models.UnrealizedPosition{},
+	}
+	priced := 0
+	for isin, pos := range positions {
+		pos.CostBasisEUR = utils.RoundMoney(pos.CostBasisEUR)
+		if p, ok := prices[isin]; ok && p.Status == "OK" {
+			priced++
+			pos.PriceStatus = "OK"
+			pos.CurrentPriceEUR = roundedPtr(p.Price, 4)
+
+			// The position and report values are summed from the rounded lot values, so they add up.
+			marketValue := 0.0
+			for i := range pos.Lots {
+				lot := &pos.Lots[i]
+				lotValue := utils.RoundMoney(p.Price * float64(lot.Quantity))
+				lotGain := utils.RoundMoney(lotValue - lot.CostBasisEUR)
+				lot.MarketValueEUR = &lotValue
+				lot.UnrealizedGainEUR = &lotGain
+				lot.UnrealizedGainPct = gainPct(lotValue, lot.CostBasisEUR)
+				if lotGain < 0 {
+					report.TotalUnrealizedLossEUR += lotGain
+				}
+				marketValue += lotValue
+			}
+			pos.MarketValueEUR = roundedPtr(marketValue, utils.MoneyDecimals)
+			pos.UnrealizedGainEUR = roundedPtr(marketValue-pos.CostBasisEUR, utils.MoneyDecimals)
+			pos.UnrealizedGainPct = gainPct(marketValue, pos.CostBasisEUR)
+			report.TotalCostBasisEUR += pos.CostBasisEUR
+			report.TotalMarketValueEUR += *pos.MarketValueEUR
+		}
+		sort.Slice(pos.Lots, func(i, j int) bool {
+			return utils.ParseDate(pos.Lots[i].BuyDate).Before(utils.ParseDate(pos.Lots[j].BuyDate))
+		})
+		report.Positions = append(report.Positions, *pos)
+	}
+	sort.Slice(report.Positions, func(i, j int) bool { return report.Positions[i].ISIN < report.Positions[j].ISIN })
+
+	report.TotalCostBasisEUR = utils.RoundMoney(report.TotalCostBasisEUR)
+	report.TotalMarketValueEUR = utils.RoundMoney(report.TotalMarketValueEUR)
+	report.TotalUnrealizedGainEUR = utils.RoundMoney(report.TotalMarketValueEUR - report.TotalCostBasisEUR)
+	report.TotalUnrealizedLossEUR = utils.RoundMoney(report.TotalUnrealizedLossEUR)
+	switch {
+	case len(positions) == 0 || priced == len(positions):
+		report.PriceStatus = "OK"
+	case priced == 0:
+		report.PriceStatus = "UNAVAILABLE"
+	default:
+		report.PriceStatus = "PARTIAL"
+	}
+	return report, nil
+}
+
+func roundedPtr(value float64, places uint) *float64 {
+	rounded := utils.RoundFloat(value, places)
+	return &rounded
+}
+
+// gainPct returns the gain as a fraction of the cost basis, or nil when there is no cost basis.
+func gainPct(marketValue, costBasis float64) *float64 {
+	if costBasis <= 0 {
+		return nil
+	}
+	return roundedPtr((marketValue-costBasis)/costBasis, 6)
+}
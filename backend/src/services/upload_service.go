@@ -2,17 +2,35 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
-	"github.com/patrickmn/go-cache"
+	"github.com/username/taxfolio/backend/src/cache"
 	"github.com/username/taxfolio/backend/src/database"
+	"github.com/username/taxfolio/backend/src/i18n"
 	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/metrics"
+	"github.com/username/taxfolio/backend/src/model"
 	"github.com/username/taxfolio/backend/src/models"
 	"github.com/username/taxfolio/backend/src/parsers"
+	"github.com/username/taxfolio/backend/src/parsers/degiro"
+	"github.com/username/taxfolio/backend/src/parsers/generic"
 	"github.com/username/taxfolio/backend/src/processors"
+	"github.com/username/taxfolio/backend/src/taxrules"
 )
 
 const (
@@ -20,102 +38,1159 @@ const (
 	ckAllStockSales       = "res_all_stock_sales_user_%d"
 	ckStockHoldingsByYear = "res_stock_holdings_by_year_user_%d"
 	ckAllFeeDetails       = "res_all_fee_details_user_%d"
+	ckCoveredCalls        = "res_covered_calls_user_%d"
 	// TODO: Add result caches for options and dividends when they are refactored
 
 	// Short-lived, aggregate cache
 	ckLatestUploadResult = "agg_latest_upload_result_user_%d"
 	ckDividendSummary    = "agg_dividend_summary_user_%d"
 
+	// Bump when the stock processor output changes so stale materialized reports are recomputed.
+	stockReportFormatVersion = "v5"
+
 	DefaultCacheExpiration = 15 * time.Minute
 	CacheCleanupInterval   = 30 * time.Minute
 )
 
 type uploadServiceImpl struct {
+	transactions          model.TransactionRepository
 	transactionProcessor  *processors.TransactionProcessor
 	dividendProcessor     processors.DividendProcessor
 	stockProcessor        processors.StockProcessor
 	optionProcessor       processors.OptionProcessor
 	cashMovementProcessor processors.CashMovementProcessor
 	feeProcessor          processors.FeeProcessor
-	reportCache           *cache.Cache
+	coveredCallProcessor  processors.CoveredCallProcessor
+	reportCache           cache.Cache
+	emailService          EmailService
+	quotaService          QuotaService
+	webhookService        WebhookService
+	largeCashMovement     float64        // Base currency amount from which a deposit or withdrawal is notified to webhooks
+	uploadLimits          parsers.Limits // Rows, parse time and memory allowed per uploaded file
+	uploadBatchSize       int            // Transactions processed and stored at a time while importing
 }
 
 func NewUploadService(
+	transactions model.TransactionRepository,
 	transactionProcessor *processors.TransactionProcessor,
 	dividendProcessor processors.DividendProcessor,
 	stockProcessor processors.StockProcessor,
 	optionProcessor processors.OptionProcessor,
 	cashMovementProcessor processors.CashMovementProcessor,
 	feeProcessor processors.FeeProcessor,
-	reportCache *cache.Cache,
+	coveredCallProcessor processors.CoveredCallProcessor,
+	reportCache cache.Cache,
+	emailService EmailService,
+	quotaService QuotaService,
+	webhookService WebhookService,
+	largeCashMovement float64,
+	uploadLimits parsers.Limits,
+	uploadBatchSize int,
 ) UploadService {
 	return &uploadServiceImpl{
+		transactions:          transactions,
 		transactionProcessor:  transactionProcessor,
 		dividendProcessor:     dividendProcessor,
 		stockProcessor:        stockProcessor,
 		optionProcessor:       optionProcessor,
 		cashMovementProcessor: cashMovementProcessor,
 		feeProcessor:          feeProcessor,
+		coveredCallProcessor:  coveredCallProcessor,
 		reportCache:           reportCache,
+		emailService:          emailService,
+		quotaService:          quotaService,
+		webhookService:        webhookService,
+		largeCashMovement:     largeCashMovement,
+		uploadLimits:          uploadLimits,
+		uploadBatchSize:       uploadBatchSize,
+	}
+}
+
+func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, source, filename, idempotencyKey string) (*UploadResult, error) {
+	return s.processUpload(userID, source, filename, idempotencyKey, []importEntry{{source: source, reader: fileReader}})
+}
+
+// ProcessArchiveUpload imports the statements of a multi-file upload, such as a ZIP archive, as a single
+// upload: each file goes through the parser of its own source, and either all of them are stored or none.
+func (s *uploadServiceImpl) ProcessArchiveUpload(files []UploadFile, userID int64, source, filename, idempotencyKey string) (*UploadResult, error) {
+	entries := make([]importEntry, len(files))
+	for i, file := range files {
+		entries[i] = importEntry{name: file.Name, source: file.Source, reader: bytes.NewReader(file.Data)}
 	}
+	return s.processUpload(userID, source, filename, idempotencyKey, entries)
 }
 
-func (s *uploadServiceImpl) ProcessUpload(fileReader io.Reader, userID int64, source string) (*UploadResult, error) {
+func (s *uploadServiceImpl) processUpload(userID int64, source, filename, idempotencyKey string, entries []importEntry) (*UploadResult, error) {
 	overallStartTime := time.Now()
-	logger.L.Info("ProcessUpload START", "userID", userID, "source", source)
+	logger.L.Info("ProcessUpload START", "userID", userID, "source", source, "idempotencyKey", idempotencyKey)
 
-	parser, err := parsers.GetParser(source)
+	batchID, replay, err := s.startUploadBatch(userID, source, filename, idempotencyKey)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrParsingFailed, err)
+		return nil, err
+	}
+	if replay {
+		logger.L.Info("Upload already processed for idempotency key, returning current result", "userID", userID, "idempotencyKey", idempotencyKey)
+		result, err := s.GetLatestUploadResult(context.Background(), userID)
+		if err != nil {
+			return nil, err
+		}
+		replayed := *result
+		replayed.Replayed = true
+		return &replayed, nil
+	}
+
+	if s.quotaService != nil {
+		if err := s.quotaService.CheckUpload(userID); err != nil {
+			s.finishUploadBatch(batchID, models.UploadSummary{Source: source, Error: err.Error()})
+			return nil, err
+		}
 	}
 
-	canonicalTxs, err := parser.Parse(fileReader)
+	// Transactions stored with a higher id are the ones this upload brings.
+	lastID, err := s.transactions.LastID(context.Background(), userID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrParsingFailed, err)
+		logger.L.Warn("Could not read the last transaction ID before the upload", "userID", userID, "error", err)
+		lastID = -1
 	}
 
-	newlyProcessedTxs := s.transactionProcessor.Process(canonicalTxs)
-	if len(newlyProcessedTxs) == 0 {
-		return s.GetLatestUploadResult(userID)
+	summary, err := s.importFiles(userID, source, entries)
+	metrics.ObserveUpload(source, time.Since(overallStartTime), err)
+	if err != nil {
+		summary.Error = err.Error()
+		s.finishUploadBatch(batchID, summary)
+		s.notifyUploadProcessed(userID, summary, lastID)
+		return nil, err
+	}
+	s.finishUploadBatch(batchID, summary)
+	s.notifyUploadProcessed(userID, summary, lastID)
+	if s.quotaService != nil {
+		if err := s.quotaService.RecordUpload(userID); err != nil {
+			logger.L.Error("Failed to record upload usage", "userID", userID, "error", err)
+		}
+	}
+
+	logger.L.Info("ProcessUpload END", "userID", userID, "duration", time.Since(overallStartTime),
+		"imported", summary.RowsImported, "duplicates", summary.Duplicates, "skipped", summary.Skipped)
+	// The upload is stored by now, so a client gone meanwhile does not stop its report being computed.
+	return s.GetLatestUploadResult(context.Background(), userID)
+}
+
+// PreviewUpload works out what importing the files would change without storing anything. The import
+// runs as an upload's would, in a database transaction that is then rolled back, so duplicates are found
+// the same way. No upload batch is recorded, no upload counts against the quota and no one is notified;
+// a preview only fails on the plan's transaction limit as the upload would.
+func (s *uploadServiceImpl) PreviewUpload(files []UploadFile, userID int64, source string) (*models.UploadPreview, error) {
+	ctx := context.Background()
+	settings, err := model.GetUserSettings(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+	lastID, err := s.transactions.LastID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the last transaction ID: %w", err)
+	}
+	storedISINs, err := s.transactions.ListISINs(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// --- Database Insertion ---
 	dbTx, err := database.DB.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("error beginning database transaction: %w", err)
 	}
+	// Nothing of the preview is kept.
 	defer dbTx.Rollback()
 
-	stmt, err := dbTx.Prepare(`INSERT INTO processed_transactions (user_id, date, source, product_name, isin, quantity, original_quantity, price, transaction_type, transaction_subtype, buy_sell, description, amount, currency, commission, order_id, exchange_rate, amount_eur, country_code, input_string, hash_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	var first, last time.Time
+	preview := &models.UploadPreview{Source: source, NewISINs: []string{}}
+	cover := func(txs []models.ProcessedTransaction) {
+		for _, tx := range txs {
+			date, err := time.Parse("02-01-2006", tx.Date)
+			if err != nil {
+				continue
+			}
+			if first.IsZero() || date.Before(first) {
+				first, preview.FirstDate = date, tx.Date
+			}
+			if last.IsZero() || date.After(last) {
+				last, preview.LastDate = date, tx.Date
+			}
+		}
+	}
+	entries := make([]importEntry, len(files))
+	for i, file := range files {
+		entries[i] = importEntry{name: file.Name, source: file.Source, reader: bytes.NewReader(file.Data), seen: cover}
+	}
+	summary := models.UploadSummary{Source: source}
+	if _, _, err := s.importEntries(dbTx, userID, settings, entries, &summary); err != nil {
+		return nil, err
+	}
+	if err := s.checkTransactionLimit(dbTx, userID); err != nil {
+		return nil, err
+	}
+
+	inserted, err := s.transactions.ListByUserAfterTx(dbTx, userID, lastID)
 	if err != nil {
-		return nil, fmt.Errorf("error preparing insert statement: %w", err)
+		return nil, err
+	}
+	known := make(map[string]bool, len(storedISINs))
+	for _, isin := range storedISINs {
+		known[isin] = true
+	}
+	for _, tx := range inserted {
+		if isTrade(tx) {
+			preview.NewTrades++
+		}
+		if tx.ISIN != "" && !known[tx.ISIN] {
+			known[tx.ISIN] = true
+			preview.NewISINs = append(preview.NewISINs, tx.ISIN)
+		}
 	}
-	defer stmt.Close()
+	sort.Strings(preview.NewISINs)
+	preview.NewTransactions = summary.RowsImported
+	preview.Duplicates = summary.Duplicates
+	preview.Skipped = summary.Skipped
+	return preview, nil
+}
 
-	for _, tx := range newlyProcessedTxs {
-		_, err := stmt.Exec(userID, tx.Date, tx.Source, tx.ProductName, tx.ISIN, tx.Quantity, tx.OriginalQuantity, tx.Price, tx.TransactionType, tx.TransactionSubType, tx.BuySell, tx.Description, tx.Amount, tx.Currency, tx.Commission, tx.OrderID, tx.ExchangeRate, tx.AmountEUR, tx.CountryCode, tx.InputString, tx.HashId)
-		if err != nil {
-			if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
-				logger.L.Debug("Skipping duplicate transaction on upload", "userID", userID, "hash_id", tx.HashId)
-				continue
+// isTrade reports whether tx buys or sells a security.
+func isTrade(tx models.ProcessedTransaction) bool {
+	switch tx.TransactionType {
+	case "STOCK", "OPTION", "BOND":
+		return tx.BuySell == "BUY" || tx.BuySell == "SELL"
+	}
+	return false
+}
+
+// startUploadBatch records a new upload batch and returns its ID. With an idempotency key already used
+// by a completed upload it returns replay=true instead, so the file is not processed twice; a key whose
+// upload failed may be retried.
+func (s *uploadServiceImpl) startUploadBatch(userID int64, source, filename, idempotencyKey string) (batchID int64, replay bool, err error) {
+	if !validIdempotencyKey(idempotencyKey) {
+		return 0, false, ErrInvalidIdempotencyKey
+	}
+	now := time.Now()
+	batchID, err = model.CreateUploadBatch(database.DB, userID, source, uploadFilename(filename), idempotencyKey, now)
+	if err == nil {
+		return batchID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) || idempotencyKey == "" {
+		return 0, false, fmt.Errorf("error recording upload batch: %w", err)
+	}
+
+	existing, err := model.GetUploadBatchByKey(database.DB, userID, idempotencyKey)
+	if err != nil {
+		return 0, false, fmt.Errorf("error loading upload batch: %w", err)
+	}
+	if existing.Source != source {
+		return 0, false, ErrIdempotencyKeyReused
+	}
+	switch existing.Status {
+	case models.UploadBatchCompleted:
+		return existing.ID, true, nil
+	case models.UploadBatchFailed:
+		if err := model.RestartUploadBatch(database.DB, existing.ID, now); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Another retry with the same key restarted it first.
+				return 0, false, ErrUploadInProgress
 			}
-			return nil, fmt.Errorf("error inserting transaction (OrderID: %s): %w", tx.OrderID, err)
+			return 0, false, fmt.Errorf("error restarting upload batch: %w", err)
+		}
+		return existing.ID, false, nil
+	default:
+		return 0, false, ErrUploadInProgress
+	}
+}
+
+// finishUploadBatch stores the outcome of an upload. Failing to do so does not fail the upload itself.
+func (s *uploadServiceImpl) finishUploadBatch(batchID int64, summary models.UploadSummary) {
+	if err := model.FinishUploadBatch(database.DB, batchID, summary, time.Now()); err != nil {
+		logger.L.Error("Failed to record upload batch outcome", "batchID", batchID, "error", err)
+	}
+}
+
+// maxUploadFilename is the number of characters of an uploaded file's name kept in the upload history.
+const maxUploadFilename = 255
+
+// uploadFilename returns the name of an uploaded file as recorded in the upload history: without the
+// directories some browsers send, control characters or more than maxUploadFilename characters.
+func uploadFilename(filename string) string {
+	filename = filename[strings.LastIndexAny(filename, `/\`)+1:]
+	filename = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename))
+	if runes := []rune(filename); len(runes) > maxUploadFilename {
+		filename = string(runes[:maxUploadFilename])
+	}
+	return filename
+}
+
+// GetUploadHistory lists the user's uploads, newest first, with the outcome recorded for each.
+func (s *uploadServiceImpl) GetUploadHistory(userID int64) ([]models.UploadHistoryEntry, error) {
+	return model.GetUploadHistory(database.DB, userID)
+}
+
+// validIdempotencyKey accepts an empty key (none sent) or up to 255 printable ASCII characters.
+func validIdempotencyKey(key string) bool {
+	if len(key) > 255 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
 		}
 	}
+	return true
+}
+
+// importEntry is one file of an upload with the source whose parser reads it.
+type importEntry struct {
+	name   string // Entry name within an archive; empty for a single file
+	source string
+	reader io.Reader
+	seen   func([]models.ProcessedTransaction) // Optional: called with each batch of transactions processed
+}
+
+// importFiles parses the files and stores any new transactions, reporting what happened to each row.
+// The files are parsed, enriched and inserted in batches within a single database transaction, so a
+// failure part way through stores nothing.
+func (s *uploadServiceImpl) importFiles(userID int64, source string, entries []importEntry) (models.UploadSummary, error) {
+	summary := models.UploadSummary{Source: source}
+
+	settings, err := model.GetUserSettings(database.DB, userID)
+	if err != nil {
+		return summary, fmt.Errorf("error loading settings: %w", err)
+	}
+
+	// --- Database Insertion ---
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return summary, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	processed, skipped, err := s.importEntries(dbTx, userID, settings, entries, &summary)
+	if err != nil {
+		return summary, err
+	}
+	if processed == 0 && skipped == 0 {
+		return summary, nil
+	}
+	if err := s.checkTransactionLimit(dbTx, userID); err != nil {
+		return summary, err
+	}
 
 	if err := dbTx.Commit(); err != nil {
-		return nil, fmt.Errorf("error committing transactions: %w", err)
+		return summary, fmt.Errorf("error committing transactions: %w", err)
 	}
 
 	// --- Invalidate Caches ---
 	// This simple strategy ensures data consistency. The next request will trigger a full, correct recalculation.
 	s.InvalidateUserCache(userID)
+	return summary, nil
+}
+
+// importEntries parses the files of an upload into dbTx, adding their outcome to summary. It returns the
+// number of transactions processed and of rows quarantined.
+func (s *uploadServiceImpl) importEntries(dbTx *sql.Tx, userID int64, settings models.UserSettings, entries []importEntry, summary *models.UploadSummary) (int, int, error) {
+	processed, skipped := 0, 0
+	for _, entry := range entries {
+		n, skippedRows, err := s.importEntry(dbTx, userID, settings, entry, summary)
+		if err != nil {
+			if entry.name != "" {
+				return 0, 0, fmt.Errorf("%s: %w", entry.name, err)
+			}
+			return 0, 0, err
+		}
+		processed += n
+		skipped += skippedRows
+	}
+	return processed, skipped, nil
+}
+
+// importEntry parses one file of an upload into dbTx, adding its outcome to summary. It returns the
+// number of transactions processed and of rows quarantined.
+func (s *uploadServiceImpl) importEntry(dbTx *sql.Tx, userID int64, settings models.UserSettings, entry importEntry, summary *models.UploadSummary) (int, int, error) {
+	parser, err := s.parserFor(userID, entry.source)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrParsingFailed, err)
+	}
+
+	processed := 0
+	var insertErr error
+	merged := make(map[int64]bool)
+	err = parsers.Stream(parser, entry.reader, s.uploadBatchSize, s.uploadLimits, func(batch []models.CanonicalTransaction) error {
+		newlyProcessedTxs := s.transactionProcessor.Process(batch, settings.BaseCurrency)
+		processed += len(newlyProcessedTxs)
+		if entry.seen != nil {
+			entry.seen(newlyProcessedTxs)
+		}
+		if entry.source == "degiro" {
+			if newlyProcessedTxs, insertErr = s.mergeDeGiroTrades(dbTx, userID, newlyProcessedTxs, merged, summary); insertErr != nil {
+				return insertErr
+			}
+		}
+		insertErr = s.insertProcessedTransactions(dbTx, userID, newlyProcessedTxs, summary)
+		return insertErr
+	})
+	if insertErr != nil {
+		return 0, 0, insertErr
+	}
+	if err != nil {
+		var validationErr *parsers.ValidationError
+		if errors.As(err, &validationErr) && entry.name != "" {
+			for i := range validationErr.Issues {
+				validationErr.Issues[i].File = entry.name
+			}
+		}
+		return 0, 0, fmt.Errorf("%w: %w", ErrParsingFailed, err)
+	}
+	var skippedRows []models.SkippedRow
+	if reporter, ok := parser.(parsers.SkipReporter); ok {
+		skippedRows = reporter.SkippedRows()
+		summary.Skipped += len(skippedRows)
+	}
+
+	// Rows the parser could not classify are quarantined so the user can review them.
+	for _, row := range skippedRows {
+		if err := model.InsertSkippedTransaction(dbTx, userID, entry.source, row); err != nil {
+			return 0, 0, fmt.Errorf("error storing skipped row: %w", err)
+		}
+		if settings.ShareUnknownDescriptions && row.Unclassified != "" {
+			hash, sample := anonymizeDescription(row.Unclassified)
+			if err := model.RecordUnknownDescription(dbTx, entry.source, hash, sample); err != nil {
+				return 0, 0, fmt.Errorf("error recording unknown description: %w", err)
+			}
+		}
+	}
+	return processed, len(skippedRows), nil
+}
+
+// maxDescriptionSample is the number of characters of an unknown description kept as its sample.
+const maxDescriptionSample = 60
+
+var numberRe = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+
+// anonymizeDescription reduces an unclassified description to a pattern that no longer identifies the
+// user: amounts, quantities, dates and identifiers are masked by replacing every number with "#".
+// The pattern is identified by its hash; only its first maxDescriptionSample characters are kept.
+func anonymizeDescription(description string) (hash, sample string) {
+	pattern := strings.Join(strings.Fields(strings.ReplaceAll(description, "\u00A0", " ")), " ")
+	pattern = numberRe.ReplaceAllString(strings.ToLower(pattern), "#")
+	sum := sha256.Sum256([]byte(pattern))
+	if runes := []rune(pattern); len(runes) > maxDescriptionSample {
+		pattern = string(runes[:maxDescriptionSample]) + "…"
+	}
+	return hex.EncodeToString(sum[:16]), pattern
+}
+
+// GetUnknownDescriptions returns the most frequent description patterns the parsers could not classify.
+func (s *uploadServiceImpl) GetUnknownDescriptions(limit int) ([]models.UnknownDescription, error) {
+	return model.GetTopUnknownDescriptions(database.DB, limit)
+}
+
+// parserFor returns the parser for an upload source. The generic CSV source is built from the user's saved mapping.
+func (s *uploadServiceImpl) parserFor(userID int64, source string) (parsers.Parser, error) {
+	if source != generic.Source {
+		return parsers.GetParser(source)
+	}
+	mapping, err := model.GetCSVMapping(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("loading CSV mapping: %w", err)
+	}
+	return generic.NewParser(*mapping)
+}
+
+// GetCSVMapping returns the user's saved generic CSV column mapping.
+func (s *uploadServiceImpl) GetCSVMapping(userID int64) (*models.CSVMapping, error) {
+	return model.GetCSVMapping(database.DB, userID)
+}
+
+// SaveCSVMapping validates and stores the column mapping used for the user's generic CSV uploads.
+func (s *uploadServiceImpl) SaveCSVMapping(userID int64, mapping models.CSVMapping) error {
+	if err := generic.ValidateMapping(mapping); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCSVMapping, err)
+	}
+	return model.UpsertCSVMapping(database.DB, userID, mapping)
+}
+
+// GetBaseCurrency returns the currency the user's reports are expressed in.
+func (s *uploadServiceImpl) GetBaseCurrency(userID int64) (string, error) {
+	return model.GetUserBaseCurrency(database.DB, userID)
+}
+
+// userLocale returns the locale of the user's generated text, or the default one when it cannot be loaded.
+func userLocale(userID int64) string {
+	stored, err := model.GetUserLocale(database.DB, userID)
+	if err != nil {
+		logger.L.Warn("Could not load user locale, using default", "userID", userID, "error", err)
+		return i18n.Default
+	}
+	if locale, ok := i18n.Normalize(stored); ok {
+		return locale
+	}
+	return i18n.Default
+}
+
+// userFiscalYear returns the boundary the user's reports are bucketed by, or the calendar year when it cannot be loaded.
+func userFiscalYear(userID int64) models.FiscalYear {
+	fiscalYear, err := model.GetUserFiscalYear(database.DB, userID)
+	if err != nil {
+		logger.L.Warn("Could not load user fiscal year, using calendar year", "userID", userID, "error", err)
+		return models.CalendarYear
+	}
+	return fiscalYear
+}
+
+// userTaxRules returns the rules of the user's tax residence, falling back to the default country's.
+func userTaxRules(userID int64) taxrules.Rules {
+	country := models.DefaultUserSettings().TaxCountry
+	if settings, err := model.GetUserSettings(database.DB, userID); err != nil {
+		logger.L.Warn("Could not load user tax country, using the default", "userID", userID, "error", err)
+	} else {
+		country = settings.TaxCountry
+	}
+	rules, err := taxrules.Get(country)
+	if err != nil {
+		logger.L.Warn("No tax rules for user tax country, using the default", "userID", userID, "country", country)
+		rules, _ = taxrules.Get(models.DefaultUserSettings().TaxCountry)
+	}
+	return rules
+}
+
+// applyTaxRules sets how the user's tax residence treats the gain of each sale. Like the asset class,
+// it is not stored with the materialized report since the user may change tax residence afterwards.
+func applyTaxRules(userID int64, sales []models.SaleDetail) {
+	taxrules.AnnotateSales(userTaxRules(userID), sales)
+}
+
+// GetFiscalYear returns the day and month the user's tax years start on, as DD-MM.
+func (s *uploadServiceImpl) GetFiscalYear(userID int64) (string, error) {
+	fiscalYear, err := model.GetUserFiscalYear(database.DB, userID)
+	if err != nil {
+		return "", err
+	}
+	return fiscalYear.String(), nil
+}
+
+// SetFiscalYear changes the start of the user's tax years. Yearly buckets are derived when reports are
+// computed, so the materialized reports and caches are dropped and rebuilt on the next request.
+func (s *uploadServiceImpl) SetFiscalYear(userID int64, start string) error {
+	fiscalYear, err := models.ParseFiscalYearStart(strings.TrimSpace(start))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFiscalYear, err)
+	}
+	current, err := model.GetUserFiscalYear(database.DB, userID)
+	if err != nil {
+		return fmt.Errorf("error loading fiscal year: %w", err)
+	}
+	if current == fiscalYear {
+		return nil
+	}
+
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err := model.SetUserFiscalYear(dbTx, userID, fiscalYear); err != nil {
+		return fmt.Errorf("error saving fiscal year: %w", err)
+	}
+	if err := model.DeleteMaterializedReports(dbTx, userID); err != nil {
+		return fmt.Errorf("error clearing materialized reports: %w", err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing fiscal year change: %w", err)
+	}
+
+	s.InvalidateUserCache(userID)
+	logger.L.Info("Changed fiscal year start", "userID", userID, "from", current.String(), "to", fiscalYear.String())
+	return nil
+}
+
+// SetBaseCurrency changes the user's reporting currency and converts every stored amount to it.
+// Rates are carried over rather than looked up again, so rates the broker actually executed at are kept.
+func (s *uploadServiceImpl) SetBaseCurrency(userID int64, currency string) error {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !processors.SupportedBaseCurrencies[currency] {
+		return fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	current, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return fmt.Errorf("error loading base currency: %w", err)
+	}
+	if current == currency {
+		return nil
+	}
+
+	txs, err := s.transactions.ListByUser(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error loading transactions: %w", err)
+	}
+
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	for i, tx := range txs {
+		date, err := time.Parse("02-01-2006", tx.Date)
+		if err != nil {
+			return fmt.Errorf("transaction %d has an invalid date %q: %w", tx.ID, tx.Date, err)
+		}
+		var rate float64
+		var rateDate time.Time
+		if tx.ExchangeRate > 0 {
+			// Units of the old base currency per unit of the new one.
+			factor, factorDate, err := processors.GetExchangeRateToWithDate(current, currency, date)
+			if err != nil {
+				return fmt.Errorf("error converting transaction %d: %w", tx.ID, err)
+			}
+			rate, rateDate = tx.ExchangeRate*factor, factorDate
+		} else {
+			if rate, rateDate, err = processors.GetExchangeRateToWithDate(tx.Currency, currency, date); err != nil {
+				return fmt.Errorf("error converting transaction %d: %w", tx.ID, err)
+			}
+		}
+		txs[i].ExchangeRate, txs[i].ExchangeRateDate, txs[i].AmountEUR = rate, rateDate.Format("02-01-2006"), tx.Amount.Convert(rate)
+	}
+	if err := s.transactions.UpdateExchangeRates(dbTx, userID, txs); err != nil {
+		return err
+	}
+
+	if err := model.SetUserBaseCurrency(dbTx, userID, currency); err != nil {
+		return fmt.Errorf("error saving base currency: %w", err)
+	}
+	if err := model.DeleteMaterializedReports(dbTx, userID); err != nil {
+		return fmt.Errorf("error clearing materialized reports: %w", err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("error committing base currency change: %w", err)
+	}
+
+	s.InvalidateUserCache(userID)
+	logger.L.Info("Changed base currency", "userID", userID, "from", current, "to", currency, "transactions", len(txs))
+	return nil
+}
+
+// maxOpeningLotsPerRequest bounds the number of opening lots accepted in one call.
+const maxOpeningLotsPerRequest = 500
+
+// AddOpeningLots stores positions transferred in from another broker as synthetic purchases, so the FIFO
+// engine can match later sales against them. Posting the same lot twice counts it as a duplicate.
+func (s *uploadServiceImpl) AddOpeningLots(userID int64, lots []models.OpeningLot) (*models.UploadSummary, error) {
+	if len(lots) == 0 || len(lots) > maxOpeningLotsPerRequest {
+		return nil, fmt.Errorf("%w: between 1 and %d lots are required", ErrInvalidOpeningLot, maxOpeningLotsPerRequest)
+	}
+
+	canonicalTxs := make([]models.CanonicalTransaction, 0, len(lots))
+	for i, lot := range lots {
+		tx, err := openingLotTransaction(lot)
+		if err != nil {
+			return nil, fmt.Errorf("%w: lot %d: %v", ErrInvalidOpeningLot, i+1, err)
+		}
+		canonicalTxs = append(canonicalTxs, tx)
+	}
+
+	baseCurrency, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading base currency: %w", err)
+	}
+
+	summary := &models.UploadSummary{Source: models.OpeningBalanceSource}
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if err := s.insertProcessedTransactions(dbTx, userID, s.transactionProcessor.Process(canonicalTxs, baseCurrency), summary); err != nil {
+		return nil, err
+	}
+	if err := s.checkTransactionLimit(dbTx, userID); err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing opening lots: %w", err)
+	}
 
-	logger.L.Info("ProcessUpload END", "userID", userID, "duration", time.Since(overallStartTime))
-	return s.GetLatestUploadResult(userID)
+	if summary.RowsImported > 0 {
+		s.InvalidateUserCache(userID)
+	}
+	logger.L.Info("Added opening lots", "userID", userID, "imported", summary.RowsImported, "duplicates", summary.Duplicates)
+	return summary, nil
 }
 
-// InvalidateUserCache clears all cached data for a user, forcing a complete rebuild on the next request.
+// openingLotTransaction validates an opening lot and converts it to the purchase it stands for.
+func openingLotTransaction(lot models.OpeningLot) (models.CanonicalTransaction, error) {
+	isin := strings.ToUpper(strings.TrimSpace(lot.ISIN))
+	if isin == "" {
+		return models.CanonicalTransaction{}, errors.New("isin is required")
+	}
+	if lot.Quantity <= 0 {
+		return models.CanonicalTransaction{}, errors.New("quantity must be positive")
+	}
+	if lot.CostBasis < 0 {
+		return models.CanonicalTransaction{}, errors.New("cost_basis cannot be negative")
+	}
+	date, err := time.Parse("02-01-2006", strings.TrimSpace(lot.BuyDate))
+	if err != nil {
+		return models.CanonicalTransaction{}, fmt.Errorf("buy_date %q is not a DD-MM-YYYY date", lot.BuyDate)
+	}
+	if date.After(time.Now()) {
+		return models.CanonicalTransaction{}, errors.New("buy_date cannot be in the future")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(lot.Currency))
+	if currency == "" {
+		currency = "EUR"
+	}
+	if len(currency) != 3 {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid currency %q", lot.Currency)
+	}
+	name := strings.TrimSpace(lot.ProductName)
+	if name == "" {
+		name = isin
+	}
+
+	return models.CanonicalTransaction{
+		Source:             models.OpeningBalanceSource,
+		TransactionDate:    date,
+		ProductName:        name,
+		ISIN:               isin,
+		Quantity:           float64(lot.Quantity),
+		Price:              lot.CostBasis / float64(lot.Quantity),
+		Currency:           currency,
+		RawText:            fmt.Sprintf("OpeningBalance|%s|%s|%d|%.4f|%s", isin, date.Format("02-01-2006"), lot.Quantity, lot.CostBasis, currency),
+		SourceAmount:       lot.CostBasis,
+		Amount:             -lot.CostBasis,
+		TransactionType:    "STOCK",
+		TransactionSubType: models.OpeningBalanceSubType,
+		BuySell:            "BUY",
+	}, nil
+}
+
+// checkTransactionLimit enforces the plan's limit on stored transactions, counting the rows dbTx inserted.
+func (s *uploadServiceImpl) checkTransactionLimit(dbTx *sql.Tx, userID int64) error {
+	if s.quotaService == nil {
+		return nil
+	}
+	return s.quotaService.CheckTransactionLimit(dbTx, userID)
+}
+
+// insertProcessedTransactions stores transactions inside dbTx, counting imported rows and duplicates in summary.
+func (s *uploadServiceImpl) insertProcessedTransactions(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction, summary *models.UploadSummary) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	start := time.Now()
+	inserted, err := s.transactions.Insert(dbTx, userID, txs)
+	if err != nil {
+		return err
+	}
+	summary.RowsImported += inserted
+	summary.Duplicates += len(txs) - inserted
+
+	elapsed := time.Since(start)
+	metrics.ObserveInsert(len(txs), elapsed)
+	logger.L.Debug("Inserted transactions", "userID", userID, "rows", len(txs), "duration", elapsed,
+		"rowsPerSecond", int(float64(len(txs))/max(elapsed.Seconds(), 1e-6)))
+	return nil
+}
+
+// mergeDeGiroTrades reconciles the trades of DeGiro's account statement and trades export, which
+// share the OrderID, so importing both files does not count a trade twice. A trade matches a stored
+// one of the same order, day, side, quantity and type; each stored trade is matched once per file, as
+// recorded in merged, so partial fills of the same size pair up one to one. The trades export is the
+// more accurate of the two: its rows replace the matching account statement rows in place, keeping
+// their ID, and account statement rows matching a stored export row are dropped. Both count as
+// duplicates in summary. Rows replaced may already have been matched by a materialized report, which
+// only picks up rows added after it, so the materialized reports are dropped when any is. It returns
+// the transactions left to insert.
+func (s *uploadServiceImpl) mergeDeGiroTrades(dbTx *sql.Tx, userID int64, txs []models.ProcessedTransaction, merged map[int64]bool, summary *models.UploadSummary) ([]models.ProcessedTransaction, error) {
+	remaining := txs[:0]
+	replaced := false
+	for _, tx := range txs {
+		if tx.OrderID == "" || (tx.TransactionType != "STOCK" && tx.TransactionType != "OPTION") {
+			remaining = append(remaining, tx)
+			continue
+		}
+		fromExport := degiro.IsTradesExportRow(tx.InputString)
+		stored, err := s.transactions.FindSameTrades(dbTx, userID, tx)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up DeGiro order %s: %w", tx.OrderID, err)
+		}
+		matchID := degiroTradeMatch(stored, tx, fromExport, merged)
+		if matchID == 0 {
+			remaining = append(remaining, tx)
+			continue
+		}
+		merged[matchID] = true
+		if fromExport {
+			if err := s.transactions.Replace(dbTx, matchID, tx); err != nil {
+				return nil, fmt.Errorf("error merging DeGiro trade (OrderID: %s): %w", tx.OrderID, err)
+			}
+			replaced = true
+		}
+		logger.L.Debug("Merged DeGiro trade with a stored one", "userID", userID, "orderID", tx.OrderID, "id", matchID, "fromExport", fromExport)
+		summary.Duplicates++
+	}
+	if replaced {
+		if err := model.DeleteMaterializedReports(dbTx, userID); err != nil {
+			return nil, fmt.Errorf("error clearing materialized reports: %w", err)
+		}
+	}
+	return remaining, nil
+}
+
+// degiroTradeMatch returns the ID of the stored trade tx merges with, or 0. A trades export row
+// replaces an account statement row; an account statement row is covered by an export row.
+func degiroTradeMatch(stored []models.ProcessedTransaction, tx models.ProcessedTransaction, fromExport bool, merged map[int64]bool) int64 {
+	for _, trade := range stored {
+		if trade.InputString == tx.InputString {
+			return 0 // Uploaded before; the insert counts it as a duplicate
+		}
+		if !merged[trade.ID] && degiro.IsTradesExportRow(trade.InputString) != fromExport {
+			return trade.ID
+		}
+	}
+	return 0
+}
+
+// GetSkippedTransactions returns the rows that were quarantined during previous uploads.
+func (s *uploadServiceImpl) GetSkippedTransactions(userID int64) ([]models.SkippedTransaction, error) {
+	return model.GetSkippedTransactionsByUserID(database.DB, userID)
+}
+
+// ReprocessSkippedTransactions runs the quarantined rows through the current parsers again.
+// Rows that now parse are imported and removed from quarantine; the others keep their latest failure reason.
+// The transactions the user entered by hand, the opening lots, are derived again from what was entered.
+func (s *uploadServiceImpl) ReprocessSkippedTransactions(userID int64) (*models.UploadSummary, error) {
+	skipped, err := model.GetSkippedTransactionsByUserID(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading skipped transactions: %w", err)
+	}
+	summary := &models.UploadSummary{}
+	baseCurrency, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading base currency: %w", err)
+	}
+
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	for _, row := range skipped {
+		parser, err := s.parserFor(userID, row.Source)
+		if err != nil {
+			summary.Skipped++
+			continue
+		}
+		canonicalTxs, err := parser.Parse(strings.NewReader(row.Payload))
+		stillSkipped, skippedReason := rowStillSkipped(parser, row.RawText)
+		if err != nil || len(canonicalTxs) == 0 || stillSkipped {
+			reason := row.Reason
+			if err != nil {
+				reason = err.Error()
+			} else if skippedReason != "" {
+				reason = skippedReason
+			}
+			if err := model.UpdateSkippedTransactionReason(dbTx, userID, row.ID, reason); err != nil {
+				return nil, fmt.Errorf("error updating skipped transaction %d: %w", row.ID, err)
+			}
+			summary.Skipped++
+			continue
+		}
+
+		if err := s.insertProcessedTransactions(dbTx, userID, s.transactionProcessor.Process(canonicalTxs, baseCurrency), summary); err != nil {
+			return nil, err
+		}
+		if err := model.DeleteSkippedTransaction(dbTx, userID, row.ID); err != nil {
+			return nil, fmt.Errorf("error removing skipped transaction %d: %w", row.ID, err)
+		}
+	}
+
+	rederived, err := s.rederiveOpeningLots(dbTx, userID, baseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if rederived > 0 {
+		if err := model.DeleteMaterializedReports(dbTx, userID); err != nil {
+			return nil, fmt.Errorf("error clearing materialized reports: %w", err)
+		}
+	}
+
+	if err := s.checkTransactionLimit(dbTx, userID); err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing reprocessed transactions: %w", err)
+	}
+	if summary.RowsImported > 0 || rederived > 0 {
+		s.InvalidateUserCache(userID)
+	}
+	logger.L.Info("Reprocessed skipped transactions", "userID", userID, "imported", summary.RowsImported, "duplicates", summary.Duplicates,
+		"stillSkipped", summary.Skipped, "openingLotsRederived", rederived)
+	return summary, nil
+}
+
+// ImportSkippedTransactionManually imports the user's quarantined row id as the transaction entered
+// for it, and removes the row from quarantine.
+func (s *uploadServiceImpl) ImportSkippedTransactionManually(userID, id int64, entry models.ManualTransaction) (*models.UploadSummary, error) {
+	baseCurrency, err := model.GetUserBaseCurrency(database.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading base currency: %w", err)
+	}
+
+	dbTx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning database transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	row, err := model.GetSkippedTransaction(dbTx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := manualTransaction(row, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManualEntry, err)
+	}
+
+	summary := &models.UploadSummary{Source: row.Source}
+	if err := s.insertProcessedTransactions(dbTx, userID, s.transactionProcessor.Process([]models.CanonicalTransaction{canonical}, baseCurrency), summary); err != nil {
+		return nil, err
+	}
+	if err := model.DeleteSkippedTransaction(dbTx, userID, id); err != nil {
+		return nil, fmt.Errorf("error removing skipped transaction %d: %w", id, err)
+	}
+	if err := s.checkTransactionLimit(dbTx, userID); err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing manual transaction: %w", err)
+	}
+
+	if summary.RowsImported > 0 {
+		s.InvalidateUserCache(userID)
+	}
+	logger.L.Info("Imported skipped transaction manually", "userID", userID, "skippedID", id, "imported", summary.RowsImported)
+	return summary, nil
+}
+
+// manualTypes are the transaction types a quarantined row can be entered as by hand.
+var manualTypes = []string{"STOCK", "DIVIDEND", "TAX", "FEE", "INTEREST", "CASH"}
+
+// manualTransaction validates the fields entered for a quarantined row and converts them to the
+// transaction the row stands for. It keeps the row's source and text, so uploading the file again
+// finds the transaction already stored.
+func manualTransaction(row models.SkippedTransaction, entry models.ManualTransaction) (models.CanonicalTransaction, error) {
+	date, err := time.Parse("02-01-2006", strings.TrimSpace(entry.Date))
+	if err != nil {
+		return models.CanonicalTransaction{}, fmt.Errorf("date %q is not a DD-MM-YYYY date", entry.Date)
+	}
+	if date.After(time.Now()) {
+		return models.CanonicalTransaction{}, errors.New("date cannot be in the future")
+	}
+	txType := strings.ToUpper(strings.TrimSpace(entry.TransactionType))
+	if !slices.Contains(manualTypes, txType) {
+		return models.CanonicalTransaction{}, fmt.Errorf("transaction_type must be one of %s", strings.Join(manualTypes, ", "))
+	}
+	isin := strings.ToUpper(strings.TrimSpace(entry.ISIN))
+	if isin == "" && (txType == "STOCK" || txType == "DIVIDEND") {
+		return models.CanonicalTransaction{}, fmt.Errorf("isin is required for %s", txType)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(entry.Currency))
+	if currency == "" {
+		currency = "EUR"
+	}
+	if len(currency) != 3 {
+		return models.CanonicalTransaction{}, fmt.Errorf("invalid currency %q", entry.Currency)
+	}
+	name := strings.TrimSpace(entry.ProductName)
+	if name == "" {
+		name = isin
+	}
+
+	tx := models.CanonicalTransaction{
+		Source:             row.Source,
+		TransactionDate:    date,
+		ProductName:        name,
+		ISIN:               isin,
+		Currency:           currency,
+		RawText:            row.RawText,
+		TransactionType:    txType,
+		TransactionSubType: strings.ToUpper(strings.TrimSpace(entry.TransactionSubType)),
+	}
+	if txType != "STOCK" {
+		if entry.Amount == 0 {
+			return models.CanonicalTransaction{}, errors.New("amount is required")
+		}
+		tx.Amount, tx.SourceAmount = entry.Amount, math.Abs(entry.Amount)
+		return tx, nil
+	}
+
+	tx.BuySell = strings.ToUpper(strings.TrimSpace(entry.BuySell))
+	if tx.BuySell != "BUY" && tx.BuySell != "SELL" {
+		return models.CanonicalTransaction{}, errors.New("buy_sell must be BUY or SELL")
+	}
+	if entry.Quantity <= 0 {
+		return models.CanonicalTransaction{}, errors.New("quantity must be positive")
+	}
+	if entry.Price < 0 || entry.Commission < 0 {
+		return models.CanonicalTransaction{}, errors.New("price and commission cannot be negative")
+	}
+	tx.Quantity, tx.Price, tx.Commission = entry.Quantity, entry.Price, entry.Commission
+	tx.SourceAmount = models.NewMoney(entry.Quantity * entry.Price).Float64()
+	tx.Amount = tx.SourceAmount
+	if tx.BuySell == "BUY" {
+		tx.Amount = -tx.SourceAmount
+	}
+	return tx, nil
+}
+
+// rowStillSkipped reports whether the parser skipped the row rawText again in its last parse, and why.
+// A payload may hold other rows besides the skipped one, such as the commission of its order, so the
+// parse can return transactions while the row itself still fails.
+func rowStillSkipped(parser parsers.Parser, rawText string) (bool, string) {
+	reporter, ok := parser.(parsers.SkipReporter)
+	if !ok {
+		return false, ""
+	}
+	for _, skipped := range reporter.SkippedRows() {
+		if skipped.RawText == rawText {
+			return true, skipped.Reason
+		}
+	}
+	if rows := reporter.SkippedRows(); len(rows) > 0 {
+		return false, rows[0].Reason
+	}
+	return false, ""
+}
+
+// rederiveOpeningLots derives the user's opening lots again from the details entered for them, kept in
+// their input string, so they get the exchange rate and country the current rules give them. It
+// overwrites the stored lots that come out different and returns how many did.
+func (s *uploadServiceImpl) rederiveOpeningLots(dbTx *sql.Tx, userID int64, baseCurrency string) (int, error) {
+	stored, err := s.transactions.ListByUserAfterTx(dbTx, userID, 0)
+	if err != nil {
+		return 0, fmt.Errorf("error loading transactions: %w", err)
+	}
+	changed := 0
+	for _, old := range stored {
+		if old.Source != models.OpeningBalanceSource {
+			continue
+		}
+		lot, err := openingLotFromInput(old.InputString)
+		if err != nil {
+			logger.L.Warn("Cannot derive opening lot again", "userID", userID, "transactionID", old.ID, "error", err)
+			continue
+		}
+		lot.ProductName = old.ProductName
+		canonical, err := openingLotTransaction(lot)
+		if err != nil {
+			logger.L.Warn("Cannot derive opening lot again", "userID", userID, "transactionID", old.ID, "error", err)
+			continue
+		}
+		processed := s.transactionProcessor.Process([]models.CanonicalTransaction{canonical}, baseCurrency)
+		if len(processed) != 1 {
+			continue
+		}
+		tx := processed[0]
+		if tx.HashId != old.HashId || (tx.ExchangeRate == old.ExchangeRate && tx.ExchangeRateDate == old.ExchangeRateDate &&
+			tx.AmountEUR == old.AmountEUR && tx.CountryCode == old.CountryCode) {
+			continue
+		}
+		tx.Quantity, tx.OriginalQuantity = old.Quantity, old.OriginalQuantity
+		if err := s.transactions.Replace(dbTx, old.ID, tx); err != nil {
+			return changed, fmt.Errorf("error updating opening lot %d: %w", old.ID, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// openingLotFromInput reads back the opening lot an input string was written from by openingLotTransaction.
+func openingLotFromInput(input string) (models.OpeningLot, error) {
+	fields := strings.Split(input, "|")
+	if len(fields) != 6 || fields[0] != "OpeningBalance" {
+		return models.OpeningLot{}, fmt.Errorf("unexpected input string %q", input)
+	}
+	quantity, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return models.OpeningLot{}, fmt.Errorf("invalid quantity %q", fields[3])
+	}
+	costBasis, err := strconv.ParseFloat(fields[4], 64)
+	if err != nil {
+		return models.OpeningLot{}, fmt.Errorf("invalid cost basis %q", fields[4])
+	}
+	return models.OpeningLot{ISIN: fields[1], BuyDate: fields[2], Quantity: quantity, CostBasis: costBasis, Currency: fields[5]}, nil
+}
+
+// notifyUploadProcessed emails the user a summary of the upload in the background, and tells the
+// user's webhooks about it and about the large deposits and withdrawals among the transactions stored
+// with an id above lastID, unless it is negative. Failures are logged and never affect the upload itself.
+func (s *uploadServiceImpl) notifyUploadProcessed(userID int64, summary models.UploadSummary, lastID int64) {
+	if s.webhookService != nil {
+		go s.notifyWebhooks(userID, summary, lastID)
+	}
+	if s.emailService == nil {
+		return
+	}
+	go func() {
+		user, err := model.GetUserByID(database.DB, userID)
+		if err != nil || user.Email == "" {
+			logger.L.Warn("Could not load user for upload notification", "userID", userID, "error", err)
+			return
+		}
+		if err := s.emailService.SendUploadSummaryEmail(user.Email, user.Username, summary, userLocale(userID)); err != nil {
+			logger.L.Error("Failed to send upload summary email", "userID", userID, "error", err)
+		}
+	}()
+}
+
+func (s *uploadServiceImpl) notifyWebhooks(userID int64, summary models.UploadSummary, lastID int64) {
+	s.webhookService.Dispatch(userID, models.WebhookUploadProcessed, summary)
+	if summary.Error != "" || summary.RowsImported == 0 || lastID < 0 || s.largeCashMovement <= 0 {
+		return
+	}
+
+	transactions, err := s.transactions.ListByUserAfter(context.Background(), userID, lastID)
+	if err != nil {
+		logger.L.Error("Failed to load the uploaded transactions for webhooks", "userID", userID, "error", err)
+		return
+	}
+	for _, movement := range s.cashMovementProcessor.Process(transactions) {
+		if movement.Type == "fx_conversion" || math.Abs(movement.AmountEUR) < s.largeCashMovement {
+			continue
+		}
+		s.webhookService.Dispatch(userID, models.WebhookLargeCashMovement, models.LargeCashMovementEvent{
+			Date:      movement.Date,
+			Type:      movement.Type,
+			Amount:    movement.Amount,
+			Currency:  movement.Currency,
+			AmountEUR: movement.AmountEUR,
+			Source:    movement.Source,
+			Threshold: s.largeCashMovement,
+		})
+	}
+}
+
+// getCached looks up a report cache entry and records the hit or miss.
+func (s *uploadServiceImpl) getCached(key string, dest any) bool {
+	found := s.reportCache.Get(key, dest)
+	metrics.CacheLookup("report", found)
+	return found
+}
+
+// InvalidateUserCache clears all cached data for a user, forcing a complete rebuild on the next request,
+// and bumps their data version.
 func (s *uploadServiceImpl) InvalidateUserCache(userID int64) {
 	keysToDelete := []string{
 		fmt.Sprintf(ckAllStockSales, userID),
@@ -123,33 +1198,75 @@ func (s *uploadServiceImpl) InvalidateUserCache(userID int64) {
 		fmt.Sprintf(ckLatestUploadResult, userID),
 		fmt.Sprintf(ckDividendSummary, userID),
 		fmt.Sprintf(ckAllFeeDetails, userID),
+		fmt.Sprintf(ckCoveredCalls, userID),
 	}
-	for _, key := range keysToDelete {
-		s.reportCache.Delete(key)
+	s.reportCache.Delete(keysToDelete...)
+	if err := model.BumpDataVersion(database.DB, userID); err != nil {
+		logger.L.Error("Failed to bump data version; report ETags may be stale", "userID", userID, "error", err)
 	}
 	logger.L.Info("Invalidated all caches for user", "userID", userID)
 }
 
+// GetDataVersion returns the version of the user's data that report ETags are derived from. It changes
+// whenever InvalidateUserCache runs.
+func (s *uploadServiceImpl) GetDataVersion(userID int64) (int64, error) {
+	return model.GetDataVersion(database.DB, userID)
+}
+
 // getStockData is the central function to populate stock-related caches on a cache miss.
-func (s *uploadServiceImpl) getStockData(userID int64) ([]models.SaleDetail, map[string][]models.PurchaseLot, error) {
+func (s *uploadServiceImpl) getStockData(ctx context.Context, userID int64) ([]models.SaleDetail, map[string][]models.PurchaseLot, error) {
 	salesCacheKey := fmt.Sprintf(ckAllStockSales, userID)
 	holdingsByYearCacheKey := fmt.Sprintf(ckStockHoldingsByYear, userID)
 
-	if cachedSales, salesFound := s.reportCache.Get(salesCacheKey); salesFound {
-		if cachedHoldings, holdingsFound := s.reportCache.Get(holdingsByYearCacheKey); holdingsFound {
-			logger.L.Debug("Cache hit for all stock data", "userID", userID)
-			return cachedSales.([]models.SaleDetail), cachedHoldings.(map[string][]models.PurchaseLot), nil
-		}
+	var cachedSales []models.SaleDetail
+	var cachedHoldings map[string][]models.PurchaseLot
+	if s.getCached(salesCacheKey, &cachedSales) && s.getCached(holdingsByYearCacheKey, &cachedHoldings) {
+		logger.L.Debug("Cache hit for all stock data", "userID", userID)
+		return cachedSales, cachedHoldings, nil
+	}
+
+	// The materialized tables survive restarts; they are only reused while the transactions they were
+	// computed from are unchanged.
+	version, err := model.GetTransactionDataVersion(ctx, database.DB, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute data version: %w", err)
+	}
+	version.DataVersion = stockReportFormatVersion + ":" + version.DataVersion
+
+	allSales, holdingsByYear, err := model.GetMaterializedStockReport(ctx, database.DB, userID, version.DataVersion)
+	if err == nil {
+		applyAssetClasses(allSales, holdingsByYear)
+		applyTaxRules(userID, allSales)
+		s.reportCache.Set(salesCacheKey, allSales, cache.NoExpiration)
+		s.reportCache.Set(holdingsByYearCacheKey, holdingsByYear, cache.NoExpiration)
+		logger.L.Info("Loaded stock data from materialized report tables", "userID", userID)
+		return allSales, holdingsByYear, nil
+	}
+	if !errors.Is(err, model.ErrReportNotMaterialized) {
+		logger.L.Warn("Failed to load materialized stock report, recalculating", "userID", userID, "error", err)
+	}
+
+	if allSales, holdingsByYear, ok := s.resumeStockData(ctx, userID, version); ok {
+		applyAssetClasses(allSales, holdingsByYear)
+		applyTaxRules(userID, allSales)
+		s.reportCache.Set(salesCacheKey, allSales, cache.NoExpiration)
+		s.reportCache.Set(holdingsByYearCacheKey, holdingsByYear, cache.NoExpiration)
+		return allSales, holdingsByYear, nil
 	}
 
 	logger.L.Info("Cache miss for stock data, recalculating from DB", "userID", userID)
-	allUserTransactions, err := fetchUserProcessedTransactions(userID)
+	allUserTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// The processor does the heavy lifting of calculating everything in one pass.
-	allSales, holdingsByYear := s.stockProcessor.Process(allUserTransactions)
+	allSales, holdingsByYear, state := s.stockProcessor.ProcessWithState(allUserTransactions, userFiscalYear(userID))
+
+	if err := model.SaveMaterializedStockReport(database.DB, userID, version, allSales, holdingsByYear, state); err != nil {
+		logger.L.Error("Failed to persist materialized stock report", "userID", userID, "error", err)
+	}
+	applyTaxRules(userID, allSales)
 
 	s.reportCache.Set(salesCacheKey, allSales, cache.NoExpiration)
 	s.reportCache.Set(holdingsByYearCacheKey, holdingsByYear, cache.NoExpiration)
@@ -158,38 +1275,123 @@ func (s *uploadServiceImpl) getStockData(userID int64) ([]models.SaleDetail, map
 	return allSales, holdingsByYear, nil
 }
 
-func (s *uploadServiceImpl) GetLatestUploadResult(userID int64) (*UploadResult, error) {
+// applyAssetClasses sets the asset class of sales and holdings loaded from the materialized report
+// tables, which do not store it since an ISIN may only be classified after the report was computed.
+func applyAssetClasses(sales []models.SaleDetail, holdingsByYear map[string][]models.PurchaseLot) {
+	isinSet := make(map[string]bool)
+	for _, sale := range sales {
+		isinSet[sale.ISIN] = true
+	}
+	for _, lots := range holdingsByYear {
+		for _, lot := range lots {
+			isinSet[lot.ISIN] = true
+		}
+	}
+	isins := make([]string, 0, len(isinSet))
+	for isin := range isinSet {
+		isins = append(isins, isin)
+	}
+	classes, err := model.GetAssetClassesByISINs(database.DB, isins)
+	if err != nil {
+		logger.L.Warn("Failed to load asset classes", "error", err)
+		return
+	}
+	for i := range sales {
+		sales[i].AssetClass = classes[sales[i].ISIN]
+	}
+	for _, lots := range holdingsByYear {
+		for i := range lots {
+			lots[i].AssetClass = classes[lots[i].ISIN]
+		}
+	}
+}
+
+// resumeStockData extends the stored report with transactions added since it was computed, resuming the
+// FIFO matching from the saved open lots. It reports false when a full recomputation is needed instead:
+// no usable stored report, transactions deleted or replaced since, or new transactions dated before the
+// saved state.
+func (s *uploadServiceImpl) resumeStockData(ctx context.Context, userID int64, version *model.ReportVersion) ([]models.SaleDetail, map[string][]models.PurchaseLot, bool) {
+	previous, err := model.GetReportVersion(ctx, database.DB, userID)
+	if err != nil {
+		if !errors.Is(err, model.ErrReportNotMaterialized) {
+			logger.L.Warn("Failed to read materialized report version", "userID", userID, "error", err)
+		}
+		return nil, nil, false
+	}
+	if !strings.HasPrefix(previous.DataVersion, stockReportFormatVersion+":") || previous.LastTransactionID >= version.LastTransactionID {
+		return nil, nil, false
+	}
+	prefixHash, err := model.HashTransactionsUpTo(ctx, database.DB, userID, previous.LastTransactionID)
+	if err != nil || previous.PrefixHash == "" || prefixHash != previous.PrefixHash {
+		return nil, nil, false
+	}
+
+	state, err := model.GetStockFIFOState(ctx, database.DB, userID, previous.DataVersion)
+	if err != nil {
+		logger.L.Warn("Failed to load saved FIFO state", "userID", userID, "error", err)
+		return nil, nil, false
+	}
+	previousSales, holdingsByYear, err := model.GetMaterializedStockReport(ctx, database.DB, userID, previous.DataVersion)
+	if err != nil {
+		logger.L.Warn("Failed to load materialized stock report for resume", "userID", userID, "error", err)
+		return nil, nil, false
+	}
+	newTransactions, err := s.transactions.ListByUserAfter(ctx, userID, previous.LastTransactionID)
+	if err != nil {
+		logger.L.Warn("Failed to fetch appended transactions", "userID", userID, "error", err)
+		return nil, nil, false
+	}
+
+	newSales, updatedHoldings, newState, err := s.stockProcessor.Resume(state, newTransactions, userFiscalYear(userID))
+	if err != nil {
+		logger.L.Info("Appended transactions cannot resume FIFO state, recalculating", "userID", userID, "reason", err)
+		return nil, nil, false
+	}
+
+	if err := model.AppendMaterializedStockReport(database.DB, userID, previous.DataVersion, version, newSales, updatedHoldings, newState); err != nil {
+		logger.L.Error("Failed to persist incremental stock report", "userID", userID, "error", err)
+	}
+
+	for year, lots := range updatedHoldings {
+		holdingsByYear[year] = lots
+	}
+	logger.L.Info("Resumed stock data from saved FIFO state", "userID", userID, "newTransactions", len(newTransactions), "newSales", len(newSales))
+	return append(previousSales, newSales...), holdingsByYear, true
+}
+
+func (s *uploadServiceImpl) GetLatestUploadResult(ctx context.Context, userID int64) (*UploadResult, error) {
 	cacheKey := fmt.Sprintf(ckLatestUploadResult, userID)
-	if cached, found := s.reportCache.Get(cacheKey); found {
+	var cached *UploadResult
+	if s.getCached(cacheKey, &cached) {
 		logger.L.Info("Cache hit for GetLatestUploadResult", "userID", userID)
-		return cached.(*UploadResult), nil
+		return cached, nil
 	}
 	logger.L.Info("Cache miss for GetLatestUploadResult, computing...", "userID", userID)
 
-	stockSaleDetails, stockHoldingsByYear, err := s.getStockData(userID)
+	stockSaleDetails, stockHoldingsByYear, err := s.getStockData(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	allTxns, err := fetchUserProcessedTransactions(userID)
+	allTxns, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	optionSaleDetails, optionHoldings := s.optionProcessor.Process(allTxns)
+	optionSaleDetails, optionHoldings, coveredCalls := s.optionData(userID, allTxns)
 	cashMovements := s.cashMovementProcessor.Process(allTxns)
 	feeDetails := s.feeProcessor.Process(allTxns)
 
 	var dividendTransactionsList []models.ProcessedTransaction
 	for _, tx := range allTxns {
-		if tx.TransactionType == "DIVIDEND" {
+		if tx.TransactionType == "DIVIDEND" || tx.TransactionType == "SCRIP_DIVIDEND" {
 			dividendTransactionsList = append(dividendTransactionsList, tx)
 		}
 	}
 
 	result := &UploadResult{
 		StockSaleDetails:         stockSaleDetails,
-		StockHoldings:            stockHoldingsByYear,
+		StockHoldings:            annotateHoldingsByYear(stockHoldingsByYear, coveredCalls),
 		OptionSaleDetails:        optionSaleDetails,
 		OptionHoldings:           optionHoldings,
 		CashMovements:            cashMovements,
@@ -200,15 +1402,16 @@ func (s *uploadServiceImpl) GetLatestUploadResult(userID int64) (*UploadResult,
 	return result, nil
 }
 
-func (s *uploadServiceImpl) GetFeeDetails(userID int64) ([]models.FeeDetail, error) {
+func (s *uploadServiceImpl) GetFeeDetails(ctx context.Context, userID int64) ([]models.FeeDetail, error) {
 	cacheKey := fmt.Sprintf(ckAllFeeDetails, userID)
-	if cached, found := s.reportCache.Get(cacheKey); found {
+	var cached []models.FeeDetail
+	if s.getCached(cacheKey, &cached) {
 		logger.L.Debug("Cache hit for fee details", "userID", userID)
-		return cached.([]models.FeeDetail), nil
+		return cached, nil
 	}
 
 	logger.L.Info("Cache miss for fee details, recalculating from DB", "userID", userID)
-	allUserTransactions, err := fetchUserProcessedTransactions(userID)
+	allUserTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -223,87 +1426,189 @@ func (s *uploadServiceImpl) GetFeeDetails(userID int64) ([]models.FeeDetail, err
 	return feeDetails, nil
 }
 
-func (s *uploadServiceImpl) GetStockSaleDetails(userID int64) ([]models.SaleDetail, error) {
-	sales, _, err := s.getStockData(userID)
+func (s *uploadServiceImpl) GetStockSaleDetails(ctx context.Context, userID int64) ([]models.SaleDetail, error) {
+	sales, _, err := s.getStockData(ctx, userID)
 	return sales, err
 }
 
-func (s *uploadServiceImpl) GetStockHoldings(userID int64) (map[string][]models.PurchaseLot, error) {
-	_, holdingsByYear, err := s.getStockData(userID)
+func (s *uploadServiceImpl) GetStockHoldings(ctx context.Context, userID int64) (map[string][]models.PurchaseLot, error) {
+	_, holdingsByYear, err := s.getStockData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	coveredCalls, err := s.getCoveredCalls(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return annotateHoldingsByYear(holdingsByYear, coveredCalls), nil
+}
+
+// GetCostBasisAdjustments returns the audit trail of the cost basis reductions made by return of capital distributions.
+func (s *uploadServiceImpl) GetCostBasisAdjustments(ctx context.Context, userID int64) ([]models.CostBasisAdjustment, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	return holdingsByYear, nil
+	return s.stockProcessor.CostBasisAdjustments(userTransactions), nil
+}
+
+// GetPositionTimeline returns the history of one instrument, or ErrPositionNotFound when the user has
+// no trades or dividends of it.
+func (s *uploadServiceImpl) GetPositionTimeline(ctx context.Context, userID int64, isin string) (*models.PositionTimeline, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	timeline := s.stockProcessor.Timeline(userTransactions, isin)
+	if len(timeline.Events) == 0 {
+		return nil, ErrPositionNotFound
+	}
+	return &timeline, nil
+}
+
+// GetStockHoldingsForYear returns the open purchase lots at the end of the given tax year
+// (or today, for the current year). Years after the last transaction carry the latest snapshot forward.
+// The cap is the calendar year so that callers asking for today's holdings by calendar year still get them.
+func (s *uploadServiceImpl) GetStockHoldingsForYear(ctx context.Context, userID int64, year string) ([]models.PurchaseLot, error) {
+	_, holdingsByYear, err := s.getStockData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	lots, ok := holdingsByYear[year]
+	if !ok {
+		latestYear := latestHoldingYear(holdingsByYear)
+		if latestYear == "" || year <= latestYear || year > strconv.Itoa(time.Now().Year()) {
+			return []models.PurchaseLot{}, nil
+		}
+		lots = holdingsByYear[latestYear]
+	}
+	coveredCalls, err := s.getCoveredCalls(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return processors.AnnotateCoveredLots(lots, coveredCalls), nil
+}
+
+// GetHoldingYears lists the tax years with a holdings snapshot, newest first, up to the current tax year.
+func (s *uploadServiceImpl) GetHoldingYears(ctx context.Context, userID int64) ([]string, error) {
+	_, holdingsByYear, err := s.getStockData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	currentYear := userFiscalYear(userID).YearOf(time.Now())
+	years := []string{}
+	for year := range holdingsByYear {
+		years = append(years, year)
+	}
+	if latestYear, err := strconv.Atoi(latestHoldingYear(holdingsByYear)); err == nil {
+		for year := latestYear + 1; year <= currentYear; year++ {
+			years = append(years, strconv.Itoa(year))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(years)))
+	return years, nil
+}
+
+func latestHoldingYear(holdingsByYear map[string][]models.PurchaseLot) string {
+	latest := ""
+	for year := range holdingsByYear {
+		if year > latest {
+			latest = year
+		}
+	}
+	return latest
 }
 
 // --- Other methods remain largely unchanged, but will benefit from future refactoring ---
 
-func (s *uploadServiceImpl) GetDividendTaxSummary(userID int64) (models.DividendTaxResult, error) {
+func (s *uploadServiceImpl) GetDividendTaxSummary(ctx context.Context, userID int64) (models.DividendTaxResult, error) {
 	cacheKey := fmt.Sprintf(ckDividendSummary, userID)
-	if data, found := s.reportCache.Get(cacheKey); found {
-		return data.(models.DividendTaxResult), nil
+	var cached models.DividendTaxResult
+	if s.getCached(cacheKey, &cached) {
+		return cached, nil
 	}
-	userTransactions, err := fetchUserProcessedTransactions(userID)
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	summary := s.dividendProcessor.CalculateTaxSummary(userTransactions)
+	summary := s.dividendProcessor.CalculateTaxSummary(userTransactions, userFiscalYear(userID))
 	s.reportCache.Set(cacheKey, summary, DefaultCacheExpiration)
 	return summary, nil
 }
 
-func (s *uploadServiceImpl) GetOptionSaleDetails(userID int64) ([]models.OptionSaleDetail, error) {
-	userTransactions, err := fetchUserProcessedTransactions(userID)
+// GetDividendDetail returns the dividend and withholding tax transactions behind one tax year and
+// country of the dividend tax summary.
+func (s *uploadServiceImpl) GetDividendDetail(ctx context.Context, userID int64, year, country string) (models.DividendDetail, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
+	if err != nil {
+		return models.DividendDetail{}, err
+	}
+	return s.dividendProcessor.CalculateDetail(userTransactions, userFiscalYear(userID), year, country), nil
+}
+
+func (s *uploadServiceImpl) GetOptionSaleDetails(ctx context.Context, userID int64) ([]models.OptionSaleDetail, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	optionSaleDetails, _ := s.optionProcessor.Process(userTransactions)
+	optionSaleDetails, _, _ := s.optionData(userID, userTransactions)
 	return optionSaleDetails, nil
 }
 
-func (s *uploadServiceImpl) GetOptionHoldings(userID int64) ([]models.OptionHolding, error) {
-	userTransactions, err := fetchUserProcessedTransactions(userID)
+func (s *uploadServiceImpl) GetOptionHoldings(ctx context.Context, userID int64) ([]models.OptionHolding, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	_, optionHoldings := s.optionProcessor.Process(userTransactions)
+	_, optionHoldings, _ := s.optionData(userID, userTransactions)
 	return optionHoldings, nil
 }
 
-func (s *uploadServiceImpl) GetDividendTransactions(userID int64) ([]models.ProcessedTransaction, error) {
-	userTransactions, err := fetchUserProcessedTransactions(userID)
+// optionData matches the option trades and links the short calls among them to the stock lots covering them.
+func (s *uploadServiceImpl) optionData(userID int64, transactions []models.ProcessedTransaction) ([]models.OptionSaleDetail, []models.OptionHolding, []models.CoveredCall) {
+	optionSaleDetails, optionHoldings := s.optionProcessor.Process(transactions, userFiscalYear(userID))
+	coveredCalls := s.coveredCallProcessor.Process(transactions, optionSaleDetails, optionHoldings)
+	s.reportCache.Set(fmt.Sprintf(ckCoveredCalls, userID), coveredCalls, DefaultCacheExpiration)
+	return optionSaleDetails, optionHoldings, coveredCalls
+}
+
+// getCoveredCalls returns the links between short calls and the stock lots covering them.
+func (s *uploadServiceImpl) getCoveredCalls(ctx context.Context, userID int64) ([]models.CoveredCall, error) {
+	var cached []models.CoveredCall
+	if s.getCached(fmt.Sprintf(ckCoveredCalls, userID), &cached) {
+		return cached, nil
+	}
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	var dividends []models.ProcessedTransaction
-	for _, tx := range userTransactions {
-		if tx.TransactionType == "DIVIDEND" {
-			dividends = append(dividends, tx)
-		}
+	_, _, coveredCalls := s.optionData(userID, userTransactions)
+	return coveredCalls, nil
+}
+
+// annotateHoldingsByYear returns a copy of the holdings snapshots with the covered calls attached to the lots.
+// The snapshots themselves are shared with the cache and left untouched.
+func annotateHoldingsByYear(holdingsByYear map[string][]models.PurchaseLot, coveredCalls []models.CoveredCall) map[string][]models.PurchaseLot {
+	if len(coveredCalls) == 0 {
+		return holdingsByYear
 	}
-	return dividends, nil
+	annotated := make(map[string][]models.PurchaseLot, len(holdingsByYear))
+	for year, lots := range holdingsByYear {
+		annotated[year] = processors.AnnotateCoveredLots(lots, coveredCalls)
+	}
+	return annotated
 }
 
-// fetchUserProcessedTransactions remains the same
-func fetchUserProcessedTransactions(userID int64) ([]models.ProcessedTransaction, error) {
-	logger.L.Debug("Fetching processed transactions from DB", "userID", userID)
-	rows, err := database.DB.Query(`SELECT id, date, source, product_name, isin, quantity, original_quantity, price, transaction_type, transaction_subtype, buy_sell, description, amount, currency, commission, order_id, exchange_rate, amount_eur, country_code, input_string, hash_id FROM processed_transactions WHERE user_id = ? ORDER BY date ASC, id ASC`, userID)
+func (s *uploadServiceImpl) GetDividendTransactions(ctx context.Context, userID int64) ([]models.ProcessedTransaction, error) {
+	userTransactions, err := s.transactions.ListByUser(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("error querying transactions for userID %d: %w", userID, err)
+		return nil, err
 	}
-	defer rows.Close()
-	var transactions []models.ProcessedTransaction
-	for rows.Next() {
-		var tx models.ProcessedTransaction
-		scanErr := rows.Scan(&tx.ID, &tx.Date, &tx.Source, &tx.ProductName, &tx.ISIN, &tx.Quantity, &tx.OriginalQuantity, &tx.Price, &tx.TransactionType, &tx.TransactionSubType, &tx.BuySell, &tx.Description, &tx.Amount, &tx.Currency, &tx.Commission, &tx.OrderID, &tx.ExchangeRate, &tx.AmountEUR, &tx.CountryCode, &tx.InputString, &tx.HashId)
-		if scanErr != nil {
-			return nil, fmt.Errorf("error scanning transaction row for userID %d: %w", userID, scanErr)
+	var dividends []models.ProcessedTransaction
+	for _, tx := range userTransactions {
+		if tx.TransactionType == "DIVIDEND" || tx.TransactionType == "SCRIP_DIVIDEND" {
+			dividends = append(dividends, tx)
 		}
-		transactions = append(transactions, tx)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over transaction rows for userID %d: %w", userID, err)
 	}
-	logger.L.Info("DB fetch complete.", "userID", userID, "transactionCount", len(transactions))
-	return transactions, nil
+	return dividends, nil
 }
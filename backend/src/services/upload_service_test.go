@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/processors"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("error")
+	os.Exit(m.Run())
+}
+
+const testUserID = 7
+
+func newTestUploadService(transactions *memoryTransactionRepository) *uploadServiceImpl {
+	return &uploadServiceImpl{
+		transactions:         transactions,
+		transactionProcessor: processors.NewTransactionProcessor(1),
+	}
+}
+
+func openingLot(t *testing.T, lot models.OpeningLot) models.ProcessedTransaction {
+	t.Helper()
+	canonical, err := openingLotTransaction(lot)
+	if err != nil {
+		t.Fatalf("openingLotTransaction: %v", err)
+	}
+	return processors.NewTransactionProcessor(1).Process([]models.CanonicalTransaction{canonical}, "EUR")[0]
+}
+
+func TestInsertProcessedTransactionsCountsDuplicates(t *testing.T) {
+	transactions := newMemoryTransactionRepository()
+	s := newTestUploadService(transactions)
+	first := openingLot(t, models.OpeningLot{ISIN: "IE00B4L5Y983", Quantity: 10, BuyDate: "15-03-2020", CostBasis: 1000})
+	second := openingLot(t, models.OpeningLot{ISIN: "US0378331005", Quantity: 5, BuyDate: "02-01-2019", CostBasis: 700})
+
+	summary := &models.UploadSummary{}
+	if err := s.insertProcessedTransactions(nil, testUserID, []models.ProcessedTransaction{first}, summary); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.insertProcessedTransactions(nil, testUserID, []models.ProcessedTransaction{first, second}, summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.RowsImported != 2 || summary.Duplicates != 1 {
+		t.Errorf("got %d imported and %d duplicates, want 2 and 1", summary.RowsImported, summary.Duplicates)
+	}
+	if count, _ := transactions.CountByUser(context.Background(), testUserID); count != 2 {
+		t.Errorf("stored %d transactions, want 2", count)
+	}
+}
+
+func TestRederiveOpeningLots(t *testing.T) {
+	transactions := newMemoryTransactionRepository()
+	s := newTestUploadService(transactions)
+	lot := openingLot(t, models.OpeningLot{ISIN: "IE00B4L5Y983", ProductName: "iShares Core MSCI World", Quantity: 10, BuyDate: "15-03-2020", CostBasis: 1000})
+	trade := lot
+	trade.Source, trade.InputString, trade.HashId = "degiro", "15-03-2020,09:00,...", "trade"
+	if _, err := transactions.Insert(nil, testUserID, []models.ProcessedTransaction{lot, trade}); err != nil {
+		t.Fatal(err)
+	}
+	// Both were stored before a rate was known for their day.
+	for _, id := range []int64{1, 2} {
+		transactions.update(testUserID, id, func(tx *models.ProcessedTransaction) { tx.ExchangeRate, tx.AmountEUR = 0, 0 })
+	}
+
+	changed, err := s.rederiveOpeningLots(nil, testUserID, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Fatalf("rederived %d opening lots, want 1", changed)
+	}
+	stored, _ := transactions.ListByUser(context.Background(), testUserID)
+	if got := stored[0]; got.AmountEUR != models.NewMoney(-1000) || got.ExchangeRate != 1 || got.ProductName != "iShares Core MSCI World" {
+		t.Errorf("opening lot rederived as %+v", got)
+	}
+	if got := stored[1]; got.AmountEUR != 0 {
+		t.Errorf("broker trade was changed to %+v", got)
+	}
+
+	if changed, err := s.rederiveOpeningLots(nil, testUserID, "EUR"); err != nil || changed != 0 {
+		t.Errorf("second run rederived %d opening lots (error %v), want none", changed, err)
+	}
+}
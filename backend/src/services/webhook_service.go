@@ -0,0 +1,346 @@
+// backend/src/services/webhook_service.go
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/logger"
+	"github.com/username/taxfolio/backend/src/model"
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/security"
+)
+
+// ErrInvalidWebhook is returned when a webhook's URL or events are not acceptable.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Events are queued as deliveries before they are posted, so one a webhook fails to accept is
+// retried in the background.
+const (
+	maxWebhooks           = 10
+	webhookTimeout        = 10 * time.Second
+	webhookMaxAttempts    = 8
+	webhookRetryDelay     = time.Minute // After the first failed attempt, doubling for each further one
+	webhookMaxRetryDelay  = time.Hour
+	webhookBatchSize      = 50 // Deliveries attempted per run of the worker
+	webhookMaxErrorLength = 200
+
+	// Headers of a delivery. The signature is "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">"
+	// keyed with the webhook's secret.
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type webhookServiceImpl struct {
+	db           *sql.DB
+	keyring      *security.Keyring
+	allowPrivate bool // Accept plain HTTP and private addresses, for local development
+	httpClient   http.Client
+}
+
+// NewWebhookService creates a new WebhookService. Webhook secrets are encrypted at rest with keyring.
+// Unless allowPrivate is set, webhooks must use HTTPS and are never posted to loopback, private or
+// link-local addresses, so they cannot reach the server's own network.
+func NewWebhookService(db *sql.DB, keyring *security.Keyring, allowPrivate bool) WebhookService {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivateAddresses
+	}
+	return &webhookServiceImpl{
+		db:           db,
+		keyring:      keyring,
+		allowPrivate: allowPrivate,
+		httpClient: http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// A redirect counts as a failed delivery rather than being followed somewhere else.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// refusePrivateAddresses stops connections to addresses of the server's own network, checked after
+// the host name was resolved.
+func refusePrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+func (s *webhookServiceImpl) GetWebhooks(userID int64) ([]models.Webhook, error) {
+	return model.GetWebhooks(s.db, userID)
+}
+
+// CreateWebhook registers a webhook with a new secret, returned in the result only this once.
+func (s *webhookServiceImpl) CreateWebhook(userID int64, webhook models.Webhook) (*models.Webhook, error) {
+	if err := s.validateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+	count, err := model.CountWebhooks(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooks {
+		return nil, fmt.Errorf("%w: no more than %d webhooks are allowed", ErrInvalidWebhook, maxWebhooks)
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	secret := "whsec_" + hex.EncodeToString(secretBytes)
+	if webhook.EncryptedSecret, err = s.keyring.Encrypt(secret); err != nil {
+		return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	webhook.UserID = userID
+	if err := model.CreateWebhook(s.db, &webhook); err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return &webhook, nil
+}
+
+func (s *webhookServiceImpl) UpdateWebhook(userID, id int64, webhook models.Webhook) (*models.Webhook, error) {
+	if err := s.validateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+	webhook.ID, webhook.UserID = id, userID
+	if err := model.UpdateWebhook(s.db, &webhook); err != nil {
+		return nil, err
+	}
+	return model.GetWebhook(s.db, userID, id)
+}
+
+func (s *webhookServiceImpl) DeleteWebhook(userID, id int64) error {
+	return model.DeleteWebhook(s.db, userID, id)
+}
+
+func (s *webhookServiceImpl) GetDeliveries(userID, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := model.GetWebhook(s.db, userID, webhookID); err != nil {
+		return nil, err
+	}
+	return model.GetWebhookDeliveries(s.db, userID, webhookID, limit)
+}
+
+// validateWebhook checks a webhook's URL and events, removing repeated events.
+func (s *webhookServiceImpl) validateWebhook(webhook *models.Webhook) error {
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && (u.Scheme != "http" || !s.allowPrivate)) {
+		return fmt.Errorf("%w: the URL must be an absolute https URL", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: the URL must not carry credentials", ErrInvalidWebhook)
+	}
+	if !s.allowPrivate {
+		if ip := net.ParseIP(u.Hostname()); ip != nil && refusePrivateAddresses("tcp", net.JoinHostPort(ip.String(), "443"), nil) != nil {
+			return fmt.Errorf("%w: the URL must point to a public address", ErrInvalidWebhook)
+		}
+	}
+
+	if len(webhook.Events) == 0 {
+		return fmt.Errorf("%w: subscribe to at least one of %s", ErrInvalidWebhook, strings.Join(models.WebhookEvents, ", "))
+	}
+	events := []string{}
+	for _, event := range webhook.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			return fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalidWebhook, event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	webhook.Events = events
+	return nil
+}
+
+// Dispatch queues event, with data as its payload, for each of the user's webhooks subscribed to it,
+// and attempts the deliveries in the background. Failures are logged and never reach the caller.
+func (s *webhookServiceImpl) Dispatch(userID int64, event string, data interface{}) {
+	webhooks, err := model.GetSubscribedWebhooks(s.db, userID, event)
+	if err != nil {
+		logger.L.Error("Failed to list webhooks", "userID", userID, "event", event, "error", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{Event: event, CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		logger.L.Error("Failed to encode webhook payload", "userID", userID, "event", event, "error", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		delivery := models.WebhookDelivery{
+			WebhookID: webhook.ID,
+			Event:     event,
+			Payload:   string(payload),
+			// Keeps the worker away while the first attempt runs
+			NextAttemptAt: now.Add(webhookRetryDelay),
+			CreatedAt:     now,
+		}
+		if delivery.ID, err = model.InsertWebhookDelivery(s.db, delivery); err != nil {
+			logger.L.Error("Failed to queue webhook delivery", "webhookID", webhook.ID, "event", event, "error", err)
+			continue
+		}
+		go s.attemptDelivery(webhook, delivery)
+	}
+}
+
+// attemptDelivery posts a delivery and records the outcome, scheduling the next attempt after a
+// failure. It reports whether the delivery was accepted.
+func (s *webhookServiceImpl) attemptDelivery(webhook models.Webhook, delivery models.WebhookDelivery) bool {
+	responseStatus, err := s.post(webhook, delivery)
+	now := time.Now()
+	if err == nil {
+		if err := model.MarkWebhookDeliveryDelivered(s.db, delivery.ID, responseStatus, now); err != nil {
+			logger.L.Error("Failed to mark webhook delivery as delivered", "deliveryID", delivery.ID, "error", err)
+		}
+		logger.L.Info("Webhook delivered", "webhookID", webhook.ID, "event", delivery.Event, "deliveryID", delivery.ID)
+		return true
+	}
+
+	attempts := delivery.Attempts + 1
+	status, nextAttemptAt := models.WebhookDeliveryPending, now.Add(webhookBackoff(attempts))
+	if attempts >= webhookMaxAttempts {
+		status = models.WebhookDeliveryFailed
+	}
+	lastError := err.Error()
+	if len(lastError) > webhookMaxErrorLength {
+		lastError = lastError[:webhookMaxErrorLength]
+	}
+	if markErr := model.MarkWebhookDeliveryFailed(s.db, delivery.ID, status, responseStatus, lastError, nextAttemptAt); markErr != nil {
+		logger.L.Error("Failed to record webhook delivery attempt", "deliveryID", delivery.ID, "error", markErr)
+	}
+	if status == models.WebhookDeliveryFailed {
+		logger.L.Warn("Gave up on webhook delivery", "webhookID", webhook.ID, "event", delivery.Event, "deliveryID", delivery.ID,
+			"attempts", attempts, "error", err)
+		return false
+	}
+	logger.L.Info("Webhook delivery kept for a retry", "webhookID", webhook.ID, "event", delivery.Event, "deliveryID", delivery.ID,
+		"attempts", attempts, "nextAttemptAt", nextAttemptAt, "error", err)
+	return false
+}
+
+// post sends a delivery's payload to its webhook, signed with the webhook's secret. It returns the
+// HTTP status of the response, if there was one, and an error unless it was a 2xx.
+func (s *webhookServiceImpl) post(webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	secret, err := s.keyring.Decrypt(webhook.EncryptedSecret)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Rumoclaro-Webhooks/1.0")
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhookSignatureHeader, "t="+timestamp+",v1="+signWebhookPayload(secret, timestamp, delivery.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload is the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with secret. Including the
+// timestamp lets receivers reject replayed deliveries.
+func signWebhookPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the wait before the attempt following the given number of failed ones.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxRetryDelay)
+}
+
+// StartDeliveryWorker retries the pending deliveries in the background.
+func (s *webhookServiceImpl) StartDeliveryWorker(interval time.Duration) {
+	StartPeriodicJob("webhook-deliveries", interval, func() {
+		if _, err := s.RetryDeliveries(); err != nil {
+			logger.L.Error("Webhook delivery run failed", "error", err)
+		}
+	})
+}
+
+// RetryDeliveries attempts the pending deliveries whose retry is due and returns how many were
+// accepted. Deliveries of a webhook disabled meanwhile are attempted all the same, as they were
+// queued while it was enabled.
+func (s *webhookServiceImpl) RetryDeliveries() (int, error) {
+	deliveries, err := model.GetDueWebhookDeliveries(s.db, time.Now(), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.WebhookID)
+	}
+	webhooks, err := model.GetWebhooksByIDs(s.db, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			continue // Deleted along with its deliveries since they were read
+		}
+		if s.attemptDelivery(webhook, delivery) {
+			delivered++
+		}
+	}
+	if len(deliveries) > 0 {
+		logger.L.Info("Webhook deliveries retried", "attempted", len(deliveries), "delivered", delivered)
+	}
+	return delivered, nil
+}
+
+// SubscribedUsers lists the users with an enabled webhook subscribed to event.
+func (s *webhookServiceImpl) SubscribedUsers(event string) ([]int64, error) {
+	return model.GetSubscribedUserIDs(s.db, event)
+}
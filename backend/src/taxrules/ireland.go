@@ -0,0 +1,73 @@
+// backend/src/taxrules/ireland.go
+package taxrules
+
+import (
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+const (
+	// ieCGTRate is the capital gains tax rate.
+	ieCGTRate = 0.33
+	// ieCGTExemption is the annual personal exemption from capital gains tax.
+	ieCGTExemption = 1270.0
+)
+
+// ieExitTaxRate returns the exit tax rate on gains from EU funds and ETFs in the year, lowered by the
+// 2026 budget.
+func ieExitTaxRate(year int) float64 {
+	if year >= 2026 {
+		return 0.38
+	}
+	return 0.41
+}
+
+// ireland applies the rules for residents of Ireland: gains on shares and options are subject to
+// capital gains tax, gains on ETFs and funds to exit tax, and dividends to income tax.
+type ireland struct{}
+
+func (ireland) Country() string { return "IE" }
+
+// ClassifySale taxes the whole gain of every sale, however long the shares were held.
+func (ireland) ClassifySale(models.SaleDetail) (string, float64) { return "", 1 }
+
+func (ireland) Apply(in Input) models.TaxReport {
+	report := newReport("IE", in.TaxYear)
+
+	var gains, fundGains, fundLosses float64
+	for _, sale := range in.Sales {
+		gain := saleGain(sale)
+		switch {
+		case sale.AssetClass != models.AssetClassETF && sale.AssetClass != models.AssetClassFund:
+			gains += gain
+		case gain > 0:
+			fundGains += gain
+		default:
+			fundLosses -= gain
+		}
+	}
+	for _, option := range in.Options {
+		gains += optionGain(option)
+	}
+
+	year, _ := strconv.Atoi(in.TaxYear)
+	exemption := min(ieCGTExemption, max(0, gains))
+	addCategory(&report, "IE_CAPITAL_GAINS", "Capital gains on shares and options (capital gains tax)", gains, exemption, ieCGTRate, 0)
+	addExemption(&report, "IE_CGT_EXEMPTION", "Annual personal exemption from capital gains tax", exemption)
+	addCategory(&report, "IE_FUND_GAINS", "Gains on ETFs and funds (exit tax)", fundGains, 0, ieExitTaxRate(year), 0)
+	addExemption(&report, "IE_FUND_LOSSES", "Losses on ETFs and funds, which cannot be offset", fundLosses)
+
+	// Dividends are taxed at the marginal income tax rate, which depends on the user's other income.
+	dividends := 0.0
+	for _, summary := range in.Dividends {
+		dividends += summary.GrossAmt
+	}
+	addCategory(&report, "IE_DIVIDENDS", "Foreign dividends (income tax at your marginal rate, USC and PRSI)", dividends, 0, 0, 0)
+	if dividends > 0 {
+		report.Notes = append(report.Notes, "Dividends are added to your other income and taxed at your marginal rate; their tax is not included in the total.",
+			"Foreign tax withheld on dividends is credited up to the rate of the double taxation treaty with the source country.")
+	}
+	report.Notes = append(report.Notes, "Deemed disposals of ETFs held for eight years are reported separately and are not included.")
+	return report
+}
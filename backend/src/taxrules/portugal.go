@@ -0,0 +1,114 @@
+// backend/src/taxrules/portugal.go
+package taxrules
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+const (
+	// ptRate is the autonomous rate of investment income and capital gains (IRS articles 71 and 72).
+	ptRate = 0.28
+	// ptBlacklistRate applies instead to income from the jurisdictions of ptBlacklist.
+	ptBlacklistRate = 0.35
+
+	// ptHoldingRulesFrom is the first year of the holding period rules for securities (Lei 24-D/2022).
+	ptHoldingRulesFrom = 2023
+	// ptShortTermDays is the holding period below which gains must be added to other income once
+	// taxable income reaches the top bracket.
+	ptShortTermDays = 365
+)
+
+// ptBlacklist holds the jurisdictions with a clearly more favourable tax regime listed by Portaria
+// 150/2004, as amended. Gains and dividends from them are taxed at ptBlacklistRate and their losses
+// cannot be offset.
+var ptBlacklist = map[string]bool{
+	"AD": true, "AG": true, "AI": true, "AS": true, "AW": true, "BB": true, "BH": true, "BM": true,
+	"BN": true, "BS": true, "BZ": true, "CK": true, "DM": true, "FJ": true, "FK": true, "FM": true,
+	"GD": true, "GG": true, "GI": true, "GU": true, "GY": true, "HK": true, "IM": true, "JE": true,
+	"JM": true, "JO": true, "KI": true, "KN": true, "KW": true, "KY": true, "LB": true, "LC": true,
+	"LI": true, "LR": true, "MH": true, "MO": true, "MS": true, "MU": true, "MV": true, "NR": true,
+	"NU": true, "OM": true, "PA": true, "PF": true, "PR": true, "PW": true, "QA": true, "SB": true,
+	"SC": true, "SH": true, "SM": true, "SZ": true, "TC": true, "TK": true, "TO": true, "TT": true,
+	"TV": true, "VC": true, "VG": true, "VI": true, "VU": true, "WS": true, "YE": true,
+}
+
+// ptShortTerm is the holding period rule of Portuguese sales of shares held for less than ptShortTermDays.
+const ptShortTerm = "PT_SHORT_TERM"
+
+// portugal applies the rules for residents of Portugal opting for autonomous taxation: capital gains
+// are category G income of annex J, dividends category E income.
+type portugal struct{}
+
+func (portugal) Country() string { return "PT" }
+
+// ClassifySale applies the holding period rules to sales made from 2023: shares held for less than
+// 365 days are flagged PT_SHORT_TERM. The whole gain is taxed however long the shares were held: the
+// 50% inclusion of art. 43.º(3) CIRS only covers shares of unlisted micro and small companies, which
+// the brokers' statements do not hold.
+func (portugal) ClassifySale(sale models.SaleDetail) (string, float64) {
+	buyDate, saleDate := utils.ParseDate(sale.BuyDate), utils.ParseDate(sale.SaleDate)
+	if buyDate.IsZero() || saleDate.Year() < ptHoldingRulesFrom {
+		return "", 1
+	}
+	if heldDays(sale) < ptShortTermDays {
+		return ptShortTerm, 1
+	}
+	return "", 1
+}
+
+func (p portugal) Apply(in Input) models.TaxReport {
+	report := newReport("PT", in.TaxYear)
+
+	var gains, blacklistedGains, blacklistedLosses, shortTermGains float64
+	for _, sale := range in.Sales {
+		gain := taxableGain(p, sale).Float64()
+		if rule, _ := p.ClassifySale(sale); rule == ptShortTerm {
+			shortTermGains += gain
+		}
+		switch {
+		case !ptBlacklist[issuerCountry(sale.ISIN)]:
+			gains += gain
+		case gain > 0:
+			blacklistedGains += gain
+		default:
+			blacklistedLosses -= gain
+		}
+	}
+	for _, option := range in.Options {
+		gains += optionGain(option)
+	}
+
+	var dividends, dividendsWithheld, blacklistedDividends, blacklistedWithheld float64
+	for label, summary := range in.Dividends {
+		// Withholding is credited up to the Portuguese tax on the dividends of the same country.
+		if ptBlacklist[countryOf(label)] {
+			blacklistedDividends += summary.GrossAmt
+			blacklistedWithheld += math.Min(-summary.TaxedAmt, summary.GrossAmt*ptBlacklistRate)
+			continue
+		}
+		dividends += summary.GrossAmt
+		dividendsWithheld += math.Min(-summary.TaxedAmt, summary.GrossAmt*ptRate)
+	}
+
+	addCategory(&report, "PT_CAPITAL_GAINS", "Capital gains on shares, funds and derivatives (category G, annex J tables 9.2A and 9.2B)", gains, 0, ptRate, 0)
+	if blacklistedGains > 0 {
+		addCategory(&report, "PT_CAPITAL_GAINS_BLACKLISTED", "Capital gains on securities from listed tax havens (category G, annex J)", blacklistedGains, 0, ptBlacklistRate, 0)
+	}
+	addCategory(&report, "PT_DIVIDENDS", "Dividends (category E, annex J table 8A)", dividends, 0, ptRate, dividendsWithheld)
+	if blacklistedDividends > 0 {
+		addCategory(&report, "PT_DIVIDENDS_BLACKLISTED", "Dividends from listed tax havens (category E, annex J table 8A)", blacklistedDividends, 0, ptBlacklistRate, blacklistedWithheld)
+	}
+	addExemption(&report, "PT_BLACKLISTED_LOSSES", "Losses on securities from listed tax havens, which cannot be offset", blacklistedLosses)
+
+	if fees := custodyFees(in.Fees); fees > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("Account and custody fees of %.2f EUR are not deductible from investment income.", fees))
+	}
+	if shortTermGains > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("Gains of %.2f EUR come from shares held for less than 365 days. They must be added to your other income when your taxable income reaches the top bracket.", shortTermGains))
+	}
+	return report
+}
@@ -0,0 +1,105 @@
+// backend/src/taxrules/spain.go
+package taxrules
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// esOffsetLimit is the share of one kind of savings income a net loss of the other kind may offset
+// (Ley 35/2006, article 49).
+const esOffsetLimit = 0.25
+
+// esTreatyRate is the withholding rate of most of Spain's double taxation treaties, up to which
+// foreign tax on dividends is credited.
+const esTreatyRate = 0.15
+
+// esBracket is a band of the savings income scale: income up to upTo is taxed at rate.
+type esBracket struct {
+	upTo float64
+	rate float64
+}
+
+// esSavingsScale returns the state and regional savings income scale combined, as in force in the year.
+func esSavingsScale(year int) []esBracket {
+	switch {
+	case year >= 2025:
+		return []esBracket{{6000, 0.19}, {50000, 0.21}, {200000, 0.23}, {300000, 0.27}, {math.Inf(1), 0.30}}
+	case year >= 2023:
+		return []esBracket{{6000, 0.19}, {50000, 0.21}, {200000, 0.23}, {300000, 0.27}, {math.Inf(1), 0.28}}
+	default:
+		return []esBracket{{6000, 0.19}, {50000, 0.21}, {200000, 0.23}, {math.Inf(1), 0.26}}
+	}
+}
+
+// spain applies the rules for residents of Spain: gains and dividends make up the savings base, taxed
+// on a progressive scale, and custody fees are deducted from the dividends.
+type spain struct{}
+
+func (spain) Country() string { return "ES" }
+
+// ClassifySale taxes the whole gain of every sale, however long the shares were held.
+func (spain) ClassifySale(models.SaleDetail) (string, float64) { return "", 1 }
+
+func (spain) Apply(in Input) models.TaxReport {
+	report := newReport("ES", in.TaxYear)
+
+	gains := 0.0
+	for _, sale := range in.Sales {
+		gains += saleGain(sale)
+	}
+	for _, option := range in.Options {
+		gains += optionGain(option)
+	}
+	var grossDividends, withheld float64
+	for _, summary := range in.Dividends {
+		grossDividends += summary.GrossAmt
+		withheld += math.Min(-summary.TaxedAmt, summary.GrossAmt*esTreatyRate)
+	}
+	fees := math.Min(custodyFees(in.Fees), math.Max(0, grossDividends))
+	dividends := grossDividends - fees
+	addExemption(&report, "ES_CUSTODY_FEES", "Administration and custody fees deducted from dividends", fees)
+
+	// A net loss on sales offsets up to a quarter of the dividends, and a net loss on dividends up to
+	// a quarter of the gains. What is left is carried forward for four years.
+	if gains < 0 && dividends > 0 {
+		offset := math.Min(-gains, dividends*esOffsetLimit)
+		dividends -= offset
+		addExemption(&report, "ES_LOSS_OFFSET", "Capital losses offset against dividends", offset)
+		addExemption(&report, "ES_LOSS_CARRIED_FORWARD", "Capital losses left to offset in the next four years", -gains-offset)
+		gains = 0
+	} else if dividends < 0 && gains > 0 {
+		offset := math.Min(-dividends, gains*esOffsetLimit)
+		gains -= offset
+		addExemption(&report, "ES_LOSS_OFFSET", "Negative dividend income offset against capital gains", offset)
+		dividends = 0
+	}
+
+	// The scale applies to the savings base as a whole; each category bears the average rate.
+	year, _ := strconv.Atoi(in.TaxYear)
+	base := math.Max(0, gains) + math.Max(0, dividends)
+	rate := 0.0
+	if base > 0 {
+		rate = math.Round(progressiveTax(base, esSavingsScale(year))/base*1e4) / 1e4
+	}
+	addCategory(&report, "ES_CAPITAL_GAINS", "Capital gains and losses (savings base)", gains, 0, rate, 0)
+	addCategory(&report, "ES_DIVIDENDS", "Dividends net of deductible expenses (savings base)", dividends, 0, rate, withheld)
+	report.Notes = append(report.Notes, "Losses on shares bought back within two months of their sale cannot be offset until the shares bought back are sold.",
+		"Foreign tax withheld is credited up to 15% of the dividends; some treaties set a lower limit.")
+	return report
+}
+
+// progressiveTax returns the tax on income under a scale of brackets.
+func progressiveTax(income float64, scale []esBracket) float64 {
+	tax, lower := 0.0, 0.0
+	for _, bracket := range scale {
+		if income <= lower {
+			break
+		}
+		tax += (math.Min(income, bracket.upTo) - lower) * bracket.rate
+		lower = bracket.upTo
+	}
+	return tax
+}
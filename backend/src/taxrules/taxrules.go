@@ -0,0 +1,165 @@
+// backend/src/taxrules/taxrules.go
+
+// Package taxrules turns a user's realized results into the taxable categories and exemptions of the
+// country they are tax resident in. Each country implements Rules; Get selects the user's.
+package taxrules
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/username/taxfolio/backend/src/models"
+	"github.com/username/taxfolio/backend/src/utils"
+)
+
+// Countries lists the tax residences rules are available for, by ISO 3166 alpha-2 code.
+var Countries = []string{"PT", "ES", "IE"}
+
+// Input is a user's realized results for one tax year, with amounts in EUR.
+type Input struct {
+	TaxYear   string
+	Sales     []models.SaleDetail
+	Options   []models.OptionSaleDetail
+	Dividends map[string]models.DividendCountrySummary // By country label, as in the dividend tax summary
+	Fees      []models.FeeDetail
+}
+
+// Rules applies a jurisdiction's tax rules to a year's realized results.
+type Rules interface {
+	Country() string
+	// ClassifySale returns the holding period rule a sale falls under, if any, and the share of its
+	// gain that is taxable.
+	ClassifySale(sale models.SaleDetail) (rule string, inclusionRate float64)
+	Apply(in Input) models.TaxReport
+}
+
+// Get returns the rules of the tax residence with the given ISO 3166 alpha-2 code.
+func Get(country string) (Rules, error) {
+	switch country {
+	case "PT":
+		return portugal{}, nil
+	case "ES":
+		return spain{}, nil
+	case "IE":
+		return ireland{}, nil
+	default:
+		return nil, fmt.Errorf("no tax rules available for country: %s", country)
+	}
+}
+
+// newReport starts the report of a year, with empty lists rather than nulls.
+func newReport(country, taxYear string) models.TaxReport {
+	return models.TaxReport{
+		Country:    country,
+		TaxYear:    taxYear,
+		Categories: []models.TaxCategory{},
+		Exemptions: []models.TaxExemption{},
+		Notes:      []string{},
+	}
+}
+
+// addCategory adds a category taxed at a flat rate. Foreign tax withheld is credited up to the tax due.
+func addCategory(report *models.TaxReport, code, label string, income, exempt, rate, withheld float64) {
+	taxable := math.Max(0, income-exempt)
+	tax := utils.RoundMoney(taxable * rate)
+	credit := utils.RoundMoney(math.Min(withheld, tax))
+	report.Categories = append(report.Categories, models.TaxCategory{
+		Code:                code,
+		Label:               label,
+		IncomeEUR:           utils.RoundMoney(income),
+		ExemptEUR:           utils.RoundMoney(math.Min(exempt, math.Max(0, income))),
+		TaxableEUR:          utils.RoundMoney(taxable),
+		Rate:                rate,
+		TaxEUR:              utils.RoundMoney(tax - credit),
+		ForeignTaxCreditEUR: credit,
+	})
+	report.TaxEUR = utils.RoundMoney(report.TaxEUR + tax - credit)
+}
+
+// addExemption records an amount left out of the taxable income. Zero amounts are not listed.
+func addExemption(report *models.TaxReport, code, label string, amount float64) {
+	if amount == 0 {
+		return
+	}
+	report.Exemptions = append(report.Exemptions, models.TaxExemption{Code: code, Label: label, AmountEUR: utils.RoundMoney(amount)})
+}
+
+// AnnotateSales sets the holding period, holding rule, inclusion rate and gains of each sale under the rules.
+func AnnotateSales(rules Rules, sales []models.SaleDetail) {
+	for i := range sales {
+		sale := &sales[i]
+		sale.HoldingDays = heldDays(*sale)
+		sale.HeldOver24Months = heldOver24Months(*sale)
+		sale.HoldingRule, sale.InclusionRate = rules.ClassifySale(*sale)
+		sale.GainEUR = sale.Delta - sale.Commission - sale.TransactionTax
+		sale.TaxableGainEUR = taxableGain(rules, *sale)
+	}
+}
+
+// saleGain is the gain of a sale after the commissions and transaction taxes of both legs.
+func saleGain(sale models.SaleDetail) float64 {
+	return (sale.Delta - sale.Commission - sale.TransactionTax).Float64()
+}
+
+// taxableGain is the part of a sale's gain the rules tax, in cents.
+func taxableGain(rules Rules, sale models.SaleDetail) models.Money {
+	_, inclusionRate := rules.ClassifySale(sale)
+	return utils.RoundAmount((sale.Delta - sale.Commission - sale.TransactionTax).Mul(inclusionRate))
+}
+
+// optionGain is the gain of a closed option position after its commissions.
+func optionGain(option models.OptionSaleDetail) float64 {
+	return option.Delta - option.Commission
+}
+
+// heldDays is the number of days between the purchase and the sale of a sale's shares.
+func heldDays(sale models.SaleDetail) int {
+	buy, sold := utils.ParseDate(sale.BuyDate), utils.ParseDate(sale.SaleDate)
+	if buy.IsZero() || sold.IsZero() {
+		return 0
+	}
+	return int(sold.Sub(buy) / (24 * time.Hour))
+}
+
+// heldOver24Months reports whether a sale's shares were held for more than 24 calendar months, which
+// the day count cannot tell exactly across leap years.
+func heldOver24Months(sale models.SaleDetail) bool {
+	buy, sold := utils.ParseDate(sale.BuyDate), utils.ParseDate(sale.SaleDate)
+	if buy.IsZero() || sold.IsZero() {
+		return false
+	}
+	return sold.After(buy.AddDate(0, 24, 0))
+}
+
+// countryOf returns the ISO 3166 alpha-2 code of a country label of the dividend tax summary,
+// such as "840 - United States of America (the)", or "" when it is not a known country.
+func countryOf(label string) string {
+	numeric, _, _ := strings.Cut(label, " - ")
+	country, ok := utils.GetCountryByNumeric(numeric)
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(country.Alpha2)
+}
+
+// issuerCountry returns the country prefix of an ISIN.
+func issuerCountry(isin string) string {
+	if len(isin) < 2 {
+		return ""
+	}
+	return strings.ToUpper(isin[:2])
+}
+
+// custodyFees sums the account, connectivity and custody fees of the year, as a positive amount.
+// Trade commissions and transaction taxes are already deducted from the gains of the sales they belong to.
+func custodyFees(fees []models.FeeDetail) float64 {
+	total := 0.0
+	for _, fee := range fees {
+		if fee.Category == "Brokerage Fee" {
+			total -= fee.AmountEUR
+		}
+	}
+	return math.Max(0, total)
+}
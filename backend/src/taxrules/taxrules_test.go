@@ -0,0 +1,32 @@
+package taxrules
+
+import (
+	"testing"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+func TestAnnotateSalesFlagsSharesHeldOver24Months(t *testing.T) {
+	tests := []struct {
+		name, buyDate, saleDate string
+		want                    bool
+	}{
+		{"held one year", "15-03-2022", "15-03-2023", false},
+		{"held exactly 24 months", "15-03-2022", "15-03-2024", false},
+		{"held a day over 24 months", "15-03-2022", "16-03-2024", true},
+		{"bought on a leap day", "29-02-2024", "02-03-2026", true},
+		{"no buy date", "", "16-03-2024", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sales := []models.SaleDetail{{BuyDate: tt.buyDate, SaleDate: tt.saleDate, Delta: models.NewMoney(100)}}
+			AnnotateSales(portugal{}, sales)
+			if got := sales[0].HeldOver24Months; got != tt.want {
+				t.Errorf("HeldOver24Months = %v after %d days, want %v", got, sales[0].HoldingDays, tt.want)
+			}
+			if sales[0].InclusionRate != 1 {
+				t.Errorf("InclusionRate = %v, want the whole gain taxed", sales[0].InclusionRate)
+			}
+		})
+	}
+}
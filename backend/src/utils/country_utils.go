@@ -11,14 +11,16 @@ import (
 )
 
 type CountryInfo struct {
-	Country string `json:"country"`
-	Alpha2  string `json:"alpha2"`
-	Alpha3  string `json:"alpha3"`
-	Numeric string `json:"numeric"`
+	Country   string `json:"country"`
+	CountryPT string `json:"country_pt"` // European Portuguese name
+	Alpha2    string `json:"alpha2"`
+	Alpha3    string `json:"alpha3"`
+	Numeric   string `json:"numeric"`
 }
 
 var (
 	countryMap map[string]CountryInfo
+	numericMap map[string]CountryInfo
 	loadOnce   sync.Once
 	loadError  error
 	dataLoaded bool = false
@@ -45,8 +47,10 @@ func InitCountryData(filePath string) error {
 		}
 
 		countryMap = make(map[string]CountryInfo)
+		numericMap = make(map[string]CountryInfo)
 		for _, country := range countries {
 			countryMap[strings.ToUpper(country.Alpha2)] = country
+			numericMap[strings.TrimSpace(country.Numeric)] = country
 		}
 		dataLoaded = true
 		logger.L.Info("Country data loaded successfully.", "path", filePath, "countryCount", len(countryMap))
@@ -67,8 +71,22 @@ func GetCountryCodeString(isin string) string {
 	if len(isin) < 2 {
 		return "Invalid ISIN (Too Short)"
 	}
+	return GetCountryCodeStringByAlpha2(isin[:2])
+}
 
-	alpha2Code := strings.ToUpper(isin[:2])
+// GetCountryCodeStringByAlpha2 builds the same label as GetCountryCodeString from an ISO 3166 alpha-2 code,
+// for instruments whose broker exports no ISIN.
+func GetCountryCodeStringByAlpha2(alpha2 string) string {
+	if !dataLoaded {
+		logger.L.Error("Attempted to GetCountryCodeStringByAlpha2 before country data was loaded.")
+		return "Country Data Not Initialized"
+	}
+	if loadError != nil {
+		logger.L.Warn("Cannot get country code string due to earlier data load error", "error", loadError)
+		return "Error Loading Country Data"
+	}
+
+	alpha2Code := strings.ToUpper(alpha2)
 	countryInfo, found := countryMap[alpha2Code]
 	if !found {
 		return "Unknown Code: " + alpha2Code
@@ -80,3 +98,13 @@ func GetCountryCodeString(isin string) string {
 	}
 	return fmt.Sprintf("%s - %s", numericCode, countryInfo.Country)
 }
+
+// GetCountryByNumeric returns the country with the given ISO 3166 numeric code, as found at the start
+// of the labels built by GetCountryCodeString.
+func GetCountryByNumeric(numeric string) (CountryInfo, bool) {
+	if !dataLoaded {
+		return CountryInfo{}, false
+	}
+	country, found := numericMap[strings.TrimSpace(numeric)]
+	return country, found
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// CashFlow is a dated amount used by the return calculations.
+// Negative amounts are money invested, positive amounts are money returned.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+const (
+	xirrMaxIterations = 100
+	xirrTolerance     = 1e-7
+	daysPerYear       = 365.0
+)
+
+// ErrNoSolution is returned when a return calculation cannot produce a meaningful value.
+var ErrNoSolution = errors.New("no solution for the given cash flows")
+
+// XIRR computes the annualised money-weighted rate of return for irregularly spaced cash flows.
+// It uses Newton-Raphson and falls back to bisection if Newton does not converge.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoSolution
+	}
+	hasPositive, hasNegative := false, false
+	first := flows[0].Date
+	for _, f := range flows {
+		if f.Amount > 0 {
+			hasPositive = true
+		} else if f.Amount < 0 {
+			hasNegative = true
+		}
+		if f.Date.Before(first) {
+			first = f.Date
+		}
+	}
+	if !hasPositive || !hasNegative {
+		return 0, ErrNoSolution
+	}
+
+	npv := func(rate float64) (float64, float64) {
+		var value, derivative float64
+		for _, f := range flows {
+			years := f.Date.Sub(first).Hours() / 24 / daysPerYear
+			factor := math.Pow(1+rate, years)
+			value += f.Amount / factor
+			derivative -= years * f.Amount / (factor * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	// Newton-Raphson
+	rate := 0.1
+	for i := 0; i < xirrMaxIterations; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < xirrTolerance {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < xirrTolerance {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Bisection fallback on (-0.9999, 100)
+	low, high := -0.9999, 100.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return 0, ErrNoSolution
+	}
+	for i := 0; i < 1000; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < xirrTolerance || (high-low)/2 < xirrTolerance {
+			return mid, nil
+		}
+		if midValue*lowValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+	return 0, ErrNoSolution
+}
+
+// ModifiedDietz approximates the time-weighted return of a portfolio over [start, end]
+// when intermediate valuations are not available. Flows are external contributions
+// into the portfolio (positive) or withdrawals from it (negative).
+func ModifiedDietz(startValue, endValue float64, start, end time.Time, flows []CashFlow) (float64, error) {
+	totalDays := end.Sub(start).Hours() / 24
+	if totalDays <= 0 {
+		return 0, ErrNoSolution
+	}
+	var netFlow, weightedFlow float64
+	for _, f := range flows {
+		weight := end.Sub(f.Date).Hours() / 24 / totalDays
+		weight = math.Max(0, math.Min(1, weight))
+		netFlow += f.Amount
+		weightedFlow += weight * f.Amount
+	}
+	denominator := startValue + weightedFlow
+	if denominator == 0 {
+		return 0, ErrNoSolution
+	}
+	return (endValue - startValue - netFlow) / denominator, nil
+}
@@ -2,34 +2,44 @@
 package utils
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"net/http" // Added for http.ResponseWriter and status codes
+	"strings"
 
 	"github.com/username/taxfolio/backend/src/logger" // For logger.L
 )
 
-// GenerateETag creates a SHA256 hash of the JSON representation of the data.
-// Returns the ETag string (hex-encoded hash) and any error during JSON marshaling.
-func GenerateETag(data interface{}) (string, error) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal data for ETag generation: %w", err)
-	}
-	hash := sha256.Sum256(jsonData)
-	return hex.EncodeToString(hash[:]), nil
+// ErrorResponse is the body of every JSON error response.
+type ErrorResponse struct {
+	Code    string      `json:"code"`              // Stable, machine-readable, e.g. NOT_FOUND or QUOTA_EXCEEDED
+	Message string      `json:"message"`           // Human-readable, may be localized
+	Details interface{} `json:"details,omitempty"` // Extra data specific to the code
 }
 
-// SendJSONError is a helper function to send JSON formatted error responses.
-// It now resides in the utils package.
-func SendJSONError(w http.ResponseWriter, message string, statusCode int) {
+// SendError sends a JSON error response with an explicit code and optional details.
+func SendError(w http.ResponseWriter, statusCode int, code, message string, details interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if logger.L != nil { // Check if logger is initialized
-		logger.L.Warn("Sending JSON error to client", "message", message, "statusCode", statusCode)
+		logger.L.Warn("Sending JSON error to client", "code", code, "message", message, "statusCode", statusCode)
 	}
 	// Even if logger isn't ready, still try to send the error response
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: message, Details: details})
+}
+
+// SendJSONError sends a JSON error response whose code is derived from the status, e.g. BAD_REQUEST.
+func SendJSONError(w http.ResponseWriter, message string, statusCode int) {
+	SendError(w, statusCode, ErrorCodeForStatus(statusCode), message, nil)
+}
+
+// ErrorCodeForStatus returns the generic error code for an HTTP status, e.g. NOT_FOUND for 404.
+func ErrorCodeForStatus(statusCode int) string {
+	if statusCode == http.StatusInternalServerError {
+		return "INTERNAL_ERROR"
+	}
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
 }
@@ -1,7 +1,5 @@
 package utils
 
-import "math"
-
 // MinInt returns the smaller of two integers.
 func MinInt(a, b int) int {
 	if a < b {
@@ -17,9 +15,3 @@ func AbsInt(x int) int {
 	}
 	return x
 }
-
-// RoundFloat rounds a float64 to a specified number of decimal places.
-func RoundFloat(val float64, precision uint) float64 {
-	ratio := math.Pow(10, float64(precision))
-	return math.Round(val*ratio) / ratio
-}
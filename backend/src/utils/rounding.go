@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/username/taxfolio/backend/src/models"
+)
+
+// RoundingMode decides which way a value exactly halfway between two roundings goes.
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half-up"   // Away from zero: 0.125 becomes 0.13 and -0.125 becomes -0.13
+	RoundHalfEven RoundingMode = "half-even" // To the even neighbour (banker's rounding): 0.125 becomes 0.12
+)
+
+// MoneyDecimals is the number of decimal places amounts are reported with.
+const MoneyDecimals = 2
+
+// roundingMode is the policy RoundFloat applies. Amounts are kept unrounded while they are calculated
+// and rounded once, when they are reported; totals are summed from the rounded line items they list so
+// that they add up.
+var roundingMode = RoundHalfUp
+
+// SetRoundingMode changes the policy of RoundFloat and RoundMoney. It is meant to be called once at startup.
+func SetRoundingMode(mode string) error {
+	switch m := RoundingMode(mode); m {
+	case RoundHalfUp, RoundHalfEven:
+		roundingMode = m
+		return nil
+	}
+	return fmt.Errorf("unknown rounding mode %q, use %s or %s", mode, RoundHalfUp, RoundHalfEven)
+}
+
+// RoundMoney rounds an amount to MoneyDecimals places.
+func RoundMoney(val float64) float64 {
+	return RoundFloat(val, MoneyDecimals)
+}
+
+// RoundAmount rounds a fixed-point amount to MoneyDecimals places, exactly.
+func RoundAmount(amount models.Money) models.Money {
+	const step = models.MoneyScale / 100 // Money units per cent, for MoneyDecimals = 2
+	quotient, remainder := amount/step, amount%step
+	twiceRemainder := 2 * remainder.Abs()
+	if twiceRemainder > step || twiceRemainder == step && (roundingMode == RoundHalfUp || quotient%2 != 0) {
+		if amount < 0 {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return quotient * step
+}
+
+// RoundFloat rounds a float64 to a specified number of decimal places, breaking ties as the rounding
+// mode says. It rounds the decimal the float64 is printed as rather than its binary approximation:
+// 1.005 is stored as 1.00499999999999989..., which scaling and math.Round would take down to 1.00.
+func RoundFloat(val float64, precision uint) float64 {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return val
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(val, 'g', -1, 64))
+	if !ok {
+		return val
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	// Truncate towards zero, then move away from zero when the remainder is over half, or exactly half
+	// and the mode says so.
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	twiceRemainder := remainder.Abs(remainder).Lsh(remainder, 1)
+	cmp := twiceRemainder.Cmp(r.Denom())
+	if cmp > 0 || cmp == 0 && (roundingMode == RoundHalfUp || quotient.Bit(0) == 1) {
+		quotient.Add(quotient, big.NewInt(int64(r.Sign())))
+	}
+	rounded, _ := new(big.Rat).SetFrac(quotient, scale).Float64()
+	return rounded
+}
@@ -9,6 +9,7 @@ import RealizedGainsPage from './pages/RealizedGainsPage';
 import ProcessedTransactionsPage from './pages/ProcessedTransactionsPage';
 import NotFoundPage from './pages/NotFoundPage';
 import VerifyEmailPage from './pages/VerifyEmailPage';
+import UnlockAccountPage from './pages/UnlockAccountPage';
 import RequestPasswordResetPage from './pages/RequestPasswordResetPage';
 import ResetPasswordPage from './pages/ResetPasswordPage';
 import SettingsPage from './pages/SettingsPage';
@@ -91,6 +92,7 @@ function App() {
 
             {/* Rotas de Informação */}
             <Route path="/verify-email" element={<VerifyEmailPage />} />
+            <Route path="/unlock-account" element={<UnlockAccountPage />} />
             <Route path="/policies/privacy-policy" element={<PrivacyPolicyPage />} />
             <Route path="/policies/terms-of-service" element={<TermsOfServicePage />} />
             <Route path="/policies/contact-information" element={<ContactInformationPage />} />
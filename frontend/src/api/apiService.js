@@ -98,7 +98,14 @@ apiClient.interceptors.request.use(
 );
 
 apiClient.interceptors.response.use(
-  (response) => response,
+  (response) => {
+    // The backend rotates the CSRF token when it issues a new session (login, refresh).
+    const rotatedCsrfToken = response.headers?.['x-csrf-token'];
+    if (rotatedCsrfToken) {
+      setApiServiceCsrfToken(rotatedCsrfToken);
+    }
+    return response;
+  },
   async (error) => {
     const originalRequest = error.config;
     if (error.response?.data?.code === 'EMAIL_NOT_VERIFIED') {
@@ -189,11 +196,13 @@ apiClient.interceptors.response.use(
 export const apiLogin = (email, password) => apiClient.post(API_ENDPOINTS.AUTH_LOGIN, { email, password });
 export const apiRegister = (username, email, password) => apiClient.post(API_ENDPOINTS.AUTH_REGISTER, { username, email, password });
 export const apiLogout = () => apiClient.post(API_ENDPOINTS.AUTH_LOGOUT, {});
+export const apiExchangeGoogleCode = (code) => apiClient.post(API_ENDPOINTS.AUTH_GOOGLE_EXCHANGE, { code });
 export const apiRequestPasswordReset = (email) => apiClient.post(API_ENDPOINTS.AUTH_REQUEST_PASSWORD_RESET, { email });
 export const apiResetPassword = (token, password, confirm_password) => apiClient.post(API_ENDPOINTS.AUTH_RESET_PASSWORD, { token, password, confirm_password });
 export const apiChangePassword = (currentPassword, newPassword, confirmNewPassword) => apiClient.post(API_ENDPOINTS.USER_CHANGE_PASSWORD, { current_password: currentPassword, new_password: newPassword, confirm_new_password: confirmNewPassword });
 export const apiDeleteAccount = (password) => apiClient.post(API_ENDPOINTS.USER_DELETE_ACCOUNT, { password });
 export const apiUploadFile = (formData, onUploadProgress) => apiClient.post(API_ENDPOINTS.UPLOAD, formData, { headers: { 'Content-Type': 'multipart/form-data' }, onUploadProgress });
+export const apiFetchCSVMapping = () => apiClient.get(API_ENDPOINTS.UPLOAD_CSV_MAPPING);
 export const apiFetchRealizedGainsData = () => apiClient.get(API_ENDPOINTS.REALIZEDGAINS_DATA);
 export const apiFetchProcessedTransactions = () => apiClient.get(API_ENDPOINTS.PROCESSED_TRANSACTIONS);
 export const apiFetchStockHoldings = () => apiClient.get(API_ENDPOINTS.STOCK_HOLDINGS);
@@ -206,6 +215,7 @@ export const apiFetchDividendTransactions = () => apiClient.get(API_ENDPOINTS.DI
 export const apiCheckUserHasData = () => apiClient.get(API_ENDPOINTS.USER_HAS_DATA);
 export const apiDeleteAllTransactions = () => apiClient.delete(API_ENDPOINTS.DELETE_ALL_TRANSACTIONS);
 export const apiVerifyEmail = (token) => apiClient.get(`${API_ENDPOINTS.AUTH_VERIFY_EMAIL}?token=${token}`);
+export const apiUnlockAccount = (token) => apiClient.post(API_ENDPOINTS.AUTH_UNLOCK_ACCOUNT, { token });
 export const apiFetchFees = () => apiClient.get(API_ENDPOINTS.FEES_DATA);
 
 export default apiClient;
\ No newline at end of file
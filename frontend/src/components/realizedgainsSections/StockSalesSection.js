@@ -14,6 +14,11 @@ const calculateAnnualizedReturnForStocksLocal = (sale) => {
     return calculateAnnualizedReturn(sale.Delta, sale.BuyAmountEUR, daysHeld);
 };
 
+// Holding period rules of the user's tax residence that change how a sale is taxed.
+const HOLDING_RULE_LABELS = {
+    PT_SHORT_TERM: 'Detido menos de 365 dias: englobamento obrigatório no último escalão',
+};
+
 const columns = [
     {
       field: 'BuyDate',
@@ -54,6 +59,18 @@ const columns = [
             </Box>
         ),
     },
+    { field: 'gain_eur', headerName: 'L/P líquido (€)', type: 'number', width: 120, valueFormatter: (value) => typeof value === 'number' ? value.toFixed(2) : '' },
+    {
+        field: 'taxable_gain_eur',
+        headerName: 'L/P tributável (€)',
+        type: 'number',
+        width: 140,
+        renderCell: (params) => (
+            <Box title={HOLDING_RULE_LABELS[params.row.holding_rule] || ''}>
+                {params.value?.toFixed(2)}{HOLDING_RULE_LABELS[params.row.holding_rule] ? ' *' : ''}
+            </Box>
+        ),
+    },
 ];
 
 export default function StockSalesSection({ stockSalesData, selectedYear }) {
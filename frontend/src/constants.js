@@ -8,12 +8,15 @@
       AUTH_LOGOUT: `${API_BASE_PATH}/auth/logout`,
       AUTH_REFRESH: `${API_BASE_PATH}/auth/refresh`,
       AUTH_VERIFY_EMAIL: `${API_BASE_PATH}/auth/verify-email`,
+      AUTH_UNLOCK_ACCOUNT: `${API_BASE_PATH}/auth/unlock-account`,
       AUTH_REQUEST_PASSWORD_RESET: `${API_BASE_PATH}/auth/request-password-reset`,
       AUTH_RESET_PASSWORD: `${API_BASE_PATH}/auth/reset-password`,
       AUTH_RESET_PASSWORD_PAGE: `${API_BASE_PATH}/auth/reset-password`, 
       AUTH_GOOGLE_LOGIN: `${API_BASE_PATH}/auth/google/login`,
+      AUTH_GOOGLE_EXCHANGE: `${API_BASE_PATH}/auth/google/exchange`,
 
       UPLOAD: `${API_BASE_PATH}/upload`,
+      UPLOAD_CSV_MAPPING: `${API_BASE_PATH}/upload/csv-mapping`,
       REALIZEDGAINS_DATA: `${API_BASE_PATH}/realizedgains-data`,
       PROCESSED_TRANSACTIONS: `${API_BASE_PATH}/transactions/processed`,
       STOCK_HOLDINGS: `${API_BASE_PATH}/holdings/stocks`,
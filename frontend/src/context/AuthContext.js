@@ -138,7 +138,7 @@ export const AuthProvider = ({ children }) => {
       const response = await apiRegister(username, email, password);
       if (onSuccess) onSuccess(response.data);
     } catch (err) {
-      const errMsg = err.response?.data?.error || err.message || 'Registration failed.';
+      const errMsg = err.response?.data?.message || err.message || 'Registration failed.';
       setAuthError(errMsg);
       if (onError) onError(new Error(errMsg));
     } finally {
@@ -167,7 +167,7 @@ export const AuthProvider = ({ children }) => {
       
       return response.data;
     } catch (err) {
-      const errMsg = err.response?.data?.error || err.message || 'Login failed.';
+      const errMsg = err.response?.data?.message || err.message || 'Login failed.';
       performLogout(false, `Login failed: ${errMsg}`);
       setAuthError(errMsg);
       throw new Error(errMsg);
@@ -178,7 +178,7 @@ export const AuthProvider = ({ children }) => {
   };
   
   // --- NOVA FUNÇÃO PARA O CALLBACK DO GOOGLE ---
-  const loginWithGoogleToken = useCallback(async (appToken, googleUserData) => {
+  const loginWithGoogleToken = useCallback(async (appToken, googleUserData, refreshToken) => {
     setIsAuthActionLoading(true);
     setCheckingData(true);
     setAuthError(null);
@@ -192,17 +192,23 @@ export const AuthProvider = ({ children }) => {
 
     setUser(appUser);
     setToken(appToken);
-    setRefreshTokenState(null); // O Google Auth não usa o nosso sistema de refresh token
+    setRefreshTokenState(refreshToken || null);
 
     localStorage.setItem('auth_token', appToken);
     localStorage.setItem('user', JSON.stringify(appUser));
-    localStorage.removeItem('refresh_token'); // Limpar refresh token antigo se houver
+    if (refreshToken) {
+      localStorage.setItem('refresh_token', refreshToken);
+    } else {
+      localStorage.removeItem('refresh_token');
+    }
 
+    // CSRF tokens are bound to the session, so get one for the new access token.
+    await fetchCsrfTokenAndUpdateService(true);
     await checkUserData();
     
     setIsAuthActionLoading(false);
     setCheckingData(false);
-  }, [checkUserData]);
+  }, [checkUserData, fetchCsrfTokenAndUpdateService]);
 
   const logout = async () => {
     setIsAuthActionLoading(true);
@@ -1,7 +1,8 @@
 // frontend/src/pages/GoogleAuthCallbackPage.js
-import React, { useEffect, useContext } from 'react';
+import React, { useEffect, useContext, useRef } from 'react';
 import { useLocation, useNavigate } from 'react-router-dom';
 import { AuthContext } from '../context/AuthContext';
+import { apiExchangeGoogleCode } from '../api/apiService';
 import { Box, CircularProgress, Typography } from '@mui/material';
 
 const GoogleAuthCallbackPage = () => {
@@ -9,21 +10,29 @@ const GoogleAuthCallbackPage = () => {
     const navigate = useNavigate();
     const { loginWithGoogleToken } = useContext(AuthContext); // Precisará de adicionar esta função ao context
 
+    const exchanged = useRef(false); // The code can only be exchanged once
+
     useEffect(() => {
+        if (exchanged.current) {
+            return;
+        }
         const params = new URLSearchParams(location.search);
-        const token = params.get('token');
+        const code = params.get('code');
         const userStr = params.get('user');
 
-        if (token && userStr) {
-            try {
-                const user = JSON.parse(decodeURIComponent(userStr));
-                // Chame uma função no seu context para guardar o token e o utilizador
-                loginWithGoogleToken(token, user);
-                navigate('/dashboard');
-            } catch (e) {
-                 console.error("Failed to parse user data from Google callback", e);
-                 navigate('/signin?error=callback_failed');
-            }
+        if (code && userStr) {
+            exchanged.current = true;
+            // Os tokens não vêm no URL: o código de uso único é trocado por eles
+            apiExchangeGoogleCode(code)
+                .then(res => {
+                    const user = JSON.parse(decodeURIComponent(userStr));
+                    loginWithGoogleToken(res.data.access_token, user, res.data.refresh_token);
+                    navigate('/dashboard');
+                })
+                .catch(e => {
+                    console.error("Failed to complete Google login", e);
+                    navigate('/signin?error=callback_failed');
+                });
         } else {
             navigate('/signin?error=missing_token');
         }
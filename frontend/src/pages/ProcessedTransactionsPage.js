@@ -188,7 +188,7 @@ const ProcessedTransactionsPage = () => {
           </DialogContentText>
           {deleteTransactionsMutation.isError && (
             <Alert severity="error" sx={{ mt: 2 }}>
-              {deleteTransactionsMutation.error.response?.data?.error || deleteTransactionsMutation.error.message || "Falha a excluir as transações."}
+              {deleteTransactionsMutation.error.response?.data?.message || deleteTransactionsMutation.error.message || "Falha a excluir as transações."}
             </Alert>
           )}
         </DialogContent>
@@ -38,7 +38,7 @@ function RequestPasswordResetPage() {
       const response = await apiRequestPasswordReset(email);
       setMessage(response.data.message || 'If an account with that email exists, a password reset link has been sent.');
     } catch (err) {
-      setError(err.response?.data?.error || err.message || 'Failed to request password reset. Please try again.');
+      setError(err.response?.data?.message || err.message || 'Failed to request password reset. Please try again.');
     } finally {
       setIsLoading(false);
     }
@@ -45,8 +45,8 @@
           setIsLoading(false);
           return;
         }
-        if (password.length < 6) {
-          setError('Password must be at least 6 characters long.');
+        if (password.length < 8) {
+          setError('Password must be at least 8 characters long.');
           setIsLoading(false);
           return;
         }
@@ -57,7 +57,7 @@
           setMessage(response.data.message || 'Password has been reset successfully. You can now log in.');
           setTimeout(() => navigate('/signin'), 3000);
         } catch (err) {
-          setError(err.response?.data?.error || err.message || 'Failed to reset password. The link may be invalid or expired.');
+          setError(err.response?.data?.message || err.message || 'Failed to reset password. The link may be invalid or expired.');
         } finally {
           setIsLoading(false);
         }
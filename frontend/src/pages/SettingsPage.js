@@ -37,7 +37,7 @@ function SettingsPage() {
     },
     onError: (error) => {
       setChangePasswordSuccess('');
-      setChangePasswordError(error.response?.data?.error || error.message || 'Falha ao mudar a password.');
+      setChangePasswordError(error.response?.data?.message || error.message || 'Falha ao mudar a password.');
     }
   });
 
@@ -52,7 +52,7 @@ function SettingsPage() {
       navigate('/signin');
     },
     onError: (error) => {
-      setDeleteAccountErrorDialog(error.response?.data?.error || error.message || 'Falha ao tentar eliminar a conta. A password poderá estar incorrecta.');
+      setDeleteAccountErrorDialog(error.response?.data?.message || error.message || 'Falha ao tentar eliminar a conta. A password poderá estar incorrecta.');
     }
   });
 
@@ -65,8 +65,8 @@ function SettingsPage() {
       setChangePasswordError("As passwords novas não são iguais.");
       return;
     }
-    if (newPassword.length < 6) {
-      setChangePasswordError("A nova password precisa de ter no mínimo 6 caracteres.");
+    if (newPassword.length < 8) {
+      setChangePasswordError("A nova password precisa de ter no mínimo 8 caracteres.");
       return;
     }
     if (!currentPassword) {
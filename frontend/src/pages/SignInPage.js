@@ -44,7 +44,7 @@ function SignInPage() {
       setLocalSuccess(true);
     } catch (err) {
       if (err.response?.data?.code === 'EMAIL_NOT_VERIFIED') {
-        const errorMessage = err.response.data.error || 'O teu e-mail ainda não foi validado. Foi enviado um novo link.';
+        const errorMessage = err.response.data.message || 'O teu e-mail ainda não foi validado. Foi enviado um novo link.';
         setLocalError(errorMessage);
       } else {
         const errorMessage = err.message || 'Ocorreu um erro inesperado durante o login.';
@@ -29,7 +29,7 @@ function SignUpPage() {
     if (!email.trim()) clientValidationError = 'Email é obrigatório.';
     else if (!/\S+@\S+\.\S+/.test(email)) clientValidationError = 'Email inválido.';
     else if (!password) clientValidationError = 'Senha é obrigatória.';
-    else if (password.length < 6) clientValidationError = 'A senha deve ter pelo menos 6 caracteres.';
+    else if (password.length < 8) clientValidationError = 'A senha deve ter pelo menos 8 caracteres.';
     else if (password !== confirmPassword) clientValidationError = 'As senhas não coincidem.';
 
     if (clientValidationError) {
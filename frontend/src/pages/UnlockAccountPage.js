@@ -0,0 +1,63 @@
+// frontend/src/pages/UnlockAccountPage.js
+import React, { useMemo } from 'react';
+import { useLocation, useNavigate } from 'react-router-dom';
+import { useMutation } from '@tanstack/react-query';
+import { apiUnlockAccount } from '../api/apiService';
+import { Typography, Box, Button, CircularProgress, Alert } from '@mui/material';
+
+// The account is only unlocked when the user confirms, so opening the emailed link (as mail
+// scanners do) does not use up the token.
+const UnlockAccountPage = () => {
+  const location = useLocation();
+  const navigate = useNavigate();
+
+  const token = useMemo(() => {
+    const queryParams = new URLSearchParams(location.search);
+    return queryParams.get('token');
+  }, [location.search]);
+
+  const unlockMutation = useMutation({
+    mutationFn: async () => {
+      const response = await apiUnlockAccount(token);
+      return response.data;
+    },
+    onSuccess: () => {
+      setTimeout(() => navigate('/signin'), 3000);
+    },
+  });
+  const { data, error, isPending, isSuccess, isError } = unlockMutation;
+
+  return (
+    <Box sx={{ display: 'flex', flexDirection: 'column', alignItems: 'center', justifyContent: 'center', p: 3, mt: 4, textAlign: 'center' }}>
+      <Typography variant="h5" gutterBottom>
+        Desbloqueio de conta
+      </Typography>
+
+      {token && !isSuccess && (
+        <Button variant="contained" onClick={() => unlockMutation.mutate()} disabled={isPending} sx={{ my: 2 }}>
+          {isPending ? <CircularProgress size={24} /> : 'Desbloquear conta'}
+        </Button>
+      )}
+
+      {isSuccess && (
+        <Alert severity="success" sx={{ my: 2, width: '100%', maxWidth: '500px' }}>
+          {data?.message || 'Conta desbloqueada com sucesso! Redirecionando...'}
+        </Alert>
+      )}
+
+      {isError && (
+        <Alert severity="error" sx={{ my: 2, width: '100%', maxWidth: '500px' }}>
+          {error.response?.data?.message || error.message || 'An unknown error occurred.'}
+        </Alert>
+      )}
+
+      {!token && (
+         <Alert severity="warning" sx={{ my: 2, width: '100%', maxWidth: '500px' }}>
+          Link de desbloqueio inválido. Nenhum token fornecido.
+        </Alert>
+      )}
+    </Box>
+  );
+};
+
+export default UnlockAccountPage;
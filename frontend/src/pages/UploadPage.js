@@ -1,9 +1,9 @@
 // frontend/src/pages/UploadPage.js
-import React, { useState, useCallback, useRef } from 'react';
+import React, { useState, useCallback, useRef, useEffect } from 'react';
 import { useAuth } from '../context/AuthContext';
-import { apiUploadFile } from '../api/apiService';
+import { apiUploadFile, apiFetchCSVMapping } from '../api/apiService';
 import { MAX_FILE_SIZE_BYTES, MAX_FILE_SIZE_MB } from '../constants';
-import { Typography, Box, Button, LinearProgress, Paper, Alert, Modal, IconButton, Link as MuiLink, CircularProgress } from '@mui/material';
+import { Typography, Box, Button, LinearProgress, Paper, Alert, Modal, IconButton, Link as MuiLink, CircularProgress, TextField, MenuItem } from '@mui/material';
 import { styled } from '@mui/material/styles';
 import { useQueryClient } from '@tanstack/react-query';
 import { UploadFile as UploadFileIcon, CheckCircleOutline as CheckCircleIcon, ErrorOutline as ErrorIcon, Close as CloseIcon } from '@mui/icons-material';
@@ -71,6 +71,27 @@ const uploadWithRetry = async (formData, onUploadProgress) => {
     throw lastError;
 };
 
+// Brokers whose exports cannot be told apart by file extension must be picked explicitly.
+const BROKER_OPTIONS = [
+    { value: 'auto', label: 'Detetar automaticamente (Degiro / IBKR)' },
+    { value: 'degiro', label: 'Degiro (CSV)' },
+    { value: 'ibkr', label: 'Interactive Brokers (XML)' },
+    { value: 'xtb', label: 'XTB (XLSX ou CSV)' },
+    { value: 'etoro', label: 'eToro (XLSX)' },
+    { value: 'generic', label: 'Outra corretora (CSV com mapeamento de colunas)' },
+];
+
+const CSV_MAPPING_EXAMPLE = `{
+  "date_column": "Data",
+  "date_format": "DD-MM-YYYY",
+  "type_column": "Tipo",
+  "amount_column": "Montante",
+  "isin_column": "ISIN",
+  "quantity_column": "Quantidade",
+  "default_currency": "EUR",
+  "type_values": { "Compra": "BUY", "Venda": "SELL", "Dividendo": "DIVIDEND" }
+}`;
+
 const UploadPage = () => {
     const { token, refreshUserDataCheck } = useAuth();
     const queryClient = useQueryClient();
@@ -80,12 +101,22 @@ const UploadPage = () => {
     const [uploadStatus, setUploadStatus] = useState('idle'); // 'idle', 'uploading', 'processing', 'success', 'error'
     const [fileError, setFileError] = useState(null);
     const [isDragActive, setIsDragActive] = useState(false);
+    const [broker, setBroker] = useState('auto');
+    const [csvMapping, setCsvMapping] = useState('');
     const fileInputRef = useRef(null);
     
     const [guideModal, setGuideModal] = useState(null);
     const handleOpenGuide = (broker) => setGuideModal(broker);
     const handleCloseGuide = () => setGuideModal(null);
 
+    // Prefill the mapping saved with the last generic CSV upload.
+    useEffect(() => {
+        if (broker !== 'generic' || csvMapping) return;
+        apiFetchCSVMapping()
+            .then((response) => setCsvMapping(JSON.stringify(response.data, null, 2)))
+            .catch(() => {}); // No mapping saved yet
+    }, [broker, csvMapping]);
+
     const resetState = () => {
         setSelectedFile(null);
         setUploadProgress(0);
@@ -103,13 +134,36 @@ const UploadPage = () => {
         const fileName = file.name.toLowerCase();
         const isCsv = fileName.endsWith('.csv');
         const isXml = fileName.endsWith('.xml');
+        const isXlsx = fileName.endsWith('.xlsx');
+        const isZip = fileName.endsWith('.zip');
+        const isPdf = fileName.endsWith('.pdf');
 
-        if (!isCsv && !isXml) {
-            setFileError('Tipo de ficheiro inválido. Por favor, carregue um ficheiro .csv (Degiro) ou .xml (IBKR).');
+        if (!isCsv && !isXml && !isXlsx && !isZip && !isPdf) {
+            setFileError('Tipo de ficheiro inválido. Por favor, carregue um ficheiro .csv ou .pdf (Degiro), .csv (XTB), .xml (IBKR), .xlsx (XTB, eToro) ou um .zip com vários extratos.');
+            setUploadStatus('error');
+            return;
+        }
+        if (broker === 'auto' && isXlsx) {
+            setFileError('Para ficheiros .xlsx, selecione a corretora (XTB ou eToro) antes de carregar.');
             setUploadStatus('error');
             return;
         }
 
+        if (broker === 'generic') {
+            if (!isCsv && !isZip) {
+                setFileError('O mapeamento de colunas só está disponível para ficheiros .csv.');
+                setUploadStatus('error');
+                return;
+            }
+            try {
+                JSON.parse(csvMapping);
+            } catch (e) {
+                setFileError('O mapeamento de colunas não é um JSON válido.');
+                setUploadStatus('error');
+                return;
+            }
+        }
+
         if (file.size > MAX_FILE_SIZE_BYTES) {
             setFileError(`O tamanho do ficheiro excede o limite de ${MAX_FILE_SIZE_MB}MB.`);
             setUploadStatus('error');
@@ -118,10 +172,17 @@ const UploadPage = () => {
 
         setSelectedFile(file);
         
-        const brokerType = isCsv ? 'degiro' : 'ibkr';
+        let brokerType = broker;
+        if (broker === 'auto') {
+            // The files of a .zip are recognized by the server; the source only covers the ones it cannot tell.
+            brokerType = isCsv || isZip || isPdf ? 'degiro' : 'ibkr';
+        }
         const formData = new FormData();
         formData.append('file', file);
         formData.append('source', brokerType);
+        if (brokerType === 'generic') {
+            formData.append('mapping', csvMapping);
+        }
 
         try {
             setUploadStatus('uploading');
@@ -145,9 +206,9 @@ const UploadPage = () => {
 
         } catch (err) {
             setUploadStatus('error');
-            setFileError(err.response?.data?.error || err.message || 'Falha no carregamento. Por favor tente de novo.');
+            setFileError(err.response?.data?.message || err.message || 'Falha no carregamento. Por favor tente de novo.');
         }
-    }, [token, queryClient, refreshUserDataCheck]);
+    }, [token, queryClient, refreshUserDataCheck, broker, csvMapping]);
 
     const handleDragEnter = (e) => { e.preventDefault(); e.stopPropagation(); setIsDragActive(true); };
     const handleDragLeave = (e) => { e.preventDefault(); e.stopPropagation(); setIsDragActive(false); };
@@ -189,6 +250,35 @@ const UploadPage = () => {
             </Typography>
 
             <Paper elevation={0} sx={{ p: { xs: 2, sm: 3 }, border: '1px solid', borderColor: 'divider' }}>
+                {uploadStatus === 'idle' && (
+                    <TextField
+                        select
+                        fullWidth
+                        size="small"
+                        label="Corretora"
+                        value={broker}
+                        onChange={(e) => setBroker(e.target.value)}
+                        sx={{ mb: 2 }}
+                    >
+                        {BROKER_OPTIONS.map((option) => (
+                            <MenuItem key={option.value} value={option.value}>{option.label}</MenuItem>
+                        ))}
+                    </TextField>
+                )}
+                {uploadStatus === 'idle' && broker === 'generic' && (
+                    <TextField
+                        fullWidth
+                        multiline
+                        minRows={6}
+                        size="small"
+                        label="Mapeamento de colunas (JSON)"
+                        placeholder={CSV_MAPPING_EXAMPLE}
+                        helperText="Indique os nomes das colunas do seu ficheiro e o tipo (BUY, SELL, DIVIDEND, DIVIDEND_TAX, RETURN_OF_CAPITAL, FEE, DEPOSIT, WITHDRAWAL) de cada valor da coluna de tipo. O mapeamento fica guardado para os próximos carregamentos."
+                        value={csvMapping}
+                        onChange={(e) => setCsvMapping(e.target.value)}
+                        sx={{ mb: 2, '& textarea': { fontFamily: 'monospace' } }}
+                    />
+                )}
                 {uploadStatus === 'idle' && (
                     <UploadDropzone
                         isDragActive={isDragActive}
@@ -208,7 +298,7 @@ const UploadPage = () => {
                         <UploadFileIcon sx={{ fontSize: 50, mb: 2 }} />
                         <Typography variant="h6">Arraste e solte o seu ficheiro aqui</Typography>
                         <Typography>ou clique para selecionar o ficheiro</Typography>
-                        <Typography variant="caption" sx={{ mt: 1 }}>Tipos suportados: CSV (Degiro), XML (IBKR) | Limite: {MAX_FILE_SIZE_MB}MB<br/>
+                        <Typography variant="caption" sx={{ mt: 1 }}>Tipos suportados: CSV (Degiro, XTB), XML (IBKR), XLSX (XTB, eToro) | Limite: {MAX_FILE_SIZE_MB}MB<br/>
   Problemas no telemóvel? Se o ficheiro aparecer a cinzento, tente renomeá-lo para garantir que termina em .csv.</Typography>
                     </UploadDropzone>
                 )}
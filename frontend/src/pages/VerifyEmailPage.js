@@ -20,7 +20,7 @@ const verifyEmailToken = async (token) => {
     const { data } = await axios.get(verificationUrl); // <-- THIS IS THE PROBLEM LINE
     return data;
   } catch (err) {
-    const errorMessage = err.response?.data?.error || err.response?.data?.message || err.message || 'Failed to verify email.';
+    const errorMessage = err.response?.data?.message || err.message || 'Failed to verify email.';
     throw new Error(errorMessage);
   }
 };